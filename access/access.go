@@ -0,0 +1,153 @@
+// Package access sorts the people who message the agent into permission
+// tiers (owner, trusted, guest, blocked) that decide whether the agent
+// answers them, which tools their messages may trigger, and how long its
+// replies may be. Tiers apply to channel messages; gateway clients
+// authenticate with tokens instead.
+package access
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/plexusone/omniagent/identity"
+)
+
+// Tiers, from most to least trusted.
+const (
+	TierOwner   = "owner"
+	TierTrusted = "trusted"
+	TierGuest   = "guest"
+	TierBlocked = "blocked"
+)
+
+// tierOrder decides between tiers when someone is listed in several:
+// blocked wins, then the most trusted.
+var tierOrder = []string{TierBlocked, TierOwner, TierTrusted, TierGuest}
+
+// Tier is what members of a tier may do.
+type Tier struct {
+	// Tools the tier's messages may trigger; nil allows every tool and an
+	// empty list allows none.
+	Tools []string
+
+	// MaxTokens caps each reply; 0 keeps the agent's limit.
+	MaxTokens int
+
+	// Silent tiers get no reply at all.
+	Silent bool
+}
+
+// DefaultTiers answer everyone but the blocked. Owners and trusted
+// members may trigger every tool, guests none.
+var DefaultTiers = map[string]Tier{
+	TierOwner:   {},
+	TierTrusted: {},
+	TierGuest:   {Tools: []string{}},
+	TierBlocked: {Silent: true},
+}
+
+// TierOverride changes some settings of a tier; unset fields keep the
+// tier's defaults.
+type TierOverride struct {
+	// Tools replaces the tier's tools unless nil; "*" allows every tool.
+	Tools []string
+
+	MaxTokens *int
+	Silent    *bool
+}
+
+// Config configures access control.
+type Config struct {
+	// Members lists contact IDs ("channel:senderID") and person IDs
+	// ("person:alice") by tier name.
+	Members map[string][]string
+
+	// Tiers overrides the default settings of the named tiers.
+	Tiers map[string]TierOverride
+
+	// Default is the tier of everyone not listed (default: guest).
+	Default string
+}
+
+// Control resolves senders to tiers.
+type Control struct {
+	tiers    map[string]Tier
+	members  map[string]string // Contact ID to tier name
+	fallback string
+	mu       sync.RWMutex
+}
+
+// New creates access control. People linked in the identity directory
+// share a tier across all their contacts.
+func New(config Config, people *identity.Directory) (*Control, error) {
+	c := &Control{
+		tiers:    make(map[string]Tier, len(DefaultTiers)),
+		members:  make(map[string]string),
+		fallback: config.Default,
+	}
+	for name, t := range DefaultTiers {
+		c.tiers[name] = t
+	}
+	for name, o := range config.Tiers {
+		t, ok := DefaultTiers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown access tier %q", name)
+		}
+		switch {
+		case slices.Contains(o.Tools, "*"):
+			t.Tools = nil
+		case o.Tools != nil:
+			t.Tools = o.Tools
+		}
+		if o.MaxTokens != nil {
+			t.MaxTokens = *o.MaxTokens
+		}
+		if o.Silent != nil {
+			t.Silent = *o.Silent
+		}
+		c.tiers[name] = t
+	}
+	if c.fallback == "" {
+		c.fallback = TierGuest
+	}
+	if _, ok := c.tiers[c.fallback]; !ok {
+		return nil, fmt.Errorf("unknown default access tier %q", c.fallback)
+	}
+	for name := range config.Members {
+		if _, ok := c.tiers[name]; !ok {
+			return nil, fmt.Errorf("unknown access tier %q", name)
+		}
+	}
+
+	// Assign from least to most binding so the stronger tier wins
+	for i := len(tierOrder) - 1; i >= 0; i-- {
+		name := tierOrder[i]
+		for _, id := range people.Expand(config.Members[name]) {
+			c.members[id] = name
+		}
+	}
+	return c, nil
+}
+
+// Update replaces the tiers and members with those of other, as on a
+// config reload.
+func (c *Control) Update(other *Control) {
+	other.mu.RLock()
+	tiers, members, fallback := other.tiers, other.members, other.fallback
+	other.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tiers, c.members, c.fallback = tiers, members, fallback
+}
+
+// Tier returns the tier name and settings for a sender's contact ID.
+func (c *Control) Tier(contactID string) (string, Tier) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	name, ok := c.members[contactID]
+	if !ok {
+		name = c.fallback
+	}
+	return name, c.tiers[name]
+}
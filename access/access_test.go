@@ -0,0 +1,111 @@
+package access
+
+import (
+	"testing"
+
+	"github.com/plexusone/omniagent/identity"
+)
+
+func TestTiers(t *testing.T) {
+	people, err := identity.New([]identity.Person{{ID: "alice", Contacts: []string{"telegram:1", "discord:1"}}})
+	if err != nil {
+		t.Fatalf("identity.New() error = %v", err)
+	}
+	maxTokens := 500
+	c, err := New(Config{
+		Members: map[string][]string{
+			TierOwner:   {"person:alice"},
+			TierTrusted: {"telegram:2", "telegram:3"},
+			TierBlocked: {"telegram:3"},
+		},
+		Tiers: map[string]TierOverride{TierGuest: {Tools: []string{}, MaxTokens: &maxTokens}},
+	}, people)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		contact string
+		want    string
+	}{
+		{"discord:1", TierOwner},
+		{"telegram:2", TierTrusted},
+		{"telegram:3", TierBlocked}, // Blocked wins
+		{"sms:+1555", TierGuest},
+	}
+	for _, tt := range tests {
+		if got, _ := c.Tier(tt.contact); got != tt.want {
+			t.Errorf("Tier(%q) = %q, want %q", tt.contact, got, tt.want)
+		}
+	}
+
+	if _, tier := c.Tier("sms:+1555"); tier.Tools == nil || len(tier.Tools) != 0 || tier.MaxTokens != 500 {
+		t.Errorf("guest tier = %+v, want no tools and 500 tokens", tier)
+	}
+	if _, tier := c.Tier("telegram:3"); !tier.Silent {
+		t.Error("blocked tier should be silent")
+	}
+	if _, tier := c.Tier("telegram:2"); tier.Tools != nil || tier.Silent {
+		t.Errorf("trusted tier = %+v, want the defaults", tier)
+	}
+
+	defaults, err := New(Config{}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if name, tier := defaults.Tier("sms:+1555"); name != TierGuest || tier.Tools == nil || len(tier.Tools) != 0 {
+		t.Errorf("default tier = %s %+v, want guest without tools", name, tier)
+	}
+
+	reloaded, err := New(Config{Members: map[string][]string{TierTrusted: {"sms:+1555"}}}, people)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	c.Update(reloaded)
+	if got, _ := c.Tier("sms:+1555"); got != TierTrusted {
+		t.Errorf("Tier() after Update = %q, want trusted", got)
+	}
+	if got, _ := c.Tier("discord:1"); got != TierGuest {
+		t.Errorf("Tier() after Update = %q, want guest", got)
+	}
+
+	if _, err := New(Config{Members: map[string][]string{"admin": {"telegram:1"}}}, nil); err == nil {
+		t.Error("unknown tier should fail")
+	}
+	if _, err := New(Config{Default: "nobody"}, nil); err == nil {
+		t.Error("unknown default tier should fail")
+	}
+}
+
+func TestTierOverridesKeepDefaults(t *testing.T) {
+	maxTokens, silent := 200, false
+	c, err := New(Config{
+		Members: map[string][]string{TierTrusted: {"telegram:2"}, TierBlocked: {"telegram:3"}},
+		Tiers: map[string]TierOverride{
+			TierGuest:   {MaxTokens: &maxTokens},
+			TierBlocked: {MaxTokens: &maxTokens},
+			TierTrusted: {Tools: []string{"time"}, Silent: &silent},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, tier := c.Tier("sms:+1555"); tier.Tools == nil || len(tier.Tools) != 0 || tier.MaxTokens != 200 {
+		t.Errorf("guest tier = %+v, want no tools and 200 tokens", tier)
+	}
+	if _, tier := c.Tier("telegram:3"); !tier.Silent || tier.MaxTokens != 200 {
+		t.Errorf("blocked tier = %+v, want silent with 200 tokens", tier)
+	}
+	if _, tier := c.Tier("telegram:2"); len(tier.Tools) != 1 || tier.Silent {
+		t.Errorf("trusted tier = %+v, want only time", tier)
+	}
+
+	open, err := New(Config{Tiers: map[string]TierOverride{TierGuest: {Tools: []string{"*"}}}}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, tier := open.Tier("sms:+1555"); tier.Tools != nil {
+		t.Errorf("guest tier = %+v, want every tool", tier)
+	}
+}
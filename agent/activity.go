@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Activity kinds reported to observers.
+const (
+	ActivityMessage  = "message"   // A user message was received
+	ActivityToolCall = "tool_call" // The model called a tool
+	ActivityReply    = "reply"     // The agent replied or failed
+)
+
+// Activity is a live step of a conversation, reported as it happens so
+// monitoring clients can follow along.
+type Activity struct {
+	Time      time.Time
+	Kind      string
+	SessionID string
+	Channel   string
+	Persona   string          // Empty for the default agent
+	Content   string          // Message, tool result, or reply text
+	Tool      string          // Set for tool calls
+	Args      json.RawMessage // Set for tool calls
+	Error     string
+}
+
+// activityHook holds the observer callback. Derived persona agents share
+// it, so a callback set after the pool is built sees every agent.
+type activityHook struct {
+	mu sync.RWMutex
+	fn func(Activity)
+}
+
+// OnActivity registers fn to receive conversation activity from this agent
+// and every persona derived from it. fn runs synchronously and must not
+// block.
+func (a *Agent) OnActivity(fn func(Activity)) {
+	a.activity.mu.Lock()
+	a.activity.fn = fn
+	a.activity.mu.Unlock()
+}
+
+// emit reports an activity to the registered observer, if any.
+func (a *Agent) emit(act Activity) {
+	a.activity.mu.RLock()
+	fn := a.activity.fn
+	a.activity.mu.RUnlock()
+	if fn == nil {
+		return
+	}
+	act.Time = time.Now()
+	act.Channel = ChannelFromSession(act.SessionID)
+	act.Persona = a.persona
+	fn(act)
+}
@@ -4,22 +4,90 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/plexusone/omnillm"
 	"github.com/plexusone/omnillm/provider"
-
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/plexusone/omniagent/approvals"
+	"github.com/plexusone/omniagent/audit"
+	"github.com/plexusone/omniagent/budget"
+	"github.com/plexusone/omniagent/clock"
+	"github.com/plexusone/omniagent/eval"
+	"github.com/plexusone/omniagent/flags"
+	"github.com/plexusone/omniagent/ratelimit"
 	"github.com/plexusone/omniagent/skills"
+	"github.com/plexusone/omniagent/transcripts"
+	"github.com/plexusone/omniagent/usage"
 )
 
 // Agent is the AI agent that processes messages.
 type Agent struct {
-	client *omnillm.ChatClient
-	tools  *ToolRegistry
-	skills []*skills.Skill
-	config Config
-	logger *slog.Logger
+	client      *omnillm.ChatClient
+	tools       *ToolRegistry
+	skills      []*skills.Skill
+	config      Config
+	logger      *slog.Logger
+	commands    *commandRegistry
+	prefs       *PreferenceStore
+	pins        *PinStore
+	traces      *eval.Store
+	clock       *clock.Resolver
+	budget      *budget.Tracker
+	throttle    *ratelimit.Throttle
+	approvals   *approvals.Manager
+	transcripts *transcripts.Store
+	flags       *flags.Flags
+	audit       *audit.Log
+	ledger      *usage.Ledger
+
+	// contextVars stores message metadata per session; nil disables it.
+	contextVars *contextStore
+
+	// retriever adds relevant context, such as memories, to prompts.
+	retriever Retriever
+
+	// attachmentReader shows the model the contents of incoming files.
+	attachmentReader AttachmentReader
+
+	// toolHook checks or rewrites tool calls before they run.
+	toolHook ToolHook
+
+	// skillSelector picks the skills injected per message; nil injects all.
+	skillSelector SkillSelector
+
+	// channelNames lists enabled messaging channels for /capabilities.
+	channelNames []string
+
+	// toolFilter limits a persona to some tools; nil allows all.
+	toolFilter map[string]bool
+
+	// persona names a derived agent; empty for the default agent.
+	persona     string
+	description string
+
+	activity *activityHook
+
+	// replies holds each session's latest reply, for /pin and reactions.
+	replies *replyLog
+
+	// fallbacks are tried in order when the model is unavailable.
+	fallbacks []*llmTarget
+
+	// mu guards the settings and skills a config reload can change.
+	mu sync.RWMutex
+
+	// nativeTools is cleared when the model rejects tool definitions.
+	nativeTools atomic.Bool
 }
 
 // Config configures the agent.
@@ -31,6 +99,16 @@ type Config struct {
 	Temperature       float64
 	MaxTokens         int
 	SystemPrompt      string
+	PromptVars        map[string]string        // Custom system prompt template variables
+	ContextLength     int                      // Model context window in tokens; 0 if unknown
+	ToolCallMode      string                   // auto, native, or prompt (default: auto)
+	MaxToolIterations int                      // Model calls per message before giving up (default: 5)
+	MaxRepeatedCalls  int                      // Identical tool calls allowed per message (default: 2)
+	Channels          map[string]ChannelConfig // Per-channel overrides keyed by channel name
+	Experiment        *Experiment              // Optional A/B test of models or prompts
+	ContentFilter     ContentFilterConfig      // Reply to provider refusals
+	Retry             RetryPolicy              // Retries of transient model errors
+	Fallbacks         []FallbackModel          // Models tried in order when the model is unavailable
 	Logger            *slog.Logger
 	ObservabilityHook omnillm.ObservabilityHook
 }
@@ -40,15 +118,38 @@ func New(config Config) (*Agent, error) {
 	if config.Logger == nil {
 		config.Logger = slog.Default()
 	}
-
-	// Build provider configuration
-	providerConfig := omnillm.ProviderConfig{
-		Provider: omnillm.ProviderName(config.Provider),
-		APIKey:   config.APIKey,
+	if config.ToolCallMode == "" {
+		config.ToolCallMode = ToolCallModeAuto
+	}
+	if config.MaxToolIterations <= 0 {
+		config.MaxToolIterations = defaultMaxToolIterations
+	}
+	if config.MaxRepeatedCalls <= 0 {
+		config.MaxRepeatedCalls = defaultMaxRepeatedCalls
+	}
+	if config.Retry.Backoff <= 0 {
+		config.Retry.Backoff = defaultRetryBackoff
+	}
+	if config.Retry.MaxBackoff < config.Retry.Backoff {
+		config.Retry.MaxBackoff = max(defaultRetryMaxBackoff, config.Retry.Backoff)
+	}
+	if config.ContentFilter.Message == "" {
+		config.ContentFilter.Message = DefaultContentFilterMessage
+	}
+	if !validToolCallMode(config.ToolCallMode) {
+		return nil, fmt.Errorf("invalid tool call mode %q", config.ToolCallMode)
 	}
-	if config.BaseURL != "" {
-		providerConfig.BaseURL = config.BaseURL
+	if _, err := promptTemplate(config.SystemPrompt); err != nil {
+		return nil, err
 	}
+	if config.Experiment != nil {
+		if err := config.Experiment.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Build provider configuration
+	providerConfig := providerConfigFor(config)
 
 	// Create omnillm client
 	client, err := omnillm.NewClient(omnillm.ClientConfig{
@@ -59,29 +160,167 @@ func New(config Config) (*Agent, error) {
 	if err != nil {
 		return nil, fmt.Errorf("create llm client: %w", err)
 	}
+	fallbacks, err := newFallbacks(config)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Agent{
-		client: client,
-		tools:  NewToolRegistry(),
-		config: config,
-		logger: config.Logger,
-	}, nil
+	a := &Agent{
+		client:    client,
+		fallbacks: fallbacks,
+		tools:     NewToolRegistry(),
+		config:    config,
+		logger:    config.Logger,
+		commands:  newCommandRegistry(),
+		activity:  &activityHook{},
+		replies:   newReplyLog(),
+	}
+	a.RegisterCommand(Command{
+		Name:        "capabilities",
+		Description: "List enabled channels, tools, and commands",
+		Usage:       "/capabilities",
+		Handler:     a.capabilitiesCommand,
+	})
+	switch config.ToolCallMode {
+	case ToolCallModeNative:
+		a.nativeTools.Store(true)
+	case ToolCallModeAuto:
+		a.nativeTools.Store(SupportsNativeTools(config.Provider))
+	}
+	return a, nil
+}
+
+// Result is the outcome of processing a message.
+type Result struct {
+	Content          string
+	Model            string
+	Variant          string // Experiment variant; empty when no experiment runs
+	TraceID          string // Empty when tracing is disabled or for slash commands
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// runState tracks a single request through the tool-calling loop.
+type runState struct {
+	sessionID        string
+	settings         requestSettings
+	toolsCalled      []string
+	callCounts       map[string]int      // Identical calls, keyed by name and arguments
+	toolEntries      []transcripts.Entry // Recorded only when transcripts are enabled
+	promptTokens     int
+	completionTokens int
+	softened         bool // A filtered request was retried with a softened prompt
+
+	// basePrompt is the system prompt with persona and skills, before
+	// per-request context; fingerprinted in traces.
+	basePrompt string
 }
 
 // Process processes a message and returns a response.
 func (a *Agent) Process(ctx context.Context, sessionID, content string) (string, error) {
-	a.logger.Info("processing message", "model", a.config.Model, "provider", a.config.Provider)
+	res, err := a.ProcessWithResult(ctx, sessionID, content)
+	if err != nil {
+		return "", err
+	}
+	return res.Content, nil
+}
+
+// ProcessWithResult processes a message and returns the response along
+// with the trace ID used for feedback.
+func (a *Agent) ProcessWithResult(ctx context.Context, sessionID, content string) (*Result, error) {
+	// Slash commands are answered directly without calling the model
+	if reply, handled, err := a.handleCommand(ctx, sessionID, content); handled {
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Content: reply}, nil
+	}
+	return a.process(ctx, sessionID, content)
+}
+
+// process runs a message through the model, skipping slash commands.
+func (a *Agent) process(ctx context.Context, sessionID, content string) (*Result, error) {
+	a.emit(Activity{Kind: ActivityMessage, SessionID: sessionID, Content: content})
+	run := &runState{sessionID: sessionID, settings: a.settingsFor(sessionID)}
+	ctx, span := tracer.Start(ctx, "agent.process", trace.WithAttributes(
+		sessionAttr.String(sessionID),
+		modelKey.String(run.settings.model),
+	))
+	start := time.Now()
+	output, err := a.run(ctx, sessionID, content, run)
+	span.SetAttributes(
+		inputTokensKey.Int(run.promptTokens),
+		outputTokensKey.Int(run.completionTokens),
+		attribute.StringSlice("omniagent.tools", run.toolsCalled),
+	)
+	endSpan(span, err)
+
+	reply := Activity{Kind: ActivityReply, SessionID: sessionID, Content: output}
+	if err != nil {
+		reply.Error = err.Error()
+	}
+	a.emit(reply)
+
+	res := &Result{
+		Content:          output,
+		Model:            run.settings.model,
+		Variant:          run.settings.variant,
+		PromptTokens:     run.promptTokens,
+		CompletionTokens: run.completionTokens,
+	}
+	if a.traces != nil {
+		res.TraceID = a.recordTrace(ctx, sessionID, content, output, err, run, time.Since(start))
+	}
+	if a.transcripts != nil {
+		a.recordTranscript(ctx, sessionID, content, output, err, run, start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.replies.note(sessionID, output)
+	return res, nil
+}
+
+// run executes the model and tool-calling loop for one message.
+func (a *Agent) run(ctx context.Context, sessionID, content string, run *runState) (string, error) {
+	ctx = WithSession(ctx, sessionID)
+	settings := run.settings
+	a.logger.InfoContext(ctx, "processing message", "model", settings.model, "provider", a.config.Provider,
+		"channel", ChannelFromSession(sessionID))
 	messages := []provider.Message{
 		{
 			Role:    provider.RoleUser,
-			Content: content,
+			Content: attributed(ctx, a.readMessage(ctx, content).String()),
 		},
 	}
 
 	// Add system prompt with injected skills
-	systemPrompt := a.buildSystemPrompt()
+	systemPrompt := a.buildSystemPrompt(ctx, a.renderPrompt(ctx, sessionID, settings.systemPrompt), content)
+	run.basePrompt = systemPrompt
+	if a.prefs != nil {
+		if prefsPrompt := a.preferences(ctx, sessionID).Prompt(); prefsPrompt != "" {
+			systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + prefsPrompt)
+		}
+	}
+	if a.pins != nil {
+		if pinsPrompt := a.pins.Prompt(sessionID); pinsPrompt != "" {
+			systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + pinsPrompt)
+		}
+	}
+	if a.clock != nil {
+		systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + a.clock.Prompt(TimezoneKeys(ctx)...))
+	}
+	if _, ok := SpeakerFromContext(ctx); ok {
+		systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + groupPrompt)
+	}
+	if a.contextVars != nil {
+		vars := a.contextVars.update(sessionID, MetadataFromContext(ctx))
+		systemPrompt = a.contextVars.contextPrompt(systemPrompt, vars)
+		ctx = withContextVars(ctx, vars)
+	}
+	systemPrompt = a.retrievedPrompt(ctx, sessionID, content, systemPrompt)
 	if systemPrompt != "" {
-		a.logger.Info("using system prompt", "length", len(systemPrompt), "skills", len(a.skills))
+		a.logger.Info("using system prompt", "length", len(systemPrompt), "skills", len(a.loadedSkills()))
 		messages = append([]provider.Message{
 			{
 				Role:    provider.RoleSystem,
@@ -91,47 +330,115 @@ func (a *Agent) Process(ctx context.Context, sessionID, content string) (string,
 	}
 
 	// Add tools if available
-	tools := a.tools.GetTools()
+	tools := slices.DeleteFunc(a.tools.GetTools(), func(t provider.Tool) bool {
+		return !a.toolAllowed(ctx, t.Function.Name)
+	})
 	a.logger.Info("tools available for request", "count", len(tools))
 	for _, t := range tools {
 		paramsJSON, _ := json.Marshal(t.Function.Parameters)
 		a.logger.Info("tool in request", "name", t.Function.Name, "type", t.Type, "params", string(paramsJSON))
 	}
 
-	// Process with potential tool calls (max 5 iterations to prevent infinite loops)
-	for i := 0; i < 5; i++ {
+	// Process with potential tool calls, bounded to prevent infinite loops
+	emulating := false
+	maxIterations := a.maxToolIterations(ctx)
+	for i := 0; i < maxIterations; i++ {
+		// Describe tools in the prompt when the model can't take them natively
+		if len(tools) > 0 && !emulating && !a.nativeTools.Load() {
+			emulating = true
+			messages = withToolPrompt(messages, renderToolPrompt(tools))
+			a.logger.Info("emulating tool calls via prompt", "model", settings.model)
+		}
+
+		// Leave room for the reply when trimming to the context window
+		maxTokens := a.maxTokens(ctx)
+		reserve := maxTokens
+		if reserve == 0 {
+			reserve = a.config.ContextLength / 4
+		}
+		messages = fitContext(messages, a.config.ContextLength, reserve)
+
 		req := &provider.ChatCompletionRequest{
-			Model:    a.config.Model,
+			Model:    settings.model,
 			Messages: messages,
 		}
 
-		if a.config.Temperature > 0 {
-			req.Temperature = &a.config.Temperature
+		if settings.temperature > 0 {
+			req.Temperature = &settings.temperature
 		}
-		if a.config.MaxTokens > 0 {
-			req.MaxTokens = &a.config.MaxTokens
+		if maxTokens > 0 {
+			req.MaxTokens = &maxTokens
 		}
 
-		if len(tools) > 0 {
+		withTools := len(tools) > 0 && a.nativeTools.Load()
+		if withTools {
 			req.Tools = tools
 		}
 
-		resp, err := a.client.CreateChatCompletion(ctx, req)
+		resp, servedBy, err := a.complete(ctx, req)
+		if withTools && toolsUnsupported(err) && a.config.ToolCallMode == ToolCallModeAuto && IsLocalProvider(a.config.Provider) {
+			// Many local models lack function calling and the server rejects
+			// tool definitions; emulate tool calls for this agent from now on.
+			// Other errors, such as timeouts, leave native tools in place.
+			a.logger.Warn("model rejected tools, falling back to prompt tool calling",
+				"model", settings.model, "error", err)
+			a.nativeTools.Store(false)
+			emulating = true
+			req.Tools = nil
+			req.Messages = withToolPrompt(messages, renderToolPrompt(tools))
+			messages = req.Messages
+			resp, servedBy, err = a.complete(ctx, req)
+		}
+		if filter := filteredError(err); filter != nil {
+			if retry := a.handleContentFilter(ctx, run, messages, filter); retry != nil {
+				messages = retry
+				continue
+			}
+			return a.config.ContentFilter.Message, nil
+		}
 		if err != nil {
 			return "", fmt.Errorf("chat completion: %w", err)
 		}
 
+		run.promptTokens += resp.Usage.PromptTokens
+		run.completionTokens += resp.Usage.CompletionTokens
+		reportUsage(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+		if a.budget != nil {
+			a.budget.Charge(ChannelFromSession(sessionID), req.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+		}
+		a.recordUsage(sessionID, servedBy, req.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+
 		if len(resp.Choices) == 0 {
 			return "", fmt.Errorf("no response choices")
 		}
 
 		choice := resp.Choices[0]
 
-		a.logger.Info("LLM response",
+		a.logger.InfoContext(ctx, "LLM response",
 			"content_length", len(choice.Message.Content),
 			"tool_calls", len(choice.Message.ToolCalls),
 			"finish_reason", choice.FinishReason)
 
+		if filter := filteredChoice(choice); filter != nil {
+			if retry := a.handleContentFilter(ctx, run, messages, filter); retry != nil {
+				messages = retry
+				continue
+			}
+			return a.config.ContentFilter.Message, nil
+		}
+
+		if emulating {
+			calls := parseToolCalls(choice.Message.Content, tools)
+			if len(calls) == 0 {
+				return choice.Message.Content, nil
+			}
+			messages, err = a.executeEmulatedCalls(ctx, run, messages, choice.Message.Content, calls)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+
 		// Check if the model wants to call tools
 		if len(choice.Message.ToolCalls) == 0 {
 			// No tool calls, return the response
@@ -149,11 +456,14 @@ func (a *Agent) Process(ctx context.Context, sessionID, content string) (string,
 
 		// Execute each tool and add results
 		for _, toolCall := range choice.Message.ToolCalls {
-			a.logger.Info("calling tool", "name", toolCall.Function.Name)
+			a.logger.InfoContext(ctx, "calling tool", "name", toolCall.Function.Name)
 
-			result, err := a.tools.Execute(ctx, toolCall.Function.Name, []byte(toolCall.Function.Arguments))
+			result, err := a.callTool(ctx, run, toolCall.Function.Name, []byte(toolCall.Function.Arguments))
+			if loopErr := (*ToolLoopError)(nil); errors.As(err, &loopErr) {
+				return "", err
+			}
 			if err != nil {
-				a.logger.Error("tool execution failed", "name", toolCall.Function.Name, "error", err)
+				a.logger.ErrorContext(ctx, "tool execution failed", "name", toolCall.Function.Name, "error", err)
 				result = fmt.Sprintf("Error: %v", err)
 			}
 
@@ -167,7 +477,121 @@ func (a *Agent) Process(ctx context.Context, sessionID, content string) (string,
 		}
 	}
 
-	return "", fmt.Errorf("exceeded maximum tool call iterations")
+	return "", fmt.Errorf("exceeded maximum tool call iterations (%d)", maxIterations)
+}
+
+// executeEmulatedCalls runs tool calls parsed from model text and appends
+// the exchange to the conversation.
+func (a *Agent) executeEmulatedCalls(ctx context.Context, run *runState, messages []provider.Message, content string, calls []emulatedCall) ([]provider.Message, error) {
+	a.logger.Info("executing emulated tool calls", "count", len(calls))
+
+	messages = append(messages, provider.Message{
+		Role:    provider.RoleAssistant,
+		Content: content,
+	})
+
+	results := make([]string, 0, len(calls))
+	for _, call := range calls {
+		if call.Err != nil {
+			a.logger.WarnContext(ctx, "unparsable tool call", "name", call.Name, "error", call.Err)
+			results = append(results, formatToolResult(call.Name, fmt.Sprintf("Error: %v", call.Err)))
+			continue
+		}
+		a.logger.InfoContext(ctx, "calling tool", "name", call.Name, "emulated", true)
+
+		result, err := a.callTool(ctx, run, call.Name, call.Arguments)
+		if loopErr := (*ToolLoopError)(nil); errors.As(err, &loopErr) {
+			return nil, err
+		}
+		if err != nil {
+			a.logger.ErrorContext(ctx, "tool execution failed", "name", call.Name, "error", err)
+			result = fmt.Sprintf("Error: %v", err)
+		}
+		results = append(results, formatToolResult(call.Name, result))
+	}
+
+	return append(messages, provider.Message{
+		Role:    provider.RoleUser,
+		Content: strings.Join(results, "\n\n"),
+	}), nil
+}
+
+// callTool executes a tool requested by the model and records the call.
+func (a *Agent) callTool(ctx context.Context, run *runState, name string, args json.RawMessage) (result string, err error) {
+	// Gate and run ID-form calls ("builtin/shell") under the name the
+	// model sees, which is what filters, flags, and approvals name
+	exposed, visible := a.tools.Resolve(name)
+	if visible {
+		name = exposed
+	}
+
+	ctx, span := tracer.Start(ctx, "tool.execute", trace.WithAttributes(toolKey.String(name)))
+	defer func() { endSpan(span, err) }()
+
+	// Executed calls are audited by the registry
+	started := time.Now()
+	executed := false
+	defer func() {
+		if !executed {
+			a.recordDenied(ctx, name, args, err, started)
+		}
+	}()
+
+	run.toolsCalled = append(run.toolsCalled, name)
+	if !visible {
+		return "", &ToolNotFoundError{Name: name}
+	}
+	if !a.toolAllowed(ctx, name) {
+		return "", fmt.Errorf("tool %q is not allowed for this request", name)
+	}
+	if err := run.countCall(name, args, a.config.MaxRepeatedCalls); err != nil {
+		a.logger.Warn("stopping repeated tool call", "name", name, "session", run.sessionID)
+		return "", err
+	}
+	if a.toolHook != nil {
+		hooked, ok := a.toolHook(ctx, name, args)
+		if !ok {
+			return "", fmt.Errorf("tool %q was blocked by a hook", name)
+		}
+		args = hooked
+	}
+	if a.approvals != nil && a.approvals.Requires(name) {
+		if err := a.awaitApproval(ctx, run.sessionID, name, args); err != nil {
+			return "", err
+		}
+	}
+	executed = true
+	result, err = a.tools.Execute(withToolName(ctx, name), name, args)
+
+	act := Activity{Kind: ActivityToolCall, SessionID: run.sessionID, Tool: name, Args: validJSON(args)}
+	if err != nil {
+		act.Error = err.Error()
+	} else {
+		act.Content = truncate(result, maxTranscriptToolResult)
+	}
+	a.emit(act)
+
+	if a.transcripts != nil {
+		run.toolEntries = append(run.toolEntries, transcripts.Entry{
+			Time:    started,
+			Role:    transcripts.RoleTool,
+			Tool:    name,
+			Args:    act.Args,
+			Content: act.Content,
+			Error:   act.Error,
+		})
+	}
+	return result, err
+}
+
+// withToolPrompt appends tool instructions to the system message, adding
+// one if the conversation has none.
+func withToolPrompt(messages []provider.Message, toolPrompt string) []provider.Message {
+	if len(messages) > 0 && messages[0].Role == provider.RoleSystem {
+		messages[0].Content = messages[0].Content + "\n\n" + toolPrompt
+		return messages
+	}
+	return append([]provider.Message{{Role: provider.RoleSystem, Content: toolPrompt}}, messages...)
 }
 
 // ProcessWithMemory processes a message using conversation memory.
@@ -176,14 +600,79 @@ func (a *Agent) ProcessWithMemory(ctx context.Context, sessionID, content string
 	return a.Process(ctx, sessionID, content)
 }
 
-// RegisterTool registers a tool with the agent.
-func (a *Agent) RegisterTool(tool Tool) {
-	a.tools.Register(tool)
+// ContextLength returns the model's context window in tokens, or 0 if unknown.
+func (a *Agent) ContextLength() int {
+	return a.config.ContextLength
+}
+
+// Model returns the configured model.
+func (a *Agent) Model() string {
+	return a.config.Model
+}
+
+// ExecuteTool runs a registered tool directly, bypassing the model.
+func (a *Agent) ExecuteTool(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	return a.tools.Execute(ctx, name, args)
+}
+
+// RegisterTool registers a built-in tool with the agent.
+func (a *Agent) RegisterTool(tool Tool) error {
+	return a.tools.Register(tool)
+}
+
+// RegisterToolFrom registers a tool from source, such as "mcp:github".
+func (a *Agent) RegisterToolFrom(source string, tool Tool) error {
+	return a.tools.RegisterFrom(source, tool)
+}
+
+// SetToolPrecedence orders tool sources for resolving name collisions,
+// highest first. Call it before registering tools.
+func (a *Agent) SetToolPrecedence(sources []string) {
+	a.tools.SetPrecedence(sources)
+}
+
+// AliasTool exposes the tool with the given ID (source/name) under alias.
+func (a *Agent) AliasTool(alias, id string) error {
+	return a.tools.Alias(alias, id)
+}
+
+// SetToolMock routes every tool call through m instead of running tools
+// directly, so tests can replay canned results.
+func (a *Agent) SetToolMock(m ToolMock) {
+	a.tools.SetMock(m)
+}
+
+// ToolHook runs before each tool call. It returns the arguments to call the
+// tool with, or false to block the call.
+type ToolHook func(ctx context.Context, tool string, args json.RawMessage) (json.RawMessage, bool)
+
+// SetToolHook runs h before each tool call.
+func (a *Agent) SetToolHook(h ToolHook) {
+	a.toolHook = h
+}
+
+// Tool returns a registered tool by name or ID.
+func (a *Agent) Tool(name string) (Tool, bool) {
+	return a.tools.Get(name)
+}
+
+// ToolIDs returns the namespaced IDs of all registered tools.
+func (a *Agent) ToolIDs() []string {
+	return a.tools.IDs()
+}
+
+// UnregisterTool removes a tool from the agent by name or ID.
+func (a *Agent) UnregisterTool(name string) {
+	a.tools.Unregister(name)
 }
 
 // Close closes the agent and releases resources.
 func (a *Agent) Close() error {
-	return a.client.Close()
+	err := a.client.Close()
+	for _, fb := range a.fallbacks {
+		err = errors.Join(err, fb.client.Close())
+	}
+	return err
 }
 
 // LoadSkills loads skills from the given directories.
@@ -209,21 +698,47 @@ func (a *Agent) LoadSkills(dirs []string) error {
 		}
 	}
 
+	a.mu.Lock()
 	a.skills = available
+	a.mu.Unlock()
 	a.logger.Info("skills loaded", "total", len(discovered), "available", len(available))
 	return nil
 }
 
 // GetSkills returns the loaded skills.
 func (a *Agent) GetSkills() []*skills.Skill {
-	return a.skills
+	return a.loadedSkills()
+}
+
+// SkillSelector picks the skills relevant to a message.
+type SkillSelector interface {
+	Select(ctx context.Context, skills []*skills.Skill, content string) ([]*skills.Skill, error)
+}
+
+// SetSkillSelector injects only the skills s selects for each message,
+// instead of all loaded skills.
+func (a *Agent) SetSkillSelector(s SkillSelector) {
+	a.skillSelector = s
 }
 
-// buildSystemPrompt builds the system prompt with injected skills.
-func (a *Agent) buildSystemPrompt() string {
-	if len(a.skills) == 0 {
-		return a.config.SystemPrompt
+// buildSystemPrompt builds the system prompt with injected skills. With a
+// skill selector, only the skills relevant to content are injected; if
+// selection fails, all are.
+func (a *Agent) buildSystemPrompt(ctx context.Context, base, content string) string {
+	loaded := a.loadedSkills()
+	if len(loaded) == 0 {
+		return base
+	}
+	if a.skillSelector != nil {
+		selected, err := a.skillSelector.Select(ctx, loaded, content)
+		if err == nil {
+			if len(selected) == 0 {
+				return base
+			}
+			return skills.InjectIntoPrompt(base, selected, skills.DefaultInjectConfig())
+		}
+		a.logger.WarnContext(ctx, "skill selection failed, injecting all skills", "error", err)
 	}
 
-	return skills.InjectIntoPrompt(a.config.SystemPrompt, a.skills, skills.DefaultInjectConfig())
+	return skills.InjectIntoPrompt(base, loaded, skills.DefaultInjectConfig())
 }
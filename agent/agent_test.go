@@ -0,0 +1,396 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omnillm/provider"
+
+	"github.com/plexusone/omniagent/approvals"
+	"github.com/plexusone/omniagent/audit"
+	"github.com/plexusone/omniagent/flags"
+)
+
+// echoTool returns its arguments.
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Echo the arguments" }
+func (echoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (echoTool) Execute(_ context.Context, args json.RawMessage) (string, error) {
+	return string(args), nil
+}
+
+// fakeServer is an OpenAI-compatible chat completions server. It answers
+// requests carrying tools with toolsStatus and toolsError, and all other
+// requests with replies in turn, repeating the last.
+type fakeServer struct {
+	toolsStatus int
+	toolsError  string
+	replies     []string
+
+	mu       sync.Mutex
+	requests []map[string]any
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req map[string]any
+	_ = json.Unmarshal(body, &req)
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	reply := ""
+	if len(f.replies) > 0 {
+		reply = f.replies[0]
+		if len(f.replies) > 1 {
+			f.replies = f.replies[1:]
+		}
+	}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, ok := req["tools"]; ok && f.toolsStatus != 0 {
+		w.WriteHeader(f.toolsStatus)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]any{"message": f.toolsError, "type": "invalid_request_error"},
+		})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":      "chatcmpl-1",
+		"object":  "chat.completion",
+		"created": 1,
+		"model":   req["model"],
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": reply},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]any{"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2},
+	})
+}
+
+func (f *fakeServer) sentTools() []bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sent []bool
+	for _, req := range f.requests {
+		_, ok := req["tools"]
+		sent = append(sent, ok)
+	}
+	return sent
+}
+
+// messages returns the messages of the nth request.
+func (f *fakeServer) messages(n int) []map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var msgs []map[string]any
+	list, _ := f.requests[n]["messages"].([]any)
+	for _, m := range list {
+		if m, ok := m.(map[string]any); ok {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+func newTestAgent(t *testing.T, f *fakeServer, mode ...string) *Agent {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	config := Config{
+		Provider: ProviderOpenAICompatible,
+		Model:    "test-model",
+		BaseURL:  srv.URL + "/v1",
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	if len(mode) > 0 {
+		config.ToolCallMode = mode[0]
+	}
+	a, err := New(config)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := a.RegisterTool(echoTool{}); err != nil {
+		t.Fatalf("RegisterTool: %v", err)
+	}
+	return a
+}
+
+func TestToolsUnsupportedFallback(t *testing.T) {
+	f := &fakeServer{
+		toolsStatus: http.StatusBadRequest,
+		toolsError:  `"auto" tool choice requires --enable-auto-tool-choice and --tool-call-parser to be set`,
+		replies:     []string{"hello"},
+	}
+	a := newTestAgent(t, f)
+
+	for range 2 {
+		reply, err := a.Process(context.Background(), "test:1", "hi")
+		if err != nil {
+			t.Fatalf("Process: %v", err)
+		}
+		if reply != "hello" {
+			t.Errorf("reply = %q, want hello", reply)
+		}
+	}
+	// Tools are sent once, then emulated for the rest of the agent's life
+	want := []bool{true, false, false}
+	if got := f.sentTools(); !slices.Equal(got, want) {
+		t.Errorf("requests with tools = %v, want %v", got, want)
+	}
+	if a.nativeTools.Load() {
+		t.Error("native tools still enabled after the server rejected them")
+	}
+}
+
+func TestOtherErrorsKeepNativeTools(t *testing.T) {
+	f := &fakeServer{
+		toolsStatus: http.StatusInternalServerError,
+		toolsError:  "internal server error",
+		replies:     []string{"hello"},
+	}
+	a := newTestAgent(t, f)
+
+	if _, err := a.Process(context.Background(), "test:1", "hi"); err == nil {
+		t.Fatal("Process succeeded despite a server error")
+	}
+	if got := f.sentTools(); !slices.Equal(got, []bool{true}) {
+		t.Errorf("requests with tools = %v, want a single native attempt", got)
+	}
+	if !a.nativeTools.Load() {
+		t.Error("native tools disabled by an unrelated error")
+	}
+}
+
+func TestToolsUnsupported(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New(`registry.ollama.ai/library/gemma:2b does not support tools`), true},
+		{errors.New(`"auto" tool choice requires --enable-auto-tool-choice and --tool-call-parser to be set`), true},
+		{errors.New(`tools param requires --jinja flag`), true},
+		{errors.New(`function calling is not enabled for this model`), true},
+		{errors.New(`dial tcp 127.0.0.1:8080: connect: connection refused`), false},
+		{errors.New(`context deadline exceeded (Client.Timeout exceeded while awaiting headers)`), false},
+		{errors.New(`500 internal server error`), false},
+		{errors.New(`model does not support vision`), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := toolsUnsupported(tt.err); got != tt.want {
+			t.Errorf("toolsUnsupported(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFitContext(t *testing.T) {
+	output := strings.Repeat("x", 4000)
+	messages := []provider.Message{
+		{Role: provider.RoleSystem, Content: "You are helpful."},
+		{Role: provider.RoleUser, Content: "read the files"},
+		{Role: provider.RoleTool, Content: output},
+		{Role: provider.RoleUser, Content: formatToolResult("read", output)},
+		{Role: provider.RoleAssistant, Content: "done"},
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		got := fitContext(messages, 10000, 1000)
+		if got[2].Content != output || got[3].Content != formatToolResult("read", output) {
+			t.Error("trimmed messages that already fit")
+		}
+	})
+
+	t.Run("unknown context length", func(t *testing.T) {
+		got := fitContext(messages, 0, 1000)
+		if got[2].Content != output {
+			t.Error("trimmed without a context length")
+		}
+	})
+
+	t.Run("trims oldest tool results first", func(t *testing.T) {
+		got := fitContext(messages, 1800, 300)
+		if n := messageTokens(got) + 300; n > 1800 {
+			t.Errorf("estimated %d tokens, want at most 1800", n)
+		}
+		if !strings.HasSuffix(got[2].Content, truncatedMarker) {
+			t.Errorf("oldest tool result not truncated: %d bytes", len(got[2].Content))
+		}
+		if got[3].Content != formatToolResult("read", output) {
+			t.Error("newer tool result trimmed while the oldest was enough")
+		}
+		if got[0].Content != messages[0].Content || got[1].Content != messages[1].Content || got[4].Content != "done" {
+			t.Error("trimmed messages other than tool results")
+		}
+		if messages[2].Content != output {
+			t.Error("fitContext modified its input")
+		}
+	})
+
+	t.Run("trims every tool result when needed", func(t *testing.T) {
+		got := fitContext(messages, 50, 0)
+		if got[2].Content != truncatedMarker {
+			t.Errorf("native tool result = %.40q, want the marker", got[2].Content)
+		}
+		if want := formatToolResult("read", truncatedMarker); got[3].Content != want {
+			t.Errorf("emulated tool result = %.40q, want %q", got[3].Content, want)
+		}
+	})
+}
+
+func TestEmulatedToolLoop(t *testing.T) {
+	f := &fakeServer{replies: []string{
+		"TOOL: echo\nARGS: {\"text\": \"ping\"}",
+		"The tool said ping.",
+	}}
+	a := newTestAgent(t, f, ToolCallModePrompt)
+
+	reply, err := a.Process(context.Background(), "test:1", "call echo")
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if reply != "The tool said ping." {
+		t.Errorf("reply = %q", reply)
+	}
+	if got := f.sentTools(); !slices.Equal(got, []bool{false, false}) {
+		t.Fatalf("requests with tools = %v, want two prompt-only requests", got)
+	}
+
+	first := f.messages(0)
+	if len(first) == 0 || first[0]["role"] != "system" || !strings.Contains(first[0]["content"].(string), "### echo") {
+		t.Errorf("first request does not describe the tools in a system message: %v", first)
+	}
+	second := f.messages(1)
+	last := second[len(second)-1]
+	if last["role"] != "user" || last["content"] != formatToolResult("echo", `{"text": "ping"}`) {
+		t.Errorf("last message of the second request = %v, want the tool result", last)
+	}
+}
+
+func TestEmulatedBadArgs(t *testing.T) {
+	f := &fakeServer{replies: []string{
+		"TOOL: echo\nARGS: {\"text\": ping}",
+		"Sorry.",
+	}}
+	a := newTestAgent(t, f, ToolCallModePrompt)
+
+	if _, err := a.Process(context.Background(), "test:1", "call echo"); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	second := f.messages(1)
+	result, _ := second[len(second)-1]["content"].(string)
+	if !strings.Contains(result, "Error: invalid ARGS for echo") || !strings.Contains(result, "ping") {
+		t.Errorf("tool result = %q, want the ARGS error instead of calling echo with {}", result)
+	}
+}
+
+func TestToolIDCallsAreGated(t *testing.T) {
+	ctx := context.Background()
+	run := &runState{sessionID: "test:1"}
+
+	t.Run("approval", func(t *testing.T) {
+		a := newTestAgent(t, &fakeServer{})
+		m, err := approvals.New(approvals.Config{Tools: []string{"echo"}, Timeout: 10 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.SetApprovals(m)
+		for _, name := range []string{"echo", "builtin/echo"} {
+			if _, err := a.callTool(ctx, run, name, json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "needs approval") {
+				t.Errorf("callTool(%s) error = %v, want approval required", name, err)
+			}
+		}
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		a := newTestAgent(t, &fakeServer{})
+		f, err := flags.New(map[string]bool{flags.Tool("echo"): false}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.SetFlags(f)
+		for _, name := range []string{"echo", "builtin/echo"} {
+			if _, err := a.callTool(ctx, run, name, json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "not allowed") {
+				t.Errorf("callTool(%s) error = %v, want the disabled flag to block it", name, err)
+			}
+		}
+	})
+
+	t.Run("shadowed", func(t *testing.T) {
+		a := newTestAgent(t, &fakeServer{})
+		a.SetToolPrecedence([]string{SourceBuiltin})
+		if err := a.RegisterToolFrom("mcp:x", echoTool{}); err != nil {
+			t.Fatal(err)
+		}
+		var notFound *ToolNotFoundError
+		if _, err := a.callTool(ctx, run, "mcp:x/echo", json.RawMessage(`{}`)); !errors.As(err, &notFound) {
+			t.Errorf("callTool(shadowed ID) error = %v, want not found", err)
+		}
+		if err := a.AliasTool("echo2", "mcp:x/echo"); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := a.tools.Resolve("mcp:x/echo"); !ok || got != "echo2" {
+			t.Errorf("Resolve(aliased ID) = %q, %v; want echo2", got, ok)
+		}
+	})
+}
+
+func TestAuditRecordsEveryPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	a := newTestAgent(t, &fakeServer{})
+	a.SetAuditLog(log)
+	ctx := WithSession(context.Background(), "test:1")
+
+	// Scheduled tool tasks run with ExecuteTool, outside the agent loop
+	if _, err := a.ExecuteTool(ctx, "echo", json.RawMessage(`{"x":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	// Agent loop calls are recorded once, not by both callTool and the registry
+	if _, err := a.callTool(ctx, &runState{sessionID: "test:1"}, "echo", json.RawMessage(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := flags.New(map[string]bool{flags.Tool("echo"): false}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetFlags(f)
+	_, _ = a.callTool(ctx, &runState{sessionID: "test:1"}, "echo", json.RawMessage(`{}`))
+
+	entries, err := audit.Query(path, audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		if e.Tool != "echo" || e.SessionID != "test:1" {
+			t.Errorf("entry = %+v, want echo in test:1", e)
+		}
+		got = append(got, e.Status)
+	}
+	want := []string{audit.StatusOK, audit.StatusOK, audit.StatusDenied}
+	if !slices.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+}
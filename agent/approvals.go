@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/plexusone/omniagent/approvals"
+)
+
+// SetApprovals pauses calls to tools that need approval until an approver
+// decides, and registers /approve and /deny for approvers.
+func (a *Agent) SetApprovals(m *approvals.Manager) {
+	a.approvals = m
+	a.RegisterCommand(Command{
+		Name:        "approve",
+		Description: "Approve a pending tool call (approvers only)",
+		Usage:       "/approve [id]",
+		Handler:     a.decideCommand(true),
+	})
+	a.RegisterCommand(Command{
+		Name:        "deny",
+		Description: "Deny a pending tool call (approvers only)",
+		Usage:       "/deny [id]",
+		Handler:     a.decideCommand(false),
+	})
+}
+
+// awaitApproval blocks until a tool call is approved. It returns an error
+// explaining the refusal otherwise, which the model relays to the user.
+func (a *Agent) awaitApproval(ctx context.Context, sessionID, name string, args json.RawMessage) error {
+	a.logger.Info("waiting for tool approval", "name", name, "session", sessionID)
+	d, err := a.approvals.Await(ctx, approvals.Request{
+		Tool:      name,
+		Args:      validJSON(args),
+		SessionID: sessionID,
+		Persona:   a.persona,
+	})
+	switch {
+	case err != nil:
+		return fmt.Errorf("tool %q needs approval: %w", name, err)
+	case d.Approved:
+		return nil
+	case d.Approver == "":
+		return fmt.Errorf("tool %q needs approval and no approver responded in time", name)
+	default:
+		return fmt.Errorf("tool %q was denied by an approver", name)
+	}
+}
+
+// decideCommand handles /approve and /deny. Without an ID it lists the
+// pending requests.
+func (a *Agent) decideCommand(approve bool) CommandHandler {
+	return func(ctx context.Context, _, args string) (string, error) {
+		approver := ContactFromContext(ctx)
+		if !a.approvals.CanApprove(approver) {
+			return "Only approvers can decide tool calls.", nil
+		}
+
+		id := strings.TrimSpace(args)
+		if id == "" {
+			pending := a.approvals.Pending()
+			if len(pending) == 0 {
+				return "No tool calls are waiting for approval.", nil
+			}
+			var sb strings.Builder
+			sb.WriteString("Waiting for approval:\n")
+			for _, req := range pending {
+				fmt.Fprintf(&sb, "- %s: %s %s\n", req.ID, req.Tool, truncate(string(req.Args), 200))
+			}
+			return strings.TrimSpace(sb.String()), nil
+		}
+
+		req, err := a.approvals.Resolve(id, approve, approver)
+		if errors.Is(err, approvals.ErrNotFound) {
+			return fmt.Sprintf("No pending tool call with ID %s.", id), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if approve {
+			return fmt.Sprintf("Approved %s (%s).", req.Tool, req.ID), nil
+		}
+		return fmt.Sprintf("Denied %s (%s).", req.Tool, req.ID), nil
+	}
+}
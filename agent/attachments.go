@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Attachment is a file exchanged between a channel and tools.
+type Attachment struct {
+	Filename string
+	MimeType string
+	Data     []byte
+}
+
+// Attachments carries files for one message: those the user sent, and
+// those tools attach to the reply.
+type Attachments struct {
+	Incoming []Attachment
+
+	outgoing []Attachment
+	mu       sync.Mutex
+}
+
+// Attach adds a file to the reply.
+func (a *Attachments) Attach(att Attachment) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.outgoing = append(a.outgoing, att)
+}
+
+// Outgoing returns the files attached to the reply.
+func (a *Attachments) Outgoing() []Attachment {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Attachment(nil), a.outgoing...)
+}
+
+// WithAttachments returns a context carrying a message's attachments.
+// Channels that can deliver files set it; tools check for it before
+// attaching.
+func WithAttachments(ctx context.Context, a *Attachments) context.Context {
+	return context.WithValue(ctx, attachmentsKey, a)
+}
+
+// AttachmentsFromContext returns the message's attachments, or nil if the
+// channel cannot exchange files.
+func AttachmentsFromContext(ctx context.Context) *Attachments {
+	a, _ := ctx.Value(attachmentsKey).(*Attachments)
+	return a
+}
+
+// AttachmentReader turns a file the user sent into text the model can
+// read: a document's text, a description of an image, or a transcript.
+type AttachmentReader interface {
+	Read(ctx context.Context, att Attachment) (string, error)
+}
+
+// SetAttachmentReader shows the model the contents of the files sent with
+// each message. Without one, files are only available to tools.
+func (a *Agent) SetAttachmentReader(r AttachmentReader) {
+	a.attachmentReader = r
+}
+
+// Message is a user message as the model receives it: the text typed and
+// what was read from each file sent with it.
+type Message struct {
+	Text  string
+	Files []File
+}
+
+// File is an attachment and what was read from it.
+type File struct {
+	Attachment
+	Text string
+	Err  error // Why the file could not be read
+}
+
+// String renders the message for the model, with each file's contents
+// after the text.
+func (m Message) String() string {
+	var b strings.Builder
+	b.WriteString(m.Text)
+	for _, f := range m.Files {
+		name := cmp.Or(f.Filename, "unnamed file")
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		switch {
+		case f.Err != nil:
+			fmt.Fprintf(&b, "[Attached %s (%s); it could not be read: %v]", name, f.MimeType, f.Err)
+		case strings.HasPrefix(f.MimeType, "image/"):
+			fmt.Fprintf(&b, "[Attached image %s, described: %s]", name, f.Text)
+		default:
+			fmt.Fprintf(&b, "[Attached %s (%s)]\n%s\n[End of %s]", name, f.MimeType, f.Text, name)
+		}
+	}
+	return b.String()
+}
+
+// readMessage builds the model's view of a message from its text and the
+// incoming attachments in ctx.
+func (a *Agent) readMessage(ctx context.Context, content string) Message {
+	msg := Message{Text: content}
+	atts := AttachmentsFromContext(ctx)
+	if a.attachmentReader == nil || atts == nil {
+		return msg
+	}
+	for _, att := range atts.Incoming {
+		text, err := a.attachmentReader.Read(ctx, att)
+		if err != nil {
+			a.logger.Warn("failed to read attachment", "file", att.Filename, "type", att.MimeType, "error", err)
+		}
+		msg.Files = append(msg.Files, File{Attachment: att, Text: text, Err: err})
+	}
+	return msg
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/plexusone/omniagent/audit"
+)
+
+// SetAuditLog records every tool execution, including denied ones, in log.
+// Executions are recorded by the tool registry, so tools run directly with
+// ExecuteTool are recorded too.
+func (a *Agent) SetAuditLog(log *audit.Log) {
+	a.audit = log
+	a.tools.SetAuditor(AuditTo(log, a.persona, a.logger))
+}
+
+// AuditTo returns a ToolAuditFunc recording executions in log, for
+// registries used without an agent, such as the MCP server's.
+func AuditTo(log *audit.Log, persona string, logger *slog.Logger) ToolAuditFunc {
+	return func(ctx context.Context, name string, args json.RawMessage, result string, err error, started time.Time) {
+		status := audit.StatusOK
+		if err != nil {
+			status = audit.StatusError
+		}
+		recordAudit(ctx, log, persona, logger, name, args, result, status, err, started)
+	}
+}
+
+// recordDenied appends a tool call that was not executed to the audit
+// log, if any.
+func (a *Agent) recordDenied(ctx context.Context, name string, args json.RawMessage, err error, started time.Time) {
+	if a.audit == nil {
+		return
+	}
+	recordAudit(ctx, a.audit, a.persona, a.logger, name, args, "", audit.StatusDenied, err, started)
+}
+
+// recordAudit appends a tool call to log. The session comes from ctx.
+func recordAudit(ctx context.Context, log *audit.Log, persona string, logger *slog.Logger, name string, args json.RawMessage, result, status string, err error, started time.Time) {
+	sessionID := SessionFromContext(ctx)
+	e := audit.Entry{
+		Time:        started,
+		SessionID:   sessionID,
+		Channel:     ChannelFromSession(sessionID),
+		Contact:     ContactFromContext(ctx),
+		Person:      PersonFromContext(ctx),
+		Persona:     persona,
+		Tool:        name,
+		Args:        validJSON(args),
+		ResultBytes: len(result),
+		DurationMS:  time.Since(started).Milliseconds(),
+		Status:      status,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if err := log.Record(e); err != nil {
+		logger.Error("failed to record tool audit entry", "tool", name, "error", err)
+	}
+}
@@ -0,0 +1,9 @@
+package agent
+
+import "github.com/plexusone/omniagent/budget"
+
+// SetBudget charges model usage against budget caps and downgrades the
+// model as caps are approached.
+func (a *Agent) SetBudget(b *budget.Tracker) {
+	a.budget = b
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SetChannelNames records the messaging channels the agent is reachable
+// on, for /capabilities.
+func (a *Agent) SetChannelNames(names []string) {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	a.channelNames = sorted
+}
+
+// Capabilities describes the channels, tools, and commands currently
+// enabled, so users see what this deployment can actually do.
+func (a *Agent) Capabilities() string {
+	var sb strings.Builder
+
+	if len(a.channelNames) > 0 {
+		fmt.Fprintf(&sb, "Channels: %s\n\n", strings.Join(a.channelNames, ", "))
+	}
+
+	toolNames := a.tools.List()
+	sort.Strings(toolNames)
+	if len(toolNames) > 0 {
+		sb.WriteString("Tools:\n")
+		for _, name := range toolNames {
+			tool, ok := a.tools.Get(name)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&sb, "- %s: %s\n", name, firstSentence(tool.Description()))
+		}
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString("Tools: none (conversation only)\n\n")
+	}
+
+	if loaded := a.loadedSkills(); len(loaded) > 0 {
+		names := make([]string, 0, len(loaded))
+		for _, sk := range loaded {
+			names = append(names, sk.Name)
+		}
+		fmt.Fprintf(&sb, "Skills: %s\n\n", strings.Join(names, ", "))
+	}
+
+	sb.WriteString("Commands:\n")
+	for _, cmd := range a.Commands() {
+		fmt.Fprintf(&sb, "- /%s: %s\n", cmd.Name, cmd.Description)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// capabilitiesCommand implements /capabilities.
+func (a *Agent) capabilitiesCommand(_ context.Context, _, _ string) (string, error) {
+	return a.Capabilities(), nil
+}
+
+// firstSentence shortens a tool description for listings.
+func firstSentence(s string) string {
+	if i := strings.Index(s, ". "); i >= 0 {
+		return s[:i+1]
+	}
+	return s
+}
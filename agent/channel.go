@@ -0,0 +1,73 @@
+package agent
+
+import "strings"
+
+// ChannelConfig overrides agent settings for messages from one channel.
+// Empty fields fall back to the agent configuration.
+type ChannelConfig struct {
+	Model        string
+	SystemPrompt string
+	Temperature  *float64
+}
+
+// requestSettings are the model settings resolved for a single request.
+type requestSettings struct {
+	model        string
+	systemPrompt string
+	temperature  float64
+	variant      string // Experiment variant; empty when no experiment runs
+}
+
+// ChannelFromSession returns the channel name from a router session ID of
+// the form "channel:chatID". It returns "" for other session IDs.
+func ChannelFromSession(sessionID string) string {
+	channel, _, ok := strings.Cut(sessionID, ":")
+	if !ok {
+		return ""
+	}
+	return channel
+}
+
+// settingsFor resolves model settings for the channel a session belongs to,
+// the experiment variant it is assigned to, and the budget.
+func (a *Agent) settingsFor(sessionID string) requestSettings {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	settings := requestSettings{
+		model:        a.config.Model,
+		systemPrompt: a.config.SystemPrompt,
+		temperature:  a.config.Temperature,
+	}
+
+	if override, ok := a.config.Channels[ChannelFromSession(sessionID)]; ok {
+		if override.Model != "" {
+			settings.model = override.Model
+		}
+		if override.SystemPrompt != "" {
+			settings.systemPrompt = override.SystemPrompt
+		}
+		if override.Temperature != nil {
+			settings.temperature = *override.Temperature
+		}
+	}
+
+	// Experiment variants take precedence so every channel is measured
+	if exp := a.config.Experiment; exp != nil {
+		settings.variant = ControlVariant
+		if v := exp.assign(sessionID); v != nil {
+			settings.variant = v.Name
+			if v.Model != "" {
+				settings.model = v.Model
+			}
+			if v.SystemPrompt != "" {
+				settings.systemPrompt = v.SystemPrompt
+			}
+		}
+	}
+
+	// A nearly spent budget overrides everything with the cheaper model
+	if a.budget != nil {
+		settings.model = a.budget.Model(ChannelFromSession(sessionID), settings.model)
+	}
+	return settings
+}
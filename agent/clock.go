@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/clock"
+)
+
+// SetClock enables current-time prompt injection and the /timezone command.
+func (a *Agent) SetClock(r *clock.Resolver) {
+	a.clock = r
+	a.RegisterCommand(Command{
+		Name:        "timezone",
+		Description: "Show or set your timezone",
+		Usage:       "/timezone [<IANA name> | reset]",
+		Handler:     a.timezoneCommand,
+	})
+}
+
+// Clock returns the timezone resolver, or nil if none is set.
+func (a *Agent) Clock() *clock.Resolver {
+	return a.clock
+}
+
+// TimezoneKeys returns the keys identifying whose timezone applies to the
+// request in ctx: the sender's contact ID if known, then the session ID.
+func TimezoneKeys(ctx context.Context) []string {
+	var keys []string
+	if contact := ContactFromContext(ctx); contact != "" {
+		keys = append(keys, contact)
+	}
+	if session := SessionFromContext(ctx); session != "" {
+		keys = append(keys, session)
+	}
+	return keys
+}
+
+// timezoneCommand implements /timezone.
+func (a *Agent) timezoneCommand(ctx context.Context, sessionID, args string) (string, error) {
+	ctx = WithSession(ctx, sessionID)
+	keys := TimezoneKeys(ctx)
+
+	if args == "" {
+		loc := a.clock.Location(keys...)
+		return "Your timezone is " + loc.String() + ". It is " +
+			a.clock.Now(loc).Format("Mon 2 Jan 15:04") + ".\n\nChange it with /timezone <name>, e.g. /timezone Europe/Paris.", nil
+	}
+
+	name := strings.TrimSpace(args)
+	if strings.EqualFold(name, "reset") {
+		if _, err := a.clock.SetLocation(keys[0], ""); err != nil {
+			return "", err
+		}
+		return "Timezone reset to " + a.clock.Location(keys...).String() + ".", nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Sprintf("Unknown timezone %q. Use an IANA name like Europe/Paris.", name), nil
+	}
+
+	loc, err := a.clock.SetLocation(keys[0], name)
+	if err != nil {
+		return "", err
+	}
+	return "Timezone set to " + loc.String() + ".", nil
+}
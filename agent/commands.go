@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CommandHandler handles a slash command. args is the text after the
+// command name with surrounding whitespace removed.
+type CommandHandler func(ctx context.Context, sessionID, args string) (string, error)
+
+// Command is a slash command available on every channel (e.g., "/prefs").
+type Command struct {
+	Name        string // Without the leading slash
+	Description string
+	Usage       string
+	Handler     CommandHandler
+}
+
+// commandRegistry holds the slash commands known to an agent.
+type commandRegistry struct {
+	commands map[string]Command
+	mu       sync.RWMutex
+}
+
+func newCommandRegistry() *commandRegistry {
+	return &commandRegistry{commands: make(map[string]Command)}
+}
+
+func (r *commandRegistry) register(cmd Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands[cmd.Name] = cmd
+}
+
+func (r *commandRegistry) get(name string) (Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+func (r *commandRegistry) list() []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmds := make([]Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return cmds
+}
+
+// parseCommand splits "/name args" into its parts. ok is false if content
+// is not a slash command.
+func parseCommand(content string) (name, args string, ok bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "/") || len(content) < 2 {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(content[1:], " ")
+	// Telegram appends the bot name in groups: /prefs@my_bot
+	name, _, _ = strings.Cut(name, "@")
+	return strings.ToLower(name), strings.TrimSpace(args), true
+}
+
+// RegisterCommand registers a slash command with the agent.
+func (a *Agent) RegisterCommand(cmd Command) {
+	a.commands.register(cmd)
+}
+
+// Commands returns the registered slash commands sorted by name.
+func (a *Agent) Commands() []Command {
+	return a.commands.list()
+}
+
+// handleCommand runs a registered slash command. handled is false if the
+// content is not a known command and should go to the model.
+func (a *Agent) handleCommand(ctx context.Context, sessionID, content string) (reply string, handled bool, err error) {
+	name, args, ok := parseCommand(content)
+	if !ok {
+		return "", false, nil
+	}
+	cmd, ok := a.commands.get(name)
+	if !ok {
+		return "", false, nil
+	}
+
+	a.logger.Info("handling command", "command", name, "session", sessionID)
+	reply, err = cmd.Handler(ctx, sessionID, args)
+	if err != nil {
+		return "", true, fmt.Errorf("command /%s: %w", name, err)
+	}
+	return reply, true, nil
+}
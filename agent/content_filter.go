@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/plexusone/omnillm"
+	"github.com/plexusone/omnillm/provider"
+)
+
+// Content filter classes.
+const (
+	FilterClassInput   = "input"   // The provider blocked the prompt
+	FilterClassOutput  = "output"  // The provider stopped the reply
+	FilterClassRefusal = "refusal" // The model declined to answer
+)
+
+// DefaultContentFilterMessage is the reply when a provider refuses a request.
+const DefaultContentFilterMessage = "Sorry, I can't help with that request."
+
+// softenedPrompt is added to the system prompt when a filtered request is
+// retried.
+const softenedPrompt = "Your previous reply to this message was blocked by a content filter. " +
+	"Answer only the parts you can help with safely, and briefly decline the rest."
+
+// ContentFilterConfig configures how provider refusals are answered.
+type ContentFilterConfig struct {
+	// Message is sent to the user instead of an error
+	// (default: DefaultContentFilterMessage).
+	Message string
+	// Retry resends a filtered request once with softenedPrompt.
+	Retry bool
+}
+
+// contentFilter describes a request the provider refused.
+type contentFilter struct {
+	class  string
+	reason string
+}
+
+// filterFinishReasons maps finish reasons that mean the reply was filtered
+// to their class: OpenAI and Azure report content_filter, Anthropic
+// refusal, and Gemini a safety category.
+var filterFinishReasons = map[string]string{
+	"content_filter":     FilterClassOutput,
+	"refusal":            FilterClassRefusal,
+	"safety":             FilterClassOutput,
+	"recitation":         FilterClassOutput,
+	"blocklist":          FilterClassOutput,
+	"prohibited_content": FilterClassOutput,
+	"spii":               FilterClassOutput,
+	"image_safety":       FilterClassOutput,
+}
+
+// filterErrorMarkers appear in errors from providers that block a prompt
+// outright.
+var filterErrorMarkers = []string{
+	"content_filter",
+	"content filter",
+	"content_policy",
+	"content policy",
+	"content management policy",
+	"responsibleaipolicyviolation",
+	"prompt_blocked",
+	"blockreason",
+	"safety",
+}
+
+// filteredChoice reports whether a reply was stopped by a content filter.
+func filteredChoice(choice provider.ChatCompletionChoice) *contentFilter {
+	if choice.FinishReason == nil {
+		return nil
+	}
+	reason := strings.ToLower(*choice.FinishReason)
+	if class, ok := filterFinishReasons[reason]; ok {
+		return &contentFilter{class: class, reason: reason}
+	}
+	return nil
+}
+
+// filteredError reports whether a request failed because a content filter
+// blocked the prompt.
+func filteredError(err error) *contentFilter {
+	if err == nil {
+		return nil
+	}
+	text := err.Error()
+	var apiErr *omnillm.APIError
+	if errors.As(err, &apiErr) {
+		text = apiErr.Type + " " + apiErr.Code + " " + apiErr.Message
+	}
+	lower := strings.ToLower(text)
+	for _, marker := range filterErrorMarkers {
+		if strings.Contains(lower, marker) {
+			return &contentFilter{class: FilterClassInput, reason: truncate(text, 200)}
+		}
+	}
+	return nil
+}
+
+// handleContentFilter logs a refused request. It returns the messages to
+// retry with, or nil when the user should get the configured reply.
+func (a *Agent) handleContentFilter(ctx context.Context, run *runState, messages []provider.Message, filter *contentFilter) []provider.Message {
+	retry := a.config.ContentFilter.Retry && !run.softened
+	a.logger.WarnContext(ctx, "provider content filter",
+		"class", filter.class,
+		"reason", filter.reason,
+		"provider", a.config.Provider,
+		"model", run.settings.model,
+		"channel", ChannelFromSession(run.sessionID),
+		"retry", retry)
+	if !retry {
+		return nil
+	}
+	run.softened = true
+	return withSoftenedPrompt(messages)
+}
+
+// withSoftenedPrompt adds softenedPrompt to the system message, creating
+// one if needed.
+func withSoftenedPrompt(messages []provider.Message) []provider.Message {
+	out := slices.Clone(messages)
+	if len(out) > 0 && out[0].Role == provider.RoleSystem {
+		out[0].Content = strings.TrimSpace(out[0].Content + "\n\n" + softenedPrompt)
+		return out
+	}
+	return append([]provider.Message{{Role: provider.RoleSystem, Content: softenedPrompt}}, out...)
+}
@@ -0,0 +1,210 @@
+package agent
+
+import "context"
+
+type contextKey int
+
+const (
+	usageFuncKey contextKey = iota
+	sessionKey
+	contactKey
+	attachmentsKey
+	allowedToolsKey
+	delegationKey
+	progressKey
+	toolNameKey
+	metadataKey
+	contextVarsKey
+	toolIterationsKey
+	speakerKey
+	personKey
+	maxTokensKey
+	senderNameKey
+	tierKey
+)
+
+// UsageFunc receives token usage for each model call made while
+// processing a message.
+type UsageFunc func(promptTokens, completionTokens int)
+
+// WithUsageFunc returns a context that reports model token usage to fn.
+// Callers such as rate limiters use it to account tokens per request.
+func WithUsageFunc(ctx context.Context, fn UsageFunc) context.Context {
+	return context.WithValue(ctx, usageFuncKey, fn)
+}
+
+// reportUsage calls the context's usage function, if any.
+func reportUsage(ctx context.Context, promptTokens, completionTokens int) {
+	if fn, ok := ctx.Value(usageFuncKey).(UsageFunc); ok && fn != nil {
+		fn(promptTokens, completionTokens)
+	}
+}
+
+// WithSession returns a context carrying the session ID being processed.
+// The agent sets it before running tools.
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey, sessionID)
+}
+
+// SessionFromContext returns the session ID being processed, if any.
+func SessionFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(sessionKey).(string)
+	return s
+}
+
+// WithContact returns a context identifying the person who sent the
+// message, as "channel:senderID". Tools use it to keep per-contact state
+// that spans group and direct chats.
+func WithContact(ctx context.Context, contactID string) context.Context {
+	return context.WithValue(ctx, contactKey, contactID)
+}
+
+// ContactFromContext returns the sender's contact ID, if known.
+func ContactFromContext(ctx context.Context) string {
+	c, _ := ctx.Value(contactKey).(string)
+	return c
+}
+
+// WithSenderName returns a context carrying the sender's display name, as
+// reported by the channel.
+func WithSenderName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, senderNameKey, name)
+}
+
+// SenderNameFromContext returns the sender's display name, if known.
+func SenderNameFromContext(ctx context.Context) string {
+	n, _ := ctx.Value(senderNameKey).(string)
+	return n
+}
+
+// WithTier returns a context carrying the sender's access tier, e.g.
+// "owner". Tools that act beyond the current chat check it.
+func WithTier(ctx context.Context, tier string) context.Context {
+	return context.WithValue(ctx, tierKey, tier)
+}
+
+// TierFromContext returns the sender's access tier, or "" when access
+// control is off.
+func TierFromContext(ctx context.Context) string {
+	t, _ := ctx.Value(tierKey).(string)
+	return t
+}
+
+// WithPerson returns a context identifying the person behind the sender's
+// contact, when their accounts are linked across channels. Memory and rate
+// limits then follow the person instead of the contact.
+func WithPerson(ctx context.Context, personID string) context.Context {
+	return context.WithValue(ctx, personKey, personID)
+}
+
+// PersonFromContext returns the sender's person ID, if linked.
+func PersonFromContext(ctx context.Context) string {
+	p, _ := ctx.Value(personKey).(string)
+	return p
+}
+
+// WithSpeaker returns a context marking the message as sent to a group
+// chat by speaker, whose name the agent adds to the message.
+func WithSpeaker(ctx context.Context, speaker string) context.Context {
+	return context.WithValue(ctx, speakerKey, speaker)
+}
+
+// SpeakerFromContext returns the group chat speaker's name, and whether
+// the message came from a group chat.
+func SpeakerFromContext(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(speakerKey).(string)
+	return s, ok
+}
+
+// WithAllowedTools returns a context that restricts the model to the named
+// tools; an empty list allows none. Requests triggered by untrusted input,
+// such as webhooks, use it to limit what a prompt can do.
+func WithAllowedTools(ctx context.Context, names []string) context.Context {
+	allowed := make(map[string]bool, len(names))
+	for _, n := range names {
+		allowed[n] = true
+	}
+	return context.WithValue(ctx, allowedToolsKey, allowed)
+}
+
+// WithMaxTokens returns a context that caps each model reply at n tokens,
+// below the agent's own limit.
+func WithMaxTokens(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxTokensKey, n)
+}
+
+// maxTokens returns the reply token limit for a request, or 0 for none.
+func (a *Agent) maxTokens(ctx context.Context) int {
+	limit := a.config.MaxTokens
+	if n, ok := ctx.Value(maxTokensKey).(int); ok && n > 0 && (limit == 0 || n < limit) {
+		limit = n
+	}
+	return limit
+}
+
+// toolAllowed reports whether the context and the agent's persona permit
+// a tool.
+func (a *Agent) toolAllowed(ctx context.Context, name string) bool {
+	if a.toolFilter != nil && !a.toolFilter[name] {
+		return false
+	}
+	if !a.flags.ToolEnabled(name) {
+		return false
+	}
+	allowed, ok := ctx.Value(allowedToolsKey).(map[string]bool)
+	return !ok || allowed[name]
+}
+
+// withDelegation returns a context recording that the named agent is
+// working on the request. The default agent is recorded as "".
+func withDelegation(ctx context.Context, name string) context.Context {
+	chain := delegationChain(ctx)
+	return context.WithValue(ctx, delegationKey, append(chain[:len(chain):len(chain)], name))
+}
+
+// delegationChain returns the agents working on the request, outermost
+// first.
+func delegationChain(ctx context.Context) []string {
+	chain, _ := ctx.Value(delegationKey).([]string)
+	return chain
+}
+
+// ProgressFunc receives intermediate output from a running tool.
+type ProgressFunc func(tool, text string)
+
+// WithProgress returns a context that streams intermediate tool output to
+// fn, so channels can show progress on long-running tools.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey, fn)
+}
+
+// ReportProgress sends intermediate output from a running tool to the
+// requester, if it asked for progress. Long-running tools call it as
+// output arrives.
+func ReportProgress(ctx context.Context, text string) {
+	if fn, ok := ctx.Value(progressKey).(ProgressFunc); ok && fn != nil && text != "" {
+		name, _ := ctx.Value(toolNameKey).(string)
+		fn(name, text)
+	}
+}
+
+// WithMaxToolIterations returns a context allowing n model calls for the
+// request instead of the configured limit. Background jobs use it for
+// tasks that take many steps.
+func WithMaxToolIterations(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, toolIterationsKey, n)
+}
+
+// maxToolIterations returns the request's model call limit.
+func (a *Agent) maxToolIterations(ctx context.Context) int {
+	if n, ok := ctx.Value(toolIterationsKey).(int); ok && n > 0 {
+		return n
+	}
+	return a.config.MaxToolIterations
+}
+
+// withToolName returns a context naming the tool being run, for progress
+// reports.
+func withToolName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, toolNameKey, name)
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Limits on stored context variables, so clients cannot grow sessions or
+// prompts without bound.
+const (
+	maxContextVars     = 32
+	maxContextValueLen = 1024
+)
+
+// WithMetadata returns a context carrying structured metadata a channel or
+// client attached to the message, such as location, device, or the page
+// the user is on. The agent stores it on the session.
+func WithMetadata(ctx context.Context, md map[string]any) context.Context {
+	return context.WithValue(ctx, metadataKey, md)
+}
+
+// MetadataFromContext returns the metadata attached to the message, if any.
+func MetadataFromContext(ctx context.Context) map[string]any {
+	md, _ := ctx.Value(metadataKey).(map[string]any)
+	return md
+}
+
+// ContextVars returns the session's context variables exposed to tools:
+// the selected metadata keys most recently attached to its messages.
+func ContextVars(ctx context.Context) map[string]any {
+	vars, _ := ctx.Value(contextVarsKey).(map[string]any)
+	return vars
+}
+
+// withContextVars returns a context carrying the exposed variables.
+func withContextVars(ctx context.Context, vars map[string]any) context.Context {
+	return context.WithValue(ctx, contextVarsKey, vars)
+}
+
+// SetContextKeys stores message metadata on each session and exposes the
+// listed keys to the system prompt, as {context.<key>} placeholders and a
+// context section, and to tools via ContextVars. It registers /context.
+func (a *Agent) SetContextKeys(keys []string) {
+	a.contextVars = &contextStore{keys: keys, sessions: make(map[string]map[string]any)}
+	a.RegisterCommand(Command{
+		Name:        "context",
+		Description: "Show or clear the context your app shared",
+		Usage:       "/context [clear]",
+		Handler:     a.contextCommand,
+	})
+}
+
+// contextStore keeps the latest metadata per session.
+type contextStore struct {
+	keys     []string // Exposed keys
+	sessions map[string]map[string]any
+	mu       sync.Mutex
+}
+
+// update merges metadata into the session's stored values and returns the
+// exposed ones. A null value removes a key.
+func (s *contextStore) update(sessionID string, md map[string]any) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.sessions[sessionID]
+	for k, v := range md {
+		if v == nil {
+			delete(stored, k)
+			continue
+		}
+		if _, ok := stored[k]; !ok && len(stored) >= maxContextVars {
+			continue
+		}
+		if b, err := json.Marshal(v); err != nil || len(b) > maxContextValueLen {
+			continue
+		}
+		if stored == nil {
+			stored = make(map[string]any)
+			s.sessions[sessionID] = stored
+		}
+		stored[k] = v
+	}
+
+	exposed := make(map[string]any)
+	for _, k := range s.keys {
+		if v, ok := stored[k]; ok {
+			exposed[k] = v
+		}
+	}
+	return exposed
+}
+
+// clear removes the session's stored values.
+func (s *contextStore) clear(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+}
+
+// contextPrompt fills {context.<key>} placeholders in prompt and appends
+// the exposed variables the prompt does not mention.
+func (s *contextStore) contextPrompt(prompt string, vars map[string]any) string {
+	var lines []string
+	for _, k := range s.keys {
+		placeholder := "{context." + k + "}"
+		v, ok := vars[k]
+		if strings.Contains(prompt, placeholder) {
+			prompt = strings.ReplaceAll(prompt, placeholder, formatContextValue(v))
+			continue
+		}
+		if ok {
+			lines = append(lines, fmt.Sprintf("- %s: %s", k, formatContextValue(v)))
+		}
+	}
+	if len(lines) == 0 {
+		return prompt
+	}
+	return strings.TrimSpace(prompt + "\n\nContext shared by the user's app or device:\n" + strings.Join(lines, "\n"))
+}
+
+// formatContextValue renders strings as-is and other values as JSON. An
+// unknown value renders as "unknown".
+func formatContextValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "unknown"
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// contextCommand implements /context.
+func (a *Agent) contextCommand(_ context.Context, sessionID, args string) (string, error) {
+	if strings.EqualFold(strings.TrimSpace(args), "clear") {
+		a.contextVars.clear(sessionID)
+		return "Context cleared.", nil
+	}
+
+	vars := a.contextVars.update(sessionID, nil)
+	if len(vars) == 0 {
+		return "Your app hasn't shared any context in this conversation.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Context in use:\n")
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		fmt.Fprintf(&sb, "- %s: %s\n", k, formatContextValue(vars[k]))
+	}
+	sb.WriteString("\nClear it with /context clear.")
+	return sb.String(), nil
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/plexusone/omnillm/provider"
+)
+
+const (
+	// charsPerToken is a rough estimate that holds for English text and code.
+	charsPerToken = 4
+
+	// messageOverhead approximates the tokens a chat template adds per message.
+	messageOverhead = 4
+
+	// truncatedMarker replaces tool output that was cut to fit the context.
+	truncatedMarker = "[truncated to fit the context window]"
+
+	// minTruncatedOutput is the shortest head of tool output worth keeping.
+	minTruncatedOutput = 256
+)
+
+// messageTokens approximates the prompt size of messages in tokens.
+func messageTokens(messages []provider.Message) int {
+	n := 0
+	for _, m := range messages {
+		n += messageOverhead + len(m.Content)/charsPerToken
+		for _, tc := range m.ToolCalls {
+			n += (len(tc.Function.Name) + len(tc.Function.Arguments)) / charsPerToken
+		}
+	}
+	return n
+}
+
+// fitContext shortens the oldest tool results until messages, plus reserve
+// tokens for the reply, fit in a context window of contextLength tokens.
+// Other messages are left alone, so the result may still overflow when the
+// conversation itself is too long. A contextLength of 0 disables trimming.
+func fitContext(messages []provider.Message, contextLength, reserve int) []provider.Message {
+	if contextLength <= 0 {
+		return messages
+	}
+	excess := messageTokens(messages) + reserve - contextLength
+	if excess <= 0 {
+		return messages
+	}
+
+	trimmed := append([]provider.Message(nil), messages...)
+	for i := range trimmed {
+		if excess <= 0 {
+			break
+		}
+		m := &trimmed[i]
+		if !isToolResult(*m) {
+			continue
+		}
+		// Emulated results keep their header so the model knows the tool
+		header, body := "", m.Content
+		if m.Role == provider.RoleUser {
+			header, body, _ = strings.Cut(m.Content, "\n")
+			header += "\n"
+		}
+		if body == truncatedMarker {
+			continue
+		}
+		// Keep as much of the head of the output as the overflow allows
+		keep := len(body) - excess*charsPerToken - len(truncatedMarker) - 1
+		before := len(m.Content) / charsPerToken
+		if keep < minTruncatedOutput {
+			m.Content = header + truncatedMarker
+		} else {
+			m.Content = header + body[:keep] + "\n" + truncatedMarker
+		}
+		excess -= before - len(m.Content)/charsPerToken
+	}
+	return trimmed
+}
+
+// isToolResult reports whether m carries tool output, either natively or as
+// an emulated tool result.
+func isToolResult(m provider.Message) bool {
+	return m.Role == provider.RoleTool ||
+		(m.Role == provider.RoleUser && strings.HasPrefix(m.Content, "TOOL RESULT ("))
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxDelegationDepth bounds chains of agents delegating to each other.
+const maxDelegationDepth = 3
+
+// DelegateTool hands a subtask to a named persona in a pool and returns
+// its answer as the tool result.
+type DelegateTool struct {
+	pool *Pool
+}
+
+// Ensure DelegateTool implements Tool
+var _ Tool = (*DelegateTool)(nil)
+
+// NewDelegateTool creates a delegate tool for the personas in pool.
+func NewDelegateTool(pool *Pool) *DelegateTool {
+	return &DelegateTool{pool: pool}
+}
+
+// Name returns the tool name.
+func (t *DelegateTool) Name() string {
+	return "delegate"
+}
+
+// Description returns the tool description, listing the agents available.
+func (t *DelegateTool) Description() string {
+	var b strings.Builder
+	b.WriteString("Hand a self-contained subtask to another agent and get its answer. " +
+		"The agent cannot see this conversation, so include everything it needs in the task. Agents:")
+	for _, name := range t.names() {
+		b.WriteString("\n- " + name)
+		if desc := t.pool.personas[name].description; desc != "" {
+			b.WriteString(": " + desc)
+		}
+	}
+	return b.String()
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *DelegateTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"agent": map[string]interface{}{
+				"type":        "string",
+				"description": "Name of the agent to delegate to",
+				"enum":        t.names(),
+			},
+			"task": map[string]interface{}{
+				"type":        "string",
+				"description": "The subtask, with all context the agent needs",
+			},
+		},
+		"required": []string{"agent", "task"},
+	}
+}
+
+// names returns the persona names in order.
+func (t *DelegateTool) names() []string {
+	names := make([]string, 0, len(t.pool.personas))
+	for name := range t.pool.personas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Execute runs the subtask on the named agent. An agent already working on
+// the request cannot be delegated to again, which prevents loops.
+func (t *DelegateTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Agent string `json:"agent"`
+		Task  string `json:"task"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(params.Task) == "" {
+		return "", fmt.Errorf("task required")
+	}
+	target := t.pool.personas[params.Agent]
+	if target == nil {
+		return "", fmt.Errorf("unknown agent %q", params.Agent)
+	}
+
+	chain := delegationChain(ctx)
+	if slices.Contains(chain, params.Agent) {
+		return "", fmt.Errorf("agent %q is already working on this request", params.Agent)
+	}
+	if len(chain) > maxDelegationDepth {
+		return "", fmt.Errorf("delegation depth limit (%d) reached", maxDelegationDepth)
+	}
+
+	// Delegated work runs in a sub-session so its transcript and traces
+	// stay separate from the caller's.
+	sessionID := SessionFromContext(ctx) + "/" + params.Agent
+	res, err := target.process(withDelegation(ctx, params.Agent), sessionID, params.Task)
+	if err != nil {
+		return "", fmt.Errorf("agent %q: %w", params.Agent, err)
+	}
+	return res.Content, nil
+}
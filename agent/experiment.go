@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// ControlVariant names the traffic not assigned to any experiment variant.
+const ControlVariant = "control"
+
+// Experiment splits traffic between prompt or model variants.
+type Experiment struct {
+	Name     string
+	Variants []Variant
+}
+
+// Variant is one arm of an experiment. Weight is the percentage of sessions
+// assigned to it; sessions not covered by any variant use the base settings
+// and are reported as the control group.
+type Variant struct {
+	Name         string
+	Weight       int
+	Model        string
+	SystemPrompt string
+}
+
+// validate checks variant names and weights.
+func (e *Experiment) validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("experiment name required")
+	}
+	total := 0
+	seen := make(map[string]bool)
+	for _, v := range e.Variants {
+		if v.Name == "" || v.Name == ControlVariant {
+			return fmt.Errorf("experiment %s: invalid variant name %q", e.Name, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("experiment %s: duplicate variant %q", e.Name, v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight < 0 {
+			return fmt.Errorf("experiment %s: variant %s has negative weight", e.Name, v.Name)
+		}
+		total += v.Weight
+	}
+	if total > 100 {
+		return fmt.Errorf("experiment %s: variant weights total %d%%, must not exceed 100%%", e.Name, total)
+	}
+	return nil
+}
+
+// assign picks the variant for a session. Assignment is a stable hash of
+// the experiment name and session ID, so a conversation keeps its variant
+// across messages and restarts. It returns nil for the control group.
+func (e *Experiment) assign(sessionID string) *Variant {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(e.Name + "\x00" + sessionID))
+	bucket := int(h.Sum32() % 100)
+
+	for i := range e.Variants {
+		if bucket < e.Variants[i].Weight {
+			return &e.Variants[i]
+		}
+		bucket -= e.Variants[i].Weight
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/plexusone/omnillm"
+	"github.com/plexusone/omnillm/provider"
+)
+
+// Retry defaults.
+const (
+	defaultRetryBackoff    = time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// RetryPolicy retries model requests that fail with transient errors,
+// such as server errors, timeouts, and network failures.
+type RetryPolicy struct {
+	Attempts   int           // Retries after the first try, per model (default: 0)
+	Backoff    time.Duration // Delay before the first retry, doubling each time (default: 1s)
+	MaxBackoff time.Duration // Longest delay between retries (default: 30s)
+}
+
+// FallbackModel is a model tried, in order, when the ones before it keep
+// failing with transient errors. Credentials default to the agent's when
+// the provider is the same.
+type FallbackModel struct {
+	Provider string
+	Model    string
+	APIKey   string //nolint:gosec // G117: APIKey is intentionally stored for provider authentication
+	BaseURL  string
+}
+
+// llmTarget is a model and the client serving it.
+type llmTarget struct {
+	provider string
+	model    string
+	client   *omnillm.ChatClient
+}
+
+// newFallbacks creates a client for each fallback model.
+func newFallbacks(config Config) ([]*llmTarget, error) {
+	targets := make([]*llmTarget, 0, len(config.Fallbacks))
+	for _, fb := range config.Fallbacks {
+		if fb.Model == "" {
+			return nil, errors.New("fallback model: model required")
+		}
+		fc := fallbackConfig(config, fb)
+		client, err := omnillm.NewClient(omnillm.ClientConfig{
+			Providers:         []omnillm.ProviderConfig{providerConfigFor(fc)},
+			Logger:            config.Logger,
+			ObservabilityHook: config.ObservabilityHook,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fallback model %s: create llm client: %w", fb.Model, err)
+		}
+		targets = append(targets, &llmTarget{provider: fc.Provider, model: fb.Model, client: client})
+	}
+	return targets, nil
+}
+
+// fallbackConfig returns config with the provider and credentials of a
+// fallback model.
+func fallbackConfig(config Config, fb FallbackModel) Config {
+	fc := config
+	fc.Model = fb.Model
+	if fb.Provider != "" && fb.Provider != config.Provider {
+		fc.Provider, fc.APIKey, fc.BaseURL = fb.Provider, "", ""
+	}
+	if fb.APIKey != "" {
+		fc.APIKey = fb.APIKey
+	}
+	if fb.BaseURL != "" {
+		fc.BaseURL = fb.BaseURL
+	}
+	return fc
+}
+
+// completeFallback sends the request to the configured model, then to each
+// fallback model in turn while failures are transient. It returns the
+// provider that answered; req.Model is set to the model that answered.
+func (a *Agent) completeFallback(ctx context.Context, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, string, error) {
+	target := &llmTarget{provider: a.config.Provider, model: req.Model, client: a.client}
+	resp, err := a.completeRetrying(ctx, target, req)
+	for _, fb := range a.fallbacks {
+		if err == nil || !transient(ctx, err) {
+			break
+		}
+		a.logger.WarnContext(ctx, "model unavailable, falling back",
+			"provider", target.provider, "model", target.model,
+			"fallback_provider", fb.provider, "fallback_model", fb.model, "error", err)
+		target = fb
+		req.Model = fb.model
+		resp, err = a.completeRetrying(ctx, target, req)
+	}
+	return resp, target.provider, err
+}
+
+// completeRetrying sends the request to target, retrying transient
+// failures with backoff.
+func (a *Agent) completeRetrying(ctx context.Context, target *llmTarget, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, error) {
+	policy := a.config.Retry
+	backoff := policy.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := a.completeThrottled(ctx, target, req)
+		if err == nil || attempt >= policy.Attempts || !transient(ctx, err) {
+			return resp, err
+		}
+		a.logger.WarnContext(ctx, "model request failed, retrying",
+			"provider", target.provider, "model", target.model, "attempt", attempt+1, "in", backoff, "error", err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		backoff = min(backoff*2, policy.MaxBackoff)
+	}
+}
+
+// transient reports whether a failed request may succeed if tried again
+// or on another model. Providers often report failures without a status
+// code, so errors are transient unless known to be permanent, such as
+// invalid requests, bad credentials, or content filter refusals.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || filteredError(err) != nil {
+		return false
+	}
+	return omnillm.IsRetryableError(err)
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/eval"
+)
+
+// SetTraceStore enables trace recording and the /feedback command.
+func (a *Agent) SetTraceStore(store *eval.Store) {
+	a.traces = store
+	a.RegisterCommand(Command{
+		Name:        "feedback",
+		Description: "Rate the last reply",
+		Usage:       "/feedback up|down [comment]",
+		Handler:     a.feedbackCommand,
+	})
+}
+
+// RecordFeedback rates the most recent response in a session. It is used
+// for channel reactions, which don't reference a trace directly.
+func (a *Agent) RecordFeedback(ctx context.Context, sessionID string, rating int, comment, source string) error {
+	if a.traces == nil {
+		return fmt.Errorf("feedback capture not enabled")
+	}
+	trace, ok := a.traces.LastTrace(sessionID)
+	if !ok {
+		return eval.ErrTraceNotFound
+	}
+	return a.traces.RecordFeedback(ctx, eval.Feedback{
+		TraceID:   trace.ID,
+		SessionID: sessionID,
+		Rating:    rating,
+		Comment:   comment,
+		Source:    source,
+	})
+}
+
+// feedbackCommand implements /feedback.
+func (a *Agent) feedbackCommand(ctx context.Context, sessionID, args string) (string, error) {
+	ratingArg, comment, _ := strings.Cut(args, " ")
+	rating, err := eval.ParseRating(strings.ToLower(ratingArg))
+	if err != nil {
+		return "Usage: /feedback up|down [comment]", nil
+	}
+
+	err = a.RecordFeedback(ctx, sessionID, rating, strings.TrimSpace(comment), "command")
+	if err == eval.ErrTraceNotFound {
+		return "There's no reply to rate yet.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "Thanks for the feedback!", nil
+}
+
+// recordTrace stores the trace for a processed message and returns its ID.
+func (a *Agent) recordTrace(ctx context.Context, sessionID, input, output string, runErr error, run *runState, duration time.Duration) string {
+	loaded := a.loadedSkills()
+	skillNames := make([]string, 0, len(loaded))
+	for _, sk := range loaded {
+		skillNames = append(skillNames, sk.Name)
+	}
+
+	trace := eval.Trace{
+		ID:        newTraceID(),
+		SessionID: sessionID,
+		Channel:   ChannelFromSession(sessionID),
+		Provider:  a.config.Provider,
+		Model:     run.settings.model,
+		Skills:    skillNames,
+		Tools:     run.toolsCalled,
+		Input:     input,
+		Output:    output,
+		Duration:  duration,
+		CreatedAt: time.Now(),
+
+		PromptTokens:     run.promptTokens,
+		CompletionTokens: run.completionTokens,
+
+		PromptFingerprint: eval.Fingerprint(run.basePrompt),
+	}
+	if exp := a.config.Experiment; exp != nil {
+		trace.Experiment = exp.Name
+		trace.Variant = run.settings.variant
+	}
+	if runErr != nil {
+		trace.Error = runErr.Error()
+	}
+
+	if err := a.traces.SavePrompt(trace.PromptFingerprint, run.basePrompt); err != nil {
+		a.logger.Warn("failed to save prompt", "error", err)
+	}
+	if err := a.traces.RecordTrace(ctx, trace); err != nil {
+		a.logger.Warn("failed to record trace", "error", err)
+		return ""
+	}
+	return trace.ID
+}
+
+// newTraceID returns a random trace ID.
+func newTraceID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,9 @@
+package agent
+
+import "github.com/plexusone/omniagent/flags"
+
+// SetFlags gates tools and memory recall with feature flags, which can be
+// changed while the agent runs.
+func (a *Agent) SetFlags(f *flags.Flags) {
+	a.flags = f
+}
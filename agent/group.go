@@ -0,0 +1,20 @@
+package agent
+
+import (
+	"context"
+	"strings"
+)
+
+// groupPrompt is added to the system prompt for group chat messages.
+const groupPrompt = "## Group Chat\n\nThis conversation is a group chat with several people. " +
+	"Each message starts with the name of the person who sent it. Address people by name when " +
+	"it helps, and don't assume the sender of one message wrote the others."
+
+// attributed prefixes a group chat message with its speaker's name.
+func attributed(ctx context.Context, message string) string {
+	speaker, ok := SpeakerFromContext(ctx)
+	if !ok || strings.TrimSpace(speaker) == "" {
+		return message
+	}
+	return speaker + ": " + message
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plexusone/omnillm/provider"
+	"github.com/plexusone/omniserp"
+	"github.com/plexusone/omniserp/client"
+)
+
+// Ping sends a minimal completion to the configured model. It keeps the
+// provider connection warm and fails when the provider does.
+func (a *Agent) Ping(ctx context.Context) error {
+	maxTokens := 1
+	_, err := a.client.CreateChatCompletion(ctx, &provider.ChatCompletionRequest{
+		Model:     a.config.Model,
+		Messages:  []provider.Message{{Role: provider.RoleUser, Content: "ping"}},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		return fmt.Errorf("chat completion: %w", err)
+	}
+	return nil
+}
+
+// Ping runs a cheap query against the search engine, preferring
+// autocomplete where the engine supports it.
+func (t *SearchTool) Ping(ctx context.Context) error {
+	params := omniserp.SearchParams{Query: "weather"}
+	var err error
+	if t.client.SupportsOperation(client.OpSearchAutocomplete) {
+		_, err = t.client.SearchAutocomplete(ctx, params)
+	} else {
+		_, err = t.client.Search(ctx, params)
+	}
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omnillm"
+)
+
+// Local provider names. "openai-compatible" covers llama.cpp's server,
+// LM Studio, vLLM, and other servers exposing the OpenAI chat API.
+const (
+	ProviderOllama           = "ollama"
+	ProviderOpenAICompatible = "openai-compatible"
+)
+
+// Default base URLs for local inference servers.
+const (
+	DefaultOllamaBaseURL           = "http://localhost:11434"
+	DefaultOpenAICompatibleBaseURL = "http://localhost:8080/v1"
+)
+
+// localAPIKeyPlaceholder satisfies clients that require a key when the
+// server does not check one.
+const localAPIKeyPlaceholder = "sk-no-key-required" //nolint:gosec // G101: Placeholder, not a credential
+
+// localRequestTimeout allows for slow local generation on modest hardware.
+const localRequestTimeout = 5 * time.Minute
+
+// IsLocalProvider reports whether the provider talks to a local inference
+// server and therefore does not require an API key.
+func IsLocalProvider(name string) bool {
+	switch name {
+	case ProviderOllama, ProviderOpenAICompatible:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsNativeTools reports whether the provider passes tool definitions
+// through to the model. The omnillm Ollama adapter drops tools, so tool use
+// with Ollama must be handled by the agent.
+func SupportsNativeTools(name string) bool {
+	return name != ProviderOllama
+}
+
+// providerConfigFor maps agent configuration to an omnillm provider
+// configuration, applying local provider presets.
+func providerConfigFor(config Config) omnillm.ProviderConfig {
+	pc := omnillm.ProviderConfig{
+		Provider: omnillm.ProviderName(config.Provider),
+		APIKey:   config.APIKey,
+		BaseURL:  config.BaseURL,
+	}
+
+	switch config.Provider {
+	case ProviderOllama:
+		if pc.BaseURL == "" {
+			pc.BaseURL = DefaultOllamaBaseURL
+		}
+		pc.HTTPClient = &http.Client{Timeout: localRequestTimeout}
+	case ProviderOpenAICompatible:
+		pc.Provider = omnillm.ProviderNameOpenAI
+		if pc.BaseURL == "" {
+			pc.BaseURL = DefaultOpenAICompatibleBaseURL
+		}
+		if pc.APIKey == "" {
+			pc.APIKey = localAPIKeyPlaceholder
+		}
+		pc.HTTPClient = &http.Client{Timeout: localRequestTimeout}
+	}
+
+	return pc
+}
+
+// ModelInfo describes a model available on an inference server.
+type ModelInfo struct {
+	Name       string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+// ListModels lists the models available on a local inference server.
+func ListModels(ctx context.Context, providerName, baseURL, apiKey string) ([]ModelInfo, error) {
+	switch providerName {
+	case ProviderOllama:
+		if baseURL == "" {
+			baseURL = DefaultOllamaBaseURL
+		}
+		var resp struct {
+			Models []struct {
+				Name       string    `json:"name"`
+				Size       int64     `json:"size"`
+				ModifiedAt time.Time `json:"modified_at"`
+			} `json:"models"`
+		}
+		if err := localJSON(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/tags", apiKey, nil, &resp); err != nil {
+			return nil, err
+		}
+		models := make([]ModelInfo, 0, len(resp.Models))
+		for _, m := range resp.Models {
+			models = append(models, ModelInfo{Name: m.Name, Size: m.Size, ModifiedAt: m.ModifiedAt})
+		}
+		return sortModels(models), nil
+
+	case ProviderOpenAICompatible:
+		if baseURL == "" {
+			baseURL = DefaultOpenAICompatibleBaseURL
+		}
+		var resp struct {
+			Data []struct {
+				ID      string `json:"id"`
+				Created int64  `json:"created"`
+			} `json:"data"`
+		}
+		if err := localJSON(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/models", apiKey, nil, &resp); err != nil {
+			return nil, err
+		}
+		models := make([]ModelInfo, 0, len(resp.Data))
+		for _, m := range resp.Data {
+			info := ModelInfo{Name: m.ID}
+			if m.Created > 0 {
+				info.ModifiedAt = time.Unix(m.Created, 0)
+			}
+			models = append(models, info)
+		}
+		return sortModels(models), nil
+
+	default:
+		return nil, fmt.Errorf("model listing not supported for provider %q", providerName)
+	}
+}
+
+// CheckLocalModel verifies that a local inference server is reachable and,
+// for Ollama, that the model has been pulled. OpenAI-compatible servers
+// often serve a single model under any name, so only connectivity is
+// checked for them.
+func CheckLocalModel(ctx context.Context, providerName, baseURL, apiKey, model string) error {
+	models, err := ListModels(ctx, providerName, baseURL, apiKey)
+	if err != nil {
+		return fmt.Errorf("%s server unreachable: %w", providerName, err)
+	}
+	if providerName != ProviderOllama {
+		return nil
+	}
+	for _, m := range models {
+		// Ollama reports untagged models with the ":latest" tag
+		if m.Name == model || m.Name == model+":latest" {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %q not found on ollama server (run: ollama pull %s)", model, model)
+}
+
+// CheckModelChain checks the model of config and its fallbacks in turn,
+// returning the errors of local models that are unavailable. It fails only
+// when no model in the chain is usable, so a stopped local server doesn't
+// block startup when a fallback can serve. Hosted models are assumed to be
+// usable, as checking them would cost a request.
+func CheckModelChain(ctx context.Context, config Config) ([]error, error) {
+	chain := []Config{config}
+	for _, fb := range config.Fallbacks {
+		chain = append(chain, fallbackConfig(config, fb))
+	}
+
+	var unavailable []error
+	for _, c := range chain {
+		if !IsLocalProvider(c.Provider) {
+			return unavailable, nil
+		}
+		if err := CheckLocalModel(ctx, c.Provider, c.BaseURL, c.APIKey, c.Model); err != nil {
+			unavailable = append(unavailable, fmt.Errorf("model %s: %w", c.Model, err))
+			continue
+		}
+		return unavailable, nil
+	}
+	return unavailable, errors.Join(unavailable...)
+}
+
+// DetectContextLength asks a local inference server for the model's context
+// window size in tokens. It returns 0 if the server does not report one.
+func DetectContextLength(ctx context.Context, providerName, baseURL, model string) (int, error) {
+	switch providerName {
+	case ProviderOllama:
+		if baseURL == "" {
+			baseURL = DefaultOllamaBaseURL
+		}
+		var resp struct {
+			ModelInfo map[string]any `json:"model_info"`
+		}
+		body := map[string]string{"model": model}
+		if err := localJSON(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/api/show", "", body, &resp); err != nil {
+			return 0, err
+		}
+		// Keys are architecture-prefixed, e.g. "llama.context_length"
+		for k, v := range resp.ModelInfo {
+			if strings.HasSuffix(k, ".context_length") {
+				if n, ok := v.(float64); ok {
+					return int(n), nil
+				}
+			}
+		}
+		return 0, nil
+
+	case ProviderOpenAICompatible:
+		if baseURL == "" {
+			baseURL = DefaultOpenAICompatibleBaseURL
+		}
+		// llama.cpp serves /props at the server root, outside /v1
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return 0, fmt.Errorf("invalid base URL: %w", err)
+		}
+		u.Path = "/props"
+		var resp struct {
+			DefaultGenerationSettings struct {
+				NCtx int `json:"n_ctx"`
+			} `json:"default_generation_settings"`
+		}
+		if err := localJSON(ctx, http.MethodGet, u.String(), "", nil, &resp); err != nil {
+			return 0, nil // Not a llama.cpp server; context length unknown
+		}
+		return resp.DefaultGenerationSettings.NCtx, nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// localJSON performs a JSON request against a local inference server.
+func localJSON(ctx context.Context, method, endpoint, apiKey string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req) //nolint:gosec // G704: Endpoint comes from operator configuration
+	if err != nil {
+		return fmt.Errorf("request %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("request %s: %s: %s", endpoint, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func sortModels(models []ModelInfo) []ModelInfo {
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeOllama serves /api/tags listing models.
+func fakeOllama(t *testing.T, models ...string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		body := `{"models":[`
+		for i, m := range models {
+			if i > 0 {
+				body += ","
+			}
+			body += `{"name":"` + m + `"}`
+		}
+		_, _ = w.Write([]byte(body + `]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCheckModelChain(t *testing.T) {
+	up := fakeOllama(t, "llama3.1:latest")
+	down := "http://127.0.0.1:1"
+
+	tests := []struct {
+		name        string
+		config      Config
+		unavailable int
+		wantErr     bool
+	}{
+		{
+			name:   "primary up",
+			config: Config{Provider: ProviderOllama, Model: "llama3.1", BaseURL: up},
+		},
+		{
+			name:   "hosted primary",
+			config: Config{Provider: "openai", Model: "gpt-4o"},
+		},
+		{
+			name:        "primary down",
+			config:      Config{Provider: ProviderOllama, Model: "llama3.1", BaseURL: down},
+			unavailable: 1,
+			wantErr:     true,
+		},
+		{
+			name:        "model not pulled",
+			config:      Config{Provider: ProviderOllama, Model: "qwen2.5", BaseURL: up},
+			unavailable: 1,
+			wantErr:     true,
+		},
+		{
+			name: "local fallback",
+			config: Config{Provider: ProviderOllama, Model: "llama3.1", BaseURL: down,
+				Fallbacks: []FallbackModel{{Model: "llama3.1", BaseURL: up}}},
+			unavailable: 1,
+		},
+		{
+			name: "hosted fallback",
+			config: Config{Provider: ProviderOllama, Model: "llama3.1", BaseURL: down,
+				Fallbacks: []FallbackModel{{Provider: "openai", Model: "gpt-4o"}}},
+			unavailable: 1,
+		},
+		{
+			name: "all down",
+			config: Config{Provider: ProviderOllama, Model: "llama3.1", BaseURL: down,
+				Fallbacks: []FallbackModel{{Model: "qwen2.5", BaseURL: up}}},
+			unavailable: 2,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unavailable, err := CheckModelChain(context.Background(), tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckModelChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(unavailable) != tt.unavailable {
+				t.Errorf("unavailable = %v, want %d", unavailable, tt.unavailable)
+			}
+		})
+	}
+}
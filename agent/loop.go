@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Defaults for bounding the tool-calling loop.
+const (
+	defaultMaxToolIterations = 5
+	defaultMaxRepeatedCalls  = 2
+)
+
+// ToolLoopError is returned when the model keeps calling a tool with the
+// same arguments, which would otherwise use up every iteration without
+// making progress.
+type ToolLoopError struct {
+	Tool  string
+	Calls int
+}
+
+func (e *ToolLoopError) Error() string {
+	return fmt.Sprintf("stopped after the model called %q %d times with the same arguments; "+
+		"try rephrasing the request or giving more detail", e.Tool, e.Calls)
+}
+
+// countCall records a tool call and returns a *ToolLoopError once the same
+// name and arguments exceed limit calls in this request.
+func (r *runState) countCall(name string, args json.RawMessage, limit int) error {
+	if r.callCounts == nil {
+		r.callCounts = make(map[string]int)
+	}
+	key := name + "\x00" + canonicalArgs(args)
+	r.callCounts[key]++
+	if n := r.callCounts[key]; n > limit {
+		return &ToolLoopError{Tool: name, Calls: n}
+	}
+	return nil
+}
+
+// canonicalArgs normalizes JSON arguments so calls differing only in key
+// order or whitespace compare equal.
+func canonicalArgs(args json.RawMessage) string {
+	var v any
+	if err := json.Unmarshal(args, &v); err != nil {
+		return string(bytes.TrimSpace(args))
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return string(args)
+	}
+	return string(data)
+}
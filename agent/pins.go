@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits on pinned messages, which are added to every prompt.
+const (
+	maxPins      = 20
+	maxPinLength = 2000
+)
+
+// Pin is a message kept in a conversation's context.
+type Pin struct {
+	Text     string    `json:"text"`
+	From     string    `json:"from"` // user or assistant
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// PinStore keeps pinned messages per session, optionally persisted to a
+// JSON file. Pins are part of the system prompt, so trimming or
+// summarizing history never drops them.
+type PinStore struct {
+	path string
+	pins map[string][]Pin
+	mu   sync.RWMutex
+}
+
+// NewPinStore creates a pin store. If path is non-empty, pins are loaded
+// from and saved to that file.
+func NewPinStore(path string) (*PinStore, error) {
+	s := &PinStore{
+		path: path,
+		pins: make(map[string][]Pin),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read pins: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.pins); err != nil {
+		return nil, fmt.Errorf("parse pins: %w", err)
+	}
+	return s, nil
+}
+
+// List returns the session's pins, oldest first.
+func (s *PinStore) List(sessionID string) []Pin {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Pin(nil), s.pins[sessionID]...)
+}
+
+// Add pins text to a session.
+func (s *PinStore) Add(sessionID, from, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("nothing to pin")
+	}
+	if len(text) > maxPinLength {
+		return fmt.Errorf("message is too long to pin (%d characters, limit %d)", len(text), maxPinLength)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pins[sessionID]) >= maxPins {
+		return fmt.Errorf("this conversation already has %d pins; remove one with /unpin", maxPins)
+	}
+	s.pins[sessionID] = append(s.pins[sessionID], Pin{Text: text, From: from, PinnedAt: time.Now()})
+	return s.save()
+}
+
+// Remove unpins the nth pin, counting from 1.
+func (s *PinStore) Remove(sessionID string, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pins := s.pins[sessionID]
+	if n < 1 || n > len(pins) {
+		return fmt.Errorf("no pin %d", n)
+	}
+	pins = append(pins[:n-1:n-1], pins[n:]...)
+	if len(pins) == 0 {
+		delete(s.pins, sessionID)
+	} else {
+		s.pins[sessionID] = pins
+	}
+	return s.save()
+}
+
+// Clear removes all of a session's pins.
+func (s *PinStore) Clear(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pins, sessionID)
+	return s.save()
+}
+
+// Prompt renders the session's pins as a system prompt section.
+func (s *PinStore) Prompt(sessionID string) string {
+	pins := s.List(sessionID)
+	if len(pins) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Pinned Messages\n\nThe user pinned these messages to keep them in mind throughout the conversation:\n")
+	for _, p := range pins {
+		fmt.Fprintf(&b, "\n- (%s) %s", p.From, p.Text)
+	}
+	return b.String()
+}
+
+// save writes the pins file. Callers must hold the write lock.
+func (s *PinStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.pins, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode pins: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("create pins dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write pins: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// SetPinStore enables pinned messages and the /pin, /pins, and /unpin
+// commands.
+func (a *Agent) SetPinStore(store *PinStore) {
+	a.pins = store
+	a.RegisterCommand(Command{
+		Name:        "pin",
+		Description: "Pin the last reply, or the given text, to this conversation",
+		Usage:       "/pin [text]",
+		Handler:     a.pinCommand,
+	})
+	a.RegisterCommand(Command{
+		Name:        "pins",
+		Description: "List pinned messages",
+		Usage:       "/pins",
+		Handler:     a.pinsCommand,
+	})
+	a.RegisterCommand(Command{
+		Name:        "unpin",
+		Description: "Remove a pinned message",
+		Usage:       "/unpin <number>|all",
+		Handler:     a.unpinCommand,
+	})
+}
+
+// pinCommand implements /pin.
+func (a *Agent) pinCommand(_ context.Context, sessionID, args string) (string, error) {
+	from, text := "user", args
+	if text == "" {
+		from, text = "assistant", a.replies.last(sessionID)
+		if text == "" {
+			return "There is no reply to pin yet. Use /pin <text> to pin something else.", nil
+		}
+	}
+	if err := a.pins.Add(sessionID, from, text); err != nil {
+		return err.Error(), nil
+	}
+	return fmt.Sprintf("Pinned (%d of %d). See /pins.", len(a.pins.List(sessionID)), maxPins), nil
+}
+
+// pinsCommand implements /pins.
+func (a *Agent) pinsCommand(_ context.Context, sessionID, _ string) (string, error) {
+	pins := a.pins.List(sessionID)
+	if len(pins) == 0 {
+		return "No pinned messages. Pin the last reply with /pin, or any text with /pin <text>.", nil
+	}
+	var b strings.Builder
+	b.WriteString("Pinned messages:")
+	for i, p := range pins {
+		fmt.Fprintf(&b, "\n%d. (%s, %s) %s", i+1, p.From, p.PinnedAt.Format("2006-01-02"), truncate(p.Text, 200))
+	}
+	b.WriteString("\n\nRemove one with /unpin <number>.")
+	return b.String(), nil
+}
+
+// unpinCommand implements /unpin.
+func (a *Agent) unpinCommand(_ context.Context, sessionID, args string) (string, error) {
+	if strings.EqualFold(args, "all") {
+		if err := a.pins.Clear(sessionID); err != nil {
+			return "", err
+		}
+		return "All pins removed.", nil
+	}
+	n, err := strconv.Atoi(args)
+	if err != nil {
+		return "Usage: /unpin <number>|all", nil
+	}
+	if err := a.pins.Remove(sessionID, n); err != nil {
+		return err.Error(), nil
+	}
+	return "Unpinned.", nil
+}
+
+// PinTool lets the model pin a message the user wants kept in mind.
+type PinTool struct {
+	store *PinStore
+}
+
+// Ensure PinTool implements Tool
+var _ Tool = (*PinTool)(nil)
+
+// NewPinTool creates a pin tool backed by store.
+func NewPinTool(store *PinStore) *PinTool {
+	return &PinTool{store: store}
+}
+
+// Name returns the tool name.
+func (t *PinTool) Name() string {
+	return "pin_message"
+}
+
+// Description returns the tool description.
+func (t *PinTool) Description() string {
+	return "Pin a message so it stays in this conversation's context, for example when the user asks you to keep " +
+		"something in mind. Pinned messages are shown to you with every request and listed by /pins."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *PinTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "The message to pin, quoted exactly",
+			},
+			"from": map[string]interface{}{
+				"type":        "string",
+				"description": "Who wrote the message",
+				"enum":        []string{"user", "assistant"},
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+// Execute pins the message to the current session.
+func (t *PinTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Text string `json:"text"`
+		From string `json:"from"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	sessionID := SessionFromContext(ctx)
+	if sessionID == "" {
+		return "", fmt.Errorf("no conversation to pin to")
+	}
+	if params.From != "assistant" {
+		params.From = "user"
+	}
+	if err := t.store.Add(sessionID, params.From, params.Text); err != nil {
+		return "", err
+	}
+	return "Pinned.", nil
+}
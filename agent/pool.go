@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/plexusone/omnillm"
+)
+
+// Persona configures a named agent derived from a base agent. Empty
+// fields keep the base agent's settings.
+type Persona struct {
+	Name         string
+	Description  string // What the persona is for, shown to delegating agents
+	Provider     string
+	Model        string
+	APIKey       string //nolint:gosec // G117: APIKey is intentionally stored for provider authentication
+	BaseURL      string
+	SystemPrompt string
+	Temperature  *float64
+
+	// Tools restricts the persona to the named tools. Nil allows all of
+	// the base agent's tools.
+	Tools []string
+}
+
+// Derive creates an agent for a persona. It shares the base agent's tool
+// registry, commands, skills, and stores, so it should be called once the
+// base agent is fully set up. Channel overrides and experiments do not
+// apply to personas.
+func (a *Agent) Derive(p Persona) (*Agent, error) {
+	if p.Name == "" {
+		return nil, fmt.Errorf("persona name required")
+	}
+	a.mu.RLock()
+	config := a.config
+	a.mu.RUnlock()
+	config.Channels = nil
+	config.Experiment = nil
+	if p.Model != "" {
+		config.Model = p.Model
+	}
+	if p.SystemPrompt != "" {
+		config.SystemPrompt = p.SystemPrompt
+	}
+	if p.Temperature != nil {
+		config.Temperature = *p.Temperature
+	}
+
+	client := a.client
+	if p.Provider != "" || p.APIKey != "" || p.BaseURL != "" {
+		if p.Provider != "" && p.Provider != a.config.Provider {
+			// Credentials of the base provider don't carry over
+			config.APIKey, config.BaseURL = "", ""
+			config.ContextLength = 0
+			config.Provider = p.Provider
+		}
+		if p.APIKey != "" {
+			config.APIKey = p.APIKey
+		}
+		if p.BaseURL != "" {
+			config.BaseURL = p.BaseURL
+		}
+		var err error
+		client, err = omnillm.NewClient(omnillm.ClientConfig{
+			Providers:         []omnillm.ProviderConfig{providerConfigFor(config)},
+			Logger:            config.Logger,
+			ObservabilityHook: config.ObservabilityHook,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("persona %q: create llm client: %w", p.Name, err)
+		}
+	}
+
+	d := &Agent{
+		client:           client,
+		fallbacks:        a.fallbacks,
+		tools:            a.tools,
+		skills:           a.loadedSkills(),
+		config:           config,
+		logger:           a.logger.With("persona", p.Name),
+		commands:         a.commands,
+		prefs:            a.prefs,
+		pins:             a.pins,
+		traces:           a.traces,
+		clock:            a.clock,
+		budget:           a.budget,
+		throttle:         a.throttle,
+		approvals:        a.approvals,
+		transcripts:      a.transcripts,
+		audit:            a.audit,
+		ledger:           a.ledger,
+		flags:            a.flags,
+		contextVars:      a.contextVars,
+		retriever:        a.retriever,
+		attachmentReader: a.attachmentReader,
+		toolHook:         a.toolHook,
+		skillSelector:    a.skillSelector,
+		channelNames:     a.channelNames,
+		persona:          p.Name,
+		description:      p.Description,
+		activity:         a.activity,
+		replies:          a.replies,
+	}
+	if p.Tools != nil {
+		d.toolFilter = make(map[string]bool, len(p.Tools))
+		for _, name := range p.Tools {
+			d.toolFilter[name] = true
+		}
+	}
+	switch config.ToolCallMode {
+	case ToolCallModeNative:
+		d.nativeTools.Store(true)
+	case ToolCallModeAuto:
+		d.nativeTools.Store(SupportsNativeTools(config.Provider))
+	}
+	return d, nil
+}
+
+// Route selects a persona for matching messages.
+type Route struct {
+	Agent    string   // Persona name
+	Channels []string // Channel names, e.g. "telegram"
+	Senders  []string // Contact IDs, e.g. "telegram:12345"
+
+	// Prefix routes messages starting with it, e.g. "@coder". The prefix
+	// is removed before the persona sees the message.
+	Prefix string
+}
+
+// Pool routes messages among named agents. Messages starting with a
+// route prefix or "@<persona>" go to that persona; otherwise the first
+// route matching the message's channel or sender wins, falling back to
+// the default agent.
+type Pool struct {
+	def      *Agent
+	personas map[string]*Agent
+	routes   []Route
+}
+
+// NewPool creates a pool routing between the default agent and personas.
+func NewPool(def *Agent, personas map[string]*Agent, routes []Route) (*Pool, error) {
+	if def == nil {
+		return nil, fmt.Errorf("default agent required")
+	}
+	for _, r := range routes {
+		if personas[r.Agent] == nil {
+			return nil, fmt.Errorf("route to unknown agent %q", r.Agent)
+		}
+		if r.Prefix == "" && len(r.Channels) == 0 && len(r.Senders) == 0 {
+			return nil, fmt.Errorf("route to %q matches nothing", r.Agent)
+		}
+	}
+	return &Pool{def: def, personas: personas, routes: routes}, nil
+}
+
+// Select returns the agent for a message and the message with any routing
+// prefix removed. The persona name is empty for the default agent.
+func (p *Pool) Select(ctx context.Context, sessionID, content string) (*Agent, string, string) {
+	trimmed := strings.TrimSpace(content)
+	for _, r := range p.routes {
+		if rest, ok := cutPrefix(trimmed, r.Prefix); ok {
+			return p.personas[r.Agent], r.Agent, rest
+		}
+	}
+	if word, rest, _ := strings.Cut(trimmed, " "); strings.HasPrefix(word, "@") {
+		name := strings.TrimPrefix(word, "@")
+		if a := p.personas[name]; a != nil {
+			return a, name, strings.TrimSpace(rest)
+		}
+	}
+
+	channel := ChannelFromSession(sessionID)
+	contact := ContactFromContext(ctx)
+	for _, r := range p.routes {
+		if slices.Contains(r.Channels, channel) || (contact != "" && slices.Contains(r.Senders, contact)) {
+			return p.personas[r.Agent], r.Agent, content
+		}
+	}
+	return p.def, "", content
+}
+
+// cutPrefix removes a routing prefix followed by whitespace or the end of
+// the message.
+func cutPrefix(content, prefix string) (string, bool) {
+	if prefix == "" {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(content, prefix)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\n') {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// Process routes a message and returns the selected agent's response.
+func (p *Pool) Process(ctx context.Context, sessionID, content string) (string, error) {
+	res, err := p.ProcessWithResult(ctx, sessionID, content)
+	if err != nil {
+		return "", err
+	}
+	return res.Content, nil
+}
+
+// ProcessWithResult routes a message and returns the selected agent's
+// result.
+func (p *Pool) ProcessWithResult(ctx context.Context, sessionID, content string) (*Result, error) {
+	a, name, content := p.Select(ctx, sessionID, content)
+	if name != "" {
+		a.logger.Info("routing message to persona", "session", sessionID)
+	}
+	return a.ProcessWithResult(withDelegation(ctx, name), sessionID, content)
+}
+
+// Default returns the default agent.
+func (p *Pool) Default() *Agent {
+	return p.def
+}
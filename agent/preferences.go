@@ -0,0 +1,290 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// Preferences are reply preferences a user sets for their conversations.
+type Preferences struct {
+	Language  string `json:"language,omitempty"`  // e.g., "French"
+	Formality string `json:"formality,omitempty"` // casual, neutral, formal
+	Emoji     string `json:"emoji,omitempty"`     // on, off
+	Length    string `json:"length,omitempty"`    // short, medium, long
+}
+
+// preferenceOptions lists the allowed values for enumerated preferences.
+var preferenceOptions = map[string][]string{
+	"formality": {"casual", "neutral", "formal"},
+	"emoji":     {"on", "off"},
+	"length":    {"short", "medium", "long"},
+}
+
+// IsZero reports whether no preferences are set.
+func (p Preferences) IsZero() bool {
+	return p == Preferences{}
+}
+
+// Set updates a preference by key, validating enumerated values.
+func (p *Preferences) Set(key, value string) error {
+	key = strings.ToLower(key)
+	value = strings.TrimSpace(value)
+
+	if opts, ok := preferenceOptions[key]; ok {
+		value = strings.ToLower(value)
+		valid := false
+		for _, o := range opts {
+			if value == o {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("%s must be one of: %s", key, strings.Join(opts, ", "))
+		}
+	}
+
+	switch key {
+	case "language":
+		name, ok := languageName(value)
+		if !ok {
+			return fmt.Errorf("unknown language %q (use a name such as Spanish or a tag such as es)", value)
+		}
+		p.Language = name
+	case "formality":
+		p.Formality = value
+	case "emoji":
+		p.Emoji = value
+	case "length":
+		p.Length = value
+	default:
+		return fmt.Errorf("unknown preference %q (use language, formality, emoji, or length)", key)
+	}
+	return nil
+}
+
+// languageNames maps lowercase language names, in English and in the
+// language itself, to their English names.
+var languageNames = sync.OnceValue(func() map[string]string {
+	english := display.English.Languages()
+	names := make(map[string]string)
+	for _, tag := range display.Supported.Tags() {
+		name := english.Name(tag)
+		if name == "" {
+			continue
+		}
+		names[strings.ToLower(name)] = name
+		if self := display.Self.Name(tag); self != "" {
+			names[strings.ToLower(self)] = name
+		}
+	}
+	return names
+})
+
+// languageName returns the English name of a language given by name or
+// BCP 47 tag, so only known languages reach the system prompt.
+func languageName(value string) (string, bool) {
+	if name, ok := languageNames()[strings.ToLower(value)]; ok {
+		return name, true
+	}
+	tag, err := language.Parse(value)
+	if err != nil {
+		return "", false
+	}
+	name := display.English.Languages().Name(tag)
+	return name, name != ""
+}
+
+// Prompt renders the preferences as system prompt instructions.
+func (p Preferences) Prompt() string {
+	if p.IsZero() {
+		return ""
+	}
+
+	var lines []string
+	if p.Language != "" {
+		lines = append(lines, fmt.Sprintf("- Reply in %s.", p.Language))
+	}
+	switch p.Formality {
+	case "casual":
+		lines = append(lines, "- Use a casual, friendly tone.")
+	case "formal":
+		lines = append(lines, "- Use a formal, professional tone.")
+	}
+	switch p.Emoji {
+	case "on":
+		lines = append(lines, "- Emoji are welcome where they fit.")
+	case "off":
+		lines = append(lines, "- Do not use emoji.")
+	}
+	switch p.Length {
+	case "short":
+		lines = append(lines, "- Keep replies short: one to three sentences.")
+	case "medium":
+		lines = append(lines, "- Keep replies to a short paragraph unless more is needed.")
+	case "long":
+		lines = append(lines, "- Detailed, thorough replies are welcome.")
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "## User Preferences\n\n" + strings.Join(lines, "\n")
+}
+
+// String formats the preferences for display.
+func (p Preferences) String() string {
+	show := func(v string) string {
+		if v == "" {
+			return "(default)"
+		}
+		return v
+	}
+	return fmt.Sprintf("language: %s\nformality: %s\nemoji: %s\nlength: %s",
+		show(p.Language), show(p.Formality), show(p.Emoji), show(p.Length))
+}
+
+// PreferenceStore keeps preferences by key, optionally persisted to a JSON
+// file.
+type PreferenceStore struct {
+	path  string
+	prefs map[string]Preferences
+	mu    sync.RWMutex
+}
+
+// NewPreferenceStore creates a preference store. If path is non-empty,
+// preferences are loaded from and saved to that file.
+func NewPreferenceStore(path string) (*PreferenceStore, error) {
+	s := &PreferenceStore{
+		path:  path,
+		prefs: make(map[string]Preferences),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read preferences: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.prefs); err != nil {
+		return nil, fmt.Errorf("parse preferences: %w", err)
+	}
+	return s, nil
+}
+
+// Get returns the preferences stored under key.
+func (s *PreferenceStore) Get(key string) Preferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.prefs[key]
+}
+
+// Set stores the preferences under key.
+func (s *PreferenceStore) Set(key string, p Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.IsZero() {
+		delete(s.prefs, key)
+	} else {
+		s.prefs[key] = p
+	}
+	return s.save()
+}
+
+// prefsKey returns the key of the sender's preferences: their person when
+// linked across channels, else their contact, so preferences follow them
+// across chats. Messages without a sender fall back to the session.
+func prefsKey(ctx context.Context, sessionID string) string {
+	if person := PersonFromContext(ctx); person != "" {
+		return "person:" + person
+	}
+	if contact := ContactFromContext(ctx); contact != "" {
+		return "contact:" + contact
+	}
+	return sessionID
+}
+
+// preferences returns the sender's preferences, or those saved for the
+// session before preferences followed the sender.
+func (a *Agent) preferences(ctx context.Context, sessionID string) Preferences {
+	if p := a.prefs.Get(prefsKey(ctx, sessionID)); !p.IsZero() {
+		return p
+	}
+	return a.prefs.Get(sessionID)
+}
+
+// save writes the preferences file. Callers must hold the write lock.
+func (s *PreferenceStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.prefs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode preferences: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("create preferences dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write preferences: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// SetPreferenceStore enables per-sender preferences and the /prefs command.
+func (a *Agent) SetPreferenceStore(store *PreferenceStore) {
+	a.prefs = store
+	a.RegisterCommand(Command{
+		Name:        "prefs",
+		Description: "Show or change reply preferences",
+		Usage:       "/prefs [language <name> | formality casual|neutral|formal | emoji on|off | length short|medium|long | reset]",
+		Handler:     a.prefsCommand,
+	})
+}
+
+// prefsCommand implements /prefs.
+func (a *Agent) prefsCommand(ctx context.Context, sessionID, args string) (string, error) {
+	owner := prefsKey(ctx, sessionID)
+	current := a.preferences(ctx, sessionID)
+
+	if args == "" {
+		return "Your preferences:\n" + current.String() +
+			"\n\nChange with /prefs <language|formality|emoji|length> <value>, or /prefs reset.", nil
+	}
+
+	if strings.EqualFold(args, "reset") {
+		if err := a.prefs.Set(owner, Preferences{}); err != nil {
+			return "", err
+		}
+		if err := a.prefs.Set(sessionID, Preferences{}); err != nil {
+			return "", err
+		}
+		return "Preferences reset to defaults.", nil
+	}
+
+	key, value, _ := strings.Cut(args, " ")
+	if strings.TrimSpace(value) == "" {
+		return "Usage: /prefs <language|formality|emoji|length> <value>", nil
+	}
+	if err := current.Set(key, value); err != nil {
+		return err.Error(), nil
+	}
+	if err := a.prefs.Set(owner, current); err != nil {
+		return "", err
+	}
+	return "Preferences updated:\n" + current.String(), nil
+}
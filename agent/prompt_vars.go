@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// promptTemplate parses a system prompt as a Go template. Prompts without
+// actions are left alone so literal text is never misread.
+func promptTemplate(prompt string) (*template.Template, error) {
+	if !strings.Contains(prompt, "{{") {
+		return nil, nil
+	}
+	t, err := template.New("system_prompt").Option("missingkey=zero").Parse(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("parse system prompt template: %w", err)
+	}
+	return t, nil
+}
+
+// renderPrompt fills Go template placeholders in a system prompt with
+// per-request values: {{.Date}}, {{.Time}}, {{.Weekday}}, {{.Timezone}},
+// {{.Channel}}, {{.SessionID}}, and {{.UserName}}, plus the configured
+// prompt variables. On error the prompt is used as written.
+func (a *Agent) renderPrompt(ctx context.Context, sessionID, prompt string) string {
+	t, err := promptTemplate(prompt)
+	if err != nil || t == nil {
+		if err != nil {
+			a.logger.WarnContext(ctx, "system prompt template invalid", "error", err)
+		}
+		return prompt
+	}
+
+	loc := time.Local
+	now := time.Now()
+	if a.clock != nil {
+		loc = a.clock.Location(TimezoneKeys(ctx)...)
+		now = a.clock.Now(loc)
+	}
+	now = now.In(loc)
+
+	a.mu.RLock()
+	data := make(map[string]string, len(a.config.PromptVars)+7)
+	maps.Copy(data, a.config.PromptVars)
+	a.mu.RUnlock()
+	data["Date"] = now.Format(time.DateOnly)
+	data["Time"] = now.Format("15:04")
+	data["Weekday"] = now.Weekday().String()
+	data["Timezone"] = loc.String()
+	data["Channel"] = ChannelFromSession(sessionID)
+	data["SessionID"] = sessionID
+	data["UserName"] = userName(ctx)
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		a.logger.WarnContext(ctx, "render system prompt", "error", err)
+		return prompt
+	}
+	return sb.String()
+}
+
+// userName returns the sender's display name, falling back to the group
+// chat speaker.
+func userName(ctx context.Context) string {
+	if name := SenderNameFromContext(ctx); name != "" {
+		return name
+	}
+	speaker, _ := SpeakerFromContext(ctx)
+	return speaker
+}
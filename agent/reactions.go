@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxElaborationQuote limits how much of the last reply an elaboration
+// request quotes.
+const maxElaborationQuote = 4000
+
+// replyLog keeps each session's latest reply.
+type replyLog struct {
+	replies map[string]string
+	mu      sync.RWMutex
+}
+
+// newReplyLog creates an empty reply log.
+func newReplyLog() *replyLog {
+	return &replyLog{replies: make(map[string]string)}
+}
+
+// note records the session's latest reply.
+func (l *replyLog) note(sessionID, reply string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.replies[sessionID] = reply
+}
+
+// forget drops the session's latest reply.
+func (l *replyLog) forget(sessionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.replies, sessionID)
+}
+
+// last returns the session's latest reply.
+func (l *replyLog) last(sessionID string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.replies[sessionID]
+}
+
+// ElaborationPrompt returns a message asking the agent to expand on its
+// latest reply in a session, as when the user reacts to it with a
+// question mark. It reports false if the session has no reply yet.
+func (a *Agent) ElaborationPrompt(sessionID string) (string, bool) {
+	reply := a.replies.last(sessionID)
+	if reply == "" {
+		return "", false
+	}
+	return fmt.Sprintf("I didn't fully follow your last answer. Please explain it in more detail, with an example if it helps.\n\nYour last answer:\n%s", truncate(reply, maxElaborationQuote)), true
+}
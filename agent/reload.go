@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"github.com/plexusone/omniagent/skills"
+)
+
+// RuntimeConfig holds the agent settings a config reload can change while
+// the agent runs.
+type RuntimeConfig struct {
+	SystemPrompt string
+	Temperature  float64
+	PromptVars   map[string]string
+	Channels     map[string]ChannelConfig
+}
+
+// Reconfigure applies reloaded settings to messages processed from now on.
+// Messages already being processed finish with the previous settings.
+// Personas keep the settings they were created with.
+func (a *Agent) Reconfigure(rc RuntimeConfig) error {
+	if _, err := promptTemplate(rc.SystemPrompt); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.config.SystemPrompt = rc.SystemPrompt
+	a.config.Temperature = rc.Temperature
+	a.config.PromptVars = rc.PromptVars
+	a.config.Channels = rc.Channels
+	return nil
+}
+
+// loadedSkills returns the skills in use.
+func (a *Agent) loadedSkills() []*skills.Skill {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.skills
+}
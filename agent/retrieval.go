@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/plexusone/omniagent/flags"
+)
+
+// Retriever finds context relevant to a message, such as what is
+// remembered about the user, to add to the system prompt.
+type Retriever interface {
+	// Retrieve returns a prompt section for content, or "" if nothing is
+	// relevant. ctx carries the session and contact.
+	Retrieve(ctx context.Context, sessionID, content string) (string, error)
+}
+
+// SetRetriever adds the retriever's context to the system prompt of every
+// request. Retrieval errors are logged and do not fail the request.
+func (a *Agent) SetRetriever(r Retriever) {
+	a.retriever = r
+}
+
+// retrievedPrompt appends retrieved context to the system prompt.
+func (a *Agent) retrievedPrompt(ctx context.Context, sessionID, content, prompt string) string {
+	if a.retriever == nil || !a.flags.Enabled(flags.Memory) {
+		return prompt
+	}
+	section, err := a.retriever.Retrieve(ctx, sessionID, content)
+	if err != nil {
+		a.logger.Warn("context retrieval failed", "session", sessionID, "error", err)
+		return prompt
+	}
+	if section == "" {
+		return prompt
+	}
+	if prompt == "" {
+		return section
+	}
+	return prompt + "\n\n" + section
+}
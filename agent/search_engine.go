@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/plexusone/omniserp"
+
+	"github.com/plexusone/omniagent/sandbox"
+)
+
+// Search engine names.
+const (
+	SearchEngineSerper  = "serper"
+	SearchEngineSerpAPI = "serpapi"
+)
+
+// Search API endpoints.
+const (
+	serperBaseURL  = "https://google.serper.dev"
+	serpAPIBaseURL = "https://serpapi.com/search.json"
+)
+
+// searchEngine implements omniserp.Engine for Serper and SerpAPI, sending
+// every request through sandbox.HostFunctions so search obeys the same
+// egress policy (allowed hosts, response size, timeout) as http_fetch.
+type searchEngine struct {
+	name    string
+	apiKey  string
+	baseURL string
+	host    *sandbox.HostFunctions
+}
+
+// newSearchEngine selects an engine by name, or with an empty name the
+// SEARCH_ENGINE environment variable, then whichever API key is set.
+func newSearchEngine(name string, host *sandbox.HostFunctions) (*searchEngine, error) {
+	if name == "" {
+		name = os.Getenv("SEARCH_ENGINE")
+	}
+	if name == "" {
+		name = SearchEngineSerper
+		if os.Getenv("SERPER_API_KEY") == "" && os.Getenv("SERPAPI_API_KEY") != "" {
+			name = SearchEngineSerpAPI
+		}
+	}
+
+	e := &searchEngine{name: name, host: host}
+	switch name {
+	case SearchEngineSerper:
+		e.apiKey, e.baseURL = os.Getenv("SERPER_API_KEY"), serperBaseURL
+	case SearchEngineSerpAPI:
+		e.apiKey, e.baseURL = os.Getenv("SERPAPI_API_KEY"), serpAPIBaseURL
+	default:
+		return nil, fmt.Errorf("unknown search engine %q", name)
+	}
+	if e.apiKey == "" {
+		return nil, fmt.Errorf("%s: no API key; set %s_API_KEY", name, strings.ToUpper(name))
+	}
+	return e, nil
+}
+
+// GetName returns the engine name, which selects the result normalizer.
+func (e *searchEngine) GetName() string { return e.name }
+
+// GetVersion returns the engine version.
+func (e *searchEngine) GetVersion() string { return "1.0.0" }
+
+// GetSupportedTools returns the supported operations.
+func (e *searchEngine) GetSupportedTools() []string {
+	tools := []string{
+		"google_search",
+		"google_search_news",
+		"google_search_images",
+		"google_search_videos",
+		"google_search_places",
+		"google_search_maps",
+		"google_search_reviews",
+		"google_search_shopping",
+		"google_search_scholar",
+		"google_search_autocomplete",
+	}
+	if e.name == SearchEngineSerper {
+		tools = append(tools, "google_search_lens", "webpage_scrape")
+	}
+	return tools
+}
+
+// Search performs a general web search.
+func (e *searchEngine) Search(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	return e.request(ctx, "search", "google", searchParams(p))
+}
+
+// SearchNews performs a news search.
+func (e *searchEngine) SearchNews(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	return e.request(ctx, "news", "google_news", searchParams(p))
+}
+
+// SearchImages performs an image search.
+func (e *searchEngine) SearchImages(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	return e.request(ctx, "images", "google_images", searchParams(p))
+}
+
+// SearchVideos performs a video search.
+func (e *searchEngine) SearchVideos(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	return e.request(ctx, "videos", "google_videos", searchParams(p))
+}
+
+// SearchPlaces performs a places search.
+func (e *searchEngine) SearchPlaces(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	params := searchParams(p)
+	if e.name == SearchEngineSerpAPI {
+		params["type"] = "search"
+	}
+	return e.request(ctx, "places", "google_maps", params)
+}
+
+// SearchMaps performs a maps search.
+func (e *searchEngine) SearchMaps(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	return e.request(ctx, "maps", "google_maps", searchParams(p))
+}
+
+// SearchReviews performs a reviews search.
+func (e *searchEngine) SearchReviews(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	params := searchParams(p)
+	if e.name == SearchEngineSerpAPI {
+		params["q"] = p.Query + " reviews"
+	}
+	return e.request(ctx, "reviews", "google", params)
+}
+
+// SearchShopping performs a shopping search.
+func (e *searchEngine) SearchShopping(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	return e.request(ctx, "shopping", "google_shopping", searchParams(p))
+}
+
+// SearchScholar performs a scholar search.
+func (e *searchEngine) SearchScholar(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	params := searchParams(p)
+	delete(params, "location")
+	delete(params, "gl")
+	return e.request(ctx, "scholar", "google_scholar", params)
+}
+
+// SearchLens performs a visual search, on Serper only.
+func (e *searchEngine) SearchLens(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	if e.name != SearchEngineSerper {
+		return nil, fmt.Errorf("google_search_lens is not supported by %s", e.name)
+	}
+	params := searchParams(p)
+	delete(params, "location")
+	return e.request(ctx, "lens", "", params)
+}
+
+// SearchAutocomplete gets search suggestions.
+func (e *searchEngine) SearchAutocomplete(ctx context.Context, p omniserp.SearchParams) (*omniserp.SearchResult, error) {
+	params := searchParams(p)
+	delete(params, "location")
+	delete(params, "num")
+	return e.request(ctx, "autocomplete", "google_autocomplete", params)
+}
+
+// ScrapeWebpage scrapes a page through Serper. Other engines would fetch
+// the page directly, which is what http_fetch is for.
+func (e *searchEngine) ScrapeWebpage(ctx context.Context, p omniserp.ScrapeParams) (*omniserp.SearchResult, error) {
+	if e.name != SearchEngineSerper {
+		return nil, fmt.Errorf("webpage_scrape is not supported by %s; use http_fetch", e.name)
+	}
+	if _, err := url.Parse(p.URL); err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	return e.request(ctx, "scrape", "", map[string]any{"url": p.URL})
+}
+
+// searchParams converts SearchParams to API parameters.
+func searchParams(p omniserp.SearchParams) map[string]any {
+	params := map[string]any{"q": p.Query}
+	if p.Location != "" {
+		params["location"] = p.Location
+	}
+	if p.Language != "" {
+		params["hl"] = p.Language
+	}
+	if p.Country != "" {
+		params["gl"] = p.Country
+	}
+	if p.NumResults > 0 {
+		params["num"] = p.NumResults
+	}
+	return params
+}
+
+// request calls the search API: Serper takes a JSON POST to
+// /<endpoint>, SerpAPI a GET with the engine and key in the query.
+func (e *searchEngine) request(ctx context.Context, endpoint, serpAPIEngine string, params map[string]any) (*omniserp.SearchResult, error) {
+	var (
+		method  = http.MethodGet
+		target  string
+		body    []byte
+		headers map[string]string
+	)
+	switch e.name {
+	case SearchEngineSerper:
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+		method, target, body = http.MethodPost, e.baseURL+"/"+endpoint, data
+		headers = map[string]string{"X-API-KEY": e.apiKey, "Content-Type": "application/json"}
+	default:
+		q := url.Values{"engine": {serpAPIEngine}, "api_key": {e.apiKey}}
+		for k, v := range params {
+			q.Set(k, fmt.Sprint(v))
+		}
+		target = e.baseURL + "?" + q.Encode()
+	}
+
+	data, status, err := e.host.HTTPFetch(ctx, method, target, body, headers)
+	if err != nil {
+		// SerpAPI's key is in the URL, which request errors include
+		return nil, errors.New(strings.ReplaceAll(err.Error(), e.apiKey, "REDACTED"))
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s API error (status %d): %s", e.name, status, truncate(string(data), 200))
+	}
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("decode %s response: %w", e.name, err)
+	}
+	return &omniserp.SearchResult{Data: result, Raw: string(data)}, nil
+}
+
+var _ omniserp.Engine = (*searchEngine)(nil)
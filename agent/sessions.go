@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SessionState is the conversation state the agent keeps for a session.
+type SessionState struct {
+	ID          string         `json:"id"`
+	LastReply   string         `json:"last_reply,omitempty"`
+	Pins        []Pin          `json:"pins,omitempty"`
+	Preferences *Preferences   `json:"preferences,omitempty"`
+	Context     map[string]any `json:"context,omitempty"` // Stored message metadata
+}
+
+// Sessions returns the state of every session the agent holds any for,
+// sorted by ID.
+func (a *Agent) Sessions() []SessionState {
+	ids := make(map[string]bool)
+	a.replies.mu.RLock()
+	for id := range a.replies.replies {
+		ids[id] = true
+	}
+	a.replies.mu.RUnlock()
+	if a.pins != nil {
+		a.pins.mu.RLock()
+		for id := range a.pins.pins {
+			ids[id] = true
+		}
+		a.pins.mu.RUnlock()
+	}
+	if a.prefs != nil {
+		a.prefs.mu.RLock()
+		for id := range a.prefs.prefs {
+			// Skip the preferences of senders, which span sessions
+			if !strings.HasPrefix(id, "person:") && !strings.HasPrefix(id, "contact:") {
+				ids[id] = true
+			}
+		}
+		a.prefs.mu.RUnlock()
+	}
+	if a.contextVars != nil {
+		a.contextVars.mu.Lock()
+		for id := range a.contextVars.sessions {
+			ids[id] = true
+		}
+		a.contextVars.mu.Unlock()
+	}
+
+	out := make([]SessionState, 0, len(ids))
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		state, _ := a.Session(id)
+		out = append(out, state)
+	}
+	return out
+}
+
+// Session returns a session's state, reporting false if the agent holds
+// none.
+func (a *Agent) Session(sessionID string) (SessionState, bool) {
+	state := SessionState{ID: sessionID, LastReply: a.replies.last(sessionID)}
+	if a.pins != nil {
+		state.Pins = a.pins.List(sessionID)
+	}
+	if a.prefs != nil {
+		if p := a.prefs.Get(sessionID); !p.IsZero() {
+			state.Preferences = &p
+		}
+	}
+	if a.contextVars != nil {
+		a.contextVars.mu.Lock()
+		if stored := a.contextVars.sessions[sessionID]; len(stored) > 0 {
+			state.Context = maps.Clone(stored)
+		}
+		a.contextVars.mu.Unlock()
+	}
+	ok := state.LastReply != "" || len(state.Pins) > 0 || state.Preferences != nil || state.Context != nil
+	return state, ok
+}
+
+// ClearSession resets a session's conversation: its last reply, pins, and
+// stored context. The user's preferences are kept.
+func (a *Agent) ClearSession(sessionID string) error {
+	a.replies.forget(sessionID)
+	if a.contextVars != nil {
+		a.contextVars.clear(sessionID)
+	}
+	if a.pins != nil {
+		if err := a.pins.Clear(sessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteSession removes all of a session's state, including preferences.
+func (a *Agent) DeleteSession(sessionID string) error {
+	if err := a.ClearSession(sessionID); err != nil {
+		return err
+	}
+	if a.prefs != nil {
+		return a.prefs.Set(sessionID, Preferences{})
+	}
+	return nil
+}
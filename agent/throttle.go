@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omnillm"
+	"github.com/plexusone/omnillm/provider"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/plexusone/omniagent/ratelimit"
+)
+
+// Retries of model requests the provider rejects for rate limits.
+const (
+	maxRateLimitRetries = 3
+	rateLimitBackoff    = 2 * time.Second
+)
+
+// SetThrottle paces model requests to provider rate limits. Requests the
+// provider still rejects for rate limits are retried with backoff instead
+// of failing.
+func (a *Agent) SetThrottle(t *ratelimit.Throttle) {
+	a.throttle = t
+}
+
+// complete sends a chat completion request through the throttle, in a
+// span covering any retries and fallbacks. It returns the provider that
+// answered and sets req.Model to the model that did.
+func (a *Agent) complete(ctx context.Context, req *provider.ChatCompletionRequest) (resp *provider.ChatCompletionResponse, servedBy string, err error) {
+	ctx, span := tracer.Start(ctx, "llm.chat", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		systemKey.String(a.config.Provider),
+		modelKey.String(req.Model),
+		attribute.Int("omniagent.tools", len(req.Tools)),
+	))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(
+				systemKey.String(servedBy),
+				modelKey.String(req.Model),
+				inputTokensKey.Int(resp.Usage.PromptTokens),
+				outputTokensKey.Int(resp.Usage.CompletionTokens),
+			)
+			if len(resp.Choices) > 0 && resp.Choices[0].FinishReason != nil {
+				span.SetAttributes(finishKey.StringSlice([]string{*resp.Choices[0].FinishReason}))
+			}
+		}
+		endSpan(span, err)
+	}()
+	return a.completeFallback(ctx, req)
+}
+
+// completeThrottled paces the request to the provider's rate limits.
+func (a *Agent) completeThrottled(ctx context.Context, target *llmTarget, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, error) {
+	if a.throttle == nil {
+		return target.client.CreateChatCompletion(ctx, req)
+	}
+
+	name := target.provider
+	estimate := estimateTokens(req)
+	for attempt := 0; ; attempt++ {
+		if err := a.throttle.Wait(ctx, name, SessionFromContext(ctx), estimate); err != nil {
+			return nil, err
+		}
+		resp, err := target.client.CreateChatCompletion(ctx, req)
+		if err == nil {
+			a.throttle.Used(name, estimate, resp.Usage.PromptTokens+resp.Usage.CompletionTokens)
+			return resp, nil
+		}
+		a.throttle.Used(name, estimate, 0)
+		if !isRateLimited(err) {
+			return nil, err
+		}
+		backoff := rateLimitBackoff << attempt
+		a.throttle.RateLimited(name, backoff)
+		if attempt == maxRateLimitRetries {
+			a.logger.Warn("model rate limited, giving up", "provider", name, "error", err)
+			return nil, fmt.Errorf("%w (%s rate limited)", ratelimit.ErrThrottled, name)
+		}
+		a.logger.Warn("model rate limited, retrying", "provider", name, "in", backoff)
+	}
+}
+
+// isRateLimited reports whether err is a provider rate limit (HTTP 429).
+func isRateLimited(err error) bool {
+	var apiErr *omnillm.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 429
+	}
+	if errors.Is(err, omnillm.ErrRateLimitExceeded) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+}
+
+// estimateTokens roughly estimates the tokens a request uses, at four
+// characters per token plus the completion limit.
+func estimateTokens(req *provider.ChatCompletionRequest) int {
+	chars := 0
+	for _, m := range req.Messages {
+		chars += len(m.Content)
+	}
+	tokens := chars / 4
+	if req.MaxTokens != nil {
+		tokens += *req.MaxTokens
+	}
+	return tokens
+}
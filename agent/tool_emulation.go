@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/plexusone/omnillm/provider"
+)
+
+// Tool call modes control how tools are offered to the model.
+const (
+	// ToolCallModeAuto uses native function calling when the provider
+	// supports it and falls back to prompt emulation otherwise.
+	ToolCallModeAuto = "auto"
+	// ToolCallModeNative always sends tool definitions to the provider.
+	ToolCallModeNative = "native"
+	// ToolCallModePrompt describes tools in the system prompt and parses
+	// TOOL: blocks or JSON tool calls from the model's text output.
+	ToolCallModePrompt = "prompt"
+)
+
+// emulatedCall is a tool call parsed from model text output. Err is set
+// when the call's arguments could not be parsed; the tool is not run and
+// the error goes back to the model instead.
+type emulatedCall struct {
+	Name      string
+	Arguments json.RawMessage
+	Err       error
+}
+
+// toolLineRe matches the start of an emulated tool call block.
+var toolLineRe = regexp.MustCompile(`(?m)^[ \t]*(?:\*\*)?TOOL:(?:\*\*)?[ \t]*([A-Za-z0-9_.\-]+)[ \t]*$`)
+
+// renderToolPrompt describes the tools and the TOOL: call format for models
+// without native function calling.
+func renderToolPrompt(tools []provider.Tool) string {
+	var sb strings.Builder
+	sb.WriteString("## Tools\n\n")
+	sb.WriteString("You can call tools. To call a tool, reply with ONLY a block in this exact format and nothing else:\n\n")
+	sb.WriteString("TOOL: <tool_name>\nARGS: {\"param\": \"value\"}\n\n")
+	sb.WriteString("ARGS must be a single JSON object matching the tool's parameters. ")
+	sb.WriteString("The tool result will be sent back to you in a message starting with TOOL RESULT. ")
+	sb.WriteString("When you have enough information, reply to the user normally without a TOOL block.\n\n")
+	sb.WriteString("Available tools:\n")
+
+	sorted := make([]provider.Tool, len(tools))
+	copy(sorted, tools)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Function.Name < sorted[j].Function.Name })
+
+	for _, t := range sorted {
+		params, _ := json.Marshal(t.Function.Parameters)
+		fmt.Fprintf(&sb, "\n### %s\n%s\nParameters (JSON schema): %s\n", t.Function.Name, t.Function.Description, params)
+	}
+	return sb.String()
+}
+
+// parseToolCalls extracts tool calls from model output: TOOL: blocks, or
+// failing those, a reply that is nothing but JSON calls to known tools, as
+// many models are trained to emit. JSON may be fenced or wrapped in
+// <tool_call> tags, but JSON within prose, such as an example the model
+// quotes, is not a call.
+func parseToolCalls(content string, tools []provider.Tool) []emulatedCall {
+	if calls := parseToolBlocks(content); len(calls) > 0 {
+		return calls
+	}
+
+	known := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		known[t.Function.Name] = true
+	}
+	return parseJSONCalls(unwrapCall(content), known)
+}
+
+// parseToolBlocks extracts TOOL: blocks from model output.
+func parseToolBlocks(content string) []emulatedCall {
+	matches := toolLineRe.FindAllStringSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var calls []emulatedCall
+	for i, m := range matches {
+		name := content[m[2]:m[3]]
+
+		end := len(content)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		rest := strings.TrimSpace(content[m[1]:end])
+
+		call := emulatedCall{Name: name, Arguments: json.RawMessage("{}")}
+		if after, ok := strings.CutPrefix(strings.TrimLeft(rest, "*"), "ARGS:"); ok {
+			after = strings.TrimLeft(after, "* \t\r\n")
+			after = strings.TrimPrefix(after, "```json")
+			after = strings.TrimPrefix(after, "```")
+			var raw json.RawMessage
+			err := json.NewDecoder(strings.NewReader(after)).Decode(&raw)
+			if err == nil {
+				var ok bool
+				if call.Arguments, ok = objectArgs(raw); !ok {
+					err = errors.New("not a JSON object")
+				}
+			}
+			if err != nil {
+				call.Err = fmt.Errorf("invalid ARGS for %s, not called: %v; ARGS was %.200q", name, err, strings.TrimSpace(after))
+			}
+		}
+
+		calls = append(calls, call)
+	}
+
+	return calls
+}
+
+// unwrapCall strips a code fence or <tool_call> tags enclosing the whole
+// of content.
+func unwrapCall(content string) string {
+	content = strings.TrimSpace(content)
+	if body, ok := strings.CutPrefix(content, "```"); ok {
+		if body, ok = strings.CutSuffix(body, "```"); ok {
+			// Drop the fence's language tag
+			if _, after, found := strings.Cut(body, "\n"); found {
+				body = after
+			}
+			return strings.TrimSpace(body)
+		}
+	}
+	if strings.HasPrefix(content, "<tool_call>") {
+		content = strings.ReplaceAll(content, "<tool_call>", "")
+		content = strings.ReplaceAll(content, "</tool_call>", "")
+	}
+	return strings.TrimSpace(content)
+}
+
+// jsonToolCall is the shape of tool calls models emit as JSON, such as
+// {"name": "web_search", "arguments": {"query": "..."}} or the OpenAI
+// {"function": {"name": ..., "arguments": "<json string>"}} form.
+type jsonToolCall struct {
+	Name       string          `json:"name"`
+	Tool       string          `json:"tool"`
+	Function   *jsonToolCall   `json:"function"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Parameters json.RawMessage `json:"parameters"`
+	Args       json.RawMessage `json:"args"`
+}
+
+// parseJSONCalls parses content made up entirely of JSON tool calls, as
+// objects or arrays of objects. It returns nil unless every value is a
+// call to a known tool.
+func parseJSONCalls(content string, known map[string]bool) []emulatedCall {
+	if !strings.HasPrefix(content, "{") && !strings.HasPrefix(content, "[") {
+		return nil
+	}
+
+	var calls []emulatedCall
+	dec := json.NewDecoder(strings.NewReader(content))
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil
+		}
+		objects := []json.RawMessage{raw}
+		if bytes.HasPrefix(raw, []byte("[")) {
+			objects = nil
+			if err := json.Unmarshal(raw, &objects); err != nil {
+				return nil
+			}
+		}
+		for _, obj := range objects {
+			call, ok := jsonCall(obj, known)
+			if !ok {
+				return nil
+			}
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// jsonCall interprets a JSON object as a call to a known tool.
+func jsonCall(raw json.RawMessage, known map[string]bool) (emulatedCall, bool) {
+	var c jsonToolCall
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return emulatedCall{}, false
+	}
+	if c.Function != nil {
+		c = *c.Function
+	}
+	name := c.Name
+	if name == "" {
+		name = c.Tool
+	}
+	if !known[name] {
+		return emulatedCall{}, false
+	}
+
+	args := json.RawMessage("{}")
+	for _, a := range []json.RawMessage{c.Arguments, c.Parameters, c.Args} {
+		if len(bytes.TrimSpace(a)) > 0 {
+			args = callArgs(a)
+			break
+		}
+	}
+	return emulatedCall{Name: name, Arguments: args}, true
+}
+
+// callArgs returns the arguments of a call as a JSON object, decoding a
+// JSON-encoded string as in the OpenAI format. Anything else yields {}.
+func callArgs(raw json.RawMessage) json.RawMessage {
+	a, _ := objectArgs(raw)
+	return a
+}
+
+// objectArgs is callArgs, also reporting whether raw held an object.
+func objectArgs(raw json.RawMessage) (json.RawMessage, bool) {
+	a := bytes.TrimSpace(raw)
+	var s string
+	if json.Unmarshal(a, &s) == nil {
+		a = json.RawMessage(strings.TrimSpace(s))
+	}
+	if bytes.HasPrefix(a, []byte("{")) && json.Valid(a) {
+		return a, true
+	}
+	return json.RawMessage("{}"), false
+}
+
+// toolsUnsupported reports whether err is a server refusing tool definitions,
+// as Ollama, vLLM and llama.cpp do for models or launches without function
+// calling.
+func toolsUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	if !strings.Contains(msg, "tool") && !strings.Contains(msg, "function") {
+		return false
+	}
+	for _, phrase := range []string{"not support", "unsupported", "requires --", "not enabled"} {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatToolResult renders an emulated tool result for the next prompt.
+func formatToolResult(name, result string) string {
+	return fmt.Sprintf("TOOL RESULT (%s):\n%s", name, result)
+}
+
+// validToolCallMode reports whether mode is a known tool call mode.
+func validToolCallMode(mode string) bool {
+	switch mode {
+	case ToolCallModeAuto, ToolCallModeNative, ToolCallModePrompt:
+		return true
+	default:
+		return false
+	}
+}
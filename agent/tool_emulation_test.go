@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omnillm/provider"
+)
+
+func testTools(names ...string) []provider.Tool {
+	var tools []provider.Tool
+	for _, name := range names {
+		tools = append(tools, provider.Tool{
+			Type:     "function",
+			Function: provider.ToolSpec{Name: name, Description: "The " + name + " tool"},
+		})
+	}
+	return tools
+}
+
+func TestRenderToolPrompt(t *testing.T) {
+	tools := testTools("web_search", "time")
+	tools[0].Function.Parameters = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"query": map[string]any{"type": "string"}},
+	}
+	prompt := renderToolPrompt(tools)
+
+	for _, want := range []string{
+		"TOOL: <tool_name>\nARGS: {",
+		"TOOL RESULT",
+		"### web_search\nThe web_search tool\n",
+		`"properties":{"query":{"type":"string"}}`,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+	// Tools are listed by name, so the prompt is stable across requests
+	if strings.Index(prompt, "### time") > strings.Index(prompt, "### web_search") {
+		t.Error("tools not sorted by name")
+	}
+	if renderToolPrompt(testTools("time", "web_search")) != renderToolPrompt(testTools("web_search", "time")) {
+		t.Error("prompt depends on tool order")
+	}
+
+	messages := withToolPrompt([]provider.Message{{Role: provider.RoleUser, Content: "hi"}}, prompt)
+	if len(messages) != 2 || messages[0].Role != provider.RoleSystem || messages[0].Content != prompt {
+		t.Errorf("withToolPrompt() = %v, want the prompt first", messages)
+	}
+}
+
+func TestParseToolCalls(t *testing.T) {
+	tools := testTools("web_search", "time")
+	type call struct{ name, args string }
+	tests := []struct {
+		name    string
+		content string
+		want    []call
+	}{
+		{"plain reply", "The capital of France is Paris.", nil},
+		{
+			"tool block",
+			"TOOL: web_search\nARGS: {\"query\": \"go 1.25\"}",
+			[]call{{"web_search", `{"query": "go 1.25"}`}},
+		},
+		{
+			"bold tool block with fenced args",
+			"**TOOL:** time\n**ARGS:**\n```json\n{\"zone\": \"UTC\"}\n```",
+			[]call{{"time", `{"zone": "UTC"}`}},
+		},
+		{"tool block without args", "TOOL: time", []call{{"time", `{}`}}},
+		{
+			"several tool blocks",
+			"TOOL: time\nARGS: {}\nTOOL: web_search\nARGS: {\"query\": \"x\"}",
+			[]call{{"time", `{}`}, {"web_search", `{"query": "x"}`}},
+		},
+		{
+			"bare json",
+			`{"name": "web_search", "arguments": {"query": "weather"}}`,
+			[]call{{"web_search", `{"query": "weather"}`}},
+		},
+		{
+			"fenced json",
+			"```json\n{\"tool\": \"time\", \"parameters\": {\"zone\": \"UTC\"}}\n```",
+			[]call{{"time", `{"zone": "UTC"}`}},
+		},
+		{
+			"tool_call tags",
+			"<tool_call>\n{\"name\": \"time\", \"arguments\": {}}\n</tool_call>\n<tool_call>\n{\"name\": \"web_search\", \"arguments\": {\"query\": \"x\"}}\n</tool_call>",
+			[]call{{"time", `{}`}, {"web_search", `{"query": "x"}`}},
+		},
+		{
+			"openai function form with string arguments",
+			`{"type": "function", "function": {"name": "web_search", "arguments": "{\"query\": \"q\"}"}}`,
+			[]call{{"web_search", `{"query": "q"}`}},
+		},
+		{
+			"array of calls",
+			`[{"name": "time"}, {"name": "web_search", "args": {"query": "q"}}]`,
+			[]call{{"time", `{}`}, {"web_search", `{"query": "q"}`}},
+		},
+		{
+			"prose quoting json",
+			`To search, a tool call looks like {"name": "web_search", "arguments": {"query": "x"}}.`,
+			nil,
+		},
+		{
+			"prose before fenced json",
+			"Here is an example:\n```json\n{\"name\": \"web_search\", \"arguments\": {}}\n```",
+			nil,
+		},
+		{
+			"json then prose",
+			`{"name": "time", "arguments": {}} and then I will answer.`,
+			nil,
+		},
+		{"unknown tool", `{"name": "shell", "arguments": {"command": "rm -rf /"}}`, nil},
+		{
+			"known and unknown tools",
+			`[{"name": "time"}, {"name": "shell"}]`,
+			nil,
+		},
+		{"json that is not a call", `{"answer": 42}`, nil},
+		{
+			"malformed arguments",
+			`{"name": "time", "arguments": "not json"}`,
+			[]call{{"time", `{}`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := parseToolCalls(tt.content, tools)
+			var got []call
+			for _, c := range calls {
+				got = append(got, call{c.Name, string(c.Arguments)})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseToolCalls() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].name != tt.want[i].name || !jsonEqual(t, got[i].args, tt.want[i].args) {
+					t.Errorf("call %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseToolBlocksBadArgs(t *testing.T) {
+	for _, content := range []string{
+		"TOOL: web_search\nARGS: {\"query\": go}",
+		"TOOL: web_search\nARGS: [\"go\"]",
+	} {
+		calls := parseToolCalls(content, testTools("web_search"))
+		if len(calls) != 1 || calls[0].Err == nil || !strings.Contains(calls[0].Err.Error(), "ARGS") {
+			t.Errorf("parseToolCalls(%q) = %+v, want a call with an ARGS error", content, calls)
+		}
+	}
+}
+
+func TestParseToolCallsLongReply(t *testing.T) {
+	// A long reply full of braces must not take quadratic time to reject
+	content := strings.Repeat(`{"a": {"b": [1, 2, {"c": "{{{"}]}} `, 20000)
+	if calls := parseToolCalls(content, testTools("time")); len(calls) != 0 {
+		t.Errorf("parseToolCalls() = %v, want none", calls)
+	}
+}
+
+func jsonEqual(t *testing.T, a, b string) bool {
+	t.Helper()
+	var x, y any
+	if err := json.Unmarshal([]byte(a), &x); err != nil {
+		t.Fatalf("invalid JSON %q: %v", a, err)
+	}
+	if err := json.Unmarshal([]byte(b), &y); err != nil {
+		t.Fatalf("invalid JSON %q: %v", b, err)
+	}
+	xa, _ := json.Marshal(x)
+	ya, _ := json.Marshal(y)
+	return string(xa) == string(ya)
+}
@@ -3,9 +3,15 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/plexusone/omnillm/provider"
+
+	"github.com/plexusone/omniagent/sandbox"
 )
 
 // Tool represents an agent tool that can be invoked.
@@ -20,81 +26,300 @@ type Tool interface {
 	Execute(ctx context.Context, args json.RawMessage) (string, error)
 }
 
-// ToolRegistry manages available tools.
+// ExecTool is implemented by tools that execute commands. When sandboxing is
+// configured, the tool routes execution through the given executor instead
+// of running on the host.
+type ExecTool interface {
+	Tool
+	SetExecutor(executor sandbox.Executor)
+}
+
+// SourceBuiltin is the source of tools registered without one.
+const SourceBuiltin = "builtin"
+
+// ToolID returns the namespaced ID of a tool, "source/name".
+func ToolID(source, name string) string {
+	return source + "/" + name
+}
+
+// ToolRegistry manages available tools. Each tool is registered from a
+// source, such as "builtin" or "mcp:github", and identified by its
+// namespaced ID. The model sees plain names: when sources register the
+// same name, the source listed first in the precedence wins and the others
+// stay reachable by ID or through an alias.
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools      map[string]*registeredTool // Keyed by ID
+	exposed    map[string]*registeredTool // Keyed by the name the model sees
+	aliases    map[string]string          // Alias to tool ID
+	precedence []string
+	mock       ToolMock
+	auditor    ToolAuditFunc
+	mu         sync.RWMutex
+}
+
+// ToolAuditFunc records a tool execution: its exposed name or ID,
+// arguments, result, error, and start time.
+type ToolAuditFunc func(ctx context.Context, name string, args json.RawMessage, result string, err error, started time.Time)
+
+// ToolMock intercepts tool calls, for example to answer them from fixture
+// files in tests. Call receives the tool's name and arguments and a
+// function that runs the real tool.
+type ToolMock interface {
+	Call(ctx context.Context, name string, args json.RawMessage, run func(context.Context, json.RawMessage) (string, error)) (string, error)
+}
+
+// registeredTool is a tool and the source that registered it.
+type registeredTool struct {
+	id     string
+	source string
+	tool   Tool
 }
 
 // NewToolRegistry creates a new tool registry.
 func NewToolRegistry() *ToolRegistry {
 	return &ToolRegistry{
-		tools: make(map[string]Tool),
+		tools:   make(map[string]*registeredTool),
+		exposed: make(map[string]*registeredTool),
+		aliases: make(map[string]string),
 	}
 }
 
-// Register adds a tool to the registry.
-func (r *ToolRegistry) Register(tool Tool) {
+// SetPrecedence orders sources for resolving name collisions, highest
+// first. Unlisted sources rank below listed ones and tie with each other.
+func (r *ToolRegistry) SetPrecedence(sources []string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.tools[tool.Name()] = tool
+	r.precedence = sources
+	r.rebuild()
+}
+
+// rank returns a source's position in the precedence; lower wins.
+func (r *ToolRegistry) rank(source string) int {
+	if i := slices.Index(r.precedence, source); i >= 0 {
+		return i
+	}
+	return len(r.precedence)
+}
+
+// Register adds a built-in tool to the registry.
+func (r *ToolRegistry) Register(tool Tool) error {
+	return r.RegisterFrom(SourceBuiltin, tool)
 }
 
-// Unregister removes a tool from the registry.
+// RegisterFrom adds a tool from source. Registering the same ID again
+// replaces the tool. It returns a *DuplicateToolError when another source
+// already registered the name at the same precedence, or the name is
+// taken by an alias.
+func (r *ToolRegistry) RegisterFrom(source string, tool Tool) error {
+	name := tool.Name()
+	id := ToolID(source, name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if target, ok := r.aliases[name]; ok {
+		return &DuplicateToolError{Name: name, Existing: "alias for " + target, Source: source}
+	}
+	for _, rt := range r.tools {
+		if rt.id != id && rt.tool.Name() == name && r.rank(rt.source) == r.rank(source) {
+			return &DuplicateToolError{Name: name, Existing: rt.source, Source: source}
+		}
+	}
+	r.tools[id] = &registeredTool{id: id, source: source, tool: tool}
+	r.rebuild()
+	return nil
+}
+
+// Alias exposes the tool with the given ID under another name, for
+// example to keep a shadowed tool available to the model. The tool may be
+// registered later.
+func (r *ToolRegistry) Alias(alias, id string) error {
+	if !strings.Contains(id, "/") {
+		return fmt.Errorf("alias %q: target must be a tool ID (source/name), got %q", alias, id)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if target, ok := r.aliases[alias]; ok && target != id {
+		return fmt.Errorf("alias %q already points to %s", alias, target)
+	}
+	for _, rt := range r.tools {
+		if rt.tool.Name() == alias {
+			return &DuplicateToolError{Name: alias, Existing: rt.source, Source: "alias for " + id}
+		}
+	}
+	r.aliases[alias] = id
+	r.rebuild()
+	return nil
+}
+
+// rebuild recomputes the names exposed to the model. Callers hold r.mu.
+func (r *ToolRegistry) rebuild() {
+	exposed := make(map[string]*registeredTool, len(r.tools)+len(r.aliases))
+	for _, rt := range r.tools {
+		name := rt.tool.Name()
+		if cur, ok := exposed[name]; !ok || r.rank(rt.source) < r.rank(cur.source) {
+			exposed[name] = rt
+		}
+	}
+	for alias, id := range r.aliases {
+		if rt, ok := r.tools[id]; ok {
+			exposed[alias] = rt
+		}
+	}
+	r.exposed = exposed
+}
+
+// Unregister removes a tool by ID, or the tool currently exposed under
+// name.
 func (r *ToolRegistry) Unregister(name string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	delete(r.tools, name)
+	if rt, ok := r.lookup(name); ok {
+		delete(r.tools, rt.id)
+		r.rebuild()
+	}
+}
+
+// lookup finds a tool by exposed name or ID. Callers hold r.mu.
+func (r *ToolRegistry) lookup(name string) (*registeredTool, bool) {
+	if rt, ok := r.exposed[name]; ok {
+		return rt, true
+	}
+	rt, ok := r.tools[name]
+	return rt, ok
 }
 
-// Get retrieves a tool by name.
+// Get retrieves a tool by exposed name or ID.
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	tool, ok := r.tools[name]
-	return tool, ok
+	rt, ok := r.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return rt.tool, true
+}
+
+// Resolve returns the name the model sees for a tool given by exposed name
+// or ID. A tool exposed under its own name and an alias resolves to its
+// own name. Registered tools the model can't see, such as shadowed tools
+// without an alias, are not found.
+func (r *ToolRegistry) Resolve(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.exposed[name]; ok {
+		return name, true
+	}
+	rt, ok := r.tools[name]
+	if !ok {
+		return "", false
+	}
+	if r.exposed[rt.tool.Name()] == rt {
+		return rt.tool.Name(), true
+	}
+	var aliases []string
+	for alias, id := range r.aliases {
+		if id == rt.id && r.exposed[alias] == rt {
+			aliases = append(aliases, alias)
+		}
+	}
+	if len(aliases) == 0 {
+		return "", false
+	}
+	return slices.Min(aliases), true
+}
+
+// ID returns the namespaced ID of the tool exposed under name.
+func (r *ToolRegistry) ID(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rt, ok := r.lookup(name)
+	if !ok {
+		return "", false
+	}
+	return rt.id, true
 }
 
-// List returns all registered tool names.
+// List returns the tool names exposed to the model.
 func (r *ToolRegistry) List() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	names := make([]string, 0, len(r.tools))
-	for name := range r.tools {
+	names := make([]string, 0, len(r.exposed))
+	for name := range r.exposed {
 		names = append(names, name)
 	}
 	return names
 }
 
-// GetTools returns tool definitions for the LLM.
+// IDs returns the namespaced IDs of all registered tools, including
+// shadowed ones.
+func (r *ToolRegistry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.tools))
+	for id := range r.tools {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// GetTools returns tool definitions for the LLM, under their exposed
+// names.
 func (r *ToolRegistry) GetTools() []provider.Tool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	tools := make([]provider.Tool, 0, len(r.tools))
-	for _, tool := range r.tools {
+	tools := make([]provider.Tool, 0, len(r.exposed))
+	for name, rt := range r.exposed {
 		tools = append(tools, provider.Tool{
 			Type: "function",
 			Function: provider.ToolSpec{
-				Name:        tool.Name(),
-				Description: tool.Description(),
-				Parameters:  tool.Parameters(),
+				Name:        name,
+				Description: rt.tool.Description(),
+				Parameters:  rt.tool.Parameters(),
 			},
 		})
 	}
 	return tools
 }
 
-// Execute runs a tool by name with the given arguments.
-func (r *ToolRegistry) Execute(ctx context.Context, name string, args json.RawMessage) (string, error) {
+// Execute runs a tool by exposed name or ID with the given arguments, and
+// records the execution with the auditor, if any.
+func (r *ToolRegistry) Execute(ctx context.Context, name string, args json.RawMessage) (result string, err error) {
 	tool, ok := r.Get(name)
 	if !ok {
 		return "", &ToolNotFoundError{Name: name}
 	}
+	r.mu.RLock()
+	mock, auditor := r.mock, r.auditor
+	r.mu.RUnlock()
+	if auditor != nil {
+		started := time.Now()
+		defer func() { auditor(ctx, name, args, result, err, started) }()
+	}
+	if mock != nil {
+		return mock.Call(ctx, tool.Name(), args, tool.Execute)
+	}
 	return tool.Execute(ctx, args)
 }
 
+// SetAuditor records every execution with fn. A nil fn stops recording.
+func (r *ToolRegistry) SetAuditor(fn ToolAuditFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditor = fn
+}
+
+// SetMock routes every tool call through m. A nil m runs tools directly.
+func (r *ToolRegistry) SetMock(m ToolMock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mock = m
+}
+
 // ToolNotFoundError is returned when a tool is not found.
 type ToolNotFoundError struct {
 	Name string
@@ -104,6 +329,18 @@ func (e *ToolNotFoundError) Error() string {
 	return "tool not found: " + e.Name
 }
 
+// DuplicateToolError is returned when a tool name is already registered
+// by another source at the same precedence.
+type DuplicateToolError struct {
+	Name     string
+	Existing string // Source or alias already holding the name
+	Source   string // Source of the rejected registration
+}
+
+func (e *DuplicateToolError) Error() string {
+	return fmt.Sprintf("tool %q from %s conflicts with %s; set a source precedence or register an alias", e.Name, e.Source, e.Existing)
+}
+
 // BaseTool provides a base implementation for tools.
 type BaseTool struct {
 	name        string
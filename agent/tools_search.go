@@ -8,6 +8,8 @@ import (
 
 	"github.com/plexusone/omniserp"
 	"github.com/plexusone/omniserp/client"
+
+	"github.com/plexusone/omniagent/sandbox"
 )
 
 // SearchTool provides web search capabilities via omniserp.
@@ -21,11 +23,33 @@ type SearchArgs struct {
 	Type  string `json:"type,omitempty"` // "web", "news", "images" (default: "web")
 }
 
+// SearchConfig configures the search tool.
+type SearchConfig struct {
+	// Engine is "serper" or "serpapi". The default is the SEARCH_ENGINE
+	// environment variable, then whichever of SERPER_API_KEY and
+	// SERPAPI_API_KEY is set.
+	Engine string
+
+	// Host carries the search API requests, applying its egress policy.
+	// The default allows any public host.
+	Host *sandbox.HostFunctions
+}
+
 // NewSearchTool creates a new search tool.
-func NewSearchTool() (*SearchTool, error) {
-	c, err := client.NewWithOptions(&client.Options{
-		Silent: true,
-	})
+func NewSearchTool(config SearchConfig) (*SearchTool, error) {
+	if config.Host == nil {
+		sc := sandbox.DefaultConfig()
+		sc.Capabilities = []sandbox.Capability{sandbox.CapNetHTTP}
+		sc.BlockPrivateNetworks = true
+		config.Host = sandbox.NewHostFunctions(sc)
+	}
+	engine, err := newSearchEngine(config.Engine, config.Host)
+	if err != nil {
+		return nil, fmt.Errorf("create search engine: %w", err)
+	}
+	registry := omniserp.NewRegistry()
+	registry.Register(engine)
+	c, err := client.NewWithRegistry(registry, engine.GetName())
 	if err != nil {
 		return nil, fmt.Errorf("create search client: %w", err)
 	}
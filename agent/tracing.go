@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records agent runs, model calls, and tool executions. It uses the
+// global tracer provider, so spans are dropped unless tracing is set up.
+var tracer = otel.Tracer("github.com/plexusone/omniagent/agent")
+
+// Attribute keys of agent spans, following the OpenTelemetry GenAI
+// conventions where they apply.
+const (
+	sessionAttr     = attribute.Key("omniagent.session")
+	systemKey       = attribute.Key("gen_ai.system")
+	modelKey        = attribute.Key("gen_ai.request.model")
+	inputTokensKey  = attribute.Key("gen_ai.usage.input_tokens")
+	outputTokensKey = attribute.Key("gen_ai.usage.output_tokens")
+	finishKey       = attribute.Key("gen_ai.response.finish_reasons")
+	toolKey         = attribute.Key("gen_ai.tool.name")
+)
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/plexusone/omniagent/transcripts"
+)
+
+// maxTranscriptToolResult caps tool results recorded in transcripts.
+const maxTranscriptToolResult = 8 * 1024
+
+// SetTranscripts records each conversation, including tool calls and
+// attachments, in store for export.
+func (a *Agent) SetTranscripts(store *transcripts.Store) {
+	a.transcripts = store
+}
+
+// recordTranscript appends a processed message and its reply to the
+// session's transcript.
+func (a *Agent) recordTranscript(ctx context.Context, sessionID, input, output string, runErr error, run *runState, start time.Time) {
+	atts := AttachmentsFromContext(ctx)
+
+	user := transcripts.Entry{Time: start, Role: transcripts.RoleUser, Content: input}
+	if atts != nil {
+		user.Attachments = a.saveTranscriptFiles(sessionID, atts.Incoming)
+	}
+	entries := append([]transcripts.Entry{user}, run.toolEntries...)
+
+	reply := transcripts.Entry{Time: time.Now(), Role: transcripts.RoleAssistant, Content: output, Model: run.settings.model}
+	if runErr != nil {
+		reply.Error = runErr.Error()
+	}
+	if atts != nil {
+		reply.Attachments = a.saveTranscriptFiles(sessionID, atts.Outgoing())
+	}
+	entries = append(entries, reply)
+
+	if err := a.transcripts.Append(sessionID, entries...); err != nil {
+		a.logger.Warn("failed to record transcript", "error", err)
+	}
+}
+
+// saveTranscriptFiles stores attachments alongside the transcript.
+func (a *Agent) saveTranscriptFiles(sessionID string, atts []Attachment) []transcripts.File {
+	var files []transcripts.File
+	for _, att := range atts {
+		f, err := a.transcripts.SaveFile(sessionID, att.Filename, att.MimeType, att.Data)
+		if err != nil {
+			a.logger.Warn("failed to save transcript attachment", "file", att.Filename, "error", err)
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// validJSON returns args as raw JSON, quoting it as a string when the
+// model produced invalid JSON.
+func validJSON(args json.RawMessage) json.RawMessage {
+	if len(args) == 0 || json.Valid(args) {
+		return args
+	}
+	quoted, _ := json.Marshal(string(args))
+	return quoted
+}
+
+// truncate shortens s to at most n bytes, marking the cut.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n[truncated]"
+}
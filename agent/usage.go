@@ -0,0 +1,28 @@
+package agent
+
+import "github.com/plexusone/omniagent/usage"
+
+// SetUsageLedger records the tokens and cost of every model request in
+// ledger.
+func (a *Agent) SetUsageLedger(ledger *usage.Ledger) {
+	a.ledger = ledger
+}
+
+// recordUsage appends a model request's usage to the ledger, if any.
+func (a *Agent) recordUsage(sessionID, providerName, model string, promptTokens, completionTokens int) {
+	if a.ledger == nil {
+		return
+	}
+	err := a.ledger.Record(usage.Record{
+		SessionID:        sessionID,
+		Channel:          ChannelFromSession(sessionID),
+		Persona:          a.persona,
+		Provider:         providerName,
+		Model:            model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	})
+	if err != nil {
+		a.logger.Error("failed to record usage", "error", err)
+	}
+}
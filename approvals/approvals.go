@@ -0,0 +1,243 @@
+// Package approvals pauses sensitive tool calls until a person approves
+// them, and keeps an audit log of every decision.
+package approvals
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when resolving an unknown or already decided
+// request.
+var ErrNotFound = errors.New("approval request not found")
+
+// defaultTimeout is how long a request waits before it is denied.
+const defaultTimeout = 10 * time.Minute
+
+// Request is a tool call waiting for approval.
+type Request struct {
+	ID        string          `json:"id"`
+	Tool      string          `json:"tool"`
+	Args      json.RawMessage `json:"args,omitempty"`
+	SessionID string          `json:"session_id"`
+	Persona   string          `json:"persona,omitempty"`
+	Details   string          `json:"details,omitempty"` // More for approvers to review, such as a diff
+	Created   time.Time       `json:"created"`
+}
+
+// Decision is the outcome of a request.
+type Decision struct {
+	Approved bool
+	Approver string // Who decided; empty when the request expired
+}
+
+// Notifier delivers a new request to approvers.
+type Notifier func(ctx context.Context, req Request) error
+
+// Config configures a Manager.
+type Config struct {
+	// Tools lists the tools that need approval; "*" matches all.
+	Tools []string
+
+	// Approvers are the contact IDs ("telegram:12345") allowed to decide.
+	Approvers []string
+
+	// Timeout denies requests nobody decides in time (default: 10m).
+	Timeout time.Duration
+
+	// AuditPath appends a JSON line per event to this file when set.
+	AuditPath string
+
+	Logger *slog.Logger
+}
+
+// Manager tracks pending approval requests.
+type Manager struct {
+	config    Config
+	logger    *slog.Logger
+	notifiers []Notifier
+	pending   map[string]*pending
+	mu        sync.Mutex
+	auditMu   sync.Mutex
+}
+
+type pending struct {
+	req      Request
+	decision chan Decision
+}
+
+// New creates a Manager.
+func New(config Config) (*Manager, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if config.AuditPath != "" {
+		if err := os.MkdirAll(filepath.Dir(config.AuditPath), 0o700); err != nil {
+			return nil, fmt.Errorf("create audit log directory: %w", err)
+		}
+	}
+	return &Manager{
+		config:  config,
+		logger:  config.Logger,
+		pending: make(map[string]*pending),
+	}, nil
+}
+
+// Requires reports whether calls to tool need approval.
+func (m *Manager) Requires(tool string) bool {
+	return slices.Contains(m.config.Tools, "*") || slices.Contains(m.config.Tools, tool)
+}
+
+// CanApprove reports whether contactID may decide requests.
+func (m *Manager) CanApprove(contactID string) bool {
+	return contactID != "" && slices.Contains(m.config.Approvers, contactID)
+}
+
+// Approvers returns the contact IDs allowed to decide.
+func (m *Manager) Approvers() []string {
+	return m.config.Approvers
+}
+
+// OnRequest adds a notifier called for each new request. Call it before
+// requests are made.
+func (m *Manager) OnRequest(n Notifier) {
+	m.notifiers = append(m.notifiers, n)
+}
+
+// Await registers req, notifies approvers, and blocks until it is decided,
+// it times out, or ctx is done. Expired requests are denied.
+func (m *Manager) Await(ctx context.Context, req Request) (Decision, error) {
+	req.ID = newID()
+	req.Created = time.Now()
+	p := &pending{req: req, decision: make(chan Decision, 1)}
+	m.mu.Lock()
+	m.pending[req.ID] = p
+	m.mu.Unlock()
+	m.audit("requested", req, "")
+
+	delivered := false
+	for _, notify := range m.notifiers {
+		if err := notify(ctx, req); err != nil {
+			m.logger.Warn("approval request not delivered", "id", req.ID, "error", err)
+			continue
+		}
+		delivered = true
+	}
+	if !delivered {
+		m.remove(req.ID)
+		m.audit("undeliverable", req, "")
+		return Decision{}, fmt.Errorf("no approver could be reached for %s", req.Tool)
+	}
+
+	timer := time.NewTimer(m.config.Timeout)
+	defer timer.Stop()
+	select {
+	case d := <-p.decision:
+		return d, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	if !m.remove(req.ID) {
+		// Decided while giving up
+		return <-p.decision, nil
+	}
+	m.audit("expired", req, "")
+	if err := ctx.Err(); err != nil {
+		return Decision{}, err
+	}
+	return Decision{}, nil
+}
+
+// Resolve decides a pending request and returns it.
+func (m *Manager) Resolve(id string, approved bool, approver string) (Request, error) {
+	m.mu.Lock()
+	p, ok := m.pending[id]
+	delete(m.pending, id)
+	m.mu.Unlock()
+	if !ok {
+		return Request{}, ErrNotFound
+	}
+
+	event := "denied"
+	if approved {
+		event = "approved"
+	}
+	m.audit(event, p.req, approver)
+	p.decision <- Decision{Approved: approved, Approver: approver}
+	return p.req, nil
+}
+
+// Pending returns undecided requests, oldest first.
+func (m *Manager) Pending() []Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reqs := make([]Request, 0, len(m.pending))
+	for _, p := range m.pending {
+		reqs = append(reqs, p.req)
+	}
+	slices.SortFunc(reqs, func(a, b Request) int { return a.Created.Compare(b.Created) })
+	return reqs
+}
+
+// remove drops a pending request, reporting whether it was still pending.
+func (m *Manager) remove(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.pending[id]
+	delete(m.pending, id)
+	return ok
+}
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	Approver string    `json:"approver,omitempty"`
+	Request
+}
+
+// audit logs an event and appends it to the audit file.
+func (m *Manager) audit(event string, req Request, approver string) {
+	m.logger.Info("tool approval", "event", event, "id", req.ID, "tool", req.Tool,
+		"session", req.SessionID, "approver", approver)
+	if m.config.AuditPath == "" {
+		return
+	}
+
+	line, err := json.Marshal(auditEntry{Time: time.Now(), Event: event, Approver: approver, Request: req})
+	if err != nil {
+		m.logger.Error("encode approval audit entry", "error", err)
+		return
+	}
+	m.auditMu.Lock()
+	defer m.auditMu.Unlock()
+	f, err := os.OpenFile(m.config.AuditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		m.logger.Error("open approval audit log", "error", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		m.logger.Error("write approval audit log", "error", err)
+	}
+}
+
+// newID returns a short random request ID that is easy to type.
+func newID() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,93 @@
+package approvals
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAwaitResolve(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "approvals.jsonl")
+	m, err := New(Config{Tools: []string{"shell"}, Approvers: []string{"telegram:1"}, AuditPath: auditPath})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if !m.Requires("shell") || m.Requires("web_search") {
+		t.Error("Requires() does not match configured tools")
+	}
+	if !m.CanApprove("telegram:1") || m.CanApprove("telegram:2") || m.CanApprove("") {
+		t.Error("CanApprove() does not match configured approvers")
+	}
+
+	requests := make(chan Request, 1)
+	m.OnRequest(func(_ context.Context, req Request) error {
+		requests <- req
+		return nil
+	})
+
+	done := make(chan Decision, 1)
+	go func() {
+		d, err := m.Await(context.Background(), Request{Tool: "shell", Args: []byte(`{"command":"ls"}`), SessionID: "s1"})
+		if err != nil {
+			t.Errorf("Await() error = %v", err)
+		}
+		done <- d
+	}()
+
+	req := <-requests
+	if pending := m.Pending(); len(pending) != 1 || pending[0].ID != req.ID {
+		t.Fatalf("Pending() = %+v", pending)
+	}
+	if _, err := m.Resolve("nope", true, "telegram:1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve(unknown) error = %v", err)
+	}
+	if _, err := m.Resolve(req.ID, true, "telegram:1"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if d := <-done; !d.Approved || d.Approver != "telegram:1" {
+		t.Errorf("decision = %+v", d)
+	}
+	if _, err := m.Resolve(req.ID, false, "telegram:1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve() twice error = %v", err)
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"event":"requested"`) ||
+		!strings.Contains(lines[1], `"event":"approved"`) || !strings.Contains(lines[1], `"approver":"telegram:1"`) {
+		t.Errorf("audit log = %s", data)
+	}
+}
+
+func TestAwaitDenied(t *testing.T) {
+	m, _ := New(Config{Tools: []string{"*"}, Timeout: 20 * time.Millisecond})
+
+	// Nobody to ask
+	if _, err := m.Await(context.Background(), Request{Tool: "shell"}); err == nil {
+		t.Error("Await() without notifiers succeeded")
+	}
+
+	// Nobody answers
+	m.OnRequest(func(context.Context, Request) error { return nil })
+	d, err := m.Await(context.Background(), Request{Tool: "shell"})
+	if err != nil || d.Approved || d.Approver != "" {
+		t.Errorf("Await() = %+v, %v; want expired denial", d, err)
+	}
+	if len(m.Pending()) != 0 {
+		t.Error("expired request still pending")
+	}
+
+	// Canceled while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.Await(ctx, Request{Tool: "shell"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Await() canceled error = %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package audit keeps an append-only log of every tool execution: who
+// asked for it, what it was called with, and how it ended. Each entry is a
+// JSON line, so the log can be shipped or inspected with standard tools.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Execution outcomes.
+const (
+	StatusOK     = "ok"
+	StatusError  = "error"  // The tool ran and failed
+	StatusDenied = "denied" // Not allowed, repeated too often, or not approved
+)
+
+// Entry is one tool execution.
+type Entry struct {
+	Time        time.Time       `json:"time"`
+	SessionID   string          `json:"session_id"`
+	Channel     string          `json:"channel,omitempty"`
+	Contact     string          `json:"contact,omitempty"` // Sender's contact ID, if known
+	Person      string          `json:"person,omitempty"`  // Linked identity, if any
+	Persona     string          `json:"persona,omitempty"` // Empty for the default agent
+	Tool        string          `json:"tool"`
+	Args        json.RawMessage `json:"args,omitempty"`
+	ResultBytes int             `json:"result_bytes"`
+	DurationMS  int64           `json:"duration_ms"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// User returns the person, contact, or session the entry belongs to, in
+// that order of preference.
+func (e Entry) User() string {
+	switch {
+	case e.Person != "":
+		return e.Person
+	case e.Contact != "":
+		return e.Contact
+	}
+	return e.SessionID
+}
+
+// Log appends entries to a file. It is safe for concurrent use.
+type Log struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+// Open opens the log at path for appending, creating it if needed.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return &Log{path: path, f: f}, nil
+}
+
+// Path returns the log file path.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends e, setting its time if unset.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode audit entry: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// Filter selects entries. Zero fields match everything.
+type Filter struct {
+	Since   time.Time
+	Until   time.Time
+	Session string
+	User    string // Matches the person, contact, or session
+	Tool    string
+	Status  string
+
+	// Limit keeps only the latest entries when positive.
+	Limit int
+}
+
+// match reports whether e passes the filter.
+func (f Filter) match(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	case f.Session != "" && e.SessionID != f.Session:
+		return false
+	case f.User != "" && e.Person != f.User && e.Contact != f.User && e.SessionID != f.User:
+		return false
+	case f.Tool != "" && e.Tool != f.Tool:
+		return false
+	case f.Status != "" && e.Status != f.Status:
+		return false
+	}
+	return true
+}
+
+// Query reads the log at path and returns the matching entries, oldest
+// first. A missing log has no entries.
+func Query(path string, filter Filter) ([]Entry, error) {
+	f, err := os.Open(path) //nolint:gosec // G304: Path comes from operator configuration
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		if filter.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLogQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "tools.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: start, SessionID: "telegram:1", Contact: "telegram:1", Person: "alice", Tool: "shell", Args: json.RawMessage(`{"command":"ls"}`), ResultBytes: 120, DurationMS: 15, Status: StatusOK},
+		{Time: start.Add(time.Minute), SessionID: "telegram:1", Contact: "telegram:1", Person: "alice", Tool: "shell", Status: StatusDenied, Error: "not approved"},
+		{Time: start.Add(2 * time.Minute), SessionID: "discord:9", Contact: "discord:9", Tool: "http_fetch", Status: StatusError, Error: "timeout"},
+	}
+	for _, e := range entries {
+		if err := log.Record(e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening appends rather than truncating
+	log, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Record(Entry{SessionID: "cli", Tool: "read_file", Status: StatusOK}); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all", Filter{}, 4},
+		{"tool", Filter{Tool: "shell"}, 2},
+		{"person", Filter{User: "alice"}, 2},
+		{"contact", Filter{User: "discord:9"}, 1},
+		{"status", Filter{Status: StatusDenied}, 1},
+		{"since", Filter{Since: start.Add(time.Minute)}, 3},
+		{"until", Filter{Until: start.Add(time.Minute)}, 1},
+		{"limit", Filter{Limit: 2}, 2},
+	}
+	for _, tt := range tests {
+		got, err := Query(path, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: Query() error = %v", tt.name, err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("%s: got %d entries, want %d", tt.name, len(got), tt.want)
+		}
+	}
+
+	latest, _ := Query(path, Filter{Limit: 1})
+	if latest[0].Tool != "read_file" || latest[0].Time.IsZero() {
+		t.Errorf("latest = %+v", latest[0])
+	}
+	first, _ := Query(path, Filter{Limit: 0})
+	if string(first[0].Args) != `{"command":"ls"}` || first[0].User() != "alice" {
+		t.Errorf("first = %+v", first[0])
+	}
+
+	if got, err := Query(filepath.Join(t.TempDir(), "missing.jsonl"), Filter{}); err != nil || got != nil {
+		t.Errorf("Query(missing) = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,319 @@
+// Package budget enforces daily and monthly token and cost caps, globally
+// and per channel.
+package budget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omniagent/eval"
+)
+
+// Limit caps usage in one period. Zero disables a cap.
+type Limit struct {
+	Tokens int
+	Cost   float64 // USD, estimated from Config.Pricing
+}
+
+// Policy sets the daily and monthly caps for one scope.
+type Policy struct {
+	Daily   Limit
+	Monthly Limit
+}
+
+// Status is how close usage is to a cap.
+type Status int
+
+const (
+	StatusOK       Status = iota
+	StatusWarning         // At or above Config.WarnAt of a cap
+	StatusExceeded        // At or above a cap
+)
+
+// String returns the status name.
+func (s Status) String() string {
+	switch s {
+	case StatusWarning:
+		return "warning"
+	case StatusExceeded:
+		return "exceeded"
+	default:
+		return "ok"
+	}
+}
+
+// GlobalScope is the scope name of the global caps.
+const GlobalScope = "global"
+
+// Config configures a Tracker.
+type Config struct {
+	// Global caps apply to all traffic combined.
+	Global Policy
+
+	// Channels caps traffic on specific channels, keyed by channel name.
+	Channels map[string]Policy
+
+	// WarnAt is the share of a cap at which usage is downgraded and the
+	// owner notified (default: 0.8).
+	WarnAt float64
+
+	// DowngradeModel replaces the configured model while any cap that
+	// applies is at warning or above. Empty keeps the model.
+	DowngradeModel string
+
+	// Pricing estimates request costs for cost caps.
+	Pricing eval.Pricing
+
+	// Location sets when days and months start (default: local).
+	Location *time.Location
+
+	// Path is a JSON file persisting usage across restarts. Empty keeps
+	// usage in memory.
+	Path string
+
+	// Notify is called when usage first crosses into warning or exceeded
+	// for a cap in the current period.
+	Notify func(Alert)
+
+	Logger *slog.Logger
+	Now    func() time.Time // For tests; default time.Now
+}
+
+// Usage is tokens and cost consumed in one period.
+type Usage struct {
+	Tokens int     `json:"tokens"`
+	Cost   float64 `json:"cost"`
+}
+
+// Alert reports a cap that was approached or exceeded.
+type Alert struct {
+	Status Status
+	Scope  string // GlobalScope or a channel name
+	Period string // "daily" or "monthly"
+	Used   Usage
+	Limit  Limit
+}
+
+// String describes the alert for the owner.
+func (a Alert) String() string {
+	scope := "global"
+	if a.Scope != GlobalScope {
+		scope = a.Scope
+	}
+	var parts []string
+	if a.Limit.Tokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d tokens", a.Used.Tokens, a.Limit.Tokens))
+	}
+	if a.Limit.Cost > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f/$%.2f", a.Used.Cost, a.Limit.Cost))
+	}
+	state := "is nearly used up"
+	if a.Status == StatusExceeded {
+		state = "is used up"
+	}
+	return fmt.Sprintf("The %s %s budget %s (%s).", a.Period, scope, state, strings.Join(parts, ", "))
+}
+
+// Decision is the most severe status across the caps that apply.
+type Decision struct {
+	Status Status
+	Scope  string
+	Period string
+}
+
+// Tracker accounts usage against caps.
+type Tracker struct {
+	config Config
+	usage  map[string]Usage // Keyed by "scope|period start", e.g. "telegram|2026-03-10"
+	mu     sync.Mutex
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// New creates a Tracker, loading persisted usage.
+func New(config Config) (*Tracker, error) {
+	if config.WarnAt <= 0 || config.WarnAt > 1 {
+		config.WarnAt = 0.8
+	}
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	t := &Tracker{
+		config: config,
+		usage:  make(map[string]Usage),
+		logger: config.Logger,
+		now:    config.Now,
+	}
+	if config.Path != "" {
+		data, err := os.ReadFile(config.Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("read budget usage: %w", err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &t.usage); err != nil {
+				return nil, fmt.Errorf("parse budget usage: %w", err)
+			}
+		}
+	}
+	return t, nil
+}
+
+// Check returns the most severe status of the global and channel caps.
+func (t *Tracker) Check(channel string) Decision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var d Decision
+	for _, c := range t.caps(channel) {
+		if s := t.status(t.usage[c.key], c.limit); s > d.Status {
+			d = Decision{Status: s, Scope: c.scope, Period: c.period}
+		}
+	}
+	return d
+}
+
+// Model returns the model to use on channel: the downgrade model while a
+// cap is at warning or above, otherwise model.
+func (t *Tracker) Model(channel, model string) string {
+	if t.config.DowngradeModel == "" || t.Check(channel).Status == StatusOK {
+		return model
+	}
+	return t.config.DowngradeModel
+}
+
+// Charge records a request's usage against the global and channel caps
+// and notifies when a cap is crossed.
+func (t *Tracker) Charge(channel, model string, promptTokens, completionTokens int) {
+	used := Usage{
+		Tokens: promptTokens + completionTokens,
+		Cost:   t.config.Pricing.Cost(model, promptTokens, completionTokens),
+	}
+	if used.Tokens <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	var alerts []Alert
+	for _, c := range t.caps(channel) {
+		before := t.usage[c.key]
+		after := Usage{Tokens: before.Tokens + used.Tokens, Cost: before.Cost + used.Cost}
+		t.usage[c.key] = after
+		if s := t.status(after, c.limit); s > t.status(before, c.limit) {
+			alerts = append(alerts, Alert{Status: s, Scope: c.scope, Period: c.period, Used: after, Limit: c.limit})
+		}
+	}
+	t.prune()
+	if err := t.save(); err != nil {
+		t.logger.Error("save budget usage failed", "error", err)
+	}
+	t.mu.Unlock()
+
+	for _, a := range alerts {
+		t.logger.Warn("budget threshold crossed", "scope", a.Scope, "period", a.Period,
+			"status", a.Status, "tokens", a.Used.Tokens, "cost", a.Used.Cost)
+		if t.config.Notify != nil {
+			t.config.Notify(a)
+		}
+	}
+}
+
+// Usage returns usage in the current day and month for a scope.
+func (t *Tracker) Usage(scope string) (daily, monthly Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	day, month := t.periods()
+	return t.usage[scope+"|"+day], t.usage[scope+"|"+month]
+}
+
+// capCheck is one cap applying to a request.
+type capCheck struct {
+	key    string
+	scope  string
+	period string
+	limit  Limit
+}
+
+// caps returns the caps that apply to channel in the current periods.
+// Callers must hold the lock.
+func (t *Tracker) caps(channel string) []capCheck {
+	day, month := t.periods()
+	add := func(checks []capCheck, scope string, p Policy) []capCheck {
+		return append(checks,
+			capCheck{key: scope + "|" + day, scope: scope, period: "daily", limit: p.Daily},
+			capCheck{key: scope + "|" + month, scope: scope, period: "monthly", limit: p.Monthly})
+	}
+	checks := add(nil, GlobalScope, t.config.Global)
+	if p, ok := t.config.Channels[channel]; ok && channel != "" {
+		checks = add(checks, channel, p)
+	}
+	return checks
+}
+
+// periods returns the keys of the current day and month.
+func (t *Tracker) periods() (day, month string) {
+	now := t.now().In(t.config.Location)
+	return now.Format("2006-01-02"), now.Format("2006-01")
+}
+
+// status compares usage with a limit.
+func (t *Tracker) status(u Usage, l Limit) Status {
+	ratio := 0.0
+	if l.Tokens > 0 {
+		ratio = float64(u.Tokens) / float64(l.Tokens)
+	}
+	if l.Cost > 0 {
+		ratio = max(ratio, u.Cost/l.Cost)
+	}
+	switch {
+	case ratio >= 1:
+		return StatusExceeded
+	case ratio >= t.config.WarnAt:
+		return StatusWarning
+	default:
+		return StatusOK
+	}
+}
+
+// prune removes usage from past periods. Callers must hold the lock.
+func (t *Tracker) prune() {
+	day, month := t.periods()
+	for key := range t.usage {
+		_, period, _ := strings.Cut(key, "|")
+		if period != day && period != month {
+			delete(t.usage, key)
+		}
+	}
+}
+
+// save writes usage to the configured path. Callers must hold the lock.
+func (t *Tracker) save() error {
+	if t.config.Path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(t.usage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode budget usage: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.config.Path), 0o750); err != nil {
+		return fmt.Errorf("create budget dir: %w", err)
+	}
+	tmp := t.config.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write budget usage: %w", err)
+	}
+	return os.Rename(tmp, t.config.Path)
+}
@@ -0,0 +1,77 @@
+package budget
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omniagent/eval"
+)
+
+func TestTrackerThresholds(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	var alerts []Alert
+	path := filepath.Join(t.TempDir(), "budget.json")
+	config := Config{
+		Global:         Policy{Monthly: Limit{Cost: 1}},
+		Channels:       map[string]Policy{"telegram": {Daily: Limit{Tokens: 1000}}},
+		DowngradeModel: "small",
+		Pricing:        eval.Pricing{"big": {Prompt: 100, Completion: 100}},
+		Location:       time.UTC,
+		Path:           path,
+		Notify:         func(a Alert) { alerts = append(alerts, a) },
+		Now:            func() time.Time { return now },
+	}
+	tr, err := New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tr.Charge("telegram", "big", 500, 200)
+	if d := tr.Check("telegram"); d.Status != StatusOK {
+		t.Errorf("Check() = %+v, want ok", d)
+	}
+	if got := tr.Model("telegram", "big"); got != "big" {
+		t.Errorf("Model() = %s, want big", got)
+	}
+
+	tr.Charge("telegram", "big", 100, 50)
+	if len(alerts) != 1 || alerts[0].Status != StatusWarning || alerts[0].Scope != "telegram" || alerts[0].Period != "daily" {
+		t.Fatalf("alerts = %+v, want one daily telegram warning", alerts)
+	}
+	if got := tr.Model("telegram", "big"); got != "small" {
+		t.Errorf("Model() = %s, want downgrade to small", got)
+	}
+	if got := tr.Model("discord", "big"); got != "big" {
+		t.Errorf("Model() on uncapped channel = %s, want big", got)
+	}
+
+	// Crossing again within the period does not repeat the alert
+	tr.Charge("telegram", "big", 10, 10)
+	if len(alerts) != 1 {
+		t.Errorf("alerts = %d, want 1", len(alerts))
+	}
+
+	// Usage survives a restart; the global cost cap is hit from discord
+	tr, err = New(config)
+	if err != nil {
+		t.Fatalf("New() reload error = %v", err)
+	}
+	tr.Charge("discord", "big", 5000, 5000)
+	if d := tr.Check("telegram"); d.Status != StatusExceeded || d.Scope != GlobalScope {
+		t.Errorf("Check() = %+v, want global exceeded", d)
+	}
+	if len(alerts) != 2 || alerts[1].Status != StatusExceeded {
+		t.Errorf("alerts = %+v, want global exceeded alert", alerts)
+	}
+
+	// A new day resets the daily cap but not the monthly one
+	now = now.Add(24 * time.Hour)
+	daily, monthly := tr.Usage("telegram")
+	if daily.Tokens != 0 || monthly.Tokens != 870 {
+		t.Errorf("telegram usage = %+v/%+v, want 0 today and 870 this month", daily, monthly)
+	}
+	if d := tr.Check("telegram"); d.Status != StatusExceeded || d.Period != "monthly" {
+		t.Errorf("Check() next day = %+v, want monthly exceeded", d)
+	}
+}
@@ -0,0 +1,97 @@
+package catchup
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/pipeline"
+)
+
+// recorder is a Processor and Sender that records calls.
+type recorder struct {
+	mu      sync.Mutex
+	prompts []string
+	sent    []string
+}
+
+func (r *recorder) Process(ctx context.Context, sessionID, content string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prompts = append(r.prompts, content)
+	return "summary", nil
+}
+
+func (r *recorder) Send(ctx context.Context, providerName, chatID string, msg provider.OutgoingMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, providerName+":"+chatID+":"+msg.Content)
+	return nil
+}
+
+func TestRecordGroupMessages(t *testing.T) {
+	h := NewHistory(HistoryConfig{MaxAge: time.Hour, MaxMessages: 2})
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	handler := pipeline.Chain(func(context.Context, provider.IncomingMessage) error { return nil }, Record(h))
+	for _, msg := range []provider.IncomingMessage{
+		{ChatType: provider.ChatTypeGroup, Content: "too old", Timestamp: now.Add(-2 * time.Hour)},
+		{ChatType: provider.ChatTypeGroup, Content: "first"},
+		{ChatType: provider.ChatTypeDM, Content: "private"},
+		{ChatType: provider.ChatTypeGroup, Content: "/catchup"},
+		{ChatType: provider.ChatTypeGroup, Content: "second"},
+		{ChatType: provider.ChatTypeGroup, Content: "third"},
+	} {
+		msg.ProviderName, msg.ChatID, msg.SenderName = "telegram", "-100", "ann"
+		if err := handler(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	msgs, _ := h.Messages(context.Background(), "telegram", "-100", now.Add(-time.Hour))
+	var got []string
+	for _, m := range msgs {
+		got = append(got, m.Content)
+	}
+	if strings.Join(got, ",") != "second,third" {
+		t.Errorf("recorded %v, want the last two group messages", got)
+	}
+}
+
+func TestCatchupCommand(t *testing.T) {
+	h := NewHistory(HistoryConfig{})
+	h.Add("telegram", "-100", Message{Sender: "ann", Content: "release is friday", Time: time.Now()})
+	rec := &recorder{}
+	d, err := New(Config{Source: h, Agent: rec, Sender: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := d.Command(0)
+
+	ctx := agent.WithContact(context.Background(), "telegram:7")
+	reply, err := cmd.Handler(ctx, "telegram:-100", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply, "privately") {
+		t.Errorf("reply = %q", reply)
+	}
+	if len(rec.prompts) != 1 || !strings.Contains(rec.prompts[0], "ann: release is friday") {
+		t.Errorf("prompts = %q", rec.prompts)
+	}
+	if len(rec.sent) != 1 || !strings.HasPrefix(rec.sent[0], "telegram:7:Catch-up for the last 2 hours") {
+		t.Errorf("sent = %q, want a private digest", rec.sent)
+	}
+
+	// A quiet chat sends nothing
+	reply, _ = cmd.Handler(ctx, "telegram:-200", "")
+	if !strings.Contains(reply, "haven't seen any messages") || len(rec.sent) != 1 {
+		t.Errorf("quiet chat: reply = %q, sent = %d", reply, len(rec.sent))
+	}
+}
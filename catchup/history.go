@@ -0,0 +1,136 @@
+package catchup
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/pipeline"
+)
+
+// History defaults.
+const (
+	DefaultMaxAge      = 24 * time.Hour
+	DefaultMaxMessages = 500
+)
+
+// Message is one message in a group chat.
+type Message struct {
+	Sender  string
+	Content string
+	Time    time.Time
+}
+
+// Source returns a chat's messages since a time, oldest first. Channels
+// whose API exposes chat history can implement it; History records the
+// messages of the others as they arrive.
+type Source interface {
+	Messages(ctx context.Context, channel, chatID string, since time.Time) ([]Message, error)
+}
+
+// HistoryConfig configures a History.
+type HistoryConfig struct {
+	MaxAge      time.Duration // Messages are forgotten after this; default 24h
+	MaxMessages int           // Kept per chat; default 500
+}
+
+// History keeps recent group chat messages in memory, since most chat
+// APIs do not let bots read back what was said.
+type History struct {
+	config HistoryConfig
+	chats  map[string][]Message
+	mu     sync.Mutex
+	now    func() time.Time
+}
+
+// NewHistory creates a History.
+func NewHistory(config HistoryConfig) *History {
+	if config.MaxAge <= 0 {
+		config.MaxAge = DefaultMaxAge
+	}
+	if config.MaxMessages <= 0 {
+		config.MaxMessages = DefaultMaxMessages
+	}
+	return &History{
+		config: config,
+		chats:  make(map[string][]Message),
+		now:    time.Now,
+	}
+}
+
+// MaxAge returns how far back History remembers.
+func (h *History) MaxAge() time.Duration {
+	return h.config.MaxAge
+}
+
+// Add records a message in a chat.
+func (h *History) Add(channel, chatID string, m Message) {
+	key := channel + ":" + chatID
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	msgs := append(h.chats[key], m)
+	if n := len(msgs) - h.config.MaxMessages; n > 0 {
+		msgs = msgs[n:]
+	}
+	h.chats[key] = h.prune(msgs)
+
+	// Forget chats that went quiet
+	for k, msgs := range h.chats {
+		if msgs = h.prune(msgs); len(msgs) == 0 {
+			delete(h.chats, k)
+		} else {
+			h.chats[k] = msgs
+		}
+	}
+}
+
+// Messages returns the chat's recorded messages since a time.
+func (h *History) Messages(_ context.Context, channel, chatID string, since time.Time) ([]Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []Message
+	for _, m := range h.chats[channel+":"+chatID] {
+		if !m.Time.Before(since) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// prune drops messages older than MaxAge. Callers must hold the lock.
+func (h *History) prune(msgs []Message) []Message {
+	cutoff := h.now().Add(-h.config.MaxAge)
+	i := 0
+	for i < len(msgs) && msgs[i].Time.Before(cutoff) {
+		i++
+	}
+	return msgs[i:]
+}
+
+// Record adds group chat messages to h. Direct messages and slash
+// commands are not recorded.
+func Record(h *History) pipeline.Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			if msg.ChatType != provider.ChatTypeDM && msg.ChatType != "" &&
+				msg.Content != "" && !strings.HasPrefix(msg.Content, "/") {
+				sender := msg.SenderName
+				if sender == "" {
+					sender = msg.SenderID
+				}
+				ts := msg.Timestamp
+				if ts.IsZero() {
+					ts = h.now()
+				}
+				h.Add(msg.ProviderName, msg.ChatID, Message{Sender: sender, Content: msg.Content, Time: ts})
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Ensure History implements Source.
+var _ Source = (*History)(nil)
@@ -0,0 +1,185 @@
+// Package clock resolves contact timezones and renders the current local
+// time for prompts.
+package clock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures a Resolver.
+type Config struct {
+	Default  string            // IANA timezone when nothing else is known (default: server local time)
+	Contacts map[string]string // Timezones keyed by contact ID ("telegram:42") or session ID
+	Path     string            // JSON file persisting timezones learned at runtime; empty keeps them in memory
+	Now      func() time.Time  // For tests; default time.Now
+}
+
+// Resolver determines the timezone for a contact.
+type Resolver struct {
+	fallback   *time.Location
+	configured map[string]*time.Location
+	learned    map[string]string
+	path       string
+	now        func() time.Time
+	mu         sync.RWMutex
+}
+
+// New creates a Resolver, validating configured timezone names.
+func New(config Config) (*Resolver, error) {
+	r := &Resolver{
+		fallback:   time.Local,
+		configured: make(map[string]*time.Location, len(config.Contacts)),
+		learned:    make(map[string]string),
+		path:       config.Path,
+		now:        config.Now,
+	}
+	if r.now == nil {
+		r.now = time.Now
+	}
+
+	if config.Default != "" {
+		loc, err := time.LoadLocation(config.Default)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default timezone: %w", err)
+		}
+		r.fallback = loc
+	}
+	for key, name := range config.Contacts {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone for %s: %w", key, err)
+		}
+		r.configured[key] = loc
+	}
+
+	if r.path != "" {
+		data, err := os.ReadFile(r.path) //nolint:gosec // G304: Path comes from operator configuration
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read timezones: %w", err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &r.learned); err != nil {
+				return nil, fmt.Errorf("parse timezones: %w", err)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Location returns the timezone for a contact. Keys are tried in order
+// (typically contact ID, then session ID); a timezone the user stated wins
+// over configuration, which wins over the default.
+func (r *Resolver) Location(keys ...string) *time.Location {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range keys {
+		if name, ok := r.learned[key]; ok {
+			if loc, err := time.LoadLocation(name); err == nil {
+				return loc
+			}
+		}
+	}
+	for _, key := range keys {
+		if loc, ok := r.configured[key]; ok {
+			return loc
+		}
+	}
+	return r.fallback
+}
+
+// SetLocation records a timezone for key, e.g. after the user says where
+// they are. An empty name forgets the learned timezone.
+func (r *Resolver) SetLocation(key, name string) (*time.Location, error) {
+	if key == "" {
+		return nil, fmt.Errorf("contact required")
+	}
+
+	var loc *time.Location
+	if name != "" {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q (use an IANA name like Europe/Paris)", name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if loc == nil {
+		delete(r.learned, key)
+	} else {
+		r.learned[key] = loc.String()
+	}
+	return loc, r.save()
+}
+
+// Now returns the current time in loc.
+func (r *Resolver) Now(loc *time.Location) time.Time {
+	return r.now().In(loc)
+}
+
+// layouts are accepted time formats, tried in order. Times without an
+// offset are interpreted in the requested location.
+var layouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"15:04",
+}
+
+// Parse parses s in loc, returning the current time for "" or "now".
+// A clock time alone ("15:04") means today in loc.
+func (r *Resolver) Parse(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "now") {
+		return r.Now(loc), nil
+	}
+	for _, layout := range layouts {
+		parsed, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			continue
+		}
+		if layout == "15:04" {
+			now := r.Now(loc)
+			parsed = time.Date(now.Year(), now.Month(), now.Day(), parsed.Hour(), parsed.Minute(), 0, 0, loc)
+		}
+		return parsed, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q (use RFC 3339 or 2006-01-02 15:04)", s)
+}
+
+// Prompt describes the current local time for a system prompt so the
+// model can answer date questions correctly.
+func (r *Resolver) Prompt(keys ...string) string {
+	now := r.Now(r.Location(keys...))
+	return fmt.Sprintf("## Current Time\n\nIt is %s (%s, UTC%s) for the user. "+
+		"Use this for relative dates like \"today\" or \"next Friday\".",
+		now.Format("Monday, 2 January 2006, 15:04"), now.Location(), now.Format("-07:00"))
+}
+
+// save writes learned timezones. Callers must hold the write lock.
+func (r *Resolver) save() error {
+	if r.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.learned, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode timezones: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o750); err != nil {
+		return fmt.Errorf("create timezones dir: %w", err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write timezones: %w", err)
+	}
+	return os.Rename(tmp, r.path)
+}
@@ -0,0 +1,63 @@
+package clock
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolverPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timezones.json")
+	r, err := New(Config{
+		Default:  "UTC",
+		Contacts: map[string]string{"telegram:42": "America/New_York"},
+		Path:     path,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if got := r.Location("discord:7").String(); got != "UTC" {
+		t.Errorf("Location(unknown) = %s, want UTC", got)
+	}
+	if got := r.Location("telegram:42", "telegram:100").String(); got != "America/New_York" {
+		t.Errorf("Location(configured) = %s, want America/New_York", got)
+	}
+
+	// A timezone the user stated overrides configuration and persists
+	if _, err := r.SetLocation("telegram:42", "Europe/Paris"); err != nil {
+		t.Fatalf("SetLocation() error = %v", err)
+	}
+	r, err = New(Config{Default: "UTC", Contacts: map[string]string{"telegram:42": "America/New_York"}, Path: path})
+	if err != nil {
+		t.Fatalf("New() reload error = %v", err)
+	}
+	if got := r.Location("telegram:42").String(); got != "Europe/Paris" {
+		t.Errorf("Location(learned) = %s, want Europe/Paris", got)
+	}
+
+	if _, err := r.SetLocation("telegram:42", "Mars/Olympus"); err == nil {
+		t.Error("SetLocation() with unknown timezone should fail")
+	}
+	if _, err := New(Config{Default: "Nowhere/City"}); err == nil {
+		t.Error("New() with invalid default should fail")
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	fixed := time.Date(2026, 3, 6, 23, 30, 0, 0, time.UTC)
+	r, err := New(Config{
+		Contacts: map[string]string{"telegram:42": "Asia/Tokyo"},
+		Now:      func() time.Time { return fixed },
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// 23:30 UTC Friday is 08:30 Saturday in Tokyo
+	got := r.Prompt("telegram:42")
+	if !strings.Contains(got, "Saturday, 7 March 2026, 08:30") || !strings.Contains(got, "Asia/Tokyo, UTC+09:00") {
+		t.Errorf("Prompt() = %q", got)
+	}
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
+)
+
+// newAgentPool derives the configured personas from the default agent and
+// routes messages among them.
+func newAgentPool(cfg *config.Config, agentInstance *agent.Agent, logger *slog.Logger) (*agent.Pool, error) {
+	personas := make(map[string]*agent.Agent, len(cfg.Agents))
+	for _, pc := range cfg.Agents {
+		if personas[pc.Name] != nil {
+			return nil, fmt.Errorf("duplicate agent %q", pc.Name)
+		}
+		persona, err := agentInstance.Derive(agent.Persona{
+			Name:         pc.Name,
+			Description:  pc.Description,
+			Provider:     pc.Provider,
+			Model:        pc.Model,
+			APIKey:       pc.APIKey,
+			BaseURL:      pc.BaseURL,
+			SystemPrompt: pc.SystemPrompt,
+			Temperature:  pc.Temperature,
+			Tools:        pc.Tools,
+		})
+		if err != nil {
+			return nil, err
+		}
+		personas[pc.Name] = persona
+		logger.Info("agent persona initialized", "name", pc.Name, "model", persona.Model())
+	}
+
+	routes := make([]agent.Route, 0, len(cfg.Routing))
+	for _, rc := range cfg.Routing {
+		routes = append(routes, agent.Route{
+			Agent:    rc.Agent,
+			Channels: rc.Channels,
+			Senders:  rc.Senders,
+			Prefix:   rc.Prefix,
+		})
+	}
+	pool, err := agent.NewPool(agentInstance, personas, routes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Personas share the tool registry, so every agent can delegate
+	if len(personas) > 0 {
+		if err := agentInstance.RegisterTool(agent.NewDelegateTool(pool)); err != nil {
+			return nil, fmt.Errorf("register delegate tool: %w", err)
+		}
+	}
+	return pool, nil
+}
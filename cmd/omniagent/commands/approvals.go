@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/approvals"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/identity"
+)
+
+// newApprovals creates the tool approval manager. Requests are sent to each
+// approver as a direct message on the approver's channel, and any linked
+// account of an approver may decide.
+func newApprovals(cfg *config.Config, people *identity.Directory, router *provider.Router, logger *slog.Logger) (*approvals.Manager, error) {
+	auditPath := cfg.Approvals.AuditPath
+	if auditPath == "" {
+		auditPath = filepath.Join(cfg.Storage.Path, "approvals.jsonl")
+	}
+	approvers := cfg.Approvals.Approvers
+	if len(approvers) == 0 {
+		approvers = cfg.Owners
+	}
+
+	m, err := approvals.New(approvals.Config{
+		Tools:     cfg.Approvals.RequiresApproval,
+		Approvers: people.Expand(approvers),
+		Timeout:   cfg.Approvals.Timeout,
+		AuditPath: auditPath,
+		Logger:    logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(approvers) > 0 {
+		m.OnRequest(func(ctx context.Context, req approvals.Request) error {
+			return sendApprovalRequest(ctx, router, people.Contacts(approvers), req, logger)
+		})
+	}
+	return m, nil
+}
+
+// sendApprovalRequest messages each approver, failing if none received it.
+func sendApprovalRequest(ctx context.Context, router *provider.Router, approvers []string, req approvals.Request, logger *slog.Logger) error {
+	text := fmt.Sprintf("Approval needed (%s): %s %s in session %s.\nReply /approve %s or /deny %s.",
+		req.ID, req.Tool, req.Args, req.SessionID, req.ID, req.ID)
+	if req.Details != "" {
+		text += "\n\n" + req.Details
+	}
+	delivered := false
+	for _, approver := range approvers {
+		channel, chatID, ok := strings.Cut(approver, ":")
+		if !ok {
+			logger.Warn("invalid approver contact ID", "approver", approver)
+			continue
+		}
+		if err := router.Send(ctx, channel, chatID, provider.OutgoingMessage{Content: text}); err != nil {
+			logger.Warn("approval request not delivered", "approver", approver, "error", err)
+			continue
+		}
+		delivered = true
+	}
+	if !delivered {
+		return fmt.Errorf("no approver received request %s", req.ID)
+	}
+	return nil
+}
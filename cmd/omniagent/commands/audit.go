@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/audit"
+	"github.com/plexusone/omniagent/config"
+)
+
+var (
+	auditSince   time.Duration
+	auditSession string
+	auditUser    string
+	auditTool    string
+	auditStatus  string
+	auditLimit   int
+	auditJSON    bool
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Tool execution audit log commands",
+	Long: `Commands for the append-only log of tool executions.
+
+Enable recording with audit.enabled in the config.`,
+}
+
+var auditQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "List tool executions, oldest first",
+	Long: `List recorded tool executions matching the filters. --user matches a
+linked identity (alice), a contact ID (telegram:12345), or a session.`,
+	RunE: auditQuery,
+}
+
+func init() {
+	auditQueryCmd.Flags().DurationVar(&auditSince, "since", 24*time.Hour, "query period (0 for all time)")
+	auditQueryCmd.Flags().StringVar(&auditSession, "session", "", "only this session")
+	auditQueryCmd.Flags().StringVar(&auditUser, "user", "", "only this person, contact, or session")
+	auditQueryCmd.Flags().StringVar(&auditTool, "tool", "", "only this tool")
+	auditQueryCmd.Flags().StringVar(&auditStatus, "status", "", "only this status (ok, error, or denied)")
+	auditQueryCmd.Flags().IntVarP(&auditLimit, "limit", "n", 100, "show at most this many of the latest entries (0 for all)")
+	auditQueryCmd.Flags().BoolVar(&auditJSON, "json", false, "output JSON lines")
+	auditCmd.AddCommand(auditQueryCmd)
+}
+
+// auditPath returns where tool executions are logged.
+func auditPath(cfg *config.Config) string {
+	if cfg.Audit.Path != "" {
+		return cfg.Audit.Path
+	}
+	return filepath.Join(cfg.Storage.Path, "audit.jsonl")
+}
+
+func auditQuery(cmd *cobra.Command, args []string) error {
+	filter := audit.Filter{
+		Session: auditSession,
+		User:    auditUser,
+		Tool:    auditTool,
+		Status:  auditStatus,
+		Limit:   auditLimit,
+	}
+	if auditSince > 0 {
+		filter.Since = time.Now().Add(-auditSince)
+	}
+	entries, err := audit.Query(auditPath(getConfig()), filter)
+	if err != nil {
+		return err
+	}
+
+	if auditJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Println("No tool executions recorded.")
+		return nil
+	}
+	fmt.Printf("%-19s %-24s %-20s %-16s %-7s %8s %9s  %s\n", "TIME", "SESSION", "USER", "TOOL", "STATUS", "DURATION", "RESULT", "ARGS")
+	for _, e := range entries {
+		detail := string(e.Args)
+		if e.Error != "" {
+			detail = e.Error
+		}
+		fmt.Printf("%-19s %-24s %-20s %-16s %-7s %7dms %8dB  %s\n",
+			e.Time.Local().Format(time.DateTime), e.SessionID, e.User(), e.Tool, e.Status,
+			e.DurationMS, e.ResultBytes, preview(detail))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/plexusone/omniagent/keychain"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Provider API key commands",
+	Long: `Store provider API keys in the OS keychain (macOS Keychain, the Linux
+Secret Service, or Windows Credential Manager) instead of the config file
+or environment. A stored key takes precedence over both.`,
+}
+
+var authSetCmd = &cobra.Command{
+	Use:   "set <provider>",
+	Short: "Store a provider's API key",
+	Long: `Store the API key for a provider, such as openai or anthropic. The key
+is prompted for, or read from standard input when it is not a terminal.`,
+	Args: cobra.ExactArgs(1),
+	RunE: authSet,
+}
+
+var authDeleteCmd = &cobra.Command{
+	Use:   "delete <provider>",
+	Short: "Remove a provider's stored API key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := keychain.Delete(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed the %s API key from the keychain.\n", args[0])
+		return nil
+	},
+}
+
+var authListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show which configured providers have a stored API key",
+	RunE:  authList,
+}
+
+func init() {
+	authCmd.AddCommand(authSetCmd)
+	authCmd.AddCommand(authDeleteCmd)
+	authCmd.AddCommand(authListCmd)
+}
+
+func authSet(cmd *cobra.Command, args []string) error {
+	key, err := readSecret(fmt.Sprintf("%s API key: ", args[0]))
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("no API key given")
+	}
+	if err := keychain.Set(args[0], key); err != nil {
+		return err
+	}
+	fmt.Printf("Stored the %s API key in the keychain.\n", args[0])
+	return nil
+}
+
+func authList(cmd *cobra.Command, args []string) error {
+	providers := getConfig().Providers()
+	if len(providers) == 0 {
+		fmt.Println("No providers configured.")
+		return nil
+	}
+	for _, p := range providers {
+		status := "not stored"
+		if _, err := keychain.Get(p); err == nil {
+			status = "stored"
+		} else if !errors.Is(err, keychain.ErrNotFound) {
+			status = err.Error()
+		}
+		fmt.Printf("%-12s %s\n", p, status)
+	}
+	return nil
+}
+
+// readSecret prompts for a secret without echo on a terminal, or reads the
+// first line of standard input.
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd()) //nolint:gosec // G115: File descriptors fit in int
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(b)), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read API key: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/storage"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Backup commands",
+	Long: `Commands for backing up local state to the configured blob store.
+
+Backups include the configuration file and all state under the data
+directory (storage.path), such as channel session databases, memory,
+the scheduler, budget, contacts, and approvals, plus any of those stores
+configured to live elsewhere. Sandbox workspaces are left out.
+Configure an S3-compatible storage backend to keep backups off-machine.`,
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a backup",
+	Long:  "Upload the configuration file and local state to the blob store.",
+	RunE:  createBackup,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backups",
+	Long:  "List backups stored in the blob store.",
+	RunE:  listBackups,
+}
+
+func init() {
+	backupCmd.AddCommand(backupCreateCmd)
+	backupCmd.AddCommand(backupListCmd)
+}
+
+// openBlobStore creates the blob store described by the storage configuration.
+func openBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	return storage.New(storage.Config{
+		Backend: cfg.Storage.Backend,
+		Path:    cfg.Storage.Path,
+		S3: storage.S3Config{
+			Endpoint:        cfg.Storage.S3.Endpoint,
+			Region:          cfg.Storage.S3.Region,
+			Bucket:          cfg.Storage.S3.Bucket,
+			AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+			Prefix:          cfg.Storage.S3.Prefix,
+			PathStyle:       cfg.Storage.S3.PathStyle,
+		},
+	})
+}
+
+func createBackup(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+
+	store, err := openBlobStore(cfg)
+	if err != nil {
+		return fmt.Errorf("open storage: %w", err)
+	}
+
+	sources, err := backupSources(cfg)
+	if err != nil {
+		return fmt.Errorf("collect state: %w", err)
+	}
+
+	ctx := context.Background()
+	prefix := "backups/" + time.Now().UTC().Format("20060102T150405Z") + "/"
+
+	uploaded := 0
+	for _, path := range slices.Sorted(maps.Keys(sources)) {
+		f, err := os.Open(path) //nolint:gosec // G304: Paths come from operator configuration
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("open %s: %w", path, err)
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("stat %s: %w", path, err)
+		}
+
+		key := prefix + sources[path]
+		err = store.Put(ctx, key, f, info.Size())
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("upload %s: %w", path, err)
+		}
+		fmt.Printf("  %s -> %s (%d bytes)\n", path, key, info.Size())
+		uploaded++
+	}
+
+	if uploaded == 0 {
+		fmt.Println("Nothing to back up.")
+		return nil
+	}
+
+	fmt.Printf("\nBackup created: %s (%d files, %s backend)\n", prefix, uploaded, cfg.Storage.Backend)
+	return nil
+}
+
+// backupSkip names the entries of the data directory left out of
+// backups: the local blob store's own backups, and sandbox workspaces,
+// which are scratch space.
+var backupSkip = map[string]bool{"backups": true, "workspaces": true}
+
+// statePaths lists the state files and directories whose location is
+// configurable. Stores kept outside the data directory are backed up only
+// if listed here, so add new stores as they are introduced.
+func statePaths(cfg *config.Config) []string {
+	return []string{
+		cfgFile,
+		cfg.Channels.WhatsApp.DBPath,
+		cfg.Agent.PreferencesFile,
+		cfg.Agent.PinsFile,
+		cfg.Tools.Scratchpad.Path,
+		cfg.Scheduler.Path,
+		cfg.Memory.Path,
+		cfg.Knowledge.Path,
+		cfg.Templates.Path,
+		cfg.Flags.Path,
+		cfg.Jobs.Path,
+		cfg.Proposals.Path,
+		cfg.Eval.Path,
+		cfg.Transcripts.Path,
+		cfg.Audit.Path,
+		cfg.Usage.Path,
+		cfg.Budget.Path,
+		cfg.Approvals.AuditPath,
+		cfg.Time.Path,
+		cfg.Contacts.Path,
+	}
+}
+
+// backupSources maps the local files to back up to their keys within a
+// backup: everything under the data directory, under data/, and the
+// configured state that lives elsewhere, by base name.
+func backupSources(cfg *config.Config) (map[string]string, error) {
+	sources := make(map[string]string)
+	if err := addBackupTree(sources, cfg.Storage.Path, "data", backupSkip); err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool)
+	for _, path := range statePaths(cfg) {
+		if path == "" || within(cfg.Storage.Path, path) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		key := filepath.Base(path)
+		for i := 2; taken[key]; i++ {
+			key = fmt.Sprintf("%d-%s", i, filepath.Base(path))
+		}
+		taken[key] = true
+		if info.IsDir() {
+			if err := addBackupTree(sources, path, key, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		sources[path] = key
+	}
+	return sources, nil
+}
+
+// addBackupTree adds the files under dir to sources, keyed under prefix,
+// leaving out the top-level entries in skip and temporary files.
+func addBackupTree(sources map[string]string, dir, prefix string, skip map[string]bool) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skip[rel] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".tmp-") || strings.HasSuffix(d.Name(), ".tmp") {
+			return nil
+		}
+		sources[path] = prefix + "/" + filepath.ToSlash(rel)
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// within reports whether path is inside dir.
+func within(dir, path string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func listBackups(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+
+	store, err := openBlobStore(cfg)
+	if err != nil {
+		return fmt.Errorf("open storage: %w", err)
+	}
+
+	blobs, err := store.List(context.Background(), "backups/")
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+
+	if len(blobs) == 0 {
+		fmt.Println("No backups found.")
+		return nil
+	}
+
+	for _, b := range blobs {
+		fmt.Printf("  %-60s %10d  %s\n", b.Key, b.Size, b.LastModified.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/budget"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/identity"
+)
+
+// newBudget creates the budget tracker. Alerts are sent to each owner as a
+// direct message on the owner's channel.
+func newBudget(cfg *config.Config, people *identity.Directory, router *provider.Router, loc *time.Location, logger *slog.Logger) (*budget.Tracker, error) {
+	channels := make(map[string]budget.Policy, len(cfg.Budget.Channels))
+	for name, c := range cfg.Budget.Channels {
+		channels[name] = budget.Policy{Daily: budgetLimit(c.Daily), Monthly: budgetLimit(c.Monthly)}
+	}
+
+	return budget.New(budget.Config{
+		Global:         budget.Policy{Daily: budgetLimit(cfg.Budget.Daily), Monthly: budgetLimit(cfg.Budget.Monthly)},
+		Channels:       channels,
+		WarnAt:         cfg.Budget.WarnAt,
+		DowngradeModel: cfg.Budget.DowngradeModel,
+		Pricing:        evalPricing(cfg),
+		Location:       loc,
+		Path:           budgetPath(cfg),
+		Logger:         logger,
+		Notify: func(alert budget.Alert) {
+			text := alert.String()
+			if alert.Status == budget.StatusWarning && cfg.Budget.DowngradeModel != "" {
+				text += " Switched to " + cfg.Budget.DowngradeModel + "."
+			}
+			if alert.Status == budget.StatusExceeded {
+				text += " Only owners are being answered until it resets."
+			}
+			notifyOwners(router, people, budgetOwners(cfg), text, logger)
+		},
+	})
+}
+
+// budgetPath returns where budget usage is persisted.
+func budgetPath(cfg *config.Config) string {
+	if cfg.Budget.Path != "" {
+		return cfg.Budget.Path
+	}
+	return filepath.Join(cfg.Storage.Path, "budget.json")
+}
+
+// budgetOwners returns the budget owners, defaulting to the global owners.
+func budgetOwners(cfg *config.Config) []string {
+	if len(cfg.Budget.Owners) > 0 {
+		return cfg.Budget.Owners
+	}
+	return cfg.Owners
+}
+
+func budgetLimit(l config.BudgetLimits) budget.Limit {
+	return budget.Limit{Tokens: l.Tokens, Cost: l.Cost}
+}
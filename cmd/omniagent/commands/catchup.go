@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/catchup"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/identity"
+	"github.com/plexusone/omniagent/scheduler"
+)
+
+// newCatchup creates the group chat history and the digester that
+// summarizes it, and registers /catchup.
+func newCatchup(cfg *config.Config, agentInstance *agent.Agent, router *provider.Router, logger *slog.Logger) (*catchup.History, *catchup.Digester, error) {
+	history := catchup.NewHistory(catchup.HistoryConfig{
+		MaxAge:      cfg.Catchup.MaxAge,
+		MaxMessages: cfg.Catchup.MaxMessages,
+	})
+	digester, err := catchup.New(catchup.Config{
+		Source: history,
+		MaxAge: history.MaxAge(),
+		Agent:  agentInstance,
+		Sender: router,
+		Logger: logger,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	agentInstance.RegisterCommand(digester.Command(cfg.Catchup.Hours))
+	return history, digester, nil
+}
+
+// addCatchupDigests schedules the configured catch-up digests.
+func addCatchupDigests(cfg *config.Config, people *identity.Directory, sched *scheduler.Scheduler, digester *catchup.Digester) error {
+	for _, dc := range cfg.Catchup.Digests {
+		hours := dc.Hours
+		if hours <= 0 {
+			hours = cfg.Catchup.Hours
+		}
+		recipients := dc.Recipients
+		if len(recipients) == 0 {
+			recipients = cfg.Owners
+		}
+		recipients = people.Contacts(recipients)
+		if dc.Channel == "" || dc.ChatID == "" || len(recipients) == 0 {
+			return fmt.Errorf("catch-up digest %q requires a channel, chat ID, and recipients", dc.Name)
+		}
+
+		window := time.Duration(hours) * time.Hour
+		if _, err := sched.Define(scheduler.Task{
+			ID:       "digest:" + dc.Name,
+			Name:     dc.Name,
+			Schedule: dc.Schedule,
+			Channel:  dc.Channel,
+			ChatID:   dc.ChatID,
+			Run: func(ctx context.Context) error {
+				return digester.Send(ctx, dc.Channel, dc.ChatID, window, recipients)
+			},
+		}); err != nil {
+			return fmt.Errorf("catch-up digest %q: %w", dc.Name, err)
+		}
+	}
+	return nil
+}
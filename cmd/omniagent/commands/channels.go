@@ -1,9 +1,15 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/connections"
 )
 
 var channelsCmd = &cobra.Command{
@@ -51,6 +57,20 @@ func listChannels(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("  discord     %s\n", discordStatus)
 
+	// SMS
+	smsStatus := "disabled"
+	if cfg.Channels.SMS.Enabled {
+		smsStatus = "enabled"
+	}
+	fmt.Printf("  sms         %s\n", smsStatus)
+
+	// Signal
+	signalStatus := "disabled"
+	if cfg.Channels.Signal.Enabled {
+		signalStatus = "enabled"
+	}
+	fmt.Printf("  signal      %s\n", signalStatus)
+
 	fmt.Println()
 	fmt.Println("Use 'envoy channels status' to check connection status.")
 
@@ -85,5 +105,64 @@ func statusChannels(cmd *cobra.Command, args []string) error {
 		fmt.Println("  discord     disabled")
 	}
 
+	// SMS
+	if cfg.Channels.SMS.Enabled {
+		tokenSet := "credentials not set"
+		if cfg.Channels.SMS.AccountSID != "" && cfg.Channels.SMS.AuthToken != "" {
+			tokenSet = "credentials configured"
+		}
+		fmt.Printf("  sms         enabled     %s\n", tokenSet)
+	} else {
+		fmt.Println("  sms         disabled")
+	}
+
+	// Signal
+	if cfg.Channels.Signal.Enabled {
+		fmt.Printf("  signal      enabled     daemon %s\n", cfg.Channels.Signal.URL)
+	} else {
+		fmt.Println("  signal      disabled")
+	}
+
+	// Live connection states from a running gateway
+	fmt.Println()
+	statuses, err := fetchChannelStatuses(cfg)
+	if err != nil {
+		fmt.Printf("Gateway not reachable (%v); connection states are shown while it runs.\n", err)
+		return nil
+	}
+	fmt.Println("Connections:")
+	fmt.Println()
+	if len(statuses) == 0 {
+		fmt.Println("  none")
+	}
+	for _, s := range statuses {
+		line := fmt.Sprintf("  %-11s %-11s %s, %d attempt(s), since %s", s.Channel, s.State, s.Policy, s.Attempts, s.Since.Format(time.DateTime))
+		if s.Error != "" && s.State != connections.StateConnected {
+			line += ": " + s.Error
+		}
+		fmt.Println(line)
+	}
+
 	return nil
 }
+
+// fetchChannelStatuses asks the running gateway for channel connection
+// states.
+func fetchChannelStatuses(cfg *config.Config) ([]connections.Status, error) {
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(gatewayURL(cfg) + "/health")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("health check returned %s", resp.Status)
+	}
+	var health struct {
+		Channels []connections.Status `json:"channels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("decode health: %w", err)
+	}
+	return health.Channels, nil
+}
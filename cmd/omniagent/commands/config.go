@@ -3,6 +3,7 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -45,9 +46,72 @@ func showConfig(cmd *cobra.Command, args []string) error {
 	if redacted.Channels.Discord.Token != "" {
 		redacted.Channels.Discord.Token = "***REDACTED***"
 	}
+	if redacted.Gateway.AdminToken != "" {
+		redacted.Gateway.AdminToken = "***REDACTED***"
+	}
+	if redacted.Encryption.Passphrase != "" {
+		redacted.Encryption.Passphrase = "***REDACTED***"
+	}
+	if len(redacted.Gateway.Observers) > 0 {
+		redacted.Gateway.Observers = slices.Clone(redacted.Gateway.Observers)
+		for i := range redacted.Gateway.Observers {
+			redacted.Gateway.Observers[i].Token = "***REDACTED***"
+		}
+	}
+	if len(redacted.Gateway.Approvers) > 0 {
+		redacted.Gateway.Approvers = slices.Clone(redacted.Gateway.Approvers)
+		for i := range redacted.Gateway.Approvers {
+			redacted.Gateway.Approvers[i].Token = "***REDACTED***"
+		}
+	}
+	if redacted.Voice.STT.APIKey != "" {
+		redacted.Voice.STT.APIKey = "***REDACTED***"
+	}
+	if redacted.Voice.TTS.APIKey != "" {
+		redacted.Voice.TTS.APIKey = "***REDACTED***"
+	}
+	redacted.Voice.STTFallbacks = slices.Clone(redacted.Voice.STTFallbacks)
+	for i := range redacted.Voice.STTFallbacks {
+		if redacted.Voice.STTFallbacks[i].APIKey != "" {
+			redacted.Voice.STTFallbacks[i].APIKey = "***REDACTED***"
+		}
+	}
+	redacted.Voice.TTSFallbacks = slices.Clone(redacted.Voice.TTSFallbacks)
+	for i := range redacted.Voice.TTSFallbacks {
+		if redacted.Voice.TTSFallbacks[i].APIKey != "" {
+			redacted.Voice.TTSFallbacks[i].APIKey = "***REDACTED***"
+		}
+	}
 	if redacted.Observability.APIKey != "" {
 		redacted.Observability.APIKey = "***REDACTED***"
 	}
+	if redacted.Storage.S3.SecretAccessKey != "" {
+		redacted.Storage.S3.SecretAccessKey = "***REDACTED***"
+	}
+	if redacted.Memory.Embedding.APIKey != "" {
+		redacted.Memory.Embedding.APIKey = "***REDACTED***"
+	}
+	if redacted.Tools.Images.APIKey != "" {
+		redacted.Tools.Images.APIKey = "***REDACTED***"
+	}
+	if redacted.Attachments.Vision.APIKey != "" {
+		redacted.Attachments.Vision.APIKey = "***REDACTED***"
+	}
+	if redacted.Knowledge.Embedding.APIKey != "" {
+		redacted.Knowledge.Embedding.APIKey = "***REDACTED***"
+	}
+	if redacted.Skills.Embedding.APIKey != "" {
+		redacted.Skills.Embedding.APIKey = "***REDACTED***"
+	}
+	redacted.Knowledge.Sources = slices.Clone(redacted.Knowledge.Sources)
+	for i := range redacted.Knowledge.Sources {
+		if redacted.Knowledge.Sources[i].Token != "" {
+			redacted.Knowledge.Sources[i].Token = "***REDACTED***"
+		}
+	}
+	if redacted.Instance.Token != "" {
+		redacted.Instance.Token = "***REDACTED***"
+	}
 
 	var output []byte
 	var err error
@@ -65,6 +129,9 @@ func showConfig(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("marshal config: %w", err)
 	}
 
+	if configFormat == "yaml" && cfg.Instance.Applied != "" {
+		fmt.Printf("# instance %s, overlay %s\n", cfg.InstanceName(), cfg.Instance.Applied)
+	}
 	fmt.Println(string(output))
 	return nil
 }
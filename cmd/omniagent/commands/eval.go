@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/eval"
+)
+
+var (
+	evalSince        time.Duration
+	evalExportFormat string
+	evalExportOutput string
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Evaluation commands",
+	Long: `Commands for reviewing response quality from captured feedback.
+
+Enable capture with eval.enabled in the config. Users rate replies with
+/feedback up|down, channel reactions, or gateway feedback messages.`,
+}
+
+var evalReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show a feedback report",
+	Long:  "Aggregate feedback, token usage, and cost by model, skill, channel, and experiment variant.",
+	RunE:  evalReport,
+}
+
+var evalExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export usage records to CSV or Parquet",
+	Long: `Export token usage, latency, tool calls, and estimated cost per reply,
+without message content, for analysis in spreadsheets or DuckDB.`,
+	RunE: evalExport,
+}
+
+func init() {
+	evalReportCmd.Flags().DurationVar(&evalSince, "since", 7*24*time.Hour, "report period (0 for all time)")
+	evalExportCmd.Flags().DurationVar(&evalSince, "since", 0, "export period (0 for all time)")
+	evalExportCmd.Flags().StringVarP(&evalExportFormat, "format", "f", eval.FormatCSV, "output format (csv or parquet)")
+	evalExportCmd.Flags().StringVarP(&evalExportOutput, "output", "o", "", "output file (default: stdout)")
+	evalCmd.AddCommand(evalReportCmd)
+	evalCmd.AddCommand(evalExportCmd)
+}
+
+// evalDir returns the trace and feedback directory.
+func evalDir(cfg *config.Config) string {
+	if cfg.Eval.Path != "" {
+		return cfg.Eval.Path
+	}
+	return filepath.Join(cfg.Storage.Path, "eval")
+}
+
+// openEvalStore opens the trace and feedback store.
+func openEvalStore(cfg *config.Config) (*eval.Store, error) {
+	return eval.Open(evalDir(cfg))
+}
+
+// newEvalExporter creates the scheduled usage exporter.
+func newEvalExporter(cfg *config.Config, store *eval.Store, logger *slog.Logger) (*eval.Exporter, error) {
+	dir := cfg.Eval.Export.Path
+	if dir == "" {
+		dir = filepath.Join(evalDir(cfg), "exports")
+	}
+	return eval.NewExporter(store, eval.ExportConfig{
+		Dir:      dir,
+		Format:   cfg.Eval.Export.Format,
+		Interval: cfg.Eval.Export.Interval,
+		Pricing:  evalPricing(cfg),
+		Logger:   logger,
+	})
+}
+
+func evalReport(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+
+	store, err := openEvalStore(cfg)
+	if err != nil {
+		return fmt.Errorf("open eval store: %w", err)
+	}
+
+	var since time.Time
+	if evalSince > 0 {
+		since = time.Now().Add(-evalSince)
+	}
+	report, err := store.Report(since, evalPricing(cfg))
+	if err != nil {
+		return err
+	}
+
+	if report.Total.Traces == 0 {
+		fmt.Println("No traces recorded in this period.")
+		return nil
+	}
+
+	if since.IsZero() {
+		fmt.Println("Feedback report (all time)")
+	} else {
+		fmt.Printf("Feedback report since %s\n", since.Format("2006-01-02 15:04"))
+	}
+	fmt.Println()
+
+	printGroups("Overall", []eval.Group{report.Total})
+	printGroups("By model", report.ByModel)
+	printGroups("By skill", report.BySkill)
+	printGroups("By channel", report.ByChannel)
+	if len(report.ByVariant) > 0 {
+		printGroups("By experiment variant", report.ByVariant)
+	}
+	return nil
+}
+
+func evalExport(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+
+	store, err := openEvalStore(cfg)
+	if err != nil {
+		return fmt.Errorf("open eval store: %w", err)
+	}
+	var since time.Time
+	if evalSince > 0 {
+		since = time.Now().Add(-evalSince)
+	}
+
+	records, err := store.Records(since, evalPricing(cfg))
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if evalExportOutput != "" {
+		f, err := os.Create(evalExportOutput)
+		if err != nil {
+			return fmt.Errorf("create output: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	return eval.WriteRecords(w, evalExportFormat, records)
+}
+
+// evalPricing converts configured model prices for cost estimates.
+func evalPricing(cfg *config.Config) eval.Pricing {
+	pricing := make(eval.Pricing, len(cfg.Eval.Pricing))
+	for model, p := range cfg.Eval.Pricing {
+		pricing[model] = eval.Price{Prompt: p.Prompt, Completion: p.Completion}
+	}
+	return pricing
+}
+
+func printGroups(title string, groups []eval.Group) {
+	fmt.Println(title + ":")
+	fmt.Printf("  %-32s %7s %6s %5s %5s %8s %7s %10s %10s\n", "", "traces", "rated", "up", "down", "approval", "errors", "tokens", "cost")
+	for _, g := range groups {
+		approval := "-"
+		if a := g.Approval(); a >= 0 {
+			approval = fmt.Sprintf("%.0f%%", a*100)
+		}
+		cost := "-"
+		if g.Cost > 0 {
+			cost = fmt.Sprintf("$%.4f", g.Cost)
+		}
+		fmt.Printf("  %-32s %7d %6d %5d %5d %8s %7d %10d %10s\n", g.Key, g.Traces, g.Rated, g.Up, g.Down, approval, g.Errors,
+			g.PromptTokens+g.CompletionTokens, cost)
+	}
+	fmt.Println()
+}
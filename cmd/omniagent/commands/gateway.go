@@ -4,15 +4,51 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/mdp/qrterminal/v3"
 	"github.com/spf13/cobra"
 
+	"github.com/plexusone/omniagent/access"
 	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/approvals"
+	"github.com/plexusone/omniagent/audit"
+	"github.com/plexusone/omniagent/budget"
+	"github.com/plexusone/omniagent/catchup"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/connections"
+	"github.com/plexusone/omniagent/contacts"
+	"github.com/plexusone/omniagent/credstore"
+	"github.com/plexusone/omniagent/errlog"
+	"github.com/plexusone/omniagent/eval"
+	"github.com/plexusone/omniagent/flags"
 	"github.com/plexusone/omniagent/gateway"
+	"github.com/plexusone/omniagent/health"
+	"github.com/plexusone/omniagent/hooks"
+	"github.com/plexusone/omniagent/identity"
+	"github.com/plexusone/omniagent/kb"
+	"github.com/plexusone/omniagent/mcp"
+	"github.com/plexusone/omniagent/media"
+	"github.com/plexusone/omniagent/pipeline"
+	"github.com/plexusone/omniagent/privacy"
+	"github.com/plexusone/omniagent/ratelimit"
+	"github.com/plexusone/omniagent/scheduler"
+	"github.com/plexusone/omniagent/signalcli"
+	"github.com/plexusone/omniagent/skills"
+	"github.com/plexusone/omniagent/sms"
+	"github.com/plexusone/omniagent/supervisor"
+	"github.com/plexusone/omniagent/tasks"
+	"github.com/plexusone/omniagent/tools/fixtures"
+	"github.com/plexusone/omniagent/tracing"
+	"github.com/plexusone/omniagent/transcripts"
+	"github.com/plexusone/omniagent/usage"
 	"github.com/plexusone/omniagent/voice"
 	"github.com/plexusone/omnichat/provider"
 	"github.com/plexusone/omnichat/providers/discord"
@@ -27,6 +63,17 @@ var (
 	gatewayAddress string
 )
 
+const (
+	// smsWebhookPath is where Twilio posts inbound SMS.
+	smsWebhookPath = "/webhooks/sms"
+
+	// taskWebhookPattern triggers named tasks.
+	taskWebhookPattern = "POST /webhooks/tasks/{name}"
+
+	// shutdownTimeout bounds how long each service has to stop.
+	shutdownTimeout = 15 * time.Second
+)
+
 var gatewayCmd = &cobra.Command{
 	Use:   "gateway",
 	Short: "Gateway management commands",
@@ -50,14 +97,36 @@ func init() {
 }
 
 func runGateway(cmd *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	context.AfterFunc(ctx, func() { fmt.Println("\nShutting down...") })
+	return serveGateway(ctx)
+}
+
+// serveGateway runs the gateway and its channels until ctx is canceled.
+func serveGateway(ctx context.Context) error {
 	cfg := getConfig()
-	logger := slog.Default()
+	// Keep recent errors for the admin API
+	recentErrors := errlog.New(100)
+	logger := slog.New(tracing.LogHandler(recentErrors.Handler(slog.Default().Handler())))
 
 	// Override from flag if provided
 	address := cfg.Gateway.Address
 	if gatewayAddress != "" {
 		address = gatewayAddress
 	}
+	if cfg.Instance.Applied != "" {
+		logger.Info("instance overlay applied", "instance", cfg.InstanceName(), "overlay", cfg.Instance.Applied)
+	}
+
+	// Privacy mode refuses to start with anything that sends conversation
+	// content off this machine
+	if cfg.Privacy.LocalOnly {
+		if problems := privacy.Check(ctx, cfg); len(problems) > 0 {
+			return fmt.Errorf("privacy mode is local_only, but:\n  %s", strings.Join(problems, "\n  "))
+		}
+		logger.Info("privacy mode enabled", "mode", privacy.Mode)
+	}
 
 	// Initialize observability if enabled
 	var llmopsProvider llmops.Provider
@@ -84,24 +153,101 @@ func runGateway(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Create agent if API key is configured
+	// Trace messages through the pipeline
+	if cfg.Observability.Tracing.Enabled {
+		tc := cfg.Observability.Tracing
+		shutdown, err := tracing.Setup(ctx, tracing.Config{
+			Endpoint:    tc.Endpoint,
+			Headers:     tc.Headers,
+			ServiceName: tc.ServiceName,
+			Instance:    cfg.InstanceName(),
+			SampleRatio: tc.SampleRatio,
+		})
+		if err != nil {
+			return fmt.Errorf("set up tracing: %w", err)
+		}
+		defer func() {
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer stopCancel()
+			if err := shutdown(stopCtx); err != nil {
+				logger.Warn("failed to flush traces", "error", err)
+			}
+		}()
+		logger.Info("tracing enabled", "endpoint", tc.Endpoint)
+	}
+
+	// Gate experimental features; overrides set at runtime persist
+	featureFlags, err := newFlags(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Link each person's accounts across channels
+	people, err := newIdentities(cfg)
+	if err != nil {
+		return err
+	}
+	accessControl, err := newAccess(cfg, people)
+	if err != nil {
+		return err
+	}
+
+	// Create agent if API key is configured or a local provider is used
 	var agentInstance *agent.Agent
-	if cfg.Agent.APIKey != "" {
+	var skillMgr *skillManager
+	var throttle *ratelimit.Throttle
+	var evalStore *eval.Store
+	var transcriptStore *transcripts.Store
+	var usageLedger *usage.Ledger
+	var knowledge *kb.Base
+	if cfg.Agent.APIKey != "" || agent.IsLocalProvider(cfg.Agent.Provider) {
 		agentConfig := agent.Config{
-			Provider:     cfg.Agent.Provider,
-			Model:        cfg.Agent.Model,
-			APIKey:       cfg.Agent.APIKey,
-			BaseURL:      cfg.Agent.BaseURL,
-			Temperature:  cfg.Agent.Temperature,
-			MaxTokens:    cfg.Agent.MaxTokens,
-			SystemPrompt: cfg.Agent.SystemPrompt,
-			Logger:       logger,
+			Provider:          cfg.Agent.Provider,
+			Model:             cfg.Agent.Model,
+			APIKey:            cfg.Agent.APIKey,
+			BaseURL:           cfg.Agent.BaseURL,
+			Temperature:       cfg.Agent.Temperature,
+			MaxTokens:         cfg.Agent.MaxTokens,
+			SystemPrompt:      cfg.Agent.SystemPrompt,
+			PromptVars:        cfg.Agent.PromptVars,
+			ContextLength:     cfg.Agent.ContextLength,
+			ToolCallMode:      cfg.Agent.ToolCallMode,
+			MaxToolIterations: cfg.Agent.MaxToolIterations,
+			MaxRepeatedCalls:  cfg.Agent.MaxRepeatedCalls,
+			Channels:          channelOverrides(cfg),
+			Experiment:        experimentConfig(cfg.Agent.Experiment),
+			ContentFilter: agent.ContentFilterConfig{
+				Message: cfg.Agent.ContentFilter.Message,
+				Retry:   cfg.Agent.ContentFilter.Retry,
+			},
+			Retry: agent.RetryPolicy{
+				Attempts:   cfg.Agent.Retry.Attempts,
+				Backoff:    cfg.Agent.Retry.Backoff,
+				MaxBackoff: cfg.Agent.Retry.MaxBackoff,
+			},
+			Fallbacks: fallbackModels(cfg.Agent.Fallbacks),
+			Logger:    logger,
+		}
+		unavailable, err := agent.CheckModelChain(context.Background(), agentConfig)
+		if err != nil {
+			return fmt.Errorf("check local model: %w", err)
+		}
+		for _, reason := range unavailable {
+			logger.Warn("model unavailable, using fallback", "error", reason)
+		}
+		if agentConfig.ContextLength == 0 && agent.IsLocalProvider(cfg.Agent.Provider) {
+			n, err := agent.DetectContextLength(context.Background(), cfg.Agent.Provider, cfg.Agent.BaseURL, cfg.Agent.Model)
+			if err != nil {
+				logger.Warn("failed to detect model context length", "model", cfg.Agent.Model, "error", err)
+			} else if n > 0 {
+				agentConfig.ContextLength = n
+				logger.Info("detected model context length", "model", cfg.Agent.Model, "tokens", n)
+			}
 		}
 		// Only set hook if non-nil to avoid interface{type, nil} gotcha
 		if observabilityHook != nil {
 			agentConfig.ObservabilityHook = observabilityHook
 		}
-		var err error
 		agentInstance, err = agent.New(agentConfig)
 		if err != nil {
 			return fmt.Errorf("create agent: %w", err)
@@ -109,21 +255,151 @@ func runGateway(cmd *cobra.Command, args []string) error {
 		defer agentInstance.Close()
 		logger.Info("agent initialized", "provider", cfg.Agent.Provider, "model", cfg.Agent.Model)
 
-		// Register search tool if available
-		if searchTool, err := agent.NewSearchTool(); err == nil {
-			agentInstance.RegisterTool(searchTool)
-			logger.Info("search tool registered")
-		} else {
-			logger.Debug("search tool not available", "error", err)
+		// Pace model requests across channels and personas
+		throttle = newThrottle(cfg.Agent)
+		agentInstance.SetThrottle(throttle)
+		agentInstance.SetFlags(featureFlags)
+
+		// Expose client-supplied context such as location (/context)
+		if len(cfg.Agent.ContextKeys) > 0 {
+			agentInstance.SetContextKeys(cfg.Agent.ContextKeys)
+		}
+
+		// Enable per-conversation preferences (/prefs)
+		prefsFile := cfg.Agent.PreferencesFile
+		if prefsFile == "" {
+			prefsFile = filepath.Join(cfg.Storage.Path, "preferences.json")
+		}
+		prefs, err := agent.NewPreferenceStore(prefsFile)
+		if err != nil {
+			return fmt.Errorf("load preferences: %w", err)
+		}
+		agentInstance.SetPreferenceStore(prefs)
+
+		// Enable pinned messages (/pin, /pins, /unpin)
+		pinsFile := cfg.Agent.PinsFile
+		if pinsFile == "" {
+			pinsFile = filepath.Join(cfg.Storage.Path, "pins.json")
+		}
+		pins, err := agent.NewPinStore(pinsFile)
+		if err != nil {
+			return fmt.Errorf("load pins: %w", err)
+		}
+		agentInstance.SetPinStore(pins)
+		if err := agentInstance.RegisterTool(agent.NewPinTool(pins)); err != nil {
+			return fmt.Errorf("register pin tool: %w", err)
+		}
+
+		// Register built-in tools
+		builtins, err := buildTools(cfg, logger)
+		defer builtins.Close()
+		if err != nil {
+			return err
+		}
+		agentInstance.SetClock(builtins.Clock)
+		if builtins.Memory != nil && cfg.Memory.AutoRecall {
+			agentInstance.SetRetriever(builtins.Memory)
+		}
+		knowledge = builtins.Knowledge
+		if builtins.Templates != nil {
+			agentInstance.RegisterCommand(builtins.Templates.Command())
+		}
+		agentInstance.SetToolPrecedence(cfg.Tools.Precedence)
+		for alias, id := range cfg.Tools.Aliases {
+			if err := agentInstance.AliasTool(alias, id); err != nil {
+				return fmt.Errorf("tool alias: %w", err)
+			}
+		}
+		for _, tool := range builtins.Tools {
+			if err := agentInstance.RegisterTool(tool); err != nil {
+				return fmt.Errorf("register tool: %w", err)
+			}
+		}
+		if fc := cfg.Tools.Fixtures; fc.Mode != "" {
+			dir := fc.Dir
+			if dir == "" {
+				dir = filepath.Join("testdata", "fixtures")
+			}
+			mock, err := fixtures.New(fixtures.Config{Dir: dir, Mode: fc.Mode, Logger: logger})
+			if err != nil {
+				return fmt.Errorf("tool fixtures: %w", err)
+			}
+			agentInstance.SetToolMock(mock)
+			logger.Warn("tool calls answered from fixtures", "mode", fc.Mode, "dir", dir)
+		}
+
+		// Record traces and capture feedback if enabled
+		if cfg.Eval.Enabled {
+			traceStore, err := openEvalStore(cfg)
+			if err != nil {
+				return fmt.Errorf("open eval store: %w", err)
+			}
+			agentInstance.SetTraceStore(traceStore)
+			evalStore = traceStore
+			logger.Info("trace and feedback capture enabled")
+		}
+
+		// Record conversation transcripts if enabled
+		if cfg.Transcripts.Enabled {
+			transcriptStore, err = openTranscripts(cfg)
+			if err != nil {
+				return fmt.Errorf("open transcripts: %w", err)
+			}
+			agentInstance.SetTranscripts(transcriptStore)
+			logger.Info("conversation transcripts enabled")
+		}
+
+		// Record every tool execution if enabled
+		if cfg.Audit.Enabled {
+			auditLog, err := audit.Open(auditPath(cfg))
+			if err != nil {
+				return err
+			}
+			defer auditLog.Close()
+			agentInstance.SetAuditLog(auditLog)
+			logger.Info("tool audit log enabled", "path", auditLog.Path())
+		}
+
+		// Record token usage and cost if enabled
+		if cfg.Usage.Enabled {
+			usageLedger, err = usage.Open(usagePath(cfg), evalPricing(cfg))
+			if err != nil {
+				return err
+			}
+			defer usageLedger.Close()
+			agentInstance.SetUsageLedger(usageLedger)
+		}
+
+		// Connect MCP servers and register their tools
+		if len(cfg.MCPServers) > 0 {
+			mcpManager, err := connectMCPServers(cfg, agentInstance, logger)
+			if err != nil {
+				return err
+			}
+			defer mcpManager.Close()
 		}
 
 		// Load skills if enabled
-		if cfg.Skills.Enabled {
-			searchPaths := cfg.Skills.Paths
-			if len(searchPaths) == 0 {
-				searchPaths = nil // Use defaults
+		skillMgr = newSkillManager(cfg, agentInstance, logger)
+		defer skillMgr.Close()
+		switch cfg.Skills.Injection {
+		case "", "all":
+		case "relevant":
+			embedding := cfg.Skills.Embedding
+			if embedding == (config.EmbeddingConfig{}) {
+				embedding = cfg.Memory.Embedding
 			}
-			if err := agentInstance.LoadSkills(searchPaths); err != nil {
+			agentInstance.SetSkillSelector(skills.NewSelector(skills.SelectorConfig{
+				Embedder: newEmbedder(cfg, embedding),
+				TopK:     cfg.Skills.TopK,
+				MinScore: cfg.Skills.MinScore,
+			}))
+			logger.Info("relevant skill injection enabled")
+		default:
+			return fmt.Errorf("skills.injection: unknown mode %q (must be all or relevant)", cfg.Skills.Injection)
+		}
+		if cfg.Skills.Enabled {
+			if err := skillMgr.Load(ctx, cfg.Skills.Paths); err != nil {
 				logger.Warn("failed to load skills", "error", err)
 			}
 		}
@@ -138,18 +414,16 @@ func runGateway(cmd *cobra.Command, args []string) error {
 		voiceProcessor, err = voice.New(voice.Config{
 			Enabled:      true,
 			ResponseMode: cfg.Voice.ResponseMode,
-			STT: voice.STTConfig{
-				Provider: cfg.Voice.STT.Provider,
-				APIKey:   cfg.Voice.STT.APIKey,
-				Model:    cfg.Voice.STT.Model,
-				Language: cfg.Voice.STT.Language,
-			},
-			TTS: voice.TTSConfig{
-				Provider: cfg.Voice.TTS.Provider,
-				APIKey:   cfg.Voice.TTS.APIKey,
-				Model:    cfg.Voice.TTS.Model,
-				VoiceID:  cfg.Voice.TTS.VoiceID,
+			STT:          voiceSTTConfig(cfg.Voice.STT),
+			TTS:          voiceTTSConfig(cfg.Voice.TTS),
+			STTFallbacks: voiceSTTFallbacks(cfg.Voice.STTFallbacks),
+			TTSFallbacks: voiceTTSFallbacks(cfg.Voice.TTSFallbacks),
+			Transcode: voice.TranscodeConfig{
+				Enabled:        cfg.Voice.Transcode.Enabled,
+				FFmpegPath:     cfg.Voice.Transcode.FFmpegPath,
+				ChannelFormats: cfg.Voice.Transcode.ChannelFormats,
 			},
+			Flags: featureFlags,
 		}, logger)
 		if err != nil {
 			return fmt.Errorf("create voice processor: %w", err)
@@ -158,24 +432,86 @@ func runGateway(cmd *cobra.Command, args []string) error {
 		logger.Info("voice processor initialized",
 			"stt_provider", cfg.Voice.STT.Provider,
 			"tts_provider", cfg.Voice.TTS.Provider,
+			"stt_fallbacks", len(cfg.Voice.STTFallbacks),
+			"tts_fallbacks", len(cfg.Voice.TTSFallbacks),
 			"response_mode", cfg.Voice.ResponseMode)
+		if cfg.Voice.STT.Streaming && !voiceProcessor.SupportsStreaming() {
+			logger.Warn("stt provider has no streaming API; transcribing voice notes in batch", "provider", cfg.Voice.STT.Provider)
+		}
 	}
 
-	// Setup graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Show the model the files users send
+	if cfg.Attachments.Read && agentInstance != nil {
+		agentInstance.SetAttachmentReader(newAttachmentReader(cfg, voiceProcessor, logger))
+	}
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	// Let the agent send recordings when voice is enabled
+	if cfg.Tools.Speech.Enabled && agentInstance != nil {
+		if voiceProcessor == nil {
+			logger.Warn("the speak tool requires voice to be enabled")
+		} else if err := agentInstance.RegisterTool(media.NewSpeechTool(voiceProcessor)); err != nil {
+			return fmt.Errorf("register tool: %w", err)
+		}
+	}
 
-	go func() {
-		<-sigCh
-		fmt.Println("\nShutting down...")
-		cancel()
+	// Long-running services join the group as they start and are stopped
+	// in reverse order: the gateway first, channels last
+	group := supervisor.New(ctx, supervisor.Config{Logger: logger})
+	defer func() {
+		// Stops services already started if setup fails; a no-op after Wait
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer stopCancel()
+		_ = group.Stop(stopCtx)
 	}()
 
+	// Create rate limiter if enabled
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		limiter = newRateLimiter(cfg.RateLimit)
+		logger.Info("rate limiting enabled")
+	}
+
+	// Load message hooks; pre_send hooks wrap each channel
+	var hookManager *hooks.Manager
+	if cfg.Hooks.Enabled {
+		var err error
+		hookManager, err = newHooks(ctx, cfg, logger)
+		if err != nil {
+			return fmt.Errorf("load hooks: %w", err)
+		}
+		defer hookManager.Close(context.Background())
+		if agentInstance != nil {
+			if cfg.Hooks.Skills {
+				loadSkillHooks(ctx, hookManager, agentInstance.GetSkills(), logger)
+			}
+			if hookManager.Has(hooks.PreTool) {
+				agentInstance.SetToolHook(hookManager.ToolHook())
+			}
+		}
+	}
+
 	// Create message router and register channels
+	conns, err := connections.New(connections.Config{
+		Policy:           cfg.Channels.Startup.Policy,
+		Channels:         cfg.Channels.Startup.Channels,
+		RetryInterval:    cfg.Channels.Startup.RetryInterval,
+		MaxRetryInterval: cfg.Channels.Startup.MaxRetryInterval,
+		Logger:           logger,
+	})
+	if err != nil {
+		return fmt.Errorf("channel startup: %w", err)
+	}
 	router := provider.NewRouter(logger)
+	register := func(p provider.Provider, delivery media.DeliveryConfig) {
+		p = media.Deliver(p, delivery)
+		if hookManager != nil {
+			p = hookManager.Provider(p)
+		}
+		if cfg.Observability.Tracing.Enabled {
+			p = tracing.Provider(p)
+		}
+		router.Register(p)
+	}
 
 	// Register Telegram if configured
 	if cfg.Channels.Telegram.Enabled {
@@ -184,10 +520,16 @@ func runGateway(cmd *cobra.Command, args []string) error {
 			Logger: logger,
 		})
 		if err != nil {
-			return fmt.Errorf("create telegram provider: %w", err)
+			if err := conns.Failed("telegram", err); err != nil {
+				return err
+			}
+		} else {
+			register(tg, media.DeliveryConfig{
+				Sender:       media.NewTelegramUploader(media.UploadConfig{Token: cfg.Channels.Telegram.Token}),
+				CaptionLimit: media.TelegramCaptionLimit,
+			})
+			logger.Info("telegram provider registered")
 		}
-		router.Register(tg)
-		logger.Info("telegram provider registered")
 	}
 
 	// Register Discord if configured
@@ -198,10 +540,16 @@ func runGateway(cmd *cobra.Command, args []string) error {
 			Logger:  logger,
 		})
 		if err != nil {
-			return fmt.Errorf("create discord provider: %w", err)
+			if err := conns.Failed("discord", err); err != nil {
+				return err
+			}
+		} else {
+			register(dc, media.DeliveryConfig{
+				Sender:       media.NewDiscordUploader(media.UploadConfig{Token: cfg.Channels.Discord.Token}),
+				CaptionLimit: media.DiscordCaptionLimit,
+			})
+			logger.Info("discord provider registered")
 		}
-		router.Register(dc)
-		logger.Info("discord provider registered")
 	}
 
 	// Register WhatsApp if configured
@@ -210,6 +558,23 @@ func runGateway(cmd *cobra.Command, args []string) error {
 		if dbPath == "" {
 			dbPath = "whatsapp.db"
 		}
+		if cfg.Encryption.Enabled {
+			sealed, err := openSealedDB(cfg.Encryption, dbPath, logger)
+			if err != nil {
+				return fmt.Errorf("open whatsapp database: %w", err)
+			}
+			// Deferred before the provider connects, so it is sealed after
+			// the provider disconnects
+			defer func() {
+				if err := sealed.Close(); err != nil {
+					logger.Error("failed to seal whatsapp database", "error", err)
+				}
+			}()
+			_ = group.Go("whatsapp-checkpoint", func(ctx context.Context) error {
+				return sealed.Run(ctx, cfg.Encryption.Checkpoint)
+			})
+			dbPath = sealed.Path()
+		}
 		wa, err := whatsapp.New(whatsapp.Config{
 			DBPath: dbPath,
 			Logger: logger,
@@ -228,10 +593,125 @@ func runGateway(cmd *cobra.Command, args []string) error {
 			},
 		})
 		if err != nil {
-			return fmt.Errorf("create whatsapp provider: %w", err)
+			if err := conns.Failed("whatsapp", err); err != nil {
+				return err
+			}
+		} else {
+			register(wa, media.DeliveryConfig{Native: isAudio})
+			logger.Info("whatsapp provider registered")
+		}
+	}
+
+	// Register Signal if configured
+	if cfg.Channels.Signal.Enabled {
+		sc, err := signalcli.New(signalcli.Config{
+			URL:            cfg.Channels.Signal.URL,
+			Account:        cfg.Channels.Signal.Account,
+			AttachmentsDir: cfg.Channels.Signal.AttachmentsDir,
+			Logger:         logger,
+		})
+		if err != nil {
+			if err := conns.Failed(signalcli.Name, err); err != nil {
+				return err
+			}
+		} else {
+			register(sc, media.DeliveryConfig{Native: hasData})
+			logger.Info("signal provider registered")
+		}
+	}
+
+	// Register SMS if configured; Twilio posts to the gateway webhook
+	webhooks := make(map[string]http.Handler)
+	if cfg.Channels.SMS.Enabled {
+		sp, err := sms.New(sms.Config{
+			AccountSID: cfg.Channels.SMS.AccountSID,
+			AuthToken:  cfg.Channels.SMS.AuthToken,
+			From:       cfg.Channels.SMS.From,
+			WebhookURL: cfg.Channels.SMS.WebhookURL,
+			MaxLength:  cfg.Channels.SMS.MaxLength,
+			Logger:     logger,
+		})
+		if err != nil {
+			if err := conns.Failed(sms.Name, err); err != nil {
+				return err
+			}
+		} else {
+			register(sp, media.DeliveryConfig{Native: hasURL})
+			webhooks[smsWebhookPath] = sp
+			logger.Info("sms provider registered", "webhook", smsWebhookPath)
+		}
+	}
+
+	// Enforce usage budgets
+	var budgetTracker *budget.Tracker
+	if cfg.Budget.Enabled && agentInstance != nil {
+		var err error
+		budgetTracker, err = newBudget(cfg, people, router, agentInstance.Clock().Location(), logger)
+		if err != nil {
+			return fmt.Errorf("create budget: %w", err)
+		}
+		agentInstance.SetBudget(budgetTracker)
+	}
+
+	// Pause sensitive tool calls for approval. Proposed changes always
+	// need approval, even when no tool does.
+	var approvalManager *approvals.Manager
+	if (len(cfg.Approvals.RequiresApproval) > 0 || cfg.Proposals.Enabled) && agentInstance != nil {
+		var err error
+		approvalManager, err = newApprovals(cfg, people, router, logger)
+		if err != nil {
+			return fmt.Errorf("create approvals: %w", err)
+		}
+		agentInstance.SetApprovals(approvalManager)
+	}
+
+	// Settings that can change without a restart
+	reload := reloadConfig(reloadTargets{
+		flags:    featureFlags,
+		access:   accessControl,
+		people:   people,
+		agent:    agentInstance,
+		skills:   skillMgr,
+		throttle: throttle,
+		logger:   logger,
+	})
+
+	// Let the agent propose changes to its own config and skills
+	if cfg.Proposals.Enabled && agentInstance != nil {
+		if _, err := newProposals(cfg, agentInstance, approvalManager, reload, logger); err != nil {
+			return fmt.Errorf("create proposals: %w", err)
+		}
+	}
+
+	// Record group chats so members can catch up
+	var catchupHistory *catchup.History
+	var digester *catchup.Digester
+	if cfg.Catchup.Enabled && agentInstance != nil {
+		var err error
+		catchupHistory, digester, err = newCatchup(cfg, agentInstance, router, logger)
+		if err != nil {
+			return fmt.Errorf("create catchup: %w", err)
+		}
+	}
+
+	// Route messages among the default agent and personas
+	var pool *agent.Pool
+	if agentInstance != nil {
+		agentInstance.SetChannelNames(router.ListProviders())
+		var err error
+		pool, err = newAgentPool(cfg, agentInstance, logger)
+		if err != nil {
+			return fmt.Errorf("create agents: %w", err)
+		}
+	}
+
+	// Run long tasks in the background
+	if cfg.Jobs.Enabled && agentInstance != nil {
+		jobManager, err := newJobs(cfg, agentInstance, pool, router, logger)
+		if err != nil {
+			return fmt.Errorf("create jobs: %w", err)
 		}
-		router.Register(wa)
-		logger.Info("whatsapp provider registered")
+		_ = group.Go("jobs", jobManager.Run)
 	}
 
 	// Check if any channels are configured
@@ -241,49 +721,649 @@ func runGateway(cmd *cobra.Command, args []string) error {
 	} else {
 		// Set up agent processing if available
 		if agentInstance != nil {
-			router.SetAgent(agentInstance)
+			if hookManager != nil {
+				router.SetAgent(hookManager.Processor(pool))
+			} else {
+				router.SetAgent(pool)
+			}
+			handler := router.ProcessWithAgent()
 			if voiceProcessor != nil {
-				router.OnMessage(provider.All(), router.ProcessWithVoice(voiceProcessor))
+				handler = router.ProcessWithVoice(voiceProcessor)
 				logger.Info("voice processing enabled for messages")
-			} else {
-				router.OnMessage(provider.All(), router.ProcessWithAgent())
+			}
+
+			var middleware []pipeline.Middleware
+			if cfg.Observability.Tracing.Enabled {
+				middleware = append(middleware, tracing.Receive())
+			}
+			middleware = append(middleware, pipeline.Contact(), pipeline.Identity(people))
+			if accessControl != nil {
+				middleware = append(middleware, pipeline.Access(accessControl))
+			}
+			middleware = append(middleware, pipeline.Group(), pipeline.Metadata())
+			if catchupHistory != nil {
+				middleware = append(middleware, catchup.Record(catchupHistory))
+			}
+			triggers, err := pipeline.Triggers(channelTriggers(cfg))
+			if err != nil {
+				return fmt.Errorf("channel triggers: %w", err)
+			}
+			middleware = append(middleware, triggers)
+			if policies, ok := attachmentPolicies(cfg.Attachments); ok {
+				policies.Sender = router
+				policies.Logger = logger
+				middleware = append(middleware, pipeline.AttachmentPolicies(policies))
+			}
+			middleware = append(middleware, pipeline.Attachments(router, logger))
+			if cfg.Channels.Progress.Enabled {
+				middleware = append(middleware, pipeline.Progress(pipeline.ProgressConfig{
+					Sender:    router,
+					Interval:  cfg.Channels.Progress.Interval,
+					MaxLength: cfg.Channels.Progress.MaxLength,
+					Logger:    logger,
+				}))
+			}
+			if budgetTracker != nil {
+				middleware = append(middleware, pipeline.Budget(pipeline.BudgetConfig{
+					Tracker: budgetTracker,
+					Owners:  people.Expand(budgetOwners(cfg)),
+					Sender:  router,
+					Reply:   cfg.Budget.Reply,
+				}))
+			}
+			if limiter != nil {
+				middleware = append(middleware, pipeline.RateLimit(pipeline.RateLimitConfig{
+					Limiter:      limiter,
+					Sender:       router,
+					MessageReply: cfg.RateLimit.MessageLimitReply,
+					TokenReply:   cfg.RateLimit.TokenLimitReply,
+				}))
+			}
+
+			// Register contacts and greet new ones
+			contactsPath := cfg.Contacts.Path
+			if contactsPath == "" {
+				contactsPath = filepath.Join(cfg.Storage.Path, "contacts.json")
+			}
+			contactStore, err := contacts.Open(contactsPath)
+			if err != nil {
+				return fmt.Errorf("open contacts: %w", err)
+			}
+			defer func() {
+				if err := contactStore.Save(); err != nil {
+					logger.Warn("failed to save contacts", "error", err)
+				}
+			}()
+			onboarding := pipeline.OnboardingConfig{
+				Contacts:     contactStore,
+				Sender:       router,
+				Capabilities: agentInstance.Capabilities,
+				Logger:       logger,
+			}
+			if cfg.Onboarding.Enabled {
+				onboarding.Message = cfg.Onboarding.Message
+			}
+			middleware = append(middleware, pipeline.Onboarding(onboarding))
+			if hookManager != nil {
+				middleware = append(middleware, hookManager.Middleware())
+			}
+
+			chain := pipeline.Chain(handler, middleware...)
+			router.OnMessage(provider.All(), chain)
+			if evalStore != nil || cfg.Reactions.Enabled {
+				registerReactions(router, cfg.Reactions, agentInstance, chain, logger)
 			}
 		}
 
 		// Connect all channels
-		if err := router.ConnectAll(ctx); err != nil {
+		if err := conns.Connect(ctx, router); err != nil {
 			return fmt.Errorf("connect channels: %w", err)
 		}
-		defer func() {
-			if err := router.DisconnectAll(context.Background()); err != nil {
+		_ = group.Go("channels", func(ctx context.Context) error {
+			<-ctx.Done()
+			conns.Wait()
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer stopCancel()
+			if err := router.DisconnectAll(stopCtx); err != nil {
 				logger.Error("disconnect error", "error", err)
 			}
-		}()
+			return nil
+		})
 		logger.Info("channels connected", "count", len(channels))
 	}
 
+	// Run named tasks from webhooks and the scheduler
+	var runner *tasks.Runner
+	if len(cfg.Tasks) > 0 {
+		if agentInstance == nil {
+			logger.Warn("tasks configured but no agent configured, tasks disabled")
+		} else {
+			var err error
+			runner, err = newTaskRunner(cfg, agentInstance, router, logger)
+			if err != nil {
+				return fmt.Errorf("create tasks: %w", err)
+			}
+			webhooks[taskWebhookPattern] = runner
+			_ = group.Go("tasks", func(ctx context.Context) error {
+				<-ctx.Done()
+				stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+				defer stopCancel()
+				return runner.Stop(stopCtx)
+			})
+		}
+	}
+
+	// Start scheduler if enabled. Results of tasks gateway clients
+	// schedule go to the gateway once it is created.
+	var sched *scheduler.Scheduler
+	var schedClients atomic.Pointer[gateway.Gateway]
+	if cfg.Scheduler.Enabled {
+		if agentInstance == nil {
+			logger.Warn("scheduler enabled but no agent configured, scheduler disabled")
+		} else {
+			var err error
+			sched, err = newScheduler(cfg, agentInstance, router, runner, &schedClients, logger)
+			if err != nil {
+				return fmt.Errorf("create scheduler: %w", err)
+			}
+			defer sched.Close()
+			if digester != nil {
+				if err := addCatchupDigests(cfg, people, sched, digester); err != nil {
+					return fmt.Errorf("create scheduler: %w", err)
+				}
+			}
+			_ = group.Go("scheduler", sched.Run)
+		}
+	}
+	if len(cfg.Catchup.Digests) > 0 && (digester == nil || sched == nil) {
+		logger.Warn("catch-up digests require catchup and the scheduler to be enabled")
+	}
+
+	// Sync knowledge sources in the background
+	if len(cfg.Knowledge.Sources) > 0 {
+		if knowledge == nil {
+			logger.Warn("knowledge sources require knowledge.enabled and an agent")
+		} else {
+			syncer, err := newKnowledgeSyncer(ctx, cfg, knowledge, logger)
+			if err != nil {
+				return err
+			}
+			_ = group.Go("knowledge-sync", syncer.Run)
+			logger.Info("knowledge sync started", "sources", syncer.Sources())
+		}
+	}
+
+	// Export usage records on a schedule
+	if cfg.Eval.Export.Enabled {
+		if evalStore == nil {
+			logger.Warn("eval.export requires eval.enabled and an agent")
+		} else {
+			exporter, err := newEvalExporter(cfg, evalStore, logger)
+			if err != nil {
+				return fmt.Errorf("create eval exporter: %w", err)
+			}
+			_ = group.Go("eval-export", exporter.Run)
+			logger.Info("scheduled usage export enabled", "interval", cfg.Eval.Export.Interval)
+		}
+	}
+
+	// Probe providers in the background
+	var monitor *health.Monitor
+	if cfg.Health.Enabled {
+		monitor = newHealthMonitor(cfg, agentInstance, voiceProcessor, people, router, logger)
+		_ = group.Go("health", monitor.Run)
+	}
+
 	// Create and start gateway
-	gw, err := gateway.New(gateway.Config{
+	gwConfig := gateway.Config{
 		Address:      address,
 		ReadTimeout:  cfg.Gateway.ReadTimeout,
 		WriteTimeout: cfg.Gateway.WriteTimeout,
 		PingInterval: cfg.Gateway.PingInterval,
-		Agent:        agentInstance,
 		Logger:       logger,
-	})
+		Instance:     cfg.InstanceName(),
+		LocalOnly:    cfg.Privacy.LocalOnly,
+
+		AllowedOrigins: cfg.Gateway.AllowedOrigins,
+		Token:          cfg.Gateway.Token,
+		TLS: &gateway.TLSConfig{
+			CertFile:         cfg.Gateway.TLS.CertFile,
+			KeyFile:          cfg.Gateway.TLS.KeyFile,
+			Autocert:         cfg.Gateway.TLS.Autocert,
+			AutocertDomains:  cfg.Gateway.TLS.AutocertDomains,
+			AutocertCacheDir: cfg.Gateway.TLS.AutocertCacheDir,
+			AutocertEmail:    cfg.Gateway.TLS.AutocertEmail,
+			ChallengeAddress: cfg.Gateway.TLS.ChallengeAddress,
+		},
+	}
+	if cfg.Encryption.Enabled && cfg.Gateway.TLS.Autocert {
+		if gwConfig.TLS.AutocertPassphrase, err = encryptionPassphrase(cfg.Encryption); err != nil {
+			return fmt.Errorf("encrypt certificate cache: %w", err)
+		}
+	}
+	// Only set agent if non-nil to avoid interface{type, nil} gotcha
+	if agentInstance != nil {
+		gwConfig.Agent = pool
+		gwConfig.Sessions = pool.Default()
+	}
+	gwConfig.Scheduler = sched
+	gwConfig.Feedback = evalStore
+	gwConfig.RateLimiter = limiter
+	gwConfig.Health = monitor
+	gwConfig.Throttle = throttle
+	gwConfig.Channels = conns
+	gwConfig.Transcripts = transcriptStore
+	if transcriptStore != nil && cfg.Gateway.AdminToken == "" {
+		logger.Info("transcript export over http disabled: set gateway.admin_token to enable it")
+	}
+	gwConfig.Webhooks = webhooks
+	gwConfig.Flags = featureFlags
+	gwConfig.AdminToken = cfg.Gateway.AdminToken
+	gwConfig.Errors = recentErrors
+	gwConfig.Usage = usageLedger
+	gwConfig.Budget = budgetTracker
+	gwConfig.Reload = reload
+	if cfg.Gateway.HTTP.Enabled {
+		gwConfig.HTTP = &gateway.HTTPConfig{
+			Token:          cfg.Gateway.HTTP.Token,
+			CallbackSecret: cfg.Gateway.HTTP.CallbackSecret,
+			CallbackHosts:  cfg.Gateway.HTTP.CallbackHosts,
+		}
+	}
+	for _, o := range cfg.Gateway.Observers {
+		gwConfig.Observers = append(gwConfig.Observers, gateway.ObserverConfig{
+			Name:     o.Name,
+			Token:    o.Token,
+			Channels: o.Channels,
+		})
+	}
+	gwConfig.Approvals = approvalManager
+	for _, a := range cfg.Gateway.Approvers {
+		gwConfig.Approvers = append(gwConfig.Approvers, gateway.ApproverConfig{
+			Name:  a.Name,
+			Token: a.Token,
+		})
+	}
+	for _, w := range cfg.Gateway.Workers {
+		gwConfig.Workers = append(gwConfig.Workers, gateway.WorkerConfig{
+			Name:    w.Name,
+			Token:   w.Token,
+			Timeout: w.Timeout,
+		})
+	}
+	if agentInstance != nil {
+		gwConfig.Tools = agentInstance
+	}
+	gw, err := gateway.New(gwConfig)
 	if err != nil {
 		return fmt.Errorf("create gateway: %w", err)
 	}
+	if approvalManager != nil && len(gwConfig.Approvers) > 0 {
+		approvalManager.OnRequest(gw.NotifyApproval)
+	}
+	schedClients.Store(gw)
+	if agentInstance != nil && len(gwConfig.Observers) > 0 {
+		agentInstance.OnActivity(gw.Observe)
+	}
 
 	// Start gateway
 	fmt.Printf("OmniAgent running on %s\n", address)
 	fmt.Printf("Channels: %v\n", channels)
 	fmt.Println("Press Ctrl+C to stop")
 
-	if err := gw.Run(ctx); err != nil && err != context.Canceled {
+	_ = group.Go("gateway", gw.Run)
+	if skillMgr != nil && cfg.Skills.Enabled && cfg.Skills.Watch {
+		_ = group.Go("skills-watch", skillMgr.Run)
+	}
+	_ = group.Go("config-reload", func(ctx context.Context) error {
+		watchConfig(ctx, cfg.Reload, reload, logger)
+		return nil
+	})
+	if err := group.Wait(); err != nil {
 		return fmt.Errorf("gateway error: %w", err)
 	}
 
 	fmt.Println("OmniAgent stopped")
 	return nil
 }
+
+// voiceSTTConfig converts speech-to-text configuration for the voice
+// processor.
+// newFlags loads the feature flags with the overrides set at runtime.
+func newFlags(cfg *config.Config) (*flags.Flags, error) {
+	path := cfg.Flags.Path
+	if path == "" {
+		path = filepath.Join(cfg.Storage.Path, "flags.json")
+	}
+	featureFlags, err := flags.New(cfg.Flags.Features, path)
+	if err != nil {
+		return nil, fmt.Errorf("load feature flags: %w", err)
+	}
+	return featureFlags, nil
+}
+
+func voiceSTTConfig(c config.STTConfig) voice.STTConfig {
+	return voice.STTConfig{
+		Provider:  c.Provider,
+		APIKey:    c.APIKey,
+		BaseURL:   c.BaseURL,
+		Options:   c.Options,
+		Model:     c.Model,
+		Language:  c.Language,
+		Streaming: c.Streaming,
+	}
+}
+
+// voiceTTSConfig converts text-to-speech configuration for the voice
+// processor.
+func voiceTTSConfig(c config.TTSConfig) voice.TTSConfig {
+	return voice.TTSConfig{
+		Provider: c.Provider,
+		APIKey:   c.APIKey,
+		BaseURL:  c.BaseURL,
+		Model:    c.Model,
+		VoiceID:  c.VoiceID,
+		Options:  c.Options,
+	}
+}
+
+// voiceSTTFallbacks converts the STT fallback chain.
+func voiceSTTFallbacks(cs []config.STTConfig) []voice.STTConfig {
+	out := make([]voice.STTConfig, 0, len(cs))
+	for _, c := range cs {
+		out = append(out, voiceSTTConfig(c))
+	}
+	return out
+}
+
+// voiceTTSFallbacks converts the TTS fallback chain.
+func voiceTTSFallbacks(cs []config.TTSConfig) []voice.TTSConfig {
+	out := make([]voice.TTSConfig, 0, len(cs))
+	for _, c := range cs {
+		out = append(out, voiceTTSConfig(c))
+	}
+	return out
+}
+
+// experimentConfig converts experiment configuration for the agent.
+func experimentConfig(ec *config.ExperimentConfig) *agent.Experiment {
+	if ec == nil {
+		return nil
+	}
+	exp := &agent.Experiment{Name: ec.Name}
+	for _, v := range ec.Variants {
+		exp.Variants = append(exp.Variants, agent.Variant{
+			Name:         v.Name,
+			Weight:       v.Weight,
+			Model:        v.Model,
+			SystemPrompt: v.SystemPrompt,
+		})
+	}
+	return exp
+}
+
+// fallbackModels converts the configured fallback models.
+func fallbackModels(models []config.FallbackModelConfig) []agent.FallbackModel {
+	fallbacks := make([]agent.FallbackModel, 0, len(models))
+	for _, m := range models {
+		fallbacks = append(fallbacks, agent.FallbackModel{
+			Provider: m.Provider,
+			Model:    m.Model,
+			APIKey:   m.APIKey,
+			BaseURL:  m.BaseURL,
+		})
+	}
+	return fallbacks
+}
+
+// channelOverrides collects per-channel agent settings keyed by channel name.
+func channelOverrides(cfg *config.Config) map[string]agent.ChannelConfig {
+	overrides := map[string]agent.ChannelConfig{}
+	add := func(name string, c config.ChannelAgentConfig) {
+		if c.Model == "" && c.SystemPrompt == "" && c.Temperature == nil {
+			return
+		}
+		overrides[name] = agent.ChannelConfig{
+			Model:        c.Model,
+			SystemPrompt: c.SystemPrompt,
+			Temperature:  c.Temperature,
+		}
+	}
+	add("telegram", cfg.Channels.Telegram.ChannelAgentConfig)
+	add("discord", cfg.Channels.Discord.ChannelAgentConfig)
+	add("whatsapp", cfg.Channels.WhatsApp.ChannelAgentConfig)
+	add("sms", cfg.Channels.SMS.ChannelAgentConfig)
+	add("signal", cfg.Channels.Signal.ChannelAgentConfig)
+	return overrides
+}
+
+// channelTriggers collects group chat triggers keyed by channel name.
+func channelTriggers(cfg *config.Config) pipeline.TriggerConfig {
+	triggers := map[string]pipeline.Trigger{}
+	add := func(name string, c config.ChannelAgentConfig) {
+		triggers[name] = pipeline.Trigger{
+			Mentions: c.Trigger.Mentions,
+			Prefixes: c.Trigger.Prefixes,
+			Patterns: c.Trigger.Patterns,
+		}
+	}
+	add("telegram", cfg.Channels.Telegram.ChannelAgentConfig)
+	add("discord", cfg.Channels.Discord.ChannelAgentConfig)
+	add("whatsapp", cfg.Channels.WhatsApp.ChannelAgentConfig)
+	add("sms", cfg.Channels.SMS.ChannelAgentConfig)
+	add("signal", cfg.Channels.Signal.ChannelAgentConfig)
+	return pipeline.TriggerConfig{Channels: triggers}
+}
+
+// newThrottle converts provider rate limits to a model request throttle.
+func newThrottle(ac config.AgentConfig) *ratelimit.Throttle {
+	return ratelimit.NewThrottle(throttleConfig(ac))
+}
+
+// throttleConfig converts provider rate limits to throttle configuration.
+func throttleConfig(ac config.AgentConfig) ratelimit.ThrottleConfig {
+	providers := make(map[string]ratelimit.ProviderLimits, len(ac.RateLimits))
+	for name, l := range ac.RateLimits {
+		providers[name] = ratelimit.ProviderLimits{RequestsPerMinute: l.RequestsPerMinute, TokensPerMinute: l.TokensPerMinute}
+	}
+	return ratelimit.ThrottleConfig{Providers: providers, MaxWait: ac.ThrottleWait}
+}
+
+// newRateLimiter converts rate limit configuration to a limiter.
+func newRateLimiter(rc config.RateLimitConfig) *ratelimit.Limiter {
+	toLimits := func(l config.RateLimits) ratelimit.Limits {
+		return ratelimit.Limits{MessagesPerMinute: l.MessagesPerMinute, TokensPerHour: l.TokensPerHour}
+	}
+
+	channels := make(map[string]ratelimit.ChannelLimits, len(rc.Channels))
+	for name, c := range rc.Channels {
+		var cl ratelimit.ChannelLimits
+		if c.User != nil {
+			l := toLimits(*c.User)
+			cl.User = &l
+		}
+		if c.Channel != nil {
+			l := toLimits(*c.Channel)
+			cl.Channel = &l
+		}
+		channels[name] = cl
+	}
+
+	return ratelimit.New(ratelimit.Config{
+		User:     toLimits(rc.User),
+		Channel:  toLimits(rc.Channel),
+		Channels: channels,
+	})
+}
+
+// connectMCPServers connects configured MCP servers. A server that fails
+// to connect is logged and skipped so the others remain usable.
+func connectMCPServers(cfg *config.Config, agentInstance *agent.Agent, logger *slog.Logger) (*mcp.Manager, error) {
+	manager, err := mcp.New(mcp.Config{Registrar: agentInstance, Logger: logger})
+	if err != nil {
+		return nil, fmt.Errorf("create mcp manager: %w", err)
+	}
+
+	for _, sc := range cfg.MCPServers {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := manager.Connect(ctx, mcp.ServerConfig{
+			Name:      sc.Name,
+			Transport: sc.Transport,
+			Command:   sc.Command,
+			Args:      sc.Args,
+			Env:       sc.Env,
+			URL:       sc.URL,
+		})
+		cancel()
+		if err != nil {
+			logger.Warn("failed to connect mcp server", "server", sc.Name, "error", err)
+			continue
+		}
+		logger.Info("mcp server connected", "server", sc.Name, "tools", len(manager.Tools(sc.Name)))
+	}
+	return manager, nil
+}
+
+// attachmentPolicies converts attachment policies for the pipeline. It
+// reports false when no policy limits anything.
+func attachmentPolicies(ac config.AttachmentsConfig) (pipeline.AttachmentPolicyConfig, bool) {
+	toPolicy := func(p config.AttachmentPolicyConfig) pipeline.AttachmentPolicy {
+		return pipeline.AttachmentPolicy{
+			MaxBytes:    p.MaxBytes,
+			MimeTypes:   p.MimeTypes,
+			OnViolation: p.OnViolation,
+			Reply:       p.Reply,
+		}
+	}
+	limits := func(p config.AttachmentPolicyConfig) bool {
+		return p.MaxBytes > 0 || len(p.MimeTypes) > 0
+	}
+
+	enabled := limits(ac.Policy)
+	channels := make(map[string]pipeline.AttachmentPolicy, len(ac.Channels))
+	for name, p := range ac.Channels {
+		channels[name] = toPolicy(p)
+		enabled = enabled || limits(p)
+	}
+	return pipeline.AttachmentPolicyConfig{Default: toPolicy(ac.Policy), Channels: channels}, enabled
+}
+
+// openSealedDB opens an encrypted credential database with the configured
+// passphrase or OS keychain key.
+func openSealedDB(ec config.EncryptionConfig, path string, logger *slog.Logger) (*credstore.DB, error) {
+	passphrase, err := encryptionPassphrase(ec)
+	if err != nil {
+		return nil, err
+	}
+	return credstore.OpenDB(path, passphrase, logger)
+}
+
+// encryptionPassphrase returns the configured passphrase or OS keychain
+// key that credential state is encrypted with.
+func encryptionPassphrase(ec config.EncryptionConfig) (string, error) {
+	passphrase := ec.Passphrase
+	if ec.Keychain {
+		var err error
+		if passphrase, err = credstore.KeychainPassphrase("credentials"); err != nil {
+			return "", err
+		}
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("encryption enabled without a passphrase or keychain")
+	}
+	return passphrase, nil
+}
+
+// reloadTargets are the running components a config reload updates. Nil
+// components are skipped.
+type reloadTargets struct {
+	flags    *flags.Flags
+	access   *access.Control
+	people   *identity.Directory
+	agent    *agent.Agent
+	skills   *skillManager
+	throttle *ratelimit.Throttle
+	logger   *slog.Logger
+}
+
+// reloadConfig returns a function that re-reads the config file and
+// applies the settings that can change while the gateway runs: feature
+// flag defaults, access tiers, the agent's system prompt, temperature,
+// prompt variables, and channel overrides, skills, and model rate limits.
+// Everything else needs a restart.
+func reloadConfig(t reloadTargets) func() ([]string, error) {
+	return func() ([]string, error) {
+		next, err := config.Load(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+		// Build everything before applying anything, so a bad config
+		// changes nothing
+		var reloadedAccess *access.Control
+		if t.access != nil {
+			if reloadedAccess, err = newAccess(next, t.people); err != nil {
+				return nil, err
+			}
+		}
+		applied := []string{"flags"}
+		if t.agent != nil {
+			if err := t.agent.Reconfigure(agent.RuntimeConfig{
+				SystemPrompt: next.Agent.SystemPrompt,
+				Temperature:  next.Agent.Temperature,
+				PromptVars:   next.Agent.PromptVars,
+				Channels:     channelOverrides(next),
+			}); err != nil {
+				return nil, err
+			}
+			applied = append(applied, "agent")
+		}
+
+		t.flags.SetDefaults(next.Flags.Features)
+		if reloadedAccess != nil {
+			t.access.Update(reloadedAccess)
+			applied = append(applied, "access")
+		}
+		if t.throttle != nil {
+			t.throttle.SetLimits(throttleConfig(next.Agent))
+			applied = append(applied, "rate_limits")
+		}
+		if t.skills != nil && next.Skills.Enabled {
+			// A skill that fails to load keeps the rest of the reload
+			if err := t.skills.Load(context.Background(), next.Skills.Paths); err != nil {
+				t.logger.Warn("failed to reload skills", "error", err)
+			} else {
+				applied = append(applied, "skills")
+			}
+		}
+		return applied, nil
+	}
+}
+
+// watchConfig reloads the config file on SIGHUP and, if enabled, when the
+// file changes, until ctx is canceled.
+func watchConfig(ctx context.Context, rc config.ReloadConfig, reload func() ([]string, error), logger *slog.Logger) {
+	apply := func(trigger string) {
+		applied, err := reload()
+		if err != nil {
+			logger.Error("config reload failed", "trigger", trigger, "error", err)
+			return
+		}
+		logger.Warn("config reloaded", "trigger", trigger, "applied", strings.Join(applied, ", "))
+	}
+
+	if rc.Watch && cfgFile != "" {
+		go config.Watch(ctx, cfgFile, rc.Interval, func() { apply("file") })
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			apply("SIGHUP")
+		}
+	}
+}
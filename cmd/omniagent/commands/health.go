@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/health"
+	"github.com/plexusone/omniagent/identity"
+	"github.com/plexusone/omniagent/voice"
+)
+
+// newHealthMonitor creates probes for the configured LLM, voice, and
+// search providers. Owners are told when a provider fails or recovers.
+func newHealthMonitor(cfg *config.Config, agentInstance *agent.Agent, voiceProcessor *voice.Processor, people *identity.Directory, router *provider.Router, logger *slog.Logger) *health.Monitor {
+	enabled := func(name string) bool {
+		return len(cfg.Health.Probes) == 0 || slices.Contains(cfg.Health.Probes, name)
+	}
+
+	var probes []health.Probe
+	if agentInstance != nil {
+		if enabled("llm") {
+			probes = append(probes, health.Probe{Name: "llm", Check: agentInstance.Ping})
+		}
+		if tool, ok := agentInstance.Tool("web_search"); ok && enabled("search") {
+			if p, ok := tool.(interface{ Ping(context.Context) error }); ok {
+				probes = append(probes, health.Probe{Name: "search", Check: p.Ping})
+			}
+		}
+	}
+	if voiceProcessor != nil {
+		if enabled("stt") {
+			probes = append(probes, health.Probe{Name: "stt", Check: voiceProcessor.PingSTT})
+		}
+		if enabled("tts") {
+			probes = append(probes, health.Probe{Name: "tts", Check: voiceProcessor.PingTTS})
+		}
+	}
+
+	return health.New(health.Config{
+		Probes:           probes,
+		Interval:         cfg.Health.Interval,
+		Timeout:          cfg.Health.Timeout,
+		FailureThreshold: cfg.Health.FailureThreshold,
+		Logger:           logger,
+		Notify: func(e health.Event) {
+			notifyOwners(router, people, cfg.Owners, e.String(), logger)
+		},
+	})
+}
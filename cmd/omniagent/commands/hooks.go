@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/hooks"
+	"github.com/plexusone/omniagent/skills"
+)
+
+// newHooks loads the message hooks from the hooks directory.
+func newHooks(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*hooks.Manager, error) {
+	dir := cfg.Hooks.Dir
+	if dir == "" {
+		dir = filepath.Join(cfg.Storage.Path, "hooks")
+	}
+	m, err := hooks.Load(ctx, hooks.Config{
+		Dir:           dir,
+		Timeout:       cfg.Hooks.Timeout,
+		MemoryLimitMB: cfg.Hooks.MemoryLimitMB,
+		FuelLimit:     cfg.Hooks.FuelLimit,
+		Logger:        logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("message hooks loaded", "dir", dir, "hooks", m.Names())
+	return m, nil
+}
+
+// loadSkillHooks adds the hooks shipped by the agent's skills to m. A
+// skill whose hooks fail to compile is skipped.
+func loadSkillHooks(ctx context.Context, m *hooks.Manager, loaded []*skills.Skill, logger *slog.Logger) {
+	for _, skill := range loaded {
+		if !skill.HasHooks {
+			continue
+		}
+		if err := m.LoadSkill(ctx, skill); err != nil {
+			logger.Warn("failed to load skill hooks", "skill", skill.Name, "error", err)
+			continue
+		}
+		logger.Info("skill hooks loaded", "skill", skill.Name)
+	}
+}
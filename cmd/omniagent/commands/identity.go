@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/plexusone/omniagent/access"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/identity"
+)
+
+// newIdentities creates the directory of people linked across channels.
+func newIdentities(cfg *config.Config) (*identity.Directory, error) {
+	people := make([]identity.Person, 0, len(cfg.Identities))
+	for _, ic := range cfg.Identities {
+		people = append(people, identity.Person{ID: ic.ID, Name: ic.Name, Contacts: ic.Contacts})
+	}
+	d, err := identity.New(people)
+	if err != nil {
+		return nil, fmt.Errorf("identities: %w", err)
+	}
+	return d, nil
+}
+
+// newAccess creates access control, or returns nil when no tiers are
+// configured. Owners are always in the owner tier.
+func newAccess(cfg *config.Config, people *identity.Directory) (*access.Control, error) {
+	ac := cfg.Access
+	if ac.Default == "" && len(ac.Members) == 0 && len(ac.Tiers) == 0 {
+		return nil, nil
+	}
+	members := make(map[string][]string, len(ac.Members)+1)
+	for tier, ids := range ac.Members {
+		members[tier] = ids
+	}
+	members[access.TierOwner] = append(slices.Clone(members[access.TierOwner]), cfg.Owners...)
+
+	tiers := make(map[string]access.TierOverride, len(ac.Tiers))
+	for name, t := range ac.Tiers {
+		tiers[name] = access.TierOverride{Tools: t.Tools, MaxTokens: t.MaxTokens, Silent: t.Silent}
+	}
+	control, err := access.New(access.Config{Members: members, Tiers: tiers, Default: ac.Default}, people)
+	if err != nil {
+		return nil, fmt.Errorf("access: %w", err)
+	}
+	return control, nil
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/jobs"
+)
+
+// newJobs creates the background job manager and registers the
+// background_task tool and /tasks. Jobs run through the pool, so persona
+// routing applies, and post to their chat through the router.
+func newJobs(cfg *config.Config, agentInstance *agent.Agent, pool *agent.Pool, router *provider.Router, logger *slog.Logger) (*jobs.Manager, error) {
+	path := cfg.Jobs.Path
+	if path == "" {
+		path = filepath.Join(cfg.Storage.Path, "jobs.json")
+	}
+	m, err := jobs.New(jobs.Config{
+		Agent:             pool,
+		Sender:            router,
+		Path:              path,
+		MaxConcurrent:     cfg.Jobs.MaxConcurrent,
+		MaxRuntime:        cfg.Jobs.MaxRuntime,
+		MaxToolIterations: cfg.Jobs.MaxToolIterations,
+		ProgressInterval:  cfg.Jobs.ProgressInterval,
+		Logger:            logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tool := jobs.NewTool(m, router.ListProviders())
+	if err := agentInstance.RegisterTool(tool); err != nil {
+		return nil, fmt.Errorf("register background_task tool: %w", err)
+	}
+	agentInstance.RegisterCommand(tool.Command())
+	return m, nil
+}
@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/kb"
+)
+
+var kbCmd = &cobra.Command{
+	Use:   "kb",
+	Short: "Knowledge base commands",
+	Long: `Commands for managing the documents the agent can search.
+
+Enable the knowledge_search tool with knowledge.enabled in the config.`,
+}
+
+var kbAddCmd = &cobra.Command{
+	Use:   "add <path|url>...",
+	Short: "Add documents to the knowledge base",
+	Long: `Add PDF, Markdown, text, and HTML documents to the knowledge base.
+
+A directory adds every supported file under it. Adding a document again
+updates it; unchanged documents are skipped.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: kbAdd,
+}
+
+var kbListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List documents in the knowledge base",
+	RunE:  kbList,
+}
+
+var kbRemoveCmd = &cobra.Command{
+	Use:   "remove <source>",
+	Short: "Remove a document from the knowledge base",
+	Args:  cobra.ExactArgs(1),
+	RunE:  kbRemove,
+}
+
+var kbSyncCmd = &cobra.Command{
+	Use:   "sync [source]...",
+	Short: "Sync documents from knowledge sources",
+	Long: `Sync the sources in knowledge.sources now, or only the named ones.
+
+Only documents changed since the last sync are fetched. The gateway also
+syncs each source on its interval.`,
+	RunE: kbSync,
+}
+
+var kbSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the knowledge base",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  kbSearch,
+}
+
+func init() {
+	kbCmd.AddCommand(kbAddCmd)
+	kbCmd.AddCommand(kbListCmd)
+	kbCmd.AddCommand(kbRemoveCmd)
+	kbCmd.AddCommand(kbSearchCmd)
+	kbCmd.AddCommand(kbSyncCmd)
+}
+
+// openKnowledge opens the knowledge base. Callers must close the store.
+func openKnowledge(cfg *config.Config, logger *slog.Logger) (*kb.Base, *kb.Store, error) {
+	path := cfg.Knowledge.Path
+	if path == "" {
+		path = filepath.Join(cfg.Storage.Path, "kb.db")
+	}
+	store, err := kb.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open knowledge base: %w", err)
+	}
+	embedding := cfg.Knowledge.Embedding
+	if embedding == (config.EmbeddingConfig{}) {
+		embedding = cfg.Memory.Embedding
+	}
+	base, err := kb.New(kb.Config{
+		Store:       store,
+		Embedder:    newEmbedder(cfg, embedding),
+		ChunkSize:   cfg.Knowledge.ChunkSize,
+		SearchLimit: cfg.Knowledge.SearchLimit,
+		MinScore:    cfg.Knowledge.MinScore,
+		Logger:      logger,
+	})
+	if err != nil {
+		_ = store.Close()
+		return nil, nil, fmt.Errorf("create knowledge base: %w", err)
+	}
+	return base, store, nil
+}
+
+func kbAdd(cmd *cobra.Command, args []string) error {
+	base, store, err := openKnowledge(getConfig(), slog.Default())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := cmd.Context()
+	added := 0
+	for _, source := range args {
+		docs, err := base.Add(ctx, source)
+		if err != nil {
+			return fmt.Errorf("add %s: %w", source, err)
+		}
+		for _, doc := range docs {
+			fmt.Printf("Added %s (%d chunks)\n", doc.Source, doc.Chunks)
+		}
+		added += len(docs)
+	}
+	if added == 0 {
+		fmt.Println("No new or changed documents.")
+	}
+	return nil
+}
+
+func kbList(cmd *cobra.Command, args []string) error {
+	base, store, err := openKnowledge(getConfig(), slog.Default())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	docs, err := base.Documents(cmd.Context())
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		fmt.Println("The knowledge base is empty.")
+		return nil
+	}
+	fmt.Printf("%-60s %6s  %s\n", "SOURCE", "CHUNKS", "ADDED")
+	for _, d := range docs {
+		fmt.Printf("%-60s %6d  %s\n", d.Source, d.Chunks, d.AddedAt.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+func kbRemove(cmd *cobra.Command, args []string) error {
+	base, store, err := openKnowledge(getConfig(), slog.Default())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	source := args[0]
+	err = base.Remove(cmd.Context(), source)
+	if errors.Is(err, kb.ErrNotFound) && !strings.Contains(source, "://") {
+		// Files are stored by absolute path
+		if abs, absErr := filepath.Abs(source); absErr == nil {
+			err = base.Remove(cmd.Context(), abs)
+		}
+	}
+	if errors.Is(err, kb.ErrNotFound) {
+		return fmt.Errorf("no document from %q", source)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", source)
+	return nil
+}
+
+func kbSync(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+	if len(cfg.Knowledge.Sources) == 0 {
+		return errors.New("no knowledge sources configured (knowledge.sources)")
+	}
+	base, store, err := openKnowledge(cfg, slog.Default())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := cmd.Context()
+	syncer, err := newKnowledgeSyncer(ctx, cfg, base, slog.Default())
+	if err != nil {
+		return err
+	}
+	names := args
+	if len(names) == 0 {
+		names = syncer.Sources()
+	}
+	for _, name := range names {
+		res, err := syncer.Sync(ctx, name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d updated, %d unchanged, %d removed, %d failed\n",
+			name, res.Updated, res.Unchanged, res.Removed, res.Failed)
+	}
+	return nil
+}
+
+// newKnowledgeSyncer creates a syncer for the configured knowledge sources.
+func newKnowledgeSyncer(ctx context.Context, cfg *config.Config, base *kb.Base, logger *slog.Logger) (*kb.Syncer, error) {
+	var sources []kb.Source
+	for _, sc := range cfg.Knowledge.Sources {
+		var conn kb.Connector
+		switch sc.Type {
+		case "notion":
+			if sc.Token == "" {
+				return nil, fmt.Errorf("knowledge source %q: token required", sc.Name)
+			}
+			conn = kb.NewNotion(kb.NotionConfig{Token: sc.Token})
+		case "google_drive":
+			creds, err := os.ReadFile(sc.CredentialsFile)
+			if err != nil {
+				return nil, fmt.Errorf("knowledge source %q: read credentials: %w", sc.Name, err)
+			}
+			drive, err := kb.NewDrive(ctx, kb.DriveConfig{Credentials: creds, FolderID: sc.FolderID})
+			if err != nil {
+				return nil, fmt.Errorf("knowledge source %q: %w", sc.Name, err)
+			}
+			conn = drive
+		default:
+			return nil, fmt.Errorf("knowledge source %q: unknown type %q", sc.Name, sc.Type)
+		}
+		sources = append(sources, kb.Source{
+			Name:      sc.Name,
+			Connector: conn,
+			Include:   sc.Include,
+			Exclude:   sc.Exclude,
+			Interval:  sc.Interval,
+		})
+	}
+	syncer, err := kb.NewSyncer(kb.SyncConfig{Base: base, Sources: sources, Logger: logger})
+	if err != nil {
+		return nil, fmt.Errorf("create knowledge sync: %w", err)
+	}
+	return syncer, nil
+}
+
+func kbSearch(cmd *cobra.Command, args []string) error {
+	base, store, err := openKnowledge(getConfig(), slog.Default())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	matches, err := base.Search(cmd.Context(), strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		fmt.Println("No relevant passages found.")
+		return nil
+	}
+	for _, m := range matches {
+		fmt.Printf("%.2f  %s (part %d)\n      %s\n", m.Score, m.Source, m.Seq+1, preview(m.Content))
+	}
+	return nil
+}
+
+// preview returns a passage on one line, shortened.
+func preview(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 100 {
+		return string(r[:100]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/audit"
+	"github.com/plexusone/omniagent/flags"
+	"github.com/plexusone/omniagent/mcp"
+	"github.com/plexusone/omniagent/skills"
+)
+
+var (
+	mcpServeHTTP          string
+	mcpServeToken         string
+	mcpServeApprovalTools bool
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Model Context Protocol commands",
+	Long:  "Commands for using omniagent with other MCP-capable agent runtimes.",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve tools and skills over MCP",
+	Long: `Expose omniagent's enabled tools as MCP tools and its skills as MCP
+prompts, so clients such as Claude Desktop or an IDE can use them.
+
+By default the server speaks MCP over stdin/stdout, for clients that
+launch it as a subprocess. Use --http to serve streamable HTTP instead.
+HTTP clients must send the --token (or $OMNIAGENT_MCP_TOKEN) as a bearer
+token; without one, the server only listens on loopback addresses.
+
+MCP clients cannot be asked for approval, so tools listed under
+approvals.requires_approval are not served unless
+--include-approval-tools is set. Tools turned off by feature flags are
+never served.`,
+	RunE: runMCPServe,
+}
+
+func init() {
+	mcpServeCmd.Flags().StringVar(&mcpServeHTTP, "http", "", "serve streamable HTTP on this address (e.g. 127.0.0.1:8765)")
+	mcpServeCmd.Flags().StringVar(&mcpServeToken, "token", os.Getenv("OMNIAGENT_MCP_TOKEN"), "bearer token HTTP clients must send")
+	mcpServeCmd.Flags().BoolVar(&mcpServeApprovalTools, "include-approval-tools", false, "also serve tools that need approval, which MCP clients run without it")
+	mcpCmd.AddCommand(mcpServeCmd)
+}
+
+func runMCPServe(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+	logger := slog.Default() // Logs go to stderr, leaving stdout for the protocol
+
+	builtins, err := buildTools(cfg, logger)
+	defer builtins.Close()
+	if err != nil {
+		return err
+	}
+	featureFlags, err := newFlags(cfg)
+	if err != nil {
+		return err
+	}
+	tools := mcpTools(builtins.Tools, cfg.Approvals.RequiresApproval, featureFlags, logger)
+
+	var loaded []*skills.Skill
+	if cfg.Skills.Enabled {
+		paths := cfg.Skills.Paths
+		if len(paths) == 0 {
+			paths = skills.DefaultSearchPaths()
+		}
+		discovered, err := skills.Discover(paths)
+		if err != nil {
+			logger.Warn("failed to load skills", "error", err)
+		}
+		loaded = skills.FilterAvailable(discovered)
+	}
+
+	var auditor agent.ToolAuditFunc
+	if cfg.Audit.Enabled {
+		auditLog, err := audit.Open(auditPath(cfg))
+		if err != nil {
+			return fmt.Errorf("open audit log: %w", err)
+		}
+		defer auditLog.Close()
+		auditor = agent.AuditTo(auditLog, "", logger)
+	}
+
+	server := mcp.NewServer(mcp.ServeConfig{
+		Tools:  tools,
+		Skills: loaded,
+		Audit:  auditor,
+		Logger: logger,
+	})
+	logger.Info("mcp server ready", "tools", len(tools), "prompts", len(loaded))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if mcpServeHTTP == "" {
+		return server.Run(ctx, &sdk.StdioTransport{})
+	}
+	if mcpServeToken == "" && !isLoopbackAddr(mcpServeHTTP) {
+		return fmt.Errorf("refusing to serve tools on %s without a token; set --token or listen on a loopback address", mcpServeHTTP)
+	}
+
+	var handler http.Handler = sdk.NewStreamableHTTPHandler(func(*http.Request) *sdk.Server { return server }, nil)
+	if mcpServeToken != "" {
+		handler = requireBearer(mcpServeToken, handler)
+	}
+	httpServer := &http.Server{
+		Addr:              mcpServeHTTP,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("serving mcp over http", "address", mcpServeHTTP)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("mcp http server: %w", err)
+	}
+	return nil
+}
+
+// mcpTools returns the tools to serve over MCP. Tools turned off by flags
+// are left out, and so are tools that need approval unless
+// --include-approval-tools is set, since nobody can approve an MCP call.
+func mcpTools(tools []agent.Tool, requiresApproval []string, featureFlags *flags.Flags, logger *slog.Logger) []agent.Tool {
+	var served []agent.Tool
+	for _, tool := range tools {
+		name := tool.Name()
+		switch {
+		case !featureFlags.ToolEnabled(name):
+			logger.Info("not serving disabled tool", "tool", name)
+		case !mcpServeApprovalTools && (slices.Contains(requiresApproval, "*") || slices.Contains(requiresApproval, name)):
+			logger.Info("not serving tool that needs approval", "tool", name)
+		default:
+			served = append(served, tool)
+		}
+	}
+	return served
+}
+
+// requireBearer rejects requests that do not carry token as a bearer
+// token.
+func requireBearer(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackAddr reports whether a listen address only accepts
+// connections from this host.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"log/slog"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/media"
+	"github.com/plexusone/omniagent/voice"
+)
+
+// newAttachmentReader creates the reader that shows the model incoming
+// files. Images need a vision model and audio needs voice.
+func newAttachmentReader(cfg *config.Config, voiceProcessor *voice.Processor, logger *slog.Logger) *media.Reader {
+	rc := media.Config{MaxTextBytes: cfg.Attachments.MaxTextBytes}
+
+	vision := cfg.Attachments.Vision
+	if vision.Model == "" && vision.BaseURL == "" && cfg.Agent.Provider == "openai" {
+		vision.Model = cfg.Agent.Model
+	}
+	if vision.APIKey == "" && vision.BaseURL == "" && cfg.Agent.Provider == "openai" {
+		vision.APIKey = cfg.Agent.APIKey
+	}
+	if vision.Model != "" {
+		rc.Describer = media.NewVisionDescriber(media.VisionConfig{
+			BaseURL: vision.BaseURL,
+			APIKey:  vision.APIKey,
+			Model:   vision.Model,
+		})
+	}
+	if voiceProcessor != nil {
+		rc.Transcriber = voiceProcessor
+	}
+	logger.Info("attachment reading enabled", "vision_model", vision.Model, "audio", voiceProcessor != nil)
+	return media.New(rc)
+}
+
+// isAudio reports whether m is audio data, which WhatsApp sends itself.
+func isAudio(m provider.Media) bool {
+	return len(m.Data) > 0 && (m.Type == provider.MediaTypeAudio || m.Type == provider.MediaTypeVoice)
+}
+
+// hasData reports whether m carries its data, which Signal uploads.
+func hasData(m provider.Media) bool { return len(m.Data) > 0 }
+
+// hasURL reports whether m links its data, which SMS attaches as MMS.
+func hasURL(m provider.Media) bool { return m.URL != "" }
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Local model commands",
+	Long: `Commands for inspecting models served by a local inference server.
+
+Supported providers are "ollama" and "openai-compatible" (llama.cpp,
+LM Studio, vLLM). The provider and base URL come from the agent config.`,
+}
+
+var modelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available models",
+	Long:  "List the models available on the configured local inference server.",
+	RunE:  listModels,
+}
+
+func init() {
+	modelsCmd.AddCommand(modelsListCmd)
+}
+
+func listModels(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+
+	if !agent.IsLocalProvider(cfg.Agent.Provider) {
+		return fmt.Errorf("provider %q is not a local provider (use ollama or openai-compatible)", cfg.Agent.Provider)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	models, err := agent.ListModels(ctx, cfg.Agent.Provider, cfg.Agent.BaseURL, cfg.Agent.APIKey)
+	if err != nil {
+		return fmt.Errorf("list models: %w", err)
+	}
+
+	if len(models) == 0 {
+		fmt.Println("No models found.")
+		return nil
+	}
+
+	fmt.Printf("Found %d models:\n\n", len(models))
+	for _, m := range models {
+		marker := " "
+		if m.Name == cfg.Agent.Model {
+			marker = "*"
+		}
+		ctxLen := ""
+		if n, err := agent.DetectContextLength(ctx, cfg.Agent.Provider, cfg.Agent.BaseURL, m.Name); err == nil && n > 0 {
+			ctxLen = fmt.Sprintf("%d ctx", n)
+		}
+		size := ""
+		if m.Size > 0 {
+			size = fmt.Sprintf("%.1f GB", float64(m.Size)/1e9)
+		}
+		fmt.Printf("%s %-40s %10s  %s\n", marker, m.Name, size, ctxLen)
+	}
+	return nil
+}
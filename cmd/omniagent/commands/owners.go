@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/identity"
+)
+
+// notifyOwners sends text to each owner contact ID ("channel:senderID"),
+// or each contact of an owner person ID, as a direct message. Delivery
+// failures are logged.
+func notifyOwners(router *provider.Router, people *identity.Directory, owners []string, text string, logger *slog.Logger) {
+	for _, owner := range people.Contacts(owners) {
+		channel, chatID, ok := strings.Cut(owner, ":")
+		if !ok {
+			logger.Warn("invalid owner contact ID", "owner", owner)
+			continue
+		}
+		if err := router.Send(context.Background(), channel, chatID, provider.OutgoingMessage{Content: text}); err != nil {
+			logger.Warn("owner notification not delivered", "owner", owner, "error", err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var promptSince time.Duration
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "System prompt history commands",
+	Long: `Commands for matching behavior changes to system prompt changes.
+
+Every trace records a fingerprint of the system prompt with its persona
+and skills. Enable traces with eval.enabled in the config.`,
+}
+
+var promptHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List system prompt fingerprints",
+	Long:  "List each system prompt fingerprint with when it was first and last used, how often, and by which models.",
+	RunE:  promptHistory,
+}
+
+var promptShowCmd = &cobra.Command{
+	Use:   "show <fingerprint>",
+	Short: "Print the system prompt with a fingerprint",
+	Args:  cobra.ExactArgs(1),
+	RunE:  promptShow,
+}
+
+func init() {
+	promptHistoryCmd.Flags().DurationVar(&promptSince, "since", 0, "history period (0 for all time)")
+	promptCmd.AddCommand(promptHistoryCmd)
+	promptCmd.AddCommand(promptShowCmd)
+}
+
+func promptHistory(cmd *cobra.Command, args []string) error {
+	store, err := openEvalStore(getConfig())
+	if err != nil {
+		return fmt.Errorf("open eval store: %w", err)
+	}
+
+	var since time.Time
+	if promptSince > 0 {
+		since = time.Now().Add(-promptSince)
+	}
+	versions, err := store.PromptHistory(since)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		fmt.Println("No prompt fingerprints recorded in this period.")
+		return nil
+	}
+	fmt.Printf("%-14s %-17s %-17s %7s  %s\n", "FINGERPRINT", "FIRST SEEN", "LAST SEEN", "TRACES", "MODELS")
+	for _, v := range versions {
+		fmt.Printf("%-14s %-17s %-17s %7d  %s\n", v.Fingerprint,
+			v.FirstSeen.Format("2006-01-02 15:04"), v.LastSeen.Format("2006-01-02 15:04"),
+			v.Traces, strings.Join(v.Models, ", "))
+	}
+	return nil
+}
+
+func promptShow(cmd *cobra.Command, args []string) error {
+	store, err := openEvalStore(getConfig())
+	if err != nil {
+		return fmt.Errorf("open eval store: %w", err)
+	}
+	fingerprint, prompt, err := store.Prompt(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# %s\n\n%s\n", fingerprint, prompt)
+	return nil
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/approvals"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/proposals"
+	"github.com/plexusone/omniagent/skills"
+)
+
+// newProposals creates the proposal manager and registers the
+// propose_change tool. Approved config changes are reloaded right away;
+// skill changes are picked up on restart.
+func newProposals(cfg *config.Config, agentInstance *agent.Agent, approvalManager *approvals.Manager, reload func() ([]string, error), logger *slog.Logger) (*proposals.Manager, error) {
+	targets := cfg.Proposals.Targets
+	if len(targets) == 0 {
+		targets = []string{proposals.TargetConfig, proposals.TargetSkill}
+	}
+	pc := proposals.Config{
+		Dir:       proposalsDir(cfg),
+		Approvals: approvalManager,
+		Logger:    logger,
+		OnApply: func(c proposals.Change) {
+			if c.Target != proposals.TargetConfig {
+				return
+			}
+			applied, err := reload()
+			if err != nil {
+				logger.Error("reload after config change failed", "change", c.ID, "error", err)
+				return
+			}
+			logger.Info("config reloaded after change", "change", c.ID, "applied", applied)
+		},
+	}
+	if slices.Contains(targets, proposals.TargetConfig) {
+		if cfgFile == "" {
+			logger.Warn("no config file, config proposals disabled")
+		}
+		pc.ConfigPath = cfgFile
+	}
+	if slices.Contains(targets, proposals.TargetSkill) {
+		pc.SkillsDir = proposalSkillsDir(cfg)
+	}
+
+	m, err := proposals.New(pc)
+	if err != nil {
+		return nil, err
+	}
+	if err := agentInstance.RegisterTool(proposals.NewTool(m)); err != nil {
+		return nil, fmt.Errorf("register propose_change tool: %w", err)
+	}
+	return m, nil
+}
+
+// proposalsDir returns where changes are logged.
+func proposalsDir(cfg *config.Config) string {
+	if cfg.Proposals.Path != "" {
+		return cfg.Proposals.Path
+	}
+	return filepath.Join(cfg.Storage.Path, "proposals")
+}
+
+// proposalSkillsDir returns where proposed skills are written.
+func proposalSkillsDir(cfg *config.Config) string {
+	if cfg.Proposals.SkillsDir != "" {
+		return cfg.Proposals.SkillsDir
+	}
+	if len(cfg.Skills.Paths) > 0 {
+		return cfg.Skills.Paths[0]
+	}
+	return skills.DefaultSearchPaths()[0]
+}
+
+var changesCmd = &cobra.Command{
+	Use:   "changes",
+	Short: "Review changes the agent made to its config and skills",
+	Long: `Commands for the changes the agent proposed with the propose_change
+tool and an approver accepted (see proposals in the config).`,
+}
+
+var changesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List applied changes, newest first",
+	RunE:  changesList,
+}
+
+var changesShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a change and its diff",
+	Args:  cobra.ExactArgs(1),
+	RunE:  changesShow,
+}
+
+var changesRollbackCmd = &cobra.Command{
+	Use:   "rollback <id>",
+	Short: "Restore the file a change replaced",
+	Long: `Restore the file a change replaced, or remove it if the change created
+it. Only the latest change to a file can be rolled back. A running gateway
+reads a rolled back config on POST /v1/admin/reload or restart.`,
+	Args: cobra.ExactArgs(1),
+	RunE: changesRollback,
+}
+
+func init() {
+	changesCmd.AddCommand(changesListCmd)
+	changesCmd.AddCommand(changesShowCmd)
+	changesCmd.AddCommand(changesRollbackCmd)
+}
+
+// openProposals opens the change log without a running gateway.
+func openProposals() (*proposals.Manager, error) {
+	return proposals.New(proposals.Config{Dir: proposalsDir(getConfig())})
+}
+
+func changesList(cmd *cobra.Command, args []string) error {
+	m, err := openProposals()
+	if err != nil {
+		return err
+	}
+	history, err := m.History()
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+	fmt.Printf("%-8s %-16s %-7s %-20s %-12s %s\n", "ID", "APPLIED", "TARGET", "NAME", "STATUS", "REASON")
+	for _, c := range history {
+		status := "applied"
+		if c.RolledBack != nil {
+			status = "rolled back"
+		}
+		name := c.Name
+		if name == "" {
+			name = filepath.Base(c.Path)
+		}
+		fmt.Printf("%-8s %-16s %-7s %-20s %-12s %s\n", c.ID, c.Applied.Format("2006-01-02 15:04"), c.Target, name, status, preview(c.Reason))
+	}
+	return nil
+}
+
+func changesShow(cmd *cobra.Command, args []string) error {
+	m, err := openProposals()
+	if err != nil {
+		return err
+	}
+	history, err := m.History()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(history, func(c proposals.Change) bool { return c.ID == args[0] })
+	if i < 0 {
+		return proposals.ErrNotFound
+	}
+	c := history[i]
+	fmt.Printf("Change:   %s\n", c.ID)
+	fmt.Printf("File:     %s\n", c.Path)
+	fmt.Printf("Applied:  %s, approved by %s\n", c.Applied.Format(time.DateTime), c.Approver)
+	if c.RolledBack != nil {
+		fmt.Printf("Rolled back: %s\n", c.RolledBack.Format(time.DateTime))
+	}
+	if c.SessionID != "" {
+		fmt.Printf("Session:  %s\n", c.SessionID)
+	}
+	if c.Reason != "" {
+		fmt.Printf("Reason:   %s\n", c.Reason)
+	}
+	fmt.Printf("\n%s", c.Diff)
+	return nil
+}
+
+func changesRollback(cmd *cobra.Command, args []string) error {
+	m, err := openProposals()
+	if err != nil {
+		return err
+	}
+	c, err := m.Rollback(args[0])
+	if errors.Is(err, proposals.ErrNotFound) {
+		return fmt.Errorf("change %s not found", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rolled back %s (%s)\n", c.ID, c.Path)
+	return nil
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/pipeline"
+)
+
+// feedbackReactions are the reactions handled when only eval is enabled.
+var feedbackReactions = map[string]string{
+	"👍":  pipeline.ReactionUp,
+	"+1": pipeline.ReactionUp,
+	"👎":  pipeline.ReactionDown,
+	"-1": pipeline.ReactionDown,
+}
+
+// registerReactions handles emoji reactions on channels that report them:
+// thumbs rate the chat's last reply and, when reactions are enabled,
+// question marks ask the agent to explain it through handler.
+func registerReactions(router *provider.Router, rc config.ReactionsConfig, agentInstance *agent.Agent, handler provider.MessageHandler, logger *slog.Logger) {
+	reactions := pipeline.ReactionConfig{
+		Emoji: feedbackReactions,
+		Feedback: func(ctx context.Context, sessionID string, rating int) error {
+			return agentInstance.RecordFeedback(ctx, sessionID, rating, "", "reaction")
+		},
+		Logger: logger,
+	}
+	if rc.Enabled {
+		reactions.Emoji = rc.Emoji
+		reactions.Channels = rc.Channels
+		reactions.Elaborate = agentInstance.ElaborationPrompt
+		reactions.Handler = handler
+	}
+	events := pipeline.Reactions(reactions)
+	for _, name := range router.ListProviders() {
+		if p, ok := router.GetProvider(name); ok {
+			p.OnEvent(events)
+		}
+	}
+}
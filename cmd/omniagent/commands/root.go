@@ -36,6 +36,10 @@ Show configuration:
 			return nil
 		}
 
+		if cfgFile == "" {
+			cfgFile = config.Find()
+		}
+
 		var err error
 		cfg, err = config.Load(cfgFile)
 		if err != nil {
@@ -51,13 +55,30 @@ func Execute() error {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default: omniagent.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default: omniagent.yaml in the working or user config directory)")
 
 	// Add subcommands
 	rootCmd.AddCommand(gatewayCmd)
 	rootCmd.AddCommand(channelsCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(skillsCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(modelsCmd)
+	rootCmd.AddCommand(usageCmd)
+	rootCmd.AddCommand(evalCmd)
+	rootCmd.AddCommand(promptCmd)
+	rootCmd.AddCommand(transcriptCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(workspacesCmd)
+	rootCmd.AddCommand(changesCmd)
+	rootCmd.AddCommand(jobsCmd)
+	rootCmd.AddCommand(kbCmd)
+	rootCmd.AddCommand(templatesCmd)
+	rootCmd.AddCommand(mcpCmd)
+	rootCmd.AddCommand(workerCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(serviceCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
@@ -0,0 +1,244 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/sandbox"
+	"github.com/plexusone/omniagent/skills"
+)
+
+// newToolSandbox creates the sandbox for an exec tool. The mode is the
+// tool's own setting, falling back to tools.sandbox.mode. A nil sandbox
+// means the tool runs on the host.
+func newToolSandbox(ctx context.Context, cfg *config.Config, toolMode, workingDir string) (sandbox.Sandbox, error) {
+	mode := toolMode
+	if mode == "" {
+		mode = cfg.Tools.Sandbox.Mode
+	}
+	mode, err := sandbox.ParseMode(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case sandbox.ModeDocker:
+		dc, err := dockerConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if workingDir != "" {
+			absDir, err := filepath.Abs(workingDir)
+			if err != nil {
+				return nil, fmt.Errorf("resolve working dir: %w", err)
+			}
+			dc.Mounts = append(dc.Mounts, sandbox.DockerMount{HostPath: absDir, ContainerPath: "/workspace"})
+			dc.WorkingDir = "/workspace"
+		}
+
+		return newDockerSandbox(ctx, cfg, dc, "tools.sandbox.mode")
+
+	case sandbox.ModeWASM:
+		sc := wasmConfig(cfg, sandbox.DefaultConfig())
+		if workingDir != "" {
+			sc.WorkingDir = workingDir
+			sc.Capabilities = []sandbox.Capability{sandbox.CapFSRead, sandbox.CapFSWrite}
+		}
+		return newWASMSandbox(ctx, cfg, sc)
+
+	case sandbox.ModeProcess:
+		sc := sandbox.DefaultConfig()
+		sc.WorkingDir = workingDir
+		sc.Timeout = 0 // The tool applies its own timeout
+		return sandbox.NewProcess(sc), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// newWorkspaceSandbox creates a sandbox for an exec tool that runs each
+// session's commands in its persistent workspace. The mode is resolved as
+// for newToolSandbox, and must not be none.
+func newWorkspaceSandbox(cfg *config.Config, toolMode string) (sandbox.Sandbox, error) {
+	mode := toolMode
+	if mode == "" {
+		mode = cfg.Tools.Sandbox.Mode
+	}
+	if mode, err := sandbox.ParseMode(mode); err != nil {
+		return nil, err
+	} else if mode == sandbox.ModeNone {
+		return nil, errors.New("tools.sandbox.workspaces needs a sandbox mode")
+	}
+	return sandbox.NewWorkspaceSandbox(sandbox.WorkspaceSandboxConfig{
+		Workspaces: sandbox.NewWorkspaces(workspacesDir(cfg)),
+		Key:        agent.SessionFromContext,
+		New: func(ctx context.Context, dir string) (sandbox.Sandbox, error) {
+			return newToolSandbox(ctx, cfg, toolMode, dir)
+		},
+	})
+}
+
+// workspacesDir returns where persistent sandbox workspaces live.
+func workspacesDir(cfg *config.Config) string {
+	if cfg.Tools.Sandbox.Workspaces.Dir != "" {
+		return cfg.Tools.Sandbox.Workspaces.Dir
+	}
+	return filepath.Join(cfg.Storage.Path, "workspaces")
+}
+
+// newSkillSandbox creates the sandbox a skill's scripts run in, limited
+// to what the skill declares. The skill directory is always readable and
+// writable only with fs_write; declared paths are mounted only with
+// fs_read or fs_write; the network is reachable only with net_http. The
+// mode is skills.sandbox, falling back to tools.sandbox.mode; the process
+// sandbox is refused, as it can enforce none of this.
+func newSkillSandbox(ctx context.Context, cfg *config.Config, skill *skills.Skill) (sandbox.Sandbox, error) {
+	mode := cfg.Skills.Sandbox
+	if mode == "" {
+		mode = cfg.Tools.Sandbox.Mode
+	}
+	mode, err := sandbox.ParseMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := skill.Sandbox(sandbox.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	writable := sc.HasCapability(sandbox.CapFSWrite)
+
+	switch mode {
+	case sandbox.ModeDocker:
+		dc, err := dockerConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !sc.HasCapability(sandbox.CapNetHTTP):
+			dc.NetworkMode = "none"
+		case len(sc.AllowedHosts) > 0:
+			// Container networking is all or nothing
+			return nil, fmt.Errorf("skill %s limits net_http to hosts, which the container sandbox cannot enforce", skill.Name)
+		}
+		for i, path := range sc.AllowedPaths {
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return nil, fmt.Errorf("resolve skill path: %w", err)
+			}
+			mount := sandbox.DockerMount{HostPath: absPath, ContainerPath: absPath, ReadOnly: !writable}
+			if i == 0 {
+				mount.ContainerPath = "/workspace" // The skill directory
+			}
+			dc.Mounts = append(dc.Mounts, mount)
+		}
+		dc.WorkingDir = "/workspace"
+		return newDockerSandbox(ctx, cfg, dc, "skills.sandbox")
+
+	case sandbox.ModeWASM:
+		sc = wasmConfig(cfg, sc)
+		if !writable && !sc.HasCapability(sandbox.CapFSRead) {
+			sc.Capabilities = append(sc.Capabilities, sandbox.CapFSRead) // To read the script
+		}
+		return newWASMSandbox(ctx, cfg, sc)
+
+	case sandbox.ModeProcess:
+		return nil, errors.New("the process sandbox cannot enforce skill permissions; set skills.sandbox to docker or wasm")
+
+	default:
+		return nil, nil
+	}
+}
+
+// dockerConfig returns the container sandbox settings from
+// tools.sandbox.docker.
+func dockerConfig(cfg *config.Config) (sandbox.DockerConfig, error) {
+	dc := sandbox.DefaultDockerConfig()
+	dc.Host = cfg.Tools.Sandbox.Docker.Host
+	dc.Runtime = cfg.Tools.Sandbox.Docker.Runtime
+	if cfg.Tools.Sandbox.Docker.Image != "" {
+		dc.Image = cfg.Tools.Sandbox.Docker.Image
+	}
+	if cfg.Tools.Sandbox.Docker.NetworkMode != "" {
+		networkMode, err := sandbox.ParseNetworkMode(cfg.Tools.Sandbox.Docker.NetworkMode)
+		if err != nil {
+			return sandbox.DockerConfig{}, err
+		}
+		dc.NetworkMode = networkMode
+	}
+	if cfg.Tools.Sandbox.Docker.MemoryMB > 0 {
+		dc.MemoryLimit = int64(cfg.Tools.Sandbox.Docker.MemoryMB) * 1024 * 1024
+	}
+	if cfg.Tools.Sandbox.Docker.Timeout > 0 {
+		dc.Timeout = cfg.Tools.Sandbox.Docker.Timeout
+	}
+	return dc, nil
+}
+
+// newDockerSandbox creates a container sandbox on the engine set by
+// tools.sandbox.docker.engine and pulls its image. The setting names
+// where the sandbox mode came from, for the hint when the engine is down.
+func newDockerSandbox(ctx context.Context, cfg *config.Config, dc sandbox.DockerConfig, setting string) (sandbox.Sandbox, error) {
+	engine, err := sandbox.ParseEngine(cfg.Tools.Sandbox.Docker.Engine)
+	if err != nil {
+		return nil, err
+	}
+	if !sandbox.IsEngineAvailable(ctx, engine, dc.Host) {
+		return nil, fmt.Errorf("%s sandbox: %s is not reachable; %s, or set %s to wasm", engine, engine, engineHint(engine), setting)
+	}
+	cs, err := sandbox.NewContainerSandbox(ctx, engine, dc, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create %s sandbox: %w", engine, err)
+	}
+	if err := cs.EnsureImage(ctx); err != nil {
+		cs.Close()
+		return nil, fmt.Errorf("prepare %s sandbox: %w", engine, err)
+	}
+	return cs, nil
+}
+
+// wasmConfig applies the tools.sandbox.wasm settings to sc.
+func wasmConfig(cfg *config.Config, sc sandbox.Config) sandbox.Config {
+	if cfg.Tools.Sandbox.WASM.MemoryLimitMB > 0 {
+		sc.MemoryLimitMB = cfg.Tools.Sandbox.WASM.MemoryLimitMB
+	}
+	sc.FuelLimit = cfg.Tools.Sandbox.WASM.FuelLimit
+	sc.Timeout = 0 // The tool applies its own timeout
+	return sc
+}
+
+// newWASMSandbox creates a WASM shell sandbox.
+func newWASMSandbox(ctx context.Context, cfg *config.Config, sc sandbox.Config) (sandbox.Sandbox, error) {
+	ws, err := sandbox.NewWASMShell(ctx, sc, cfg.Tools.Sandbox.WASM.Module)
+	if err != nil {
+		return nil, fmt.Errorf("create wasm sandbox: %w", err)
+	}
+	return ws, nil
+}
+
+// engineHint suggests how to make a container engine available.
+func engineHint(engine string) string {
+	switch engine {
+	case sandbox.EnginePodman:
+		return "run systemctl --user enable --now podman.socket or set tools.sandbox.docker.host"
+	case sandbox.EngineContainerd:
+		return "install nerdctl and check that containerd is running"
+	default:
+		return dockerHint()
+	}
+}
+
+// dockerHint suggests how to make Docker available on this platform.
+func dockerHint() string {
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		return "start Docker Desktop"
+	default:
+		return "start the docker service or check DOCKER_HOST"
+	}
+}
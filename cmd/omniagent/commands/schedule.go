@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/gateway"
+	"github.com/plexusone/omniagent/scheduler"
+	"github.com/plexusone/omniagent/tasks"
+	"github.com/plexusone/omniagent/tools/sendlater"
+)
+
+// newScheduler creates the task scheduler and adds the configured tasks.
+// Task results are delivered to the task's channel through the router, or
+// to the gateway client that scheduled the task once clients is set.
+func newScheduler(cfg *config.Config, agentInstance *agent.Agent, router *provider.Router, runner *tasks.Runner, clients *atomic.Pointer[gateway.Gateway], logger *slog.Logger) (*scheduler.Scheduler, error) {
+	loc := time.Local
+	tz := cfg.Scheduler.Timezone
+	if tz == "" {
+		tz = cfg.Time.Timezone
+	}
+	if tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("load timezone: %w", err)
+		}
+	}
+
+	path := cfg.Scheduler.Path
+	if path == "" {
+		path = filepath.Join(cfg.Storage.Path, "scheduler.db")
+	}
+	legacy := filepath.Join(cfg.Storage.Path, "scheduled.json")
+	if strings.HasSuffix(path, ".json") {
+		// Configured before tasks moved to SQLite
+		legacy = path
+		path = strings.TrimSuffix(path, ".json") + ".db"
+	}
+
+	sched, err := scheduler.New(scheduler.Config{
+		Location:   loc,
+		Path:       path,
+		LegacyPath: legacy,
+		Jitter:     cfg.Scheduler.Jitter,
+		Logger:     logger,
+		Handler: func(ctx context.Context, task scheduler.Task) error {
+			if task.Message != "" {
+				return router.Send(ctx, task.Channel, task.ChatID, provider.OutgoingMessage{Content: task.Message})
+			}
+			if task.Trigger != "" {
+				if runner == nil {
+					return fmt.Errorf("no task %q", task.Trigger)
+				}
+				_, err := runner.Run(ctx, task.Trigger, map[string]any{"time": time.Now()})
+				return err
+			}
+
+			var content string
+			var err error
+			if task.Tool != "" {
+				content, err = agentInstance.ExecuteTool(ctx, task.Tool, task.ToolArgs)
+			} else {
+				sessionID := "scheduler:" + task.ID
+				switch {
+				case task.Session != "":
+					sessionID = task.Session // Scheduled by a gateway client
+				case task.Channel != "":
+					sessionID = task.Channel + ":" + task.ChatID
+				}
+				content, err = agentInstance.Process(ctx, sessionID, task.Prompt)
+			}
+			if err != nil {
+				return err
+			}
+
+			if task.Channel == "" {
+				if gw := clients.Load(); gw != nil && gw.DeliverTaskResult(task, content) {
+					return nil
+				}
+				logger.Info("scheduled task completed", "id", task.ID, "name", task.Name, "output_length", len(content))
+				return nil
+			}
+			return router.Send(ctx, task.Channel, task.ChatID, provider.OutgoingMessage{Content: content})
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Configured tasks keep their run state across restarts under IDs
+	// derived from their names
+	for i, tc := range cfg.Scheduler.Tasks {
+		var args json.RawMessage
+		if tc.ToolArgs != nil {
+			if args, err = json.Marshal(tc.ToolArgs); err != nil {
+				return nil, fmt.Errorf("task %q: encode tool args: %w", tc.Name, err)
+			}
+		}
+		id := "config:" + tc.Name
+		if tc.Name == "" {
+			id = fmt.Sprintf("config:%d", i)
+		}
+		if _, err := sched.Define(scheduler.Task{
+			ID:       id,
+			Name:     tc.Name,
+			Schedule: tc.Schedule,
+			Prompt:   tc.Prompt,
+			Tool:     tc.Tool,
+			ToolArgs: args,
+			Channel:  tc.Channel,
+			ChatID:   tc.ChatID,
+			Jitter:   tc.Jitter,
+		}); err != nil {
+			return nil, fmt.Errorf("task %q: %w", tc.Name, err)
+		}
+	}
+
+	// Run named tasks that have a schedule
+	for _, tc := range cfg.Tasks {
+		if tc.Schedule == "" {
+			continue
+		}
+		if _, err := sched.Define(scheduler.Task{ID: "task:" + tc.Name, Name: tc.Name, Schedule: tc.Schedule, Trigger: tc.Name}); err != nil {
+			return nil, fmt.Errorf("task %q: %w", tc.Name, err)
+		}
+	}
+
+	// Let the agent schedule messages when it knows the user's timezone
+	if clockResolver := agentInstance.Clock(); clockResolver != nil {
+		tool, err := sendlater.New(sendlater.Config{
+			Scheduler: sched,
+			Clock:     clockResolver,
+			Channels:  router.ListProviders(),
+			Logger:    logger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create send_later tool: %w", err)
+		}
+		if err := agentInstance.RegisterTool(tool); err != nil {
+			return nil, fmt.Errorf("register send_later tool: %w", err)
+		}
+	}
+
+	return sched, nil
+}
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Manage the running gateway's scheduled jobs",
+	Long: `Commands for the scheduled jobs behind digests, reminders, and
+workflows in the running gateway.
+
+The commands use the gateway admin API, so gateway.admin_token must be
+set.`,
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled jobs",
+	RunE:  jobsList,
+}
+
+var jobsRunCmd = &cobra.Command{
+	Use:   "run <id>",
+	Short: "Run a job now",
+	Args:  cobra.ExactArgs(1),
+	RunE:  jobsAction("run", "Started"),
+}
+
+var jobsPauseCmd = &cobra.Command{
+	Use:   "pause <id>",
+	Short: "Pause a job",
+	Args:  cobra.ExactArgs(1),
+	RunE:  jobsAction("pause", "Paused"),
+}
+
+var jobsResumeCmd = &cobra.Command{
+	Use:   "resume <id>",
+	Short: "Resume a paused job",
+	Args:  cobra.ExactArgs(1),
+	RunE:  jobsAction("resume", "Resumed"),
+}
+
+var jobsDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Delete a job",
+	Long:  "Delete a job. Jobs defined in the config come back on the next restart.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  jobsDelete,
+}
+
+func init() {
+	jobsCmd.AddCommand(jobsListCmd)
+	jobsCmd.AddCommand(jobsRunCmd)
+	jobsCmd.AddCommand(jobsPauseCmd)
+	jobsCmd.AddCommand(jobsResumeCmd)
+	jobsCmd.AddCommand(jobsDeleteCmd)
+}
+
+func jobsList(cmd *cobra.Command, args []string) error {
+	var resp struct {
+		Jobs []scheduler.Task `json:"jobs"`
+	}
+	if err := adminRequest(getConfig(), http.MethodGet, "/v1/admin/jobs", &resp); err != nil {
+		return err
+	}
+	if len(resp.Jobs) == 0 {
+		fmt.Println("No scheduled jobs.")
+		return nil
+	}
+	fmt.Printf("%-20s %-20s %-16s %-8s %-16s %-16s %s\n", "ID", "NAME", "SCHEDULE", "STATE", "LAST RUN", "NEXT RUN", "LAST ERROR")
+	for _, j := range resp.Jobs {
+		schedule := j.Schedule
+		if schedule == "" {
+			schedule = "once"
+		}
+		state := "active"
+		switch {
+		case j.Running:
+			state = "running"
+		case j.Paused:
+			state = "paused"
+		}
+		fmt.Printf("%-20s %-20s %-16s %-8s %-16s %-16s %s\n", j.ID, j.Name, schedule, state,
+			formatJobTime(j.LastRun), formatJobTime(j.NextRun), j.LastError)
+	}
+	return nil
+}
+
+// jobsAction returns a command that posts an action for a job.
+func jobsAction(action, done string) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		path := "/v1/admin/jobs/" + url.PathEscape(args[0]) + "/" + action
+		if err := adminRequest(getConfig(), http.MethodPost, path, nil); err != nil {
+			return err
+		}
+		fmt.Printf("%s %s\n", done, args[0])
+		return nil
+	}
+}
+
+func jobsDelete(cmd *cobra.Command, args []string) error {
+	if err := adminRequest(getConfig(), http.MethodDelete, "/v1/admin/jobs/"+url.PathEscape(args[0]), nil); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s\n", args[0])
+	return nil
+}
+
+// formatJobTime formats a job time, or "-" if unset.
+func formatJobTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
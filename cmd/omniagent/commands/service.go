@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var serviceName string
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Windows service commands",
+	Long: `Commands for running the gateway as a Windows service.
+
+The service runs "omniagent gateway run" with the config file given at
+install time, starting with Windows and restarting after failures. On
+Linux and macOS, run the gateway under systemd or launchd instead.`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the gateway service",
+	Long:  "Register the gateway as an automatically started Windows service. Requires an elevated prompt.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installService(serviceName)
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the gateway service",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return uninstallService(serviceName)
+	},
+}
+
+var serviceStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the gateway service",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return startService(serviceName)
+	},
+}
+
+var serviceStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the gateway service",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stopService(serviceName)
+	},
+}
+
+var serviceRunCmd = &cobra.Command{
+	Use:    "run",
+	Short:  "Run as the gateway service (used by the service manager)",
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runService(serviceName)
+	},
+}
+
+func init() {
+	serviceCmd.PersistentFlags().StringVar(&serviceName, "name", "omniagent", "service name")
+
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(serviceUninstallCmd)
+	serviceCmd.AddCommand(serviceStartCmd)
+	serviceCmd.AddCommand(serviceStopCmd)
+	serviceCmd.AddCommand(serviceRunCmd)
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package commands
+
+import (
+	"errors"
+)
+
+// errServiceUnsupported is returned by service commands off Windows.
+var errServiceUnsupported = errors.New(`services are managed by the service manager on this platform; run "omniagent gateway run" from a systemd unit or launchd agent`)
+
+func installService(name string) error   { return errServiceUnsupported }
+func uninstallService(name string) error { return errServiceUnsupported }
+func startService(name string) error     { return errServiceUnsupported }
+func stopService(name string) error      { return errServiceUnsupported }
+func runService(name string) error       { return errServiceUnsupported }
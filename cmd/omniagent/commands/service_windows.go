@@ -0,0 +1,214 @@
+//go:build windows
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceStopTimeout bounds how long stop waits for the service to exit.
+const serviceStopTimeout = 30 * time.Second
+
+func installService(name string) error {
+	// Services start in System32, so the config path must be absolute
+	if cfgFile == "" {
+		return errors.New("no config file found; pass --config")
+	}
+	configPath, err := filepath.Abs(cfgFile)
+	if err != nil {
+		return fmt.Errorf("resolve config path: %w", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find executable: %w", err)
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer func() { _ = m.Disconnect() }()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already installed", name)
+	}
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: "OmniAgent",
+		Description: "OmniAgent gateway",
+		StartType:   mgr.StartAutomatic,
+	}, "--config", configPath, "service", "run", "--name", name)
+	if err != nil {
+		return fmt.Errorf("create service: %w", err)
+	}
+	defer s.Close()
+
+	// Restart after failures, resetting the failure count daily
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+	}, uint32((24 * time.Hour).Seconds())); err != nil {
+		return fmt.Errorf("set recovery actions: %w", err)
+	}
+	if err := eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		_ = s.Delete()
+		return fmt.Errorf("register event source: %w", err)
+	}
+
+	fmt.Printf("Installed service %s with config %s\n", name, configPath)
+	return nil
+}
+
+func uninstallService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer func() { _ = m.Disconnect() }()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("delete service: %w", err)
+	}
+	_ = eventlog.Remove(name)
+
+	fmt.Printf("Removed service %s\n", name)
+	return nil
+}
+
+func startService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer func() { _ = m.Disconnect() }()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("start service: %w", err)
+	}
+	return nil
+}
+
+func stopService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer func() { _ = m.Disconnect() }()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return fmt.Errorf("stop service: %w", err)
+	}
+	deadline := time.Now().Add(serviceStopTimeout)
+	for status.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s did not stop within %v", name, serviceStopTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return fmt.Errorf("query service: %w", err)
+		}
+	}
+	return nil
+}
+
+func runService(name string) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("detect service: %w", err)
+	}
+	if !isService {
+		return errors.New(`"service run" is started by the service manager; use "gateway run" instead`)
+	}
+
+	// Resolve relative paths in the config, such as databases and working
+	// directories, against the config file rather than System32
+	if cfgFile != "" {
+		if err := os.Chdir(filepath.Dir(cfgFile)); err != nil {
+			return fmt.Errorf("change to config directory: %w", err)
+		}
+	}
+
+	elog, err := eventlog.Open(name)
+	if err != nil {
+		return fmt.Errorf("open event log: %w", err)
+	}
+	defer elog.Close()
+
+	h := &gatewayService{}
+	if err := svc.Run(name, h); err != nil {
+		_ = elog.Error(1, fmt.Sprintf("service failed: %v", err))
+		return err
+	}
+	if h.err != nil {
+		_ = elog.Error(1, fmt.Sprintf("gateway failed: %v", h.err))
+	}
+	return h.err
+}
+
+// gatewayService runs the gateway under the Windows service manager.
+type gatewayService struct {
+	err error
+}
+
+// Execute implements svc.Handler.
+func (h *gatewayService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- serveGateway(ctx) }()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case h.err = <-done:
+			// The gateway stopped on its own; report failures so the
+			// recovery actions restart it
+			return h.exitCode()
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				h.err = <-done
+				return h.exitCode()
+			}
+		}
+	}
+}
+
+// exitCode returns the service exit code for the gateway's result.
+func (h *gatewayService) exitCode() (bool, uint32) {
+	if h.err != nil {
+		return true, 1
+	}
+	return false, 0
+}
@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/gateway"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manage the running gateway's sessions",
+	Long: `Commands for inspecting and resetting conversation state in the
+running gateway, without restarting it.
+
+Sessions are named channel:chat, e.g. telegram:12345. The commands use
+the gateway admin API, so gateway.admin_token must be set.`,
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sessions",
+	RunE:  sessionsList,
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <session>",
+	Short: "Show a session's state",
+	Args:  cobra.ExactArgs(1),
+	RunE:  sessionsShow,
+}
+
+var sessionsClearCmd = &cobra.Command{
+	Use:   "clear <session>",
+	Short: "Reset a session's conversation",
+	Long:  "Forget a session's last reply, pins, and stored context. Preferences and the transcript are kept.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  sessionsClear,
+}
+
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete <session>",
+	Short: "Delete a session",
+	Long:  "Remove all of a session's state, including preferences and its transcript.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  sessionsDelete,
+}
+
+func init() {
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsShowCmd)
+	sessionsCmd.AddCommand(sessionsClearCmd)
+	sessionsCmd.AddCommand(sessionsDeleteCmd)
+}
+
+func sessionsList(cmd *cobra.Command, args []string) error {
+	var resp struct {
+		Sessions []gateway.SessionInfo `json:"sessions"`
+	}
+	if err := adminRequest(getConfig(), http.MethodGet, "/v1/admin/sessions", &resp); err != nil {
+		return err
+	}
+	if len(resp.Sessions) == 0 {
+		fmt.Println("No sessions.")
+		return nil
+	}
+	fmt.Printf("%-30s %5s %5s %8s  %s\n", "SESSION", "PINS", "PREFS", "ENTRIES", "LAST REPLY")
+	for _, s := range resp.Sessions {
+		prefs := "-"
+		if s.Preferences != nil {
+			prefs = "yes"
+		}
+		fmt.Printf("%-30s %5d %5s %8d  %s\n", s.ID, len(s.Pins), prefs, s.Entries, preview(s.LastReply))
+	}
+	return nil
+}
+
+func sessionsShow(cmd *cobra.Command, args []string) error {
+	var s gateway.SessionInfo
+	if err := adminRequest(getConfig(), http.MethodGet, "/v1/admin/sessions/"+url.PathEscape(args[0]), &s); err != nil {
+		return err
+	}
+	fmt.Printf("Session: %s\n", s.ID)
+	if s.Updated != nil {
+		fmt.Printf("Transcript: %d entries, updated %s\n", s.Entries, s.Updated.Format(time.DateTime))
+	}
+	if p := s.Preferences; p != nil {
+		fmt.Printf("Preferences: language=%q formality=%q emoji=%q length=%q\n", p.Language, p.Formality, p.Emoji, p.Length)
+	}
+	if len(s.Context) > 0 {
+		data, _ := json.Marshal(s.Context)
+		fmt.Printf("Context: %s\n", data)
+	}
+	for i, p := range s.Pins {
+		fmt.Printf("Pin %d (%s): %s\n", i+1, p.From, preview(p.Text))
+	}
+	if s.LastReply != "" {
+		fmt.Printf("\nLast reply:\n%s\n", s.LastReply)
+	}
+	return nil
+}
+
+func sessionsClear(cmd *cobra.Command, args []string) error {
+	if err := adminRequest(getConfig(), http.MethodPost, "/v1/admin/sessions/"+url.PathEscape(args[0])+"/clear", nil); err != nil {
+		return err
+	}
+	fmt.Printf("Cleared %s\n", args[0])
+	return nil
+}
+
+func sessionsDelete(cmd *cobra.Command, args []string) error {
+	if err := adminRequest(getConfig(), http.MethodDelete, "/v1/admin/sessions/"+url.PathEscape(args[0]), nil); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s\n", args[0])
+	return nil
+}
+
+// gatewayURL returns the base URL of the configured gateway.
+func gatewayURL(cfg *config.Config) string {
+	scheme := "http"
+	if cfg.Gateway.TLS.CertFile != "" || cfg.Gateway.TLS.Autocert {
+		scheme = "https"
+	}
+	return scheme + "://" + cfg.Gateway.Address
+}
+
+// adminRequest calls the running gateway's admin API and decodes the JSON
+// response into out, if non-nil.
+func adminRequest(cfg *config.Config, method, path string, out any) error {
+	if cfg.Gateway.AdminToken == "" {
+		return fmt.Errorf("gateway.admin_token is not set")
+	}
+	req, err := http.NewRequest(method, gatewayURL(cfg)+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.Gateway.AdminToken)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gateway not reachable: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("gateway: %s", e.Error)
+		}
+		return fmt.Errorf("gateway returned %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -1,12 +1,22 @@
 package commands
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
 	"github.com/plexusone/omniagent/skills"
+	"github.com/plexusone/omniagent/tools/skillscript"
 )
 
 var skillsCmd = &cobra.Command{
@@ -209,8 +219,296 @@ var skillsCheckCmd = &cobra.Command{
 	},
 }
 
+var skillsLintCmd = &cobra.Command{
+	Use:   "lint <dir>...",
+	Short: "Validate skill directories",
+	Long: `Validate skills before publishing or installing them.
+
+Checks each skill's SKILL.md frontmatter against the SKILL.md schema, its
+name, description, requirements, and installers, flags instructions too
+long to add to every prompt, and verifies that the scripts and hooks the
+instructions mention exist. Exits non-zero if any problem is found.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := 0
+		for _, dir := range args {
+			problems, err := skills.LintDir(dir)
+			if err != nil {
+				return fmt.Errorf("lint %s: %w", dir, err)
+			}
+			if len(problems) == 0 {
+				fmt.Printf("✓ %s\n", dir)
+				continue
+			}
+			failed++
+			fmt.Printf("✗ %s\n", dir)
+			for _, problem := range problems {
+				fmt.Printf("    - %s\n", problem)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d skills have problems", failed, len(args))
+		}
+		return nil
+	},
+}
+
+var skillsNewDir string
+
+var skillsNewCmd = &cobra.Command{
+	Use:   "new <name>",
+	Short: "Create a skill from a template",
+	Long: `Create a skill directory to start authoring from: a SKILL.md with
+frontmatter for the emoji, requirements, and installers, a script in
+scripts/, a pre_tool hook source in hooks/, and a Makefile whose test
+target lints the skill and runs its scripts.`,
+	Example: `  omniagent skills new weather
+  omniagent skills new weather --dir ./skills`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := skills.Scaffold(skillsNewDir, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n\n", dir)
+		fmt.Println("Next steps:")
+		fmt.Println("  1. Fill in the description and instructions in SKILL.md")
+		fmt.Println("  2. Replace scripts/hello.sh with your own scripts")
+		fmt.Printf("  3. Run make -C %s test\n", dir)
+		return nil
+	},
+}
+
+var (
+	skillsInstallRegistry string
+	skillsInstallForce    bool
+	skillsInstallYes      bool
+)
+
+var skillsInstallCmd = &cobra.Command{
+	Use:   "install <name|git-url>",
+	Short: "Install a skill from ClawHub or a git repository",
+	Long: `Install a skill into ~/.omniagent/skills.
+
+A plain name is downloaded from the ClawHub registry; a URL is cloned with
+git. The skill's SKILL.md must parse and name the skill. Missing required
+binaries are reported, and the skill's declared installers (brew, apt, go,
+npm) are offered for each one, asking before running anything.`,
+	Example: `  omniagent skills install sonoscli
+  omniagent skills install https://github.com/user/weather-skill.git`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		skill, err := skills.Install(cmd.Context(), args[0], skills.InstallOptions{
+			Registry: skillsInstallRegistry,
+			Force:    skillsInstallForce,
+		})
+		if err != nil {
+			return fmt.Errorf("install skill: %w", err)
+		}
+		fmt.Printf("Installed %s to %s\n", skill.Name, skill.Path)
+		for _, problem := range skills.Lint(skill) {
+			fmt.Printf("  ⚠ %s\n", problem)
+		}
+
+		errs := skill.CheckRequirements()
+		if len(errs) == 0 {
+			fmt.Println("Status: ✓ Available")
+			return nil
+		}
+		var missing []string
+		for _, e := range errs {
+			if reqErr, ok := e.(*skills.RequirementError); ok {
+				fmt.Printf("  ⚠ Missing %s: %s\n", reqErr.Type, reqErr.Name)
+				if reqErr.Type == "binary" {
+					missing = append(missing, reqErr.Name)
+				}
+			}
+		}
+
+		reader := bufio.NewReader(os.Stdin)
+		for _, inst := range skill.Installers(missing) {
+			command := inst.Command()
+			if command == nil {
+				continue
+			}
+			line := strings.Join(command, " ")
+			if !skillsInstallYes && !confirm(reader, fmt.Sprintf("Run %q?", line)) {
+				continue
+			}
+			run := exec.CommandContext(cmd.Context(), command[0], command[1:]...) //nolint:gosec // G204: Installer declared by the skill, confirmed by the operator
+			run.Stdin, run.Stdout, run.Stderr = os.Stdin, os.Stdout, os.Stderr
+			if err := run.Run(); err != nil {
+				fmt.Printf("  ✗ %s: %v\n", line, err)
+			}
+		}
+
+		if len(skill.CheckRequirements()) == 0 {
+			fmt.Println("Status: ✓ Available")
+		} else {
+			fmt.Println("Status: ✗ Unavailable until the missing requirements are installed")
+		}
+		return nil
+	},
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func confirm(reader *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 func init() {
+	skillsNewCmd.Flags().StringVar(&skillsNewDir, "dir", skills.DefaultInstallDir(), "directory to create the skill in")
+	skillsInstallCmd.Flags().StringVar(&skillsInstallRegistry, "registry", skills.DefaultRegistry, "registry to install skills from by name")
+	skillsInstallCmd.Flags().BoolVarP(&skillsInstallForce, "force", "f", false, "replace an installed skill of the same name")
+	skillsInstallCmd.Flags().BoolVarP(&skillsInstallYes, "yes", "y", false, "run the skill's installers without asking")
+
 	skillsCmd.AddCommand(skillsListCmd)
 	skillsCmd.AddCommand(skillsInfoCmd)
 	skillsCmd.AddCommand(skillsCheckCmd)
+	skillsCmd.AddCommand(skillsInstallCmd)
+	skillsCmd.AddCommand(skillsLintCmd)
+	skillsCmd.AddCommand(skillsNewCmd)
+}
+
+// skillManager loads the agent's skills and exposes their scripts as
+// tools, and reloads both when skill directories change.
+type skillManager struct {
+	cfg    *config.Config
+	agent  *agent.Agent
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	paths   []string // Search paths; empty for the defaults
+	tools   []string // IDs of the registered script tools
+	closers []func()
+}
+
+func newSkillManager(cfg *config.Config, a *agent.Agent, logger *slog.Logger) *skillManager {
+	return &skillManager{cfg: cfg, agent: a, logger: logger}
+}
+
+// Load loads the skills in paths, replacing the agent's skills and script
+// tools.
+func (m *skillManager) Load(ctx context.Context, paths []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.agent.LoadSkills(paths); err != nil {
+		return err
+	}
+	m.paths = paths
+	if m.cfg.Skills.Scripts {
+		m.registerScripts(ctx)
+	}
+	return nil
+}
+
+// Run reloads skills whenever a skill is added, removed, or edited in the
+// search paths, checking every skills.watch_interval until ctx is done.
+func (m *skillManager) Run(ctx context.Context) error {
+	interval := m.cfg.Skills.WatchInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	last := skills.Fingerprint(m.dirs())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		fp := skills.Fingerprint(m.dirs())
+		if fp == last {
+			continue
+		}
+		last = fp
+		m.mu.Lock()
+		paths := m.paths
+		m.mu.Unlock()
+		if err := m.Load(ctx, paths); err != nil {
+			m.logger.Warn("failed to reload skills", "error", err)
+			continue
+		}
+		m.logger.Info("skills changed, reloaded", "skills", len(m.agent.GetSkills()))
+	}
+}
+
+// dirs returns the directories skills are loaded from.
+func (m *skillManager) dirs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.paths) == 0 {
+		return skills.DefaultSearchPaths()
+	}
+	return m.paths
+}
+
+// Close releases the script sandboxes.
+func (m *skillManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeScripts()
+}
+
+// registerScripts exposes the scripts of each loaded skill as tools from
+// source skill:<name>, replacing those registered before. Scripts run in
+// a sandbox rooted at the skill directory, limited to the capabilities the
+// skill declares, never on the host.
+func (m *skillManager) registerScripts(ctx context.Context) {
+	m.closeScripts()
+	for _, skill := range m.agent.GetSkills() {
+		scripts, err := skill.Scripts()
+		if err != nil {
+			m.logger.Warn("failed to list skill scripts", "skill", skill.Name, "error", err)
+			continue
+		}
+		if len(scripts) == 0 {
+			continue
+		}
+
+		sb, err := newSkillSandbox(ctx, m.cfg, skill)
+		if err != nil {
+			m.logger.Warn("failed to create skill script sandbox", "skill", skill.Name, "error", err)
+			continue
+		}
+		if sb == nil {
+			m.logger.Warn("skill scripts need a sandbox; set skills.sandbox or tools.sandbox.mode", "skill", skill.Name)
+			return
+		}
+		m.closers = append(m.closers, func() { _ = sb.Close() })
+
+		for _, script := range scripts {
+			tool, err := skillscript.New(skillscript.Config{
+				Skill:    skill,
+				Script:   script,
+				Executor: sb,
+				Logger:   m.logger,
+			})
+			if err == nil {
+				err = m.agent.RegisterToolFrom(skillscript.Source(skill), tool)
+			}
+			if err != nil {
+				m.logger.Warn("failed to register skill script", "skill", skill.Name, "script", script.File, "error", err)
+				continue
+			}
+			m.tools = append(m.tools, agent.ToolID(skillscript.Source(skill), script.Name))
+			m.logger.Info("skill script registered", "skill", skill.Name, "tool", script.Name)
+		}
+	}
+}
+
+// closeScripts unregisters the script tools and closes their sandboxes.
+func (m *skillManager) closeScripts() {
+	for _, id := range m.tools {
+		m.agent.UnregisterTool(id)
+	}
+	for i := len(m.closers) - 1; i >= 0; i-- {
+		m.closers[i]()
+	}
+	m.tools, m.closers = nil, nil
 }
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"log/slog"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/tasks"
+)
+
+// newTaskRunner creates the runner for the configured named tasks.
+func newTaskRunner(cfg *config.Config, agentInstance *agent.Agent, router *provider.Router, logger *slog.Logger) (*tasks.Runner, error) {
+	defs := make([]tasks.Definition, 0, len(cfg.Tasks))
+	for _, tc := range cfg.Tasks {
+		defs = append(defs, tasks.Definition{
+			Name:    tc.Name,
+			Prompt:  tc.Prompt,
+			Channel: tc.Channel,
+			ChatID:  tc.ChatID,
+			Tools:   tc.Tools,
+			Secret:  tc.Secret,
+		})
+		if tc.Secret == "" {
+			logger.Info("task has no secret and can only run on its schedule", "task", tc.Name)
+		}
+		if tc.Schedule != "" && !cfg.Scheduler.Enabled {
+			logger.Warn("task schedule ignored because the scheduler is disabled", "task", tc.Name)
+		}
+	}
+	return tasks.New(tasks.Config{
+		Tasks:  defs,
+		Agent:  agentInstance,
+		Sender: router,
+		Logger: logger,
+	})
+}
@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/templates"
+)
+
+var (
+	templateText        string
+	templateFile        string
+	templateDescription string
+)
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Canned response commands",
+	Long: `Commands for managing canned responses.
+
+The agent fills them in with the use_template tool, and users send them
+with /send-template. Enable both with templates.enabled in the config.
+Text may contain {placeholders}, filled in when a template is used.`,
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List templates",
+	RunE:  templatesList,
+}
+
+var templatesShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a template's text",
+	Args:  cobra.ExactArgs(1),
+	RunE:  templatesShow,
+}
+
+var templatesAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or replace a template",
+	Long: `Add or replace a template, with its text from --text, --file, or
+standard input.`,
+	Example: `  omniagent templates add invoice-paid -d "Confirm a payment" \
+    --text "Thanks, {name}! Invoice {number} is paid in full."`,
+	Args: cobra.ExactArgs(1),
+	RunE: templatesAdd,
+}
+
+var templatesRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a template",
+	Args:  cobra.ExactArgs(1),
+	RunE:  templatesRemove,
+}
+
+func init() {
+	templatesAddCmd.Flags().StringVar(&templateText, "text", "", "Template text")
+	templatesAddCmd.Flags().StringVarP(&templateFile, "file", "f", "", "Read the template text from a file")
+	templatesAddCmd.Flags().StringVarP(&templateDescription, "description", "d", "", "When to use the template")
+
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesShowCmd)
+	templatesCmd.AddCommand(templatesAddCmd)
+	templatesCmd.AddCommand(templatesRemoveCmd)
+}
+
+// openTemplates opens the template store.
+func openTemplates(cfg *config.Config) (*templates.Store, error) {
+	path := cfg.Templates.Path
+	if path == "" {
+		path = filepath.Join(cfg.Storage.Path, "templates.yaml")
+	}
+	store, err := templates.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open templates: %w", err)
+	}
+	return store, nil
+}
+
+func templatesList(cmd *cobra.Command, args []string) error {
+	store, err := openTemplates(getConfig())
+	if err != nil {
+		return err
+	}
+	list, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Println("No templates. Add one with: omniagent templates add <name>")
+		return nil
+	}
+	fmt.Printf("%-24s %-30s %s\n", "NAME", "PLACEHOLDERS", "DESCRIPTION")
+	for _, t := range list {
+		fmt.Printf("%-24s %-30s %s\n", t.Name, strings.Join(t.Variables(), ", "), t.Description)
+	}
+	return nil
+}
+
+func templatesShow(cmd *cobra.Command, args []string) error {
+	store, err := openTemplates(getConfig())
+	if err != nil {
+		return err
+	}
+	t, err := store.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if t.Description != "" {
+		fmt.Printf("# %s\n\n", t.Description)
+	}
+	fmt.Println(t.Text)
+	return nil
+}
+
+func templatesAdd(cmd *cobra.Command, args []string) error {
+	text := templateText
+	switch {
+	case text != "" && templateFile != "":
+		return errors.New("use either --text or --file")
+	case templateFile != "":
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return fmt.Errorf("read template: %w", err)
+		}
+		text = string(data)
+	case text == "":
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("read template: %w", err)
+		}
+		text = string(data)
+	}
+
+	store, err := openTemplates(getConfig())
+	if err != nil {
+		return err
+	}
+	t := templates.Template{Name: args[0], Description: templateDescription, Text: strings.TrimRight(text, "\n")}
+	if err := store.Put(t); err != nil {
+		return err
+	}
+	fmt.Printf("Saved template %s\n", strings.ToLower(args[0]))
+	return nil
+}
+
+func templatesRemove(cmd *cobra.Command, args []string) error {
+	store, err := openTemplates(getConfig())
+	if err != nil {
+		return err
+	}
+	if err := store.Remove(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Removed template %s\n", args[0])
+	return nil
+}
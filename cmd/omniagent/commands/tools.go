@@ -0,0 +1,287 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/clock"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/kb"
+	"github.com/plexusone/omniagent/media"
+	"github.com/plexusone/omniagent/memory"
+	"github.com/plexusone/omniagent/sandbox"
+	"github.com/plexusone/omniagent/templates"
+	"github.com/plexusone/omniagent/tools/browser"
+	"github.com/plexusone/omniagent/tools/file"
+	"github.com/plexusone/omniagent/tools/httpfetch"
+	"github.com/plexusone/omniagent/tools/scratchpad"
+	"github.com/plexusone/omniagent/tools/shell"
+	"github.com/plexusone/omniagent/tools/timetool"
+	"github.com/plexusone/omniagent/tools/webdav"
+)
+
+// toolSet holds the built-in tools enabled by configuration.
+type toolSet struct {
+	Tools     []agent.Tool
+	Clock     *clock.Resolver
+	Memory    *memory.Memory  // Set when memory is enabled
+	Knowledge *kb.Base        // Set when the knowledge base is enabled
+	Templates *templates.Tool // Set when templates are enabled
+	closers   []func()
+}
+
+// Close releases tool resources.
+func (ts *toolSet) Close() {
+	for i := len(ts.closers) - 1; i >= 0; i-- {
+		ts.closers[i]()
+	}
+}
+
+// buildTools creates the built-in tools enabled in cfg. Callers must Close
+// the returned set, even on error.
+func buildTools(cfg *config.Config, logger *slog.Logger) (*toolSet, error) {
+	ts := &toolSet{}
+
+	// Resolve timezones for the current-time prompt and time tool
+	tzPath := cfg.Time.Path
+	if tzPath == "" {
+		tzPath = filepath.Join(cfg.Storage.Path, "timezones.json")
+	}
+	clockResolver, err := clock.New(clock.Config{
+		Default:  cfg.Time.Timezone,
+		Contacts: cfg.Time.Contacts,
+		Path:     tzPath,
+	})
+	if err != nil {
+		return ts, fmt.Errorf("configure time: %w", err)
+	}
+	ts.Clock = clockResolver
+	timeTool, err := timetool.New(timetool.Config{Clock: clockResolver, Logger: logger})
+	if err != nil {
+		return ts, fmt.Errorf("create time tool: %w", err)
+	}
+	ts.Tools = append(ts.Tools, timeTool)
+
+	// Agent-initiated HTTP requests share the http_fetch egress policy
+	fetchConfig := httpfetch.Config{
+		AllowedHosts:    cfg.Tools.HTTPFetch.AllowedHosts,
+		PrivateOnly:     cfg.Privacy.LocalOnly,
+		MaxResponseSize: cfg.Tools.HTTPFetch.MaxResponseKB << 10,
+		Timeout:         cfg.Tools.HTTPFetch.Timeout,
+		Logger:          logger,
+	}
+
+	// Register search tool if available; searches go to a cloud API, so
+	// privacy mode leaves it out
+	if cfg.Privacy.LocalOnly {
+		logger.Info("search tool disabled in privacy mode")
+	} else if searchTool, err := agent.NewSearchTool(agent.SearchConfig{Host: httpfetch.NewHost(fetchConfig)}); err == nil {
+		ts.Tools = append(ts.Tools, searchTool)
+		logger.Info("search tool registered")
+	} else {
+		logger.Debug("search tool not available", "error", err)
+	}
+
+	// Register shell tool if enabled
+	if cfg.Tools.Shell.Enabled {
+		var sb sandbox.Sandbox
+		var err error
+		if cfg.Tools.Sandbox.Workspaces.Enabled {
+			sb, err = newWorkspaceSandbox(cfg, cfg.Tools.Shell.Sandbox)
+		} else {
+			sb, err = newToolSandbox(context.Background(), cfg, cfg.Tools.Shell.Sandbox, cfg.Tools.Shell.WorkingDir)
+		}
+		if err != nil {
+			return ts, fmt.Errorf("create shell sandbox: %w", err)
+		}
+		var executor sandbox.Executor
+		if sb != nil {
+			executor = sb
+			ts.closers = append(ts.closers, func() { _ = sb.Close() })
+		}
+
+		shellTool, err := shell.New(shell.Config{
+			WorkingDir: cfg.Tools.Shell.WorkingDir,
+			Allowlist:  cfg.Tools.Shell.Allowlist,
+			Executor:   executor,
+			Logger:     logger,
+		})
+		if err != nil {
+			return ts, fmt.Errorf("create shell tool: %w", err)
+		}
+		ts.Tools = append(ts.Tools, shellTool)
+		logger.Info("shell tool registered", "sandboxed", executor != nil)
+	}
+
+	// Register local file tool if enabled
+	if cfg.Tools.File.Enabled {
+		fileTool, err := file.New(file.Config{
+			Paths:       cfg.Tools.File.Paths,
+			Write:       cfg.Tools.File.Write,
+			MaxFileSize: cfg.Tools.File.MaxFileKB << 10,
+			Logger:      logger,
+		})
+		if err != nil {
+			return ts, fmt.Errorf("create file tool: %w", err)
+		}
+		ts.Tools = append(ts.Tools, fileTool)
+		logger.Info("file tool registered", "paths", cfg.Tools.File.Paths, "write", cfg.Tools.File.Write)
+	}
+
+	// Register HTTP fetch tool if enabled
+	if cfg.Tools.HTTPFetch.Enabled {
+		fetchTool, err := httpfetch.New(fetchConfig)
+		if err != nil {
+			return ts, fmt.Errorf("create http fetch tool: %w", err)
+		}
+		ts.Tools = append(ts.Tools, fetchTool)
+		logger.Info("http fetch tool registered", "allowed_hosts", cfg.Tools.HTTPFetch.AllowedHosts)
+	}
+
+	// Register scratchpad tool if enabled
+	if cfg.Tools.Scratchpad.Enabled {
+		path := cfg.Tools.Scratchpad.Path
+		if path == "" {
+			path = filepath.Join(cfg.Storage.Path, "scratchpad.db")
+		}
+		store, err := scratchpad.Open(path)
+		if err != nil {
+			return ts, fmt.Errorf("open scratchpad: %w", err)
+		}
+		ts.closers = append(ts.closers, func() { _ = store.Close() })
+
+		scratchpadTool, err := scratchpad.New(scratchpad.Config{Store: store, Logger: logger})
+		if err != nil {
+			return ts, fmt.Errorf("create scratchpad tool: %w", err)
+		}
+		ts.Tools = append(ts.Tools, scratchpadTool)
+		logger.Info("scratchpad tool registered", "path", path)
+	}
+
+	// Register memory tools if enabled
+	if cfg.Memory.Enabled {
+		path := cfg.Memory.Path
+		if path == "" {
+			path = filepath.Join(cfg.Storage.Path, "memory.db")
+		}
+		store, err := memory.Open(path)
+		if err != nil {
+			return ts, fmt.Errorf("open memory: %w", err)
+		}
+		ts.closers = append(ts.closers, func() { _ = store.Close() })
+
+		mem, err := memory.New(memory.Config{
+			Store:       store,
+			Embedder:    newEmbedder(cfg, cfg.Memory.Embedding),
+			RecallLimit: cfg.Memory.RecallLimit,
+			MinScore:    cfg.Memory.MinScore,
+			GroupScope:  cfg.Memory.GroupScope,
+			Logger:      logger,
+		})
+		if err != nil {
+			return ts, fmt.Errorf("create memory: %w", err)
+		}
+		ts.Memory = mem
+		ts.Tools = append(ts.Tools, memory.NewRememberTool(mem), memory.NewRecallTool(mem))
+		logger.Info("memory tools registered", "path", path, "auto_recall", cfg.Memory.AutoRecall)
+	}
+
+	// Register knowledge search if enabled
+	if cfg.Knowledge.Enabled {
+		base, store, err := openKnowledge(cfg, logger)
+		if err != nil {
+			return ts, err
+		}
+		ts.closers = append(ts.closers, func() { _ = store.Close() })
+		ts.Knowledge = base
+		ts.Tools = append(ts.Tools, kb.NewTool(base))
+		logger.Info("knowledge search tool registered")
+	}
+
+	// Register browser tool if enabled
+	if cfg.Tools.Browser.Enabled {
+		browserTool, err := browser.New(browser.Config{
+			Headless:      cfg.Tools.Browser.Headless,
+			UserData:      cfg.Tools.Browser.UserData,
+			MaxSessions:   cfg.Tools.Browser.MaxSessions,
+			MaxConcurrent: cfg.Tools.Browser.MaxConcurrent,
+			IdleTimeout:   cfg.Tools.Browser.IdleTimeout,
+			Logger:        logger,
+		})
+		if err != nil {
+			return ts, fmt.Errorf("create browser tool: %w", err)
+		}
+		ts.closers = append(ts.closers, func() {
+			st := browserTool.Stats(context.Background())
+			logger.Info("browser usage", "launches", st.Launches, "actions", st.Actions, "failures", st.Failures)
+			_ = browserTool.Close()
+		})
+		ts.Tools = append(ts.Tools, browserTool)
+		logger.Info("browser tool registered", "headless", cfg.Tools.Browser.Headless)
+	}
+
+	// Register WebDAV file tool if enabled
+	if cfg.Tools.WebDAV.Enabled {
+		folders := make([]webdav.Folder, 0, len(cfg.Tools.WebDAV.Folders))
+		for _, f := range cfg.Tools.WebDAV.Folders {
+			folders = append(folders, webdav.Folder{Path: f.Path, Write: f.Write})
+		}
+		filesTool, err := webdav.New(webdav.Config{
+			URL:         cfg.Tools.WebDAV.URL,
+			Username:    cfg.Tools.WebDAV.Username,
+			Password:    cfg.Tools.WebDAV.Password,
+			Folders:     folders,
+			MaxFileSize: int64(cfg.Tools.WebDAV.MaxFileMB) << 20,
+			Logger:      logger,
+		})
+		if err != nil {
+			return ts, fmt.Errorf("create webdav tool: %w", err)
+		}
+		ts.Tools = append(ts.Tools, filesTool)
+		logger.Info("webdav file tool registered", "folders", len(folders))
+	}
+
+	// Register canned responses if enabled
+	if cfg.Templates.Enabled {
+		store, err := openTemplates(cfg)
+		if err != nil {
+			return ts, err
+		}
+		ts.Templates = templates.NewTool(store)
+		ts.Tools = append(ts.Tools, ts.Templates)
+		logger.Info("template tool registered")
+	}
+
+	// Register image generation if enabled
+	if cfg.Tools.Images.Enabled {
+		images := cfg.Tools.Images
+		if images.APIKey == "" && images.BaseURL == "" && cfg.Agent.Provider == "openai" {
+			images.APIKey = cfg.Agent.APIKey
+		}
+		ts.Tools = append(ts.Tools, media.NewImageTool(media.ImageConfig{
+			BaseURL: images.BaseURL,
+			APIKey:  images.APIKey,
+			Model:   images.Model,
+			Size:    images.Size,
+		}))
+		logger.Info("image generation tool registered", "model", images.Model)
+	}
+
+	return ts, nil
+}
+
+// newEmbedder creates the embedder for an embeddings endpoint, using the
+// agent's API key for OpenAI when none is set.
+func newEmbedder(cfg *config.Config, embedding config.EmbeddingConfig) memory.Embedder {
+	if embedding.APIKey == "" && embedding.BaseURL == "" && cfg.Agent.Provider == "openai" {
+		embedding.APIKey = cfg.Agent.APIKey
+	}
+	return memory.NewOpenAIEmbedder(memory.EmbedderConfig{
+		BaseURL: embedding.BaseURL,
+		APIKey:  embedding.APIKey,
+		Model:   embedding.Model,
+	})
+}
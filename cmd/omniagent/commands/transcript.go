@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/storage"
+	"github.com/plexusone/omniagent/transcripts"
+)
+
+var (
+	transcriptFormat string
+	transcriptTools  bool
+	transcriptInline bool
+	transcriptOutput string
+)
+
+var transcriptCmd = &cobra.Command{
+	Use:   "transcript",
+	Short: "Conversation transcript commands",
+	Long: `Commands for exporting recorded conversations.
+
+Enable recording with transcripts.enabled in the config.`,
+}
+
+var transcriptListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded sessions",
+	RunE:  transcriptList,
+}
+
+var transcriptExportCmd = &cobra.Command{
+	Use:   "export <session>",
+	Short: "Export a session as Markdown, HTML, or JSON",
+	Long: `Export a session's transcript as a readable document, or as JSON
+for audits and tooling.
+
+Sessions are named channel:chat, e.g. telegram:12345. Attachments are
+linked to the files on disk (or named by their key in S3 storage) unless
+--inline embeds them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: transcriptExport,
+}
+
+func init() {
+	transcriptExportCmd.Flags().StringVarP(&transcriptFormat, "format", "f", "markdown", "output format (markdown, html, or json)")
+	transcriptExportCmd.Flags().BoolVar(&transcriptTools, "tools", false, "include tool calls and results")
+	transcriptExportCmd.Flags().BoolVar(&transcriptInline, "inline", false, "embed attachments instead of linking them")
+	transcriptExportCmd.Flags().StringVarP(&transcriptOutput, "output", "o", "", "output file (default: stdout)")
+	transcriptCmd.AddCommand(transcriptListCmd)
+	transcriptCmd.AddCommand(transcriptExportCmd)
+}
+
+// openTranscripts opens the transcript store: transcripts.path if set,
+// else transcripts/ in the configured blob store.
+func openTranscripts(cfg *config.Config) (*transcripts.Store, error) {
+	if cfg.Transcripts.Path != "" {
+		return transcripts.Open(cfg.Transcripts.Path)
+	}
+	if strings.EqualFold(cfg.Storage.Backend, "s3") {
+		blobs, err := openBlobStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return transcripts.New(storage.WithPrefix(blobs, "transcripts/")), nil
+	}
+	return transcripts.Open(filepath.Join(cfg.Storage.Path, "transcripts"))
+}
+
+func transcriptList(cmd *cobra.Command, args []string) error {
+	store, err := openTranscripts(getConfig())
+	if err != nil {
+		return fmt.Errorf("open transcripts: %w", err)
+	}
+	sessions, err := store.Sessions()
+	if err != nil {
+		return fmt.Errorf("list transcripts: %w", err)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No transcripts recorded.")
+		return nil
+	}
+	fmt.Printf("%-40s %8s  %s\n", "SESSION", "ENTRIES", "UPDATED")
+	for _, s := range sessions {
+		fmt.Printf("%-40s %8d  %s\n", s.ID, s.Entries, s.Updated.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+func transcriptExport(cmd *cobra.Command, args []string) error {
+	format, err := transcripts.ParseFormat(transcriptFormat)
+	if err != nil {
+		return err
+	}
+	store, err := openTranscripts(getConfig())
+	if err != nil {
+		return fmt.Errorf("open transcripts: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if transcriptOutput != "" {
+		f, err := os.Create(transcriptOutput)
+		if err != nil {
+			return fmt.Errorf("create output: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	err = store.Export(w, args[0], transcripts.Options{
+		Format: format,
+		Tools:  transcriptTools,
+		Inline: transcriptInline,
+	})
+	if errors.Is(err, transcripts.ErrNotFound) {
+		return fmt.Errorf("no transcript for session %q", args[0])
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/budget"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/usage"
+)
+
+var (
+	usageSince time.Duration
+	usageBy    string
+	usageJSON  bool
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token usage and estimated cost",
+	Long: `Show model token usage and estimated cost, grouped by provider, model,
+channel, session, or day, and the current budget usage if budgets are
+enabled.
+
+Enable recording with usage.enabled in the config. Costs are estimated
+with eval.pricing when each request is recorded.`,
+	RunE: runUsage,
+}
+
+func init() {
+	usageCmd.Flags().DurationVar(&usageSince, "since", 30*24*time.Hour, "report period (0 for all time)")
+	usageCmd.Flags().StringVar(&usageBy, "by", usage.ByModel, "group by provider, model, channel, session, or day")
+	usageCmd.Flags().BoolVar(&usageJSON, "json", false, "output as JSON")
+}
+
+// usagePath returns where model usage is recorded.
+func usagePath(cfg *config.Config) string {
+	if cfg.Usage.Path != "" {
+		return cfg.Usage.Path
+	}
+	return filepath.Join(cfg.Storage.Path, "usage.jsonl")
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+	var since time.Time
+	if usageSince > 0 {
+		since = time.Now().Add(-usageSince)
+	}
+	records, err := usage.Query(usagePath(cfg), since, time.Time{})
+	if err != nil {
+		return err
+	}
+	totals, err := usage.Summarize(records, usageBy, time.Local)
+	if err != nil {
+		return err
+	}
+
+	if usageJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(totals)
+	}
+
+	if len(totals) == 0 {
+		fmt.Println("No usage recorded.")
+	} else {
+		var sum usage.Total
+		fmt.Printf("%-40s %8s %12s %12s %10s\n", usageBy, "requests", "prompt", "completion", "cost")
+		for _, t := range totals {
+			key := t.Key
+			if key == "" {
+				key = "-"
+			}
+			fmt.Printf("%-40s %8d %12d %12d %10s\n", key, t.Requests, t.PromptTokens, t.CompletionTokens, fmt.Sprintf("$%.4f", t.Cost))
+			sum.Requests += t.Requests
+			sum.PromptTokens += t.PromptTokens
+			sum.CompletionTokens += t.CompletionTokens
+			sum.Cost += t.Cost
+		}
+		fmt.Printf("%-40s %8d %12d %12d %10s\n", "total", sum.Requests, sum.PromptTokens, sum.CompletionTokens, fmt.Sprintf("$%.4f", sum.Cost))
+	}
+
+	if cfg.Budget.Enabled {
+		return printBudget(cfg)
+	}
+	return nil
+}
+
+// printBudget shows global budget usage in the current day and month.
+func printBudget(cfg *config.Config) error {
+	tracker, err := budget.New(budget.Config{
+		Global: budget.Policy{Daily: budgetLimit(cfg.Budget.Daily), Monthly: budgetLimit(cfg.Budget.Monthly)},
+		WarnAt: cfg.Budget.WarnAt,
+		Path:   budgetPath(cfg),
+	})
+	if err != nil {
+		return err
+	}
+	daily, monthly := tracker.Usage(budget.GlobalScope)
+	fmt.Printf("\nBudget (%s):\n", tracker.Check("").Status)
+	printBudgetLine("today", daily, cfg.Budget.Daily)
+	printBudgetLine("this month", monthly, cfg.Budget.Monthly)
+	return nil
+}
+
+func printBudgetLine(period string, used budget.Usage, limit config.BudgetLimits) {
+	tokens := fmt.Sprintf("%d tokens", used.Tokens)
+	if limit.Tokens > 0 {
+		tokens = fmt.Sprintf("%d/%d tokens", used.Tokens, limit.Tokens)
+	}
+	cost := fmt.Sprintf("$%.2f", used.Cost)
+	if limit.Cost > 0 {
+		cost = fmt.Sprintf("$%.2f/$%.2f", used.Cost, limit.Cost)
+	}
+	fmt.Printf("  %-11s %s, %s\n", period+":", tokens, cost)
+}
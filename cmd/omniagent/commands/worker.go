@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/worker"
+)
+
+var (
+	workerGateway string
+	workerToken   string
+	workerTools   []string
+)
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Run tools for an agent on another machine",
+	Long: `Commands for worker mode, where this machine offers its tools to an
+agent running elsewhere, such as a shell on a home server or a browser on
+a desktop.`,
+}
+
+var workerRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Connect to a gateway and serve tool calls",
+	Long: `Connect to the agent's gateway with a worker token (see gateway.workers
+in its config) and offer this machine's enabled tools. The agent sees them
+as <worker>_<tool>, for example home_shell. The worker reconnects when the
+connection drops.`,
+	RunE: runWorker,
+}
+
+func init() {
+	workerRunCmd.Flags().StringVar(&workerGateway, "gateway", "", "gateway WebSocket URL (e.g. ws://home-server:18789/ws)")
+	workerRunCmd.Flags().StringVar(&workerToken, "token", "", "worker token")
+	workerRunCmd.Flags().StringSliceVar(&workerTools, "tools", nil, "tools to offer (default: all enabled)")
+	workerCmd.AddCommand(workerRunCmd)
+}
+
+func runWorker(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+	logger := slog.Default()
+
+	url := cfg.Worker.Gateway
+	if workerGateway != "" {
+		url = workerGateway
+	}
+	token := cfg.Worker.Token
+	if workerToken != "" {
+		token = workerToken
+	}
+	names := cfg.Worker.Tools
+	if len(workerTools) > 0 {
+		names = workerTools
+	}
+	if url == "" {
+		return errors.New("gateway URL required (--gateway or worker.gateway)")
+	}
+
+	builtins, err := buildTools(cfg, logger)
+	defer builtins.Close()
+	if err != nil {
+		return err
+	}
+	tools, err := selectTools(builtins.Tools, names)
+	if err != nil {
+		return err
+	}
+
+	w, err := worker.New(worker.Config{URL: url, Token: token, Tools: tools, Logger: logger})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	offered := make([]string, 0, len(tools))
+	for _, t := range tools {
+		offered = append(offered, t.Name())
+	}
+	fmt.Printf("Worker connecting to %s with tools: %v\n", url, offered)
+	fmt.Println("Press Ctrl+C to stop")
+	return w.Run(ctx)
+}
+
+// selectTools returns the named tools, or all of them if names is empty.
+func selectTools(all []agent.Tool, names []string) ([]agent.Tool, error) {
+	if len(names) == 0 {
+		return all, nil
+	}
+	var selected []agent.Tool
+	for _, name := range names {
+		i := slices.IndexFunc(all, func(t agent.Tool) bool { return t.Name() == name })
+		if i < 0 {
+			return nil, fmt.Errorf("tool %q is not enabled", name)
+		}
+		selected = append(selected, all[i])
+	}
+	return selected, nil
+}
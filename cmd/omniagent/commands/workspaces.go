@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/plexusone/omniagent/sandbox"
+)
+
+var workspacesCmd = &cobra.Command{
+	Use:   "workspaces",
+	Short: "Manage persistent sandbox workspaces",
+	Long: `Commands for the persistent sandbox workspaces enabled by
+tools.sandbox.workspaces. Each session gets its own workspace, mounted as
+the working directory of its sandboxed commands, so files survive across
+tool calls.
+
+Workspaces can be named by session, e.g. telegram:12345, or by the
+directory name shown by list.`,
+}
+
+var workspacesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workspaces, least recently used first",
+	RunE:  workspacesList,
+}
+
+var workspacesResetCmd = &cobra.Command{
+	Use:   "reset <workspace>",
+	Short: "Empty a workspace",
+	Args:  cobra.ExactArgs(1),
+	RunE:  workspacesReset,
+}
+
+var workspacesRemoveCmd = &cobra.Command{
+	Use:   "rm <workspace>",
+	Short: "Delete a workspace",
+	Args:  cobra.ExactArgs(1),
+	RunE:  workspacesRemove,
+}
+
+var workspacesGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete idle workspaces",
+	Long:  "Delete the workspaces no sandbox has used for longer than --idle (default: tools.sandbox.workspaces.max_idle).",
+	RunE:  workspacesGC,
+}
+
+var workspacesGCIdle time.Duration
+
+func init() {
+	workspacesCmd.AddCommand(workspacesListCmd)
+	workspacesCmd.AddCommand(workspacesResetCmd)
+	workspacesCmd.AddCommand(workspacesRemoveCmd)
+	workspacesCmd.AddCommand(workspacesGCCmd)
+
+	workspacesGCCmd.Flags().DurationVar(&workspacesGCIdle, "idle", 0, "delete workspaces idle for longer than this")
+}
+
+func workspacesList(cmd *cobra.Command, args []string) error {
+	list, err := sandbox.NewWorkspaces(workspacesDir(getConfig())).List()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Println("No workspaces.")
+		return nil
+	}
+	fmt.Printf("%-40s %10s  %s\n", "WORKSPACE", "SIZE", "LAST USED")
+	for _, ws := range list {
+		fmt.Printf("%-40s %10s  %s\n", ws.Name, formatWorkspaceSize(ws.Size), ws.LastUsed.Format(time.DateTime))
+	}
+	return nil
+}
+
+func workspacesReset(cmd *cobra.Command, args []string) error {
+	name := sandbox.WorkspaceName(args[0])
+	if err := sandbox.NewWorkspaces(workspacesDir(getConfig())).Reset(name); err != nil {
+		return err
+	}
+	fmt.Printf("Reset %s\n", name)
+	return nil
+}
+
+func workspacesRemove(cmd *cobra.Command, args []string) error {
+	name := sandbox.WorkspaceName(args[0])
+	if err := sandbox.NewWorkspaces(workspacesDir(getConfig())).Remove(name); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", name)
+	return nil
+}
+
+func workspacesGC(cmd *cobra.Command, args []string) error {
+	cfg := getConfig()
+	idle := workspacesGCIdle
+	if idle <= 0 {
+		idle = cfg.Tools.Sandbox.Workspaces.MaxIdle
+	}
+	if idle <= 0 {
+		return errors.New("set --idle or tools.sandbox.workspaces.max_idle")
+	}
+	removed, err := sandbox.NewWorkspaces(workspacesDir(cfg)).GC(idle)
+	for _, name := range removed {
+		fmt.Printf("Removed %s\n", name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Printf("No workspaces idle for more than %s.\n", idle)
+	}
+	return nil
+}
+
+// formatWorkspaceSize formats a byte count for the list.
+func formatWorkspaceSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
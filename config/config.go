@@ -12,6 +12,44 @@ type Config struct {
 	Skills        SkillsConfig        `json:"skills" yaml:"skills"`
 	Voice         VoiceConfig         `json:"voice" yaml:"voice"`
 	Observability ObservabilityConfig `json:"observability" yaml:"observability"`
+	Storage       StorageConfig       `json:"storage" yaml:"storage"`
+	Scheduler     SchedulerConfig     `json:"scheduler" yaml:"scheduler"`
+	Eval          EvalConfig          `json:"eval" yaml:"eval"`
+	RateLimit     RateLimitConfig     `json:"rate_limit" yaml:"rate_limit"`
+	MCPServers    []MCPServerConfig   `json:"mcp_servers" yaml:"mcp_servers"`
+	Time          TimeConfig          `json:"time" yaml:"time"`
+	Contacts      ContactsConfig      `json:"contacts" yaml:"contacts"`
+	Onboarding    OnboardingConfig    `json:"onboarding" yaml:"onboarding"`
+	Budget        BudgetConfig        `json:"budget" yaml:"budget"`
+	Approvals     ApprovalsConfig     `json:"approvals" yaml:"approvals"`
+	Health        HealthConfig        `json:"health" yaml:"health"`
+	Transcripts   TranscriptsConfig   `json:"transcripts" yaml:"transcripts"`
+	Audit         AuditConfig         `json:"audit" yaml:"audit"`
+	Usage         UsageConfig         `json:"usage" yaml:"usage"`
+	Catchup       CatchupConfig       `json:"catchup" yaml:"catchup"`
+	Jobs          JobsConfig          `json:"jobs" yaml:"jobs"`
+	Instance      InstanceConfig      `json:"instance" yaml:"instance"`
+	Memory        MemoryConfig        `json:"memory" yaml:"memory"`
+	Knowledge     KnowledgeConfig     `json:"knowledge" yaml:"knowledge"`
+	Hooks         HooksConfig         `json:"hooks" yaml:"hooks"`
+	Attachments   AttachmentsConfig   `json:"attachments" yaml:"attachments"`
+	Reactions     ReactionsConfig     `json:"reactions" yaml:"reactions"`
+	Templates     TemplatesConfig     `json:"templates" yaml:"templates"`
+	Privacy       PrivacyConfig       `json:"privacy" yaml:"privacy"`
+	Flags         FlagsConfig         `json:"flags" yaml:"flags"`
+	Proposals     ProposalsConfig     `json:"proposals" yaml:"proposals"`
+	Tasks         []TaskConfig        `json:"tasks" yaml:"tasks"`
+	Agents        []PersonaConfig     `json:"agents" yaml:"agents"`
+	Routing       []RouteConfig       `json:"routing" yaml:"routing"`
+	Identities    []IdentityConfig    `json:"identities" yaml:"identities"`
+	Access        AccessConfig        `json:"access" yaml:"access"`
+	Worker        WorkerConfig        `json:"worker" yaml:"worker"`
+	Reload        ReloadConfig        `json:"reload" yaml:"reload"`
+	Encryption    EncryptionConfig    `json:"encryption" yaml:"encryption"`
+
+	// Owners are contact IDs ("telegram:12345") of the people running this
+	// agent. They receive operational alerts as direct messages.
+	Owners []string `json:"owners" yaml:"owners"`
 }
 
 // GatewayConfig configures the WebSocket gateway.
@@ -20,17 +58,233 @@ type GatewayConfig struct {
 	ReadTimeout  time.Duration `json:"read_timeout" yaml:"read_timeout"`
 	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout"`
 	PingInterval time.Duration `json:"ping_interval" yaml:"ping_interval"`
+	TLS          TLSConfig     `json:"tls" yaml:"tls"`
+	HTTP         HTTPConfig    `json:"http" yaml:"http"`
+
+	// AllowedOrigins are the browser origins, besides the gateway's own,
+	// that may open WebSocket connections; "*" allows any.
+	AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
+
+	// Token, when set, is required to open a WebSocket connection.
+	Token string `json:"token" yaml:"token"` //nolint:gosec // G117: Token loaded from config file
+
+	// Observers authenticate read-only WebSocket clients that watch a
+	// redacted live view of agent activity.
+	Observers []ObserverConfig `json:"observers" yaml:"observers"`
+
+	// Approvers authenticate WebSocket clients that approve or deny tool
+	// calls (see approvals).
+	Approvers []ApproverConfig `json:"approvers" yaml:"approvers"`
+
+	// Workers authenticate remote machines that run tools for the agent
+	// (see omniagent worker run).
+	Workers []RemoteWorkerConfig `json:"workers" yaml:"workers"`
+
+	// AdminToken enables the admin API (/v1/admin/...) for bearer
+	// requests with this token.
+	AdminToken string `json:"admin_token" yaml:"admin_token"` //nolint:gosec // G117: Token loaded from config file
+}
+
+// ApproverConfig configures a tool approver token.
+type ApproverConfig struct {
+	Name  string `json:"name" yaml:"name"`
+	Token string `json:"token" yaml:"token"` //nolint:gosec // G117: Token loaded from config file
+}
+
+// RemoteWorkerConfig configures a worker token. The worker's tools are
+// named "<name>_<tool>".
+type RemoteWorkerConfig struct {
+	Name    string        `json:"name" yaml:"name"`
+	Token   string        `json:"token" yaml:"token"` //nolint:gosec // G117: Token loaded from config file
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// WorkerConfig configures this instance as a worker, running tools for
+// an agent on another machine (omniagent worker run).
+type WorkerConfig struct {
+	// Gateway is the agent gateway's WebSocket URL, such as
+	// ws://home-server:18789/ws.
+	Gateway string `json:"gateway" yaml:"gateway"`
+	Token   string `json:"token" yaml:"token"` //nolint:gosec // G117: Token loaded from config file
+
+	// Tools are the names of the built-in tools to offer (default: all
+	// enabled in tools).
+	Tools []string `json:"tools" yaml:"tools"`
+}
+
+// ObserverConfig configures a read-only observer token.
+type ObserverConfig struct {
+	Name     string   `json:"name" yaml:"name"`
+	Token    string   `json:"token" yaml:"token"` //nolint:gosec // G117: Token loaded from config file
+	Channels []string `json:"channels" yaml:"channels"`
+}
+
+// HTTPConfig configures the inbound HTTP channel (POST /v1/messages).
+type HTTPConfig struct {
+	Enabled        bool     `json:"enabled" yaml:"enabled"`
+	Token          string   `json:"token" yaml:"token"`                     //nolint:gosec // G117: Token loaded from config file
+	CallbackSecret string   `json:"callback_secret" yaml:"callback_secret"` //nolint:gosec // G117: Signing secret loaded from config file
+	CallbackHosts  []string `json:"callback_hosts" yaml:"callback_hosts"`   // Private hosts callbacks may reach
+}
+
+// TLSConfig configures TLS for the gateway.
+type TLSConfig struct {
+	CertFile         string   `json:"cert_file" yaml:"cert_file"`
+	KeyFile          string   `json:"key_file" yaml:"key_file"`
+	Autocert         bool     `json:"autocert" yaml:"autocert"`
+	AutocertDomains  []string `json:"autocert_domains" yaml:"autocert_domains"`
+	AutocertCacheDir string   `json:"autocert_cache_dir" yaml:"autocert_cache_dir"`
+	AutocertEmail    string   `json:"autocert_email" yaml:"autocert_email"`
+	ChallengeAddress string   `json:"challenge_address" yaml:"challenge_address"`
 }
 
 // AgentConfig configures the AI agent.
 type AgentConfig struct {
-	Provider     string  `json:"provider" yaml:"provider"`
-	Model        string  `json:"model" yaml:"model"`
-	APIKey       string  `json:"api_key" yaml:"api_key"` //nolint:gosec // G117: APIKey loaded from config file
-	BaseURL      string  `json:"base_url" yaml:"base_url"`
-	Temperature  float64 `json:"temperature" yaml:"temperature"`
-	MaxTokens    int     `json:"max_tokens" yaml:"max_tokens"`
-	SystemPrompt string  `json:"system_prompt" yaml:"system_prompt"`
+	Provider      string  `json:"provider" yaml:"provider"`
+	Model         string  `json:"model" yaml:"model"`
+	APIKey        string  `json:"api_key" yaml:"api_key"` //nolint:gosec // G117: APIKey loaded from config file
+	BaseURL       string  `json:"base_url" yaml:"base_url"`
+	Temperature   float64 `json:"temperature" yaml:"temperature"`
+	MaxTokens     int     `json:"max_tokens" yaml:"max_tokens"`
+	SystemPrompt  string  `json:"system_prompt" yaml:"system_prompt"`
+	ContextLength int     `json:"context_length" yaml:"context_length"` // 0 = detect for local providers
+	ToolCallMode  string  `json:"tool_call_mode" yaml:"tool_call_mode"` // auto, native, or prompt
+
+	// MaxToolIterations bounds model calls per message (default: 5), and
+	// MaxRepeatedCalls stops a message once the model repeats an identical
+	// tool call more often than this (default: 2).
+	MaxToolIterations int `json:"max_tool_iterations" yaml:"max_tool_iterations"`
+	MaxRepeatedCalls  int `json:"max_repeated_calls" yaml:"max_repeated_calls"`
+
+	// RateLimits paces model requests to each provider's limits, keyed by
+	// provider name, across all channels and personas. ThrottleWait bounds
+	// how long a request queues before the user is told to retry
+	// (default: 2m).
+	RateLimits   map[string]ProviderRateLimits `json:"rate_limits,omitempty" yaml:"rate_limits,omitempty"`
+	ThrottleWait time.Duration                 `json:"throttle_wait" yaml:"throttle_wait"`
+
+	// PreferencesFile persists per-conversation /prefs settings
+	// (default: <storage.path>/preferences.json).
+	PreferencesFile string `json:"preferences_file" yaml:"preferences_file"`
+
+	// PinsFile persists messages pinned with /pin
+	// (default: <storage.path>/pins.json).
+	PinsFile string `json:"pins_file" yaml:"pins_file"`
+
+	// ContextKeys are the message metadata keys, such as location or
+	// current_url, exposed to the system prompt and tools.
+	ContextKeys []string `json:"context_keys" yaml:"context_keys"`
+
+	// PromptVars are custom values for {{.Name}} placeholders in system
+	// prompts, alongside built-ins such as {{.Date}} and {{.UserName}}.
+	PromptVars map[string]string `json:"prompt_vars,omitempty" yaml:"prompt_vars,omitempty"`
+
+	// Experiment splits traffic between model or prompt variants.
+	Experiment *ExperimentConfig `json:"experiment,omitempty" yaml:"experiment,omitempty"`
+
+	// ContentFilter sets the reply when the provider's safety filters
+	// refuse a request.
+	ContentFilter ContentFilterConfig `json:"content_filter" yaml:"content_filter"`
+
+	// Retry retries model requests that fail with transient errors, and
+	// Fallbacks are models tried in order when they keep failing.
+	Retry     RetryConfig           `json:"retry" yaml:"retry"`
+	Fallbacks []FallbackModelConfig `json:"fallbacks" yaml:"fallbacks"`
+}
+
+// RetryConfig configures retries of server errors, timeouts, and network
+// failures.
+type RetryConfig struct {
+	Attempts   int           `json:"attempts" yaml:"attempts"`       // Retries per model (default: 0)
+	Backoff    time.Duration `json:"backoff" yaml:"backoff"`         // First delay, doubling (default: 1s)
+	MaxBackoff time.Duration `json:"max_backoff" yaml:"max_backoff"` // Default: 30s
+}
+
+// FallbackModelConfig is a model used when the ones before it are
+// unavailable. Credentials default to the agent's for the same provider.
+type FallbackModelConfig struct {
+	Provider string `json:"provider" yaml:"provider"`
+	Model    string `json:"model" yaml:"model"`
+	APIKey   string `json:"api_key" yaml:"api_key"` //nolint:gosec // G117: API key loaded from config file
+	BaseURL  string `json:"base_url" yaml:"base_url"`
+}
+
+// ContentFilterConfig configures the reply to provider refusals.
+type ContentFilterConfig struct {
+	// Message replaces the error the user would otherwise see
+	// (default: "Sorry, I can't help with that request.").
+	Message string `json:"message" yaml:"message"`
+	// Retry resends a refused request once, asking the model to answer
+	// only what it safely can.
+	Retry bool `json:"retry" yaml:"retry"`
+}
+
+// ProviderRateLimits are a model provider's request and token limits. Zero
+// disables a limit.
+type ProviderRateLimits struct {
+	RequestsPerMinute int `json:"requests_per_minute" yaml:"requests_per_minute"`
+	TokensPerMinute   int `json:"tokens_per_minute" yaml:"tokens_per_minute"`
+}
+
+// ExperimentConfig configures an A/B test. Sessions not assigned to a
+// variant use the base agent settings and report as "control".
+type ExperimentConfig struct {
+	Name     string                    `json:"name" yaml:"name"`
+	Variants []ExperimentVariantConfig `json:"variants" yaml:"variants"`
+}
+
+// ExperimentVariantConfig configures one experiment variant.
+type ExperimentVariantConfig struct {
+	Name         string `json:"name" yaml:"name"`
+	Weight       int    `json:"weight" yaml:"weight"` // Percent of sessions
+	Model        string `json:"model,omitempty" yaml:"model,omitempty"`
+	SystemPrompt string `json:"system_prompt,omitempty" yaml:"system_prompt,omitempty"`
+}
+
+// PersonaConfig defines a named agent. Empty fields fall back to the agent
+// configuration.
+type PersonaConfig struct {
+	Name         string   `json:"name" yaml:"name"`
+	Description  string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Provider     string   `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Model        string   `json:"model,omitempty" yaml:"model,omitempty"`
+	APIKey       string   `json:"api_key,omitempty" yaml:"api_key,omitempty"` //nolint:gosec // G117: APIKey loaded from config file
+	BaseURL      string   `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty" yaml:"system_prompt,omitempty"`
+	Temperature  *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	Tools        []string `json:"tools,omitempty" yaml:"tools,omitempty"` // Allowed tools; unset allows all
+}
+
+// IdentityConfig links one person's contact IDs ("telegram:12345") across
+// channels. Owner and approver lists accept "person:<id>".
+type IdentityConfig struct {
+	ID       string   `json:"id" yaml:"id"`
+	Name     string   `json:"name" yaml:"name"`
+	Contacts []string `json:"contacts" yaml:"contacts"`
+}
+
+// AccessConfig sorts senders into permission tiers: owner, trusted, guest,
+// and blocked. Owners are always in the owner tier.
+type AccessConfig struct {
+	Default string                      `json:"default" yaml:"default"` // Tier for unlisted senders (default: guest)
+	Members map[string][]string         `json:"members" yaml:"members"` // Contact and person IDs by tier
+	Tiers   map[string]AccessTierConfig `json:"tiers" yaml:"tiers"`     // Overrides a tier's defaults
+}
+
+// AccessTierConfig configures what one permission tier may do. Unset
+// fields keep the tier's defaults.
+type AccessTierConfig struct {
+	Tools     []string `json:"tools,omitempty" yaml:"tools,omitempty"` // Allowed tools; "*" allows all
+	MaxTokens *int     `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	Silent    *bool    `json:"silent,omitempty" yaml:"silent,omitempty"` // Never reply
+}
+
+// RouteConfig sends matching messages to a named agent.
+type RouteConfig struct {
+	Agent    string   `json:"agent" yaml:"agent"`
+	Channels []string `json:"channels,omitempty" yaml:"channels,omitempty"`
+	Senders  []string `json:"senders,omitempty" yaml:"senders,omitempty"` // Contact IDs, e.g. telegram:12345
+	Prefix   string   `json:"prefix,omitempty" yaml:"prefix,omitempty"`   // e.g. "@coder"
 }
 
 // ChannelsConfig configures messaging channels.
@@ -38,38 +292,167 @@ type ChannelsConfig struct {
 	Telegram TelegramConfig `json:"telegram" yaml:"telegram"`
 	Discord  DiscordConfig  `json:"discord" yaml:"discord"`
 	WhatsApp WhatsAppConfig `json:"whatsapp" yaml:"whatsapp"`
+	SMS      SMSConfig      `json:"sms" yaml:"sms"`
+	Signal   SignalConfig   `json:"signal" yaml:"signal"`
+	Progress ProgressConfig `json:"progress" yaml:"progress"`
+	Startup  StartupConfig  `json:"startup" yaml:"startup"`
+}
+
+// StartupConfig decides what happens when a channel fails to start:
+// fail_fast stops the gateway, retry keeps reconnecting in the background,
+// and disable runs without the channel.
+type StartupConfig struct {
+	Policy           string            `json:"policy" yaml:"policy"`                         // Default: fail_fast
+	Channels         map[string]string `json:"channels,omitempty" yaml:"channels,omitempty"` // Policy by channel name
+	RetryInterval    time.Duration     `json:"retry_interval" yaml:"retry_interval"`         // First retry wait, doubling (default: 5s)
+	MaxRetryInterval time.Duration     `json:"max_retry_interval" yaml:"max_retry_interval"` // Default: 5m
+}
+
+// ProgressConfig configures progress updates sent to channels while
+// long-running tools work.
+type ProgressConfig struct {
+	Enabled   bool          `json:"enabled" yaml:"enabled"`
+	Interval  time.Duration `json:"interval" yaml:"interval"`     // Minimum time between updates (default: 10s)
+	MaxLength int           `json:"max_length" yaml:"max_length"` // Bytes of recent output per update (default: 500)
+}
+
+// SignalConfig configures the Signal channel, which talks to a signal-cli
+// daemon started with --http.
+type SignalConfig struct {
+	Enabled            bool   `json:"enabled" yaml:"enabled"`
+	URL                string `json:"url" yaml:"url"`                         // signal-cli HTTP daemon
+	Account            string `json:"account" yaml:"account"`                 // Registered number, for multi-account daemons
+	AttachmentsDir     string `json:"attachments_dir" yaml:"attachments_dir"` // Read attachments from disk instead of over JSON-RPC
+	ChannelAgentConfig `yaml:",inline"`
+}
+
+// SMSConfig configures the Twilio SMS channel. Twilio posts inbound
+// messages to the gateway at /webhooks/sms.
+type SMSConfig struct {
+	Enabled            bool   `json:"enabled" yaml:"enabled"`
+	AccountSID         string `json:"account_sid" yaml:"account_sid"`
+	AuthToken          string `json:"auth_token" yaml:"auth_token"`   //nolint:gosec // G117: AuthToken loaded from config file
+	From               string `json:"from" yaml:"from"`               // Default sending number (E.164)
+	WebhookURL         string `json:"webhook_url" yaml:"webhook_url"` // Public webhook URL, for signature checks behind proxies
+	MaxLength          int    `json:"max_length" yaml:"max_length"`   // Split replies longer than this (default: 1600)
+	ChannelAgentConfig `yaml:",inline"`
 }
 
 // WhatsAppConfig configures the WhatsApp channel.
 type WhatsAppConfig struct {
-	Enabled bool   `json:"enabled" yaml:"enabled"`
-	DBPath  string `json:"db_path" yaml:"db_path"`
+	Enabled            bool   `json:"enabled" yaml:"enabled"`
+	DBPath             string `json:"db_path" yaml:"db_path"`
+	ChannelAgentConfig `yaml:",inline"`
 }
 
 // TelegramConfig configures the Telegram channel.
 type TelegramConfig struct {
-	Enabled bool   `json:"enabled" yaml:"enabled"`
-	Token   string `json:"token" yaml:"token"`
+	Enabled            bool   `json:"enabled" yaml:"enabled"`
+	Token              string `json:"token" yaml:"token"`
+	ChannelAgentConfig `yaml:",inline"`
 }
 
 // DiscordConfig configures the Discord channel.
 type DiscordConfig struct {
-	Enabled bool   `json:"enabled" yaml:"enabled"`
-	Token   string `json:"token" yaml:"token"`
-	GuildID string `json:"guild_id" yaml:"guild_id"`
+	Enabled            bool   `json:"enabled" yaml:"enabled"`
+	Token              string `json:"token" yaml:"token"`
+	GuildID            string `json:"guild_id" yaml:"guild_id"`
+	ChannelAgentConfig `yaml:",inline"`
+}
+
+// ChannelAgentConfig overrides agent settings for messages from a channel.
+// Empty fields fall back to the agent configuration.
+type ChannelAgentConfig struct {
+	Model        string   `json:"model,omitempty" yaml:"model,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty" yaml:"system_prompt,omitempty"`
+	Temperature  *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+
+	// Trigger limits which group chat messages are answered.
+	Trigger TriggerConfig `json:"trigger,omitempty" yaml:"trigger,omitempty"`
+}
+
+// TriggerConfig makes the agent answer only group chat messages that
+// mention it, start with a prefix, or match a pattern. Without any, every
+// message is answered. Direct messages and slash commands always are.
+type TriggerConfig struct {
+	Mentions []string `json:"mentions,omitempty" yaml:"mentions,omitempty"` // Such as "@omniagent"
+	Prefixes []string `json:"prefixes,omitempty" yaml:"prefixes,omitempty"` // Such as "!ai"; removed from the message
+	Patterns []string `json:"patterns,omitempty" yaml:"patterns,omitempty"` // Regular expressions
 }
 
 // ToolsConfig configures available tools.
 type ToolsConfig struct {
-	Browser BrowserToolConfig `json:"browser" yaml:"browser"`
-	Shell   ShellToolConfig   `json:"shell" yaml:"shell"`
+	Browser    BrowserToolConfig    `json:"browser" yaml:"browser"`
+	Shell      ShellToolConfig      `json:"shell" yaml:"shell"`
+	File       FileToolConfig       `json:"file" yaml:"file"`
+	HTTPFetch  HTTPFetchToolConfig  `json:"http_fetch" yaml:"http_fetch"`
+	Scratchpad ScratchpadToolConfig `json:"scratchpad" yaml:"scratchpad"`
+	WebDAV     WebDAVToolConfig     `json:"webdav" yaml:"webdav"`
+	Images     ImageToolConfig      `json:"images" yaml:"images"`
+	Speech     SpeechToolConfig     `json:"speech" yaml:"speech"`
+	Sandbox    SandboxConfig        `json:"sandbox" yaml:"sandbox"`
+
+	// Precedence orders tool sources ("builtin", "mcp:<server>") for
+	// resolving name collisions, highest first.
+	Precedence []string `json:"precedence" yaml:"precedence"`
+
+	// Aliases exposes tools by ID ("source/name") under another name.
+	Aliases map[string]string `json:"aliases" yaml:"aliases"`
+
+	// Fixtures answers tool calls from files for reproducible tests.
+	Fixtures FixturesConfig `json:"fixtures" yaml:"fixtures"`
+}
+
+// FixturesConfig replaces tool execution with canned results from
+// fixture files. Leave Mode empty in production.
+type FixturesConfig struct {
+	Mode string `json:"mode" yaml:"mode"` // replay or record; empty runs tools
+	Dir  string `json:"dir" yaml:"dir"`   // Default: testdata/fixtures
+}
+
+// SandboxConfig configures isolated execution for the shell tool and skill
+// scripts. Other tools run on the host.
+type SandboxConfig struct {
+	Mode   string              `json:"mode" yaml:"mode"` // Default for shell and skill scripts: none, docker, wasm, process
+	Docker DockerSandboxConfig `json:"docker" yaml:"docker"`
+	WASM   WASMSandboxConfig   `json:"wasm" yaml:"wasm"`
+
+	Workspaces WorkspacesConfig `json:"workspaces" yaml:"workspaces"`
+}
+
+// WorkspacesConfig gives each session a persistent sandbox workspace.
+type WorkspacesConfig struct {
+	Enabled bool          `json:"enabled" yaml:"enabled"`
+	Dir     string        `json:"dir" yaml:"dir"`           // Default: <storage>/workspaces
+	MaxIdle time.Duration `json:"max_idle" yaml:"max_idle"` // Idle time before workspaces gc removes one
+}
+
+// DockerSandboxConfig configures the container sandbox backend.
+type DockerSandboxConfig struct {
+	Engine      string        `json:"engine" yaml:"engine"` // docker, podman, or containerd; default: docker
+	Host        string        `json:"host" yaml:"host"`     // Docker or Podman API endpoint
+	Image       string        `json:"image" yaml:"image"`
+	NetworkMode string        `json:"network_mode" yaml:"network_mode"`
+	Runtime     string        `json:"runtime" yaml:"runtime"` // OCI runtime, e.g. runsc or kata-runtime
+	MemoryMB    int           `json:"memory_mb" yaml:"memory_mb"`
+	Timeout     time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// WASMSandboxConfig configures the WASM sandbox backend.
+type WASMSandboxConfig struct {
+	Module        string `json:"module" yaml:"module"` // WASI shell module, e.g. busybox.wasm
+	MemoryLimitMB int    `json:"memory_limit_mb" yaml:"memory_limit_mb"`
+	FuelLimit     uint64 `json:"fuel_limit" yaml:"fuel_limit"` // Function calls per command; 0 = unlimited
 }
 
 // BrowserToolConfig configures the browser automation tool.
 type BrowserToolConfig struct {
-	Enabled  bool   `json:"enabled" yaml:"enabled"`
-	Headless bool   `json:"headless" yaml:"headless"`
-	UserData string `json:"user_data" yaml:"user_data"`
+	Enabled       bool          `json:"enabled" yaml:"enabled"`
+	Headless      bool          `json:"headless" yaml:"headless"`
+	UserData      string        `json:"user_data" yaml:"user_data"`
+	MaxSessions   int           `json:"max_sessions" yaml:"max_sessions"`
+	MaxConcurrent int           `json:"max_concurrent" yaml:"max_concurrent"`
+	IdleTimeout   time.Duration `json:"idle_timeout" yaml:"idle_timeout"`
 }
 
 // ShellToolConfig configures the shell execution tool.
@@ -77,6 +460,62 @@ type ShellToolConfig struct {
 	Enabled    bool     `json:"enabled" yaml:"enabled"`
 	WorkingDir string   `json:"working_dir" yaml:"working_dir"`
 	Allowlist  []string `json:"allowlist" yaml:"allowlist"`
+	Sandbox    string   `json:"sandbox" yaml:"sandbox"` // Overrides tools.sandbox.mode
+}
+
+// FileToolConfig configures the local file tool.
+type FileToolConfig struct {
+	Enabled   bool     `json:"enabled" yaml:"enabled"`
+	Paths     []string `json:"paths" yaml:"paths"` // Allowed directories; relative paths resolve against the first
+	Write     bool     `json:"write" yaml:"write"`
+	MaxFileKB int      `json:"max_file_kb" yaml:"max_file_kb"`
+}
+
+// HTTPFetchToolConfig configures the HTTP fetch tool.
+type HTTPFetchToolConfig struct {
+	Enabled       bool          `json:"enabled" yaml:"enabled"`
+	AllowedHosts  []string      `json:"allowed_hosts" yaml:"allowed_hosts"` // Empty allows any public host
+	MaxResponseKB int           `json:"max_response_kb" yaml:"max_response_kb"`
+	Timeout       time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// ScratchpadToolConfig configures the persistent key-value scratchpad.
+type ScratchpadToolConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Path    string `json:"path" yaml:"path"` // Default: <storage.path>/scratchpad.db
+}
+
+// WebDAVToolConfig configures the WebDAV/Nextcloud file tool.
+type WebDAVToolConfig struct {
+	Enabled   bool                 `json:"enabled" yaml:"enabled"`
+	URL       string               `json:"url" yaml:"url"` // e.g. https://cloud.example.com/remote.php/dav/files/alice
+	Username  string               `json:"username" yaml:"username"`
+	Password  string               `json:"password" yaml:"password"` //nolint:gosec // G117: Password loaded from config file
+	Folders   []WebDAVFolderConfig `json:"folders" yaml:"folders"`
+	MaxFileMB int                  `json:"max_file_mb" yaml:"max_file_mb"`
+}
+
+// ImageToolConfig configures the generate_image tool, which uses an
+// OpenAI-compatible images endpoint. The API key defaults to agent.api_key
+// for OpenAI.
+type ImageToolConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	BaseURL string `json:"base_url" yaml:"base_url"` // Default: https://api.openai.com/v1
+	APIKey  string `json:"api_key" yaml:"api_key"`   //nolint:gosec // G117: API key loaded from config file
+	Model   string `json:"model" yaml:"model"`       // Default: gpt-image-1
+	Size    string `json:"size" yaml:"size"`         // Default: 1024x1024
+}
+
+// SpeechToolConfig configures the speak tool, which reads text aloud with
+// the voice TTS provider.
+type SpeechToolConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// WebDAVFolderConfig allows access to a folder and its subfolders.
+type WebDAVFolderConfig struct {
+	Path  string `json:"path" yaml:"path"`
+	Write bool   `json:"write" yaml:"write"`
 }
 
 // SkillsConfig configures skill loading.
@@ -85,6 +524,20 @@ type SkillsConfig struct {
 	Paths       []string `json:"paths" yaml:"paths"`
 	Disabled    []string `json:"disabled" yaml:"disabled"`
 	MaxInjected int      `json:"max_injected" yaml:"max_injected"`
+	Scripts     bool     `json:"scripts" yaml:"scripts"` // Expose skills' scripts/ as tools
+	Sandbox     string   `json:"sandbox" yaml:"sandbox"` // Script sandbox: docker or wasm; default: tools.sandbox.mode
+
+	// Watch reloads skills when skill directories change
+	Watch         bool          `json:"watch" yaml:"watch"`
+	WatchInterval time.Duration `json:"watch_interval" yaml:"watch_interval"` // Default: 5s
+
+	// Injection is "all" (default) to add every skill to the system
+	// prompt, or "relevant" to add only those whose descriptions best
+	// match each message.
+	Injection string          `json:"injection" yaml:"injection"`
+	TopK      int             `json:"top_k" yaml:"top_k"`         // Relevant skills per message; default 5
+	MinScore  float64         `json:"min_score" yaml:"min_score"` // Cosine similarity; default 0
+	Embedding EmbeddingConfig `json:"embedding" yaml:"embedding"`
 }
 
 // VoiceConfig configures voice processing.
@@ -93,22 +546,51 @@ type VoiceConfig struct {
 	ResponseMode string    `json:"response_mode" yaml:"response_mode"`
 	STT          STTConfig `json:"stt" yaml:"stt"`
 	TTS          TTSConfig `json:"tts" yaml:"tts"`
+
+	// STTFallbacks and TTSFallbacks are tried in order when the provider
+	// before them fails, such as on an outage or exhausted quota.
+	STTFallbacks []STTConfig `json:"stt_fallbacks,omitempty" yaml:"stt_fallbacks,omitempty"`
+	TTSFallbacks []TTSConfig `json:"tts_fallbacks,omitempty" yaml:"tts_fallbacks,omitempty"`
+
+	// Transcode converts audio with ffmpeg.
+	Transcode TranscodeConfig `json:"transcode" yaml:"transcode"`
+}
+
+// TranscodeConfig configures audio conversion in the voice pipeline.
+type TranscodeConfig struct {
+	// Enabled converts voice notes in formats STT providers may not accept
+	// (AMR, M4A, WebM) to WAV, and voice replies to the format each
+	// channel needs.
+	Enabled    bool   `json:"enabled" yaml:"enabled"`
+	FFmpegPath string `json:"ffmpeg_path" yaml:"ffmpeg_path"` // Default: ffmpeg on PATH
+
+	// ChannelFormats sets a channel's reply format, "ogg", "mp3", or
+	// "wav" (default: ogg for WhatsApp and Telegram).
+	ChannelFormats map[string]string `json:"channel_formats,omitempty" yaml:"channel_formats,omitempty"`
 }
 
 // STTConfig configures speech-to-text.
 type STTConfig struct {
-	Provider string `json:"provider" yaml:"provider"`
-	APIKey   string `json:"api_key" yaml:"api_key"` //nolint:gosec // G117: APIKey loaded from config file
-	Model    string `json:"model" yaml:"model"`
-	Language string `json:"language" yaml:"language"`
+	Provider string            `json:"provider" yaml:"provider"`
+	APIKey   string            `json:"api_key" yaml:"api_key"` //nolint:gosec // G117: APIKey loaded from config file
+	BaseURL  string            `json:"base_url" yaml:"base_url"`
+	Model    string            `json:"model" yaml:"model"`
+	Language string            `json:"language" yaml:"language"`
+	Options  map[string]string `json:"options" yaml:"options"` // Provider-specific settings
+
+	// Streaming transcribes voice notes over the provider's streaming API
+	// (Deepgram, ElevenLabs), so long notes are transcribed incrementally.
+	Streaming bool `json:"streaming" yaml:"streaming"`
 }
 
 // TTSConfig configures text-to-speech.
 type TTSConfig struct {
-	Provider string `json:"provider" yaml:"provider"`
-	APIKey   string `json:"api_key" yaml:"api_key"` //nolint:gosec // G117: APIKey loaded from config file
-	Model    string `json:"model" yaml:"model"`
-	VoiceID  string `json:"voice_id" yaml:"voice_id"`
+	Provider string            `json:"provider" yaml:"provider"`
+	APIKey   string            `json:"api_key" yaml:"api_key"` //nolint:gosec // G117: APIKey loaded from config file
+	BaseURL  string            `json:"base_url" yaml:"base_url"`
+	Model    string            `json:"model" yaml:"model"`
+	VoiceID  string            `json:"voice_id" yaml:"voice_id"`
+	Options  map[string]string `json:"options" yaml:"options"` // Provider-specific settings, such as Azure's region
 }
 
 // ObservabilityConfig configures observability features.
@@ -117,4 +599,440 @@ type ObservabilityConfig struct {
 	Provider string `json:"provider" yaml:"provider"`
 	Endpoint string `json:"endpoint" yaml:"endpoint"`
 	APIKey   string `json:"api_key" yaml:"api_key"` //nolint:gosec // G117: APIKey loaded from config file
+
+	// Tracing exports OpenTelemetry traces of the message pipeline.
+	Tracing TracingConfig `json:"tracing" yaml:"tracing"`
+}
+
+// TracingConfig configures OpenTelemetry trace export over OTLP/HTTP.
+type TracingConfig struct {
+	Enabled     bool              `json:"enabled" yaml:"enabled"`
+	Endpoint    string            `json:"endpoint" yaml:"endpoint"`         // Collector URL; default OTEL_EXPORTER_OTLP_ENDPOINT, else http://localhost:4318
+	Headers     map[string]string `json:"headers" yaml:"headers"`           // Sent with each export, e.g. an API key
+	ServiceName string            `json:"service_name" yaml:"service_name"` // Default "omniagent"
+	SampleRatio float64           `json:"sample_ratio" yaml:"sample_ratio"` // Share of messages traced; default 1
+}
+
+// StorageConfig configures blob storage for attachments, exports, and backups.
+type StorageConfig struct {
+	Backend string   `json:"backend" yaml:"backend"` // "local" or "s3"
+	Path    string   `json:"path" yaml:"path"`       // Root directory for the local backend
+	S3      S3Config `json:"s3" yaml:"s3"`
+}
+
+// S3Config configures an S3-compatible bucket (AWS S3, MinIO, Cloudflare R2).
+type S3Config struct {
+	Endpoint        string `json:"endpoint" yaml:"endpoint"`
+	Region          string `json:"region" yaml:"region"`
+	Bucket          string `json:"bucket" yaml:"bucket"`
+	AccessKeyID     string `json:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" yaml:"secret_access_key"` //nolint:gosec // G117: Secret loaded from config file
+	Prefix          string `json:"prefix" yaml:"prefix"`
+	PathStyle       bool   `json:"path_style" yaml:"path_style"`
+}
+
+// SchedulerConfig configures scheduled tasks.
+type SchedulerConfig struct {
+	Enabled  bool                  `json:"enabled" yaml:"enabled"`
+	Timezone string                `json:"timezone" yaml:"timezone"` // IANA name; default local
+	Path     string                `json:"path" yaml:"path"`         // SQLite task database; default <storage.path>/scheduler.db
+	Jitter   time.Duration         `json:"jitter" yaml:"jitter"`     // Default random delay for recurring tasks
+	Tasks    []ScheduledTaskConfig `json:"tasks" yaml:"tasks"`
+}
+
+// ScheduledTaskConfig defines a recurring prompt or tool invocation.
+type ScheduledTaskConfig struct {
+	Name     string                 `json:"name" yaml:"name"`
+	Schedule string                 `json:"schedule" yaml:"schedule"` // Cron expression
+	Prompt   string                 `json:"prompt" yaml:"prompt"`
+	Tool     string                 `json:"tool" yaml:"tool"`
+	ToolArgs map[string]interface{} `json:"tool_args" yaml:"tool_args"`
+	Channel  string                 `json:"channel" yaml:"channel"`
+	ChatID   string                 `json:"chat_id" yaml:"chat_id"`
+	Jitter   time.Duration          `json:"jitter" yaml:"jitter"`
+}
+
+// CatchupConfig configures /catchup summaries of group chats. Group
+// messages are kept in memory for MaxAge so they can be summarized.
+type CatchupConfig struct {
+	Enabled     bool                  `json:"enabled" yaml:"enabled"`
+	Hours       int                   `json:"hours" yaml:"hours"`               // Default /catchup window; default 8
+	MaxAge      time.Duration         `json:"max_age" yaml:"max_age"`           // History kept; default 24h
+	MaxMessages int                   `json:"max_messages" yaml:"max_messages"` // Kept per chat; default 500
+	Digests     []CatchupDigestConfig `json:"digests" yaml:"digests"`
+}
+
+// CatchupDigestConfig sends a group chat's catch-up to recipients on a
+// schedule. Requires the scheduler.
+type CatchupDigestConfig struct {
+	Name       string   `json:"name" yaml:"name"`
+	Schedule   string   `json:"schedule" yaml:"schedule"` // Cron expression
+	Channel    string   `json:"channel" yaml:"channel"`
+	ChatID     string   `json:"chat_id" yaml:"chat_id"`
+	Hours      int      `json:"hours" yaml:"hours"`           // Default: catchup.hours
+	Recipients []string `json:"recipients" yaml:"recipients"` // Contact IDs; default: the owners
+}
+
+// MemoryConfig configures long-term memory: facts about each person kept
+// as embeddings, with remember and recall tools.
+type MemoryConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Path    string `json:"path" yaml:"path"` // Default: <storage.path>/memory.db
+
+	// AutoRecall adds relevant memories to the system prompt of each
+	// message.
+	AutoRecall  bool    `json:"auto_recall" yaml:"auto_recall"`
+	RecallLimit int     `json:"recall_limit" yaml:"recall_limit"` // Default 5
+	MinScore    float64 `json:"min_score" yaml:"min_score"`       // Cosine similarity; default 0.3
+
+	// GroupScope is "user" (default) for memories per group member, or
+	// "shared" for one set of memories per group chat.
+	GroupScope string `json:"group_scope" yaml:"group_scope"`
+
+	Embedding EmbeddingConfig `json:"embedding" yaml:"embedding"`
+}
+
+// KnowledgeConfig configures the knowledge base: the user's documents,
+// chunked and embedded, searched with the knowledge_search tool. Add
+// documents with "omniagent kb add".
+type KnowledgeConfig struct {
+	Enabled     bool    `json:"enabled" yaml:"enabled"`
+	Path        string  `json:"path" yaml:"path"`                 // Default: <storage.path>/kb.db
+	ChunkSize   int     `json:"chunk_size" yaml:"chunk_size"`     // Bytes; default 1500
+	SearchLimit int     `json:"search_limit" yaml:"search_limit"` // Default 5
+	MinScore    float64 `json:"min_score" yaml:"min_score"`       // Cosine similarity; default 0.25
+
+	// Embedding defaults to memory.embedding.
+	Embedding EmbeddingConfig `json:"embedding" yaml:"embedding"`
+
+	// Sources are synced into the knowledge base while the gateway runs.
+	Sources []KnowledgeSourceConfig `json:"sources" yaml:"sources"`
+}
+
+// KnowledgeSourceConfig configures a service whose documents are synced
+// into the knowledge base.
+type KnowledgeSourceConfig struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"` // notion, google_drive
+
+	// Notion
+	Token string `json:"token" yaml:"token"` //nolint:gosec // G117: Integration token loaded from config file
+
+	// Google Drive
+	CredentialsFile string `json:"credentials_file" yaml:"credentials_file"` // Service account or authorized user JSON
+	FolderID        string `json:"folder_id" yaml:"folder_id"`               // Default: every readable file
+
+	// Include and Exclude match document paths, such as "Projects/*.md"
+	// or "Archive/**".
+	Include  []string      `json:"include" yaml:"include"`
+	Exclude  []string      `json:"exclude" yaml:"exclude"`
+	Interval time.Duration `json:"interval" yaml:"interval"` // Default 1h
+}
+
+// ReactionsConfig configures emoji reactions on the agent's replies as
+// signals. Without it, thumbs reactions still rate replies when eval is
+// enabled.
+type ReactionsConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Emoji maps emoji to actions: up, down, elaborate, or none. It
+	// replaces the defaults; Channels overrides it per channel.
+	Emoji    map[string]string            `json:"emoji,omitempty" yaml:"emoji,omitempty"`
+	Channels map[string]map[string]string `json:"channels,omitempty" yaml:"channels,omitempty"`
+}
+
+// AttachmentsConfig configures how files users send are shown to the
+// model: the text of documents, descriptions of images, and transcripts of
+// audio.
+type AttachmentsConfig struct {
+	Read         bool `json:"read" yaml:"read"`                     // Default true
+	MaxTextBytes int  `json:"max_text_bytes" yaml:"max_text_bytes"` // Per file; default 20000
+
+	Vision VisionConfig `json:"vision" yaml:"vision"`
+
+	// Policy limits the files every channel accepts; Channels overrides it
+	// per channel, with unset fields falling back to Policy.
+	Policy   AttachmentPolicyConfig            `json:"policy" yaml:"policy"`
+	Channels map[string]AttachmentPolicyConfig `json:"channels" yaml:"channels"`
+}
+
+// AttachmentPolicyConfig limits the size and type of files users send.
+// Violations are checked before transcription or vision.
+type AttachmentPolicyConfig struct {
+	MaxBytes    int64    `json:"max_bytes" yaml:"max_bytes"`       // Per file; 0 is unlimited
+	MimeTypes   []string `json:"mime_types" yaml:"mime_types"`     // Such as "image/*"; empty accepts all
+	OnViolation string   `json:"on_violation" yaml:"on_violation"` // reject (default) or ignore
+	Reply       string   `json:"reply" yaml:"reply"`               // Rejection reply; may contain {file} and {reason}
+}
+
+// VisionConfig configures the vision model that describes images, through
+// an OpenAI-compatible chat completions endpoint. Without a model, images
+// are described by agent.model for OpenAI and not read otherwise.
+type VisionConfig struct {
+	BaseURL string `json:"base_url" yaml:"base_url"` // Default: https://api.openai.com/v1
+	APIKey  string `json:"api_key" yaml:"api_key"`   //nolint:gosec // G117: API key loaded from config file; default: agent.api_key for OpenAI
+	Model   string `json:"model" yaml:"model"`
+}
+
+// TemplatesConfig configures canned responses, used with the use_template
+// tool and /send-template. Manage them with "omniagent templates".
+type TemplatesConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Path    string `json:"path" yaml:"path"` // Default: <storage.path>/templates.yaml
+}
+
+// FlagsConfig configures feature flags, which gate experimental features
+// such as "streaming", "memory", or a single tool ("tool.browser"). Flags
+// not listed are on.
+type FlagsConfig struct {
+	Features map[string]bool `json:"features" yaml:"features"`
+	Path     string          `json:"path" yaml:"path"` // Runtime overrides; default: <storage.path>/flags.json
+}
+
+// EncryptionConfig encrypts local credential state, such as the WhatsApp
+// session database, at rest. The key comes from Passphrase or, with
+// Keychain, from a random key kept in the OS keychain.
+type EncryptionConfig struct {
+	Enabled    bool          `json:"enabled" yaml:"enabled"`
+	Passphrase string        `json:"passphrase" yaml:"passphrase"` //nolint:gosec // G117: Passphrase loaded from config file
+	Keychain   bool          `json:"keychain" yaml:"keychain"`
+	Checkpoint time.Duration `json:"checkpoint" yaml:"checkpoint"` // How often changes are sealed (default: 1m)
+}
+
+// PrivacyConfig configures local-only processing.
+type PrivacyConfig struct {
+	// LocalOnly refuses to start unless every model, embeddings, and tool
+	// service is on this machine or a private network, and limits http_fetch
+	// to private addresses.
+	LocalOnly bool `json:"local_only" yaml:"local_only"`
+
+	// AllowedChannels are cloud messaging channels ("signal") accepted in
+	// local-only mode, whose service carries the messages themselves.
+	AllowedChannels []string `json:"allowed_channels" yaml:"allowed_channels"`
+}
+
+// HooksConfig configures message hooks: WASM modules in
+// <dir>/<point>/*.wasm that rewrite or drop messages at the pre_prompt,
+// post_response, and pre_send points.
+type HooksConfig struct {
+	Enabled       bool          `json:"enabled" yaml:"enabled"`
+	Dir           string        `json:"dir" yaml:"dir"`                         // Default: <storage.path>/hooks
+	Timeout       time.Duration `json:"timeout" yaml:"timeout"`                 // Per hook run; default 5s
+	MemoryLimitMB int           `json:"memory_limit_mb" yaml:"memory_limit_mb"` // Per hook run; default 32
+	FuelLimit     uint64        `json:"fuel_limit" yaml:"fuel_limit"`           // Function calls per hook run; 0 = unlimited
+	Skills        bool          `json:"skills" yaml:"skills"`                   // Also run hooks shipped in skills' hooks/ directories
+}
+
+// EmbeddingConfig configures an OpenAI-compatible embeddings endpoint.
+type EmbeddingConfig struct {
+	BaseURL string `json:"base_url" yaml:"base_url"` // Default: https://api.openai.com/v1
+	APIKey  string `json:"api_key" yaml:"api_key"`   //nolint:gosec // G117: API key loaded from config file; default: agent.api_key for OpenAI
+	Model   string `json:"model" yaml:"model"`       // Default: text-embedding-3-small
+}
+
+// InstanceConfig identifies this deployment among several sharing a base
+// config, and where its overrides are kept.
+type InstanceConfig struct {
+	Name string `json:"name" yaml:"name"` // Default: the short host name
+
+	// Overlays is a directory or http(s) URL holding per-instance
+	// overrides as <name>.yaml, .yml, or .json.
+	Overlays string `json:"overlays" yaml:"overlays"`
+	Token    string `json:"token" yaml:"token"` //nolint:gosec // G117: Bearer token for an overlay URL, loaded from config file
+
+	// Applied is the overlay file or URL loaded, if any.
+	Applied string `json:"-" yaml:"-"`
+}
+
+// JobsConfig configures background jobs for long agent tasks, started by
+// the background_task tool or /tasks start.
+type JobsConfig struct {
+	Enabled           bool          `json:"enabled" yaml:"enabled"`
+	Path              string        `json:"path" yaml:"path"`                               // Checkpoint file; default <storage.path>/jobs.json
+	MaxConcurrent     int           `json:"max_concurrent" yaml:"max_concurrent"`           // Default 2
+	MaxRuntime        time.Duration `json:"max_runtime" yaml:"max_runtime"`                 // Per attempt; default 1h
+	MaxToolIterations int           `json:"max_tool_iterations" yaml:"max_tool_iterations"` // Default 25
+	ProgressInterval  time.Duration `json:"progress_interval" yaml:"progress_interval"`     // Default 2m
+}
+
+// ProposalsConfig lets the agent change its own config file and skills
+// with the propose_change tool. Each change is validated and applied only
+// when an approver accepts its diff.
+type ProposalsConfig struct {
+	Enabled   bool     `json:"enabled" yaml:"enabled"`
+	Path      string   `json:"path" yaml:"path"`             // Change log and backups; default <storage.path>/proposals
+	Targets   []string `json:"targets" yaml:"targets"`       // "config", "skill"; default both
+	SkillsDir string   `json:"skills_dir" yaml:"skills_dir"` // Default: first of skills.paths, else the user skills directory
+}
+
+// TaskConfig defines a named agent task triggered by a webhook at
+// POST /webhooks/tasks/<name> or on a schedule.
+type TaskConfig struct {
+	Name     string   `json:"name" yaml:"name"`
+	Prompt   string   `json:"prompt" yaml:"prompt"` // Go template; the trigger payload is dot
+	Channel  string   `json:"channel" yaml:"channel"`
+	ChatID   string   `json:"chat_id" yaml:"chat_id"`
+	Tools    []string `json:"tools" yaml:"tools"`       // Allowed tools; empty allows none, "*" all
+	Secret   string   `json:"secret" yaml:"secret"`     //nolint:gosec // G117: Secret loaded from config file
+	Schedule string   `json:"schedule" yaml:"schedule"` // Optional cron expression; requires the scheduler
+}
+
+// EvalConfig configures trace and feedback capture.
+type EvalConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Path    string `json:"path" yaml:"path"` // Default: <storage.path>/eval
+
+	// Pricing maps model names to USD per million tokens for cost reports.
+	Pricing map[string]ModelPricing `json:"pricing,omitempty" yaml:"pricing,omitempty"`
+
+	// Export writes usage records to CSV or Parquet files on a schedule.
+	Export EvalExportConfig `json:"export" yaml:"export"`
+}
+
+// EvalExportConfig configures scheduled exports of token usage, latency,
+// tool calls, and cost for analysis in spreadsheets or DuckDB.
+type EvalExportConfig struct {
+	Enabled  bool          `json:"enabled" yaml:"enabled"`
+	Format   string        `json:"format" yaml:"format"`     // csv or parquet (default: csv)
+	Interval time.Duration `json:"interval" yaml:"interval"` // Default: 24h
+	Path     string        `json:"path" yaml:"path"`         // Default: <eval.path>/exports
+}
+
+// TranscriptsConfig configures conversation transcripts for export.
+type TranscriptsConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Path    string `json:"path" yaml:"path"` // Default: <storage.path>/transcripts
+}
+
+// AuditConfig configures the append-only log of tool executions.
+type AuditConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Path    string `json:"path" yaml:"path"` // Default: <storage.path>/audit.jsonl
+}
+
+// UsageConfig configures the ledger of model token usage and cost. Costs
+// are estimated with eval.pricing.
+type UsageConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Path    string `json:"path" yaml:"path"` // Default: <storage.path>/usage.jsonl
+}
+
+// ReloadConfig configures reloading the config file while the gateway
+// runs. SIGHUP always triggers a reload; Watch also reloads when the file
+// changes.
+type ReloadConfig struct {
+	Watch    bool          `json:"watch" yaml:"watch"`
+	Interval time.Duration `json:"interval" yaml:"interval"` // How often the file is checked (default: 5s)
+}
+
+// ModelPricing is a model's cost in USD per million tokens.
+type ModelPricing struct {
+	Prompt     float64 `json:"prompt" yaml:"prompt"`
+	Completion float64 `json:"completion" yaml:"completion"`
+}
+
+// RateLimitConfig configures per-user and per-channel rate limits.
+type RateLimitConfig struct {
+	Enabled  bool                         `json:"enabled" yaml:"enabled"`
+	User     RateLimits                   `json:"user" yaml:"user"`
+	Channel  RateLimits                   `json:"channel" yaml:"channel"`
+	Channels map[string]ChannelRateLimits `json:"channels" yaml:"channels"`
+
+	// Replies sent when a limit is hit; may use {retry_after}, {limit}, {scope}.
+	MessageLimitReply string `json:"message_limit_reply" yaml:"message_limit_reply"`
+	TokenLimitReply   string `json:"token_limit_reply" yaml:"token_limit_reply"`
+}
+
+// RateLimits are message and token limits. Zero disables a limit.
+type RateLimits struct {
+	MessagesPerMinute int `json:"messages_per_minute" yaml:"messages_per_minute"`
+	TokensPerHour     int `json:"tokens_per_hour" yaml:"tokens_per_hour"`
+}
+
+// ChannelRateLimits overrides rate limits for one channel.
+type ChannelRateLimits struct {
+	User    *RateLimits `json:"user,omitempty" yaml:"user,omitempty"`
+	Channel *RateLimits `json:"channel,omitempty" yaml:"channel,omitempty"`
+}
+
+// BudgetConfig configures daily and monthly usage caps.
+type BudgetConfig struct {
+	Enabled  bool                           `json:"enabled" yaml:"enabled"`
+	Daily    BudgetLimits                   `json:"daily" yaml:"daily"`
+	Monthly  BudgetLimits                   `json:"monthly" yaml:"monthly"`
+	Channels map[string]ChannelBudgetConfig `json:"channels" yaml:"channels"`
+
+	// WarnAt is the share of a cap at which the model is downgraded and
+	// owners are notified.
+	WarnAt         float64 `json:"warn_at" yaml:"warn_at"`
+	DowngradeModel string  `json:"downgrade_model" yaml:"downgrade_model"`
+
+	// Owners are notified and keep being answered when a budget is used
+	// up. Default: the top-level owners.
+	Owners []string `json:"owners" yaml:"owners"`
+	Reply  string   `json:"reply" yaml:"reply"` // Sent to others while paused
+	Path   string   `json:"path" yaml:"path"`   // Default: <storage.path>/budget.json
+}
+
+// ApprovalsConfig pauses calls to sensitive tools until an approver
+// confirms them.
+type ApprovalsConfig struct {
+	// RequiresApproval lists tool names that need approval; "*" means all.
+	RequiresApproval []string `json:"requires_approval" yaml:"requires_approval"`
+
+	// Approvers are contact IDs ("telegram:12345") that are sent requests
+	// and may answer /approve or /deny. Default: the top-level owners.
+	Approvers []string      `json:"approvers" yaml:"approvers"`
+	Timeout   time.Duration `json:"timeout" yaml:"timeout"`       // Denied when undecided; default 10m
+	AuditPath string        `json:"audit_path" yaml:"audit_path"` // Default: <storage.path>/approvals.jsonl
+}
+
+// BudgetLimits caps tokens and USD cost (from eval.pricing). Zero disables a cap.
+type BudgetLimits struct {
+	Tokens int     `json:"tokens" yaml:"tokens"`
+	Cost   float64 `json:"cost" yaml:"cost"`
+}
+
+// ChannelBudgetConfig caps usage on one channel.
+type ChannelBudgetConfig struct {
+	Daily   BudgetLimits `json:"daily" yaml:"daily"`
+	Monthly BudgetLimits `json:"monthly" yaml:"monthly"`
+}
+
+// HealthConfig configures background provider health probes.
+type HealthConfig struct {
+	Enabled          bool          `json:"enabled" yaml:"enabled"`
+	Interval         time.Duration `json:"interval" yaml:"interval"`
+	Timeout          time.Duration `json:"timeout" yaml:"timeout"`
+	FailureThreshold int           `json:"failure_threshold" yaml:"failure_threshold"`
+	Probes           []string      `json:"probes" yaml:"probes"` // llm, stt, tts, search; empty probes all configured
+}
+
+// TimeConfig configures timezone resolution for prompts and the time tool.
+type TimeConfig struct {
+	Timezone string            `json:"timezone" yaml:"timezone"`                     // IANA name; default: server local time
+	Contacts map[string]string `json:"contacts,omitempty" yaml:"contacts,omitempty"` // Contact or session ID -> IANA name
+	Path     string            `json:"path" yaml:"path"`                             // Learned timezones (default: <storage.path>/timezones.json)
+}
+
+// ContactsConfig configures the contact registry.
+type ContactsConfig struct {
+	Path string `json:"path" yaml:"path"` // Default: <storage.path>/contacts.json
+}
+
+// OnboardingConfig configures the greeting sent to new contacts.
+type OnboardingConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Message string `json:"message" yaml:"message"` // May contain {name} and {capabilities}
+}
+
+// MCPServerConfig configures a Model Context Protocol server whose tools
+// are made available to the agent.
+type MCPServerConfig struct {
+	Name      string            `json:"name" yaml:"name"`           // Prefix for tool names
+	Transport string            `json:"transport" yaml:"transport"` // stdio, sse, or http
+	Command   string            `json:"command,omitempty" yaml:"command,omitempty"`
+	Args      []string          `json:"args,omitempty" yaml:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	URL       string            `json:"url,omitempty" yaml:"url,omitempty"`
 }
@@ -1,12 +1,22 @@
 package config
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	// Keep the developer's keychain out of the tests
+	keychainLookup = func(string) string { return "" }
+	os.Exit(m.Run())
+}
+
 func TestDefault(t *testing.T) {
 	cfg := Default()
 
@@ -132,6 +142,30 @@ func TestLoadJSON(t *testing.T) {
 	}
 }
 
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		ext     string
+		wantErr bool
+	}{
+		{"valid yaml", "gateway:\n  address: \"0.0.0.0:9000\"\nagent:\n  model: gpt-4\n", ".yaml", false},
+		{"empty yaml", "", ".yaml", false},
+		{"unknown yaml field", "agent:\n  modle: gpt-4\n", ".yaml", true},
+		{"bad yaml", "agent: [", ".yml", true},
+		{"valid json", `{"agent": {"provider": "gemini"}}`, ".json", false},
+		{"unknown json field", `{"agnet": {}}`, ".json", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check([]byte(tt.data), tt.ext)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestLoadEnv(t *testing.T) {
 	// Set env vars
 	os.Setenv("OMNIAGENT_GATEWAY_ADDRESS", "192.168.1.1:5000")
@@ -168,3 +202,311 @@ func TestLoadNonexistent(t *testing.T) {
 		t.Error("Expected error for nonexistent file")
 	}
 }
+
+func TestLoadChannelOverrides(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+
+	content := `
+channels:
+  telegram:
+    enabled: true
+    model: gpt-4o-mini
+    system_prompt: "Keep replies short."
+    temperature: 0.2
+  discord:
+    enabled: true
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tg := cfg.Channels.Telegram
+	if tg.Model != "gpt-4o-mini" {
+		t.Errorf("Telegram.Model = %s, want gpt-4o-mini", tg.Model)
+	}
+	if tg.SystemPrompt != "Keep replies short." {
+		t.Errorf("Telegram.SystemPrompt = %q", tg.SystemPrompt)
+	}
+	if tg.Temperature == nil || *tg.Temperature != 0.2 {
+		t.Errorf("Telegram.Temperature = %v, want 0.2", tg.Temperature)
+	}
+	if cfg.Channels.Discord.Temperature != nil {
+		t.Error("Discord.Temperature should be unset")
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Chdir(t.TempDir())
+	userDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", userDir)
+	t.Setenv("AppData", userDir)
+	t.Setenv("HOME", userDir)
+
+	if got := Find(); got != "" {
+		t.Errorf("Find() = %q, want none", got)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir() error = %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	userFile := filepath.Join(dir, "omniagent.json")
+	if err := os.WriteFile(userFile, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Find(); got != userFile {
+		t.Errorf("Find() = %q, want %q", got, userFile)
+	}
+
+	// The working directory takes precedence
+	if err := os.WriteFile("omniagent.yaml", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Find(); got != "omniagent.yaml" {
+		t.Errorf("Find() = %q, want omniagent.yaml", got)
+	}
+}
+
+func TestLoadInstanceOverlay(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "omniagent.yaml")
+	overlays := filepath.Join(dir, "instances")
+	if err := os.MkdirAll(overlays, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(base, `
+agent:
+  model: gpt-4o
+channels:
+  telegram:
+    enabled: true
+  discord:
+    enabled: true
+instance:
+  overlays: `+overlays+`
+`)
+	writeFile(filepath.Join(overlays, "laptop.yaml"), `
+channels:
+  discord:
+    enabled: false
+tools:
+  shell:
+    enabled: true
+instance:
+  overlays: /elsewhere
+`)
+
+	t.Setenv("OMNIAGENT_INSTANCE", "laptop")
+	cfg, err := Load(base)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Agent.Model != "gpt-4o" || !cfg.Channels.Telegram.Enabled {
+		t.Error("base settings not kept")
+	}
+	if cfg.Channels.Discord.Enabled || !cfg.Tools.Shell.Enabled {
+		t.Error("overlay settings not applied")
+	}
+	if cfg.Instance.Overlays != overlays || cfg.Instance.Applied != filepath.Join(overlays, "laptop.yaml") {
+		t.Errorf("instance = %+v", cfg.Instance)
+	}
+
+	// Instances without an overlay use the base
+	t.Setenv("OMNIAGENT_INSTANCE", "vps")
+	if cfg, err = Load(base); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.Channels.Discord.Enabled || cfg.Instance.Applied != "" {
+		t.Error("base config changed without an overlay")
+	}
+}
+
+func TestLoadInstanceOverlayURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/fleet/home.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"agent": {"model": "llama3"}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "omniagent.yaml")
+	content := "instance:\n  name: home\n  overlays: " + server.URL + "/fleet/\n  token: secret\n"
+	if err := os.WriteFile(base, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(base)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Agent.Model != "llama3" {
+		t.Errorf("Model = %q, want llama3 from the overlay", cfg.Agent.Model)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "omniagent.yaml")
+	if err := os.WriteFile(path, []byte("agent:\n  temperature: 0.5\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go Watch(ctx, path, 5*time.Millisecond, func() { changed <- struct{}{} })
+
+	select {
+	case <-changed:
+		t.Fatal("reload before the file changed")
+	case <-time.After(30 * time.Millisecond):
+	}
+	if err := os.WriteFile(path, []byte("agent:\n  temperature: 0.25\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("no reload after the file changed")
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "telegram_token")
+	if err := os.WriteFile(secretFile, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vt" || r.URL.Path != "/v1/secret/data/omniagent" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"discord": "vault-secret"}, "metadata": {"version": 3}}}`))
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "vt")
+	t.Setenv("OMNIAGENT_TEST_KEY", "env-secret")
+
+	run := runCommand
+	defer func() { runCommand = run }()
+	runCommand = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		switch name {
+		case "op":
+			return []byte("op-secret"), nil
+		case "aws":
+			return []byte(`{"admin": "aws-secret"}` + "\n"), nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	path := filepath.Join(dir, "omniagent.yaml")
+	data := `
+agent:
+  api_key: ${OMNIAGENT_TEST_KEY}
+  system_prompt: "Use ${HOME:-/tmp} and ${unknown:ref} as written; costs $5."
+channels:
+  telegram:
+    token: ${file:` + secretFile + `}
+  discord:
+    token: ${vault:secret/data/omniagent#discord}
+gateway:
+  http:
+    token: ${op://Private/omniagent/token}
+  admin_token: ${aws-sm:omniagent#admin}
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for name, got := range map[string]string{
+		"env":       cfg.Agent.APIKey,
+		"file":      cfg.Channels.Telegram.Token,
+		"vault":     cfg.Channels.Discord.Token,
+		"1password": cfg.Gateway.HTTP.Token,
+		"aws":       cfg.Gateway.AdminToken,
+	} {
+		if want := strings.Replace(name, "1password", "op", 1) + "-secret"; got != want {
+			t.Errorf("%s secret = %q, want %q", name, got, want)
+		}
+	}
+	if want := "Use ${HOME:-/tmp} and ${unknown:ref} as written; costs $5."; cfg.Agent.SystemPrompt != want {
+		t.Errorf("system prompt = %q, want it unchanged", cfg.Agent.SystemPrompt)
+	}
+
+	if err := os.WriteFile(path, []byte("agent:\n  api_key: ${file:"+filepath.Join(dir, "missing")+"}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load() with a missing secret file succeeded")
+	}
+}
+
+func TestKeychainKeys(t *testing.T) {
+	lookup := keychainLookup
+	defer func() { keychainLookup = lookup }()
+	keychainLookup = func(provider string) string {
+		return map[string]string{"openai": "sk-keychain", "deepgram": "dg-keychain"}[provider]
+	}
+	t.Setenv("OMNIAGENT_AGENT_API_KEY", "sk-env")
+
+	path := filepath.Join(t.TempDir(), "omniagent.yaml")
+	data := `
+agent:
+  provider: openai
+  fallbacks:
+    - provider: anthropic
+      model: claude-sonnet-4-20250514
+      api_key: sk-ant-file
+voice:
+  stt:
+    provider: deepgram
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Agent.APIKey != "sk-keychain" {
+		t.Errorf("agent key = %q, want the keychain's over the environment", cfg.Agent.APIKey)
+	}
+	if cfg.Agent.Fallbacks[0].APIKey != "sk-ant-file" {
+		t.Errorf("fallback key = %q, want the file's without a keychain entry", cfg.Agent.Fallbacks[0].APIKey)
+	}
+	if cfg.Voice.STT.APIKey != "dg-keychain" {
+		t.Errorf("stt key = %q", cfg.Voice.STT.APIKey)
+	}
+	if got := strings.Join(cfg.Providers(), ","); got != "openai,anthropic,deepgram" {
+		t.Errorf("Providers() = %s", got)
+	}
+
+	t.Setenv("OMNIAGENT_KEYCHAIN", "off")
+	if cfg, _ = Load(path); cfg.Agent.APIKey != "sk-env" {
+		t.Errorf("agent key with keychain off = %q, want sk-env", cfg.Agent.APIKey)
+	}
+}
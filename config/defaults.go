@@ -16,6 +16,7 @@ func Default() Config {
 			Model:        "claude-sonnet-4-20250514",
 			Temperature:  0.7,
 			MaxTokens:    4096,
+			ToolCallMode: "auto",
 			SystemPrompt: "You are OmniAgent, a helpful AI assistant. You represent the user across communication channels, responding on their behalf with care and precision.\n\nYou have access to the following tools:\n- web_search: Search the web for current information, news, weather, or any real-time data.\n\nIMPORTANT: When users ask about current events, news, weather, prices, or anything that requires up-to-date information, you MUST use the web_search tool. Do not say you cannot search - use your tools.",
 		},
 		Channels: ChannelsConfig{
@@ -29,6 +30,14 @@ func Default() Config {
 				Enabled: false,
 				DBPath:  "whatsapp.db",
 			},
+			SMS: SMSConfig{
+				Enabled:   false,
+				MaxLength: 1600,
+			},
+			Signal: SignalConfig{
+				Enabled: false,
+				URL:     "http://127.0.0.1:8080",
+			},
 		},
 		Tools: ToolsConfig{
 			Browser: BrowserToolConfig{
@@ -38,6 +47,36 @@ func Default() Config {
 			Shell: ShellToolConfig{
 				Enabled: false, // Disabled by default for security
 			},
+			File: FileToolConfig{
+				Enabled:   false,
+				MaxFileKB: 256,
+			},
+			HTTPFetch: HTTPFetchToolConfig{
+				Enabled:       false,
+				MaxResponseKB: 1024,
+				Timeout:       30 * time.Second,
+			},
+			Scratchpad: ScratchpadToolConfig{
+				Enabled: true,
+			},
+			WebDAV: WebDAVToolConfig{
+				MaxFileMB: 20,
+			},
+			Sandbox: SandboxConfig{
+				Mode: "none",
+				Docker: DockerSandboxConfig{
+					Image:       "alpine:latest",
+					NetworkMode: "none",
+					MemoryMB:    256,
+					Timeout:     60 * time.Second,
+				},
+				WASM: WASMSandboxConfig{
+					MemoryLimitMB: 64,
+				},
+				Workspaces: WorkspacesConfig{
+					MaxIdle: 7 * 24 * time.Hour,
+				},
+			},
 		},
 		Skills: SkillsConfig{
 			Enabled:     true,
@@ -59,5 +98,71 @@ func Default() Config {
 		Observability: ObservabilityConfig{
 			Enabled: false,
 		},
+		Storage: StorageConfig{
+			Backend: "local",
+			Path:    "data",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           false,
+			User:              RateLimits{MessagesPerMinute: 10, TokensPerHour: 100000},
+			MessageLimitReply: "You're sending messages too quickly. Please try again in {retry_after}.",
+			TokenLimitReply:   "You've reached the usage limit for now. Please try again in {retry_after}.",
+		},
+		Onboarding: OnboardingConfig{
+			Enabled: false, // Existing contacts would all be greeted on upgrade
+			Message: DefaultOnboardingMessage,
+		},
+		Health: HealthConfig{
+			Enabled:          false,
+			Interval:         5 * time.Minute,
+			Timeout:          15 * time.Second,
+			FailureThreshold: 2,
+		},
+		Transcripts: TranscriptsConfig{
+			Enabled: false, // Transcripts keep full conversations; opt in
+		},
+		Budget: BudgetConfig{
+			WarnAt: 0.8,
+			Reply:  "I'm taking a short break and will be back soon. Your message has not been answered.",
+		},
+		Approvals: ApprovalsConfig{
+			Timeout: 10 * time.Minute,
+		},
+		Jobs: JobsConfig{
+			MaxConcurrent:     2,
+			MaxRuntime:        time.Hour,
+			MaxToolIterations: 25,
+			ProgressInterval:  2 * time.Minute,
+		},
+		Memory: MemoryConfig{
+			AutoRecall:  true,
+			RecallLimit: 5,
+			MinScore:    0.3,
+		},
+		Attachments: AttachmentsConfig{
+			Read:         true,
+			MaxTextBytes: 20000,
+		},
+		Hooks: HooksConfig{
+			Timeout:       5 * time.Second,
+			MemoryLimitMB: 32,
+		},
+		Knowledge: KnowledgeConfig{
+			ChunkSize:   1500,
+			SearchLimit: 5,
+			MinScore:    0.25,
+		},
+		Catchup: CatchupConfig{
+			Hours:       8,
+			MaxAge:      24 * time.Hour,
+			MaxMessages: 500,
+		},
 	}
 }
+
+// DefaultOnboardingMessage greets new contacts.
+const DefaultOnboardingMessage = `Hi {name}! I'm an AI assistant. Here's what I can do:
+
+{capabilities}
+
+Privacy: your messages are sent to an AI model provider to generate replies, and conversation history is kept on this server. Send /capabilities any time to see this list again.`
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+
+	"github.com/plexusone/omniagent/keychain"
+)
+
+// keychainLookup returns a provider's API key from the OS keychain, or ""
+// if none is stored. Tests replace it.
+var keychainLookup = func(provider string) string {
+	key, _ := keychain.Get(provider)
+	return key
+}
+
+// applyKeychain sets the API key of each configured model and voice
+// provider that has one stored with omniagent auth set, in preference to
+// the file and environment. OMNIAGENT_KEYCHAIN=off disables lookups.
+func applyKeychain(cfg *Config) {
+	if os.Getenv("OMNIAGENT_KEYCHAIN") == "off" {
+		return
+	}
+	keys := make(map[string]string)
+	apply := func(provider string, apiKey *string) {
+		if provider == "" {
+			return
+		}
+		key, ok := keys[provider]
+		if !ok {
+			key = keychainLookup(provider)
+			keys[provider] = key
+		}
+		if key != "" {
+			*apiKey = key
+		}
+	}
+
+	apply(cfg.Agent.Provider, &cfg.Agent.APIKey)
+	for i := range cfg.Agent.Fallbacks {
+		fb := &cfg.Agent.Fallbacks[i]
+		if fb.Provider != "" && fb.Provider != cfg.Agent.Provider {
+			apply(fb.Provider, &fb.APIKey)
+		}
+	}
+	apply(cfg.Voice.STT.Provider, &cfg.Voice.STT.APIKey)
+	apply(cfg.Voice.TTS.Provider, &cfg.Voice.TTS.APIKey)
+	for i := range cfg.Voice.STTFallbacks {
+		apply(cfg.Voice.STTFallbacks[i].Provider, &cfg.Voice.STTFallbacks[i].APIKey)
+	}
+	for i := range cfg.Voice.TTSFallbacks {
+		apply(cfg.Voice.TTSFallbacks[i].Provider, &cfg.Voice.TTSFallbacks[i].APIKey)
+	}
+}
+
+// Providers returns the model and voice providers the config uses, which
+// may have API keys in the keychain.
+func (c *Config) Providers() []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	add(c.Agent.Provider)
+	for _, fb := range c.Agent.Fallbacks {
+		add(fb.Provider)
+	}
+	add(c.Voice.STT.Provider)
+	add(c.Voice.TTS.Provider)
+	for _, f := range c.Voice.STTFallbacks {
+		add(f.Provider)
+	}
+	for _, f := range c.Voice.TTSFallbacks {
+		add(f.Provider)
+	}
+	return names
+}
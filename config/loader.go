@@ -1,8 +1,11 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,8 +13,11 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// Load reads configuration from a file and environment variables.
-// Environment variables override file values.
+// Load reads configuration from a file, the instance's overlay, and
+// environment variables. The overlay overrides the file, and environment
+// variables override both, and API keys in the OS keychain override all
+// three. Secret references such as ${file:...} and ${vault:...} are then
+// resolved.
 func Load(path string) (*Config, error) {
 	cfg := Default()
 
@@ -21,7 +27,22 @@ func Load(path string) (*Config, error) {
 		}
 	}
 
+	if v := os.Getenv("OMNIAGENT_INSTANCE"); v != "" {
+		cfg.Instance.Name = v
+	}
+	if v := os.Getenv("OMNIAGENT_INSTANCE_OVERLAYS"); v != "" {
+		cfg.Instance.Overlays = v
+	}
+	if err := applyOverlay(&cfg); err != nil {
+		return nil, fmt.Errorf("load instance overlay: %w", err)
+	}
+
 	loadEnv(&cfg)
+	applyKeychain(&cfg)
+
+	if err := resolveSecrets(&cfg); err != nil {
+		return nil, fmt.Errorf("resolve secrets: %w", err)
+	}
 
 	return &cfg, nil
 }
@@ -32,9 +53,12 @@ func loadFile(path string, cfg *Config) error {
 	if err != nil {
 		return err
 	}
+	return unmarshal(data, filepath.Ext(path), cfg)
+}
 
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
+// unmarshal decodes YAML or JSON by file extension onto cfg.
+func unmarshal(data []byte, ext string, cfg *Config) error {
+	switch strings.ToLower(ext) {
 	case ".yaml", ".yml":
 		return yaml.Unmarshal(data, cfg)
 	case ".json":
@@ -48,12 +72,60 @@ func loadFile(path string, cfg *Config) error {
 	}
 }
 
+// Check decodes YAML or JSON config data strictly, reporting syntax errors
+// and fields the config does not have, such as misspelled keys, that Load
+// would silently ignore.
+func Check(data []byte, ext string) error {
+	var cfg Config
+	switch strings.ToLower(ext) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(&cfg)
+	default:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	}
+}
+
 // loadEnv loads configuration from environment variables.
 func loadEnv(cfg *Config) {
 	// Gateway
 	if v := os.Getenv("OMNIAGENT_GATEWAY_ADDRESS"); v != "" {
 		cfg.Gateway.Address = v
 	}
+	if v := os.Getenv("OMNIAGENT_GATEWAY_TLS_CERT_FILE"); v != "" {
+		cfg.Gateway.TLS.CertFile = v
+	}
+	if v := os.Getenv("OMNIAGENT_GATEWAY_TLS_KEY_FILE"); v != "" {
+		cfg.Gateway.TLS.KeyFile = v
+	}
+	if v := os.Getenv("OMNIAGENT_GATEWAY_HTTP_TOKEN"); v != "" {
+		cfg.Gateway.HTTP.Token = v
+	}
+	if v := os.Getenv("OMNIAGENT_GATEWAY_CALLBACK_SECRET"); v != "" {
+		cfg.Gateway.HTTP.CallbackSecret = v
+	}
+	if v := os.Getenv("OMNIAGENT_GATEWAY_ADMIN_TOKEN"); v != "" {
+		cfg.Gateway.AdminToken = v
+	}
+	if v := os.Getenv("OMNIAGENT_GATEWAY_TOKEN"); v != "" {
+		cfg.Gateway.Token = v
+	}
+
+	// Worker
+	if v := os.Getenv("OMNIAGENT_WORKER_TOKEN"); v != "" {
+		cfg.Worker.Token = v
+	}
+
+	// Encryption
+	if v := os.Getenv("OMNIAGENT_ENCRYPTION_PASSPHRASE"); v != "" {
+		cfg.Encryption.Passphrase = v
+	}
 
 	// Agent
 	if v := os.Getenv("OMNIAGENT_AGENT_PROVIDER"); v != "" {
@@ -103,6 +175,31 @@ func loadEnv(cfg *Config) {
 		cfg.Channels.WhatsApp.DBPath = v
 	}
 
+	// SMS (Twilio)
+	if os.Getenv("SMS_ENABLED") == "true" {
+		cfg.Channels.SMS.Enabled = true
+	}
+	if v := os.Getenv("TWILIO_ACCOUNT_SID"); v != "" {
+		cfg.Channels.SMS.AccountSID = v
+	}
+	if v := os.Getenv("TWILIO_AUTH_TOKEN"); v != "" {
+		cfg.Channels.SMS.AuthToken = v
+	}
+	if v := os.Getenv("TWILIO_FROM_NUMBER"); v != "" {
+		cfg.Channels.SMS.From = v
+	}
+
+	// Signal
+	if os.Getenv("SIGNAL_ENABLED") == "true" {
+		cfg.Channels.Signal.Enabled = true
+	}
+	if v := os.Getenv("SIGNAL_CLI_URL"); v != "" {
+		cfg.Channels.Signal.URL = v
+	}
+	if v := os.Getenv("SIGNAL_ACCOUNT"); v != "" {
+		cfg.Channels.Signal.Account = v
+	}
+
 	// Voice
 	if os.Getenv("OMNIAGENT_VOICE_ENABLED") == "true" {
 		cfg.Voice.Enabled = true
@@ -143,6 +240,31 @@ func loadEnv(cfg *Config) {
 	if v := os.Getenv("OMNIAGENT_OBSERVABILITY_API_KEY"); v != "" {
 		cfg.Observability.APIKey = v
 	}
+
+	// Storage
+	if v := os.Getenv("OMNIAGENT_STORAGE_BACKEND"); v != "" {
+		cfg.Storage.Backend = v
+	}
+	if v := os.Getenv("OMNIAGENT_STORAGE_PATH"); v != "" {
+		cfg.Storage.Path = v
+	}
+	if v := os.Getenv("OMNIAGENT_S3_ENDPOINT"); v != "" {
+		cfg.Storage.S3.Endpoint = v
+	}
+	if v := os.Getenv("OMNIAGENT_S3_BUCKET"); v != "" {
+		cfg.Storage.S3.Bucket = v
+	}
+	// S3 credentials - check specific env vars first, then fall back to AWS_*
+	if v := os.Getenv("OMNIAGENT_S3_ACCESS_KEY_ID"); v != "" {
+		cfg.Storage.S3.AccessKeyID = v
+	} else if v := os.Getenv("AWS_ACCESS_KEY_ID"); v != "" && cfg.Storage.S3.AccessKeyID == "" {
+		cfg.Storage.S3.AccessKeyID = v
+	}
+	if v := os.Getenv("OMNIAGENT_S3_SECRET_ACCESS_KEY"); v != "" {
+		cfg.Storage.S3.SecretAccessKey = v
+	} else if v := os.Getenv("AWS_SECRET_ACCESS_KEY"); v != "" && cfg.Storage.S3.SecretAccessKey == "" {
+		cfg.Storage.S3.SecretAccessKey = v
+	}
 }
 
 // ExpandEnvVars expands environment variables in string values.
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Overlay fetch limits.
+const (
+	overlayTimeout  = 10 * time.Second
+	maxOverlayBytes = 1 << 20
+)
+
+// overlayExts are the overlay file extensions tried, in order.
+var overlayExts = []string{".yaml", ".yml", ".json"}
+
+// errNoOverlay is returned when the source has no overlay for an instance.
+var errNoOverlay = errors.New("no overlay")
+
+// InstanceName returns the instance name: instance.name, else the host
+// name up to the first dot, lowercased.
+func (c *Config) InstanceName() string {
+	if c.Instance.Name != "" {
+		return c.Instance.Name
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	host, _, _ = strings.Cut(host, ".")
+	return strings.ToLower(host)
+}
+
+// applyOverlay merges the instance's overrides from instance.overlays, a
+// directory or http(s) URL holding <name>.yaml, .yml, or .json files, over
+// cfg. Settings in the overlay replace the base; lists and maps are
+// replaced as a whole. A missing overlay is not an error.
+func applyOverlay(cfg *Config) error {
+	source := cfg.Instance.Overlays
+	name := cfg.InstanceName()
+	if source == "" || name == "" {
+		return nil
+	}
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid instance name %q", name)
+	}
+
+	remote := strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+	for _, ext := range overlayExts {
+		var data []byte
+		var err error
+		var location string
+		if remote {
+			location = strings.TrimSuffix(source, "/") + "/" + name + ext
+			data, err = fetchOverlay(location, cfg.Instance.Token)
+		} else {
+			location = filepath.Join(source, name+ext)
+			data, err = os.ReadFile(location)
+			if errors.Is(err, os.ErrNotExist) {
+				err = errNoOverlay
+			}
+		}
+		if errors.Is(err, errNoOverlay) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read overlay: %w", err)
+		}
+
+		// Keep the overlay from moving itself
+		instance := cfg.Instance
+		if err := unmarshal(data, ext, cfg); err != nil {
+			return fmt.Errorf("parse overlay %s: %w", location, err)
+		}
+		cfg.Instance = instance
+		cfg.Instance.Name = name
+		cfg.Instance.Applied = location
+		return nil
+	}
+	return nil
+}
+
+// fetchOverlay downloads an overlay, returning errNoOverlay for 404.
+func fetchOverlay(url, token string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), overlayTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errNoOverlay
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOverlayBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	if len(data) > maxOverlayBytes {
+		return nil, fmt.Errorf("fetch %s: overlay larger than %d bytes", url, maxOverlayBytes)
+	}
+	return data, nil
+}
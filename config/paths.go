@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileNames are the config file names searched for, in order.
+var fileNames = []string{"omniagent.yaml", "omniagent.yml", "omniagent.json"}
+
+// Dir returns the per-user configuration directory: %AppData%\omniagent on
+// Windows, ~/Library/Application Support/omniagent on macOS, and
+// $XDG_CONFIG_HOME/omniagent (usually ~/.config/omniagent) elsewhere.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "omniagent"), nil
+}
+
+// Find returns the config file to load when none is given: the first of
+// omniagent.yaml, omniagent.yml, or omniagent.json in the working
+// directory, then in Dir. It returns "" if there is none.
+func Find() string {
+	dirs := []string{"."}
+	if dir, err := Dir(); err == nil {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		for _, name := range fileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
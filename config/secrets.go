@@ -0,0 +1,244 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// secretTimeout bounds resolving all of a config's secret references.
+const secretTimeout = 30 * time.Second
+
+// secretRefRe matches ${...} references in config values.
+var secretRefRe = regexp.MustCompile(`\$\{([^{}]+)\}`)
+
+// envNameRe matches a bare environment variable reference.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runCommand runs a secret manager CLI and returns its output. Tests
+// replace it.
+var runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return nil, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+// resolveSecrets replaces secret references in every string value of cfg:
+//
+//	${VAR} or ${env:VAR}          environment variable
+//	${file:/run/secrets/x}        file contents, without the trailing newline
+//	${vault:secret/data/app#key}  HashiCorp Vault (VAULT_ADDR, VAULT_TOKEN)
+//	${op://vault/item/field}      1Password, via the op CLI
+//	${aws-sm:name#key}            AWS Secrets Manager, via the aws CLI
+//
+// The #key suffix selects a field of a JSON secret. Other ${...} text, such
+// as shell syntax in a prompt, is left as written.
+func resolveSecrets(cfg *Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	r := &secretResolver{ctx: ctx, cache: make(map[string]string)}
+	return r.walk(reflect.ValueOf(cfg).Elem())
+}
+
+// secretResolver resolves references, fetching each secret once.
+type secretResolver struct {
+	ctx   context.Context
+	cache map[string]string
+}
+
+// walk resolves references in the string values reachable from v.
+func (r *secretResolver) walk(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		s, err := r.expand(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return r.walk(v.Elem())
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				if err := r.walk(v.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := r.walk(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map values are not addressable; resolve a copy and store it back
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := r.walk(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+	return nil
+}
+
+// expand replaces the references in s.
+func (r *secretResolver) expand(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var firstErr error
+	out := secretRefRe.ReplaceAllStringFunc(s, func(match string) string {
+		ref := match[2 : len(match)-1]
+		value, ok, err := r.resolve(ref)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("resolve ${%s}: %w", ref, err)
+		}
+		if !ok {
+			return match
+		}
+		return value
+	})
+	return out, firstErr
+}
+
+// resolve returns the value of a reference, and false if ref is not one.
+func (r *secretResolver) resolve(ref string) (string, bool, error) {
+	if envNameRe.MatchString(ref) {
+		return os.Getenv(ref), true, nil
+	}
+	if v, ok := r.cache[ref]; ok {
+		return v, true, nil
+	}
+
+	var value string
+	var err error
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		value = os.Getenv(strings.TrimPrefix(ref, "env:"))
+	case strings.HasPrefix(ref, "file:"):
+		value, err = readSecretFile(strings.TrimPrefix(ref, "file:"))
+	case strings.HasPrefix(ref, "vault:"):
+		value, err = r.vault(strings.TrimPrefix(ref, "vault:"))
+	case strings.HasPrefix(ref, "op://"):
+		value, err = r.command("op", "read", "--no-newline", ref)
+	case strings.HasPrefix(ref, "aws-sm:"):
+		name, key, _ := strings.Cut(strings.TrimPrefix(ref, "aws-sm:"), "#")
+		value, err = r.command("aws", "secretsmanager", "get-secret-value",
+			"--secret-id", name, "--query", "SecretString", "--output", "text")
+		if err == nil && key != "" {
+			value, err = jsonField([]byte(value), key)
+		}
+	default:
+		return "", false, nil
+	}
+	if err != nil {
+		return "", true, err
+	}
+	r.cache[ref] = value
+	return value, true, nil
+}
+
+// readSecretFile reads a secret from a file, such as a Docker or
+// Kubernetes secret mount.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// vault reads a field of a HashiCorp Vault secret, given as path#field.
+// The field defaults to "value". KV version 1 and 2 engines are supported.
+func (r *secretResolver) vault(ref string) (string, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	if field == "" {
+		field = "value"
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("VAULT_ADDR not set")
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := http.DefaultClient.Do(req) //nolint:gosec // G704: Vault address comes from the operator's environment
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOverlayBytes))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: %s", resp.Status)
+	}
+
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("vault: decode response: %w", err)
+	}
+	// KV version 2 nests the secret under data.data
+	data, err := json.Marshal(secret.Data)
+	if err != nil {
+		return "", err
+	}
+	if nested, ok := secret.Data["data"]; ok && secret.Data["metadata"] != nil {
+		data = nested
+	}
+	return jsonField(data, field)
+}
+
+// command runs a secret manager CLI and returns its trimmed output.
+func (r *secretResolver) command(name string, args ...string) (string, error) {
+	out, err := runCommand(r.ctx, name, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// jsonField returns a field of a JSON object as a string.
+func jsonField(data []byte, key string) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// defaultWatchInterval is how often Watch checks the file by default.
+const defaultWatchInterval = 5 * time.Second
+
+// Watch calls fn each time the file at path changes, checking its
+// modification time and size every interval, until ctx is done. Editors
+// that replace the file are handled, and a missing file is waited for.
+func Watch(ctx context.Context, path string, interval time.Duration, fn func()) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	last, _ := os.Stat(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // Being replaced, or removed; keep the last state
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		fn()
+	}
+}
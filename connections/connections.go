@@ -0,0 +1,212 @@
+// Package connections connects messaging channels at startup under a
+// per-channel policy, so one channel with a bad token or an unreachable
+// server doesn't have to take the whole gateway down.
+package connections
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+// Startup policies.
+const (
+	PolicyFailFast = "fail_fast" // Stop the gateway
+	PolicyRetry    = "retry"     // Keep retrying in the background
+	PolicyDisable  = "disable"   // Log a warning and run without the channel
+)
+
+// Channel states.
+const (
+	StateConnecting = "connecting"
+	StateConnected  = "connected"
+	StateRetrying   = "retrying"
+	StateDisabled   = "disabled"
+)
+
+// Status is a channel's connection state.
+type Status struct {
+	Channel  string    `json:"channel"`
+	State    string    `json:"state"`
+	Policy   string    `json:"policy"`
+	Error    string    `json:"error,omitempty"` // Last failure
+	Attempts int       `json:"attempts"`
+	Since    time.Time `json:"since"` // When the state last changed
+}
+
+// Config configures channel connections.
+type Config struct {
+	// Policy applies to every channel (default: PolicyFailFast). Channels
+	// overrides it by channel name.
+	Policy   string
+	Channels map[string]string
+
+	// RetryInterval is the first wait between retries, doubling up to
+	// MaxRetryInterval (defaults: 5s and 5m).
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+
+	Logger *slog.Logger
+}
+
+// Manager connects channels and tracks their state.
+type Manager struct {
+	config   Config
+	statuses map[string]*Status
+	wg       sync.WaitGroup
+	mu       sync.RWMutex
+}
+
+// New creates a manager.
+func New(config Config) (*Manager, error) {
+	if config.Policy == "" {
+		config.Policy = PolicyFailFast
+	}
+	for _, p := range append([]string{config.Policy}, slices.Collect(maps.Values(config.Channels))...) {
+		if p != PolicyFailFast && p != PolicyRetry && p != PolicyDisable {
+			return nil, fmt.Errorf("unknown startup policy %q (use %s, %s, or %s)", p, PolicyFailFast, PolicyRetry, PolicyDisable)
+		}
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = 5 * time.Second
+	}
+	if config.MaxRetryInterval < config.RetryInterval {
+		config.MaxRetryInterval = max(5*time.Minute, config.RetryInterval)
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Manager{config: config, statuses: make(map[string]*Status)}, nil
+}
+
+// Policy returns a channel's startup policy.
+func (m *Manager) Policy(channel string) string {
+	if p, ok := m.config.Channels[channel]; ok {
+		return p
+	}
+	return m.config.Policy
+}
+
+// Failed records a channel that could not be created. It returns an error
+// for fail-fast channels; others are disabled, since there is nothing to
+// retry.
+func (m *Manager) Failed(channel string, err error) error {
+	policy := m.Policy(channel)
+	if policy == PolicyFailFast {
+		return fmt.Errorf("create %s provider: %w", channel, err)
+	}
+	m.set(channel, StateDisabled, err, 1)
+	m.config.Logger.Warn("channel disabled: create failed", "channel", channel, "policy", policy, "error", err)
+	return nil
+}
+
+// Connect connects every channel registered with the router. A fail-fast
+// channel that fails to connect stops the connection and returns its
+// error. Retrying channels reconnect in the background until ctx is done;
+// disabled channels are unregistered.
+func (m *Manager) Connect(ctx context.Context, router *provider.Router) error {
+	names := router.ListProviders()
+	slices.Sort(names)
+	for _, name := range names {
+		p, ok := router.GetProvider(name)
+		if !ok {
+			continue
+		}
+		m.set(name, StateConnecting, nil, 0)
+		err := p.Connect(ctx)
+		if err == nil {
+			m.set(name, StateConnected, nil, 1)
+			m.config.Logger.Info("channel connected", "channel", name)
+			continue
+		}
+
+		switch policy := m.Policy(name); policy {
+		case PolicyRetry:
+			m.set(name, StateRetrying, err, 1)
+			m.config.Logger.Warn("channel failed to connect, retrying in the background", "channel", name, "error", err)
+			m.wg.Add(1)
+			go m.retry(ctx, p)
+		case PolicyDisable:
+			m.set(name, StateDisabled, err, 1)
+			router.Unregister(name)
+			m.config.Logger.Warn("channel disabled: connect failed", "channel", name, "error", err)
+		default:
+			return fmt.Errorf("connect %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// retry reconnects a channel with exponential backoff.
+func (m *Manager) retry(ctx context.Context, p provider.Provider) {
+	defer m.wg.Done()
+	name := p.Name()
+	wait := m.config.RetryInterval
+	for attempt := 2; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		err := p.Connect(ctx)
+		if err == nil {
+			m.set(name, StateConnected, nil, attempt)
+			m.config.Logger.Info("channel connected", "channel", name, "attempts", attempt)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		m.set(name, StateRetrying, err, attempt)
+		m.config.Logger.Warn("channel connect retry failed", "channel", name, "attempts", attempt, "error", err)
+		wait = min(wait*2, m.config.MaxRetryInterval)
+	}
+}
+
+// Wait blocks until background retries have stopped. Call it after
+// canceling the context passed to Connect, before disconnecting channels.
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Statuses returns every channel's state, sorted by channel.
+func (m *Manager) Statuses() []Status {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Status, 0, len(m.statuses))
+	for _, s := range m.statuses {
+		out = append(out, *s)
+	}
+	slices.SortFunc(out, func(a, b Status) int { return strings.Compare(a.Channel, b.Channel) })
+	return out
+}
+
+// set updates a channel's state.
+func (m *Manager) set(channel, state string, err error, attempts int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.statuses[channel]
+	if !ok {
+		s = &Status{Channel: channel, Policy: m.Policy(channel)}
+		m.statuses[channel] = s
+	}
+	if s.State != state {
+		s.Since = time.Now()
+	}
+	s.State = state
+	s.Attempts = attempts
+	s.Error = ""
+	if err != nil {
+		s.Error = err.Error()
+	}
+}
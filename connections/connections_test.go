@@ -0,0 +1,97 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+	"github.com/plexusone/omnichat/provider/providertest"
+)
+
+// flakyProvider fails to connect a number of times.
+type flakyProvider struct {
+	*providertest.MockProvider
+	failures int
+	mu       sync.Mutex
+}
+
+func (p *flakyProvider) Connect(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures != 0 {
+		p.failures--
+		return errors.New("network down")
+	}
+	return p.MockProvider.Connect(ctx)
+}
+
+func TestConnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, err := New(Config{
+		Channels:      map[string]string{"telegram": PolicyRetry, "discord": PolicyDisable},
+		RetryInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	router := provider.NewRouter(nil)
+	router.Register(&flakyProvider{MockProvider: providertest.NewMockProvider("telegram"), failures: 2})
+	router.Register(&flakyProvider{MockProvider: providertest.NewMockProvider("discord"), failures: -1})
+	router.Register(providertest.NewMockProvider("signal"))
+
+	if err := m.Connect(ctx, router); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if _, ok := router.GetProvider("discord"); ok {
+		t.Error("disabled channel should be unregistered")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if s := status(m, "telegram"); s.State == StateConnected {
+			if s.Attempts != 3 {
+				t.Errorf("telegram attempts = %d, want 3", s.Attempts)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("telegram never connected: %+v", status(m, "telegram"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s := status(m, "discord"); s.State != StateDisabled || s.Error != "network down" {
+		t.Errorf("discord status = %+v, want disabled", s)
+	}
+	if s := status(m, "signal"); s.State != StateConnected || s.Policy != PolicyFailFast {
+		t.Errorf("signal status = %+v, want connected fail_fast", s)
+	}
+	cancel()
+	m.Wait()
+
+	failFast, _ := New(Config{})
+	router = provider.NewRouter(nil)
+	router.Register(&flakyProvider{MockProvider: providertest.NewMockProvider("sms"), failures: 1})
+	if err := failFast.Connect(context.Background(), router); err == nil {
+		t.Error("fail-fast channel should stop Connect")
+	}
+	if err := failFast.Failed("sms", errors.New("bad token")); err == nil {
+		t.Error("fail-fast create failure should return an error")
+	}
+	if _, err := New(Config{Policy: "ignore"}); err == nil {
+		t.Error("unknown policy should fail")
+	}
+}
+
+// status returns a channel's status.
+func status(m *Manager, channel string) Status {
+	for _, s := range m.Statuses() {
+		if s.Channel == channel {
+			return s
+		}
+	}
+	return Status{}
+}
@@ -0,0 +1,131 @@
+// Package contacts keeps a registry of people who have messaged the agent.
+package contacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Contact is a person known to the agent on one channel.
+type Contact struct {
+	ID        string    `json:"id"` // "channel:senderID"
+	Channel   string    `json:"channel"`
+	SenderID  string    `json:"sender_id"`
+	Name      string    `json:"name,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ID returns the contact ID for a sender on a channel.
+func ID(channel, senderID string) string {
+	return channel + ":" + senderID
+}
+
+// Store persists contacts to a JSON file.
+type Store struct {
+	path     string
+	contacts map[string]Contact
+	mu       sync.RWMutex
+}
+
+// Open loads the contact store at path. An empty path keeps contacts in
+// memory only.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, contacts: make(map[string]Contact)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read contacts: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.contacts); err != nil {
+		return nil, fmt.Errorf("parse contacts: %w", err)
+	}
+	return s, nil
+}
+
+// Touch records a message from a sender, registering them if they are new.
+// isNew reports whether this is the first message seen from the contact.
+func (s *Store) Touch(channel, senderID, name string) (c Contact, isNew bool, err error) {
+	id := ID(channel, senderID)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, exists := s.contacts[id]
+	if !exists {
+		c = Contact{ID: id, Channel: channel, SenderID: senderID, FirstSeen: now}
+	}
+	changed := !exists || (name != "" && name != c.Name)
+	if name != "" {
+		c.Name = name
+	}
+	c.LastSeen = now
+	s.contacts[id] = c
+
+	// LastSeen alone is persisted with the next change to keep writes rare
+	if changed {
+		if err := s.save(); err != nil {
+			return c, !exists, err
+		}
+	}
+	return c, !exists, nil
+}
+
+// Get returns a contact by ID.
+func (s *Store) Get(id string) (Contact, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.contacts[id]
+	return c, ok
+}
+
+// List returns all contacts ordered by first contact.
+func (s *Store) List() []Contact {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Contact, 0, len(s.contacts))
+	for _, c := range s.contacts {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FirstSeen.Before(out[j].FirstSeen) })
+	return out
+}
+
+// Save writes the store, persisting updated LastSeen times.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// save writes the contacts file. Callers must hold the write lock.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.contacts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode contacts: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("create contacts dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write contacts: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,39 @@
+package contacts
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTouch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contacts.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	c, isNew, err := s.Touch("telegram", "42", "Ada")
+	if err != nil || !isNew {
+		t.Fatalf("Touch() first = %v, %v; want new", isNew, err)
+	}
+	if c.ID != "telegram:42" || c.Name != "Ada" {
+		t.Errorf("Touch() contact = %+v", c)
+	}
+	if _, isNew, _ := s.Touch("telegram", "42", ""); isNew {
+		t.Error("Touch() second should not be new")
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() reload error = %v", err)
+	}
+	if got, ok := s.Get("telegram:42"); !ok || got.Name != "Ada" {
+		t.Errorf("Get() after reload = %+v, %v", got, ok)
+	}
+	if _, isNew, _ := s.Touch("discord", "42", ""); !isNew {
+		t.Error("Touch() on another channel should be new")
+	}
+	if n := len(s.List()); n != 2 {
+		t.Errorf("List() = %d contacts, want 2", n)
+	}
+}
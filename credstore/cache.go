@@ -0,0 +1,52 @@
+package credstore
+
+import (
+	"context"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Cache is an autocert.Cache keeping ACME account and certificate keys
+// sealed in a directory. Entries written in plaintext before encryption
+// was enabled are sealed when first read.
+type Cache struct {
+	dir        autocert.DirCache
+	passphrase string
+}
+
+// NewCache creates a sealed certificate cache in dir.
+func NewCache(dir, passphrase string) *Cache {
+	return &Cache{dir: autocert.DirCache(dir), passphrase: passphrase}
+}
+
+// Get returns the decrypted entry for key, or autocert.ErrCacheMiss.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.dir.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !IsSealed(data) {
+		if err := c.Put(ctx, key, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	return Unseal(c.passphrase, data)
+}
+
+// Put seals data and stores it under key.
+func (c *Cache) Put(ctx context.Context, key string, data []byte) error {
+	sealed, err := Seal(c.passphrase, data)
+	if err != nil {
+		return err
+	}
+	return c.dir.Put(ctx, key, sealed)
+}
+
+// Delete removes the entry for key.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.dir.Delete(ctx, key)
+}
+
+// Ensure Cache implements autocert.Cache.
+var _ autocert.Cache = (*Cache)(nil)
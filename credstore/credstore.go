@@ -0,0 +1,120 @@
+// Package credstore encrypts the credential state omniagent writes, the
+// WhatsApp session database and the ACME certificate cache, at rest.
+// Files are sealed with XChaCha20-Poly1305 under a key derived from a
+// passphrase with scrypt.
+package credstore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrDecrypt is returned when sealed data cannot be opened, because the
+// passphrase is wrong or the data was modified.
+var ErrDecrypt = errors.New("credstore: wrong passphrase or corrupted data")
+
+// magic starts every sealed file, followed by the salt, the nonce, and the
+// ciphertext.
+var magic = []byte("OMNIAGENT-SEALED-1\n")
+
+// Key derivation parameters.
+const (
+	saltSize = 16
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+)
+
+// IsSealed reports whether data was produced by Seal.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Seal encrypts plaintext with a key derived from passphrase.
+func Seal(passphrase string, plaintext []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("credstore: passphrase required")
+	}
+	salt := make([]byte, saltSize)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(magic)+saltSize+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, magic), nil
+}
+
+// Unseal decrypts data produced by Seal.
+func Unseal(passphrase string, sealed []byte) ([]byte, error) {
+	if !IsSealed(sealed) {
+		return nil, errors.New("credstore: data is not sealed")
+	}
+	rest := sealed[len(magic):]
+	if len(rest) < saltSize+chacha20poly1305.NonceSizeX {
+		return nil, ErrDecrypt
+	}
+	salt, rest := rest[:saltSize], rest[saltSize:]
+	nonce, ciphertext := rest[:chacha20poly1305.NonceSizeX], rest[chacha20poly1305.NonceSizeX:]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, magic)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// newAEAD derives the file key from passphrase and salt.
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("credstore: derive key: %w", err)
+	}
+	return chacha20poly1305.NewX(key)
+}
+
+// writeSealed seals plaintext to path, replacing it atomically.
+func writeSealed(path, passphrase string, plaintext []byte) error {
+	sealed, err := Seal(passphrase, plaintext)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,173 @@
+package credstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestSeal(t *testing.T) {
+	sealed, err := Seal("correct horse", []byte("session keys"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("session keys")) {
+		t.Fatal("sealed data is not encrypted")
+	}
+	got, err := Unseal("correct horse", sealed)
+	if err != nil || string(got) != "session keys" {
+		t.Fatalf("Unseal() = %q, %v", got, err)
+	}
+	if _, err := Unseal("wrong", sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Unseal(wrong passphrase) error = %v, want ErrDecrypt", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := Unseal("correct horse", sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Unseal(modified) error = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestDB(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	path := filepath.Join(t.TempDir(), "whatsapp.db")
+
+	// A plaintext database is encrypted in place on first open
+	execSQL(t, path, "CREATE TABLE device (jid TEXT)", "INSERT INTO device VALUES ('123@s.whatsapp.net')")
+	d, err := OpenDB(path, "pass", nil)
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); !IsSealed(data) {
+		t.Fatal("database not encrypted on open")
+	}
+
+	execSQL(t, d.Path(), "INSERT INTO device VALUES ('456@s.whatsapp.net')")
+	workDir := filepath.Dir(d.Path())
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+		t.Error("working copy left behind")
+	}
+	if data, _ := os.ReadFile(path); !IsSealed(data) || bytes.Contains(data, []byte("whatsapp.net")) {
+		t.Fatal("database not sealed on close")
+	}
+
+	if _, err := OpenDB(path, "wrong", nil); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("OpenDB(wrong passphrase) error = %v", err)
+	}
+	d, err = OpenDB(path, "pass", nil)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer d.Close()
+	db, err := sql.Open("sqlite", "file:"+d.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM device").Scan(&n); err != nil || n != 2 {
+		t.Errorf("rows = %d, %v; want 2", n, err)
+	}
+}
+
+func TestDBWriteAheadLog(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	live := filepath.Join(t.TempDir(), "live.db")
+
+	// Copy a database mid-write, as a killed process leaves it: the last
+	// row is only in the write-ahead log
+	db, err := sql.Open("sqlite", "file:"+live+"?_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{"CREATE TABLE device (jid TEXT)", "INSERT INTO device VALUES ('123@s.whatsapp.net')"} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "whatsapp.db")
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		data, err := os.ReadFile(live + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path+suffix, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := OpenDB(path, "pass", nil)
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer d.Close()
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(path + suffix); !os.IsNotExist(err) {
+			t.Errorf("plaintext %s file left behind", suffix)
+		}
+	}
+	working, err := sql.Open("sqlite", "file:"+d.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer working.Close()
+	var n int
+	if err := working.QueryRow("SELECT COUNT(*) FROM device").Scan(&n); err != nil || n != 1 {
+		t.Errorf("rows = %d, %v; want the row from the log", n, err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acme_account+key"), []byte("plain key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCache(dir, "pass")
+
+	if err := c.Put(ctx, "example.com", []byte("cert and key")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "example.com")); !IsSealed(data) {
+		t.Error("certificate stored in plaintext")
+	}
+	if got, err := c.Get(ctx, "example.com"); err != nil || string(got) != "cert and key" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+
+	// Plaintext entries are read, then sealed
+	if got, err := c.Get(ctx, "acme_account+key"); err != nil || string(got) != "plain key" {
+		t.Errorf("Get(plaintext) = %q, %v", got, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "acme_account+key")); !IsSealed(data) {
+		t.Error("plaintext entry not sealed on read")
+	}
+
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, autocert.ErrCacheMiss) {
+		t.Errorf("Get(missing) error = %v, want ErrCacheMiss", err)
+	}
+}
+
+func execSQL(t *testing.T, path string, stmts ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+}
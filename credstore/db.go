@@ -0,0 +1,176 @@
+package credstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver
+)
+
+// defaultCheckpoint is how often an open database is sealed by default.
+const defaultCheckpoint = time.Minute
+
+// DB keeps a SQLite database encrypted at path while a decrypted working
+// copy is used from a private directory, preferably on a memory-backed
+// file system. The working copy is sealed back to path periodically and
+// on Close, so changes since the last checkpoint are lost on a crash.
+type DB struct {
+	path       string
+	passphrase string
+	dir        string
+	logger     *slog.Logger
+	mu         sync.Mutex
+}
+
+// OpenDB decrypts the database at path into a working copy. A plaintext
+// database at path is encrypted in place first, including changes still
+// in its write-ahead log, and its -wal and -shm files are removed. A
+// missing database starts empty.
+func OpenDB(path, passphrase string, logger *slog.Logger) (*DB, error) {
+	if passphrase == "" {
+		return nil, errors.New("credstore: passphrase required")
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	dir, err := os.MkdirTemp(runtimeDir(), "omniagent-")
+	if err != nil {
+		return nil, fmt.Errorf("create working directory: %w", err)
+	}
+	d := &DB{path: path, passphrase: passphrase, dir: dir, logger: logger}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return d, nil
+	case err != nil:
+		d.removeWorkingCopy()
+		return nil, err
+	case !IsSealed(data):
+		if data, err = d.snapshot(path); err != nil {
+			d.removeWorkingCopy()
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		if err := writeSealed(path, passphrase, data); err != nil {
+			d.removeWorkingCopy()
+			return nil, fmt.Errorf("encrypt %s: %w", path, err)
+		}
+		if err := removeSidecars(path); err != nil {
+			d.removeWorkingCopy()
+			return nil, fmt.Errorf("remove plaintext journal of %s: %w", path, err)
+		}
+		logger.Info("encrypted credential database", "path", path)
+	default:
+		if data, err = Unseal(passphrase, data); err != nil {
+			d.removeWorkingCopy()
+			return nil, fmt.Errorf("decrypt %s: %w", path, err)
+		}
+	}
+	if err := os.WriteFile(d.Path(), data, 0o600); err != nil {
+		d.removeWorkingCopy()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Path returns the decrypted working copy to open.
+func (d *DB) Path() string {
+	return filepath.Join(d.dir, filepath.Base(d.path))
+}
+
+// Checkpoint seals a consistent snapshot of the working copy to path.
+// Connections may stay open while it runs.
+func (d *DB) Checkpoint() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := os.Stat(d.Path()); errors.Is(err, os.ErrNotExist) {
+		return nil // Not created yet
+	}
+	data, err := d.snapshot(d.Path())
+	if err != nil {
+		return fmt.Errorf("snapshot %s: %w", d.path, err)
+	}
+	return writeSealed(d.path, d.passphrase, data)
+}
+
+// snapshot returns a consistent copy of the SQLite database at path,
+// including changes in its write-ahead log.
+func (d *DB) snapshot(path string) ([]byte, error) {
+	snapshot := filepath.Join(d.dir, "snapshot.db")
+	defer os.Remove(snapshot)
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec("VACUUM INTO ?", snapshot)
+	db.Close()
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(snapshot) //nolint:gosec // G304: Path is in our private directory
+}
+
+// removeSidecars deletes the journal files SQLite keeps next to a
+// database, which hold plaintext pages.
+func removeSidecars(path string) error {
+	var errs []error
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run checkpoints every interval (default: 1m) until ctx is canceled.
+func (d *DB) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultCheckpoint
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := d.Checkpoint(); err != nil {
+				d.logger.Error("credential database checkpoint failed", "path", d.path, "error", err)
+			}
+		}
+	}
+}
+
+// Close seals the working copy a final time and removes it. Close it after
+// the connections using it.
+func (d *DB) Close() error {
+	err := d.Checkpoint()
+	if err != nil {
+		// Keep the working copy so no changes are lost
+		return fmt.Errorf("checkpoint %s: %w; working copy kept in %s", d.path, err, d.dir)
+	}
+	d.removeWorkingCopy()
+	return nil
+}
+
+func (d *DB) removeWorkingCopy() {
+	if err := os.RemoveAll(d.dir); err != nil {
+		d.logger.Warn("failed to remove credential working copy", "dir", d.dir, "error", err)
+	}
+}
+
+// runtimeDir returns the per-user runtime directory, which is memory-backed
+// on most Linux systems, or the temporary directory.
+func runtimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
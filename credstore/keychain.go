@@ -0,0 +1,32 @@
+package credstore
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/plexusone/omniagent/keychain"
+)
+
+// KeychainPassphrase returns the passphrase stored under account in the
+// OS keychain, creating a random one on first use.
+func KeychainPassphrase(account string) (string, error) {
+	secret, err := keychain.Get(account)
+	if err == nil {
+		return secret, nil
+	}
+	if !errors.Is(err, keychain.ErrNotFound) {
+		return "", err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	secret = base64.RawStdEncoding.EncodeToString(key)
+	if err := keychain.Set(account, secret); err != nil {
+		return "", fmt.Errorf("store key in keychain: %w", err)
+	}
+	return secret, nil
+}
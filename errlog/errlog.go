@@ -0,0 +1,122 @@
+// Package errlog keeps the most recent error logs in memory, so operators
+// can see what went wrong from the admin API without searching log files.
+package errlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Entry is a logged error.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Message string         `json:"message"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+}
+
+// Recorder keeps the latest error logs in a ring buffer.
+type Recorder struct {
+	entries []Entry
+	next    int // Index of the next write
+	full    bool
+	mu      sync.Mutex
+}
+
+// New creates a recorder that keeps the last size errors (default: 100).
+func New(size int) *Recorder {
+	if size <= 0 {
+		size = 100
+	}
+	return &Recorder{entries: make([]Entry, size)}
+}
+
+// Handler wraps a log handler so error records are also kept by the
+// recorder.
+func (r *Recorder) Handler(next slog.Handler) slog.Handler {
+	return &handler{next: next, recorder: r}
+}
+
+// Recent returns up to n of the latest errors, newest first. n <= 0
+// returns all kept errors.
+func (r *Recorder) Recent(n int) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+	out := make([]Entry, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return out
+}
+
+// add records an error.
+func (r *Recorder) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// handler passes records on and keeps errors.
+type handler struct {
+	next     slog.Handler
+	recorder *Recorder
+	attrs    []slog.Attr
+	group    string // Prefix for attribute keys
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError {
+		e := Entry{Time: record.Time, Message: record.Message, Attrs: make(map[string]any)}
+		for _, a := range h.attrs {
+			e.Attrs[a.Key] = a.Value.Resolve().Any()
+		}
+		record.Attrs(func(a slog.Attr) bool {
+			e.Attrs[h.group+a.Key] = a.Value.Resolve().Any()
+			return true
+		})
+		for k, v := range e.Attrs {
+			if err, ok := v.(error); ok {
+				e.Attrs[k] = err.Error()
+			}
+		}
+		if len(e.Attrs) == 0 {
+			e.Attrs = nil
+		}
+		h.recorder.add(e)
+	}
+	if !h.next.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	kept := append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		kept = append(kept, slog.Attr{Key: h.group + a.Key, Value: a.Value})
+	}
+	return &handler{next: h.next.WithAttrs(attrs), recorder: h.recorder, attrs: kept, group: h.group}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &handler{next: h.next.WithGroup(name), recorder: h.recorder, attrs: h.attrs, group: h.group + name + "."}
+}
@@ -0,0 +1,40 @@
+package errlog
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	rec := New(2)
+	logger := slog.New(rec.Handler(slog.NewTextHandler(&buf, nil))).With("component", "gateway")
+
+	logger.Info("started")
+	logger.Error("first", "error", errors.New("boom"))
+	logger.WithGroup("req").Error("second", "id", 7)
+	logger.Error("third")
+
+	if !strings.Contains(buf.String(), "started") || !strings.Contains(buf.String(), "third") {
+		t.Errorf("records not passed on: %s", buf.String())
+	}
+	recent := rec.Recent(0)
+	if len(recent) != 2 || recent[0].Message != "third" || recent[1].Message != "second" {
+		t.Fatalf("Recent() = %+v, want third, second", recent)
+	}
+	if recent[1].Attrs["req.id"] != int64(7) || recent[1].Attrs["component"] != "gateway" {
+		t.Errorf("attrs = %v", recent[1].Attrs)
+	}
+	if got := rec.Recent(1); len(got) != 1 || got[0].Message != "third" {
+		t.Errorf("Recent(1) = %+v", got)
+	}
+
+	rec = New(5)
+	slog.New(rec.Handler(slog.NewTextHandler(&buf, nil))).Error("failed", "error", errors.New("boom"))
+	if got := rec.Recent(0); len(got) != 1 || got[0].Attrs["error"] != "boom" {
+		t.Errorf("Recent() = %+v, want error as string", got)
+	}
+}
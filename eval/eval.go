@@ -0,0 +1,290 @@
+// Package eval captures agent traces and user feedback for quality reporting.
+package eval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrTraceNotFound is returned when feedback references an unknown trace.
+var ErrTraceNotFound = errors.New("trace not found")
+
+// Trace records a single request/response exchange with the agent.
+type Trace struct {
+	ID        string        `json:"id"`
+	SessionID string        `json:"session_id"`
+	Channel   string        `json:"channel,omitempty"`
+	Provider  string        `json:"provider,omitempty"`
+	Model     string        `json:"model"`
+	Skills    []string      `json:"skills,omitempty"` // Skills injected into the prompt
+	Tools     []string      `json:"tools,omitempty"`  // Tools called while answering
+	Input     string        `json:"input"`
+	Output    string        `json:"output"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	CreatedAt time.Time     `json:"created_at"`
+
+	Experiment       string `json:"experiment,omitempty"`
+	Variant          string `json:"variant,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+
+	// PromptFingerprint hashes the system prompt with its persona and
+	// skills, before per-request context such as the time or memories.
+	PromptFingerprint string `json:"prompt_fingerprint,omitempty"`
+}
+
+// Rating values for feedback.
+const (
+	RatingUp   = 1
+	RatingDown = -1
+)
+
+// Feedback is a user rating of a traced response.
+type Feedback struct {
+	TraceID   string    `json:"trace_id"`
+	SessionID string    `json:"session_id,omitempty"` // Session of the rater; must own the trace
+	Rating    int       `json:"rating"`               // RatingUp or RatingDown
+	Comment   string    `json:"comment,omitempty"`
+	Source    string    `json:"source,omitempty"` // gateway, reaction, command
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ParseRating converts "up"/"down" (and common synonyms) to a rating.
+func ParseRating(s string) (int, error) {
+	switch s {
+	case "up", "+1", "1", "good", "👍":
+		return RatingUp, nil
+	case "down", "-1", "bad", "👎":
+		return RatingDown, nil
+	default:
+		return 0, fmt.Errorf("invalid rating %q (use up or down)", s)
+	}
+}
+
+// Store persists traces and feedback as JSON lines in a directory. Only
+// the most recent traces are kept in memory; reports read the files.
+type Store struct {
+	dir    string
+	recent []Trace // Ring of the most recent traces
+	next   int     // Index in recent of the next trace once it is full
+	mu     sync.RWMutex
+}
+
+const (
+	tracesFile   = "traces.jsonl"
+	feedbackFile = "feedback.jsonl"
+
+	// maxRecent bounds the traces kept in memory for feedback lookups.
+	maxRecent = 1024
+)
+
+// Open opens (or creates) a store in dir and loads existing records.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create eval dir: %w", err)
+	}
+
+	s := &Store{dir: dir}
+	if err := s.eachTrace(func(t Trace) bool {
+		s.addTrace(t)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RecordTrace stores a trace.
+func (s *Store) RecordTrace(_ context.Context, t Trace) error {
+	if t.ID == "" {
+		return fmt.Errorf("trace id required")
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := appendLine(filepath.Join(s.dir, tracesFile), t); err != nil {
+		return err
+	}
+	s.addTrace(t)
+	return nil
+}
+
+// RecordFeedback stores feedback for an existing trace of f.SessionID. A
+// trace of another session is reported as ErrTraceNotFound, so callers
+// can't rate, or probe for, traces they don't own.
+func (s *Store) RecordFeedback(_ context.Context, f Feedback) error {
+	if f.Rating != RatingUp && f.Rating != RatingDown {
+		return fmt.Errorf("invalid rating %d", f.Rating)
+	}
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now()
+	}
+
+	trace, ok, err := s.Trace(f.TraceID)
+	if err != nil {
+		return err
+	}
+	if !ok || trace.SessionID != f.SessionID {
+		return ErrTraceNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendLine(filepath.Join(s.dir, feedbackFile), f)
+}
+
+// Trace returns a trace by ID, reading the traces file when it is no
+// longer in memory.
+func (s *Store) Trace(id string) (Trace, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.recent {
+		if t.ID == id {
+			return t, true, nil
+		}
+	}
+	var found Trace
+	var ok bool
+	err := s.eachTrace(func(t Trace) bool {
+		if t.ID == id {
+			found, ok = t, true
+		}
+		return !ok
+	})
+	return found, ok, err
+}
+
+// LastTrace returns the most recent trace for a session, if it is among
+// the traces kept in memory.
+func (s *Store) LastTrace(sessionID string) (Trace, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Walk back from the newest trace, which precedes s.next in the ring
+	for i := range s.recent {
+		t := s.recent[(s.next-1-i+2*len(s.recent))%len(s.recent)]
+		if t.SessionID == sessionID {
+			return t, true
+		}
+	}
+	return Trace{}, false
+}
+
+// Traces returns all traces created at or after since, oldest first.
+func (s *Store) Traces(since time.Time) ([]Trace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []Trace
+	err := s.eachTrace(func(t Trace) bool {
+		if !t.CreatedAt.Before(since) {
+			out = append(out, t)
+		}
+		return true
+	})
+	return out, err
+}
+
+// Feedback returns all feedback records.
+func (s *Store) Feedback() ([]Feedback, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []Feedback
+	if err := readLines(filepath.Join(s.dir, feedbackFile), func(data []byte) error {
+		var f Feedback
+		if err := json.Unmarshal(data, &f); err != nil {
+			return err
+		}
+		out = append(out, f)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("load feedback: %w", err)
+	}
+	return out, nil
+}
+
+// eachTrace calls fn for each trace in the traces file, in recording
+// order, until fn returns false.
+func (s *Store) eachTrace(fn func(Trace) bool) error {
+	errStop := errors.New("stop")
+	err := readLines(filepath.Join(s.dir, tracesFile), func(data []byte) error {
+		var t Trace
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		if !fn(t) {
+			return errStop
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return fmt.Errorf("load traces: %w", err)
+	}
+	return nil
+}
+
+// addTrace adds a trace to the ring of recent traces, replacing the
+// oldest once it is full. Callers must hold the write lock.
+func (s *Store) addTrace(t Trace) {
+	if len(s.recent) < maxRecent {
+		s.recent = append(s.recent, t)
+		return
+	}
+	s.recent[s.next] = t
+	s.next = (s.next + 1) % maxRecent
+}
+
+// appendLine writes v as a JSON line to path.
+func appendLine(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) //nolint:gosec // G304: Path is within the configured eval directory
+	if err != nil {
+		return fmt.Errorf("open %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// readLines calls fn for each non-empty line of path. A missing file is not an error.
+func readLines(path string, fn func([]byte) error) error {
+	f, err := os.Open(path) //nolint:gosec // G304: Path is within the configured eval directory
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
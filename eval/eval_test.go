@@ -0,0 +1,288 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	traces := []Trace{
+		{ID: "t1", SessionID: "telegram:1", Channel: "telegram", Model: "gpt-4o", Skills: []string{"weather"}},
+		{ID: "t2", SessionID: "telegram:1", Channel: "telegram", Model: "gpt-4o"},
+		{ID: "t3", SessionID: "client-1", Model: "llama3.1", Error: "timeout"},
+	}
+	for _, tr := range traces {
+		if err := store.RecordTrace(ctx, tr); err != nil {
+			t.Fatalf("RecordTrace() error = %v", err)
+		}
+	}
+
+	if err := store.RecordFeedback(ctx, Feedback{TraceID: "t1", SessionID: "telegram:1", Rating: RatingUp}); err != nil {
+		t.Fatalf("RecordFeedback() error = %v", err)
+	}
+	if err := store.RecordFeedback(ctx, Feedback{TraceID: "t2", SessionID: "telegram:1", Rating: RatingDown}); err != nil {
+		t.Fatalf("RecordFeedback() error = %v", err)
+	}
+	if err := store.RecordFeedback(ctx, Feedback{TraceID: "missing", SessionID: "telegram:1", Rating: RatingUp}); err != ErrTraceNotFound {
+		t.Errorf("RecordFeedback(missing) error = %v, want ErrTraceNotFound", err)
+	}
+	if err := store.RecordFeedback(ctx, Feedback{TraceID: "t3", SessionID: "telegram:1", Rating: RatingDown}); err != ErrTraceNotFound {
+		t.Errorf("RecordFeedback(other session) error = %v, want ErrTraceNotFound", err)
+	}
+
+	// Reopen and verify persistence
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if last, ok := store.LastTrace("telegram:1"); !ok || last.ID != "t2" {
+		t.Errorf("LastTrace() = %v, %v; want t2", last.ID, ok)
+	}
+
+	report, err := store.Report(time.Time{}, nil)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if report.Total.Traces != 3 || report.Total.Up != 1 || report.Total.Down != 1 || report.Total.Errors != 1 {
+		t.Errorf("Total = %+v", report.Total)
+	}
+	if len(report.ByModel) != 2 || report.ByModel[0].Key != "gpt-4o" || report.ByModel[0].Approval() != 0.5 {
+		t.Errorf("ByModel = %+v", report.ByModel)
+	}
+	var sawGateway bool
+	for _, g := range report.ByChannel {
+		if g.Key == "gateway" {
+			sawGateway = true
+		}
+	}
+	if !sawGateway {
+		t.Errorf("ByChannel = %+v, want gateway group", report.ByChannel)
+	}
+}
+
+func TestStoreRecentBound(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := maxRecent + 10
+	for i := range n {
+		tr := Trace{ID: fmt.Sprintf("t%d", i), SessionID: fmt.Sprintf("s%d", i%3), Model: "m"}
+		if err := store.RecordTrace(ctx, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(store.recent) != maxRecent {
+		t.Errorf("recent traces = %d, want %d", len(store.recent), maxRecent)
+	}
+	session := fmt.Sprintf("s%d", (n-2)%3) // Its last trace is the second newest
+	if last, ok := store.LastTrace(session); !ok || last.ID != fmt.Sprintf("t%d", n-2) {
+		t.Errorf("LastTrace(%s) = %q, %v; want t%d", session, last.ID, ok, n-2)
+	}
+
+	// Traces evicted from memory are still found, rated, and reported
+	if tr, ok, err := store.Trace("t0"); err != nil || !ok || tr.SessionID != "s0" {
+		t.Errorf("Trace(t0) = %+v, %v, %v", tr, ok, err)
+	}
+	if err := store.RecordFeedback(ctx, Feedback{TraceID: "t0", SessionID: "s0", Rating: RatingUp}); err != nil {
+		t.Errorf("RecordFeedback(t0) error = %v", err)
+	}
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.recent) != maxRecent {
+		t.Errorf("recent traces after Open = %d, want %d", len(store.recent), maxRecent)
+	}
+	if traces, err := store.Traces(time.Time{}); err != nil || len(traces) != n {
+		t.Errorf("Traces() = %d, %v; want %d", len(traces), err, n)
+	}
+}
+
+func TestParseRating(t *testing.T) {
+	if r, err := ParseRating("up"); err != nil || r != RatingUp {
+		t.Errorf("ParseRating(up) = %d, %v", r, err)
+	}
+	if r, err := ParseRating("👎"); err != nil || r != RatingDown {
+		t.Errorf("ParseRating(👎) = %d, %v", r, err)
+	}
+	if _, err := ParseRating("meh"); err == nil {
+		t.Error("ParseRating(meh) should fail")
+	}
+}
+
+func TestReportByVariant(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	traces := []Trace{
+		{ID: "a1", Model: "gpt-4o", Experiment: "tone", Variant: "control", PromptTokens: 1000, CompletionTokens: 500},
+		{ID: "b1", Model: "gpt-4o-mini", Experiment: "tone", Variant: "mini", PromptTokens: 1000, CompletionTokens: 500},
+		{ID: "b2", Model: "gpt-4o-mini", Experiment: "tone", Variant: "mini", PromptTokens: 1000, CompletionTokens: 500},
+		{ID: "n1", Model: "gpt-4o"},
+	}
+	for _, tr := range traces {
+		if err := store.RecordTrace(ctx, tr); err != nil {
+			t.Fatalf("RecordTrace() error = %v", err)
+		}
+	}
+	if err := store.RecordFeedback(ctx, Feedback{TraceID: "b1", Rating: RatingUp}); err != nil { // Both unset: same session
+		t.Fatalf("RecordFeedback() error = %v", err)
+	}
+
+	pricing := Pricing{
+		"gpt-4o":      {Prompt: 2.5, Completion: 10},
+		"gpt-4o-mini": {Prompt: 0.15, Completion: 0.6},
+	}
+	report, err := store.Report(time.Time{}, pricing)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	if len(report.ByVariant) != 2 {
+		t.Fatalf("ByVariant = %+v, want 2 groups", report.ByVariant)
+	}
+	mini := report.ByVariant[0]
+	if mini.Key != "tone/mini" || mini.Traces != 2 || mini.Up != 1 || mini.PromptTokens != 2000 {
+		t.Errorf("ByVariant[0] = %+v", mini)
+	}
+	if want := 2 * (1000*0.15 + 500*0.6) / 1e6; math.Abs(mini.Cost-want) > 1e-12 {
+		t.Errorf("mini cost = %v, want %v", mini.Cost, want)
+	}
+	if control := report.ByVariant[1]; control.Key != "tone/control" || control.Cost == 0 {
+		t.Errorf("ByVariant[1] = %+v", control)
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, tr := range []Trace{
+		{ID: "t1", Channel: "telegram", Model: "gpt-4o", Tools: []string{"web_search", "http_fetch"},
+			Duration: 1500 * time.Millisecond, PromptTokens: 1000, CompletionTokens: 500, Input: "secret"},
+		{ID: "t2", Channel: "discord", Model: "llama3.1", Error: "timeout"},
+	} {
+		tr.CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		if err := store.RecordTrace(ctx, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pricing := Pricing{"gpt-4o": {Prompt: 2, Completion: 10}}
+
+	records, err := store.Records(time.Time{}, pricing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, FormatCSV, records); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("CSV rows = %v, %v", rows, err)
+	}
+	want := []string{"2026-03-01T12:00:00Z", "t1", "", "telegram", "", "gpt-4o", "", "", "1000", "500", "1500", "2", "web_search http_fetch", "", "0.007", ""}
+	if !slices.Equal(rows[1], want) {
+		t.Errorf("CSV row = %q, want %q", rows[1], want)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Error("export should not include message content")
+	}
+
+	// Scheduled exports write what is new since the previous file
+	dir := t.TempDir()
+	exporter, err := NewExporter(store, ExportConfig{Dir: dir, Format: FormatParquet, Pricing: pricing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, n, err := exporter.Export(start.Add(30 * time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("Export() = %q, %d, %v; want 1 record", path, n, err)
+	}
+	exported, err := parquet.ReadFile[Record](path)
+	if err != nil || len(exported) != 1 || exported[0].TraceID != "t1" || exported[0].CostUSD != 0.007 || !exported[0].CreatedAt.Equal(start) {
+		t.Errorf("Parquet records = %+v, %v", exported, err)
+	}
+
+	exporter, err = NewExporter(store, ExportConfig{Dir: dir, Format: FormatParquet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, n, err := exporter.Export(start.Add(2 * time.Hour)); err != nil || n != 1 {
+		t.Errorf("Export() after restart = %d records, %v; want only t2", n, err)
+	}
+}
+
+func TestPromptHistory(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, v2 := Fingerprint("You are helpful."), Fingerprint("You are terse.")
+	if v1 == v2 || v1 != Fingerprint("You are helpful.") {
+		t.Fatalf("Fingerprint() = %q, %q", v1, v2)
+	}
+	for _, p := range []string{"You are helpful.", "You are terse."} {
+		if err := store.SavePrompt(Fingerprint(p), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, tr := range []Trace{
+		{ID: "t1", Model: "gpt-4o", PromptFingerprint: v1},
+		{ID: "t2", Model: "llama3.1", PromptFingerprint: v1},
+		{ID: "t3", Model: "gpt-4o", PromptFingerprint: v2},
+		{ID: "t4", Model: "gpt-4o"},
+	} {
+		tr.CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		if err := store.RecordTrace(ctx, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := store.PromptHistory(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Fingerprint != v1 || history[0].Traces != 2 ||
+		!slices.Equal(history[0].Models, []string{"gpt-4o", "llama3.1"}) || !history[0].LastSeen.Equal(start.Add(time.Hour)) ||
+		history[1].Fingerprint != v2 {
+		t.Errorf("PromptHistory() = %+v", history)
+	}
+
+	fp, text, err := store.Prompt(v2[:6])
+	if err != nil || fp != v2 || text != "You are terse." {
+		t.Errorf("Prompt(prefix) = %q, %q, %v", fp, text, err)
+	}
+	if _, _, err := store.Prompt("../x"); err == nil {
+		t.Error("Prompt() should reject paths")
+	}
+}
@@ -0,0 +1,252 @@
+package eval
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Export formats.
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// Record is an exported trace: usage, latency, tool calls, and estimated
+// cost, without message content.
+type Record struct {
+	CreatedAt         time.Time `parquet:"created_at,timestamp(millisecond)"`
+	TraceID           string    `parquet:"trace_id"`
+	SessionID         string    `parquet:"session_id"`
+	Channel           string    `parquet:"channel"`
+	Provider          string    `parquet:"provider"`
+	Model             string    `parquet:"model"`
+	Experiment        string    `parquet:"experiment"`
+	Variant           string    `parquet:"variant"`
+	PromptTokens      int64     `parquet:"prompt_tokens"`
+	CompletionTokens  int64     `parquet:"completion_tokens"`
+	LatencyMS         int64     `parquet:"latency_ms"`
+	ToolCalls         int64     `parquet:"tool_calls"`
+	Tools             string    `parquet:"tools"` // Tool names, separated by spaces
+	Error             string    `parquet:"error"`
+	CostUSD           float64   `parquet:"cost_usd"` // 0 when the model is unpriced
+	PromptFingerprint string    `parquet:"prompt_fingerprint"`
+}
+
+// csvHeader names the CSV columns, in Record order.
+var csvHeader = []string{
+	"created_at", "trace_id", "session_id", "channel", "provider", "model", "experiment", "variant",
+	"prompt_tokens", "completion_tokens", "latency_ms", "tool_calls", "tools", "error", "cost_usd", "prompt_fingerprint",
+}
+
+// Records returns traces created at or after since as export records,
+// estimating cost with pricing (which may be nil).
+func (s *Store) Records(since time.Time, pricing Pricing) ([]Record, error) {
+	traces, err := s.Traces(since)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(traces))
+	for _, t := range traces {
+		records = append(records, Record{
+			CreatedAt:         t.CreatedAt.UTC(),
+			TraceID:           t.ID,
+			SessionID:         t.SessionID,
+			Channel:           t.Channel,
+			Provider:          t.Provider,
+			Model:             t.Model,
+			Experiment:        t.Experiment,
+			Variant:           t.Variant,
+			PromptFingerprint: t.PromptFingerprint,
+			PromptTokens:      int64(t.PromptTokens),
+			CompletionTokens:  int64(t.CompletionTokens),
+			LatencyMS:         t.Duration.Milliseconds(),
+			ToolCalls:         int64(len(t.Tools)),
+			Tools:             strings.Join(t.Tools, " "),
+			Error:             t.Error,
+			CostUSD:           pricing.Cost(t.Model, t.PromptTokens, t.CompletionTokens),
+		})
+	}
+	return records, nil
+}
+
+// WriteRecords writes records to w as CSV or Parquet.
+func WriteRecords(w io.Writer, format string, records []Record) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, records)
+	case FormatParquet:
+		if err := parquet.Write(w, records); err != nil {
+			return fmt.Errorf("write parquet: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown export format %q (use csv or parquet)", format)
+	}
+}
+
+// writeCSV writes records with a header row.
+func writeCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	for _, r := range records {
+		row := []string{
+			r.CreatedAt.Format(time.RFC3339),
+			r.TraceID,
+			r.SessionID,
+			r.Channel,
+			r.Provider,
+			r.Model,
+			r.Experiment,
+			r.Variant,
+			strconv.FormatInt(r.PromptTokens, 10),
+			strconv.FormatInt(r.CompletionTokens, 10),
+			strconv.FormatInt(r.LatencyMS, 10),
+			strconv.FormatInt(r.ToolCalls, 10),
+			r.Tools,
+			r.Error,
+			strconv.FormatFloat(r.CostUSD, 'f', -1, 64),
+			r.PromptFingerprint,
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("write csv: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return nil
+}
+
+// exportPrefix starts the names of scheduled export files, which end with
+// the time the export covers up to.
+const exportPrefix = "traces-"
+
+// exportTimeLayout is the time in export file names.
+const exportTimeLayout = "20060102T150405Z"
+
+// ExportConfig configures scheduled exports.
+type ExportConfig struct {
+	Dir      string        // Where export files are written
+	Format   string        // FormatCSV or FormatParquet (default: csv)
+	Interval time.Duration // Time between exports (default: 24h)
+	Pricing  Pricing
+	Logger   *slog.Logger
+}
+
+// Exporter periodically writes the traces recorded since the previous
+// export to a new file in a directory.
+type Exporter struct {
+	store  *Store
+	config ExportConfig
+	last   time.Time // End of the previous export
+}
+
+// NewExporter creates an exporter. It resumes after the newest export
+// file already in the directory, or exports every trace the first time.
+func NewExporter(store *Store, config ExportConfig) (*Exporter, error) {
+	if config.Format == "" {
+		config.Format = FormatCSV
+	}
+	if config.Format != FormatCSV && config.Format != FormatParquet {
+		return nil, fmt.Errorf("unknown export format %q (use csv or parquet)", config.Format)
+	}
+	if config.Interval <= 0 {
+		config.Interval = 24 * time.Hour
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create export dir: %w", err)
+	}
+
+	e := &Exporter{store: store, config: config}
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("read export dir: %w", err)
+	}
+	for _, entry := range entries {
+		name := strings.TrimPrefix(entry.Name(), exportPrefix)
+		if name == entry.Name() {
+			continue
+		}
+		stamp, _, _ := strings.Cut(name, ".")
+		if t, err := time.Parse(exportTimeLayout, stamp); err == nil && t.After(e.last) {
+			e.last = t
+		}
+	}
+	return e, nil
+}
+
+// Run exports on every interval until ctx is canceled.
+func (e *Exporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		path, n, err := e.Export(time.Now())
+		switch {
+		case err != nil:
+			e.config.Logger.Warn("trace export failed", "error", err)
+		case n > 0:
+			e.config.Logger.Info("traces exported", "file", path, "records", n)
+		}
+	}
+}
+
+// Export writes the traces recorded after the previous export and up to
+// now to a new file, returning its path and the number of records. No
+// file is written when there is nothing new.
+func (e *Exporter) Export(now time.Time) (string, int, error) {
+	now = now.UTC().Truncate(time.Second)
+	records, err := e.store.Records(e.last, e.config.Pricing)
+	if err != nil {
+		return "", 0, err
+	}
+	records = slices.DeleteFunc(records, func(r Record) bool {
+		return !r.CreatedAt.After(e.last) || r.CreatedAt.After(now)
+	})
+	if len(records) == 0 {
+		e.last = now
+		return "", 0, nil
+	}
+
+	path := filepath.Join(e.config.Dir, exportPrefix+now.Format(exportTimeLayout)+"."+e.config.Format)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gosec // G304: Path is within the configured export directory
+	if err != nil {
+		return "", 0, fmt.Errorf("create export: %w", err)
+	}
+	if err := WriteRecords(f, e.config.Format, records); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return "", 0, fmt.Errorf("write export: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", 0, fmt.Errorf("write export: %w", err)
+	}
+	e.last = now
+	return path, len(records), nil
+}
@@ -0,0 +1,106 @@
+package eval
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// promptsDir holds each fingerprinted prompt's text, named by fingerprint.
+const promptsDir = "prompts"
+
+// PromptVersion is a system prompt fingerprint and when traces used it.
+type PromptVersion struct {
+	Fingerprint string    `json:"fingerprint"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Traces      int       `json:"traces"`
+	Models      []string  `json:"models,omitempty"`
+}
+
+// Fingerprint returns a short content hash of a system prompt, so
+// behavior changes can be matched to prompt changes.
+func Fingerprint(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:6])
+}
+
+// SavePrompt stores a prompt's text under its fingerprint, once.
+func (s *Store) SavePrompt(fingerprint, prompt string) error {
+	if fingerprint == "" || strings.ContainsAny(fingerprint, `/\.`) {
+		return fmt.Errorf("invalid fingerprint %q", fingerprint)
+	}
+	path := filepath.Join(s.dir, promptsDir, fingerprint+".txt")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("create prompts dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(prompt), 0o600); err != nil {
+		return fmt.Errorf("write prompt: %w", err)
+	}
+	return nil
+}
+
+// Prompt returns the text stored for a fingerprint or a unique prefix of
+// one.
+func (s *Store) Prompt(fingerprint string) (string, string, error) {
+	if fingerprint == "" || strings.ContainsAny(fingerprint, `/\.`) {
+		return "", "", fmt.Errorf("invalid fingerprint %q", fingerprint)
+	}
+	matches, err := filepath.Glob(filepath.Join(s.dir, promptsDir, fingerprint+"*.txt"))
+	if err != nil {
+		return "", "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", "", fmt.Errorf("no prompt with fingerprint %q", fingerprint)
+	case 1:
+	default:
+		return "", "", fmt.Errorf("fingerprint %q is ambiguous", fingerprint)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		return "", "", fmt.Errorf("read prompt: %w", err)
+	}
+	return strings.TrimSuffix(filepath.Base(matches[0]), ".txt"), string(data), nil
+}
+
+// PromptHistory returns the prompt fingerprints of traces created at or
+// after since, oldest first.
+func (s *Store) PromptHistory(since time.Time) ([]PromptVersion, error) {
+	traces, err := s.Traces(since)
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string]*PromptVersion)
+	var order []string
+	for _, t := range traces {
+		if t.PromptFingerprint == "" {
+			continue
+		}
+		v, ok := byHash[t.PromptFingerprint]
+		if !ok {
+			v = &PromptVersion{Fingerprint: t.PromptFingerprint, FirstSeen: t.CreatedAt}
+			byHash[t.PromptFingerprint] = v
+			order = append(order, t.PromptFingerprint)
+		}
+		v.LastSeen = t.CreatedAt
+		v.Traces++
+		if t.Model != "" && !slices.Contains(v.Models, t.Model) {
+			v.Models = append(v.Models, t.Model)
+		}
+	}
+
+	out := make([]PromptVersion, 0, len(order))
+	for _, h := range order {
+		out = append(out, *byHash[h])
+	}
+	return out, nil
+}
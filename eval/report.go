@@ -0,0 +1,154 @@
+package eval
+
+import (
+	"sort"
+	"time"
+)
+
+// Group aggregates feedback for one value of a report dimension.
+type Group struct {
+	Key    string
+	Traces int // Traces in the period
+	Rated  int // Traces with at least one rating
+	Up     int
+	Down   int
+	Errors int
+
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64 // Estimated USD; 0 when models are unpriced
+}
+
+// Price is a model's cost in USD per million tokens.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Pricing maps model names to prices for cost estimates.
+type Pricing map[string]Price
+
+// Cost estimates the USD cost of a request. Unknown models cost 0.
+func (p Pricing) Cost(model string, promptTokens, completionTokens int) float64 {
+	price, ok := p[model]
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1e6
+}
+
+// Approval returns the share of ratings that were positive, or -1 if unrated.
+func (g Group) Approval() float64 {
+	if g.Up+g.Down == 0 {
+		return -1
+	}
+	return float64(g.Up) / float64(g.Up+g.Down)
+}
+
+// Report summarizes quality by model, skill, channel, and experiment variant.
+type Report struct {
+	Since     time.Time
+	Total     Group
+	ByModel   []Group
+	BySkill   []Group
+	ByChannel []Group
+	ByVariant []Group // Keyed "experiment/variant"; traces outside experiments are omitted
+}
+
+// Report aggregates traces created at or after since with their feedback,
+// estimating cost with pricing (which may be nil).
+func (s *Store) Report(since time.Time, pricing Pricing) (Report, error) {
+	traces, err := s.Traces(since)
+	if err != nil {
+		return Report{}, err
+	}
+	feedback, err := s.Feedback()
+	if err != nil {
+		return Report{}, err
+	}
+
+	// Latest rating per trace wins so users can change their mind
+	ratings := make(map[string]int)
+	for _, f := range feedback {
+		ratings[f.TraceID] = f.Rating
+	}
+
+	total := Group{Key: "all"}
+	byModel := map[string]*Group{}
+	bySkill := map[string]*Group{}
+	byChannel := map[string]*Group{}
+	byVariant := map[string]*Group{}
+
+	for _, t := range traces {
+		rating, rated := ratings[t.ID]
+
+		channel := t.Channel
+		if channel == "" {
+			channel = "gateway"
+		}
+		skills := t.Skills
+		if len(skills) == 0 {
+			skills = []string{"(none)"}
+		}
+
+		groups := []*Group{&total, groupFor(byModel, t.Model), groupFor(byChannel, channel)}
+		for _, sk := range skills {
+			groups = append(groups, groupFor(bySkill, sk))
+		}
+		if t.Experiment != "" {
+			groups = append(groups, groupFor(byVariant, t.Experiment+"/"+t.Variant))
+		}
+		cost := pricing.Cost(t.Model, t.PromptTokens, t.CompletionTokens)
+
+		for _, g := range groups {
+			g.Traces++
+			g.PromptTokens += t.PromptTokens
+			g.CompletionTokens += t.CompletionTokens
+			g.Cost += cost
+			if t.Error != "" {
+				g.Errors++
+			}
+			if rated {
+				g.Rated++
+				if rating == RatingUp {
+					g.Up++
+				} else {
+					g.Down++
+				}
+			}
+		}
+	}
+
+	return Report{
+		Since:     since,
+		Total:     total,
+		ByModel:   sortedGroups(byModel),
+		BySkill:   sortedGroups(bySkill),
+		ByChannel: sortedGroups(byChannel),
+		ByVariant: sortedGroups(byVariant),
+	}, nil
+}
+
+func groupFor(m map[string]*Group, key string) *Group {
+	g, ok := m[key]
+	if !ok {
+		g = &Group{Key: key}
+		m[key] = g
+	}
+	return g
+}
+
+// sortedGroups orders groups by trace count, then key.
+func sortedGroups(m map[string]*Group) []Group {
+	out := make([]Group, 0, len(m))
+	for _, g := range m {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Traces != out[j].Traces {
+			return out[i].Traces > out[j].Traces
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
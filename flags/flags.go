@@ -0,0 +1,200 @@
+// Package flags provides feature flags that gate experimental subsystems.
+// Flags default to the configured values and can be overridden at runtime,
+// for example through the gateway admin API, without a restart.
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Feature flags.
+const (
+	Streaming = "streaming" // Streaming speech-to-text and tool progress events
+	Memory    = "memory"    // Memory recall and the remember and recall tools
+)
+
+// toolPrefix starts flags that gate one tool, such as "tool.browser".
+const toolPrefix = "tool."
+
+// featureTools are the tools each feature flag gates.
+var featureTools = map[string][]string{
+	Memory: {"remember", "recall"},
+}
+
+// Tool returns the flag that gates the named tool.
+func Tool(name string) string {
+	return toolPrefix + name
+}
+
+// Flag is a flag's current state.
+type Flag struct {
+	Name       string `json:"name"`
+	Enabled    bool   `json:"enabled"`
+	Default    bool   `json:"default"`
+	Overridden bool   `json:"overridden"` // Set at runtime
+}
+
+// Flags holds feature flags. Flags that are neither configured nor
+// overridden are enabled, so a flag only turns a feature off. A nil *Flags
+// enables everything.
+type Flags struct {
+	defaults  map[string]bool
+	overrides map[string]bool
+	path      string
+	mu        sync.RWMutex
+}
+
+// New creates flags with the configured defaults. If path is non-empty,
+// runtime overrides are loaded from and saved to that file, so they
+// survive restarts until reset.
+func New(defaults map[string]bool, path string) (*Flags, error) {
+	f := &Flags{
+		defaults:  make(map[string]bool, len(defaults)),
+		overrides: make(map[string]bool),
+		path:      path,
+	}
+	for name, enabled := range defaults {
+		f.defaults[normalize(name)] = enabled
+	}
+	if path == "" {
+		return f, nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("read flags: %w", err)
+	}
+	if err := json.Unmarshal(data, &f.overrides); err != nil {
+		return nil, fmt.Errorf("parse flags: %w", err)
+	}
+	return f, nil
+}
+
+// SetDefaults replaces the configured defaults, as on a config reload.
+// Runtime overrides are kept.
+func (f *Flags) SetDefaults(defaults map[string]bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.defaults = make(map[string]bool, len(defaults))
+	for name, enabled := range defaults {
+		f.defaults[normalize(name)] = enabled
+	}
+}
+
+// Enabled reports whether a flag is on.
+func (f *Flags) Enabled(name string) bool {
+	if f == nil {
+		return true
+	}
+	return f.Get(name).Enabled
+}
+
+// ToolEnabled reports whether a tool is on: its own flag and the flags of
+// any feature it belongs to.
+func (f *Flags) ToolEnabled(name string) bool {
+	if !f.Enabled(Tool(name)) {
+		return false
+	}
+	for feature, tools := range featureTools {
+		if slices.Contains(tools, name) && !f.Enabled(feature) {
+			return false
+		}
+	}
+	return true
+}
+
+// Set overrides a flag until it is reset.
+func (f *Flags) Set(name string, enabled bool) error {
+	name = normalize(name)
+	if name == "" {
+		return fmt.Errorf("flag name required")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.overrides[name] = enabled
+	return f.save()
+}
+
+// Reset removes a flag's override, returning it to the configured value.
+func (f *Flags) Reset(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.overrides, normalize(name))
+	return f.save()
+}
+
+// List returns the known flags, sorted by name: the built-in features and
+// every configured or overridden flag.
+func (f *Flags) List() []Flag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names := []string{Streaming, Memory}
+	for name := range f.defaults {
+		names = append(names, name)
+	}
+	for name := range f.overrides {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	out := make([]Flag, 0, len(names))
+	for _, name := range names {
+		out = append(out, f.flag(name))
+	}
+	return out
+}
+
+// Get returns a flag's state.
+func (f *Flags) Get(name string) Flag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flag(normalize(name))
+}
+
+// flag returns a flag's state. Callers must hold the lock.
+func (f *Flags) flag(name string) Flag {
+	def, ok := f.defaults[name]
+	if !ok {
+		def = true
+	}
+	enabled, overridden := f.overrides[name]
+	if !overridden {
+		enabled = def
+	}
+	return Flag{Name: name, Enabled: enabled, Default: def, Overridden: overridden}
+}
+
+// save writes the overrides file. Callers must hold the write lock.
+func (f *Flags) save() error {
+	if f.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(f.overrides, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode flags: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o750); err != nil {
+		return fmt.Errorf("create flags dir: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write flags: %w", err)
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// normalize lowercases a flag name and trims spaces.
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,63 @@
+package flags
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	f, err := New(map[string]bool{"Streaming": false, "tool.browser": false}, path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if f.Enabled(Streaming) {
+		t.Error("streaming enabled, want configured off")
+	}
+	if !f.Enabled(Memory) || !f.Enabled("unknown") {
+		t.Error("unconfigured flags should be on")
+	}
+	if f.ToolEnabled("browser") || !f.ToolEnabled("shell") {
+		t.Error("tool.browser should gate only the browser tool")
+	}
+
+	if err := f.Set(Memory, false); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if f.Enabled(Memory) || f.ToolEnabled("remember") {
+		t.Error("memory off should disable memory and its tools")
+	}
+
+	// Overrides survive a restart until reset
+	f, err = New(map[string]bool{"streaming": false}, path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := f.Get(Memory); got.Enabled || !got.Default || !got.Overridden {
+		t.Errorf("Get(memory) = %+v after restart", got)
+	}
+	if err := f.Reset(Memory); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if !f.Enabled(Memory) {
+		t.Error("memory still off after reset")
+	}
+	if got := len(f.List()); got != 2 {
+		t.Errorf("List() has %d flags, want 2", got)
+	}
+
+	// Reloaded defaults keep runtime overrides
+	if err := f.Set(Memory, false); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	f.SetDefaults(map[string]bool{"streaming": true})
+	if !f.Enabled(Streaming) || f.Enabled(Memory) {
+		t.Error("SetDefaults() should turn streaming on and keep the memory override")
+	}
+
+	var none *Flags
+	if !none.Enabled(Streaming) || !none.ToolEnabled("browser") {
+		t.Error("nil flags should enable everything")
+	}
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/connections"
+	"github.com/plexusone/omniagent/errlog"
+)
+
+// ClientInfo describes a connected WebSocket client.
+type ClientInfo struct {
+	ID            string    `json:"id"`
+	Role          string    `json:"role"`
+	Authenticated bool      `json:"authenticated"`
+	RemoteAddr    string    `json:"remote_addr,omitempty"`
+	ConnectedAt   time.Time `json:"connected_at"`
+}
+
+// handleListClients handles GET /v1/admin/clients.
+func (g *Gateway) handleListClients(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	g.mu.RLock()
+	clients := make([]ClientInfo, 0, len(g.clients))
+	for _, c := range g.clients {
+		authenticated, _ := c.GetMetadata("authenticated")
+		ok, _ := authenticated.(bool)
+		clients = append(clients, ClientInfo{
+			ID:            c.ID,
+			Role:          clientRole(c),
+			Authenticated: ok,
+			RemoteAddr:    c.remoteAddr,
+			ConnectedAt:   c.connectedAt,
+		})
+	}
+	g.mu.RUnlock()
+	slices.SortFunc(clients, func(a, b ClientInfo) int { return a.ConnectedAt.Compare(b.ConnectedAt) })
+	writeHTTPJSON(w, http.StatusOK, map[string][]ClientInfo{"clients": clients})
+}
+
+// handleListChannels handles GET /v1/admin/channels.
+func (g *Gateway) handleListChannels(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	channels := g.config.Channels.Statuses()
+	if channels == nil {
+		channels = []connections.Status{}
+	}
+	writeHTTPJSON(w, http.StatusOK, map[string][]connections.Status{"channels": channels})
+}
+
+// handleRecentErrors handles GET /v1/admin/errors, returning the latest
+// logged errors, newest first. The limit query parameter caps how many.
+func (g *Gateway) handleRecentErrors(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	limit := 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeHTTPError(w, http.StatusBadRequest, "limit must be a non-negative number")
+			return
+		}
+		limit = n
+	}
+	writeHTTPJSON(w, http.StatusOK, map[string][]errlog.Entry{"errors": g.config.Errors.Recent(limit)})
+}
+
+// handleReload handles POST /v1/admin/reload, re-reading the config file
+// and applying the settings that can change without a restart.
+func (g *Gateway) handleReload(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	applied, err := g.config.Reload()
+	if err != nil {
+		g.logger.Error("config reload failed", "error", err)
+		writeHTTPError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if applied == nil {
+		applied = []string{}
+	}
+	g.logger.Warn("config reloaded", "applied", strings.Join(applied, ", "))
+	writeHTTPJSON(w, http.StatusOK, map[string][]string{"applied": applied})
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/plexusone/omniagent/approvals"
+)
+
+// RoleApprover may decide tool calls waiting for approval.
+const RoleApprover = "approver"
+
+// ApprovalsChannel is the subscription channel for approval requests.
+const ApprovalsChannel = "approvals"
+
+// ApproverConfig grants a token the right to approve or deny tool calls.
+// Decisions are audited as "gateway:<name>".
+type ApproverConfig struct {
+	Name  string
+	Token string //nolint:gosec // G117: Token loaded from config file
+}
+
+// approverMessageTypes are the only messages approvers may send.
+var approverMessageTypes = []MessageType{MessageTypePing, MessageTypeAuth, MessageTypeSubscribe, MessageTypeApproval}
+
+// errNoApprovers is returned when no approver client is listening.
+var errNoApprovers = errors.New("no gateway approvers subscribed")
+
+// approverFor returns the approver whose token matches, if any.
+func (g *Gateway) approverFor(token string) *ApproverConfig {
+	if token == "" {
+		return nil
+	}
+	for i := range g.config.Approvers {
+		a := &g.config.Approvers[i]
+		if tokenEqual(token, a.Token) {
+			return a
+		}
+	}
+	return nil
+}
+
+// NotifyApproval sends an approval request to subscribed approver clients.
+// Pass it to approvals.Manager.OnRequest.
+func (g *Gateway) NotifyApproval(_ context.Context, req approvals.Request) error {
+	msg := NewEventMessage("approval.request", ApprovalsChannel, map[string]interface{}{
+		"id":         req.ID,
+		"tool":       req.Tool,
+		"args":       string(req.Args),
+		"session_id": req.SessionID,
+		"persona":    req.Persona,
+	})
+	msg.Timestamp = req.Created
+
+	sent := false
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, client := range g.clients {
+		if clientRole(client) != RoleApprover || !client.subscribed(ApprovalsChannel) {
+			continue
+		}
+		client.Send(msg)
+		sent = true
+	}
+	if !sent {
+		return errNoApprovers
+	}
+	return nil
+}
+
+// handleApproval decides a pending tool call.
+func (h *DefaultMessageHandler) handleApproval(_ context.Context, client *Client, msg *Message) (*Message, error) {
+	v, _ := client.GetMetadata("approver")
+	approver, ok := v.(*ApproverConfig)
+	if !ok || h.gateway.config.Approvals == nil {
+		return NewErrorMessage(msg.ID, "forbidden: approval requires the approver role"), nil
+	}
+	id, _ := msg.Data["id"].(string)
+	approved, _ := msg.Data["approved"].(bool)
+	if id == "" {
+		return NewErrorMessage(msg.ID, "id required"), nil
+	}
+
+	req, err := h.gateway.config.Approvals.Resolve(id, approved, "gateway:"+approver.Name)
+	if err != nil {
+		return NewErrorMessage(msg.ID, err.Error()), nil
+	}
+	return &Message{
+		ID:   msg.ID,
+		Type: MessageTypeResponse,
+		Data: map[string]interface{}{
+			"id":       req.ID,
+			"tool":     req.Tool,
+			"approved": approved,
+		},
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// subscribed reports whether the client subscribed to channel.
+func (c *Client) subscribed(channel string) bool {
+	subs, _ := c.GetMetadata("subscriptions")
+	list, _ := subs.([]string)
+	return slices.Contains(list, channel)
+}
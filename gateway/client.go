@@ -34,6 +34,9 @@ type Client struct {
 	once     sync.Once
 	metadata map[string]interface{}
 	mu       sync.RWMutex
+
+	remoteAddr  string
+	connectedAt time.Time
 }
 
 // newClient creates a new client.
@@ -45,6 +48,9 @@ func newClient(conn *websocket.Conn, gateway *Gateway) *Client {
 		send:     make(chan *Message, 256),
 		done:     make(chan struct{}),
 		metadata: make(map[string]interface{}),
+
+		remoteAddr:  conn.RemoteAddr().String(),
+		connectedAt: time.Now(),
 	}
 }
 
@@ -83,8 +89,9 @@ func (c *Client) GetMetadata(key string) (interface{}, bool) {
 	return v, ok
 }
 
-// readPump reads messages from the WebSocket connection.
-func (c *Client) readPump() {
+// readPump reads messages from the WebSocket connection until it closes.
+// Messages are handled with ctx, which is canceled at shutdown.
+func (c *Client) readPump(ctx context.Context) error {
 	defer c.Close()
 
 	c.conn.SetReadLimit(maxMessageSize)
@@ -99,7 +106,7 @@ func (c *Client) readPump() {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				c.gateway.logger.Error("websocket read error", "client", c.ID, "error", err)
 			}
-			return
+			return nil
 		}
 
 		var msg Message
@@ -110,7 +117,6 @@ func (c *Client) readPump() {
 
 		// Handle message
 		if c.gateway.onMessage != nil {
-			ctx := context.Background()
 			response, err := c.gateway.onMessage(ctx, c, &msg)
 			if err != nil {
 				c.gateway.logger.Error("message handler error", "client", c.ID, "error", err)
@@ -127,8 +133,9 @@ func (c *Client) readPump() {
 	}
 }
 
-// writePump writes messages to the WebSocket connection.
-func (c *Client) writePump() {
+// writePump writes messages to the WebSocket connection until the client
+// closes or ctx is canceled.
+func (c *Client) writePump(ctx context.Context) error {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
@@ -141,7 +148,7 @@ func (c *Client) writePump() {
 			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
+				return nil
 			}
 
 			data, err := json.Marshal(msg)
@@ -152,17 +159,20 @@ func (c *Client) writePump() {
 
 			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
 				c.gateway.logger.Error("websocket write error", "client", c.ID, "error", err)
-				return
+				return nil
 			}
 
 		case <-ticker.C:
 			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				return
+				return nil
 			}
 
 		case <-c.done:
-			return
+			return nil
+
+		case <-ctx.Done():
+			return nil
 		}
 	}
 }
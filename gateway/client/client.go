@@ -0,0 +1,547 @@
+// Package client is a Go client for the omniagent WebSocket gateway. It
+// handles authentication, request correlation, tool progress streams, and
+// event subscriptions, and reconnects with backoff when the connection
+// drops.
+//
+// The package depends only on the wire format, not on the gateway itself,
+// so integrators can import it without pulling in the agent.
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrClosed is returned by calls on a closed client.
+var ErrClosed = errors.New("gateway client closed")
+
+// ErrDisconnected is returned for requests in flight when the connection
+// drops. The request may or may not have been processed.
+var ErrDisconnected = errors.New("gateway connection lost")
+
+// Backoff defaults.
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// Message is a gateway protocol message.
+type Message struct {
+	ID        string         `json:"id,omitempty"`
+	Type      string         `json:"type"`
+	Channel   string         `json:"channel,omitempty"`
+	Content   string         `json:"content,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Timestamp time.Time      `json:"timestamp,omitzero"`
+}
+
+// Event is an event pushed by the gateway, such as "tool.progress",
+// "activity.reply", or "approval.request".
+type Event struct {
+	Name      string
+	Channel   string
+	Data      map[string]any
+	Timestamp time.Time
+}
+
+// Reply is the agent's answer to a chat message.
+type Reply struct {
+	Content     string
+	TraceID     string // Set when tracing is enabled; used for Feedback
+	Variant     string // Experiment variant, if any
+	Attachments []Attachment
+}
+
+// Attachment is a file returned with a reply.
+type Attachment struct {
+	Filename string
+	MimeType string
+	Data     []byte
+}
+
+// Progress is output streamed by a long-running tool during a chat.
+type Progress struct {
+	Tool string
+	Text string
+}
+
+// Error is an error message returned by the gateway.
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return "gateway: " + e.Message
+}
+
+// State is the connection state reported to Options.OnState.
+type State int
+
+// Connection states.
+const (
+	StateConnected State = iota
+	StateDisconnected
+)
+
+// Options configures a Client.
+type Options struct {
+	// Token authenticates the client. Observer and approver tokens give
+	// those roles.
+	Token    string
+	DeviceID string
+
+	// Subscriptions are channels subscribed on every connect.
+	Subscriptions []string
+
+	// OnEvent receives events not tied to a chat request. It is called
+	// from the read loop and must not block.
+	OnEvent func(Event)
+
+	// OnState reports connection changes.
+	OnState func(State)
+
+	// MinBackoff and MaxBackoff bound the delay between reconnect attempts
+	// (defaults: 500ms and 30s).
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	Header http.Header
+	Dialer *websocket.Dialer
+	Logger *slog.Logger
+}
+
+// Client is a connection to the gateway. It is safe for concurrent use.
+type Client struct {
+	url  string
+	opts Options
+
+	mu        sync.Mutex
+	conn      *websocket.Conn
+	connected chan struct{} // Closed while connected
+	clientID  string
+	subs      []string
+	metadata  map[string]any
+	pending   map[string]*call
+	closed    bool
+
+	writeMu sync.Mutex
+	nextID  atomic.Uint64
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// call is a request waiting for its response.
+type call struct {
+	resp     chan Message
+	progress func(Progress)
+}
+
+// Dial connects to the gateway at url (for example ws://127.0.0.1:18789/ws)
+// and authenticates. The client reconnects on its own until Close.
+func Dial(ctx context.Context, url string, opts Options) (*Client, error) {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.MinBackoff)
+	}
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	c := &Client{
+		url:       url,
+		opts:      opts,
+		connected: make(chan struct{}),
+		subs:      append([]string(nil), opts.Subscriptions...),
+		pending:   make(map[string]*call),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go c.run(conn)
+	return c, nil
+}
+
+// ClientID returns the ID the gateway assigned to the current connection.
+// The gateway keys conversations by it, so it changes after a reconnect.
+func (c *Client) ClientID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clientID
+}
+
+// SetMetadata sets context, such as location or the current page, sent
+// with every following chat message. The agent keeps it on the
+// conversation and exposes its configured context keys to the model.
+func (c *Client) SetMetadata(md map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metadata = md
+}
+
+// Chat sends a message to the agent and waits for its reply. progress, if
+// not nil, receives tool output streamed while the agent works.
+func (c *Client) Chat(ctx context.Context, content string, progress func(Progress)) (*Reply, error) {
+	msg := Message{Type: "chat", Content: content}
+	c.mu.Lock()
+	if len(c.metadata) > 0 {
+		msg.Data = map[string]any{"metadata": c.metadata}
+	}
+	c.mu.Unlock()
+
+	resp, err := c.do(ctx, msg, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &Reply{Content: resp.Content}
+	reply.TraceID, _ = resp.Data["trace_id"].(string)
+	reply.Variant, _ = resp.Data["variant"].(string)
+	list, _ := resp.Data["attachments"].([]any)
+	for _, item := range list {
+		a, _ := item.(map[string]any)
+		encoded, _ := a["data"].(string)
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode attachment: %w", err)
+		}
+		att := Attachment{Data: data}
+		att.Filename, _ = a["filename"].(string)
+		att.MimeType, _ = a["mime_type"].(string)
+		reply.Attachments = append(reply.Attachments, att)
+	}
+	return reply, nil
+}
+
+// Subscribe subscribes to a channel, such as "activity" for observers or
+// "approvals" for approvers. Subscriptions are renewed after reconnects.
+func (c *Client) Subscribe(ctx context.Context, channel string) error {
+	if _, err := c.do(ctx, Message{Type: "subscribe", Channel: channel}, nil); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.subs {
+		if s == channel {
+			return nil
+		}
+	}
+	c.subs = append(c.subs, channel)
+	return nil
+}
+
+// Feedback rates a traced reply "up" or "down".
+func (c *Client) Feedback(ctx context.Context, traceID, rating, comment string) error {
+	_, err := c.do(ctx, Message{Type: "feedback", Data: map[string]any{
+		"trace_id": traceID,
+		"rating":   rating,
+		"comment":  comment,
+	}}, nil)
+	return err
+}
+
+// Decide approves or denies a tool call announced by an
+// "approval.request" event. It requires an approver token.
+func (c *Client) Decide(ctx context.Context, id string, approved bool) error {
+	_, err := c.do(ctx, Message{Type: "approval", Data: map[string]any{
+		"id":       id,
+		"approved": approved,
+	}}, nil)
+	return err
+}
+
+// Request sends any message and waits for its response. Gateway errors are
+// returned as *Error.
+func (c *Client) Request(ctx context.Context, msg Message) (Message, error) {
+	return c.do(ctx, msg, nil)
+}
+
+// Close closes the connection and stops reconnecting.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn := c.conn
+	c.mu.Unlock()
+
+	close(c.done)
+	var err error
+	if conn != nil {
+		c.writeMu.Lock()
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		c.writeMu.Unlock()
+		if err = conn.Close(); errors.Is(err, net.ErrClosed) {
+			err = nil // The read loop closed it first
+		}
+	}
+	<-c.stopped
+	return err
+}
+
+// do sends msg once connected and waits for the response with its ID.
+func (c *Client) do(ctx context.Context, msg Message, progress func(Progress)) (Message, error) {
+	msg.ID = "c" + strconv.FormatUint(c.nextID.Add(1), 10)
+	cl := &call{resp: make(chan Message, 1), progress: progress}
+
+	conn, err := c.waitConnected(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	c.mu.Lock()
+	c.pending[msg.ID] = cl
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, msg.ID)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(conn, msg); err != nil {
+		return Message{}, ErrDisconnected
+	}
+
+	select {
+	case resp, ok := <-cl.resp:
+		if !ok {
+			return Message{}, ErrDisconnected
+		}
+		if resp.Type == "error" {
+			return resp, &Error{Message: resp.Error}
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	case <-c.done:
+		return Message{}, ErrClosed
+	}
+}
+
+// waitConnected returns the current connection, waiting for a reconnect.
+func (c *Client) waitConnected(ctx context.Context) (*websocket.Conn, error) {
+	for {
+		c.mu.Lock()
+		conn, connected, closed := c.conn, c.connected, c.closed
+		c.mu.Unlock()
+		if closed {
+			return nil, ErrClosed
+		}
+		if conn != nil {
+			return conn, nil
+		}
+		select {
+		case <-connected:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrClosed
+		}
+	}
+}
+
+func (c *Client) write(conn *websocket.Conn, msg Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return conn.WriteJSON(msg)
+}
+
+// connect dials the gateway, authenticates, and renews subscriptions
+// before the read loop starts, so responses are read inline.
+func (c *Client) connect(ctx context.Context) (*websocket.Conn, error) {
+	// The token is also checked in the handshake when the gateway has one
+	header := c.opts.Header.Clone()
+	if c.opts.Token != "" && header.Get("Authorization") == "" {
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("Authorization", "Bearer "+c.opts.Token)
+	}
+	conn, _, err := c.opts.Dialer.DialContext(ctx, c.url, header)
+	if err != nil {
+		return nil, fmt.Errorf("connect to gateway: %w", err)
+	}
+	// Abandon the handshake if ctx ends first
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	handshake := func(msg Message) (Message, error) {
+		msg.ID = "c" + strconv.FormatUint(c.nextID.Add(1), 10)
+		if err := conn.WriteJSON(msg); err != nil {
+			return Message{}, err
+		}
+		for {
+			var resp Message
+			if err := conn.ReadJSON(&resp); err != nil {
+				return Message{}, err
+			}
+			if resp.ID != msg.ID {
+				c.dispatch(resp)
+				continue
+			}
+			if resp.Type == "error" {
+				return resp, &Error{Message: resp.Error}
+			}
+			return resp, nil
+		}
+	}
+
+	resp, err := handshake(Message{Type: "auth", Data: map[string]any{
+		"token":     c.opts.Token,
+		"device_id": c.opts.DeviceID,
+	}})
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
+	c.mu.Lock()
+	subs := append([]string(nil), c.subs...)
+	c.mu.Unlock()
+	for _, channel := range subs {
+		if _, err := handshake(Message{Type: "subscribe", Channel: channel}); err != nil {
+			stop()
+			conn.Close()
+			return nil, fmt.Errorf("subscribe to %s: %w", channel, err)
+		}
+	}
+	if !stop() {
+		return nil, fmt.Errorf("connect to gateway: %w", ctx.Err())
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		conn.Close()
+		return nil, ErrClosed
+	}
+	c.clientID, _ = resp.Data["client_id"].(string)
+	c.conn = conn
+	close(c.connected)
+	c.mu.Unlock()
+	if c.opts.OnState != nil {
+		c.opts.OnState(StateConnected)
+	}
+	return conn, nil
+}
+
+// run reads messages and reconnects until Close.
+func (c *Client) run(conn *websocket.Conn) {
+	defer close(c.stopped)
+	for conn != nil {
+		c.read(conn)
+
+		c.mu.Lock()
+		c.conn = nil
+		c.connected = make(chan struct{})
+		for id, cl := range c.pending {
+			close(cl.resp)
+			delete(c.pending, id)
+		}
+		closed := c.closed
+		c.mu.Unlock()
+		if closed {
+			return
+		}
+		if c.opts.OnState != nil {
+			c.opts.OnState(StateDisconnected)
+		}
+		conn = c.reconnect()
+	}
+}
+
+// read dispatches messages until the connection fails.
+func (c *Client) read(conn *websocket.Conn) {
+	for {
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			select {
+			case <-c.done:
+			default:
+				c.opts.Logger.Warn("gateway connection lost", "error", err)
+			}
+			conn.Close()
+			return
+		}
+		c.dispatch(msg)
+	}
+}
+
+// dispatch routes a response to its caller and events to their handlers.
+func (c *Client) dispatch(msg Message) {
+	if msg.Type == "event" {
+		// Progress for a pending chat goes to that caller
+		if msg.Content == "tool.progress" {
+			id, _ := msg.Data["id"].(string)
+			c.mu.Lock()
+			cl := c.pending[id]
+			c.mu.Unlock()
+			if cl != nil && cl.progress != nil {
+				p := Progress{}
+				p.Tool, _ = msg.Data["tool"].(string)
+				p.Text, _ = msg.Data["text"].(string)
+				cl.progress(p)
+				return
+			}
+		}
+		if c.opts.OnEvent != nil {
+			c.opts.OnEvent(Event{Name: msg.Content, Channel: msg.Channel, Data: msg.Data, Timestamp: msg.Timestamp})
+		}
+		return
+	}
+
+	c.mu.Lock()
+	cl := c.pending[msg.ID]
+	delete(c.pending, msg.ID)
+	c.mu.Unlock()
+	if cl != nil {
+		cl.resp <- msg
+	}
+}
+
+// reconnect retries with jittered exponential backoff until it connects or
+// the client is closed, in which case it returns nil.
+func (c *Client) reconnect() *websocket.Conn {
+	backoff := c.opts.MinBackoff
+	for {
+		delay := backoff/2 + rand.N(backoff/2+1) //nolint:gosec // G404: Jitter needs no crypto randomness
+		select {
+		case <-time.After(delay):
+		case <-c.done:
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.MaxBackoff)
+		conn, err := c.connect(ctx)
+		cancel()
+		if err == nil {
+			return conn
+		}
+		if errors.Is(err, ErrClosed) {
+			return nil
+		}
+		c.opts.Logger.Warn("gateway reconnect failed", "error", err, "retry_in", backoff)
+		backoff = min(backoff*2, c.opts.MaxBackoff)
+	}
+}
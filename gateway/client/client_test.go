@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeGateway speaks enough of the gateway protocol to exercise the client.
+type fakeGateway struct {
+	mu    sync.Mutex
+	auths int
+	subs  []string
+}
+
+func (f *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for {
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		resp := Message{ID: msg.ID, Type: "response"}
+		switch msg.Type {
+		case "auth":
+			f.mu.Lock()
+			f.auths++
+			f.mu.Unlock()
+			resp.Data = map[string]any{"authenticated": true, "client_id": "client-1"}
+		case "subscribe":
+			f.mu.Lock()
+			f.subs = append(f.subs, msg.Channel)
+			f.mu.Unlock()
+		case "chat":
+			switch msg.Content {
+			case "drop":
+				return
+			case "fail":
+				resp = Message{ID: msg.ID, Type: "error", Error: "rate limited"}
+			default:
+				_ = conn.WriteJSON(Message{Type: "event", Content: "tool.progress", Data: map[string]any{"id": msg.ID, "tool": "shell", "text": "working"}})
+				_ = conn.WriteJSON(Message{Type: "event", Content: "approval.request", Channel: "approvals", Data: map[string]any{"id": "abc"}})
+				resp.Content = "echo: " + msg.Content
+				resp.Data = map[string]any{"trace_id": "t1", "attachments": []any{
+					map[string]any{"filename": "a.txt", "mime_type": "text/plain", "data": "aGk="},
+				}}
+			}
+		}
+		if err := conn.WriteJSON(resp); err != nil {
+			return
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	fake := &fakeGateway{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	events := make(chan Event, 10)
+	states := make(chan State, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), Options{
+		Token:         "secret",
+		Subscriptions: []string{"approvals"},
+		OnEvent:       func(e Event) { events <- e },
+		OnState:       func(s State) { states <- s },
+		MinBackoff:    10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	if c.ClientID() != "client-1" {
+		t.Errorf("ClientID() = %q", c.ClientID())
+	}
+
+	var progress []Progress
+	reply, err := c.Chat(ctx, "hi", func(p Progress) { progress = append(progress, p) })
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if reply.Content != "echo: hi" || reply.TraceID != "t1" || len(reply.Attachments) != 1 || string(reply.Attachments[0].Data) != "hi" {
+		t.Errorf("reply = %+v", reply)
+	}
+	if len(progress) != 1 || progress[0].Tool != "shell" {
+		t.Errorf("progress = %+v", progress)
+	}
+	if e := <-events; e.Name != "approval.request" || e.Data["id"] != "abc" {
+		t.Errorf("event = %+v", e)
+	}
+
+	var gwErr *Error
+	if _, err := c.Chat(ctx, "fail", nil); !errors.As(err, &gwErr) || gwErr.Message != "rate limited" {
+		t.Errorf("Chat(fail) error = %v", err)
+	}
+
+	// A dropped connection fails the request in flight, then reconnects,
+	// authenticating and subscribing again
+	if _, err := c.Chat(ctx, "drop", nil); !errors.Is(err, ErrDisconnected) {
+		t.Errorf("Chat(drop) error = %v", err)
+	}
+	if _, err := c.Chat(ctx, "again", nil); err != nil {
+		t.Fatalf("Chat() after reconnect error = %v", err)
+	}
+	fake.mu.Lock()
+	auths, subs := fake.auths, fake.subs
+	fake.mu.Unlock()
+	if auths != 2 || len(subs) != 2 {
+		t.Errorf("auths = %d, subs = %v; want 2 of each", auths, subs)
+	}
+	if s := []State{<-states, <-states, <-states}; s[0] != StateConnected || s[1] != StateDisconnected || s[2] != StateConnected {
+		t.Errorf("states = %v", s)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, err := c.Chat(ctx, "hi", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Chat() after Close error = %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/plexusone/omniagent/flags"
+)
+
+// maxFlagBytes caps the body of a flag update.
+const maxFlagBytes = 1 << 10
+
+// authorizeAdmin checks the admin bearer token, which is always required.
+func (g *Gateway) authorizeAdmin(r *http.Request) bool {
+	token := g.config.AdminToken
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token != "" && ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// handleListFlags handles GET /v1/admin/flags.
+func (g *Gateway) handleListFlags(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, map[string][]flags.Flag{"flags": g.config.Flags.List()})
+}
+
+// handleSetFlag handles PUT /v1/admin/flags/{name} with a body of
+// {"enabled": bool}.
+func (g *Gateway) handleSetFlag(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFlagBytes)).Decode(&req); err != nil || req.Enabled == nil {
+		writeHTTPError(w, http.StatusBadRequest, `body must be {"enabled": true|false}`)
+		return
+	}
+	name := r.PathValue("name")
+	if err := g.config.Flags.Set(name, *req.Enabled); err != nil {
+		g.logger.Error("failed to set flag", "flag", name, "error", err)
+		writeHTTPError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	g.logger.Warn("feature flag changed", "flag", name, "enabled", *req.Enabled)
+	writeHTTPJSON(w, http.StatusOK, g.config.Flags.Get(name))
+}
+
+// handleResetFlag handles DELETE /v1/admin/flags/{name}, returning the flag
+// to its configured value.
+func (g *Gateway) handleResetFlag(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	name := r.PathValue("name")
+	if err := g.config.Flags.Reset(name); err != nil {
+		g.logger.Error("failed to reset flag", "flag", name, "error", err)
+		writeHTTPError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	g.logger.Warn("feature flag reset", "flag", name)
+	writeHTTPJSON(w, http.StatusOK, g.config.Flags.Get(name))
+}
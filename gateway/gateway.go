@@ -4,12 +4,31 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/approvals"
+	"github.com/plexusone/omniagent/budget"
+	"github.com/plexusone/omniagent/connections"
+	"github.com/plexusone/omniagent/errlog"
+	"github.com/plexusone/omniagent/eval"
+	"github.com/plexusone/omniagent/flags"
+	"github.com/plexusone/omniagent/health"
+	"github.com/plexusone/omniagent/privacy"
+	"github.com/plexusone/omniagent/ratelimit"
+	"github.com/plexusone/omniagent/scheduler"
+	"github.com/plexusone/omniagent/supervisor"
+	"github.com/plexusone/omniagent/transcripts"
+	"github.com/plexusone/omniagent/usage"
 )
 
 // AgentProcessor processes messages through an AI agent.
@@ -17,6 +36,13 @@ type AgentProcessor interface {
 	Process(ctx context.Context, sessionID, content string) (string, error)
 }
 
+// ResultProcessor is implemented by agents that return trace IDs with
+// responses. Chat responses then carry the trace ID for feedback and the
+// experiment variant, if any.
+type ResultProcessor interface {
+	ProcessWithResult(ctx context.Context, sessionID, content string) (*agent.Result, error)
+}
+
 // Config configures the gateway server.
 type Config struct {
 	Address      string
@@ -25,6 +51,100 @@ type Config struct {
 	PingInterval time.Duration
 	Logger       *slog.Logger
 	Agent        AgentProcessor
+
+	// Instance names this deployment in /health, to tell a fleet apart.
+	Instance string
+
+	// AllowedOrigins are the browser origins (e.g. "https://app.example.com")
+	// that may open WebSocket connections, besides the gateway's own; "*"
+	// allows any. Clients that send no Origin, such as the Go client, are
+	// not browsers and are always allowed.
+	AllowedOrigins []string
+
+	// Token, when set, must be presented when opening a WebSocket, as a
+	// bearer Authorization header or a token query parameter. Observer,
+	// approver, and worker tokens are accepted too.
+	Token string
+
+	// TLS enables HTTPS/WSS when configured.
+	TLS *TLSConfig
+
+	// Scheduler enables schedule.* messages when set, and with an
+	// AdminToken, managing scheduled jobs at /v1/admin/jobs.
+	Scheduler *scheduler.Scheduler
+
+	// Feedback enables feedback messages when set.
+	Feedback *eval.Store
+
+	// RateLimiter limits chat messages per client when set.
+	// Gateway clients are limited under the "gateway" channel.
+	RateLimiter *ratelimit.Limiter
+
+	// HTTP enables the inbound HTTP channel at POST /v1/messages when set.
+	// HTTP clients are rate limited under the "http" channel.
+	HTTP *HTTPConfig
+
+	// Health reports provider probe results at /readyz when set.
+	Health *health.Monitor
+
+	// Throttle reports model request throttling at /health when set.
+	Throttle *ratelimit.Throttle
+
+	// Channels reports messaging channel connection states at /health
+	// when set.
+	Channels *connections.Manager
+
+	// Webhooks mounts channel webhook handlers, keyed by path
+	// (e.g. "/webhooks/sms").
+	Webhooks map[string]http.Handler
+
+	// Transcripts serves session transcripts at
+	// GET /v1/sessions/{id}/transcript when set, using the HTTP channel
+	// token if configured.
+	Transcripts *transcripts.Store
+
+	// Sessions lets admin clients list, inspect, clear, and delete
+	// sessions at /v1/admin/sessions when set with an AdminToken.
+	Sessions SessionManager
+
+	// Observers are tokens that authenticate WebSocket clients as
+	// read-only observers of redacted agent activity.
+	Observers []ObserverConfig
+
+	// Approvals lets approver clients decide tool calls when set, and
+	// Approvers are the tokens that authenticate them.
+	Approvals *approvals.Manager
+	Approvers []ApproverConfig
+
+	// Workers are the tokens that authenticate remote tool workers. Their
+	// tools are registered with Tools while they are connected.
+	Workers []WorkerConfig
+	Tools   ToolRegistrar
+
+	// AdminToken enables the admin API at /v1/admin: connected clients,
+	// channel states, and whichever of the features below are set.
+	AdminToken string
+
+	// Flags gate experimental features. With an AdminToken, they can be
+	// listed and changed at /v1/admin/flags.
+	Flags *flags.Flags
+
+	// Errors serves the latest logged errors at /v1/admin/errors when set
+	// with an AdminToken.
+	Errors *errlog.Recorder
+
+	// Usage serves model usage totals at /v1/admin/usage when set with an
+	// AdminToken, along with global Budget usage if set.
+	Usage  *usage.Ledger
+	Budget *budget.Tracker
+
+	// Reload re-reads the config file for POST /v1/admin/reload when set
+	// with an AdminToken, returning the names of the settings it applied.
+	Reload func() ([]string, error)
+
+	// LocalOnly marks /health with the local-only privacy mode and limits
+	// HTTP callbacks to local addresses.
+	LocalOnly bool
 }
 
 // Gateway is the WebSocket control plane server.
@@ -36,6 +156,14 @@ type Gateway struct {
 	logger   *slog.Logger
 	agent    AgentProcessor
 
+	// workers runs client pumps and async replies, which are drained when
+	// Run shuts down.
+	workers *supervisor.Group
+
+	// toolWorkers are the connected remote tool workers by name.
+	toolWorkers map[string]*toolWorker
+	workersMu   sync.RWMutex
+
 	// Handlers
 	onMessage MessageHandler
 }
@@ -60,20 +188,38 @@ func New(config Config) (*Gateway, error) {
 	if config.Logger == nil {
 		config.Logger = slog.Default()
 	}
+	if err := config.TLS.validate(); err != nil {
+		return nil, err
+	}
+	for _, o := range config.Observers {
+		if o.Token == "" {
+			return nil, fmt.Errorf("observer %q: token required", o.Name)
+		}
+	}
+	for _, a := range config.Approvers {
+		if a.Token == "" {
+			return nil, fmt.Errorf("approver %q: token required", a.Name)
+		}
+	}
+	for _, w := range config.Workers {
+		if w.Name == "" || w.Token == "" {
+			return nil, fmt.Errorf("worker %q: name and token required", w.Name)
+		}
+	}
 
 	gw := &Gateway{
 		config: config,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin: func(r *http.Request) bool {
-				// TODO: Implement proper origin checking
-				return true
-			},
+			CheckOrigin:     config.checkOrigin,
 		},
 		clients: make(map[string]*Client),
 		logger:  config.Logger,
 		agent:   config.Agent,
+		workers: supervisor.New(context.Background(), supervisor.Config{Logger: config.Logger}),
+
+		toolWorkers: make(map[string]*toolWorker),
 	}
 
 	// Set up default message handler
@@ -88,11 +234,62 @@ func (g *Gateway) OnMessage(handler MessageHandler) {
 	g.onMessage = handler
 }
 
+// registerAdmin mounts the admin API.
+func (g *Gateway) registerAdmin(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/admin/clients", g.handleListClients)
+	mux.HandleFunc("GET /v1/admin/channels", g.handleListChannels)
+	if g.config.Errors != nil {
+		mux.HandleFunc("GET /v1/admin/errors", g.handleRecentErrors)
+	}
+	if g.config.Reload != nil {
+		mux.HandleFunc("POST /v1/admin/reload", g.handleReload)
+	}
+	if g.config.Flags != nil {
+		mux.HandleFunc("GET /v1/admin/flags", g.handleListFlags)
+		mux.HandleFunc("PUT /v1/admin/flags/{name}", g.handleSetFlag)
+		mux.HandleFunc("DELETE /v1/admin/flags/{name}", g.handleResetFlag)
+	}
+	if g.config.Scheduler != nil {
+		mux.HandleFunc("GET /v1/admin/jobs", g.handleListJobs)
+		mux.HandleFunc("POST /v1/admin/jobs/{id}/{action}", g.handleJobAction)
+		mux.HandleFunc("DELETE /v1/admin/jobs/{id}", g.handleDeleteJob)
+	}
+	if g.config.Sessions != nil {
+		mux.HandleFunc("GET /v1/admin/sessions", g.handleListSessions)
+		mux.HandleFunc("GET /v1/admin/sessions/{id}", g.handleGetSession)
+		mux.HandleFunc("POST /v1/admin/sessions/{id}/clear", g.handleClearSession)
+		mux.HandleFunc("DELETE /v1/admin/sessions/{id}", g.handleDeleteSession)
+	}
+	if g.config.Transcripts != nil {
+		// Transcripts hold every channel's messages and attachments
+		mux.HandleFunc("GET /v1/sessions", g.handleSessions)
+		mux.HandleFunc("GET /v1/sessions/{id}/transcript", g.handleTranscript)
+		mux.HandleFunc("GET /v1/sessions/{id}/files/{name}", g.handleTranscriptFile)
+	}
+	if g.config.Usage != nil {
+		mux.HandleFunc("GET /v1/admin/usage", g.handleUsage)
+	}
+	if g.config.Tools != nil {
+		mux.HandleFunc("GET /v1/admin/workers", g.handleListWorkers)
+	}
+}
+
 // Run starts the gateway server.
 func (g *Gateway) Run(ctx context.Context) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/ws", g.handleWebSocket)
 	mux.HandleFunc("/health", g.handleHealth)
+	mux.HandleFunc("/readyz", g.handleReady)
+	mux.HandleFunc("GET /sdk/omniagent.js", g.handleClientScript)
+	if g.config.HTTP != nil {
+		mux.HandleFunc("/v1/messages", g.handleHTTPMessage)
+	}
+	for path, h := range g.config.Webhooks {
+		mux.Handle(path, h)
+	}
+	if g.config.AdminToken != "" {
+		g.registerAdmin(mux)
+	}
 
 	server := &http.Server{
 		Addr:         g.config.Address,
@@ -101,56 +298,203 @@ func (g *Gateway) Run(ctx context.Context) error {
 		WriteTimeout: g.config.WriteTimeout,
 	}
 
-	// Start server in goroutine
-	errCh := make(chan error, 1)
-	go func() {
-		g.logger.Info("gateway starting", "address", g.config.Address)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			errCh <- err
+	challengeServer := g.configureTLS(server)
+
+	// Services stop in reverse order: the servers stop accepting requests
+	// first, then connected clients and in-flight replies are drained
+	group := supervisor.New(ctx, supervisor.Config{Logger: g.logger})
+	_ = group.Go("clients", func(ctx context.Context) error {
+		<-ctx.Done()
+		g.closeClients()
+		stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return g.workers.Stop(stopCtx)
+	})
+	if challengeServer != nil {
+		// Serve ACME HTTP-01 challenges for autocert
+		_ = group.Go("acme", func(ctx context.Context) error {
+			g.logger.Info("acme challenge server starting", "address", challengeServer.Addr)
+			return serve(ctx, challengeServer, challengeServer.ListenAndServe)
+		})
+	}
+	_ = group.Go("http", func(ctx context.Context) error {
+		g.logger.Info("gateway starting", "address", g.config.Address, "tls", g.config.TLS.Enabled())
+		if g.config.TLS.Enabled() {
+			// Empty paths use the certificates from server.TLSConfig (autocert)
+			return serve(ctx, server, func() error {
+				return server.ListenAndServeTLS(g.config.TLS.CertFile, g.config.TLS.KeyFile)
+			})
 		}
-	}()
+		return serve(ctx, server, server.ListenAndServe)
+	})
+
+	err := group.Wait()
+	g.logger.Info("gateway stopped")
+	return err
+}
+
+// shutdownTimeout bounds graceful shutdown of servers and clients.
+const shutdownTimeout = 10 * time.Second
 
-	// Wait for context cancellation or error
-	select {
-	case <-ctx.Done():
-		g.logger.Info("gateway shutting down")
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// serve runs listen until ctx is canceled, then shuts server down
+// gracefully and waits for the shutdown to finish.
+func serve(ctx context.Context, server *http.Server, listen func() error) error {
+	shutdown := make(chan error, 1)
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
-		return server.Shutdown(shutdownCtx)
-	case err := <-errCh:
+		shutdown <- server.Shutdown(shutdownCtx)
+	})
+	err := listen()
+	if !errors.Is(err, http.ErrServerClosed) {
+		stop()
 		return err
 	}
+	return <-shutdown
 }
 
 // handleWebSocket handles WebSocket upgrade requests.
 func (g *Gateway) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeWebSocket(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 	conn, err := g.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		g.logger.Error("websocket upgrade failed", "error", err)
 		return
 	}
 
+	// Observer, approver, and worker tokens restrict the client to
+	// their role from the start, as an auth message with them would
 	client := newClient(conn, g)
+	g.setTokenRole(client, handshakeToken(r))
 	g.registerClient(client)
 
-	go client.readPump()
-	go client.writePump()
+	if err := g.workers.Spawn("ws-read", client.readPump); err != nil {
+		client.Close()
+		return
+	}
+	_ = g.workers.Spawn("ws-write", client.writePump)
+}
+
+// checkOrigin allows WebSocket requests without an Origin, from the
+// gateway's own origin, or from an allowed one, so other websites cannot
+// drive a local gateway from a user's browser.
+func (c Config) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeWebSocket checks the handshake token when Token is set.
+func (g *Gateway) authorizeWebSocket(r *http.Request) bool {
+	if g.config.Token == "" {
+		return true
+	}
+	token := handshakeToken(r)
+	return token != "" && (tokenEqual(token, g.config.Token) || g.observerFor(token) != nil ||
+		g.approverFor(token) != nil || g.workerFor(token) != nil)
+}
+
+// handshakeToken returns the token of a WebSocket handshake, from the
+// Authorization header or the token query parameter.
+func handshakeToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	return token
+}
+
+// setTokenRole gives client the role of an observer, approver, or worker
+// token and returns it. Other tokens leave the client's role unchanged and
+// return RoleClient.
+func (g *Gateway) setTokenRole(client *Client, token string) string {
+	role := RoleClient
+	if token == "" {
+		return role
+	}
+	if o := g.observerFor(token); o != nil {
+		role = RoleObserver
+		client.SetMetadata("observer", o)
+	} else if a := g.approverFor(token); a != nil {
+		role = RoleApprover
+		client.SetMetadata("approver", a)
+	} else if w := g.workerFor(token); w != nil {
+		role = RoleWorker
+		client.SetMetadata("worker", w)
+	} else {
+		return role
+	}
+	client.SetMetadata("role", role)
+	return role
 }
 
 // handleHealth handles health check requests.
 func (g *Gateway) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	if g.config.LocalOnly {
+		w.Header().Set("X-Privacy-Mode", privacy.Mode)
+	}
 	w.WriteHeader(http.StatusOK)
 	resp := struct {
-		Status  string `json:"status"`
-		Clients int    `json:"clients"`
+		Status   string                    `json:"status"`
+		Instance string                    `json:"instance,omitempty"`
+		Privacy  string                    `json:"privacy,omitempty"`
+		Clients  int                       `json:"clients"`
+		Throttle []ratelimit.ThrottleStats `json:"throttle,omitempty"`
+		Channels []connections.Status      `json:"channels,omitempty"`
 	}{
-		Status:  "ok",
-		Clients: g.ClientCount(),
+		Status:   "ok",
+		Instance: g.config.Instance,
+		Clients:  g.ClientCount(),
+		Channels: g.config.Channels.Statuses(),
+	}
+	if g.config.LocalOnly {
+		resp.Privacy = privacy.Mode
+	}
+	if g.config.Throttle != nil {
+		resp.Throttle = g.config.Throttle.Stats()
 	}
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// handleReady reports whether the agent's providers are healthy. It returns
+// 503 while any probe is failing so load balancers can route around it.
+func (g *Gateway) handleReady(w http.ResponseWriter, _ *http.Request) {
+	resp := struct {
+		Ready     bool            `json:"ready"`
+		Providers []health.Status `json:"providers,omitempty"`
+	}{Ready: true}
+	if m := g.config.Health; m != nil {
+		resp.Providers = m.Statuses()
+		resp.Ready = m.Ready()
+	}
+
+	status := http.StatusOK
+	if !resp.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
 // registerClient registers a new client.
 func (g *Gateway) registerClient(client *Client) {
 	g.mu.Lock()
@@ -162,11 +506,25 @@ func (g *Gateway) registerClient(client *Client) {
 // unregisterClient removes a client.
 func (g *Gateway) unregisterClient(client *Client) {
 	g.mu.Lock()
-	defer g.mu.Unlock()
 	if _, ok := g.clients[client.ID]; ok {
 		delete(g.clients, client.ID)
 		g.logger.Info("client disconnected", "id", client.ID)
 	}
+	g.mu.Unlock()
+	g.dropWorker(client)
+}
+
+// closeClients disconnects every client.
+func (g *Gateway) closeClients() {
+	g.mu.RLock()
+	clients := make([]*Client, 0, len(g.clients))
+	for _, c := range g.clients {
+		clients = append(clients, c)
+	}
+	g.mu.RUnlock()
+	for _, c := range clients {
+		c.Close()
+	}
 }
 
 // ClientCount returns the number of connected clients.
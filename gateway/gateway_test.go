@@ -2,14 +2,32 @@ package gateway
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/approvals"
+	"github.com/plexusone/omniagent/errlog"
+	"github.com/plexusone/omniagent/eval"
+	"github.com/plexusone/omniagent/flags"
+	"github.com/plexusone/omniagent/health"
+	"github.com/plexusone/omniagent/scheduler"
+	"github.com/plexusone/omniagent/transcripts"
+	"github.com/plexusone/omniagent/usage"
 )
 
 // mockAgent is a simple agent for testing.
@@ -194,6 +212,16 @@ func TestGatewayHealth(t *testing.T) {
 	if health["status"] != "ok" {
 		t.Errorf("Expected status ok, got %v", health["status"])
 	}
+	if _, ok := health["privacy"]; ok {
+		t.Errorf("Expected no privacy mode, got %v", health["privacy"])
+	}
+
+	gw.config.LocalOnly = true
+	rec := httptest.NewRecorder()
+	gw.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Header().Get("X-Privacy-Mode") != "local-only" || !strings.Contains(rec.Body.String(), `"privacy":"local-only"`) {
+		t.Errorf("Expected local-only privacy mode, got %q %s", rec.Header().Get("X-Privacy-Mode"), rec.Body.String())
+	}
 }
 
 func TestGatewayNoAgent(t *testing.T) {
@@ -295,3 +323,1021 @@ func TestGatewayBroadcast(t *testing.T) {
 		}
 	}
 }
+
+func TestTLSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     *TLSConfig
+		wantErr bool
+	}{
+		{name: "nil", tls: nil},
+		{name: "empty", tls: &TLSConfig{}},
+		{name: "cert and key", tls: &TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}},
+		{name: "cert without key", tls: &TLSConfig{CertFile: "cert.pem"}, wantErr: true},
+		{name: "autocert", tls: &TLSConfig{Autocert: true, AutocertDomains: []string{"agent.example.com"}}},
+		{name: "autocert without domains", tls: &TLSConfig{Autocert: true}, wantErr: true},
+		{name: "autocert with cert", tls: &TLSConfig{Autocert: true, AutocertDomains: []string{"a.example.com"}, CertFile: "c", KeyFile: "k"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{Address: "127.0.0.1:0", TLS: tt.tls})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleMessages(t *testing.T) {
+	sched, err := scheduler.New(scheduler.Config{
+		Handler: func(context.Context, scheduler.Task) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("scheduler.New() error = %v", err)
+	}
+	gw, err := New(Config{Address: "127.0.0.1:0", Scheduler: sched})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	h := NewDefaultMessageHandler(gw)
+	ctx := context.Background()
+	alice, bob := &Client{ID: "alice"}, &Client{ID: "bob"}
+
+	// Tools, messages, and other chats are out of reach of clients
+	for name, data := range map[string]map[string]interface{}{
+		"tool":     {"schedule": "@daily", "tool": "shell", "tool_args": map[string]interface{}{"command": "id"}},
+		"message":  {"schedule": "@daily", "message": "hi", "channel": "telegram", "chat_id": "42"},
+		"delivery": {"schedule": "@daily", "prompt": "digest", "channel": "telegram", "chat_id": "42"},
+	} {
+		resp, _ := h.Handle(ctx, alice, &Message{ID: "s-0", Type: MessageTypeScheduleCreate, Data: data})
+		if resp.Type != MessageTypeError {
+			t.Errorf("create %s task: got %s, want error", name, resp.Type)
+		}
+	}
+	if n := len(sched.List()); n != 0 {
+		t.Fatalf("%d tasks created from refused requests", n)
+	}
+
+	resp, _ := h.Handle(ctx, alice, &Message{
+		ID:   "s-1",
+		Type: MessageTypeScheduleCreate,
+		Data: map[string]interface{}{
+			"name":     "digest",
+			"schedule": "0 8 * * *",
+			"prompt":   "Send me a news digest",
+		},
+	})
+	if resp.Type != MessageTypeResponse {
+		t.Fatalf("create: got %s (%s)", resp.Type, resp.Error)
+	}
+	tasks := sched.List()
+	if len(tasks) != 1 || tasks[0].Session != "alice" {
+		t.Fatalf("tasks = %+v, want one task of alice", tasks)
+	}
+
+	// Other clients neither see nor remove it
+	for client, want := range map[*Client]int{alice: 1, bob: 0} {
+		resp, _ = h.Handle(ctx, client, &Message{ID: "s-2", Type: MessageTypeScheduleList})
+		if listed, _ := resp.Data["tasks"].([]scheduler.Task); resp.Type != MessageTypeResponse || len(listed) != want {
+			t.Errorf("list for %s: got %+v, want %d tasks", client.ID, resp, want)
+		}
+	}
+	resp, _ = h.Handle(ctx, bob, &Message{
+		ID:   "s-3",
+		Type: MessageTypeScheduleRemove,
+		Data: map[string]interface{}{"id": tasks[0].ID},
+	})
+	if resp.Type != MessageTypeError || len(sched.List()) != 1 {
+		t.Errorf("remove by another client: got %s, %d tasks left", resp.Type, len(sched.List()))
+	}
+	resp, _ = h.Handle(ctx, alice, &Message{
+		ID:   "s-3",
+		Type: MessageTypeScheduleRemove,
+		Data: map[string]interface{}{"id": tasks[0].ID},
+	})
+	if resp.Type != MessageTypeResponse || len(sched.List()) != 0 {
+		t.Errorf("remove: got %+v, %d tasks left", resp, len(sched.List()))
+	}
+
+	// Admin job controls
+	gw.config.AdminToken = "admin"
+	task, err := sched.Add(scheduler.Task{Schedule: "@daily", Prompt: "digest"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	job := func(h http.HandlerFunc, method, id, action string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/admin/jobs/"+id+"/"+action, nil)
+		req.SetPathValue("id", id)
+		req.SetPathValue("action", action)
+		req.Header.Set("Authorization", "Bearer admin")
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+	if rec := job(gw.handleListJobs, http.MethodGet, "", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), task.ID) {
+		t.Errorf("list jobs = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := job(gw.handleJobAction, http.MethodPost, task.ID, "pause"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"paused":true`) {
+		t.Errorf("pause = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := job(gw.handleJobAction, http.MethodPost, task.ID, "run"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("run while stopped = %d, want 503", rec.Code)
+	}
+	if rec := job(gw.handleJobAction, http.MethodPost, "missing", "resume"); rec.Code != http.StatusNotFound {
+		t.Errorf("resume missing = %d, want 404", rec.Code)
+	}
+	if rec := job(gw.handleDeleteJob, http.MethodDelete, task.ID, ""); rec.Code != http.StatusOK || len(sched.List()) != 0 {
+		t.Errorf("delete = %d, %d jobs left", rec.Code, len(sched.List()))
+	}
+
+	// Disabled scheduler
+	gw2, _ := New(Config{Address: "127.0.0.1:0"})
+	resp, _ = NewDefaultMessageHandler(gw2).Handle(ctx, alice, &Message{ID: "s-4", Type: MessageTypeScheduleList})
+	if resp.Type != MessageTypeError {
+		t.Errorf("expected error without scheduler, got %s", resp.Type)
+	}
+}
+
+func TestFeedbackMessage(t *testing.T) {
+	ctx := context.Background()
+	store, err := eval.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("eval.Open() error = %v", err)
+	}
+	if err := store.RecordTrace(ctx, eval.Trace{ID: "trace-1", SessionID: "c1", Model: "m"}); err != nil {
+		t.Fatalf("RecordTrace() error = %v", err)
+	}
+
+	gw, err := New(Config{Address: "127.0.0.1:0", Feedback: store})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	h := NewDefaultMessageHandler(gw)
+
+	client := &Client{ID: "c1"}
+	resp, _ := h.Handle(ctx, client, &Message{
+		ID:   "f-1",
+		Type: MessageTypeFeedback,
+		Data: map[string]interface{}{"trace_id": "trace-1", "rating": "up", "comment": "great"},
+	})
+	if resp.Type != MessageTypeResponse {
+		t.Fatalf("feedback: got %s (%s)", resp.Type, resp.Error)
+	}
+	if fb, _ := store.Feedback(); len(fb) != 1 || fb[0].Rating != eval.RatingUp || fb[0].Source != "gateway" {
+		t.Errorf("Feedback() = %+v", fb)
+	}
+
+	resp, _ = h.Handle(ctx, client, &Message{
+		ID:   "f-2",
+		Type: MessageTypeFeedback,
+		Data: map[string]interface{}{"trace_id": "unknown", "rating": "down"},
+	})
+	if resp.Type != MessageTypeError {
+		t.Errorf("expected error for unknown trace, got %s", resp.Type)
+	}
+
+	resp, _ = h.Handle(ctx, &Client{ID: "c2"}, &Message{
+		ID:   "f-3",
+		Type: MessageTypeFeedback,
+		Data: map[string]interface{}{"trace_id": "trace-1", "rating": "down"},
+	})
+	if resp.Type != MessageTypeError {
+		t.Errorf("expected error for another client's trace, got %s", resp.Type)
+	}
+}
+
+func TestHTTPMessages(t *testing.T) {
+	gw, err := New(Config{
+		Agent: &mockAgent{},
+		HTTP:  &HTTPConfig{Token: "secret", CallbackSecret: "sign", CallbackHosts: []string{"127.0.0.1"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(gw.handleHTTPMessage))
+	defer server.Close()
+
+	post := func(token, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		resp := post("wrong", `{"session_id":"s1","content":"hi"}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		resp := post("secret", `{"session_id":"s1"}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		resp := post("secret", `{"session_id":"s1","content":"hi"}`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+		var got HTTPMessageResponse
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode: %v", err)
+		}
+		if got.Reply != "Echo: hi" || got.SessionID != "s1" {
+			t.Errorf("Unexpected response: %+v", got)
+		}
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		delivered := make(chan HTTPMessageResponse, 1)
+		callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				t.Errorf("Callback got the gateway token")
+			}
+			body, _ := io.ReadAll(r.Body)
+			mac := hmac.New(sha256.New, []byte("sign"))
+			mac.Write(body)
+			if r.Header.Get("X-Omniagent-Signature-256") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+				t.Errorf("Callback signature = %q", r.Header.Get("X-Omniagent-Signature-256"))
+			}
+			var got HTTPMessageResponse
+			_ = json.Unmarshal(body, &got)
+			delivered <- got
+		}))
+		defer callback.Close()
+
+		resp := post("secret", `{"session_id":"s2","content":"later","callback_url":"`+callback.URL+`"}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("Expected 202, got %d", resp.StatusCode)
+		}
+		select {
+		case got := <-delivered:
+			if got.Reply != "Echo: later" || got.SessionID != "s2" {
+				t.Errorf("Unexpected callback: %+v", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for callback")
+		}
+	})
+
+	t.Run("private callback", func(t *testing.T) {
+		for _, u := range []string{"http://localhost:8080/hook", "http://10.0.0.1/hook", "http://169.254.169.254/latest"} {
+			resp := post("secret", `{"session_id":"s3","content":"later","callback_url":"`+u+`"}`)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d", u, resp.StatusCode)
+			}
+		}
+		if err := publicAddressOnly("tcp", "127.0.0.1:80", nil); err == nil {
+			t.Error("dialer allowed loopback")
+		}
+	})
+}
+
+func TestReadyz(t *testing.T) {
+	healthy := true
+	monitor := health.New(health.Config{
+		Probes: []health.Probe{{Name: "llm", Check: func(context.Context) error {
+			if healthy {
+				return nil
+			}
+			return errors.New("down")
+		}}},
+		FailureThreshold: 1,
+	})
+	gw, err := New(Config{Agent: &mockAgent{}, Health: monitor})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	get := func() (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		gw.handleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]interface{}
+		_ = json.NewDecoder(rec.Body).Decode(&body)
+		return rec.Code, body
+	}
+
+	monitor.CheckAll(context.Background())
+	if code, body := get(); code != http.StatusOK || body["ready"] != true {
+		t.Errorf("readyz = %d %v, want 200 ready", code, body)
+	}
+
+	healthy = false
+	monitor.CheckAll(context.Background())
+	if code, body := get(); code != http.StatusServiceUnavailable || body["ready"] != false {
+		t.Errorf("readyz = %d %v, want 503 not ready", code, body)
+	}
+}
+
+func TestTranscriptEndpointWithoutToken(t *testing.T) {
+	store, err := transcripts.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Append("http:s1", transcripts.Entry{Role: transcripts.RoleUser, Content: "hello"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	gw, err := New(Config{Agent: &mockAgent{}, Transcripts: store})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	for name, h := range map[string]http.HandlerFunc{
+		"sessions":   gw.handleSessions,
+		"transcript": gw.handleTranscript,
+		"file":       gw.handleTranscriptFile,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/sessions/http:s1/transcript", nil)
+		req.SetPathValue("id", "http:s1")
+		req.SetPathValue("name", "a.txt")
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusUnauthorized || strings.Contains(rec.Body.String(), "hello") {
+			t.Errorf("%s without admin token = %d %s, want 401", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTranscriptEndpoint(t *testing.T) {
+	store, err := transcripts.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Append("http:s1", transcripts.Entry{Role: transcripts.RoleUser, Content: "hello"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	gw, err := New(Config{Agent: &mockAgent{}, Transcripts: store, HTTP: &HTTPConfig{Token: "http"}, AdminToken: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	get := func(session, query, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/sessions/"+session+"/transcript?"+query, nil)
+		req.SetPathValue("id", session)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		gw.handleTranscript(rec, req)
+		return rec
+	}
+
+	if rec := get("http:s1", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token = %d, want 401", rec.Code)
+	}
+	if rec := get("http:s1", "", "http"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("with http token = %d, want 401", rec.Code)
+	}
+	rec := get("http:s1", "format=html", "secret")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("html export = %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "hello") {
+		t.Errorf("html export missing message: %s", rec.Body.String())
+	}
+	if rec := get("http:s1", "format=pdf", "secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format = %d, want 400", rec.Code)
+	}
+	if rec := get("http:none", "", "secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown session = %d, want 404", rec.Code)
+	}
+	if rec := get("http:s1", "format=json", "secret"); rec.Header().Get("Content-Type") != "application/json" || !strings.Contains(rec.Body.String(), `"session": "http:s1"`) {
+		t.Errorf("json export = %q %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/sessions", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	gw.handleSessions(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"http:s1"`) {
+		t.Errorf("sessions = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	config := Config{AllowedOrigins: []string{"https://app.example.com"}}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://127.0.0.1:18789", true},
+		{"https://app.example.com", true},
+		{"https://evil.example.com", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:18789/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := config.checkOrigin(req); got != tt.want {
+			t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestWebSocketToken(t *testing.T) {
+	gw, err := New(Config{
+		Agent:     &mockAgent{},
+		Token:     "secret",
+		Observers: []ObserverConfig{{Name: "dash", Token: "watch"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(gw.handleWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Dial without token succeeded or got %v", resp)
+	}
+	for _, dial := range []func() (*websocket.Conn, *http.Response, error){
+		func() (*websocket.Conn, *http.Response, error) {
+			return websocket.DefaultDialer.Dial(wsURL+"?token=secret", nil)
+		},
+		func() (*websocket.Conn, *http.Response, error) {
+			return websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer watch"}})
+		},
+	} {
+		conn, _, err := dial()
+		if err != nil {
+			t.Errorf("Dial with token failed: %v", err)
+			continue
+		}
+		conn.Close()
+	}
+
+	// Chat reaches the agent only with the main token
+	for token, wantType := range map[string]MessageType{"secret": MessageTypeResponse, "watch": MessageTypeError} {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Dial(%s) error = %v", token, err)
+		}
+		if err := conn.WriteJSON(Message{ID: "1", Type: MessageTypeChat, Content: "hi"}); err != nil {
+			t.Fatalf("WriteJSON() error = %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		var resp Message
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		if resp.Type != wantType {
+			t.Errorf("chat with token %s: got %s %q, want %s", token, resp.Type, resp.Content, wantType)
+		}
+		conn.Close()
+	}
+}
+
+func TestObserverClients(t *testing.T) {
+	gw, err := New(Config{
+		Address:   "127.0.0.1:0",
+		Agent:     &mockAgent{},
+		Observers: []ObserverConfig{{Name: "dash", Token: "watch", Channels: []string{"telegram"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(gw.handleWebSocket))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	roundTrip := func(msg Message) Message {
+		t.Helper()
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("WriteJSON() error = %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		var resp Message
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		return resp
+	}
+
+	// Activity requires the observer role
+	if resp := roundTrip(Message{ID: "1", Type: MessageTypeSubscribe, Channel: ActivityChannel}); resp.Type != MessageTypeError {
+		t.Errorf("subscribe before auth: got %s", resp.Type)
+	}
+	if resp := roundTrip(Message{ID: "2", Type: MessageTypeAuth, Data: map[string]interface{}{"token": "watch"}}); resp.Data["role"] != RoleObserver {
+		t.Fatalf("auth: got %+v", resp)
+	}
+	if resp := roundTrip(Message{ID: "3", Type: MessageTypeChat, Content: "hi"}); resp.Type != MessageTypeError {
+		t.Errorf("observer chat: got %s", resp.Type)
+	}
+	if resp := roundTrip(Message{ID: "4", Type: MessageTypeSubscribe, Channel: ActivityChannel + ":discord"}); resp.Type != MessageTypeError {
+		t.Errorf("subscribe to disallowed channel: got %s", resp.Type)
+	}
+	if resp := roundTrip(Message{ID: "5", Type: MessageTypeSubscribe, Channel: ActivityChannel}); resp.Type != MessageTypeResponse {
+		t.Fatalf("subscribe: got %s (%s)", resp.Type, resp.Error)
+	}
+
+	// Activity on other channels is filtered out
+	gw.Observe(agent.Activity{Kind: agent.ActivityMessage, SessionID: "discord:1", Channel: "discord", Content: "hidden"})
+	gw.Observe(agent.Activity{
+		Kind:      agent.ActivityMessage,
+		SessionID: "telegram:42",
+		Channel:   "telegram",
+		Content:   "mail bob@example.com or call +1 (555) 123-4567 on 2026-10-17",
+	})
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var event Message
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if event.Type != MessageTypeEvent || event.Content != "activity.message" || event.Channel != "activity:telegram" {
+		t.Fatalf("event = %+v", event)
+	}
+	if want := "mail [email] or call [number] on 2026-10-17"; event.Data["content"] != want {
+		t.Errorf("content = %q, want %q", event.Data["content"], want)
+	}
+	if session, _ := event.Data["session"].(string); session == "telegram:42" || !strings.HasPrefix(session, "telegram:") {
+		t.Errorf("session = %q", session)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"key sk-abcdefghijklmnop", "key [secret]"},
+		{"Authorization: Bearer abc.def.ghijkl", "Authorization: [secret]"},
+		{"card 4111 1111 1111 1111", "card [number]"},
+		{"order 12345", "order 12345"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApproverClients(t *testing.T) {
+	m, err := approvals.New(approvals.Config{Tools: []string{"shell"}})
+	if err != nil {
+		t.Fatalf("approvals.New() error = %v", err)
+	}
+	gw, err := New(Config{
+		Address:   "127.0.0.1:0",
+		Agent:     &mockAgent{},
+		Approvals: m,
+		Approvers: []ApproverConfig{{Name: "ops", Token: "approve"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	m.OnRequest(gw.NotifyApproval)
+	server := httptest.NewServer(http.HandlerFunc(gw.handleWebSocket))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	read := func() Message {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		var resp Message
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		return resp
+	}
+	roundTrip := func(msg Message) Message {
+		t.Helper()
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("WriteJSON() error = %v", err)
+		}
+		return read()
+	}
+
+	// Approvals require the approver role
+	if resp := roundTrip(Message{ID: "1", Type: MessageTypeSubscribe, Channel: ApprovalsChannel}); resp.Type != MessageTypeError {
+		t.Errorf("subscribe before auth: got %s", resp.Type)
+	}
+	if resp := roundTrip(Message{ID: "2", Type: MessageTypeAuth, Data: map[string]interface{}{"token": "approve"}}); resp.Data["role"] != RoleApprover {
+		t.Fatalf("auth: got %+v", resp)
+	}
+	if resp := roundTrip(Message{ID: "3", Type: MessageTypeChat, Content: "hi"}); resp.Type != MessageTypeError {
+		t.Errorf("approver chat: got %s", resp.Type)
+	}
+	if resp := roundTrip(Message{ID: "4", Type: MessageTypeSubscribe, Channel: ApprovalsChannel}); resp.Type != MessageTypeResponse {
+		t.Fatalf("subscribe: got %s (%s)", resp.Type, resp.Error)
+	}
+
+	done := make(chan approvals.Decision, 1)
+	go func() {
+		d, _ := m.Await(context.Background(), approvals.Request{Tool: "shell", SessionID: "telegram:1"})
+		done <- d
+	}()
+	event := read()
+	if event.Type != MessageTypeEvent || event.Data["tool"] != "shell" {
+		t.Fatalf("approval request: got %+v", event)
+	}
+	id, _ := event.Data["id"].(string)
+	if resp := roundTrip(Message{ID: "5", Type: MessageTypeApproval, Data: map[string]interface{}{"id": id, "approved": true}}); resp.Type != MessageTypeResponse {
+		t.Fatalf("approval: got %s (%s)", resp.Type, resp.Error)
+	}
+	if d := <-done; !d.Approved || d.Approver != "gateway:ops" {
+		t.Errorf("decision = %+v", d)
+	}
+}
+
+func TestClientScript(t *testing.T) {
+	gw, err := New(Config{Address: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	gw.handleClientScript(rec, httptest.NewRequest(http.MethodGet, "/sdk/omniagent.js", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "class OmniAgentClient") {
+		t.Error("client script not served")
+	}
+}
+
+// metadataAgent records the metadata attached to the last message.
+type metadataAgent struct {
+	metadata map[string]any
+}
+
+func (m *metadataAgent) Process(ctx context.Context, _, _ string) (string, error) {
+	m.metadata = agent.MetadataFromContext(ctx)
+	return "ok", nil
+}
+
+func TestMessageMetadata(t *testing.T) {
+	a := &metadataAgent{}
+	gw, err := New(Config{Address: "127.0.0.1:0", Agent: a})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	gw.processHTTPMessage(context.Background(), HTTPMessageRequest{
+		SessionID: "crm-1",
+		Content:   "hi",
+		Metadata:  map[string]any{"current_url": "https://example.com/cart"},
+	})
+	if a.metadata["current_url"] != "https://example.com/cart" {
+		t.Errorf("HTTP metadata = %v", a.metadata)
+	}
+
+	h := NewDefaultMessageHandler(gw)
+	client := &Client{ID: "c1", send: make(chan *Message, 4), metadata: map[string]interface{}{}}
+	_, _ = h.Handle(context.Background(), client, &Message{ID: "1", Type: MessageTypeChat, Content: "hi",
+		Data: map[string]interface{}{"metadata": map[string]interface{}{"location": "home"}}})
+	if a.metadata["location"] != "home" {
+		t.Errorf("WebSocket metadata = %v", a.metadata)
+	}
+}
+
+func TestAdminFlags(t *testing.T) {
+	f, err := flags.New(map[string]bool{"streaming": false}, "")
+	if err != nil {
+		t.Fatalf("flags.New: %v", err)
+	}
+	gw, err := New(Config{Agent: &mockAgent{}, Flags: f, AdminToken: "admin"})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	do := func(h http.HandlerFunc, method, name, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/admin/flags/"+name, strings.NewReader(body))
+		req.SetPathValue("name", name)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	if rec := do(gw.handleListFlags, http.MethodGet, "", "", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token = %d, want 401", rec.Code)
+	}
+	if rec := do(gw.handleSetFlag, http.MethodPut, "streaming", `{}`, "admin"); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing enabled = %d, want 400", rec.Code)
+	}
+	if rec := do(gw.handleSetFlag, http.MethodPut, "streaming", `{"enabled":true}`, "admin"); rec.Code != http.StatusOK {
+		t.Errorf("set = %d, want 200", rec.Code)
+	}
+	if !f.Enabled(flags.Streaming) {
+		t.Error("streaming still off after set")
+	}
+	if rec := do(gw.handleResetFlag, http.MethodDelete, "streaming", "", "admin"); rec.Code != http.StatusOK {
+		t.Errorf("reset = %d, want 200", rec.Code)
+	}
+	if f.Enabled(flags.Streaming) {
+		t.Error("streaming on after reset, want configured off")
+	}
+
+	rec := do(gw.handleListFlags, http.MethodGet, "", "", "admin")
+	var resp struct {
+		Flags []flags.Flag `json:"flags"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Flags) != 2 {
+		t.Errorf("list = %s", rec.Body.String())
+	}
+}
+
+// mockSessions is an in-memory SessionManager.
+type mockSessions map[string]agent.SessionState
+
+func (m mockSessions) Sessions() []agent.SessionState {
+	out := make([]agent.SessionState, 0, len(m))
+	for _, s := range m {
+		out = append(out, s)
+	}
+	return out
+}
+
+func (m mockSessions) Session(id string) (agent.SessionState, bool) {
+	s, ok := m[id]
+	return s, ok
+}
+
+func (m mockSessions) ClearSession(id string) error {
+	if s, ok := m[id]; ok {
+		m[id] = agent.SessionState{ID: id, Preferences: s.Preferences}
+	}
+	return nil
+}
+
+func (m mockSessions) DeleteSession(id string) error {
+	delete(m, id)
+	return nil
+}
+
+func TestAdminSessions(t *testing.T) {
+	store, err := transcripts.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Append("http:s2", transcripts.Entry{Role: transcripts.RoleUser, Content: "hi"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	sessions := mockSessions{"http:s1": {ID: "http:s1", LastReply: "Hello!", Preferences: &agent.Preferences{Language: "French"}}}
+	gw, err := New(Config{Agent: &mockAgent{}, Sessions: sessions, Transcripts: store, AdminToken: "admin"})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	do := func(h http.HandlerFunc, method, id, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/admin/sessions/"+id, nil)
+		req.SetPathValue("id", id)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	if rec := do(gw.handleListSessions, http.MethodGet, "", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token = %d, want 401", rec.Code)
+	}
+	rec := do(gw.handleListSessions, http.MethodGet, "", "admin")
+	var list struct {
+		Sessions []SessionInfo `json:"sessions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Sessions) != 2 ||
+		list.Sessions[0].LastReply != "Hello!" || list.Sessions[1].Entries != 1 {
+		t.Errorf("list = %s", rec.Body.String())
+	}
+	if rec := do(gw.handleGetSession, http.MethodGet, "http:none", "admin"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown session = %d, want 404", rec.Code)
+	}
+
+	if rec := do(gw.handleClearSession, http.MethodPost, "http:s1", "admin"); rec.Code != http.StatusOK {
+		t.Errorf("clear = %d, want 200", rec.Code)
+	}
+	if s := sessions["http:s1"]; s.LastReply != "" || s.Preferences == nil {
+		t.Errorf("after clear = %+v, want preferences only", s)
+	}
+
+	if rec := do(gw.handleDeleteSession, http.MethodDelete, "http:s2", "admin"); rec.Code != http.StatusOK {
+		t.Errorf("delete = %d, want 200", rec.Code)
+	}
+	if _, err := store.Entries("http:s2"); !errors.Is(err, transcripts.ErrNotFound) {
+		t.Errorf("transcript after delete: %v, want ErrNotFound", err)
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	recent := errlog.New(10)
+	slog.New(recent.Handler(slog.NewTextHandler(io.Discard, nil))).Error("send failed", "channel", "telegram")
+	reloads := 0
+	ledger, err := usage.Open(filepath.Join(t.TempDir(), "usage.jsonl"), eval.Pricing{"gpt-4o": {Prompt: 2, Completion: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ledger.Close()
+	_ = ledger.Record(usage.Record{SessionID: "telegram:1", Provider: "openai", Model: "gpt-4o", PromptTokens: 1000, CompletionTokens: 100})
+	gw, err := New(Config{
+		Agent:      &mockAgent{},
+		AdminToken: "admin",
+		Errors:     recent,
+		Usage:      ledger,
+		Reload: func() ([]string, error) {
+			reloads++
+			return []string{"flags"}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	gw.clients["c1"] = &Client{ID: "c1", metadata: map[string]interface{}{"authenticated": true, "role": RoleObserver}}
+
+	do := func(h http.HandlerFunc, method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	if rec := do(gw.handleListClients, http.MethodGet, "/v1/admin/clients", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token = %d, want 401", rec.Code)
+	}
+	rec := do(gw.handleListClients, http.MethodGet, "/v1/admin/clients", "admin")
+	var clients struct {
+		Clients []ClientInfo `json:"clients"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &clients); err != nil || len(clients.Clients) != 1 ||
+		clients.Clients[0].Role != RoleObserver || !clients.Clients[0].Authenticated {
+		t.Errorf("clients = %s", rec.Body.String())
+	}
+
+	if rec := do(gw.handleListChannels, http.MethodGet, "/v1/admin/channels", "admin"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"channels":[]`) {
+		t.Errorf("channels = %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(gw.handleRecentErrors, http.MethodGet, "/v1/admin/errors?limit=5", "admin")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "send failed") {
+		t.Errorf("errors = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(gw.handleRecentErrors, http.MethodGet, "/v1/admin/errors?limit=x", "admin"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad limit = %d, want 400", rec.Code)
+	}
+
+	rec = do(gw.handleReload, http.MethodPost, "/v1/admin/reload", "admin")
+	if rec.Code != http.StatusOK || reloads != 1 || !strings.Contains(rec.Body.String(), `"applied":["flags"]`) {
+		t.Errorf("reload = %d %s, %d reloads", rec.Code, rec.Body.String(), reloads)
+	}
+
+	rec = do(gw.handleUsage, http.MethodGet, "/v1/admin/usage?by=provider", "admin")
+	var report UsageReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || len(report.Totals) != 1 ||
+		report.Totals[0].Key != "openai" || report.Totals[0].Cost != 0.003 {
+		t.Errorf("usage = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(gw.handleUsage, http.MethodGet, "/v1/admin/usage?by=user", "admin"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad grouping = %d, want 400", rec.Code)
+	}
+}
+
+// mockRegistrar records the tools registered by workers.
+type mockRegistrar struct {
+	mu    sync.Mutex
+	tools map[string]agent.Tool
+}
+
+func (r *mockRegistrar) RegisterToolFrom(source string, tool agent.Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[agent.ToolID(source, tool.Name())] = tool
+	return nil
+}
+
+func (r *mockRegistrar) UnregisterTool(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, id)
+}
+
+func (r *mockRegistrar) get(id string) agent.Tool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tools[id]
+}
+
+func TestWorkerClients(t *testing.T) {
+	tools := &mockRegistrar{tools: map[string]agent.Tool{}}
+	gw, err := New(Config{
+		Address: "127.0.0.1:0",
+		Agent:   &mockAgent{},
+		Workers: []WorkerConfig{{Name: "home", Token: "work", Timeout: time.Second}},
+		Tools:   tools,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(gw.handleWebSocket))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	read := func() Message {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		var resp Message
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		return resp
+	}
+	roundTrip := func(msg Message) Message {
+		t.Helper()
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("WriteJSON() error = %v", err)
+		}
+		return read()
+	}
+	register := Message{ID: "1", Type: MessageTypeWorkerRegister, Data: map[string]interface{}{
+		"tools": []map[string]interface{}{{"name": "shell", "description": "Run a command"}},
+	}}
+
+	// Registering tools requires the worker role
+	if resp := roundTrip(register); resp.Type != MessageTypeError {
+		t.Errorf("register before auth: got %s", resp.Type)
+	}
+	if resp := roundTrip(Message{ID: "2", Type: MessageTypeAuth, Data: map[string]interface{}{"token": "work"}}); resp.Data["role"] != RoleWorker {
+		t.Fatalf("auth: got %+v", resp)
+	}
+	if resp := roundTrip(Message{ID: "3", Type: MessageTypeChat, Content: "hi"}); resp.Type != MessageTypeError {
+		t.Errorf("worker chat: got %s", resp.Type)
+	}
+	if resp := roundTrip(register); resp.Type != MessageTypeResponse {
+		t.Fatalf("register: got %s (%s)", resp.Type, resp.Error)
+	}
+	tool := tools.get("worker:home/home_shell")
+	if tool == nil || !strings.HasPrefix(tool.Description(), "[runs on home]") {
+		t.Fatalf("registered tools = %v", tools.tools)
+	}
+	if workers := gw.Workers(); len(workers) != 1 || workers[0].Name != "home" {
+		t.Errorf("Workers() = %+v", workers)
+	}
+
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := tool.Execute(context.Background(), json.RawMessage(`{"command":"uptime"}`))
+		done <- result{out, err}
+	}()
+	call := read()
+	if call.Type != MessageTypeEvent || call.Content != "tool.call" || call.Data["tool"] != "shell" || call.Data["args"] != `{"command":"uptime"}` {
+		t.Fatalf("tool call: got %+v", call)
+	}
+	if resp := roundTrip(Message{ID: "4", Type: MessageTypeToolResult, Data: map[string]interface{}{"call_id": call.Data["call_id"], "result": "up 3 days"}}); resp.Type != MessageTypeResponse {
+		t.Fatalf("tool result: got %s (%s)", resp.Type, resp.Error)
+	}
+	if r := <-done; r.err != nil || r.out != "up 3 days" {
+		t.Errorf("Execute() = %q, %v", r.out, r.err)
+	}
+
+	// Calls in flight fail and tools are removed when the worker leaves
+	go func() {
+		out, err := tool.Execute(context.Background(), json.RawMessage(`{}`))
+		done <- result{out, err}
+	}()
+	read()
+	conn.Close()
+	if r := <-done; !errors.Is(r.err, errWorkerGone) {
+		t.Errorf("Execute() after disconnect error = %v, want errWorkerGone", r.err)
+	}
+	if tools.get("worker:home/home_shell") != nil {
+		t.Error("tool still registered after disconnect")
+	}
+}
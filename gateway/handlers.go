@@ -2,7 +2,13 @@ package gateway
 
 import (
 	"context"
+	"encoding/base64"
+	"strings"
 	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/eval"
+	"github.com/plexusone/omniagent/flags"
 )
 
 // DefaultMessageHandler provides a basic message handler implementation.
@@ -17,6 +23,9 @@ func NewDefaultMessageHandler(gw *Gateway) *DefaultMessageHandler {
 
 // Handle processes incoming messages.
 func (h *DefaultMessageHandler) Handle(ctx context.Context, client *Client, msg *Message) (*Message, error) {
+	if role := clientRole(client); !allowed(role, msg.Type) {
+		return NewErrorMessage(msg.ID, "forbidden: not allowed for the "+role+" role"), nil
+	}
 	switch msg.Type {
 	case MessageTypePing:
 		return h.handlePing(ctx, client, msg)
@@ -26,6 +35,16 @@ func (h *DefaultMessageHandler) Handle(ctx context.Context, client *Client, msg
 		return h.handleAuth(ctx, client, msg)
 	case MessageTypeSubscribe:
 		return h.handleSubscribe(ctx, client, msg)
+	case MessageTypeFeedback:
+		return h.handleFeedback(ctx, client, msg)
+	case MessageTypeApproval:
+		return h.handleApproval(ctx, client, msg)
+	case MessageTypeScheduleCreate, MessageTypeScheduleRemove, MessageTypeScheduleList:
+		return h.handleSchedule(ctx, client, msg)
+	case MessageTypeWorkerRegister:
+		return h.handleWorkerRegister(ctx, client, msg)
+	case MessageTypeToolResult:
+		return h.handleToolResult(ctx, client, msg)
 	default:
 		return NewErrorMessage(msg.ID, "unknown message type"), nil
 	}
@@ -52,27 +71,156 @@ func (h *DefaultMessageHandler) handleChat(ctx context.Context, client *Client,
 		}, nil
 	}
 
+	if limiter := h.gateway.config.RateLimiter; limiter != nil {
+		if d := limiter.Allow("gateway", client.ID); !d.Allowed {
+			return NewErrorMessage(msg.ID, d.Error().Error()), nil
+		}
+		ctx = agent.WithUsageFunc(ctx, func(promptTokens, completionTokens int) {
+			limiter.AddTokens("gateway", client.ID, promptTokens+completionTokens)
+		})
+	}
+
+	// Files attached by tools are returned base64-encoded in the response
+	atts := &agent.Attachments{}
+	ctx = agent.WithAttachments(ctx, atts)
+
+	// Context such as location or the current page, stored on the session
+	if md, ok := msg.Data["metadata"].(map[string]interface{}); ok && len(md) > 0 {
+		ctx = agent.WithMetadata(ctx, md)
+	}
+
+	// Stream long-running tool output as events before the response
+	if client != nil && h.gateway.config.Flags.Enabled(flags.Streaming) {
+		ctx = agent.WithProgress(ctx, func(tool, text string) {
+			client.Send(NewEventMessage("tool.progress", msg.Channel, map[string]interface{}{
+				"id":   msg.ID,
+				"tool": tool,
+				"text": text,
+			}))
+		})
+	}
+
 	// Process through agent
 	// Use client ID as session ID for conversation continuity
+	if rp, ok := h.gateway.agent.(ResultProcessor); ok {
+		result, err := rp.ProcessWithResult(ctx, client.ID, msg.Content)
+		if err != nil {
+			return NewErrorMessage(msg.ID, err.Error()), nil
+		}
+		resp := &Message{
+			ID:        msg.ID,
+			Type:      MessageTypeResponse,
+			Content:   result.Content,
+			Channel:   msg.Channel,
+			Timestamp: time.Now(),
+		}
+		if result.TraceID != "" || result.Variant != "" {
+			resp.Data = map[string]interface{}{}
+			if result.TraceID != "" {
+				resp.Data["trace_id"] = result.TraceID
+			}
+			if result.Variant != "" {
+				resp.Data["variant"] = result.Variant
+			}
+		}
+		addAttachments(resp, atts.Outgoing())
+		return resp, nil
+	}
+
 	response, err := h.gateway.agent.Process(ctx, client.ID, msg.Content)
 	if err != nil {
 		return NewErrorMessage(msg.ID, err.Error()), nil
 	}
 
-	return &Message{
+	resp := &Message{
 		ID:        msg.ID,
 		Type:      MessageTypeResponse,
 		Content:   response,
 		Channel:   msg.Channel,
 		Timestamp: time.Now(),
+	}
+	addAttachments(resp, atts.Outgoing())
+	return resp, nil
+}
+
+// addAttachments adds files to a response as Data["attachments"], a list
+// of objects with filename, mime_type, and base64 data.
+func addAttachments(resp *Message, files []agent.Attachment) {
+	if len(files) == 0 {
+		return
+	}
+	list := make([]interface{}, 0, len(files))
+	for _, f := range files {
+		list = append(list, map[string]interface{}{
+			"filename":  f.Filename,
+			"mime_type": f.MimeType,
+			"data":      base64.StdEncoding.EncodeToString(f.Data),
+		})
+	}
+	if resp.Data == nil {
+		resp.Data = map[string]interface{}{}
+	}
+	resp.Data["attachments"] = list
+}
+
+// handleFeedback records a rating for a traced response of the client's
+// session. Data carries trace_id, rating ("up" or "down"), and an
+// optional comment.
+func (h *DefaultMessageHandler) handleFeedback(ctx context.Context, client *Client, msg *Message) (*Message, error) {
+	store := h.gateway.config.Feedback
+	if store == nil {
+		return NewErrorMessage(msg.ID, "feedback not enabled"), nil
+	}
+
+	traceID, _ := msg.Data["trace_id"].(string)
+	ratingStr, _ := msg.Data["rating"].(string)
+	comment, _ := msg.Data["comment"].(string)
+	if traceID == "" {
+		return NewErrorMessage(msg.ID, "trace_id required"), nil
+	}
+	if client == nil {
+		return NewErrorMessage(msg.ID, "feedback requires a client session"), nil
+	}
+	rating, err := eval.ParseRating(ratingStr)
+	if err != nil {
+		return NewErrorMessage(msg.ID, err.Error()), nil
+	}
+
+	if err := store.RecordFeedback(ctx, eval.Feedback{
+		TraceID:   traceID,
+		SessionID: client.ID, // Chat uses the client ID as the session ID
+		Rating:    rating,
+		Comment:   comment,
+		Source:    "gateway",
+	}); err != nil {
+		return NewErrorMessage(msg.ID, err.Error()), nil
+	}
+
+	return &Message{
+		ID:        msg.ID,
+		Type:      MessageTypeResponse,
+		Data:      map[string]interface{}{"recorded": true},
+		Timestamp: time.Now(),
 	}, nil
 }
 
-// handleAuth handles authentication messages.
+// handleAuth handles authentication messages. A token matching an
+// observer makes the client a read-only observer, one matching an
+// approver lets it decide tool calls, and one matching a worker lets it
+// advertise tools.
 func (h *DefaultMessageHandler) handleAuth(_ context.Context, client *Client, msg *Message) (*Message, error) {
+	token, _ := msg.Data["token"].(string)
+	current := clientRole(client)
+	role := h.gateway.setTokenRole(client, token)
+	if role == RoleClient && current != RoleClient {
+		// Restricted clients cannot change role by re-authenticating
+		return NewErrorMessage(msg.ID, "invalid "+current+" token"), nil
+	}
+
 	// TODO: Implement proper authentication
-	// For now, accept all auth requests
+	// For now, accept all other auth requests
 	client.SetMetadata("authenticated", true)
+	client.SetMetadata("role", role)
 
 	return &Message{
 		ID:   msg.ID,
@@ -80,6 +228,7 @@ func (h *DefaultMessageHandler) handleAuth(_ context.Context, client *Client, ms
 		Data: map[string]interface{}{
 			"authenticated": true,
 			"client_id":     client.ID,
+			"role":          role,
 		},
 		Timestamp: time.Now(),
 	}, nil
@@ -91,6 +240,19 @@ func (h *DefaultMessageHandler) handleSubscribe(_ context.Context, client *Clien
 	if channel == "" {
 		return NewErrorMessage(msg.ID, "channel required"), nil
 	}
+	if channel == ApprovalsChannel && clientRole(client) != RoleApprover {
+		return NewErrorMessage(msg.ID, "forbidden: approvals require the approver role"), nil
+	}
+	if name, ok := strings.CutPrefix(channel, ActivityChannel); ok && (name == "" || name[0] == ':') {
+		v, _ := client.GetMetadata("observer")
+		o, isObserver := v.(*ObserverConfig)
+		if !isObserver {
+			return NewErrorMessage(msg.ID, "forbidden: activity requires the observer role"), nil
+		}
+		if name != "" && !o.canWatch(name[1:]) {
+			return NewErrorMessage(msg.ID, "forbidden: channel not allowed for this observer"), nil
+		}
+	}
 
 	// Store subscription in client metadata
 	subs, _ := client.GetMetadata("subscriptions")
@@ -0,0 +1,308 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/privacy"
+)
+
+// HTTPConfig configures the inbound HTTP channel at POST /v1/messages.
+type HTTPConfig struct {
+	// Token is required as a bearer token when set.
+	Token string
+
+	// CallbackTimeout bounds delivery of async replies (default: 30s).
+	CallbackTimeout time.Duration
+
+	// CallbackSecret signs callback bodies with HMAC-SHA256 in the
+	// X-Omniagent-Signature-256 header; unsigned when empty.
+	CallbackSecret string
+
+	// CallbackHosts are the host names callbacks may reach on private
+	// networks. Other hosts must resolve to public addresses.
+	CallbackHosts []string
+}
+
+// maxHTTPMessageBytes limits the request body of POST /v1/messages.
+const maxHTTPMessageBytes = 1 << 20
+
+// HTTPMessageRequest is the body of POST /v1/messages.
+type HTTPMessageRequest struct {
+	SessionID   string         `json:"session_id"`
+	Content     string         `json:"content"`
+	CallbackURL string         `json:"callback_url,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"` // Context such as location or current URL
+}
+
+// HTTPMessageResponse is the agent's reply, returned synchronously or
+// posted to the callback URL.
+type HTTPMessageResponse struct {
+	SessionID   string           `json:"session_id"`
+	Reply       string           `json:"reply,omitempty"`
+	TraceID     string           `json:"trace_id,omitempty"`
+	Variant     string           `json:"variant,omitempty"`
+	Attachments []HTTPAttachment `json:"attachments,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// HTTPAttachment is a file returned with a reply.
+type HTTPAttachment struct {
+	Filename string `json:"filename"`
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"` // base64
+}
+
+// handleHTTPMessage handles POST /v1/messages. Without a callback URL the
+// reply is returned in the response; with one the request is accepted with
+// 202 and the reply is posted to the callback when ready.
+func (g *Gateway) handleHTTPMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeHTTPError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !g.authorizeHTTP(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req HTTPMessageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHTTPMessageBytes)).Decode(&req); err != nil {
+		writeHTTPError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.SessionID == "" || req.Content == "" {
+		writeHTTPError(w, http.StatusBadRequest, "session_id and content are required")
+		return
+	}
+	if req.CallbackURL != "" {
+		if err := g.validateCallbackURL(r.Context(), req.CallbackURL); err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if limiter := g.config.RateLimiter; limiter != nil {
+		if d := limiter.Allow("http", req.SessionID); !d.Allowed {
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(d.RetryAfter.Seconds())+1))
+			writeHTTPError(w, http.StatusTooManyRequests, d.Error().Error())
+			return
+		}
+	}
+
+	if req.CallbackURL == "" {
+		resp := g.processHTTPMessage(r.Context(), req)
+		status := http.StatusOK
+		if resp.Error != "" {
+			status = http.StatusInternalServerError
+		}
+		writeHTTPJSON(w, status, resp)
+		return
+	}
+
+	// The reply outlives the request, so it runs as a gateway worker that
+	// is drained at shutdown
+	err := g.workers.Spawn("http-callback", func(ctx context.Context) error {
+		resp := g.processHTTPMessage(ctx, req)
+		if err := g.deliverCallback(ctx, req.CallbackURL, resp); err != nil {
+			return fmt.Errorf("http callback for session %s: %w", req.SessionID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		writeHTTPError(w, http.StatusServiceUnavailable, "gateway is shutting down")
+		return
+	}
+	writeHTTPJSON(w, http.StatusAccepted, HTTPMessageResponse{SessionID: req.SessionID})
+}
+
+// processHTTPMessage runs a message through the agent. HTTP sessions are
+// prefixed with "http:" so they cannot collide with WebSocket clients.
+func (g *Gateway) processHTTPMessage(ctx context.Context, req HTTPMessageRequest) HTTPMessageResponse {
+	resp := HTTPMessageResponse{SessionID: req.SessionID}
+	if g.agent == nil {
+		resp.Reply = "Message received: " + req.Content
+		return resp
+	}
+
+	if limiter := g.config.RateLimiter; limiter != nil {
+		ctx = agent.WithUsageFunc(ctx, func(promptTokens, completionTokens int) {
+			limiter.AddTokens("http", req.SessionID, promptTokens+completionTokens)
+		})
+	}
+	atts := &agent.Attachments{}
+	ctx = agent.WithAttachments(ctx, atts)
+	if len(req.Metadata) > 0 {
+		ctx = agent.WithMetadata(ctx, req.Metadata)
+	}
+
+	sessionID := "http:" + req.SessionID
+	if rp, ok := g.agent.(ResultProcessor); ok {
+		result, err := rp.ProcessWithResult(ctx, sessionID, req.Content)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Reply = result.Content
+		resp.TraceID = result.TraceID
+		resp.Variant = result.Variant
+	} else {
+		reply, err := g.agent.Process(ctx, sessionID, req.Content)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Reply = reply
+	}
+
+	for _, f := range atts.Outgoing() {
+		resp.Attachments = append(resp.Attachments, HTTPAttachment{
+			Filename: f.Filename,
+			MimeType: f.MimeType,
+			Data:     base64.StdEncoding.EncodeToString(f.Data),
+		})
+	}
+	return resp
+}
+
+// deliverCallback posts a reply to the caller's callback URL.
+func (g *Gateway) deliverCallback(ctx context.Context, callbackURL string, resp HTTPMessageResponse) error {
+	timeout := g.config.HTTP.CallbackTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("encode reply: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if secret := g.config.HTTP.CallbackSecret; secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		httpReq.Header.Set("X-Omniagent-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	// Checking addresses at connect time also covers DNS names that
+	// resolve to internal addresses
+	control := publicAddressOnly
+	if g.callbackHostListed(httpReq.URL.Hostname()) {
+		control = nil
+		if g.config.LocalOnly {
+			control = privacy.DialControl
+		}
+	}
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse // A redirect could leave the listed host
+		},
+	}
+	httpResp, err := client.Do(httpReq) //nolint:gosec // G704: Callback addresses are restricted by the dialer
+	if err != nil {
+		return fmt.Errorf("post callback: %w", err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned %s", httpResp.Status)
+	}
+	return nil
+}
+
+// authorizeHTTP checks the bearer token when one is configured.
+func (g *Gateway) authorizeHTTP(r *http.Request) bool {
+	if g.config.HTTP == nil || g.config.HTTP.Token == "" {
+		return true
+	}
+	token := g.config.HTTP.Token
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// validateCallbackURL requires an absolute http or https URL whose host
+// is listed in CallbackHosts or, outside privacy mode, is not a private
+// address.
+func (g *Gateway) validateCallbackURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("callback_url must be an absolute http or https URL")
+	}
+	host := u.Hostname()
+	ip := net.ParseIP(host)
+	switch {
+	case g.callbackHostListed(host):
+		if g.config.LocalOnly && !privacy.IsLocalURL(ctx, raw) {
+			return errors.New("callback_url must be a local address in privacy mode")
+		}
+	case g.config.LocalOnly:
+		return errors.New("callback_url host must be listed in gateway.http.callback_hosts in privacy mode")
+	case host == "localhost", ip != nil && !isPublicIP(ip):
+		return errors.New("callback_url host is private; list it in gateway.http.callback_hosts to allow it")
+	}
+	return nil
+}
+
+// callbackHostListed reports whether host is in CallbackHosts.
+func (g *Gateway) callbackHostListed(host string) bool {
+	for _, h := range g.config.HTTP.CallbackHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicAddressOnly is a net.Dialer Control function that refuses
+// loopback, private, and link-local addresses.
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicIP(net.ParseIP(host)) {
+		return fmt.Errorf("callback address %s is not public", host)
+	}
+	return nil
+}
+
+// isPublicIP reports whether ip is a routable public address.
+func isPublicIP(ip net.IP) bool {
+	return ip != nil && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsUnspecified()
+}
+
+// writeHTTPJSON writes v as a JSON response.
+func writeHTTPJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeHTTPError writes a JSON error response.
+func writeHTTPError(w http.ResponseWriter, status int, msg string) {
+	writeHTTPJSON(w, status, map[string]string{"error": msg})
+}
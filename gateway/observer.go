@@ -0,0 +1,182 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"regexp"
+	"slices"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Client roles assigned on auth.
+const (
+	RoleClient   = "client"   // May chat, schedule, and give feedback
+	RoleObserver = "observer" // Read-only; may only watch activity
+)
+
+// ActivityChannel is the subscription channel for live agent activity.
+// Observers subscribe to it for every channel they may watch, or to
+// "activity:<channel>" for one.
+const ActivityChannel = "activity"
+
+// ObserverConfig grants a token read-only access to a redacted live view
+// of conversations and tool activity.
+type ObserverConfig struct {
+	Name  string
+	Token string //nolint:gosec // G117: Token loaded from config file
+
+	// Channels limits the messaging channels the observer may watch;
+	// empty allows all. Gateway WebSocket sessions use "gateway".
+	Channels []string
+}
+
+// observerMessageTypes are the only messages observers may send.
+var observerMessageTypes = []MessageType{MessageTypePing, MessageTypeAuth, MessageTypeSubscribe}
+
+// observerFor returns the observer whose token matches, if any.
+func (g *Gateway) observerFor(token string) *ObserverConfig {
+	if token == "" {
+		return nil
+	}
+	for i := range g.config.Observers {
+		o := &g.config.Observers[i]
+		if tokenEqual(token, o.Token) {
+			return o
+		}
+	}
+	return nil
+}
+
+// tokenEqual compares tokens in constant time.
+func tokenEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// clientRole returns the role of client, defaulting to RoleClient.
+func clientRole(client *Client) string {
+	if client == nil {
+		return RoleClient
+	}
+	if role, ok := client.GetMetadata("role"); ok {
+		if s, ok := role.(string); ok {
+			return s
+		}
+	}
+	return RoleClient
+}
+
+// allowed reports whether role may send messages of type t.
+func allowed(role string, t MessageType) bool {
+	switch role {
+	case RoleObserver:
+		return slices.Contains(observerMessageTypes, t)
+	case RoleApprover:
+		return slices.Contains(approverMessageTypes, t)
+	case RoleWorker:
+		return slices.Contains(workerMessageTypes, t)
+	}
+	return true
+}
+
+// canWatch reports whether the observer may watch channel.
+func (o *ObserverConfig) canWatch(channel string) bool {
+	return len(o.Channels) == 0 || slices.Contains(o.Channels, channel)
+}
+
+// watches reports whether client is an observer subscribed to activity on
+// channel.
+func (c *Client) watches(channel string) bool {
+	v, _ := c.GetMetadata("observer")
+	o, ok := v.(*ObserverConfig)
+	if !ok || !o.canWatch(channel) {
+		return false
+	}
+	return c.subscribed(ActivityChannel) || c.subscribed(ActivityChannel+":"+channel)
+}
+
+// Observe sends a redacted activity event to subscribed observers. Pass it
+// to agent.OnActivity.
+func (g *Gateway) Observe(act agent.Activity) {
+	channel := act.Channel
+	if channel == "" {
+		channel = "gateway"
+	}
+
+	var msg *Message
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, client := range g.clients {
+		if !client.watches(channel) {
+			continue
+		}
+		if msg == nil {
+			msg = activityMessage(act, channel)
+		}
+		client.Send(msg)
+	}
+}
+
+// activityMessage builds the event sent to observers. Session IDs are
+// replaced by a stable hash, and text is redacted.
+func activityMessage(act agent.Activity, channel string) *Message {
+	data := map[string]interface{}{
+		"kind":    act.Kind,
+		"session": channel + ":" + sessionHash(act.SessionID),
+	}
+	if act.Persona != "" {
+		data["persona"] = act.Persona
+	}
+	if act.Content != "" {
+		data["content"] = Redact(act.Content)
+	}
+	if act.Tool != "" {
+		data["tool"] = act.Tool
+	}
+	if len(act.Args) > 0 {
+		data["args"] = Redact(string(act.Args))
+	}
+	if act.Error != "" {
+		data["error"] = Redact(act.Error)
+	}
+	msg := NewEventMessage("activity."+act.Kind, ActivityChannel+":"+channel, data)
+	msg.Timestamp = act.Time
+	return msg
+}
+
+// sessionHash identifies a session without revealing the chat or phone
+// number it contains.
+func sessionHash(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:6])
+}
+
+var (
+	secretPattern = regexp.MustCompile(`(?i)\b(?:sk|pk|rk|ghp|gho|ghs|xox[abpr])[-_][A-Za-z0-9_-]{10,}|\bAKIA[0-9A-Z]{16}\b|bearer\s+[A-Za-z0-9._~+/-]{10,}=*`)
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	numberPattern = regexp.MustCompile(`\+?\(?\d[\d ().-]{6,}\d`)
+)
+
+// minRedactedDigits is the digit count from which a number is treated as a
+// phone, card, or account number. Shorter runs such as dates are kept.
+const minRedactedDigits = 9
+
+// Redact masks secrets, email addresses, and long numbers such as phone
+// and card numbers in s.
+func Redact(s string) string {
+	s = secretPattern.ReplaceAllString(s, "[secret]")
+	s = emailPattern.ReplaceAllString(s, "[email]")
+	return numberPattern.ReplaceAllStringFunc(s, func(m string) string {
+		digits := 0
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minRedactedDigits {
+			return m
+		}
+		return "[number]"
+	})
+}
@@ -11,6 +11,17 @@ const (
 	MessageTypePing      MessageType = "ping"
 	MessageTypeAuth      MessageType = "auth"
 	MessageTypeSubscribe MessageType = "subscribe"
+	MessageTypeFeedback  MessageType = "feedback"
+	MessageTypeApproval  MessageType = "approval"
+
+	// Scheduled tasks
+	MessageTypeScheduleCreate MessageType = "schedule.create"
+	MessageTypeScheduleRemove MessageType = "schedule.remove"
+	MessageTypeScheduleList   MessageType = "schedule.list"
+
+	// Remote tool workers
+	MessageTypeWorkerRegister MessageType = "worker.register"
+	MessageTypeToolResult     MessageType = "tool.result"
 
 	// Gateway -> Client
 	MessageTypeResponse MessageType = "response"
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/plexusone/omniagent/scheduler"
+)
+
+// handleSchedule handles schedule.create, schedule.remove, and schedule.list.
+//
+// schedule.create takes the task fields in Data: name, schedule or at, and
+// prompt. Clients can only schedule prompts, which run in their own
+// session; results arrive as schedule.result events while the client is
+// connected. Tool, message, and delivery fields are refused, as they would
+// bypass the agent's gating or reach other chats. schedule.remove takes
+// Data.id. Clients only see and remove their own tasks.
+func (h *DefaultMessageHandler) handleSchedule(_ context.Context, client *Client, msg *Message) (*Message, error) {
+	sched := h.gateway.config.Scheduler
+	if sched == nil {
+		return NewErrorMessage(msg.ID, "scheduler not enabled"), nil
+	}
+	if client == nil {
+		return NewErrorMessage(msg.ID, "scheduling requires a client session"), nil
+	}
+
+	switch msg.Type {
+	case MessageTypeScheduleCreate:
+		// Round-trip Data through JSON to decode it into a Task
+		raw, err := json.Marshal(msg.Data)
+		if err != nil {
+			return NewErrorMessage(msg.ID, "invalid task data"), nil
+		}
+		var task scheduler.Task
+		if err := json.Unmarshal(raw, &task); err != nil {
+			return NewErrorMessage(msg.ID, "invalid task data: "+err.Error()), nil
+		}
+		if task.Tool != "" || len(task.ToolArgs) > 0 || task.Message != "" || task.Trigger != "" ||
+			task.Channel != "" || task.ChatID != "" {
+			return NewErrorMessage(msg.ID, "only prompt tasks can be scheduled; results are sent to this client"), nil
+		}
+		task.ID = "" // Always generated by the scheduler
+		task.Session = client.ID
+
+		task, err = sched.Add(task)
+		if err != nil {
+			return NewErrorMessage(msg.ID, err.Error()), nil
+		}
+		return scheduleResponse(msg.ID, map[string]interface{}{"task": task}), nil
+
+	case MessageTypeScheduleRemove:
+		id, _ := msg.Data["id"].(string)
+		if id == "" {
+			return NewErrorMessage(msg.ID, "task id required"), nil
+		}
+		if task, err := sched.Get(id); err != nil || task.Session != client.ID {
+			return NewErrorMessage(msg.ID, scheduler.ErrNotFound.Error()), nil
+		}
+		if err := sched.Remove(id); err != nil {
+			return NewErrorMessage(msg.ID, err.Error()), nil
+		}
+		return scheduleResponse(msg.ID, map[string]interface{}{"removed": id}), nil
+
+	default:
+		tasks := []scheduler.Task{}
+		for _, task := range sched.List() {
+			if task.Session == client.ID {
+				tasks = append(tasks, task)
+			}
+		}
+		return scheduleResponse(msg.ID, map[string]interface{}{"tasks": tasks}), nil
+	}
+}
+
+// DeliverTaskResult sends the result of a task scheduled by a client to
+// that client as a schedule.result event. It reports false when the task
+// wasn't scheduled by a client or the client is no longer connected.
+func (g *Gateway) DeliverTaskResult(task scheduler.Task, content string) bool {
+	if task.Session == "" {
+		return false
+	}
+	client := g.GetClient(task.Session)
+	if client == nil {
+		return false
+	}
+	client.Send(NewEventMessage("schedule.result", "", map[string]interface{}{
+		"id":      task.ID,
+		"name":    task.Name,
+		"content": content,
+	}))
+	return true
+}
+
+func scheduleResponse(id string, data map[string]interface{}) *Message {
+	return &Message{
+		ID:        id,
+		Type:      MessageTypeResponse,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+}
+
+// handleListJobs handles GET /v1/admin/jobs, listing scheduled tasks.
+func (g *Gateway) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, map[string][]scheduler.Task{"jobs": g.config.Scheduler.List()})
+}
+
+// handleJobAction handles POST /v1/admin/jobs/{id}/{action}, where action
+// is run, pause, or resume.
+func (g *Gateway) handleJobAction(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	sched := g.config.Scheduler
+	id, action := r.PathValue("id"), r.PathValue("action")
+
+	var task scheduler.Task
+	var err error
+	switch action {
+	case "run":
+		task, err = sched.RunNow(id)
+	case "pause":
+		task, err = sched.Pause(id)
+	case "resume":
+		task, err = sched.Resume(id)
+	default:
+		writeHTTPError(w, http.StatusNotFound, "unknown action")
+		return
+	}
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	g.logger.Warn("scheduled job changed", "id", id, "action", action)
+	writeHTTPJSON(w, http.StatusOK, task)
+}
+
+// handleDeleteJob handles DELETE /v1/admin/jobs/{id}.
+func (g *Gateway) handleDeleteJob(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := r.PathValue("id")
+	if err := g.config.Scheduler.Remove(id); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	g.logger.Warn("scheduled job deleted", "id", id)
+	writeHTTPJSON(w, http.StatusOK, map[string]string{"deleted": id})
+}
+
+// writeJobError maps scheduler errors to HTTP statuses.
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, scheduler.ErrNotFound):
+		writeHTTPError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, scheduler.ErrRunning):
+		writeHTTPError(w, http.StatusConflict, err.Error())
+	default:
+		writeHTTPError(w, http.StatusServiceUnavailable, err.Error())
+	}
+}
@@ -0,0 +1,19 @@
+package gateway
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// clientScript is the browser client for the gateway protocol. The Go
+// client is in the client subpackage.
+//
+//go:embed web/omniagent.js
+var clientScript []byte
+
+// handleClientScript serves the browser client at /sdk/omniagent.js.
+func (g *Gateway) handleClientScript(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	_, _ = w.Write(clientScript)
+}
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/transcripts"
+)
+
+// SessionManager inspects and resets the agent's per-session state.
+type SessionManager interface {
+	Sessions() []agent.SessionState
+	Session(sessionID string) (agent.SessionState, bool)
+	ClearSession(sessionID string) error
+	DeleteSession(sessionID string) error
+}
+
+// SessionInfo is a session's agent state and, when transcripts are
+// recorded, its transcript summary.
+type SessionInfo struct {
+	agent.SessionState
+	Entries int        `json:"entries,omitempty"` // Transcript entries
+	Updated *time.Time `json:"updated,omitempty"` // Last transcript entry
+}
+
+// handleListSessions handles GET /v1/admin/sessions.
+func (g *Gateway) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	byID := make(map[string]*SessionInfo)
+	for _, s := range g.config.Sessions.Sessions() {
+		byID[s.ID] = &SessionInfo{SessionState: s}
+	}
+	if g.config.Transcripts != nil {
+		recorded, err := g.config.Transcripts.Sessions()
+		if err != nil {
+			g.logger.Error("list transcripts failed", "error", err)
+			writeHTTPError(w, http.StatusInternalServerError, "list failed")
+			return
+		}
+		for _, t := range recorded {
+			info, ok := byID[t.ID]
+			if !ok {
+				info = &SessionInfo{SessionState: agent.SessionState{ID: t.ID}}
+				byID[t.ID] = info
+			}
+			info.Entries, info.Updated = t.Entries, &t.Updated
+		}
+	}
+
+	sessions := make([]SessionInfo, 0, len(byID))
+	for _, info := range byID {
+		sessions = append(sessions, *info)
+	}
+	slices.SortFunc(sessions, func(a, b SessionInfo) int { return strings.Compare(a.ID, b.ID) })
+	writeHTTPJSON(w, http.StatusOK, map[string][]SessionInfo{"sessions": sessions})
+}
+
+// handleGetSession handles GET /v1/admin/sessions/{id}.
+func (g *Gateway) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	sessionID := r.PathValue("id")
+	state, found := g.config.Sessions.Session(sessionID)
+	info := SessionInfo{SessionState: state}
+	if g.config.Transcripts != nil {
+		entries, err := g.config.Transcripts.Entries(sessionID)
+		switch {
+		case err == nil:
+			found = true
+			info.Entries = len(entries)
+			if len(entries) > 0 {
+				info.Updated = &entries[len(entries)-1].Time
+			}
+		case !errors.Is(err, transcripts.ErrNotFound):
+			g.logger.Error("read transcript failed", "session", sessionID, "error", err)
+		}
+	}
+	if !found {
+		writeHTTPError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, info)
+}
+
+// handleClearSession handles POST /v1/admin/sessions/{id}/clear, resetting
+// the conversation while keeping the user's preferences and transcript.
+func (g *Gateway) handleClearSession(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	sessionID := r.PathValue("id")
+	if err := g.config.Sessions.ClearSession(sessionID); err != nil {
+		g.logger.Error("failed to clear session", "session", sessionID, "error", err)
+		writeHTTPError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	g.logger.Warn("session cleared", "session", sessionID)
+	writeHTTPJSON(w, http.StatusOK, map[string]string{"cleared": sessionID})
+}
+
+// handleDeleteSession handles DELETE /v1/admin/sessions/{id}, removing all
+// of the session's state and its transcript.
+func (g *Gateway) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	sessionID := r.PathValue("id")
+	if err := g.config.Sessions.DeleteSession(sessionID); err != nil {
+		g.logger.Error("failed to delete session", "session", sessionID, "error", err)
+		writeHTTPError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if g.config.Transcripts != nil {
+		if err := g.config.Transcripts.Delete(sessionID); err != nil && !errors.Is(err, transcripts.ErrNotFound) {
+			g.logger.Error("failed to delete transcript", "session", sessionID, "error", err)
+			writeHTTPError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	g.logger.Warn("session deleted", "session", sessionID)
+	writeHTTPJSON(w, http.StatusOK, map[string]string{"deleted": sessionID})
+}
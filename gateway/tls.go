@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/plexusone/omniagent/credstore"
+)
+
+// TLSConfig configures TLS for the gateway server.
+type TLSConfig struct {
+	// CertFile and KeyFile are paths to a PEM certificate and private key.
+	CertFile string
+	KeyFile  string
+
+	// Autocert obtains certificates from Let's Encrypt automatically.
+	// Mutually exclusive with CertFile/KeyFile.
+	Autocert bool
+
+	// AutocertDomains restricts which host names certificates are issued for.
+	AutocertDomains []string
+
+	// AutocertCacheDir stores issued certificates between restarts.
+	AutocertCacheDir string
+
+	// AutocertPassphrase, if set, keeps the cached account and certificate
+	// keys encrypted at rest.
+	AutocertPassphrase string //nolint:gosec // G117: Passphrase is intentionally stored to seal the cache
+
+	// AutocertEmail is the contact address for the ACME account.
+	AutocertEmail string
+
+	// ChallengeAddress serves ACME HTTP-01 challenges (default: ":80").
+	ChallengeAddress string
+}
+
+// Enabled reports whether TLS is configured.
+func (c *TLSConfig) Enabled() bool {
+	return c != nil && (c.Autocert || c.CertFile != "" || c.KeyFile != "")
+}
+
+// validate checks that the TLS configuration is consistent.
+func (c *TLSConfig) validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.Autocert {
+		if c.CertFile != "" || c.KeyFile != "" {
+			return fmt.Errorf("tls: autocert cannot be combined with cert_file/key_file")
+		}
+		if len(c.AutocertDomains) == 0 {
+			return fmt.Errorf("tls: autocert requires at least one domain")
+		}
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("tls: both cert_file and key_file are required")
+	}
+	return nil
+}
+
+// autocertManager creates the ACME certificate manager.
+func (c *TLSConfig) autocertManager() *autocert.Manager {
+	cacheDir := c.AutocertCacheDir
+	if cacheDir == "" {
+		cacheDir = "autocert"
+	}
+	var cache autocert.Cache = autocert.DirCache(cacheDir)
+	if c.AutocertPassphrase != "" {
+		cache = credstore.NewCache(cacheDir, c.AutocertPassphrase)
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(c.AutocertDomains...),
+		Cache:      cache,
+		Email:      c.AutocertEmail,
+	}
+}
+
+// configureTLS prepares the server for TLS and returns the challenge server
+// for autocert, if any. The caller is responsible for running and shutting it down.
+func (g *Gateway) configureTLS(server *http.Server) *http.Server {
+	cfg := g.config.TLS
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	if !cfg.Autocert {
+		return nil
+	}
+
+	manager := cfg.autocertManager()
+	server.TLSConfig = manager.TLSConfig()
+	server.TLSConfig.MinVersion = tls.VersionTLS12
+
+	challengeAddr := cfg.ChallengeAddress
+	if challengeAddr == "" {
+		challengeAddr = ":80"
+	}
+	return &http.Server{
+		Addr:              challengeAddr,
+		Handler:           manager.HTTPHandler(nil),
+		ReadHeaderTimeout: g.config.ReadTimeout,
+	}
+}
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/plexusone/omniagent/transcripts"
+)
+
+// handleSessions handles GET /v1/sessions, listing recorded sessions.
+func (g *Gateway) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	sessions, err := g.config.Transcripts.Sessions()
+	if err != nil {
+		g.logger.Error("list transcripts failed", "error", err)
+		writeHTTPError(w, http.StatusInternalServerError, "list failed")
+		return
+	}
+	if sessions == nil {
+		sessions = []transcripts.Session{}
+	}
+	writeHTTPJSON(w, http.StatusOK, map[string][]transcripts.Session{"sessions": sessions})
+}
+
+// handleTranscript handles GET /v1/sessions/{id}/transcript. Query
+// parameters: format (markdown, html, or json), tools (include tool calls
+// and results), and attachments (link or inline).
+func (g *Gateway) handleTranscript(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	q := r.URL.Query()
+	format, err := transcripts.ParseFormat(q.Get("format"))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tools, _ := strconv.ParseBool(q.Get("tools"))
+	sessionID := r.PathValue("id")
+	opts := transcripts.Options{
+		Format:     format,
+		Tools:      tools,
+		Inline:     q.Get("attachments") == "inline",
+		LinkPrefix: "/v1/sessions/" + url.PathEscape(sessionID) + "/files/",
+	}
+
+	var buf bytes.Buffer
+	if err := g.config.Transcripts.Export(&buf, sessionID, opts); err != nil {
+		if errors.Is(err, transcripts.ErrNotFound) {
+			writeHTTPError(w, http.StatusNotFound, "transcript not found")
+			return
+		}
+		g.logger.Error("transcript export failed", "session", sessionID, "error", err)
+		writeHTTPError(w, http.StatusInternalServerError, "export failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	_, _ = w.Write(buf.Bytes())
+}
+
+// handleTranscriptFile handles GET /v1/sessions/{id}/files/{name}, serving
+// attachments linked from exported transcripts.
+func (g *Gateway) handleTranscriptFile(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	name := r.PathValue("name")
+	data, err := g.config.Transcripts.ReadFile(r.PathValue("id"), name)
+	if err != nil {
+		writeHTTPError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	ct := mime.TypeByExtension(path.Ext(name))
+	if ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	// Only images display inline; anything else could run script in the
+	// gateway's origin
+	if !strings.HasPrefix(ct, "image/") || ct == "image/svg+xml" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = w.Write(data)
+}
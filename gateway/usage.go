@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/plexusone/omniagent/budget"
+	"github.com/plexusone/omniagent/usage"
+)
+
+// UsageReport is the response of GET /v1/admin/usage.
+type UsageReport struct {
+	Since  time.Time     `json:"since,omitzero"`
+	By     string        `json:"by"`
+	Totals []usage.Total `json:"totals"`
+	Budget *BudgetStatus `json:"budget,omitempty"`
+}
+
+// BudgetStatus is global budget usage in the current day and month.
+type BudgetStatus struct {
+	Status  string       `json:"status"`
+	Daily   budget.Usage `json:"daily"`
+	Monthly budget.Usage `json:"monthly"`
+}
+
+// handleUsage handles GET /v1/admin/usage. The since query parameter is a
+// duration such as 24h (default; 0 for all time), and by groups the
+// totals by provider, model (default), channel, session, or day.
+func (g *Gateway) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	report := UsageReport{By: r.URL.Query().Get("by")}
+	if report.By == "" {
+		report.By = usage.ByModel
+	}
+	period := 24 * time.Hour
+	if s := r.URL.Query().Get("since"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			writeHTTPError(w, http.StatusBadRequest, "since must be a duration such as 24h")
+			return
+		}
+		period = d
+	}
+	if period > 0 {
+		report.Since = time.Now().Add(-period)
+	}
+
+	totals, err := g.config.Usage.Summary(report.Since, report.By)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	report.Totals = totals
+	if report.Totals == nil {
+		report.Totals = []usage.Total{}
+	}
+	if b := g.config.Budget; b != nil {
+		daily, monthly := b.Usage(budget.GlobalScope)
+		report.Budget = &BudgetStatus{Status: b.Check("").Status.String(), Daily: daily, Monthly: monthly}
+	}
+	writeHTTPJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,181 @@
+// OmniAgent gateway client for browsers. Served by the gateway at
+// /sdk/omniagent.js. It authenticates, correlates requests with responses,
+// streams tool progress, and reconnects with backoff when the connection
+// drops.
+//
+//   const client = new OmniAgentClient("ws://127.0.0.1:18789/ws", {token: "..."});
+//   client.onEvent = (event) => console.log(event.name, event.data);
+//   await client.connect();
+//   const reply = await client.chat("Hello", (p) => console.log(p.tool, p.text));
+(function (root) {
+  "use strict";
+
+  class GatewayError extends Error {}
+
+  class OmniAgentClient {
+    constructor(url, options = {}) {
+      this.url = url;
+      this.token = options.token || "";
+      this.deviceId = options.deviceId || "";
+      this.subscriptions = [...(options.subscriptions || [])];
+      this.minBackoff = options.minBackoff || 500;
+      this.maxBackoff = options.maxBackoff || 30000;
+      this.onEvent = null; // (event) => {}
+      this.onState = null; // ("connected" | "disconnected") => {}
+      this.metadata = null; // Sent with each chat, e.g. {location: "home"}
+      this.clientId = "";
+      this._ws = null;
+      this._nextId = 0;
+      this._pending = new Map();
+      this._closed = false;
+      this._backoff = this.minBackoff;
+      this._ready = null;
+    }
+
+    // connect opens the connection, authenticates, and renews
+    // subscriptions. It resolves once the client is ready.
+    connect() {
+      this._closed = false;
+      this._ready = new Promise((resolve, reject) => this._open(resolve, reject));
+      return this._ready;
+    }
+
+    // chat sends a message and resolves with {content, traceId, variant,
+    // attachments}. onProgress receives {tool, text} while tools run.
+    async chat(content, onProgress) {
+      const msg = {type: "chat", content};
+      if (this.metadata) msg.data = {metadata: this.metadata};
+      const resp = await this.request(msg, onProgress);
+      const data = resp.data || {};
+      return {
+        content: resp.content || "",
+        traceId: data.trace_id || "",
+        variant: data.variant || "",
+        attachments: (data.attachments || []).map((a) => ({
+          filename: a.filename,
+          mimeType: a.mime_type,
+          data: a.data, // base64
+        })),
+      };
+    }
+
+    // subscribe subscribes to a channel; it is renewed after reconnects.
+    async subscribe(channel) {
+      await this.request({type: "subscribe", channel});
+      if (!this.subscriptions.includes(channel)) this.subscriptions.push(channel);
+    }
+
+    feedback(traceId, rating, comment = "") {
+      return this.request({type: "feedback", data: {trace_id: traceId, rating, comment}});
+    }
+
+    decide(id, approved) {
+      return this.request({type: "approval", data: {id, approved}});
+    }
+
+    // request sends any message and resolves with its response. Gateway
+    // errors reject with a GatewayError.
+    async request(msg, onProgress) {
+      if (this._closed) throw new Error("gateway client closed");
+      await this._ready;
+      return this._send(msg, onProgress);
+    }
+
+    close() {
+      this._closed = true;
+      if (this._ws) this._ws.close(1000);
+    }
+
+    _send(msg, onProgress) {
+      const id = "c" + ++this._nextId;
+      return new Promise((resolve, reject) => {
+        this._pending.set(id, {resolve, reject, onProgress});
+        try {
+          this._ws.send(JSON.stringify({...msg, id}));
+        } catch (err) {
+          this._pending.delete(id);
+          reject(err);
+        }
+      });
+    }
+
+    // _open connects once. A failed first connect rejects; failed
+    // reconnects retry with the same promise, so waiting requests resume.
+    _open(resolve, reject, retrying = false) {
+      // Browsers cannot set headers on WebSockets, so the handshake token
+      // goes in the query
+      const url = new URL(this.url, root.location && root.location.href);
+      if (this.token) url.searchParams.set("token", this.token);
+      const ws = new WebSocket(url.toString());
+      let ready = false;
+      ws.onopen = async () => {
+        this._ws = ws;
+        try {
+          const auth = await this._send({type: "auth", data: {token: this.token, device_id: this.deviceId}});
+          this.clientId = (auth.data || {}).client_id || "";
+          for (const channel of this.subscriptions) {
+            await this._send({type: "subscribe", channel});
+          }
+        } catch (err) {
+          ws.close();
+          if (!retrying) reject(err);
+          return;
+        }
+        ready = true;
+        this._backoff = this.minBackoff;
+        if (this.onState) this.onState("connected");
+        resolve();
+      };
+      ws.onmessage = (e) => this._dispatch(JSON.parse(e.data));
+      ws.onclose = () => {
+        this._ws = null;
+        for (const call of this._pending.values()) call.reject(new Error("gateway connection lost"));
+        this._pending.clear();
+        if (this._closed) {
+          reject(new Error("gateway client closed"));
+        } else if (ready) {
+          if (this.onState) this.onState("disconnected");
+          this._ready = new Promise((res, rej) => this._retry(res, rej));
+          this._ready.catch(() => {});
+        } else if (retrying) {
+          this._retry(resolve, reject);
+        } else {
+          reject(new Error("could not connect to gateway"));
+        }
+      };
+    }
+
+    _retry(resolve, reject) {
+      const delay = this._backoff / 2 + Math.random() * (this._backoff / 2);
+      this._backoff = Math.min(this._backoff * 2, this.maxBackoff);
+      setTimeout(() => {
+        if (this._closed) reject(new Error("gateway client closed"));
+        else this._open(resolve, reject, true);
+      }, delay);
+    }
+
+    _dispatch(msg) {
+      if (msg.type === "event") {
+        const data = msg.data || {};
+        const call = msg.content === "tool.progress" && this._pending.get(data.id);
+        if (call && call.onProgress) {
+          call.onProgress({tool: data.tool, text: data.text});
+          return;
+        }
+        if (this.onEvent) {
+          this.onEvent({name: msg.content, channel: msg.channel, data, timestamp: msg.timestamp});
+        }
+        return;
+      }
+      const call = this._pending.get(msg.id);
+      if (!call) return;
+      this._pending.delete(msg.id);
+      if (msg.type === "error") call.reject(new GatewayError(msg.error));
+      else call.resolve(msg);
+    }
+  }
+
+  OmniAgentClient.GatewayError = GatewayError;
+  if (typeof module === "object" && module.exports) module.exports = OmniAgentClient;
+  else root.OmniAgentClient = OmniAgentClient;
+})(typeof self !== "undefined" ? self : this);
@@ -0,0 +1,344 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// RoleWorker may only advertise tools and return their results.
+const RoleWorker = "worker"
+
+// defaultWorkerTimeout bounds how long a worker has to answer a call.
+const defaultWorkerTimeout = 5 * time.Minute
+
+// WorkerConfig grants a token the right to connect as a worker: a separate
+// machine that runs tools for the agent. The worker advertises its tools
+// with a worker.register message (Data "tools": name, description, and
+// parameters of each), which are registered as "<name>_<tool>". Calls
+// arrive as "tool.call" events with call_id, tool, and args, answered
+// with a tool.result message carrying call_id and result or error.
+type WorkerConfig struct {
+	Name  string
+	Token string //nolint:gosec // G117: Token loaded from config file
+
+	// Timeout fails calls the worker does not answer in time
+	// (default: 5m).
+	Timeout time.Duration
+}
+
+// ToolRegistrar adds and removes the agent tools workers advertise.
+// *agent.Agent implements ToolRegistrar.
+type ToolRegistrar interface {
+	RegisterToolFrom(source string, tool agent.Tool) error
+	UnregisterTool(id string)
+}
+
+// Ensure Agent implements ToolRegistrar.
+var _ ToolRegistrar = (*agent.Agent)(nil)
+
+// WorkerInfo describes a connected worker.
+type WorkerInfo struct {
+	Name        string    `json:"name"`
+	ClientID    string    `json:"client_id"`
+	RemoteAddr  string    `json:"remote_addr,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Tools       []string  `json:"tools"` // Agent tool names
+	Pending     int       `json:"pending"`
+}
+
+// workerMessageTypes are the only messages workers may send.
+var workerMessageTypes = []MessageType{MessageTypePing, MessageTypeAuth, MessageTypeWorkerRegister, MessageTypeToolResult}
+
+var (
+	invalidToolChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+	// errWorkerGone is returned for calls in flight when a worker
+	// disconnects or is replaced.
+	errWorkerGone = errors.New("worker disconnected")
+)
+
+// toolWorker is a connected worker and its calls in flight.
+type toolWorker struct {
+	config  *WorkerConfig
+	client  *Client
+	tools   []string // Agent tool names
+	pending map[string]chan toolResult
+	nextID  atomic.Uint64
+	mu      sync.Mutex
+}
+
+// toolResult is a worker's answer to a call.
+type toolResult struct {
+	result string
+	err    error
+}
+
+// workerSource returns the tool source of a worker, used in tool IDs
+// ("worker:home/home_shell").
+func workerSource(name string) string {
+	return "worker:" + name
+}
+
+// workerToolName prefixes a worker's tool name so tools from different
+// workers cannot collide, keeping within the 64-character limit of model
+// APIs.
+func workerToolName(worker, tool string) string {
+	full := invalidToolChars.ReplaceAllString(worker+"_"+tool, "_")
+	if len(full) > 64 {
+		full = full[:64]
+	}
+	return full
+}
+
+// workerFor returns the worker whose token matches, if any.
+func (g *Gateway) workerFor(token string) *WorkerConfig {
+	if token == "" {
+		return nil
+	}
+	for i := range g.config.Workers {
+		w := &g.config.Workers[i]
+		if tokenEqual(token, w.Token) {
+			return w
+		}
+	}
+	return nil
+}
+
+// handleWorkerRegister registers the tools a worker advertises. A worker
+// connecting again replaces its previous connection.
+func (h *DefaultMessageHandler) handleWorkerRegister(_ context.Context, client *Client, msg *Message) (*Message, error) {
+	g := h.gateway
+	v, _ := client.GetMetadata("worker")
+	cfg, ok := v.(*WorkerConfig)
+	if !ok || g.config.Tools == nil {
+		return NewErrorMessage(msg.ID, "forbidden: registering tools requires the worker role"), nil
+	}
+	var specs []struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		Parameters  map[string]interface{} `json:"parameters"`
+	}
+	data, _ := json.Marshal(msg.Data["tools"])
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return NewErrorMessage(msg.ID, "invalid tools: "+err.Error()), nil
+	}
+
+	w := &toolWorker{config: cfg, client: client, pending: make(map[string]chan toolResult)}
+	g.replaceWorker(w)
+	source := workerSource(cfg.Name)
+	for _, s := range specs {
+		if s.Name == "" {
+			continue
+		}
+		params := s.Parameters
+		if params == nil {
+			params = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tool := &remoteTool{
+			name:        workerToolName(cfg.Name, s.Name),
+			remoteName:  s.Name,
+			description: fmt.Sprintf("[runs on %s] %s", cfg.Name, s.Description),
+			parameters:  params,
+			worker:      w,
+		}
+		if err := g.config.Tools.RegisterToolFrom(source, tool); err != nil {
+			g.logger.Warn("skipping worker tool", "worker", cfg.Name, "tool", s.Name, "error", err)
+			continue
+		}
+		w.tools = append(w.tools, tool.name)
+	}
+	g.logger.Info("worker registered", "worker", cfg.Name, "client", client.ID, "tools", w.tools)
+
+	return &Message{
+		ID:        msg.ID,
+		Type:      MessageTypeResponse,
+		Data:      map[string]interface{}{"tools": w.tools},
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// handleToolResult delivers a worker's answer to the waiting call.
+func (h *DefaultMessageHandler) handleToolResult(_ context.Context, client *Client, msg *Message) (*Message, error) {
+	callID, _ := msg.Data["call_id"].(string)
+	w := h.gateway.workerByClient(client)
+	if w == nil {
+		return NewErrorMessage(msg.ID, "worker not registered"), nil
+	}
+	w.mu.Lock()
+	ch, ok := w.pending[callID]
+	delete(w.pending, callID)
+	w.mu.Unlock()
+	if !ok {
+		return NewErrorMessage(msg.ID, "unknown or expired call_id"), nil
+	}
+
+	var res toolResult
+	res.result, _ = msg.Data["result"].(string)
+	if e, _ := msg.Data["error"].(string); e != "" {
+		res.err = errors.New(e)
+	}
+	ch <- res
+	return &Message{ID: msg.ID, Type: MessageTypeResponse, Timestamp: time.Now()}, nil
+}
+
+// replaceWorker makes w the worker's connection, dropping any previous
+// one along with its tools.
+func (g *Gateway) replaceWorker(w *toolWorker) {
+	g.workersMu.Lock()
+	old := g.toolWorkers[w.config.Name]
+	g.toolWorkers[w.config.Name] = w
+	g.workersMu.Unlock()
+	if old != nil {
+		g.releaseWorker(old)
+	}
+}
+
+// dropWorker removes the worker connected as client, if any.
+func (g *Gateway) dropWorker(client *Client) {
+	g.workersMu.Lock()
+	var gone *toolWorker
+	for name, w := range g.toolWorkers {
+		if w.client == client {
+			gone = w
+			delete(g.toolWorkers, name)
+		}
+	}
+	g.workersMu.Unlock()
+	if gone != nil {
+		g.releaseWorker(gone)
+		g.logger.Info("worker disconnected", "worker", gone.config.Name)
+	}
+}
+
+// releaseWorker unregisters a worker's tools and fails its calls in flight.
+func (g *Gateway) releaseWorker(w *toolWorker) {
+	source := workerSource(w.config.Name)
+	for _, name := range w.tools {
+		g.config.Tools.UnregisterTool(agent.ToolID(source, name))
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, ch := range w.pending {
+		ch <- toolResult{err: errWorkerGone}
+		delete(w.pending, id)
+	}
+}
+
+// workerByClient returns the worker connected as client.
+func (g *Gateway) workerByClient(client *Client) *toolWorker {
+	g.workersMu.RLock()
+	defer g.workersMu.RUnlock()
+	for _, w := range g.toolWorkers {
+		if w.client == client {
+			return w
+		}
+	}
+	return nil
+}
+
+// Workers returns the connected workers, sorted by name.
+func (g *Gateway) Workers() []WorkerInfo {
+	g.workersMu.RLock()
+	defer g.workersMu.RUnlock()
+	workers := make([]WorkerInfo, 0, len(g.toolWorkers))
+	for _, w := range g.toolWorkers {
+		w.mu.Lock()
+		pending := len(w.pending)
+		w.mu.Unlock()
+		workers = append(workers, WorkerInfo{
+			Name:        w.config.Name,
+			ClientID:    w.client.ID,
+			RemoteAddr:  w.client.remoteAddr,
+			ConnectedAt: w.client.connectedAt,
+			Tools:       w.tools,
+			Pending:     pending,
+		})
+	}
+	slices.SortFunc(workers, func(a, b WorkerInfo) int { return strings.Compare(a.Name, b.Name) })
+	return workers
+}
+
+// handleListWorkers handles GET /v1/admin/workers.
+func (g *Gateway) handleListWorkers(w http.ResponseWriter, r *http.Request) {
+	if !g.authorizeAdmin(r) {
+		writeHTTPError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, map[string][]WorkerInfo{"workers": g.Workers()})
+}
+
+// remoteTool is an agent tool that runs on a worker.
+type remoteTool struct {
+	name        string
+	remoteName  string
+	description string
+	parameters  map[string]interface{}
+	worker      *toolWorker
+}
+
+// Name returns the tool name.
+func (t *remoteTool) Name() string {
+	return t.name
+}
+
+// Description returns the tool description.
+func (t *remoteTool) Description() string {
+	return t.description
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *remoteTool) Parameters() map[string]interface{} {
+	return t.parameters
+}
+
+// Execute sends the call to the worker as a "tool.call" event and waits
+// for its tool.result.
+func (t *remoteTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	w := t.worker
+	timeout := w.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultWorkerTimeout
+	}
+	callID := strconv.FormatUint(w.nextID.Add(1), 10)
+	ch := make(chan toolResult, 1)
+	w.mu.Lock()
+	w.pending[callID] = ch
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		delete(w.pending, callID)
+		w.mu.Unlock()
+	}()
+
+	w.client.Send(NewEventMessage("tool.call", "", map[string]interface{}{
+		"call_id": callID,
+		"tool":    t.remoteName,
+		"args":    string(args),
+	}))
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			return "", fmt.Errorf("%s on %s: %w", t.remoteName, w.config.Name, res.err)
+		}
+		return res.result, nil
+	case <-timer.C:
+		return "", fmt.Errorf("%s on %s: no result within %s", t.remoteName, w.config.Name, timeout)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,194 @@
+// Package health runs background probes against external providers so
+// failures surface before users notice them.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Probe checks one provider. Check should be cheap; it runs on every
+// interval.
+type Probe struct {
+	Name  string // e.g. "llm", "stt", "tts", "search"
+	Check func(ctx context.Context) error
+}
+
+// Status is the latest result of a probe.
+type Status struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	Latency   string    `json:"latency,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+	Failures  int       `json:"consecutive_failures,omitempty"`
+
+	// Since is when the probe last changed between healthy and unhealthy.
+	Since time.Time `json:"since"`
+}
+
+// Event reports a probe changing between healthy and unhealthy.
+type Event struct {
+	Probe   string
+	Healthy bool
+	Error   string
+}
+
+// String describes the event for the owner.
+func (e Event) String() string {
+	if e.Healthy {
+		return fmt.Sprintf("%s provider recovered.", e.Probe)
+	}
+	return fmt.Sprintf("%s provider is failing: %s", e.Probe, e.Error)
+}
+
+// Config configures a Monitor.
+type Config struct {
+	Probes   []Probe
+	Interval time.Duration // Between probe rounds (default: 5m)
+	Timeout  time.Duration // Per probe (default: 15s)
+
+	// FailureThreshold is how many consecutive failures mark a probe
+	// unhealthy (default: 2), so a single blip does not alert.
+	FailureThreshold int
+
+	// Notify is called when a probe becomes unhealthy or recovers.
+	Notify func(Event)
+
+	Logger *slog.Logger
+}
+
+// Monitor runs probes in the background and tracks their status.
+type Monitor struct {
+	config   Config
+	statuses map[string]*Status
+	mu       sync.RWMutex
+	logger   *slog.Logger
+}
+
+// New creates a Monitor. Probes count as healthy until checked.
+func New(config Config) *Monitor {
+	if config.Interval == 0 {
+		config.Interval = 5 * time.Minute
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 15 * time.Second
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 2
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	m := &Monitor{
+		config:   config,
+		statuses: make(map[string]*Status, len(config.Probes)),
+		logger:   config.Logger,
+	}
+	now := time.Now()
+	for _, p := range config.Probes {
+		m.statuses[p.Name] = &Status{Name: p.Name, Healthy: true, Since: now}
+	}
+	return m
+}
+
+// Run probes immediately and then on every interval until the context is
+// canceled.
+func (m *Monitor) Run(ctx context.Context) error {
+	m.logger.Info("health probes started", "probes", len(m.config.Probes), "interval", m.config.Interval)
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.CheckAll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll runs every probe concurrently and records the results.
+func (m *Monitor) CheckAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, p := range m.config.Probes {
+		wg.Add(1)
+		go func(p Probe) {
+			defer wg.Done()
+			m.check(ctx, p)
+		}(p)
+	}
+	wg.Wait()
+}
+
+// check runs one probe and notifies on a state change.
+func (m *Monitor) check(ctx context.Context, p Probe) {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := p.Check(ctx)
+	latency := time.Since(start)
+
+	m.mu.Lock()
+	s := m.statuses[p.Name]
+	s.CheckedAt = start
+	s.Latency = latency.Round(time.Millisecond).String()
+	var event *Event
+	if err == nil {
+		s.Error = ""
+		s.Failures = 0
+		if !s.Healthy {
+			s.Healthy, s.Since = true, start
+			event = &Event{Probe: p.Name, Healthy: true}
+		}
+	} else {
+		s.Error = err.Error()
+		s.Failures++
+		if s.Healthy && s.Failures >= m.config.FailureThreshold {
+			s.Healthy, s.Since = false, start
+			event = &Event{Probe: p.Name, Error: s.Error}
+		}
+	}
+	m.mu.Unlock()
+
+	if err != nil {
+		m.logger.Warn("health probe failed", "probe", p.Name, "error", err, "latency", latency)
+	}
+	if event != nil {
+		m.logger.Info("health status changed", "probe", event.Probe, "healthy", event.Healthy)
+		if m.config.Notify != nil {
+			m.config.Notify(*event)
+		}
+	}
+}
+
+// Statuses returns the latest status of every probe in probe order.
+func (m *Monitor) Statuses() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(m.config.Probes))
+	for _, p := range m.config.Probes {
+		statuses = append(statuses, *m.statuses[p.Name])
+	}
+	return statuses
+}
+
+// Ready reports whether every probe is healthy.
+func (m *Monitor) Ready() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, s := range m.statuses {
+		if !s.Healthy {
+			return false
+		}
+	}
+	return true
+}
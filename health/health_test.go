@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMonitorTransitions(t *testing.T) {
+	var failing bool
+	var events []Event
+	m := New(Config{
+		Probes: []Probe{
+			{Name: "llm", Check: func(context.Context) error {
+				if failing {
+					return errors.New("503 service unavailable")
+				}
+				return nil
+			}},
+			{Name: "tts", Check: func(context.Context) error { return nil }},
+		},
+		FailureThreshold: 2,
+		Notify:           func(e Event) { events = append(events, e) },
+	})
+	ctx := context.Background()
+
+	m.CheckAll(ctx)
+	if !m.Ready() || len(events) != 0 {
+		t.Fatalf("Ready() = %v, events = %v; want ready and no events", m.Ready(), events)
+	}
+
+	// One failure is tolerated
+	failing = true
+	m.CheckAll(ctx)
+	if !m.Ready() {
+		t.Error("Ready() = false after one failure, want true")
+	}
+	if s := m.Statuses()[0]; s.Failures != 1 || s.Error == "" {
+		t.Errorf("status = %+v, want one failure recorded", s)
+	}
+
+	m.CheckAll(ctx)
+	if m.Ready() {
+		t.Error("Ready() = true after two failures, want false")
+	}
+	if len(events) != 1 || events[0].Healthy || events[0].Probe != "llm" {
+		t.Fatalf("events = %+v, want llm failing", events)
+	}
+
+	// Further failures do not repeat the alert
+	m.CheckAll(ctx)
+	if len(events) != 1 {
+		t.Errorf("events = %d, want 1", len(events))
+	}
+
+	failing = false
+	m.CheckAll(ctx)
+	if !m.Ready() || len(events) != 2 || !events[1].Healthy {
+		t.Errorf("Ready() = %v, events = %+v; want recovered", m.Ready(), events)
+	}
+}
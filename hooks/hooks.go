@@ -0,0 +1,301 @@
+// Package hooks runs user-provided WASM modules at points in message
+// handling, so messages can be rewritten or dropped without changing the
+// agent. Each hook is a WASI program that reads an Input as JSON on stdin
+// and writes an Output as JSON on stdout.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/sandbox"
+	"github.com/plexusone/omniagent/skills"
+)
+
+// Point is where in message handling a hook runs.
+type Point string
+
+// Hook points. Hooks for a point live in a subdirectory of the hooks
+// directory with the point's name.
+const (
+	// PrePrompt runs on each incoming message before the agent sees it.
+	PrePrompt Point = "pre_prompt"
+
+	// PreTool runs before each tool call the agent makes. Hooks may
+	// rewrite the arguments or block the call.
+	PreTool Point = "pre_tool"
+
+	// PostResponse runs on each agent reply to a channel message.
+	PostResponse Point = "post_response"
+
+	// PreSend runs on every message sent to a channel, including
+	// notifications and scheduled messages.
+	PreSend Point = "pre_send"
+)
+
+// Points lists the hook points in the order a message passes them.
+var Points = []Point{PrePrompt, PreTool, PostResponse, PreSend}
+
+// skillPointAliases maps OpenClaw hook directory names in skills to points.
+var skillPointAliases = map[string]Point{"on_message": PrePrompt}
+
+// Defaults.
+const (
+	DefaultTimeout       = 5 * time.Second
+	DefaultMemoryLimitMB = 32
+)
+
+// Input is what a hook reads on stdin.
+type Input struct {
+	Point      Point  `json:"point"`
+	Channel    string `json:"channel"`
+	ChatID     string `json:"chat_id"`
+	SenderID   string `json:"sender_id,omitempty"`
+	SenderName string `json:"sender_name,omitempty"`
+	Content    string `json:"content"`
+
+	// Tool and Args describe the call at pre_tool.
+	Tool string          `json:"tool,omitempty"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// Output is what a hook writes on stdout. A hook that writes nothing
+// leaves the message unchanged.
+type Output struct {
+	// Content replaces the message text when set.
+	Content *string `json:"content,omitempty"`
+
+	// Drop stops the message: it is not processed, or not sent. At
+	// pre_tool it blocks the call.
+	Drop bool `json:"drop,omitempty"`
+
+	// Args replaces the tool arguments at pre_tool when set to a JSON
+	// object.
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// Config configures a Manager.
+type Config struct {
+	Dir           string        // Required
+	Timeout       time.Duration // Per hook run (default: 5s)
+	MemoryLimitMB int           // Per hook run (default: 32)
+	FuelLimit     uint64        // Function calls per hook run (default: unlimited)
+	Logger        *slog.Logger
+}
+
+// Manager loads hooks and runs them.
+type Manager struct {
+	sandbox  sandbox.Config     // Base sandbox for skill runtimes
+	runtimes []*sandbox.Runtime // The hooks directory's first, then one per skill
+	hooks    map[Point][]hook   // In run order
+	logger   *slog.Logger
+}
+
+// hook is a compiled module and the runtime it runs in.
+type hook struct {
+	name    string
+	runtime *sandbox.Runtime
+}
+
+// Load compiles the hooks under config.Dir: <dir>/<point>/*.wasm, run in
+// file name order. A missing directory loads no hooks.
+func Load(ctx context.Context, config Config) (*Manager, error) {
+	if config.Dir == "" {
+		return nil, errors.New("hooks: directory required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.MemoryLimitMB <= 0 {
+		config.MemoryLimitMB = DefaultMemoryLimitMB
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	sc := sandbox.DefaultConfig()
+	sc.Timeout = config.Timeout
+	sc.MemoryLimitMB = config.MemoryLimitMB
+	sc.FuelLimit = config.FuelLimit
+	runtime, err := sandbox.NewRuntime(ctx, sc)
+	if err != nil {
+		return nil, fmt.Errorf("create hook runtime: %w", err)
+	}
+	m := &Manager{
+		sandbox:  sc,
+		runtimes: []*sandbox.Runtime{runtime},
+		hooks:    make(map[Point][]hook),
+		logger:   config.Logger,
+	}
+
+	for _, point := range Points {
+		if err := m.compileDir(ctx, runtime, filepath.Join(config.Dir, string(point)), point, string(point)+"/"); err != nil {
+			_ = runtime.Close(ctx)
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// LoadSkill compiles the hooks a skill ships in hooks/<point>/*.wasm,
+// which run after the hooks directory's at each point. The OpenClaw name
+// on_message is accepted for pre_prompt. Each skill's hooks run in their
+// own sandbox, limited to the capabilities the skill declares; the skill
+// directory is mounted only with fs_read or fs_write. On error none of
+// the skill's hooks run.
+func (m *Manager) LoadSkill(ctx context.Context, skill *skills.Skill) error {
+	sc, err := skill.Sandbox(m.sandbox)
+	if err != nil {
+		return err
+	}
+	runtime, err := sandbox.NewRuntime(ctx, sc)
+	if err != nil {
+		return fmt.Errorf("skill %s: create hook runtime: %w", skill.Name, err)
+	}
+
+	dirs := make(map[string]Point, len(Points)+len(skillPointAliases))
+	loaded := make(map[Point]int, len(Points))
+	for _, point := range Points {
+		dirs[string(point)] = point
+		loaded[point] = len(m.hooks[point])
+	}
+	for alias, point := range skillPointAliases {
+		dirs[alias] = point
+	}
+	for _, sub := range slices.Sorted(maps.Keys(dirs)) {
+		point := dirs[sub]
+		prefix := string(point) + "/" + skill.Name + "/"
+		if err := m.compileDir(ctx, runtime, filepath.Join(skill.Path, "hooks", sub), point, prefix); err != nil {
+			for point, n := range loaded {
+				m.hooks[point] = m.hooks[point][:n]
+			}
+			_ = runtime.Close(ctx)
+			return fmt.Errorf("skill %s: %w", skill.Name, err)
+		}
+	}
+	m.runtimes = append(m.runtimes, runtime)
+	return nil
+}
+
+// compileDir compiles the modules in dir, in file name order, as hooks at
+// point named prefix + file name.
+func (m *Manager) compileDir(ctx context.Context, runtime *sandbox.Runtime, dir string, point Point, prefix string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(files)
+	for _, file := range files {
+		wasm, err := os.ReadFile(file) //nolint:gosec // G304: Path under the operator's hooks or skills directory
+		if err != nil {
+			return fmt.Errorf("read hook: %w", err)
+		}
+		name := prefix + strings.TrimSuffix(filepath.Base(file), ".wasm")
+		if err := runtime.Compile(ctx, name, wasm); err != nil {
+			return fmt.Errorf("hook %s: %w", name, err)
+		}
+		m.hooks[point] = append(m.hooks[point], hook{name: name, runtime: runtime})
+	}
+	return nil
+}
+
+// Close releases the hook runtimes.
+func (m *Manager) Close(ctx context.Context) error {
+	var errs []error
+	for _, runtime := range m.runtimes {
+		errs = append(errs, runtime.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Names returns the loaded hooks as point/name, in run order.
+func (m *Manager) Names() []string {
+	var names []string
+	for _, point := range Points {
+		for _, h := range m.hooks[point] {
+			names = append(names, h.name)
+		}
+	}
+	return names
+}
+
+// Has reports whether any hooks run at point.
+func (m *Manager) Has(point Point) bool {
+	return len(m.hooks[point]) > 0
+}
+
+// Run passes in through the hooks at in.Point in turn, each seeing the
+// previous one's content, and returns the final content and whether a hook
+// dropped the message. A hook that fails is logged and skipped, so a broken
+// hook never silences the agent.
+func (m *Manager) Run(ctx context.Context, in Input) (string, bool) {
+	out, drop := m.runAll(ctx, in)
+	return out.Content, drop
+}
+
+// RunTool passes a tool call through the pre_tool hooks, and returns the
+// arguments to call the tool with and whether a hook blocked the call.
+func (m *Manager) RunTool(ctx context.Context, in Input) (json.RawMessage, bool) {
+	in.Point = PreTool
+	out, drop := m.runAll(ctx, in)
+	return out.Args, drop
+}
+
+// runAll runs the hooks at in.Point in turn, applying each one's changes.
+func (m *Manager) runAll(ctx context.Context, in Input) (Input, bool) {
+	for _, h := range m.hooks[in.Point] {
+		out, err := m.run(ctx, h, in)
+		if err != nil {
+			m.logger.Warn("hook failed", "hook", h.name, "error", err)
+			continue
+		}
+		if out.Drop {
+			m.logger.Info("hook dropped message", "hook", h.name, "channel", in.Channel, "chat", in.ChatID, "tool", in.Tool)
+			return Input{}, true
+		}
+		if out.Content != nil {
+			in.Content = *out.Content
+		}
+		if args := bytes.TrimSpace(out.Args); len(args) > 0 {
+			if !bytes.HasPrefix(args, []byte("{")) {
+				m.logger.Warn("hook returned invalid tool arguments", "hook", h.name)
+				continue
+			}
+			in.Args = args
+		}
+	}
+	return in, false
+}
+
+// run executes one hook.
+func (m *Manager) run(ctx context.Context, h hook, in Input) (Output, error) {
+	stdin, err := json.Marshal(in)
+	if err != nil {
+		return Output{}, err
+	}
+	result, err := h.runtime.Execute(ctx, h.name, stdin)
+	if err != nil {
+		return Output{}, err
+	}
+	if result.ExitCode != 0 {
+		return Output{}, fmt.Errorf("exit code %d: %s", result.ExitCode, strings.TrimSpace(string(result.Error)))
+	}
+	var out Output
+	if len(strings.TrimSpace(string(result.Output))) == 0 {
+		return out, nil
+	}
+	if err := json.Unmarshal(result.Output, &out); err != nil {
+		return Output{}, fmt.Errorf("invalid output: %w", err)
+	}
+	return out, nil
+}
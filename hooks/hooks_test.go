@@ -0,0 +1,223 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/skills"
+)
+
+// printModule returns a WASI program that writes output to stdout.
+func printModule(output string) []byte {
+	section := func(id byte, content ...byte) []byte {
+		return append(append([]byte{id}, uleb(len(content))...), content...)
+	}
+	str := func(s string) []byte { return append(uleb(len(s)), s...) }
+
+	n := len(output)
+	iov := []byte{32, 0, 0, 0, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}
+	body := []byte{
+		0x00,       // No locals
+		0x41, 0x01, // fd 1
+		0x41, 0x00, // iovs at 0
+		0x41, 0x01, // 1 iov
+		0x41, 0x10, // nwritten at 16
+		0x10, 0x00, // call fd_write
+		0x1a, // drop
+		0x0b, // end
+	}
+
+	var m []byte
+	m = append(m, 0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00)
+	m = append(m, section(1, 0x02, 0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f, 0x60, 0x00, 0x00)...)
+	imports := append([]byte{0x01}, str("wasi_snapshot_preview1")...)
+	imports = append(append(imports, str("fd_write")...), 0x00, 0x00)
+	m = append(m, section(2, imports...)...)
+	m = append(m, section(3, 0x01, 0x01)...)
+	m = append(m, section(5, 0x01, 0x00, 0x01)...)
+	exports := append(append([]byte{0x02}, str("memory")...), 0x02, 0x00)
+	exports = append(append(exports, str("_start")...), 0x00, 0x01)
+	m = append(m, section(7, exports...)...)
+	m = append(m, section(10, append(append([]byte{0x01}, uleb(len(body))...), body...)...)...)
+	data := append([]byte{0x02, 0x00, 0x41, 0x00, 0x0b}, uleb(len(iov))...)
+	data = append(append(data, iov...), 0x00, 0x41, 0x20, 0x0b)
+	data = append(append(data, uleb(n)...), output...)
+	m = append(m, section(11, data...)...)
+	return m
+}
+
+func uleb(v int) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if v == 0 {
+			return b
+		}
+	}
+}
+
+func writeHook(t *testing.T, dir string, point Point, name, output string) {
+	t.Helper()
+	path := filepath.Join(dir, string(point), name+".wasm")
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, printModule(output), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeProvider records sent messages.
+type fakeProvider struct {
+	provider.Provider
+	sent []string
+}
+
+func (p *fakeProvider) Name() string { return "test" }
+
+func (p *fakeProvider) Send(_ context.Context, _ string, msg provider.OutgoingMessage) error {
+	p.sent = append(p.sent, msg.Content)
+	return nil
+}
+
+type echoAgent struct{}
+
+func (echoAgent) Process(_ context.Context, _, content string) (string, error) {
+	return "echo: " + content, nil
+}
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeHook(t, dir, PrePrompt, "10-rewrite", `{"content":"rewritten"}`)
+	writeHook(t, dir, PrePrompt, "20-noop", ``)
+	writeHook(t, dir, PostResponse, "redact", `{"content":"[redacted]"}`)
+	writeHook(t, dir, PreSend, "drop", `{"drop":true}`)
+	if err := os.WriteFile(filepath.Join(dir, string(PreSend), "broken.wasm"), []byte("not wasm"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(ctx, Config{Dir: dir}); err == nil {
+		t.Fatal("expected invalid module to fail loading")
+	}
+	if err := os.Remove(filepath.Join(dir, string(PreSend), "broken.wasm")); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(ctx, Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close(ctx)
+	if got := len(m.Names()); got != 4 {
+		t.Fatalf("loaded %d hooks, want 4", got)
+	}
+
+	// pre_prompt rewrites; the hook that prints nothing leaves it alone
+	var seen string
+	handler := m.Middleware()(func(_ context.Context, msg provider.IncomingMessage) error {
+		seen = msg.Content
+		return nil
+	})
+	if err := handler(ctx, provider.IncomingMessage{ProviderName: "test", ChatID: "1", Content: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if seen != "rewritten" {
+		t.Errorf("pre_prompt content = %q, want rewritten", seen)
+	}
+
+	reply, err := m.Processor(echoAgent{}).Process(ctx, "test:1", "hi")
+	if err != nil || reply != "[redacted]" {
+		t.Errorf("post_response = %q, %v; want [redacted]", reply, err)
+	}
+
+	p := &fakeProvider{}
+	if err := m.Provider(p).Send(ctx, "1", provider.OutgoingMessage{Content: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.sent) != 0 {
+		t.Errorf("pre_send drop still sent %q", p.sent)
+	}
+}
+
+func TestNoHooks(t *testing.T) {
+	ctx := context.Background()
+	m, err := Load(ctx, Config{Dir: filepath.Join(t.TempDir(), "missing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close(ctx)
+
+	p := &fakeProvider{}
+	send := m.Provider(p)
+	_ = send.Send(ctx, "1", provider.OutgoingMessage{Content: "hi"})
+	_ = send.Send(ctx, "1", provider.OutgoingMessage{})
+	if len(p.sent) != 1 || p.sent[0] != "hi" {
+		t.Errorf("sent = %q, want only hi", p.sent)
+	}
+}
+
+func TestSkillHooks(t *testing.T) {
+	ctx := context.Background()
+	m, err := Load(ctx, Config{Dir: filepath.Join(t.TempDir(), "missing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close(ctx)
+
+	skill := t.TempDir()
+	writeHook(t, filepath.Join(skill, "hooks"), "on_message", "tag", `{"content":"tagged"}`)
+	writeHook(t, filepath.Join(skill, "hooks"), PreTool, "rewrite", `{"args":{"path":"/safe"}}`)
+	if err := m.LoadSkill(ctx, &skills.Skill{Name: "guard", Path: skill}); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := t.TempDir()
+	writeHook(t, filepath.Join(broken, "hooks"), PostResponse, "ok", `{"drop":true}`)
+	if err := os.MkdirAll(filepath.Join(broken, "hooks", string(PreTool)), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "hooks", string(PreTool), "bad.wasm"), []byte("not wasm"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadSkill(ctx, &skills.Skill{Name: "broken", Path: broken}); err == nil {
+		t.Fatal("expected invalid skill hook to fail loading")
+	}
+	undeclared := &skills.Skill{Name: "undeclared", Path: broken, Metadata: skills.SkillMeta{
+		OpenClaw: &skills.OpenClawMeta{Requires: &skills.Requires{Capabilities: []string{"root"}}},
+	}}
+	if err := m.LoadSkill(ctx, undeclared); err == nil {
+		t.Fatal("expected unknown capability to fail loading")
+	}
+	if got := m.Names(); len(got) != 2 || m.Has(PostResponse) {
+		t.Fatalf("hooks = %v, want only the guard skill's", got)
+	}
+
+	content, drop := m.Run(ctx, Input{Point: PrePrompt, Content: "hi"})
+	if drop || content != "tagged" {
+		t.Errorf("on_message = %q, %v; want tagged", content, drop)
+	}
+
+	args, ok := m.ToolHook()(ctx, "read_file", json.RawMessage(`{"path":"/etc/passwd"}`))
+	if !ok || string(args) != `{"path":"/safe"}` {
+		t.Errorf("pre_tool = %s, %v; want rewritten args", args, ok)
+	}
+
+	deny := t.TempDir()
+	writeHook(t, filepath.Join(deny, "hooks"), PreTool, "deny", `{"drop":true}`)
+	if err := m.LoadSkill(ctx, &skills.Skill{Name: "deny", Path: deny}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.ToolHook()(ctx, "read_file", json.RawMessage(`{}`)); ok {
+		t.Error("pre_tool drop did not block the call")
+	}
+}
@@ -0,0 +1,118 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/pipeline"
+)
+
+// Middleware runs the pre_prompt hooks on incoming messages.
+func (m *Manager) Middleware() pipeline.Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			if !m.Has(PrePrompt) {
+				return next(ctx, msg)
+			}
+			content, drop := m.Run(ctx, Input{
+				Point:      PrePrompt,
+				Channel:    msg.ProviderName,
+				ChatID:     msg.ChatID,
+				SenderID:   msg.SenderID,
+				SenderName: msg.SenderName,
+				Content:    msg.Content,
+			})
+			if drop {
+				return nil
+			}
+			msg.Content = content
+			return next(ctx, msg)
+		}
+	}
+}
+
+// ToolHook returns an agent tool hook that runs the pre_tool hooks.
+func (m *Manager) ToolHook() agent.ToolHook {
+	return func(ctx context.Context, tool string, args json.RawMessage) (json.RawMessage, bool) {
+		if !m.Has(PreTool) {
+			return args, true
+		}
+		channel, chatID, _ := strings.Cut(agent.SessionFromContext(ctx), ":")
+		_, senderID, _ := strings.Cut(agent.ContactFromContext(ctx), ":")
+		hooked, drop := m.RunTool(ctx, Input{
+			Channel:    channel,
+			ChatID:     chatID,
+			SenderID:   senderID,
+			SenderName: agent.SenderNameFromContext(ctx),
+			Tool:       tool,
+			Args:       args,
+		})
+		return hooked, !drop
+	}
+}
+
+// Processor wraps an agent so the post_response hooks run on its replies.
+// A dropped reply is returned empty, which the pre_send wrapper doesn't
+// send.
+func (m *Manager) Processor(p provider.AgentProcessor) provider.AgentProcessor {
+	return &processor{manager: m, next: p}
+}
+
+type processor struct {
+	manager *Manager
+	next    provider.AgentProcessor
+}
+
+func (p *processor) Process(ctx context.Context, sessionID, content string) (string, error) {
+	reply, err := p.next.Process(ctx, sessionID, content)
+	if err != nil || !p.manager.Has(PostResponse) {
+		return reply, err
+	}
+	channel, chatID, _ := strings.Cut(sessionID, ":")
+	_, senderID, _ := strings.Cut(agent.ContactFromContext(ctx), ":")
+	reply, drop := p.manager.Run(ctx, Input{
+		Point:    PostResponse,
+		Channel:  channel,
+		ChatID:   chatID,
+		SenderID: senderID,
+		Content:  reply,
+	})
+	if drop {
+		return "", nil
+	}
+	return reply, nil
+}
+
+// Provider wraps a channel so the pre_send hooks run on everything sent
+// through it. Messages left with no text and no media are not sent.
+func (m *Manager) Provider(p provider.Provider) provider.Provider {
+	return &sender{Provider: p, manager: m}
+}
+
+type sender struct {
+	provider.Provider
+	manager *Manager
+}
+
+func (s *sender) Send(ctx context.Context, chatID string, msg provider.OutgoingMessage) error {
+	if s.manager.Has(PreSend) {
+		content, drop := s.manager.Run(ctx, Input{
+			Point:   PreSend,
+			Channel: s.Name(),
+			ChatID:  chatID,
+			Content: msg.Content,
+		})
+		if drop {
+			return nil
+		}
+		msg.Content = content
+	}
+	if msg.Content == "" && len(msg.Media) == 0 {
+		return nil
+	}
+	return s.Provider.Send(ctx, chatID, msg)
+}
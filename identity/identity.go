@@ -0,0 +1,117 @@
+// Package identity links a person's accounts on different channels, such
+// as a Telegram user ID, a WhatsApp JID, and a Discord snowflake, so
+// memory, rate limits, and permissions follow them across channels.
+package identity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Prefix starts person IDs wherever contact IDs are accepted, as in
+// "person:alice".
+const Prefix = "person:"
+
+// Person is one human and the contact IDs ("channel:senderID") they use.
+type Person struct {
+	ID       string
+	Name     string
+	Contacts []string
+}
+
+// Directory resolves contact IDs to people. A nil *Directory knows no one.
+type Directory struct {
+	people    map[string]Person
+	byContact map[string]string // Contact ID to person ID
+}
+
+// New creates a directory. Each contact may belong to one person.
+func New(people []Person) (*Directory, error) {
+	d := &Directory{
+		people:    make(map[string]Person, len(people)),
+		byContact: make(map[string]string),
+	}
+	for _, p := range people {
+		if p.ID == "" || strings.ContainsAny(p.ID, ": ") {
+			return nil, fmt.Errorf("identity %q: id must be non-empty without colons or spaces", p.ID)
+		}
+		if _, ok := d.people[p.ID]; ok {
+			return nil, fmt.Errorf("identity %q: defined twice", p.ID)
+		}
+		for _, c := range p.Contacts {
+			if channel, sender, ok := strings.Cut(c, ":"); !ok || channel == "" || sender == "" {
+				return nil, fmt.Errorf("identity %q: contact %q must be channel:senderID", p.ID, c)
+			}
+			if other, ok := d.byContact[c]; ok {
+				return nil, fmt.Errorf("identity %q: contact %q already belongs to %q", p.ID, c, other)
+			}
+			d.byContact[c] = p.ID
+		}
+		d.people[p.ID] = p
+	}
+	return d, nil
+}
+
+// Resolve returns the person a contact ID belongs to.
+func (d *Directory) Resolve(contactID string) (Person, bool) {
+	if d == nil {
+		return Person{}, false
+	}
+	id, ok := d.byContact[contactID]
+	if !ok {
+		return Person{}, false
+	}
+	return d.people[id], true
+}
+
+// People returns everyone in the directory, sorted by ID.
+func (d *Directory) People() []Person {
+	if d == nil {
+		return nil
+	}
+	out := make([]Person, 0, len(d.people))
+	for _, p := range d.people {
+		out = append(out, p)
+	}
+	slices.SortFunc(out, func(a, b Person) int { return strings.Compare(a.ID, b.ID) })
+	return out
+}
+
+// Contacts replaces the person IDs in a list of contact and person IDs
+// with their contacts, for sending messages.
+func (d *Directory) Contacts(ids []string) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		person, ok := strings.CutPrefix(id, Prefix)
+		if !ok {
+			out = append(out, id)
+			continue
+		}
+		if d != nil {
+			out = append(out, d.people[person].Contacts...)
+		}
+	}
+	return out
+}
+
+// Expand widens a list of contact and person IDs, such as approvers or
+// owners, to every contact of each person it names directly or through
+// one of their contacts. Unknown IDs are kept as they are.
+func (d *Directory) Expand(ids []string) []string {
+	out := slices.Clone(ids)
+	if d == nil {
+		return out
+	}
+	for _, id := range ids {
+		person, ok := strings.CutPrefix(id, Prefix)
+		if !ok {
+			person = d.byContact[id]
+		}
+		if p, ok := d.people[person]; ok {
+			out = append(out, p.Contacts...)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
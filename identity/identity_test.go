@@ -0,0 +1,49 @@
+package identity
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDirectory(t *testing.T) {
+	d, err := New([]Person{
+		{ID: "alice", Name: "Alice", Contacts: []string{"telegram:1", "whatsapp:4915@s.whatsapp.net"}},
+		{ID: "bob", Contacts: []string{"discord:99"}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if p, ok := d.Resolve("whatsapp:4915@s.whatsapp.net"); !ok || p.ID != "alice" {
+		t.Errorf("Resolve() = %+v, %v, want alice", p, ok)
+	}
+	if _, ok := d.Resolve("telegram:2"); ok {
+		t.Error("Resolve() found an unlinked contact")
+	}
+
+	got := d.Expand([]string{"telegram:1", "person:bob", "sms:+1555"})
+	want := []string{"discord:99", "person:bob", "sms:+1555", "telegram:1", "whatsapp:4915@s.whatsapp.net"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expand() = %v, want %v", got, want)
+	}
+	if got := d.Contacts([]string{"person:alice", "sms:+1555", "person:nobody"}); !slices.Equal(got,
+		[]string{"telegram:1", "whatsapp:4915@s.whatsapp.net", "sms:+1555"}) {
+		t.Errorf("Contacts() = %v", got)
+	}
+
+	var none *Directory
+	if _, ok := none.Resolve("telegram:1"); ok || len(none.Expand([]string{"telegram:1"})) != 1 {
+		t.Error("nil directory should know no one")
+	}
+
+	for _, people := range [][]Person{
+		{{ID: ""}},
+		{{ID: "a", Contacts: []string{"telegram"}}},
+		{{ID: "a", Contacts: []string{"telegram:1"}}, {ID: "b", Contacts: []string{"telegram:1"}}},
+		{{ID: "a"}, {ID: "a"}},
+	} {
+		if _, err := New(people); err == nil {
+			t.Errorf("New(%+v) accepted invalid people", people)
+		}
+	}
+}
@@ -0,0 +1,521 @@
+// Package jobs runs long agent tasks, such as deep research or large
+// refactors, in the background. Jobs post progress to the chat they came
+// from, can be listed and canceled with /tasks, and are checkpointed to
+// disk so jobs interrupted by a restart run again.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/pipeline"
+)
+
+// ErrNotFound is returned for unknown job IDs.
+var ErrNotFound = errors.New("job not found")
+
+// Defaults.
+const (
+	DefaultMaxConcurrent     = 2
+	DefaultMaxRuntime        = time.Hour
+	DefaultMaxToolIterations = 25
+	DefaultProgressInterval  = 2 * time.Minute
+	DefaultMaxAttempts       = 3
+
+	// keepFinished is how many finished jobs are kept for /tasks.
+	keepFinished = 20
+
+	// maxProgressLen caps the latest output kept and posted.
+	maxProgressLen = 300
+)
+
+// Status is a job's state.
+type Status string
+
+// Job states.
+const (
+	StatusQueued   Status = "queued"
+	StatusRunning  Status = "running"
+	StatusDone     Status = "done"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+)
+
+// Job is a background agent task.
+type Job struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+
+	// Prompt is the self-contained task given to the agent.
+	Prompt string `json:"prompt"`
+
+	// SessionID is the "channel:chatID" session the job came from;
+	// progress and the result are posted there.
+	SessionID string `json:"session_id"`
+	Contact   string `json:"contact,omitempty"`
+
+	Status   Status    `json:"status"`
+	Progress string    `json:"progress,omitempty"` // Latest tool output
+	Result   string    `json:"result,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Attempts int       `json:"attempts"`
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started,omitempty"`
+	Finished time.Time `json:"finished,omitempty"`
+}
+
+// finished reports whether the job has stopped for good.
+func (j Job) finished() bool {
+	return j.Status == StatusDone || j.Status == StatusFailed || j.Status == StatusCanceled
+}
+
+// Processor runs prompts through the agent.
+type Processor interface {
+	Process(ctx context.Context, sessionID, content string) (string, error)
+}
+
+// Config configures a Manager.
+type Config struct {
+	Agent  Processor       // Required
+	Sender pipeline.Sender // Required
+
+	// Path checkpoints jobs to a JSON file so they survive restarts.
+	// Empty keeps them in memory.
+	Path string
+
+	MaxConcurrent     int           // Jobs run at once; default 2
+	MaxRuntime        time.Duration // Per attempt; default 1h
+	MaxToolIterations int           // Model calls per job; default 25
+	ProgressInterval  time.Duration // Between progress updates; default 2m
+
+	// MaxAttempts bounds how often a job is started, counting restarts
+	// after the gateway stopped mid-job (default: 3).
+	MaxAttempts int
+
+	Logger *slog.Logger
+}
+
+// Manager runs background jobs.
+type Manager struct {
+	config Config
+	logger *slog.Logger
+	jobs   map[string]*entry
+	slots  chan struct{}
+	mu     sync.Mutex
+
+	// base is the context jobs run under, set by Run.
+	base    context.Context
+	running sync.WaitGroup
+	now     func() time.Time
+}
+
+type entry struct {
+	job      Job
+	cancel   context.CancelFunc
+	canceled bool // Canceled by a user rather than a shutdown
+}
+
+// New creates a Manager and loads checkpointed jobs. Unfinished jobs
+// resume when Run is called.
+func New(config Config) (*Manager, error) {
+	if config.Agent == nil {
+		return nil, errors.New("jobs: agent required")
+	}
+	if config.Sender == nil {
+		return nil, errors.New("jobs: sender required")
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if config.MaxRuntime <= 0 {
+		config.MaxRuntime = DefaultMaxRuntime
+	}
+	if config.MaxToolIterations <= 0 {
+		config.MaxToolIterations = DefaultMaxToolIterations
+	}
+	if config.ProgressInterval <= 0 {
+		config.ProgressInterval = DefaultProgressInterval
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	m := &Manager{
+		config: config,
+		logger: config.Logger,
+		jobs:   make(map[string]*entry),
+		slots:  make(chan struct{}, config.MaxConcurrent),
+		now:    time.Now,
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run resumes unfinished jobs and runs new ones until ctx is done. Jobs
+// still running then are checkpointed to resume on the next start.
+func (m *Manager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	m.base = ctx
+	var resumed []*entry
+	for _, e := range m.jobs {
+		if !e.job.finished() {
+			resumed = append(resumed, e)
+		}
+	}
+	slices.SortFunc(resumed, func(a, b *entry) int { return a.job.Created.Compare(b.job.Created) })
+	notices := make(map[Job]string)
+	for _, e := range resumed {
+		if e.job.Attempts >= m.config.MaxAttempts {
+			e.job.Status = StatusFailed
+			e.job.Error = "interrupted by restarts too many times"
+			e.job.Finished = m.now()
+			notices[e.job] = fmt.Sprintf("Task %s (%s) was interrupted by restarts too many times and has stopped.",
+				e.job.ID, e.job.Title)
+			continue
+		}
+		if e.job.Attempts > 0 {
+			notices[e.job] = fmt.Sprintf("Restarting task %s (%s), which was interrupted by a restart.",
+				e.job.ID, e.job.Title)
+		}
+		e.job.Status = StatusQueued
+		m.launch(e)
+	}
+	m.save()
+	m.mu.Unlock()
+	m.logger.Info("job manager started", "resumed", len(resumed))
+	for job, text := range notices {
+		m.notify(job, text)
+	}
+
+	<-ctx.Done()
+	m.running.Wait()
+	return ctx.Err()
+}
+
+// Start queues a job and returns it with its ID.
+func (m *Manager) Start(job Job) (Job, error) {
+	if strings.TrimSpace(job.Prompt) == "" {
+		return Job{}, errors.New("task required")
+	}
+	if _, _, ok := strings.Cut(job.SessionID, ":"); !ok {
+		return Job{}, fmt.Errorf("invalid session %q", job.SessionID)
+	}
+	if job.Title == "" {
+		job.Title = truncate(job.Prompt, 60)
+	}
+	job.ID = newID()
+	job.Status = StatusQueued
+	job.Created = m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &entry{job: job}
+	m.jobs[job.ID] = e
+	if m.base != nil {
+		m.launch(e)
+	}
+	m.prune()
+	m.save()
+	m.logger.Info("job queued", "id", job.ID, "title", job.Title, "session", job.SessionID)
+	return job, nil
+}
+
+// Cancel stops a queued or running job.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok || e.job.finished() {
+		return Job{}, ErrNotFound
+	}
+	e.canceled = true
+	if e.cancel != nil {
+		e.cancel()
+		return e.job, nil
+	}
+
+	// Not launched yet
+	e.job.Status = StatusCanceled
+	e.job.Finished = m.now()
+	m.save()
+	return e.job, nil
+}
+
+// Get returns a job.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return e.job, nil
+}
+
+// List returns the session's jobs, or all jobs for an empty session,
+// newest first.
+func (m *Manager) List(sessionID string) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var jobs []Job
+	for _, e := range m.jobs {
+		if sessionID == "" || e.job.SessionID == sessionID {
+			jobs = append(jobs, e.job)
+		}
+	}
+	slices.SortFunc(jobs, func(a, b Job) int { return b.Created.Compare(a.Created) })
+	return jobs
+}
+
+// launch starts a goroutine that runs the job once a slot is free.
+// Callers must hold the lock.
+func (m *Manager) launch(e *entry) {
+	ctx, cancel := context.WithCancel(m.base)
+	e.cancel = cancel
+	m.running.Add(1)
+	go func() {
+		defer m.running.Done()
+		defer cancel()
+		select {
+		case m.slots <- struct{}{}:
+		case <-ctx.Done():
+			m.finish(e, "", ctx.Err())
+			return
+		}
+		defer func() { <-m.slots }()
+		m.execute(ctx, e)
+	}()
+}
+
+// execute runs a job through the agent, posting progress while it works.
+func (m *Manager) execute(ctx context.Context, e *entry) {
+	m.mu.Lock()
+	e.job.Status = StatusRunning
+	e.job.Attempts++
+	e.job.Started = m.now()
+	job := e.job
+	m.save()
+	m.mu.Unlock()
+	m.logger.Info("job started", "id", job.ID, "attempt", job.Attempts)
+
+	ctx, cancel := context.WithTimeout(ctx, m.config.MaxRuntime)
+	defer cancel()
+	ctx = withJob(ctx, job.ID)
+	ctx = agent.WithMaxToolIterations(ctx, m.config.MaxToolIterations)
+	if job.Contact != "" {
+		ctx = agent.WithContact(ctx, job.Contact)
+	}
+	ctx = agent.WithProgress(ctx, func(tool, text string) {
+		progress := lastLine(text)
+		if tool != "" {
+			progress = tool + ": " + progress
+		}
+		m.mu.Lock()
+		e.job.Progress = progress
+		m.mu.Unlock()
+	})
+
+	done := make(chan struct{})
+	go m.reportProgress(e, done)
+	result, err := m.config.Agent.Process(ctx, job.SessionID, job.Prompt)
+	close(done)
+	m.finish(e, result, err)
+}
+
+// reportProgress posts an update every ProgressInterval until done.
+func (m *Manager) reportProgress(e *entry, done <-chan struct{}) {
+	ticker := time.NewTicker(m.config.ProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		m.mu.Lock()
+		job := e.job
+		m.mu.Unlock()
+		text := fmt.Sprintf("Task %s (%s) is still running, %s so far.", job.ID, job.Title,
+			m.now().Sub(job.Started).Round(time.Second))
+		if job.Progress != "" {
+			text += "\nLatest: " + job.Progress
+		}
+		m.notify(job, text)
+	}
+}
+
+// finish records a job's outcome and posts the result.
+func (m *Manager) finish(e *entry, result string, err error) {
+	m.mu.Lock()
+	stopping := m.base.Err() != nil
+	switch {
+	case err == nil:
+		e.job.Status = StatusDone
+		e.job.Result = result
+	case e.canceled:
+		e.job.Status = StatusCanceled
+	case stopping:
+		// Resume on the next start
+		e.job.Status = StatusQueued
+	case errors.Is(err, context.DeadlineExceeded):
+		e.job.Status = StatusFailed
+		e.job.Error = fmt.Sprintf("timed out after %s", m.config.MaxRuntime)
+	default:
+		e.job.Status = StatusFailed
+		e.job.Error = err.Error()
+	}
+	if e.job.finished() {
+		e.job.Finished = m.now()
+	}
+	e.cancel = nil
+	job := e.job
+	m.save()
+	m.mu.Unlock()
+	m.logger.Info("job stopped", "id", job.ID, "status", job.Status, "error", job.Error)
+
+	switch job.Status {
+	case StatusDone:
+		m.notify(job, fmt.Sprintf("Task %s (%s) is done:\n\n%s", job.ID, job.Title, job.Result))
+	case StatusFailed:
+		m.notify(job, fmt.Sprintf("Task %s (%s) failed: %s", job.ID, job.Title, job.Error))
+	}
+}
+
+// notify posts text to the job's chat.
+func (m *Manager) notify(job Job, text string) {
+	channel, chatID, _ := strings.Cut(job.SessionID, ":")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := m.config.Sender.Send(ctx, channel, chatID, provider.OutgoingMessage{Content: text}); err != nil {
+		m.logger.Warn("failed to post job update", "id", job.ID, "channel", channel, "error", err)
+	}
+}
+
+// prune drops the oldest finished jobs beyond keepFinished. Callers must
+// hold the lock.
+func (m *Manager) prune() {
+	var finished []*entry
+	for _, e := range m.jobs {
+		if e.job.finished() {
+			finished = append(finished, e)
+		}
+	}
+	if len(finished) <= keepFinished {
+		return
+	}
+	slices.SortFunc(finished, func(a, b *entry) int { return b.job.Finished.Compare(a.job.Finished) })
+	for _, e := range finished[keepFinished:] {
+		delete(m.jobs, e.job.ID)
+	}
+}
+
+// load reads checkpointed jobs.
+func (m *Manager) load() error {
+	if m.config.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read jobs: %w", err)
+	}
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return fmt.Errorf("parse jobs: %w", err)
+	}
+	for _, job := range jobs {
+		if job.ID != "" {
+			m.jobs[job.ID] = &entry{job: job}
+		}
+	}
+	return nil
+}
+
+// save checkpoints jobs to the configured path. Callers must hold the
+// lock.
+func (m *Manager) save() {
+	if m.config.Path == "" {
+		return
+	}
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		jobs = append(jobs, e.job)
+	}
+	slices.SortFunc(jobs, func(a, b Job) int { return a.Created.Compare(b.Created) })
+	if err := writeJSON(m.config.Path, jobs); err != nil {
+		m.logger.Error("save jobs failed", "path", m.config.Path, "error", err)
+	}
+}
+
+// writeJSON atomically writes v as indented JSON.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode jobs: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("create jobs dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write jobs: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// lastLine returns the last non-empty line of text, truncated.
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return truncate(lines[len(lines)-1], maxProgressLen)
+}
+
+// truncate cuts s to n runes, marking the cut.
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// newID returns a short random job ID that is easy to type.
+func newID() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// jobKey marks contexts of running jobs.
+type jobKey struct{}
+
+// withJob returns a context recording that job id is running.
+func withJob(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, jobKey{}, id)
+}
+
+// jobFromContext returns the running job's ID, if any.
+func jobFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(jobKey{}).(string)
+	return id
+}
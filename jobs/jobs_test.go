@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// fakeAgent blocks each prompt until released or canceled.
+type fakeAgent struct {
+	started chan string
+	release chan string
+}
+
+func (a *fakeAgent) Process(ctx context.Context, sessionID, content string) (string, error) {
+	a.started <- content
+	agent.ReportProgress(ctx, "step one\nstep two")
+	select {
+	case result := <-a.release:
+		return result, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// recorder is a Sender that records messages.
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recorder) Send(ctx context.Context, providerName, chatID string, msg provider.OutgoingMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, providerName+":"+chatID+":"+msg.Content)
+	return nil
+}
+
+func (r *recorder) all() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.sent, "\n")
+}
+
+func (r *recorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sent) == 0 {
+		return ""
+	}
+	return r.sent[len(r.sent)-1]
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	fa := &fakeAgent{started: make(chan string, 1), release: make(chan string)}
+	rec := &recorder{}
+	m, err := New(Config{Agent: fa, Sender: rec, ProgressInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = m.Run(ctx) }()
+
+	tool := NewTool(m, []string{"telegram"})
+	toolCtx := agent.WithSession(context.Background(), "telegram:42")
+	out, err := tool.Execute(toolCtx, json.RawMessage(`{"title":"research","task":"dig deep"}`))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	id := m.List("telegram:42")[0].ID
+	if !strings.Contains(out, id) {
+		t.Errorf("reply %q lacks job ID", out)
+	}
+	if got := <-fa.started; got != "dig deep" {
+		t.Errorf("prompt = %q", got)
+	}
+
+	waitFor(t, func() bool { return strings.Contains(rec.last(), "Latest: step two") })
+	fa.release <- "findings"
+	waitFor(t, func() bool { return strings.HasSuffix(rec.last(), "is done:\n\nfindings") })
+	if job, _ := m.Get(id); job.Status != StatusDone || job.Result != "findings" {
+		t.Errorf("job = %+v", job)
+	}
+
+	// Cancel a running job from the command
+	cmd := tool.Command()
+	if _, err := cmd.Handler(context.Background(), "telegram:42", "start more work"); err != nil {
+		t.Fatal(err)
+	}
+	<-fa.started
+	jobs := m.List("telegram:42")
+	reply, _ := cmd.Handler(context.Background(), "telegram:7", "cancel "+jobs[0].ID)
+	if !strings.Contains(reply, "No task") {
+		t.Errorf("canceled another chat's task: %q", reply)
+	}
+	if reply, _ := cmd.Handler(context.Background(), "telegram:42", "cancel "+jobs[0].ID); !strings.HasPrefix(reply, "Canceled") {
+		t.Errorf("cancel reply = %q", reply)
+	}
+	waitFor(t, func() bool {
+		job, _ := m.Get(jobs[0].ID)
+		return job.Status == StatusCanceled
+	})
+
+	// Jobs cannot start jobs
+	if _, err := tool.Execute(withJob(toolCtx, id), json.RawMessage(`{"task":"nested"}`)); err == nil {
+		t.Error("started a job from a job")
+	}
+}
+
+func TestJobResumesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	fa := &fakeAgent{started: make(chan string, 1), release: make(chan string)}
+	rec := &recorder{}
+	m, err := New(Config{Agent: fa, Sender: rec, Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { _ = m.Run(ctx); close(done) }()
+	job, err := m.Start(Job{Prompt: "long task", SessionID: "telegram:42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-fa.started
+	cancel()
+	<-done
+
+	m2, err := New(Config{Agent: fa, Sender: rec, Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m2.Get(job.ID); got.Status != StatusQueued || got.Attempts != 1 {
+		t.Fatalf("checkpointed job = %+v", got)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = m2.Run(ctx) }()
+	<-fa.started
+	fa.release <- "ok"
+	waitFor(t, func() bool {
+		got, _ := m2.Get(job.ID)
+		return got.Status == StatusDone && got.Attempts == 2
+	})
+	if sent := rec.all(); !strings.Contains(sent, "Restarting task "+job.ID) {
+		t.Errorf("sent = %q, want a restart notice", sent)
+	}
+}
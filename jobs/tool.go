@@ -0,0 +1,157 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Tool lets the agent hand long tasks to the Manager.
+type Tool struct {
+	manager  *Manager
+	channels []string
+}
+
+// NewTool creates the background task tool. Jobs may only be started from
+// sessions on channels, where their results can be posted; empty allows
+// any.
+func NewTool(manager *Manager, channels []string) *Tool {
+	return &Tool{manager: manager, channels: channels}
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "background_task"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return "Start a task that takes many steps or minutes, such as deep research or a large refactor, " +
+		"in the background. Reply right away; progress and the result are posted to this chat. " +
+		"The task runs without this conversation, so include everything it needs."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Short title shown in progress updates",
+			},
+			"task": map[string]interface{}{
+				"type":        "string",
+				"description": "The complete task, with all context needed",
+			},
+		},
+		"required": []string{"task"},
+	}
+}
+
+// Execute starts a background job for the current session.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Title string `json:"title"`
+		Task  string `json:"task"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+	if jobFromContext(ctx) != "" {
+		return "", errors.New("already running as a background task; do the work directly")
+	}
+	sessionID := agent.SessionFromContext(ctx)
+	if len(t.channels) > 0 && !slices.Contains(t.channels, agent.ChannelFromSession(sessionID)) {
+		return "", errors.New("background tasks are not available in this chat")
+	}
+
+	job, err := t.manager.Start(Job{
+		Title:     params.Title,
+		Prompt:    params.Task,
+		SessionID: sessionID,
+		Contact:   agent.ContactFromContext(ctx),
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Started background task %s. Progress and the result will be posted to this chat; "+
+		"it can be checked with /tasks or canceled with /tasks cancel %s.", job.ID, job.ID), nil
+}
+
+// Command returns the /tasks command, which lists the chat's background
+// jobs, cancels one, or starts one directly.
+func (t *Tool) Command() agent.Command {
+	return agent.Command{
+		Name:        "tasks",
+		Description: "List, start, or cancel background tasks in this chat",
+		Usage:       "/tasks [start <task> | cancel <id>]",
+		Handler:     t.tasksCommand,
+	}
+}
+
+// tasksCommand implements /tasks.
+func (t *Tool) tasksCommand(ctx context.Context, sessionID, args string) (string, error) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+	switch strings.ToLower(sub) {
+	case "":
+		return t.list(sessionID), nil
+
+	case "start":
+		if rest == "" {
+			return "Usage: /tasks start <task>", nil
+		}
+		return t.Execute(agent.WithSession(ctx, sessionID), mustJSON(map[string]string{"task": rest}))
+
+	case "cancel":
+		job, err := t.manager.Get(rest)
+		if err != nil || job.SessionID != sessionID {
+			return fmt.Sprintf("No task %q in this chat.", rest), nil
+		}
+		if _, err := t.manager.Cancel(rest); err != nil {
+			return fmt.Sprintf("Task %s already stopped.", rest), nil
+		}
+		return fmt.Sprintf("Canceled task %s (%s).", job.ID, job.Title), nil
+
+	default:
+		return "Usage: /tasks [start <task> | cancel <id>]", nil
+	}
+}
+
+// list renders the session's jobs.
+func (t *Tool) list(sessionID string) string {
+	jobs := t.manager.List(sessionID)
+	if len(jobs) == 0 {
+		return "No background tasks in this chat."
+	}
+	now := t.manager.now()
+	var b strings.Builder
+	b.WriteString("Background tasks:\n")
+	for _, job := range jobs {
+		fmt.Fprintf(&b, "- %s %s: %s", job.ID, job.Status, job.Title)
+		switch job.Status {
+		case StatusRunning:
+			fmt.Fprintf(&b, " (%s so far)", now.Sub(job.Started).Round(time.Second))
+		case StatusFailed:
+			fmt.Fprintf(&b, " (%s)", job.Error)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// mustJSON encodes v, which must be encodable.
+func mustJSON(v any) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
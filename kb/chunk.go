@@ -0,0 +1,59 @@
+package kb
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk splits text into pieces of about size bytes, breaking between
+// paragraphs, then lines, then words where it can. Each chunk after the
+// first repeats up to overlap bytes of the previous one so passages that
+// straddle a break can still be found.
+func Chunk(text string, size, overlap int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if overlap >= size {
+		overlap = size / 4
+	}
+
+	var chunks []string
+	for len(text) > size {
+		cut := breakPoint(text, size)
+		chunks = append(chunks, strings.TrimSpace(text[:cut]))
+
+		// Start the overlap on a word, or skip it if there is none
+		next := cut
+		if start := cut - overlap; start > 0 {
+			if i := strings.IndexAny(text[start:cut], " \n"); i >= 0 {
+				next = start + i + 1
+			}
+		}
+		text = strings.TrimSpace(text[next:])
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// breakPoint returns where to end a chunk of text at most size bytes
+// long: after its last paragraph, line, sentence, or word break past the
+// halfway mark, or else at a rune boundary.
+func breakPoint(text string, size int) int {
+	s := text[:size]
+	for _, sep := range []string{"\n\n", "\n", ". ", " "} {
+		if i := strings.LastIndex(s, sep); i > size/2 {
+			return i + len(sep)
+		}
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		return size
+	}
+	return cut
+}
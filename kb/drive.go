@@ -0,0 +1,227 @@
+package kb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Google Drive API defaults.
+const (
+	DefaultDriveBaseURL = "https://www.googleapis.com/drive/v3"
+	driveScope          = "https://www.googleapis.com/auth/drive.readonly"
+	driveFolder         = "application/vnd.google-apps.folder"
+)
+
+// driveExports maps Google Workspace types to the format they are
+// exported in, and the extension that tells Extract the format.
+var driveExports = map[string][2]string{
+	"application/vnd.google-apps.document":     {"text/plain", ".txt"},
+	"application/vnd.google-apps.presentation": {"text/plain", ".txt"},
+	"application/vnd.google-apps.spreadsheet":  {"text/csv", ".txt"},
+}
+
+// DriveConfig configures a Google Drive connector.
+type DriveConfig struct {
+	// Credentials is a service account key or authorized user JSON file's
+	// content. A service account sees the files shared with its email.
+	Credentials []byte
+
+	// FolderID limits the sync to a folder and its subfolders; empty syncs
+	// every file the credentials can read.
+	FolderID string
+
+	BaseURL    string       // Default: DefaultDriveBaseURL
+	HTTPClient *http.Client // Authorized client; default: from Credentials
+}
+
+// Drive syncs documents from Google Drive: Google Docs, Slides, and Sheets
+// as text, and uploaded files in formats Extract reads.
+type Drive struct {
+	config DriveConfig
+}
+
+// NewDrive creates a Google Drive connector.
+func NewDrive(ctx context.Context, config DriveConfig) (*Drive, error) {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultDriveBaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.HTTPClient == nil {
+		var kind struct {
+			Type google.CredentialsType `json:"type"`
+		}
+		if err := json.Unmarshal(config.Credentials, &kind); err != nil {
+			return nil, fmt.Errorf("google drive: parse credentials: %w", err)
+		}
+		if kind.Type != google.ServiceAccount && kind.Type != google.AuthorizedUser {
+			return nil, fmt.Errorf("google drive: unsupported credentials type %q", kind.Type)
+		}
+		creds, err := google.CredentialsFromJSONWithType(ctx, config.Credentials, kind.Type, driveScope)
+		if err != nil {
+			return nil, fmt.Errorf("google drive: %w", err)
+		}
+		// The token source outlives ctx, so it must not be bound to it
+		client := oauth2.NewClient(context.Background(), creds.TokenSource)
+		client.Timeout = time.Minute
+		config.HTTPClient = client
+	}
+	return &Drive{config: config}, nil
+}
+
+type driveFile struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	MimeType     string `json:"mimeType"`
+	ModifiedTime string `json:"modifiedTime"`
+}
+
+// List returns the readable files, with their folder paths under
+// FolderID.
+func (d *Drive) List(ctx context.Context) ([]RemoteDocument, error) {
+	if d.config.FolderID != "" {
+		return d.listFolder(ctx, d.config.FolderID, "", 0)
+	}
+	files, err := d.files(ctx, "trashed = false and mimeType != '"+driveFolder+"'")
+	if err != nil {
+		return nil, err
+	}
+	var docs []RemoteDocument
+	for _, f := range files {
+		if readable(f) {
+			docs = append(docs, RemoteDocument{ID: f.ID, Path: f.Name, Version: f.ModifiedTime})
+		}
+	}
+	return docs, nil
+}
+
+// listFolder lists a folder's files and, recursively, its subfolders'.
+func (d *Drive) listFolder(ctx context.Context, id, prefix string, depth int) ([]RemoteDocument, error) {
+	files, err := d.files(ctx, fmt.Sprintf("'%s' in parents and trashed = false", strings.ReplaceAll(id, "'", "")))
+	if err != nil {
+		return nil, err
+	}
+	var docs []RemoteDocument
+	for _, f := range files {
+		p := path.Join(prefix, f.Name)
+		if f.MimeType == driveFolder {
+			if depth >= 10 {
+				continue
+			}
+			sub, err := d.listFolder(ctx, f.ID, p, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, sub...)
+			continue
+		}
+		if readable(f) {
+			docs = append(docs, RemoteDocument{ID: f.ID, Path: p, Version: f.ModifiedTime})
+		}
+	}
+	return docs, nil
+}
+
+// readable reports whether a file can be turned into text.
+func readable(f driveFile) bool {
+	if _, ok := driveExports[f.MimeType]; ok {
+		return true
+	}
+	return Supported(f.Name) || f.MimeType == "application/pdf" || f.MimeType == "text/markdown" ||
+		f.MimeType == "text/plain" || f.MimeType == "text/html"
+}
+
+// files pages through a files.list query.
+func (d *Drive) files(ctx context.Context, query string) ([]driveFile, error) {
+	var files []driveFile
+	token := ""
+	for {
+		params := url.Values{
+			"q":                         {query},
+			"fields":                    {"nextPageToken, files(id, name, mimeType, modifiedTime)"},
+			"pageSize":                  {"1000"},
+			"supportsAllDrives":         {"true"},
+			"includeItemsFromAllDrives": {"true"},
+		}
+		if token != "" {
+			params.Set("pageToken", token)
+		}
+		var result struct {
+			Files         []driveFile `json:"files"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		data, err := d.get(ctx, "/files?"+params.Encode())
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("google drive: decode response: %w", err)
+		}
+		files = append(files, result.Files...)
+		if result.NextPageToken == "" {
+			return files, nil
+		}
+		token = result.NextPageToken
+	}
+}
+
+// Fetch downloads a file, exporting Google Workspace documents as text.
+func (d *Drive) Fetch(ctx context.Context, doc RemoteDocument) (string, []byte, error) {
+	var f driveFile
+	data, err := d.get(ctx, "/files/"+url.PathEscape(doc.ID)+"?fields=name,mimeType&supportsAllDrives=true")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return "", nil, fmt.Errorf("google drive: decode response: %w", err)
+	}
+
+	if export, ok := driveExports[f.MimeType]; ok {
+		data, err := d.get(ctx, "/files/"+url.PathEscape(doc.ID)+"/export?mimeType="+url.QueryEscape(export[0]))
+		return f.Name + export[1], data, err
+	}
+	if strings.HasPrefix(f.MimeType, "application/vnd.google-apps.") {
+		return "", nil, errors.New("google drive: unsupported document type " + f.MimeType)
+	}
+	data, err = d.get(ctx, "/files/"+url.PathEscape(doc.ID)+"?alt=media&supportsAllDrives=true")
+	return f.Name, data, err
+}
+
+// get makes a Drive API request, reading up to DefaultMaxSize bytes.
+func (d *Drive) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.config.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("google drive: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("google drive: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("google drive: %w", err)
+	}
+	if len(data) > DefaultMaxSize {
+		return nil, fmt.Errorf("google drive: file larger than %d bytes", DefaultMaxSize)
+	}
+	return data, nil
+}
+
+// Ensure Drive implements Connector.
+var _ Connector = (*Drive)(nil)
@@ -0,0 +1,76 @@
+package kb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/plexusone/omniagent/tools/httpfetch"
+)
+
+// ErrUnsupported is returned for documents that cannot be turned into text.
+var ErrUnsupported = errors.New("unsupported document type")
+
+// Supported reports whether Extract handles files with name's extension.
+func Supported(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".txt", ".text", ".html", ".htm", ".pdf":
+		return true
+	}
+	return false
+}
+
+// Extract returns the title and plain text of a document, choosing the
+// format by name's extension and falling back to sniffing the content.
+func Extract(ctx context.Context, name string, data []byte) (title, text string, err error) {
+	switch ext := strings.ToLower(path.Ext(name)); {
+	case ext == ".pdf" || bytes.HasPrefix(data, []byte("%PDF-")):
+		text, err = pdfToText(ctx, data)
+	case ext == ".html" || ext == ".htm" || strings.HasPrefix(http.DetectContentType(data), "text/html"):
+		text = httpfetch.HTMLToText(data)
+	case utf8.Valid(data):
+		text = string(data)
+	default:
+		return "", "", fmt.Errorf("%s: %w", name, ErrUnsupported)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", name, err)
+	}
+	text = strings.TrimSpace(text)
+	return titleOf(name, text), text, nil
+}
+
+// titleOf returns the document's first heading, or its file name without
+// the extension.
+func titleOf(name, text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	if t, ok := strings.CutPrefix(line, "# "); ok && strings.TrimSpace(t) != "" {
+		return strings.TrimSpace(t)
+	}
+	base := path.Base(name)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// pdfToText extracts a PDF's text with pdftotext when it is installed,
+// which handles every font encoding, and otherwise with the built-in
+// reader.
+func pdfToText(ctx context.Context, data []byte) (string, error) {
+	if bin, err := exec.LookPath("pdftotext"); err == nil {
+		cmd := exec.CommandContext(ctx, bin, "-layout", "-enc", "UTF-8", "-", "-")
+		cmd.Stdin = bytes.NewReader(data)
+		if out, err := cmd.Output(); err == nil && len(bytes.TrimSpace(out)) > 0 {
+			return string(out), nil
+		}
+	}
+	text := readPDF(data)
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("no text found in PDF (scanned PDFs need pdftotext or OCR)")
+	}
+	return text, nil
+}
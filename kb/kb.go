@@ -0,0 +1,248 @@
+// Package kb is a knowledge base: documents such as PDFs, Markdown, and
+// web pages split into chunks and embedded, so the agent can answer from
+// the user's own material with the knowledge_search tool.
+package kb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/memory"
+)
+
+// Defaults.
+const (
+	DefaultChunkSize    = 1500
+	DefaultChunkOverlap = 200
+	DefaultSearchLimit  = 5
+	DefaultMinScore     = 0.25
+	DefaultMaxSize      = 20 << 20
+
+	// embedBatch is how many chunks are embedded per request.
+	embedBatch = 64
+)
+
+// Config configures a Base.
+type Config struct {
+	Store    *Store          // Required
+	Embedder memory.Embedder // Required
+
+	ChunkSize    int     // Bytes per chunk (default: 1500)
+	ChunkOverlap int     // Bytes repeated between chunks (default: 200)
+	SearchLimit  int     // Chunks returned by a search (default: 5)
+	MinScore     float64 // Lowest similarity that counts as relevant (default: 0.25)
+	MaxSize      int64   // Largest document read, in bytes (default: 20MB)
+
+	HTTPClient *http.Client
+	Logger     *slog.Logger
+}
+
+// Base ingests and searches documents.
+type Base struct {
+	config Config
+	logger *slog.Logger
+}
+
+// New creates a Base.
+func New(config Config) (*Base, error) {
+	if config.Store == nil {
+		return nil, errors.New("kb: store required")
+	}
+	if config.Embedder == nil {
+		return nil, errors.New("kb: embedder required")
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DefaultChunkSize
+	}
+	if config.ChunkOverlap <= 0 {
+		config.ChunkOverlap = DefaultChunkOverlap
+	}
+	if config.SearchLimit <= 0 {
+		config.SearchLimit = DefaultSearchLimit
+	}
+	if config.MinScore <= 0 {
+		config.MinScore = DefaultMinScore
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultMaxSize
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: time.Minute}
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Base{config: config, logger: config.Logger}, nil
+}
+
+// Add ingests a file, every supported file under a directory, or an
+// http(s) URL. It returns the documents that were added or changed;
+// documents whose content is unchanged are skipped.
+func (b *Base) Add(ctx context.Context, source string) ([]Document, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		data, err := b.fetch(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		return b.added(b.Ingest(ctx, source, source, data))
+	}
+
+	path, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := b.readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return b.added(b.Ingest(ctx, path, path, data))
+	}
+
+	var docs []Document
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !Supported(p) {
+			return nil
+		}
+		data, err := b.readFile(p)
+		if err != nil {
+			return err
+		}
+		doc, changed, err := b.Ingest(ctx, p, p, data)
+		if err != nil {
+			// One unreadable file shouldn't stop the rest of the directory
+			b.logger.Warn("skipping document", "path", p, "error", err)
+			return nil
+		}
+		if changed {
+			docs = append(docs, doc)
+		}
+		return nil
+	})
+	return docs, err
+}
+
+// added wraps Ingest's result for Add.
+func (b *Base) added(doc Document, changed bool, err error) ([]Document, error) {
+	if err != nil || !changed {
+		return nil, err
+	}
+	return []Document{doc}, nil
+}
+
+// Ingest stores a document's content under source, using name to choose
+// its format. It reports false without re-embedding when the same content
+// is already stored.
+func (b *Base) Ingest(ctx context.Context, source, name string, data []byte) (Document, bool, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	model := b.config.Embedder.Model()
+	if existing, err := b.config.Store.Document(ctx, source); err == nil && existing.Hash == hash && existing.Model == model {
+		return existing, false, nil
+	}
+
+	title, text, err := Extract(ctx, name, data)
+	if err != nil {
+		return Document{}, false, err
+	}
+	chunks := Chunk(text, b.config.ChunkSize, b.config.ChunkOverlap)
+	if len(chunks) == 0 {
+		return Document{}, false, fmt.Errorf("%s: no text found", name)
+	}
+
+	vecs := make([][]float32, 0, len(chunks))
+	for i := 0; i < len(chunks); i += embedBatch {
+		batch := chunks[i:min(i+embedBatch, len(chunks))]
+		v, err := b.config.Embedder.Embed(ctx, batch)
+		if err != nil {
+			return Document{}, false, fmt.Errorf("%s: %w", name, err)
+		}
+		vecs = append(vecs, v...)
+	}
+
+	doc, err := b.config.Store.Put(ctx, Document{Source: source, Title: title, Hash: hash, Model: model}, chunks, vecs)
+	if err != nil {
+		return Document{}, false, err
+	}
+	b.logger.Info("document added to knowledge base", "source", source, "chunks", doc.Chunks)
+	return doc, true, nil
+}
+
+// Search returns the chunks most relevant to query.
+func (b *Base) Search(ctx context.Context, query string) ([]Match, error) {
+	vecs, err := b.config.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return b.config.Store.Search(ctx, b.config.Embedder.Model(), vecs[0], b.config.SearchLimit, b.config.MinScore)
+}
+
+// Documents returns every document in the knowledge base.
+func (b *Base) Documents(ctx context.Context) ([]Document, error) {
+	return b.config.Store.Documents(ctx)
+}
+
+// Remove deletes the document from source.
+func (b *Base) Remove(ctx context.Context, source string) error {
+	return b.config.Store.Remove(ctx, source)
+}
+
+// readFile reads a file up to MaxSize.
+func (b *Base) readFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > b.config.MaxSize {
+		return nil, fmt.Errorf("%s: larger than %d bytes", path, b.config.MaxSize)
+	}
+	return os.ReadFile(path) //nolint:gosec // G304: Path chosen by the operator
+}
+
+// fetch downloads a URL up to MaxSize.
+func (b *Base) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, b.config.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	if int64(len(data)) > b.config.MaxSize {
+		return nil, fmt.Errorf("fetch %s: larger than %d bytes", url, b.config.MaxSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,256 @@
+package kb
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder embeds texts as bags of hashed words, so texts sharing
+// words are similar. It counts the chunks it embeds.
+type wordEmbedder struct{ embedded int }
+
+func (*wordEmbedder) Model() string { return "words" }
+
+func (e *wordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.embedded += len(texts)
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, 1024)
+		for _, w := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(strings.Trim(w, ".,?#")))
+			vec[h.Sum32()%1024]++
+		}
+		vecs[i] = vec
+	}
+	return vecs, nil
+}
+
+func TestChunk(t *testing.T) {
+	para := strings.Repeat("word ", 100) // 500 bytes
+	text := para + "\n\n" + para + "\n\n" + para
+	chunks := Chunk(text, 600, 50)
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	for _, c := range chunks {
+		if len(c) > 600 {
+			t.Errorf("chunk of %d bytes exceeds size", len(c))
+		}
+	}
+	if !strings.HasPrefix(chunks[1], "word") {
+		t.Errorf("chunk should start on a word: %q", chunks[1][:20])
+	}
+
+	// Text without breaks is cut on rune boundaries
+	for _, c := range Chunk(strings.Repeat("é", 500), 101, 10) {
+		if !strings.HasPrefix(c, "é") || strings.ContainsRune(c, '�') {
+			t.Fatalf("chunk split a rune: %q", c)
+		}
+	}
+}
+
+func TestReadPDF(t *testing.T) {
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	_, _ = w.Write([]byte("BT /F1 12 Tf 72 720 Td (Quarterly report) Tj 0 -14 Td [(Revenue ) -300 (grew \\(a lot\\))] TJ ET"))
+	_ = w.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Length 44 >>\nstream\nBT (Plain) Tj ET\nendstream\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Length 10 /Filter /FlateDecode >>\nstream\n")
+	pdf.Write(z.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n3 0 obj\n<< /Type /XObject /Subtype /Image >>\nstream\nBT (Hidden) Tj ET\nendstream\nendobj\n%%EOF\n")
+
+	got := readPDF(pdf.Bytes())
+	want := "Plain\nQuarterly report\nRevenue  grew (a lot)\n"
+	if got != want {
+		t.Errorf("readPDF = %q, want %q", got, want)
+	}
+}
+
+func TestAddAndSearch(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"boiler.md":        "# Boiler manual\n\nTo reset the boiler, hold the red button for five seconds.",
+		"recipes/pie.html": "<html><head><title>Apple pie</title></head><body><p>Bake the apple pie for forty minutes.</p></body></html>",
+		"photo.jpg":        "not a document",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := Open(filepath.Join(t.TempDir(), "kb.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	embedder := &wordEmbedder{}
+	b, err := New(Config{Store: store, Embedder: embedder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	docs, err := b.Add(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("added %d documents, want 2", len(docs))
+	}
+
+	// Unchanged documents are not embedded again
+	embedded := embedder.embedded
+	if docs, err := b.Add(ctx, dir); err != nil || len(docs) != 0 {
+		t.Fatalf("re-add = %d, %v; want nothing changed", len(docs), err)
+	}
+	if embedder.embedded != embedded {
+		t.Error("unchanged documents were embedded again")
+	}
+
+	out, err := NewTool(b).Execute(ctx, json.RawMessage(`{"query":"how do I reset the boiler?"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "[1] Boiler manual") || !strings.Contains(out, "red button") {
+		t.Errorf("search = %q", out)
+	}
+
+	if err := b.Remove(ctx, filepath.Join(dir, "boiler.md")); err != nil {
+		t.Fatal(err)
+	}
+	all, err := b.Documents(ctx)
+	if err != nil || len(all) != 1 || all[0].Title != "Apple pie" {
+		t.Errorf("documents after remove = %+v, %v", all, err)
+	}
+}
+
+// fakeConnector serves documents from a map of path to content, with a
+// version per document.
+type fakeConnector struct {
+	docs    map[string]string
+	version map[string]string
+	fetched []string
+}
+
+func (c *fakeConnector) List(context.Context) ([]RemoteDocument, error) {
+	var docs []RemoteDocument
+	for p := range c.docs {
+		docs = append(docs, RemoteDocument{ID: p, Path: p, Version: c.version[p]})
+	}
+	return docs, nil
+}
+
+func (c *fakeConnector) Fetch(_ context.Context, doc RemoteDocument) (string, []byte, error) {
+	c.fetched = append(c.fetched, doc.Path)
+	return doc.Path + ".md", []byte(c.docs[doc.Path]), nil
+}
+
+func TestSync(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "kb.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	b, err := New(Config{Store: store, Embedder: &wordEmbedder{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := &fakeConnector{
+		docs: map[string]string{
+			"Projects/Plan":  "The launch is in March.",
+			"Projects/Draft": "Unfinished ideas.",
+			"Personal/Diary": "Private notes.",
+		},
+		version: map[string]string{"Projects/Plan": "1", "Projects/Draft": "1", "Personal/Diary": "1"},
+	}
+	s, err := NewSyncer(SyncConfig{Base: b, Sources: []Source{{
+		Name:      "notes",
+		Connector: conn,
+		Include:   []string{"Projects/**"},
+		Exclude:   []string{"Draft"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	res, err := s.Sync(ctx, "notes")
+	if err != nil || res.Updated != 1 {
+		t.Fatalf("first sync = %+v, %v; want 1 updated", res, err)
+	}
+	if _, err := store.Document(ctx, "notes:Projects/Plan"); err != nil {
+		t.Fatalf("synced document missing: %v", err)
+	}
+
+	// Unchanged versions are not fetched again
+	conn.fetched = nil
+	if res, err := s.Sync(ctx, "notes"); err != nil || res.Unchanged != 1 || len(conn.fetched) != 0 {
+		t.Fatalf("second sync = %+v, %v, fetched %v; want nothing fetched", res, err, conn.fetched)
+	}
+
+	// Changed documents are fetched; deleted ones are removed
+	conn.version["Projects/Plan"] = "2"
+	conn.docs["Projects/Plan"] = "The launch moved to April."
+	if res, err := s.Sync(ctx, "notes"); err != nil || res.Updated != 1 {
+		t.Fatalf("sync after edit = %+v, %v", res, err)
+	}
+	delete(conn.docs, "Projects/Plan")
+	if res, err := s.Sync(ctx, "notes"); err != nil || res.Removed != 1 {
+		t.Fatalf("sync after delete = %+v, %v", res, err)
+	}
+}
+
+func TestNotion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Notion-Version") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/search":
+			_, _ = w.Write([]byte(`{"results":[{"id":"p1","last_edited_time":"2026-01-02T00:00:00Z",
+				"properties":{"Name":{"type":"title","title":[{"plain_text":"Recipes"}]}}}]}`))
+		case "/blocks/p1/children":
+			_, _ = w.Write([]byte(`{"results":[
+				{"id":"b1","type":"heading_2","heading_2":{"rich_text":[{"plain_text":"Pie"}]}},
+				{"id":"b2","type":"bulleted_list_item","has_children":true,"bulleted_list_item":{"rich_text":[{"plain_text":"Apples"}]}}]}`))
+		case "/blocks/b2/children":
+			_, _ = w.Write([]byte(`{"results":[{"id":"b3","type":"to_do","to_do":{"checked":true,"rich_text":[{"plain_text":"Peel"}]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	n := NewNotion(NotionConfig{Token: "secret", BaseURL: server.URL})
+	ctx := context.Background()
+	docs, err := n.List(ctx)
+	if err != nil || len(docs) != 1 || docs[0].Path != "Recipes" {
+		t.Fatalf("List = %+v, %v", docs, err)
+	}
+	name, data, err := n.Fetch(ctx, docs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Recipes\n\n## Pie\n\n- Apples\n  - [x] Peel\n\n"
+	if name != "Recipes.md" || string(data) != want {
+		t.Errorf("Fetch = %q, %q; want %q", name, data, want)
+	}
+}
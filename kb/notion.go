@@ -0,0 +1,256 @@
+package kb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notion API defaults.
+const (
+	DefaultNotionBaseURL = "https://api.notion.com/v1"
+	notionVersion        = "2022-06-28"
+
+	// notionMaxDepth bounds how deeply nested blocks are read.
+	notionMaxDepth = 5
+)
+
+// NotionConfig configures a Notion connector.
+type NotionConfig struct {
+	Token      string //nolint:gosec // G117: Integration token loaded from config
+	BaseURL    string // Default: DefaultNotionBaseURL
+	HTTPClient *http.Client
+}
+
+// Notion syncs the pages shared with a Notion integration, as Markdown.
+type Notion struct {
+	config NotionConfig
+}
+
+// NewNotion creates a Notion connector.
+func NewNotion(config NotionConfig) *Notion {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultNotionBaseURL
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: time.Minute}
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &Notion{config: config}
+}
+
+// notionPage is the part of a page object the connector reads.
+type notionPage struct {
+	ID             string `json:"id"`
+	LastEditedTime string `json:"last_edited_time"`
+	InTrash        bool   `json:"in_trash"`
+	Archived       bool   `json:"archived"`
+	Properties     map[string]struct {
+		Type  string       `json:"type"`
+		Title []notionText `json:"title"`
+	} `json:"properties"`
+}
+
+type notionText struct {
+	PlainText string `json:"plain_text"`
+}
+
+// title returns the page's title property.
+func (p notionPage) title() string {
+	for _, prop := range p.Properties {
+		if prop.Type == "title" {
+			return plainText(prop.Title)
+		}
+	}
+	return "Untitled"
+}
+
+// List returns the pages shared with the integration. Paths are page
+// titles.
+func (n *Notion) List(ctx context.Context) ([]RemoteDocument, error) {
+	var docs []RemoteDocument
+	cursor := ""
+	for {
+		body := map[string]any{
+			"filter":    map[string]string{"property": "object", "value": "page"},
+			"page_size": 100,
+		}
+		if cursor != "" {
+			body["start_cursor"] = cursor
+		}
+		var result struct {
+			Results    []notionPage `json:"results"`
+			HasMore    bool         `json:"has_more"`
+			NextCursor string       `json:"next_cursor"`
+		}
+		if err := n.call(ctx, http.MethodPost, "/search", body, &result); err != nil {
+			return nil, err
+		}
+		for _, page := range result.Results {
+			if page.InTrash || page.Archived {
+				continue
+			}
+			docs = append(docs, RemoteDocument{ID: page.ID, Path: page.title(), Version: page.LastEditedTime})
+		}
+		if !result.HasMore || result.NextCursor == "" {
+			return docs, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// Fetch returns a page as Markdown.
+func (n *Notion) Fetch(ctx context.Context, doc RemoteDocument) (string, []byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", doc.Path)
+	if err := n.writeBlocks(ctx, &b, doc.ID, 0); err != nil {
+		return "", nil, err
+	}
+	return doc.Path + ".md", []byte(b.String()), nil
+}
+
+// writeBlocks renders a block's children as Markdown lines, indenting
+// nested blocks.
+func (n *Notion) writeBlocks(ctx context.Context, b *strings.Builder, id string, depth int) error {
+	cursor := ""
+	for {
+		url := "/blocks/" + id + "/children?page_size=100"
+		if cursor != "" {
+			url += "&start_cursor=" + cursor
+		}
+		var result struct {
+			Results    []map[string]json.RawMessage `json:"results"`
+			HasMore    bool                         `json:"has_more"`
+			NextCursor string                       `json:"next_cursor"`
+		}
+		if err := n.call(ctx, http.MethodGet, url, nil, &result); err != nil {
+			return err
+		}
+		for _, block := range result.Results {
+			var blockID, kind string
+			var hasChildren bool
+			_ = json.Unmarshal(block["id"], &blockID)
+			_ = json.Unmarshal(block["type"], &kind)
+			_ = json.Unmarshal(block["has_children"], &hasChildren)
+
+			line := notionLine(kind, block[kind])
+			if line != "" {
+				indent := strings.Repeat("  ", depth)
+				b.WriteString(indent + strings.ReplaceAll(line, "\n", "\n"+indent) + "\n")
+			}
+			// Child pages are synced as pages of their own
+			if hasChildren && kind != "child_page" && kind != "child_database" && depth < notionMaxDepth {
+				if err := n.writeBlocks(ctx, b, blockID, depth+1); err != nil {
+					return err
+				}
+			}
+			// Separate top-level blocks, with their children, by blank lines
+			if line != "" && depth == 0 {
+				b.WriteString("\n")
+			}
+		}
+		if !result.HasMore || result.NextCursor == "" {
+			return nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// notionLine renders one block as Markdown.
+func notionLine(kind string, raw json.RawMessage) string {
+	var v struct {
+		RichText []notionText `json:"rich_text"`
+		Checked  bool         `json:"checked"`
+		Language string       `json:"language"`
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return ""
+	}
+	text := plainText(v.RichText)
+	switch kind {
+	case "heading_1":
+		return "# " + text
+	case "heading_2":
+		return "## " + text
+	case "heading_3":
+		return "### " + text
+	case "bulleted_list_item", "toggle":
+		return "- " + text
+	case "numbered_list_item":
+		return "1. " + text
+	case "to_do":
+		if v.Checked {
+			return "- [x] " + text
+		}
+		return "- [ ] " + text
+	case "quote", "callout":
+		return "> " + text
+	case "code":
+		return "```" + v.Language + "\n" + text + "\n```"
+	default:
+		return text
+	}
+}
+
+func plainText(parts []notionText) string {
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString(p.PlainText)
+	}
+	return b.String()
+}
+
+// call makes a Notion API request, waiting out rate limits.
+func (n *Notion) call(ctx context.Context, method, path string, body, result any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, n.config.BaseURL+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+n.config.Token)
+		req.Header.Set("Notion-Version", notionVersion)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := n.config.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("notion: %w", err)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < 3 {
+			resp.Body.Close()
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(max(wait, 1)) * time.Second):
+			}
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("notion: %s: %s", resp.Status, bytes.TrimSpace(msg))
+		}
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("notion: decode response: %w", err)
+		}
+		return nil
+	}
+}
+
+// Ensure Notion implements Connector.
+var _ Connector = (*Notion)(nil)
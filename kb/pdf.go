@@ -0,0 +1,288 @@
+package kb
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// readPDF extracts the text drawn by a PDF's content streams. It reads
+// uncompressed and Flate-compressed streams and decodes strings as
+// PDFDocEncoding or UTF-16, which covers documents written with standard
+// fonts; text in embedded CID fonts is skipped.
+func readPDF(data []byte) string {
+	var out strings.Builder
+	for rest := data; ; {
+		i := bytes.Index(rest, []byte("stream"))
+		if i < 0 {
+			break
+		}
+		dict := rest[:i]
+		if j := bytes.LastIndex(dict, []byte(" obj")); j >= 0 {
+			dict = dict[j:]
+		}
+		body := rest[i+len("stream"):]
+		rest = body
+		if !bytes.HasSuffix(bytes.TrimSpace(dict), []byte(">>")) {
+			continue // "endstream" or text inside a stream
+		}
+		body = bytes.TrimPrefix(bytes.TrimPrefix(body, []byte("\r")), []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		rest = body[end:]
+		if skipStream(dict) {
+			continue
+		}
+		content := body[:end]
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			r, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			// Keep what decodes even when the stream is truncated
+			content, _ = io.ReadAll(r)
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		contentText(&out, content)
+	}
+	return out.String()
+}
+
+// skipStream reports whether a stream dictionary describes something other
+// than page content: images, fonts, or cross-reference data.
+func skipStream(dict []byte) bool {
+	for _, key := range []string{"/Image", "/Length1", "/Length2", "/FontFile", "/XRef", "/ObjStm", "/Metadata"} {
+		if bytes.Contains(dict, []byte(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// contentText writes the text shown by a content stream's text operators.
+func contentText(out *strings.Builder, content []byte) {
+	var operands []any // string or float64
+	var array []any
+	inArray := false
+	line := false
+
+	newline := func() {
+		if line {
+			out.WriteString("\n")
+			line = false
+		}
+	}
+	show := func(s string) {
+		if s != "" {
+			out.WriteString(s)
+			line = true
+		}
+	}
+
+	for p := 0; p < len(content); {
+		c := content[p]
+		switch {
+		case c == '(':
+			s, n := literalString(content[p:])
+			p += n
+			push(&operands, &array, inArray, s)
+		case c == '<' && p+1 < len(content) && content[p+1] != '<':
+			end := bytes.IndexByte(content[p:], '>')
+			if end < 0 {
+				return
+			}
+			push(&operands, &array, inArray, hexString(content[p+1:p+end]))
+			p += end + 1
+		case c == '[':
+			inArray, array = true, nil
+			p++
+		case c == ']':
+			inArray = false
+			operands = append(operands, array)
+			p++
+		case c == '%':
+			for p < len(content) && content[p] != '\n' && content[p] != '\r' {
+				p++
+			}
+		case isSpace(c) || c == '<' || c == '>' || c == '{' || c == '}':
+			p++
+		default:
+			start := p
+			for p < len(content) && !isSpace(content[p]) && !bytes.ContainsRune([]byte("()<>[]{}/%"), rune(content[p])) {
+				p++
+			}
+			if p == start {
+				p++ // '/' starting a name
+				continue
+			}
+			tok := string(content[start:p])
+			if f, err := strconv.ParseFloat(tok, 64); err == nil {
+				push(&operands, &array, inArray, f)
+				continue
+			}
+			switch tok {
+			case "Tj":
+				show(lastString(operands))
+			case "'", "\"":
+				newline()
+				show(lastString(operands))
+			case "TJ":
+				if len(operands) > 0 {
+					if parts, ok := operands[len(operands)-1].([]any); ok {
+						for _, part := range parts {
+							switch v := part.(type) {
+							case string:
+								show(v)
+							case float64:
+								// Large negative kerning separates words
+								if v < -200 {
+									show(" ")
+								}
+							}
+						}
+					}
+				}
+			case "Td", "TD":
+				if len(operands) >= 1 {
+					if ty, ok := operands[len(operands)-1].(float64); ok && ty != 0 {
+						newline()
+					} else if line {
+						show(" ")
+					}
+				}
+			case "T*", "Tm", "ET":
+				newline()
+			}
+			operands = operands[:0]
+		}
+	}
+	newline()
+}
+
+// push adds an operand to the current array or the operand stack.
+func push(operands, array *[]any, inArray bool, v any) {
+	if inArray {
+		*array = append(*array, v)
+		return
+	}
+	*operands = append(*operands, v)
+}
+
+// lastString returns the last operand if it is a string.
+func lastString(operands []any) string {
+	if len(operands) == 0 {
+		return ""
+	}
+	s, _ := operands[len(operands)-1].(string)
+	return s
+}
+
+// literalString decodes a (literal) string at the start of b, returning it
+// and the bytes consumed.
+func literalString(b []byte) (string, int) {
+	var raw []byte
+	depth := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				raw = append(raw, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return decodeText(raw), i + 1
+			}
+			raw = append(raw, c)
+		case '\\':
+			i++
+			if i >= len(b) {
+				break
+			}
+			switch e := b[i]; e {
+			case 'n':
+				raw = append(raw, '\n')
+			case 'r':
+				raw = append(raw, '\r')
+			case 't':
+				raw = append(raw, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					n, j := 0, i
+					for ; j < len(b) && j < i+3 && b[j] >= '0' && b[j] <= '7'; j++ {
+						n = n*8 + int(b[j]-'0')
+					}
+					raw = append(raw, byte(n))
+					i = j - 1
+				} else {
+					raw = append(raw, e)
+				}
+			}
+		default:
+			raw = append(raw, c)
+		}
+	}
+	return decodeText(raw), len(b)
+}
+
+// hexString decodes a <hex> string.
+func hexString(b []byte) string {
+	var digits []byte
+	for _, c := range b {
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	raw := make([]byte, len(digits)/2)
+	for i := range raw {
+		v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return ""
+		}
+		raw[i] = byte(v)
+	}
+	return decodeText(raw)
+}
+
+// decodeText converts string bytes to text: UTF-16 with a byte order mark,
+// otherwise PDFDocEncoding, which matches Latin-1 for printable text.
+// Strings that look like two-byte glyph IDs are dropped.
+func decodeText(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xfe && raw[1] == 0xff {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var sb strings.Builder
+	for _, c := range raw {
+		switch {
+		case c == 0:
+			return ""
+		case c == '\n' || c == '\t':
+			sb.WriteByte(' ')
+		case c >= 0x20 && c != 0x7f:
+			sb.WriteRune(rune(c))
+		}
+	}
+	return sb.String()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
@@ -0,0 +1,242 @@
+package kb
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/plexusone/omniagent/memory"
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver
+)
+
+// ErrNotFound is returned when a document is not in the knowledge base.
+var ErrNotFound = errors.New("document not found")
+
+// Document is an ingested document.
+type Document struct {
+	ID      int64
+	Source  string // Path, URL, or connector ID it was read from
+	Title   string
+	Hash    string // SHA-256 of the original content
+	Version string // Revision at the source, set by sync
+	Model   string // Embedding model of its chunks
+	Chunks  int
+	AddedAt time.Time
+}
+
+// Match is a chunk found by similarity.
+type Match struct {
+	Source  string
+	Title   string
+	Seq     int // Position of the chunk in its document
+	Content string
+	Score   float64 // Cosine similarity, 1 is identical
+}
+
+// Store keeps documents and their embedded chunks in SQLite. Search
+// compares the query with every chunk, which stays fast up to tens of
+// thousands of chunks.
+type Store struct {
+	db *sql.DB
+}
+
+const kbSchema = `
+CREATE TABLE IF NOT EXISTS documents (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	source   TEXT NOT NULL UNIQUE,
+	title    TEXT NOT NULL,
+	hash     TEXT NOT NULL,
+	version  TEXT NOT NULL DEFAULT '',
+	model    TEXT NOT NULL,
+	chunks   INTEGER NOT NULL,
+	added_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS chunks (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	doc_id    INTEGER NOT NULL,
+	seq       INTEGER NOT NULL,
+	content   TEXT NOT NULL,
+	embedding BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS chunks_doc ON chunks (doc_id)`
+
+// Open opens (or creates) a knowledge base database at path.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("create knowledge base dir: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open knowledge base: %w", err)
+	}
+	// SQLite allows one writer; serialize access instead of retrying
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(kbSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create knowledge base schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put stores a document with its chunks and their embeddings, replacing
+// any document from the same source.
+func (s *Store) Put(ctx context.Context, doc Document, chunks []string, vecs [][]float32) (Document, error) {
+	if len(chunks) != len(vecs) {
+		return Document{}, fmt.Errorf("got %d embeddings for %d chunks", len(vecs), len(chunks))
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Document{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	if err := deleteDocument(ctx, tx, doc.Source); err != nil && !errors.Is(err, ErrNotFound) {
+		return Document{}, err
+	}
+	doc.Chunks = len(chunks)
+	doc.AddedAt = time.Now()
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO documents (source, title, hash, version, model, chunks, added_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		doc.Source, doc.Title, doc.Hash, doc.Version, doc.Model, doc.Chunks, doc.AddedAt.Unix())
+	if err != nil {
+		return Document{}, fmt.Errorf("add document: %w", err)
+	}
+	if doc.ID, err = res.LastInsertId(); err != nil {
+		return Document{}, fmt.Errorf("add document: %w", err)
+	}
+	for i, chunk := range chunks {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO chunks (doc_id, seq, content, embedding) VALUES (?, ?, ?, ?)`,
+			doc.ID, i, chunk, memory.EncodeVector(vecs[i])); err != nil {
+			return Document{}, fmt.Errorf("add chunk: %w", err)
+		}
+	}
+	return doc, tx.Commit()
+}
+
+// Document returns the document from source.
+func (s *Store) Document(ctx context.Context, source string) (Document, error) {
+	docs, err := s.query(ctx, `WHERE source = ?`, source)
+	if err != nil {
+		return Document{}, err
+	}
+	if len(docs) == 0 {
+		return Document{}, ErrNotFound
+	}
+	return docs[0], nil
+}
+
+// Documents returns every document, by source.
+func (s *Store) Documents(ctx context.Context) ([]Document, error) {
+	return s.query(ctx, `ORDER BY source`)
+}
+
+func (s *Store) query(ctx context.Context, where string, args ...any) ([]Document, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, source, title, hash, version, model, chunks, added_at FROM documents `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []Document
+	for rows.Next() {
+		var d Document
+		var added int64
+		if err := rows.Scan(&d.ID, &d.Source, &d.Title, &d.Hash, &d.Version, &d.Model, &d.Chunks, &added); err != nil {
+			return nil, fmt.Errorf("list documents: %w", err)
+		}
+		d.AddedAt = time.Unix(added, 0)
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+// SetVersion records the source revision of a document.
+func (s *Store) SetVersion(ctx context.Context, source, version string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE documents SET version = ? WHERE source = ?`, version, source)
+	if err != nil {
+		return fmt.Errorf("set document version: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Remove deletes the document from source.
+func (s *Store) Remove(ctx context.Context, source string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	if err := deleteDocument(ctx, tx, source); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteDocument(ctx context.Context, tx *sql.Tx, source string) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM documents WHERE source = ?`, source).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("find document: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE doc_id = ?`, id); err != nil {
+		return fmt.Errorf("delete chunks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete document: %w", err)
+	}
+	return nil
+}
+
+// Search returns up to limit chunks embedded with model, most similar to
+// vec first, scoring at least minScore.
+func (s *Store) Search(ctx context.Context, model string, vec []float32, limit int, minScore float64) ([]Match, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT d.source, d.title, c.seq, c.content, c.embedding
+		 FROM chunks c JOIN documents d ON d.id = c.doc_id WHERE d.model = ?`, model)
+	if err != nil {
+		return nil, fmt.Errorf("search knowledge base: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		var blob []byte
+		if err := rows.Scan(&m.Source, &m.Title, &m.Seq, &m.Content, &blob); err != nil {
+			return nil, fmt.Errorf("search knowledge base: %w", err)
+		}
+		m.Score = memory.Cosine(vec, memory.DecodeVector(blob))
+		if m.Score >= minScore {
+			matches = append(matches, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search knowledge base: %w", err)
+	}
+	slices.SortFunc(matches, func(a, b Match) int { return cmp.Compare(b.Score, a.Score) })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
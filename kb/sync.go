@@ -0,0 +1,225 @@
+package kb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultSyncInterval is how often a source is synced.
+const DefaultSyncInterval = time.Hour
+
+// RemoteDocument is a document offered by a connector.
+type RemoteDocument struct {
+	ID      string // Stable ID at the source
+	Path    string // Readable location, such as "Projects/Plan", matched by rules
+	Version string // Changes whenever the document does, such as its edit time
+}
+
+// Connector lists and fetches documents from an external service.
+type Connector interface {
+	List(ctx context.Context) ([]RemoteDocument, error)
+
+	// Fetch returns a document's content and a file name whose extension
+	// gives its format.
+	Fetch(ctx context.Context, doc RemoteDocument) (name string, data []byte, err error)
+}
+
+// Source is a connector synced into the knowledge base.
+type Source struct {
+	Name      string // Prefixes the source of its documents, as "<name>:<id>"
+	Connector Connector
+
+	// Include limits the documents synced to those whose path matches a
+	// pattern (empty: all); Exclude skips matches. Patterns use path.Match
+	// against the full path or its last element, and "dir/**" matches
+	// everything under dir.
+	Include []string
+	Exclude []string
+
+	Interval time.Duration // Default: DefaultSyncInterval
+}
+
+// SyncResult counts what a sync changed.
+type SyncResult struct {
+	Updated   int // Added or changed
+	Unchanged int
+	Removed   int
+	Failed    int
+}
+
+// SyncConfig configures a Syncer.
+type SyncConfig struct {
+	Base    *Base // Required
+	Sources []Source
+	Logger  *slog.Logger
+}
+
+// Syncer keeps the knowledge base in step with its sources. Each sync
+// fetches only documents whose version changed, and removes documents the
+// source no longer offers or the rules now exclude.
+type Syncer struct {
+	config SyncConfig
+	logger *slog.Logger
+}
+
+// NewSyncer creates a Syncer.
+func NewSyncer(config SyncConfig) (*Syncer, error) {
+	if config.Base == nil {
+		return nil, errors.New("kb: base required")
+	}
+	seen := make(map[string]bool)
+	for i, src := range config.Sources {
+		if src.Name == "" || src.Connector == nil {
+			return nil, errors.New("kb: sources require a name and connector")
+		}
+		if seen[src.Name] {
+			return nil, fmt.Errorf("kb: duplicate source %q", src.Name)
+		}
+		seen[src.Name] = true
+		if src.Interval <= 0 {
+			config.Sources[i].Interval = DefaultSyncInterval
+		}
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Syncer{config: config, logger: config.Logger}, nil
+}
+
+// Sources returns the names of the sources.
+func (s *Syncer) Sources() []string {
+	names := make([]string, len(s.config.Sources))
+	for i, src := range s.config.Sources {
+		names[i] = src.Name
+	}
+	return names
+}
+
+// Run syncs each source at start and then every Interval until ctx is
+// done.
+func (s *Syncer) Run(ctx context.Context) error {
+	for _, src := range s.config.Sources {
+		go s.loop(ctx, src)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (s *Syncer) loop(ctx context.Context, src Source) {
+	ticker := time.NewTicker(src.Interval)
+	defer ticker.Stop()
+	for {
+		if res, err := s.sync(ctx, src); err != nil {
+			s.logger.Warn("knowledge sync failed", "source", src.Name, "error", err)
+		} else {
+			s.logger.Info("knowledge synced", "source", src.Name, "updated", res.Updated,
+				"unchanged", res.Unchanged, "removed", res.Removed, "failed", res.Failed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync syncs the named source now.
+func (s *Syncer) Sync(ctx context.Context, name string) (SyncResult, error) {
+	for _, src := range s.config.Sources {
+		if src.Name == name {
+			return s.sync(ctx, src)
+		}
+	}
+	return SyncResult{}, fmt.Errorf("no knowledge source %q", name)
+}
+
+func (s *Syncer) sync(ctx context.Context, src Source) (SyncResult, error) {
+	var res SyncResult
+	remote, err := src.Connector.List(ctx)
+	if err != nil {
+		return res, fmt.Errorf("list %s: %w", src.Name, err)
+	}
+	store := s.config.Base.config.Store
+	existing, err := store.Documents(ctx)
+	if err != nil {
+		return res, err
+	}
+	prefix := src.Name + ":"
+	stored := make(map[string]Document)
+	for _, doc := range existing {
+		if strings.HasPrefix(doc.Source, prefix) {
+			stored[doc.Source] = doc
+		}
+	}
+
+	model := s.config.Base.config.Embedder.Model()
+	for _, rd := range remote {
+		if !included(src, rd.Path) {
+			continue
+		}
+		source := prefix + rd.ID
+		doc, ok := stored[source]
+		delete(stored, source)
+		if ok && rd.Version != "" && doc.Version == rd.Version && doc.Model == model {
+			res.Unchanged++
+			continue
+		}
+
+		name, data, err := src.Connector.Fetch(ctx, rd)
+		if err == nil {
+			_, _, err = s.config.Base.Ingest(ctx, source, name, data)
+		}
+		if err == nil {
+			err = store.SetVersion(ctx, source, rd.Version)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			res.Failed++
+			s.logger.Warn("knowledge sync skipped document", "source", src.Name, "path", rd.Path, "error", err)
+			continue
+		}
+		res.Updated++
+	}
+
+	// Whatever the source no longer offers, or the rules exclude, goes
+	for source := range stored {
+		if err := store.Remove(ctx, source); err != nil && !errors.Is(err, ErrNotFound) {
+			return res, err
+		}
+		res.Removed++
+	}
+	return res, nil
+}
+
+// included reports whether a document path passes a source's rules.
+func included(src Source, p string) bool {
+	if len(src.Include) > 0 && !matchAny(src.Include, p) {
+		return false
+	}
+	return !matchAny(src.Exclude, p)
+}
+
+func matchAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if p == dir || strings.HasPrefix(p, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(p)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package kb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Tool searches the knowledge base.
+type Tool struct {
+	base *Base
+}
+
+// NewTool creates the knowledge_search tool.
+func NewTool(b *Base) *Tool {
+	return &Tool{base: b}
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "knowledge_search"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return "Search the user's own documents, such as notes, manuals, and saved pages, for passages " +
+		"relevant to a question. Prefer this over web search for anything the user may have written down. " +
+		"Cite the source of what you use."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "A question or description of the passage to find",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+// Execute returns the matching passages with their sources.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return "", errors.New("query required")
+	}
+
+	matches, err := t.base.Search(ctx, params.Query)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "No relevant passages found.", nil
+	}
+	var b strings.Builder
+	for i, m := range matches {
+		fmt.Fprintf(&b, "[%d] %s (%s, part %d)\n%s\n\n", i+1, m.Title, m.Source, m.Seq+1, m.Content)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
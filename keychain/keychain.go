@@ -0,0 +1,34 @@
+// Package keychain stores secrets, such as provider API keys, in the OS
+// keychain: Keychain on macOS, the Secret Service on Linux, and Credential
+// Manager on Windows. Entries are grouped under the "omniagent" service.
+package keychain
+
+import "errors"
+
+// Service names omniagent's entries.
+const Service = "omniagent"
+
+// ErrNotFound is returned when the keychain has no entry for an account.
+var ErrNotFound = errors.New("keychain: not found")
+
+// Get returns the secret stored for account.
+func Get(account string) (string, error) {
+	return get(account)
+}
+
+// Set stores secret for account, replacing any previous one.
+func Set(account, secret string) error {
+	if secret == "" {
+		return errors.New("keychain: empty secret")
+	}
+	return set(account, secret)
+}
+
+// Delete removes account's entry. Deleting a missing entry is not an error.
+func Delete(account string) error {
+	err := del(account)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+//go:build !windows
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// macOS uses the security tool and other systems the Secret Service's
+// secret-tool, from libsecret.
+
+func get(account string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", Service, "-a", account, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", Service, "account", account)
+	}
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("keychain: %w", err)
+	}
+	secret := strings.TrimRight(string(out), "\r\n")
+	if secret == "" {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func set(account, secret string) error {
+	if runtime.GOOS == "darwin" {
+		return setDarwin(account, secret)
+	}
+	cmd := exec.Command("secret-tool", "store", "--label="+Service+" "+account, "service", Service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	return run(cmd)
+}
+
+// setDarwin stores a secret with the security tool. Arguments show in ps
+// to every local user, so the secret goes to security's interactive mode
+// on stdin rather than after -w.
+func setDarwin(account, secret string) error {
+	if strings.ContainsAny(secret, "\r\n") {
+		return errors.New("keychain: secrets cannot span lines")
+	}
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader("add-generic-password -U -s " + quote(Service) +
+		" -a " + quote(account) + " -w " + quote(secret) + "\n")
+
+	// Interactive mode exits 0 when a command fails, printing why
+	out, err := cmd.CombinedOutput()
+	if msg := strings.TrimSpace(strings.ReplaceAll(string(out), "security> ", "")); err == nil && msg != "" {
+		err = errors.New(msg)
+	}
+	if err != nil {
+		return fmt.Errorf("keychain: security: %w", err)
+	}
+	return nil
+}
+
+func del(account string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "delete-generic-password", "-s", Service, "-a", account)
+	} else {
+		cmd = exec.Command("secret-tool", "clear", "service", Service, "account", account)
+	}
+	var exitErr *exec.ExitError
+	if err := run(cmd); errors.As(err, &exitErr) && runtime.GOOS == "darwin" {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// quote quotes s for a security -i command line.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func run(cmd *exec.Cmd) error {
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("keychain: %s: %w: %s", cmd.Args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+// Package mcp connects to Model Context Protocol servers and registers
+// their tools with the agent.
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/internal/version"
+)
+
+// Transport names.
+const (
+	TransportStdio = "stdio" // Launch a local command and talk over stdin/stdout
+	TransportSSE   = "sse"   // Connect to a server-sent events endpoint
+	TransportHTTP  = "http"  // Connect to a streamable HTTP endpoint
+)
+
+// ServerConfig describes an MCP server to connect to.
+type ServerConfig struct {
+	Name      string            // Prefix for registered tool names
+	Transport string            // stdio, sse, or http (default: stdio with Command, sse with URL)
+	Command   string            // stdio: executable to run
+	Args      []string          // stdio: command arguments
+	Env       map[string]string // stdio: extra environment variables
+	URL       string            // sse/http: server endpoint
+}
+
+// Registrar adds and removes agent tools. *agent.Agent implements Registrar.
+type Registrar interface {
+	RegisterToolFrom(source string, tool agent.Tool) error
+	UnregisterTool(id string)
+}
+
+// Ensure Agent implements Registrar.
+var _ Registrar = (*agent.Agent)(nil)
+
+// Config configures a Manager.
+type Config struct {
+	Registrar Registrar
+	Logger    *slog.Logger
+}
+
+// Manager maintains connections to MCP servers and keeps their tools
+// registered as the servers' tool lists change.
+type Manager struct {
+	registrar Registrar
+	logger    *slog.Logger
+	servers   map[string]*server
+	mu        sync.Mutex
+}
+
+// server is a connected MCP server.
+type server struct {
+	name    string
+	session *sdk.ClientSession
+	tools   map[string]bool // Registered agent tool names
+	mu      sync.Mutex
+}
+
+// source returns the tool source for the server, e.g. "mcp:github".
+func (s *server) source() string {
+	return Source(s.name)
+}
+
+// Source returns the tool source of an MCP server, used in tool IDs
+// ("mcp:github/github_create_issue").
+func Source(serverName string) string {
+	return "mcp:" + serverName
+}
+
+// New creates a Manager.
+func New(config Config) (*Manager, error) {
+	if config.Registrar == nil {
+		return nil, fmt.Errorf("registrar required")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Manager{
+		registrar: config.Registrar,
+		logger:    config.Logger,
+		servers:   make(map[string]*server),
+	}, nil
+}
+
+// Connect connects to a server and registers its tools.
+func (m *Manager) Connect(ctx context.Context, config ServerConfig) error {
+	transport, err := newTransport(config)
+	if err != nil {
+		return fmt.Errorf("mcp server %s: %w", config.Name, err)
+	}
+	return m.connect(ctx, config.Name, transport)
+}
+
+// connect starts a session over transport and registers the server's tools.
+func (m *Manager) connect(ctx context.Context, name string, transport sdk.Transport) error {
+	if name == "" {
+		return fmt.Errorf("mcp server name required")
+	}
+
+	m.mu.Lock()
+	if _, exists := m.servers[name]; exists {
+		m.mu.Unlock()
+		return fmt.Errorf("mcp server %s already connected", name)
+	}
+	srv := &server{name: name, tools: make(map[string]bool)}
+	m.servers[name] = srv
+	m.mu.Unlock()
+
+	client := sdk.NewClient(&sdk.Implementation{Name: "omniagent", Version: version.Version}, &sdk.ClientOptions{
+		Logger: m.logger,
+		ToolListChangedHandler: func(ctx context.Context, _ *sdk.ToolListChangedRequest) {
+			// Refresh outside the notification handler, which must not block
+			go func() {
+				refreshCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				if err := m.refresh(refreshCtx, srv); err != nil {
+					m.logger.Warn("failed to refresh mcp tools", "server", name, "error", err)
+				}
+			}()
+		},
+	})
+
+	session, err := client.Connect(ctx, transport, nil)
+	if err != nil {
+		m.mu.Lock()
+		delete(m.servers, name)
+		m.mu.Unlock()
+		return fmt.Errorf("connect mcp server %s: %w", name, err)
+	}
+
+	srv.mu.Lock()
+	srv.session = session
+	srv.mu.Unlock()
+
+	if err := m.refresh(ctx, srv); err != nil {
+		_ = m.disconnect(name)
+		return err
+	}
+	return nil
+}
+
+// refresh syncs the agent's tools with the server's current tool list.
+func (m *Manager) refresh(ctx context.Context, srv *server) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.session == nil {
+		return nil
+	}
+
+	current := make(map[string]bool)
+	for t, err := range srv.session.Tools(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("list tools from %s: %w", srv.name, err)
+		}
+		tool := newTool(srv.name, srv.session, t)
+		if current[tool.Name()] {
+			// Registering would replace the server's earlier tool
+			m.logger.Warn("skipping mcp tool", "server", srv.name, "tool", t.Name,
+				"error", fmt.Sprintf("name %s is taken by another tool of the server", tool.Name()))
+			continue
+		}
+		if err := m.registrar.RegisterToolFrom(srv.source(), tool); err != nil {
+			m.logger.Warn("skipping mcp tool", "server", srv.name, "tool", t.Name, "error", err)
+			continue
+		}
+		current[tool.Name()] = true
+	}
+
+	for name := range srv.tools {
+		if !current[name] {
+			m.registrar.UnregisterTool(agent.ToolID(srv.source(), name))
+		}
+	}
+	srv.tools = current
+
+	m.logger.Info("mcp tools registered", "server", srv.name, "tools", len(current))
+	return nil
+}
+
+// Tools returns the agent tool names registered for a server.
+func (m *Manager) Tools(serverName string) []string {
+	m.mu.Lock()
+	srv, ok := m.servers[serverName]
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	names := make([]string, 0, len(srv.tools))
+	for name := range srv.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// disconnect closes one server and unregisters its tools.
+func (m *Manager) disconnect(name string) error {
+	m.mu.Lock()
+	srv, ok := m.servers[name]
+	delete(m.servers, name)
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for tool := range srv.tools {
+		m.registrar.UnregisterTool(agent.ToolID(srv.source(), tool))
+	}
+	srv.tools = nil
+	if srv.session == nil {
+		return nil
+	}
+	err := srv.session.Close()
+	srv.session = nil
+	return err
+}
+
+// Close disconnects all servers.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+
+	var firstErr error
+	for _, name := range names {
+		if err := m.disconnect(name); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("close mcp server %s: %w", name, err)
+		}
+	}
+	return firstErr
+}
+
+// newTransport builds the SDK transport for a server configuration.
+func newTransport(config ServerConfig) (sdk.Transport, error) {
+	transport := config.Transport
+	if transport == "" {
+		if config.URL != "" {
+			transport = TransportSSE
+		} else {
+			transport = TransportStdio
+		}
+	}
+
+	switch transport {
+	case TransportStdio:
+		if config.Command == "" {
+			return nil, fmt.Errorf("command required for stdio transport")
+		}
+		cmd := exec.Command(config.Command, config.Args...) //nolint:gosec // G204: Command comes from operator configuration
+		cmd.Env = os.Environ()
+		for k, v := range config.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+		return &sdk.CommandTransport{Command: cmd}, nil
+	case TransportSSE:
+		if config.URL == "" {
+			return nil, fmt.Errorf("url required for sse transport")
+		}
+		return &sdk.SSEClientTransport{Endpoint: config.URL}, nil
+	case TransportHTTP:
+		if config.URL == "" {
+			return nil, fmt.Errorf("url required for http transport")
+		}
+		return &sdk.StreamableClientTransport{Endpoint: config.URL}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", transport)
+	}
+}
+
+// invalidToolChars matches characters model APIs reject in tool names.
+var invalidToolChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// maxToolName is the longest tool name model APIs accept.
+const maxToolName = 64
+
+// toolName prefixes a server tool name so tools from different servers
+// cannot collide. Names over maxToolName are truncated and end in a hash
+// of the full name, so long names sharing a prefix stay distinct.
+func toolName(serverName, name string) string {
+	full := invalidToolChars.ReplaceAllString(serverName+"_"+name, "_")
+	if len(full) <= maxToolName {
+		return full
+	}
+	sum := sha256.Sum256([]byte(serverName + "_" + name))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	return full[:maxToolName-len(suffix)] + suffix
+}
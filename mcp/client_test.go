@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+type fakeRegistrar struct {
+	tools map[string]agent.Tool
+	mu    sync.Mutex
+}
+
+func (r *fakeRegistrar) RegisterToolFrom(source string, tool agent.Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[agent.ToolID(source, tool.Name())] = tool
+	return nil
+}
+
+func (r *fakeRegistrar) UnregisterTool(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, id)
+}
+
+type echoArgs struct {
+	Text string `json:"text"`
+}
+
+func TestManagerRegistersServerTools(t *testing.T) {
+	ctx := context.Background()
+
+	server := sdk.NewServer(&sdk.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	sdk.AddTool(server, &sdk.Tool{Name: "echo", Description: "Echo text"},
+		func(_ context.Context, _ *sdk.CallToolRequest, in echoArgs) (*sdk.CallToolResult, any, error) {
+			return &sdk.CallToolResult{Content: []sdk.Content{&sdk.TextContent{Text: "echo: " + in.Text}}}, nil, nil
+		})
+
+	serverTransport, clientTransport := sdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server.Connect() error = %v", err)
+	}
+	defer serverSession.Close()
+
+	reg := &fakeRegistrar{tools: make(map[string]agent.Tool)}
+	m, err := New(Config{Registrar: reg})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := m.connect(ctx, "test.srv", clientTransport); err != nil {
+		t.Fatalf("connect() error = %v", err)
+	}
+
+	if got := m.Tools("test.srv"); len(got) != 1 || got[0] != "test_srv_echo" {
+		t.Fatalf("Tools() = %v, want [test_srv_echo]", got)
+	}
+
+	tool := reg.tools["mcp:test.srv/test_srv_echo"]
+	if tool.Parameters()["type"] != "object" {
+		t.Errorf("Parameters() = %v, want object schema", tool.Parameters())
+	}
+	out, err := tool.Execute(ctx, json.RawMessage(`{"text":"hi"}`))
+	if err != nil || out != "echo: hi" {
+		t.Errorf("Execute() = %q, %v", out, err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if len(reg.tools) != 0 {
+		t.Errorf("tools after Close() = %v, want none", reg.tools)
+	}
+}
+
+func TestToolName(t *testing.T) {
+	long := strings.Repeat("x", 70)
+	tests := []struct {
+		server, name string
+		want         string // Empty to only check the length
+	}{
+		{"github", "create_issue", "github_create_issue"},
+		{"test.srv", "echo", "test_srv_echo"},
+		{"srv", long + "_a", ""},
+		{"srv", long + "_b", ""},
+	}
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		got := toolName(tt.server, tt.name)
+		if tt.want != "" && got != tt.want {
+			t.Errorf("toolName(%q, %q) = %q, want %q", tt.server, tt.name, got, tt.want)
+		}
+		if len(got) > maxToolName {
+			t.Errorf("toolName(%q, %q) has %d characters, want at most %d", tt.server, tt.name, len(got), maxToolName)
+		}
+		if seen[got] {
+			t.Errorf("toolName(%q, %q) = %q collides", tt.server, tt.name, got)
+		}
+		seen[got] = true
+	}
+}
+
+func TestManagerSkipsCollidingTools(t *testing.T) {
+	ctx := context.Background()
+
+	server := sdk.NewServer(&sdk.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	for _, name := range []string{"get.item", "get_item"} {
+		sdk.AddTool(server, &sdk.Tool{Name: name, Description: "Get an item"},
+			func(_ context.Context, _ *sdk.CallToolRequest, in echoArgs) (*sdk.CallToolResult, any, error) {
+				return &sdk.CallToolResult{Content: []sdk.Content{&sdk.TextContent{Text: name}}}, nil, nil
+			})
+	}
+
+	serverTransport, clientTransport := sdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server.Connect() error = %v", err)
+	}
+	defer serverSession.Close()
+
+	reg := &fakeRegistrar{tools: make(map[string]agent.Tool)}
+	m, err := New(Config{Registrar: reg})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer m.Close()
+	if err := m.connect(ctx, "srv", clientTransport); err != nil {
+		t.Fatalf("connect() error = %v", err)
+	}
+
+	// Both sanitize to srv_get_item; the first listed keeps the name
+	if got := m.Tools("srv"); len(got) != 1 || got[0] != "srv_get_item" {
+		t.Fatalf("Tools() = %v, want [srv_get_item]", got)
+	}
+	out, err := reg.tools["mcp:srv/srv_get_item"].Execute(ctx, json.RawMessage(`{"text":"hi"}`))
+	if err != nil || out != "get.item" {
+		t.Errorf("Execute() = %q, %v; want the first tool", out, err)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	if _, err := newTransport(ServerConfig{Name: "x"}); err == nil {
+		t.Error("newTransport() without command or url should fail")
+	}
+	tr, err := newTransport(ServerConfig{Name: "x", URL: "http://localhost:3000/sse"})
+	if err != nil {
+		t.Fatalf("newTransport() error = %v", err)
+	}
+	if _, ok := tr.(*sdk.SSEClientTransport); !ok {
+		t.Errorf("newTransport() = %T, want SSE transport for url", tr)
+	}
+	if _, err := newTransport(ServerConfig{Name: "x", Transport: "grpc"}); err == nil {
+		t.Error("newTransport() with unknown transport should fail")
+	}
+}
@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/internal/version"
+	"github.com/plexusone/omniagent/skills"
+)
+
+// ServeConfig configures an MCP server exposing omniagent tools and skills.
+type ServeConfig struct {
+	Tools  []agent.Tool
+	Skills []*skills.Skill     // Exposed as prompts
+	Audit  agent.ToolAuditFunc // Records each tool execution, if set
+	Logger *slog.Logger
+}
+
+// NewServer creates an MCP server that serves tools as MCP tools and
+// skills as MCP prompts.
+func NewServer(config ServeConfig) *sdk.Server {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	server := sdk.NewServer(&sdk.Implementation{Name: "omniagent", Version: version.Version},
+		&sdk.ServerOptions{Logger: config.Logger})
+
+	registry := agent.NewToolRegistry()
+	registry.SetAuditor(config.Audit)
+	for _, tool := range config.Tools {
+		if err := registry.Register(tool); err != nil {
+			config.Logger.Warn("skipping mcp tool", "tool", tool.Name(), "error", err)
+			continue
+		}
+		server.AddTool(&sdk.Tool{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			InputSchema: tool.Parameters(),
+		}, toolHandler(registry, tool.Name(), config.Logger))
+	}
+
+	for _, skill := range config.Skills {
+		server.AddPrompt(&sdk.Prompt{
+			Name:        skill.Name,
+			Description: skill.Description,
+		}, skillHandler(skill))
+	}
+
+	return server
+}
+
+// toolHandler runs a registered tool for an MCP call. Each MCP session acts
+// as an agent session so session-scoped tools keep separate state per
+// client.
+func toolHandler(registry *agent.ToolRegistry, name string, logger *slog.Logger) sdk.ToolHandler {
+	return func(ctx context.Context, req *sdk.CallToolRequest) (*sdk.CallToolResult, error) {
+		if req.Session != nil {
+			ctx = agent.WithSession(ctx, "mcp:"+req.Session.ID())
+		}
+
+		args := req.Params.Arguments
+		if len(args) == 0 {
+			args = []byte("{}")
+		}
+
+		output, err := registry.Execute(ctx, name, args)
+		if err != nil {
+			logger.Warn("mcp tool call failed", "tool", name, "error", err)
+			// Tool failures are reported to the caller, not as protocol errors
+			text := err.Error()
+			if output != "" {
+				text = output + "\n\n" + text
+			}
+			return &sdk.CallToolResult{IsError: true, Content: []sdk.Content{&sdk.TextContent{Text: text}}}, nil
+		}
+		return &sdk.CallToolResult{Content: []sdk.Content{&sdk.TextContent{Text: output}}}, nil
+	}
+}
+
+// skillHandler returns a skill's instructions as a prompt.
+func skillHandler(skill *skills.Skill) sdk.PromptHandler {
+	return func(context.Context, *sdk.GetPromptRequest) (*sdk.GetPromptResult, error) {
+		return &sdk.GetPromptResult{
+			Description: skill.Description,
+			Messages: []*sdk.PromptMessage{
+				{Role: "user", Content: &sdk.TextContent{Text: skill.Content}},
+			},
+		}, nil
+	}
+}
@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/skills"
+)
+
+func TestServerExposesToolsAndSkills(t *testing.T) {
+	ctx := context.Background()
+
+	params := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+	}
+	greet := agent.NewBaseTool("greet", "Greet someone", params, func(ctx context.Context, args json.RawMessage) (string, error) {
+		var p struct{ Name string }
+		_ = json.Unmarshal(args, &p)
+		if p.Name == "" {
+			return "", errors.New("name required")
+		}
+		return "hello " + p.Name + " from " + agent.SessionFromContext(ctx)[:4], nil
+	})
+
+	server := NewServer(ServeConfig{
+		Tools:  []agent.Tool{greet},
+		Skills: []*skills.Skill{{Name: "haiku", Description: "Write haiku", Content: "Reply in 5-7-5."}},
+	})
+	serverTransport, clientTransport := sdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server.Connect() error = %v", err)
+	}
+	defer serverSession.Close()
+
+	client := sdk.NewClient(&sdk.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client.Connect() error = %v", err)
+	}
+	defer session.Close()
+
+	res, err := session.CallTool(ctx, &sdk.CallToolParams{Name: "greet", Arguments: map[string]any{"name": "Ada"}})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if text := res.Content[0].(*sdk.TextContent).Text; text != "hello Ada from mcp:" {
+		t.Errorf("CallTool() = %q", text)
+	}
+
+	res, err = session.CallTool(ctx, &sdk.CallToolParams{Name: "greet", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if !res.IsError || !strings.Contains(res.Content[0].(*sdk.TextContent).Text, "name required") {
+		t.Errorf("CallTool() failure = %+v, want tool error", res)
+	}
+
+	prompt, err := session.GetPrompt(ctx, &sdk.GetPromptParams{Name: "haiku"})
+	if err != nil {
+		t.Fatalf("GetPrompt() error = %v", err)
+	}
+	if text := prompt.Messages[0].Content.(*sdk.TextContent).Text; text != "Reply in 5-7-5." {
+		t.Errorf("GetPrompt() = %q", text)
+	}
+}
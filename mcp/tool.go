@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Tool adapts an MCP server tool to the agent.Tool interface.
+type Tool struct {
+	name        string
+	remoteName  string
+	description string
+	parameters  map[string]interface{}
+	session     *sdk.ClientSession
+}
+
+// newTool wraps a tool advertised by an MCP server.
+func newTool(serverName string, session *sdk.ClientSession, t *sdk.Tool) *Tool {
+	params := map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	if t.InputSchema != nil {
+		// The schema arrives as decoded JSON; normalize it to a map
+		if data, err := json.Marshal(t.InputSchema); err == nil {
+			var m map[string]interface{}
+			if json.Unmarshal(data, &m) == nil && m != nil {
+				params = m
+			}
+		}
+	}
+
+	description := t.Description
+	if description == "" {
+		description = t.Title
+	}
+
+	return &Tool{
+		name:        toolName(serverName, t.Name),
+		remoteName:  t.Name,
+		description: fmt.Sprintf("[%s] %s", serverName, description),
+		parameters:  params,
+		session:     session,
+	}
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return t.name
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return t.description
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return t.parameters
+}
+
+// Execute calls the tool on the MCP server.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var arguments any
+	if len(args) > 0 {
+		arguments = args
+	}
+
+	res, err := t.session.CallTool(ctx, &sdk.CallToolParams{Name: t.remoteName, Arguments: arguments})
+	if err != nil {
+		return "", fmt.Errorf("call %s: %w", t.remoteName, err)
+	}
+
+	output := formatContent(res)
+	if res.IsError {
+		return "", fmt.Errorf("%s failed: %s", t.remoteName, output)
+	}
+	return output, nil
+}
+
+// formatContent renders tool result content as text for the model.
+func formatContent(res *sdk.CallToolResult) string {
+	var parts []string
+	for _, c := range res.Content {
+		switch c := c.(type) {
+		case *sdk.TextContent:
+			parts = append(parts, c.Text)
+		case *sdk.ImageContent:
+			parts = append(parts, fmt.Sprintf("[image: %s]", c.MIMEType))
+		case *sdk.AudioContent:
+			parts = append(parts, fmt.Sprintf("[audio: %s]", c.MIMEType))
+		case *sdk.ResourceLink:
+			parts = append(parts, fmt.Sprintf("[resource: %s]", c.URI))
+		case *sdk.EmbeddedResource:
+			if c.Resource != nil && c.Resource.Text != "" {
+				parts = append(parts, c.Resource.Text)
+			} else if c.Resource != nil {
+				parts = append(parts, fmt.Sprintf("[resource: %s]", c.Resource.URI))
+			}
+		}
+	}
+	if len(parts) == 0 && res.StructuredContent != nil {
+		if data, err := json.Marshal(res.StructuredContent); err == nil {
+			return string(data)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
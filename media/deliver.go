@@ -0,0 +1,128 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+// MediaSender uploads media to a channel directly, for providers whose
+// Send only delivers text.
+type MediaSender interface {
+	SendMedia(ctx context.Context, chatID string, m provider.Media) error
+}
+
+// DeliveryConfig configures how a channel delivers media.
+type DeliveryConfig struct {
+	// Native reports whether the provider's Send delivers an item itself.
+	Native func(provider.Media) bool
+
+	// Sender uploads the items the provider cannot send.
+	Sender MediaSender
+
+	// CaptionLimit is the longest text sent as the caption of a message's
+	// only upload; longer text is sent on its own first. 0 never captions.
+	CaptionLimit int
+}
+
+// Deliver wraps a channel provider so the media in outgoing messages reach
+// the user as native photos, audio, and files: items the provider sends
+// itself are left to it, the rest are uploaded with config.Sender, and
+// anything that cannot be delivered is named in the text instead.
+func Deliver(p provider.Provider, config DeliveryConfig) provider.Provider {
+	return &deliverer{Provider: p, config: config}
+}
+
+type deliverer struct {
+	provider.Provider
+	config DeliveryConfig
+}
+
+func (d *deliverer) Send(ctx context.Context, chatID string, msg provider.OutgoingMessage) error {
+	if len(msg.Media) == 0 {
+		return d.Provider.Send(ctx, chatID, msg)
+	}
+
+	var native, uploads []provider.Media
+	var skipped []string
+	for _, m := range msg.Media {
+		switch {
+		case d.config.Native != nil && d.config.Native(m):
+			native = append(native, m)
+		case d.config.Sender != nil && (len(m.Data) > 0 || m.URL != ""):
+			uploads = append(uploads, m)
+		default:
+			skipped = append(skipped, mediaName(m))
+		}
+	}
+	if len(skipped) > 0 {
+		note := fmt.Sprintf("(Not sent, this channel cannot deliver it: %s)", strings.Join(skipped, ", "))
+		msg.Content = strings.TrimSpace(msg.Content + "\n\n" + note)
+	}
+	if len(uploads) == 1 && len(native) == 0 && uploads[0].Caption == "" &&
+		msg.Content != "" && len([]rune(msg.Content)) <= d.config.CaptionLimit {
+		uploads[0].Caption = msg.Content
+		msg.Content = ""
+	}
+
+	msg.Media = native
+	if msg.Content != "" || len(native) > 0 {
+		if err := d.Provider.Send(ctx, chatID, msg); err != nil {
+			return err
+		}
+	}
+	for _, m := range uploads {
+		if err := d.config.Sender.SendMedia(ctx, chatID, m); err != nil {
+			return fmt.Errorf("send %s: %w", mediaName(m), err)
+		}
+	}
+	return nil
+}
+
+// mediaName names a media item in messages.
+func mediaName(m provider.Media) string {
+	switch {
+	case m.Filename != "":
+		return m.Filename
+	case m.Type != "":
+		return string(m.Type)
+	default:
+		return "file"
+	}
+}
+
+// mediaFilename returns a file name for an upload, with an extension for
+// its MIME type.
+func mediaFilename(m provider.Media) string {
+	if m.Filename != "" {
+		return m.Filename
+	}
+	return mediaName(m) + Extension(m.MimeType)
+}
+
+// commonExtensions are the usual extensions of types mime lists several
+// for.
+var commonExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"audio/mpeg": ".mp3",
+	"audio/ogg":  ".ogg",
+	"audio/wav":  ".wav",
+	"video/mp4":  ".mp4",
+	"text/plain": ".txt",
+}
+
+// Extension returns the file extension for a MIME type, or "" if it has
+// none.
+func Extension(mimeType string) string {
+	t, _, _ := mime.ParseMediaType(mimeType)
+	if ext, ok := commonExtensions[t]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(t); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
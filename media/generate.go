@@ -0,0 +1,270 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Image generation defaults.
+const (
+	DefaultImageBaseURL = "https://api.openai.com/v1"
+	DefaultImageModel   = "gpt-image-1"
+	DefaultImageSize    = "1024x1024"
+)
+
+// errNoAttachments is returned by tools on channels that cannot receive
+// files.
+var errNoAttachments = errors.New("this channel cannot receive files")
+
+// ImageConfig configures an ImageTool.
+type ImageConfig struct {
+	BaseURL    string // Default: DefaultImageBaseURL
+	APIKey     string //nolint:gosec // G117: API key loaded from config
+	Model      string // Default: DefaultImageModel
+	Size       string // Default: DefaultImageSize
+	HTTPClient *http.Client
+}
+
+// ImageTool generates images through an OpenAI-compatible
+// /images/generations endpoint and attaches them to the reply.
+type ImageTool struct {
+	config ImageConfig
+}
+
+// NewImageTool creates an ImageTool.
+func NewImageTool(config ImageConfig) *ImageTool {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultImageBaseURL
+	}
+	if config.Model == "" {
+		config.Model = DefaultImageModel
+	}
+	if config.Size == "" {
+		config.Size = DefaultImageSize
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 3 * time.Minute}
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &ImageTool{config: config}
+}
+
+// Name returns the tool name.
+func (t *ImageTool) Name() string {
+	return "generate_image"
+}
+
+// Description returns the tool description.
+func (t *ImageTool) Description() string {
+	return "Generate an image from a description and send it to the user with the reply. " +
+		"Describe the subject, style, and composition in detail."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *ImageTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prompt": map[string]interface{}{
+				"type":        "string",
+				"description": "Description of the image to generate",
+			},
+		},
+		"required": []string{"prompt"},
+	}
+}
+
+// Execute generates the image and attaches it.
+func (t *ImageTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(params.Prompt) == "" {
+		return "", errors.New("prompt is required")
+	}
+	atts := agent.AttachmentsFromContext(ctx)
+	if atts == nil {
+		return "", errNoAttachments
+	}
+
+	image, revised, err := t.generate(ctx, params.Prompt)
+	if err != nil {
+		return "", err
+	}
+	mimeType := http.DetectContentType(image)
+	atts.Attach(agent.Attachment{Filename: "image" + Extension(mimeType), MimeType: mimeType, Data: image})
+	if revised != "" {
+		return "Attached the generated image to the reply. The model drew: " + revised, nil
+	}
+	return "Attached the generated image to the reply.", nil
+}
+
+// generate returns the image and, if the model rewrote it, the prompt it
+// used.
+func (t *ImageTool) generate(ctx context.Context, prompt string) ([]byte, string, error) {
+	req := map[string]any{
+		"model":  t.config.Model,
+		"prompt": prompt,
+		"size":   t.config.Size,
+		"n":      1,
+	}
+	// DALL-E returns URLs unless asked; newer models always return data
+	if strings.HasPrefix(t.config.Model, "dall-e") {
+		req["response_format"] = "b64_json"
+	}
+	var result struct {
+		Data []struct {
+			B64JSON       string `json:"b64_json"`
+			URL           string `json:"url"`
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+	}
+	if err := t.post(ctx, "/images/generations", req, &result); err != nil {
+		return nil, "", err
+	}
+	if len(result.Data) == 0 {
+		return nil, "", errors.New("generate image: empty response")
+	}
+	d := result.Data[0]
+	if d.B64JSON != "" {
+		image, err := base64.StdEncoding.DecodeString(d.B64JSON)
+		if err != nil {
+			return nil, "", fmt.Errorf("generate image: decode: %w", err)
+		}
+		return image, d.RevisedPrompt, nil
+	}
+	if d.URL == "" {
+		return nil, "", errors.New("generate image: response has no image")
+	}
+	image, err := t.download(ctx, d.URL)
+	return image, d.RevisedPrompt, err
+}
+
+func (t *ImageTool) post(ctx context.Context, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.config.APIKey)
+	}
+	resp, err := t.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("generate image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("generate image: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("generate image: decode response: %w", err)
+	}
+	return nil
+}
+
+func (t *ImageTool) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download image: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 50<<20))
+}
+
+// Synthesizer turns text into speech. *voice.Processor implements
+// Synthesizer.
+type Synthesizer interface {
+	SynthesizeSpeech(ctx context.Context, text string) ([]byte, string, error)
+}
+
+// SpeechTool reads text aloud with the voice provider and attaches the
+// audio to the reply.
+type SpeechTool struct {
+	synth Synthesizer
+}
+
+// NewSpeechTool creates a SpeechTool.
+func NewSpeechTool(synth Synthesizer) *SpeechTool {
+	return &SpeechTool{synth: synth}
+}
+
+// Name returns the tool name.
+func (t *SpeechTool) Name() string {
+	return "speak"
+}
+
+// Description returns the tool description.
+func (t *SpeechTool) Description() string {
+	return "Read text aloud and send the recording to the user with the reply, " +
+		"for example to pronounce words or to hear a message."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *SpeechTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to read aloud",
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+// Execute synthesizes the speech and attaches it.
+func (t *SpeechTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(params.Text) == "" {
+		return "", errors.New("text is required")
+	}
+	atts := agent.AttachmentsFromContext(ctx)
+	if atts == nil {
+		return "", errNoAttachments
+	}
+
+	audio, mimeType, err := t.synth.SynthesizeSpeech(ctx, params.Text)
+	if err != nil {
+		return "", fmt.Errorf("synthesize speech: %w", err)
+	}
+	atts.Attach(agent.Attachment{Filename: "speech" + Extension(mimeType), MimeType: mimeType, Data: audio})
+	return "Attached the recording to the reply.", nil
+}
+
+// Ensure the tools implement agent.Tool.
+var (
+	_ agent.Tool = (*ImageTool)(nil)
+	_ agent.Tool = (*SpeechTool)(nil)
+)
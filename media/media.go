@@ -0,0 +1,114 @@
+// Package media reads the files users send so the agent can understand
+// them: the text of documents, descriptions of images, and transcripts of
+// audio.
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/kb"
+)
+
+// DefaultMaxTextBytes caps the text read from one file.
+const DefaultMaxTextBytes = 20000
+
+// Describer describes images.
+type Describer interface {
+	Describe(ctx context.Context, image []byte, mimeType string) (string, error)
+}
+
+// Transcriber transcribes audio. *voice.Processor implements Transcriber.
+type Transcriber interface {
+	TranscribeAudio(ctx context.Context, audio []byte, mimeType string) (string, error)
+}
+
+// Config configures a Reader.
+type Config struct {
+	// Describer describes images; without one images are not read.
+	Describer Describer
+
+	// Transcriber transcribes audio; without one audio is not read.
+	Transcriber Transcriber
+
+	// MaxTextBytes caps the text read from one file (default: 20000).
+	MaxTextBytes int
+}
+
+// Reader turns attachments into text. It implements agent.AttachmentReader.
+type Reader struct {
+	config Config
+}
+
+// New creates a Reader.
+func New(config Config) *Reader {
+	if config.MaxTextBytes <= 0 {
+		config.MaxTextBytes = DefaultMaxTextBytes
+	}
+	return &Reader{config: config}
+}
+
+// Read returns the text of a document, a description of an image, or a
+// transcript of audio.
+func (r *Reader) Read(ctx context.Context, att agent.Attachment) (string, error) {
+	mimeType := MimeType(att)
+	var text string
+	var err error
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		if r.config.Describer == nil {
+			return "", errors.New("no vision model is configured to view images")
+		}
+		text, err = r.config.Describer.Describe(ctx, att.Data, mimeType)
+	case strings.HasPrefix(mimeType, "audio/"):
+		if r.config.Transcriber == nil {
+			return "", errors.New("voice is not configured to transcribe audio")
+		}
+		text, err = r.config.Transcriber.TranscribeAudio(ctx, att.Data, mimeType)
+	case strings.HasPrefix(mimeType, "video/"):
+		return "", fmt.Errorf("unsupported file type %s", mimeType)
+	default:
+		_, text, err = kb.Extract(ctx, att.Filename, att.Data)
+		if errors.Is(err, kb.ErrUnsupported) {
+			return "", fmt.Errorf("unsupported file type %s", mimeType)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return truncate(strings.TrimSpace(text), r.config.MaxTextBytes), nil
+}
+
+// MimeType returns an attachment's MIME type, from the channel if it gave
+// a specific one, otherwise from the file name or content.
+func MimeType(att agent.Attachment) string {
+	if t, _, _ := mime.ParseMediaType(att.MimeType); t != "" && t != "application/octet-stream" {
+		return t
+	}
+	if t, _, _ := mime.ParseMediaType(mime.TypeByExtension(filepath.Ext(att.Filename))); t != "" {
+		return t
+	}
+	t, _, _ := mime.ParseMediaType(http.DetectContentType(att.Data))
+	return t
+}
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "\n[truncated]"
+}
+
+// Ensure Reader implements agent.AttachmentReader.
+var _ agent.AttachmentReader = (*Reader)(nil)
@@ -0,0 +1,176 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+type fakeTranscriber struct{}
+
+func (fakeTranscriber) TranscribeAudio(_ context.Context, audio []byte, mimeType string) (string, error) {
+	return "transcript of " + mimeType, nil
+}
+
+func TestRead(t *testing.T) {
+	var gotImage string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Messages []struct {
+				Content []struct {
+					ImageURL struct {
+						URL string `json:"url"`
+					} `json:"image_url"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotImage = req.Messages[0].Content[1].ImageURL.URL
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"A cat on a sofa."}}]}`))
+	}))
+	defer server.Close()
+
+	r := New(Config{
+		Describer:    NewVisionDescriber(VisionConfig{BaseURL: server.URL + "/v1", APIKey: "key"}),
+		Transcriber:  fakeTranscriber{},
+		MaxTextBytes: 40,
+	})
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		att  agent.Attachment
+		want string
+	}{
+		{"document", agent.Attachment{Filename: "notes.md", Data: []byte("# Notes\n\nBuy milk.")}, "# Notes\n\nBuy milk."},
+		{"long document", agent.Attachment{Filename: "long.txt", Data: []byte(strings.Repeat("word ", 20))}, strings.Repeat("word ", 8) + "\n[truncated]"},
+		{"image", agent.Attachment{Filename: "cat.png", MimeType: "image/png", Data: []byte("png")}, "A cat on a sofa."},
+		{"audio", agent.Attachment{Filename: "memo.ogg", MimeType: "audio/ogg; codecs=opus", Data: []byte("ogg")}, "transcript of audio/ogg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Read(ctx, tt.att)
+			if err != nil || got != tt.want {
+				t.Errorf("Read = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+	if gotImage != "data:image/png;base64,cG5n" {
+		t.Errorf("image sent as %q", gotImage)
+	}
+
+	// Without a describer, images can't be read
+	if _, err := New(Config{}).Read(ctx, agent.Attachment{MimeType: "image/jpeg"}); err == nil {
+		t.Error("expected an error reading an image without a describer")
+	}
+}
+
+// textProvider records the messages a text-only channel is asked to send.
+type textProvider struct {
+	provider.Provider
+	sent []provider.OutgoingMessage
+}
+
+func (p *textProvider) Send(_ context.Context, _ string, msg provider.OutgoingMessage) error {
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+func TestDeliver(t *testing.T) {
+	var uploads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		upload := r.URL.Path + " " + r.FormValue("chat_id") + " " + r.FormValue("caption")
+		if f, h, err := r.FormFile("photo"); err == nil {
+			f.Close()
+			upload += " " + h.Filename
+		}
+		uploads = append(uploads, upload)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	image := provider.Media{Type: provider.MediaTypeImage, MimeType: "image/png", Data: []byte("png")}
+	audio := provider.Media{Type: provider.MediaTypeAudio, MimeType: "audio/mpeg", Data: []byte("mp3")}
+
+	// A short reply with one file becomes its caption
+	inner := &textProvider{}
+	p := Deliver(inner, DeliveryConfig{
+		Sender:       NewTelegramUploader(UploadConfig{Token: "T", BaseURL: server.URL}),
+		CaptionLimit: 10,
+	})
+	if err := p.Send(ctx, "42", provider.OutgoingMessage{Content: "Here", Media: []provider.Media{image}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.sent) != 0 || len(uploads) != 1 || uploads[0] != "/botT/sendPhoto 42 Here image.png" {
+		t.Errorf("sent %v, uploaded %q", inner.sent, uploads)
+	}
+
+	// Longer text goes first; natively sent media stays with it
+	uploads = nil
+	inner = &textProvider{}
+	p = Deliver(inner, DeliveryConfig{
+		Native:       func(m provider.Media) bool { return m.Type == provider.MediaTypeAudio },
+		Sender:       NewTelegramUploader(UploadConfig{Token: "T", BaseURL: server.URL}),
+		CaptionLimit: 10,
+	})
+	if err := p.Send(ctx, "42", provider.OutgoingMessage{Content: "A longer reply", Media: []provider.Media{image, audio}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.sent) != 1 || inner.sent[0].Content != "A longer reply" || len(inner.sent[0].Media) != 1 ||
+		len(uploads) != 1 || uploads[0] != "/botT/sendPhoto 42  image.png" {
+		t.Errorf("sent %v, uploaded %q", inner.sent, uploads)
+	}
+
+	// Media nothing can deliver is named instead
+	inner = &textProvider{}
+	doc := provider.Media{Type: provider.MediaTypeDocument, Filename: "report.pdf", Data: []byte("%PDF")}
+	if err := Deliver(inner, DeliveryConfig{}).Send(ctx, "42", provider.OutgoingMessage{Media: []provider.Media{doc}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.sent) != 1 || !strings.Contains(inner.sent[0].Content, "report.pdf") || len(inner.sent[0].Media) != 0 {
+		t.Errorf("sent %v", inner.sent)
+	}
+}
+
+func TestImageTool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/images/generations" || req["prompt"] != "a red fox" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// "\x89PNG\r\n\x1a\n" base64-encoded
+		_, _ = w.Write([]byte(`{"data":[{"b64_json":"iVBORw0KGgo="}]}`))
+	}))
+	defer server.Close()
+
+	tool := NewImageTool(ImageConfig{BaseURL: server.URL})
+	args := json.RawMessage(`{"prompt":"a red fox"}`)
+	if _, err := tool.Execute(context.Background(), args); err == nil {
+		t.Error("expected an error without attachment support")
+	}
+
+	atts := &agent.Attachments{}
+	if _, err := tool.Execute(agent.WithAttachments(context.Background(), atts), args); err != nil {
+		t.Fatal(err)
+	}
+	out := atts.Outgoing()
+	if len(out) != 1 || out[0].MimeType != "image/png" || out[0].Filename != "image.png" {
+		t.Errorf("attached %+v", out)
+	}
+}
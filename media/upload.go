@@ -0,0 +1,175 @@
+package media
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+// Upload API defaults.
+const (
+	DefaultTelegramBaseURL = "https://api.telegram.org"
+	DefaultDiscordBaseURL  = "https://discord.com/api/v10"
+
+	// Caption limits of the channels, in characters.
+	TelegramCaptionLimit = 1024
+	DiscordCaptionLimit  = 2000
+)
+
+// UploadConfig configures a channel uploader.
+type UploadConfig struct {
+	Token      string //nolint:gosec // G117: Bot token loaded from config
+	BaseURL    string // Default: the channel's API
+	HTTPClient *http.Client
+}
+
+func (c UploadConfig) withDefaults(baseURL string) UploadConfig {
+	if c.BaseURL == "" {
+		c.BaseURL = baseURL
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: 2 * time.Minute}
+	}
+	c.BaseURL = strings.TrimSuffix(c.BaseURL, "/")
+	return c
+}
+
+// TelegramUploader sends media through the Telegram Bot API: images as
+// photos, audio and voice notes as players, and everything else as
+// documents.
+type TelegramUploader struct {
+	config UploadConfig
+}
+
+// NewTelegramUploader creates a TelegramUploader for a bot token.
+func NewTelegramUploader(config UploadConfig) *TelegramUploader {
+	return &TelegramUploader{config: config.withDefaults(DefaultTelegramBaseURL)}
+}
+
+// SendMedia sends one media item to a chat.
+func (u *TelegramUploader) SendMedia(ctx context.Context, chatID string, m provider.Media) error {
+	method, field := "sendDocument", "document"
+	switch m.Type {
+	case provider.MediaTypeImage:
+		if m.MimeType != "image/gif" {
+			method, field = "sendPhoto", "photo"
+		}
+	case provider.MediaTypeVideo:
+		method, field = "sendVideo", "video"
+	case provider.MediaTypeAudio:
+		method, field = "sendAudio", "audio"
+	case provider.MediaTypeVoice:
+		// Telegram plays only Opus in Ogg as voice notes
+		method, field = "sendAudio", "audio"
+		if strings.HasPrefix(m.MimeType, "audio/ogg") {
+			method, field = "sendVoice", "voice"
+		}
+	}
+
+	fields := map[string]string{"chat_id": chatID}
+	if m.Caption != "" {
+		fields["caption"] = m.Caption
+	}
+	if len(m.Data) == 0 {
+		// Telegram fetches URLs itself
+		fields[field] = m.URL
+		field = ""
+	}
+	return upload(ctx, u.config, u.config.BaseURL+"/bot"+u.config.Token+"/"+method, fields, field, m, nil)
+}
+
+// DiscordUploader sends media as Discord message attachments, which
+// Discord shows inline for images, audio, and video.
+type DiscordUploader struct {
+	config UploadConfig
+}
+
+// NewDiscordUploader creates a DiscordUploader for a bot token.
+func NewDiscordUploader(config UploadConfig) *DiscordUploader {
+	return &DiscordUploader{config: config.withDefaults(DefaultDiscordBaseURL)}
+}
+
+// SendMedia sends one media item to a channel.
+func (u *DiscordUploader) SendMedia(ctx context.Context, channelID string, m provider.Media) error {
+	content := m.Caption
+	field := "files[0]"
+	if len(m.Data) == 0 {
+		// Discord embeds linked media
+		content = strings.TrimSpace(content + "\n" + m.URL)
+		field = ""
+	}
+	payload, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Authorization": {"Bot " + u.config.Token}}
+	endpoint := u.config.BaseURL + "/channels/" + channelID + "/messages"
+	return upload(ctx, u.config, endpoint, map[string]string{"payload_json": string(payload)}, field, m, header)
+}
+
+// upload posts a multipart form with the fields and, unless field is
+// empty, the media as a file.
+func upload(ctx context.Context, config UploadConfig, endpoint string, fields map[string]string, field string, m provider.Media, header http.Header) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	if field != "" {
+		part, err := w.CreatePart(map[string][]string{
+			"Content-Disposition": {fmt.Sprintf(`form-data; name=%q; filename=%q`, field, mediaFilename(m))},
+			"Content-Type":        {cmp.Or(m.MimeType, "application/octet-stream")},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(m.Data); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := config.HTTPClient.Do(req)
+	if err != nil {
+		// The URL holds the Telegram token; report the failure without it
+		if uerr := (*url.Error)(nil); errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("upload: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Ensure the uploaders implement MediaSender.
+var (
+	_ MediaSender = (*TelegramUploader)(nil)
+	_ MediaSender = (*DiscordUploader)(nil)
+)
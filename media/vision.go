@@ -0,0 +1,111 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Vision defaults.
+const (
+	DefaultVisionBaseURL = "https://api.openai.com/v1"
+	DefaultVisionModel   = "gpt-4o-mini"
+	DefaultVisionPrompt  = "Describe this image in detail for someone who cannot see it. " +
+		"Transcribe any text in it exactly."
+)
+
+// VisionConfig configures a VisionDescriber.
+type VisionConfig struct {
+	BaseURL    string // Default: DefaultVisionBaseURL
+	APIKey     string //nolint:gosec // G117: API key loaded from config
+	Model      string // Default: DefaultVisionModel
+	Prompt     string // Default: DefaultVisionPrompt
+	HTTPClient *http.Client
+}
+
+// VisionDescriber describes images with a vision model through an
+// OpenAI-compatible /chat/completions endpoint, which OpenAI, Ollama, and
+// most other servers provide.
+type VisionDescriber struct {
+	config VisionConfig
+}
+
+// NewVisionDescriber creates a VisionDescriber.
+func NewVisionDescriber(config VisionConfig) *VisionDescriber {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultVisionBaseURL
+	}
+	if config.Model == "" {
+		config.Model = DefaultVisionModel
+	}
+	if config.Prompt == "" {
+		config.Prompt = DefaultVisionPrompt
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: time.Minute}
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &VisionDescriber{config: config}
+}
+
+// Describe returns a description of the image.
+func (d *VisionDescriber) Describe(ctx context.Context, image []byte, mimeType string) (string, error) {
+	dataURL := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(image)
+	body, err := json.Marshal(map[string]any{
+		"model":      d.config.Model,
+		"max_tokens": 1000,
+		"messages": []map[string]any{{
+			"role": "user",
+			"content": []map[string]any{
+				{"type": "text", "text": d.config.Prompt},
+				{"type": "image_url", "image_url": map[string]string{"url": dataURL}},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+d.config.APIKey)
+	}
+
+	resp, err := d.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("describe image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("describe image: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("describe image: decode response: %w", err)
+	}
+	if len(result.Choices) == 0 || strings.TrimSpace(result.Choices[0].Message.Content) == "" {
+		return "", errors.New("describe image: empty response")
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+// Ensure VisionDescriber implements Describer.
+var _ Describer = (*VisionDescriber)(nil)
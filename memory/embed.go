@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Embedding defaults.
+const (
+	DefaultEmbeddingBaseURL = "https://api.openai.com/v1"
+	DefaultEmbeddingModel   = "text-embedding-3-small"
+)
+
+// Embedder turns texts into vectors.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+
+	// Model names the embedding model; vectors from different models
+	// are not compared.
+	Model() string
+}
+
+// EmbedderConfig configures an OpenAIEmbedder.
+type EmbedderConfig struct {
+	BaseURL    string // Default: DefaultEmbeddingBaseURL
+	APIKey     string //nolint:gosec // G117: API key loaded from config
+	Model      string // Default: DefaultEmbeddingModel
+	HTTPClient *http.Client
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint, which
+// OpenAI, Ollama, LM Studio, and most other servers provide.
+type OpenAIEmbedder struct {
+	config EmbedderConfig
+}
+
+// NewOpenAIEmbedder creates an OpenAIEmbedder.
+func NewOpenAIEmbedder(config EmbedderConfig) *OpenAIEmbedder {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultEmbeddingBaseURL
+	}
+	if config.Model == "" {
+		config.Model = DefaultEmbeddingModel
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &OpenAIEmbedder{config: config}
+}
+
+// Model returns the embedding model.
+func (e *OpenAIEmbedder) Model() string {
+	return e.config.Model
+}
+
+// Embed returns one vector per text.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": e.config.Model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	}
+
+	resp, err := e.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("embed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("embed: decode response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embed: got %d embeddings for %d texts", len(result.Data), len(texts))
+	}
+	vecs := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, fmt.Errorf("embed: invalid index %d", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, nil
+}
+
+// Ensure OpenAIEmbedder implements Embedder.
+var _ Embedder = (*OpenAIEmbedder)(nil)
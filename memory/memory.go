@@ -0,0 +1,171 @@
+// Package memory stores facts about the people the agent talks to as
+// embeddings, and retrieves the relevant ones for each message, across
+// sessions and channels.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Defaults.
+const (
+	DefaultRecallLimit    = 5
+	DefaultMinScore       = 0.3
+	DefaultDuplicateScore = 0.92
+)
+
+// Group chat memory scopes.
+const (
+	GroupScopeUser   = "user"   // Each member has their own memories
+	GroupScopeShared = "shared" // The group shares one set of memories
+)
+
+// Config configures a Memory.
+type Config struct {
+	Store    *Store   // Required
+	Embedder Embedder // Required
+
+	// RecallLimit caps the memories returned by a search (default: 5).
+	RecallLimit int
+
+	// MinScore is the lowest similarity that counts as relevant
+	// (default: 0.3).
+	MinScore float64
+
+	// DuplicateScore is the similarity above which a new memory replaces
+	// an existing one instead of being added (default: 0.92).
+	DuplicateScore float64
+
+	// GroupScope is GroupScopeUser (the default) or GroupScopeShared.
+	GroupScope string
+
+	Logger *slog.Logger
+}
+
+// Memory remembers and recalls facts per person.
+type Memory struct {
+	config Config
+	logger *slog.Logger
+}
+
+// New creates a Memory.
+func New(config Config) (*Memory, error) {
+	if config.Store == nil {
+		return nil, errors.New("memory: store required")
+	}
+	if config.Embedder == nil {
+		return nil, errors.New("memory: embedder required")
+	}
+	if config.RecallLimit <= 0 {
+		config.RecallLimit = DefaultRecallLimit
+	}
+	if config.MinScore <= 0 {
+		config.MinScore = DefaultMinScore
+	}
+	if config.DuplicateScore <= 0 {
+		config.DuplicateScore = DefaultDuplicateScore
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Memory{config: config, logger: config.Logger}, nil
+}
+
+// Scope returns whose memories a request reads and writes: the sender's
+// person ID when their accounts are linked, or contact ID, so memories
+// follow a person across chats; or the session when the sender is unknown
+// or the group chat shares its memories.
+func (m *Memory) Scope(ctx context.Context) string {
+	if _, group := agent.SpeakerFromContext(ctx); group && m.config.GroupScope == GroupScopeShared {
+		if session := agent.SessionFromContext(ctx); session != "" {
+			return "session:" + session
+		}
+	}
+	if person := agent.PersonFromContext(ctx); person != "" {
+		return "person:" + person
+	}
+	if contact := agent.ContactFromContext(ctx); contact != "" {
+		return "contact:" + contact
+	}
+	if session := agent.SessionFromContext(ctx); session != "" {
+		return "session:" + session
+	}
+	return ""
+}
+
+// Remember stores a fact in scope and returns its ID. A fact nearly the
+// same as an existing one replaces it, so corrections don't pile up.
+func (m *Memory) Remember(ctx context.Context, scope, content string) (int64, error) {
+	content = strings.TrimSpace(content)
+	if content == "" || len(content) > MaxContentLength {
+		return 0, fmt.Errorf("memory must be 1-%d characters", MaxContentLength)
+	}
+	vec, err := m.embed(ctx, content)
+	if err != nil {
+		return 0, err
+	}
+	model := m.config.Embedder.Model()
+	similar, err := m.config.Store.Search(ctx, scope, model, vec, 1, m.config.DuplicateScore)
+	if err != nil {
+		return 0, err
+	}
+	if len(similar) > 0 {
+		id := similar[0].ID
+		return id, m.config.Store.Update(ctx, scope, id, model, content, vec)
+	}
+	return m.config.Store.Add(ctx, scope, model, content, vec)
+}
+
+// Recall returns the memories in scope most relevant to query.
+func (m *Memory) Recall(ctx context.Context, scope, query string) ([]Match, error) {
+	vec, err := m.embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return m.config.Store.Search(ctx, scope, m.config.Embedder.Model(), vec, m.config.RecallLimit, m.config.MinScore)
+}
+
+// Forget deletes a memory from scope.
+func (m *Memory) Forget(ctx context.Context, scope string, id int64) error {
+	return m.config.Store.Delete(ctx, scope, id)
+}
+
+// Retrieve returns a prompt section listing the memories relevant to the
+// message. It implements agent.Retriever.
+func (m *Memory) Retrieve(ctx context.Context, _, content string) (string, error) {
+	scope := m.Scope(ctx)
+	if scope == "" || strings.TrimSpace(content) == "" {
+		return "", nil
+	}
+	matches, err := m.Recall(ctx, scope, content)
+	if err != nil || len(matches) == 0 {
+		return "", err
+	}
+	var b strings.Builder
+	if strings.HasPrefix(scope, "session:") {
+		b.WriteString("Things you remember about this conversation that may be relevant:")
+	} else {
+		b.WriteString("Things you remember about this user that may be relevant:")
+	}
+	for _, match := range matches {
+		fmt.Fprintf(&b, "\n- %s", match.Content)
+	}
+	return b.String(), nil
+}
+
+func (m *Memory) embed(ctx context.Context, text string) ([]float32, error) {
+	vecs, err := m.config.Embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// Ensure Memory implements agent.Retriever.
+var _ agent.Retriever = (*Memory)(nil)
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// wordEmbedder embeds texts as bags of hashed words, so texts sharing
+// words are similar.
+type wordEmbedder struct{}
+
+func (wordEmbedder) Model() string { return "words" }
+
+func (wordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, 1024)
+		for _, w := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(strings.Trim(w, ".,?")))
+			vec[h.Sum32()%1024]++
+		}
+		vecs[i] = vec
+	}
+	return vecs, nil
+}
+
+func newTestMemory(t *testing.T) *Memory {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	m, err := New(Config{Store: store, Embedder: wordEmbedder{}, DuplicateScore: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestRememberRecall(t *testing.T) {
+	m := newTestMemory(t)
+	ctx := agent.WithContact(context.Background(), "telegram:7")
+	remember := NewRememberTool(m)
+	recall := NewRecallTool(m)
+
+	for _, fact := range []string{"Allergic to peanuts", "Lives in Lisbon", "Has a dog named Rex"} {
+		if _, err := remember.Execute(ctx, json.RawMessage(`{"fact":"`+fact+`"}`)); err != nil {
+			t.Fatalf("remember %q: %v", fact, err)
+		}
+	}
+	// A near-duplicate replaces the earlier fact
+	if _, err := remember.Execute(ctx, json.RawMessage(`{"fact":"Lives in Lisbon, Portugal"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := recall.Execute(ctx, json.RawMessage(`{"query":"Lisbon"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Lives in Lisbon, Portugal") || strings.Contains(out, "] Lives in Lisbon (") {
+		t.Errorf("recall = %q", out)
+	}
+
+	// Memories belong to one person
+	other := agent.WithContact(context.Background(), "telegram:8")
+	if out, _ := recall.Execute(other, json.RawMessage(`{"query":"peanuts"}`)); !strings.HasPrefix(out, "Nothing") {
+		t.Errorf("other contact recalled %q", out)
+	}
+
+	section, err := m.Retrieve(ctx, "telegram:7", "peanuts snack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(section, "Allergic to peanuts") {
+		t.Errorf("retrieved %q", section)
+	}
+}
+
+func TestGroupScope(t *testing.T) {
+	ctx := agent.WithSession(agent.WithContact(context.Background(), "telegram:7"), "telegram:-100")
+	group := agent.WithSpeaker(ctx, "Alice")
+
+	m := newTestMemory(t)
+	if got := m.Scope(group); got != "contact:telegram:7" {
+		t.Errorf("user scope = %q, want contact:telegram:7", got)
+	}
+	m.config.GroupScope = GroupScopeShared
+	if got := m.Scope(group); got != "session:telegram:-100" {
+		t.Errorf("shared scope = %q, want session:telegram:-100", got)
+	}
+	if got := m.Scope(ctx); got != "contact:telegram:7" {
+		t.Errorf("direct chat scope = %q, want contact:telegram:7", got)
+	}
+	if got := m.Scope(agent.WithPerson(ctx, "alice")); got != "person:alice" {
+		t.Errorf("linked scope = %q, want person:alice", got)
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	e := NewOpenAIEmbedder(EmbedderConfig{BaseURL: server.URL + "/v1/", APIKey: "key"})
+	vecs, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vecs[0][0] != 1 || vecs[1][1] != 1 {
+		t.Errorf("vecs = %v, want ordered by index", vecs)
+	}
+}
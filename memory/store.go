@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver
+)
+
+// Limits keep each person's memory small enough to search in full.
+const (
+	MaxContentLength = 1000
+	MaxPerScope      = 1000
+)
+
+// ErrNotFound is returned when a memory does not exist.
+var ErrNotFound = errors.New("memory not found")
+
+// Entry is a stored memory.
+type Entry struct {
+	ID        int64
+	Content   string
+	CreatedAt time.Time
+}
+
+// Match is a memory found by similarity.
+type Match struct {
+	Entry
+	Score float64 // Cosine similarity, 1 is identical
+}
+
+// Store keeps memories and their embeddings in SQLite, partitioned by
+// scope. Search compares the query with every vector in the scope, which
+// is fast at the sizes MaxPerScope allows.
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS memories (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	scope      TEXT NOT NULL,
+	content    TEXT NOT NULL,
+	model      TEXT NOT NULL,
+	embedding  BLOB NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS memories_scope ON memories (scope, model)`
+
+// Open opens (or creates) a memory database at path.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("create memory dir: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open memory: %w", err)
+	}
+	// SQLite allows one writer; serialize access instead of retrying
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create memory schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add stores content with its embedding and returns its ID. A full scope
+// drops its oldest memory.
+func (s *Store) Add(ctx context.Context, scope, model, content string, vec []float32) (int64, error) {
+	if content == "" || len(content) > MaxContentLength {
+		return 0, fmt.Errorf("memory must be 1-%d characters", MaxContentLength)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM memories WHERE id IN (
+			SELECT id FROM memories WHERE scope = ? ORDER BY id DESC LIMIT -1 OFFSET ?)`,
+		scope, MaxPerScope-1); err != nil {
+		return 0, fmt.Errorf("trim memories: %w", err)
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO memories (scope, content, model, embedding, created_at) VALUES (?, ?, ?, ?, ?)`,
+		scope, content, model, EncodeVector(vec), time.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("add memory: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("add memory: %w", err)
+	}
+	return id, tx.Commit()
+}
+
+// Update replaces a memory's content and embedding.
+func (s *Store) Update(ctx context.Context, scope string, id int64, model, content string, vec []float32) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE memories SET content = ?, model = ?, embedding = ?, created_at = ? WHERE scope = ? AND id = ?`,
+		content, model, EncodeVector(vec), time.Now().Unix(), scope, id)
+	if err != nil {
+		return fmt.Errorf("update memory: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a memory from scope.
+func (s *Store) Delete(ctx context.Context, scope string, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM memories WHERE scope = ? AND id = ?`, scope, id)
+	if err != nil {
+		return fmt.Errorf("delete memory: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Search returns up to limit memories in scope embedded with model, most
+// similar to vec first, scoring at least minScore.
+func (s *Store) Search(ctx context.Context, scope, model string, vec []float32, limit int, minScore float64) ([]Match, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, content, embedding, created_at FROM memories WHERE scope = ? AND model = ?`, scope, model)
+	if err != nil {
+		return nil, fmt.Errorf("search memories: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		var blob []byte
+		var created int64
+		if err := rows.Scan(&m.ID, &m.Content, &blob, &created); err != nil {
+			return nil, fmt.Errorf("search memories: %w", err)
+		}
+		m.CreatedAt = time.Unix(created, 0)
+		m.Score = Cosine(vec, DecodeVector(blob))
+		if m.Score >= minScore {
+			matches = append(matches, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search memories: %w", err)
+	}
+	slices.SortFunc(matches, func(a, b Match) int { return cmp.Compare(b.Score, a.Score) })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// EncodeVector packs a vector as little-endian float32s.
+func EncodeVector(vec []float32) []byte {
+	b := make([]byte, 4*len(vec))
+	for i, f := range vec {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+// DecodeVector unpacks a vector packed by EncodeVector.
+func DecodeVector(b []byte) []float32 {
+	vec := make([]float32, len(b)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return vec
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 if their
+// lengths differ.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
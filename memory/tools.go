@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// errNoScope is returned when a request has no sender or session.
+var errNoScope = errors.New("memory is not available here: the user is unknown")
+
+// RememberTool stores facts about the user.
+type RememberTool struct {
+	memory *Memory
+}
+
+// NewRememberTool creates the remember tool.
+func NewRememberTool(m *Memory) *RememberTool {
+	return &RememberTool{memory: m}
+}
+
+// Name returns the tool name.
+func (t *RememberTool) Name() string {
+	return "remember"
+}
+
+// Description returns the tool description.
+func (t *RememberTool) Description() string {
+	return "Save a lasting fact about the user, such as a preference, a name, or a plan, " +
+		"to recall in later conversations. Write it as a standalone sentence. " +
+		"A fact close to one already saved replaces it. Pass forget with a memory ID from recall to delete one."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *RememberTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"fact": map[string]interface{}{
+				"type":        "string",
+				"description": "The fact to save, e.g. \"Prefers vegetarian restaurants\"",
+			},
+			"forget": map[string]interface{}{
+				"type":        "integer",
+				"description": "ID of a memory to delete instead",
+			},
+		},
+	}
+}
+
+// Execute saves or deletes a memory.
+func (t *RememberTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Fact   string `json:"fact"`
+		Forget int64  `json:"forget"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+	scope := t.memory.Scope(ctx)
+	if scope == "" {
+		return "", errNoScope
+	}
+
+	if params.Forget != 0 {
+		if err := t.memory.Forget(ctx, scope, params.Forget); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Forgot memory %d.", params.Forget), nil
+	}
+	id, err := t.memory.Remember(ctx, scope, params.Fact)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Saved as memory %d.", id), nil
+}
+
+// RecallTool searches what is remembered about the user.
+type RecallTool struct {
+	memory *Memory
+}
+
+// NewRecallTool creates the recall tool.
+func NewRecallTool(m *Memory) *RecallTool {
+	return &RecallTool{memory: m}
+}
+
+// Name returns the tool name.
+func (t *RecallTool) Name() string {
+	return "recall"
+}
+
+// Description returns the tool description.
+func (t *RecallTool) Description() string {
+	return "Search the facts saved about the user in earlier conversations."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *RecallTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "What to look for, e.g. \"dietary preferences\"",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+// Execute returns the matching memories with their IDs.
+func (t *RecallTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return "", errors.New("query required")
+	}
+	scope := t.memory.Scope(ctx)
+	if scope == "" {
+		return "", errNoScope
+	}
+
+	matches, err := t.memory.Recall(ctx, scope, params.Query)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "Nothing relevant is saved.", nil
+	}
+	var b strings.Builder
+	for _, m := range matches {
+		fmt.Fprintf(&b, "- [%d] %s (saved %s)\n", m.ID, m.Content, m.CreatedAt.Format("2006-01-02"))
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Ensure the tools implement agent.Tool.
+var (
+	_ agent.Tool = (*RememberTool)(nil)
+	_ agent.Tool = (*RecallTool)(nil)
+)
@@ -0,0 +1,32 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/access"
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Access applies the sender's permission tier: silent tiers, such as
+// blocked, get no reply, and the others are held to their tools and reply
+// length. It must follow Contact.
+func Access(control *access.Control) Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			name, tier := control.Tier(agent.ContactFromContext(ctx))
+			if tier.Silent {
+				return nil
+			}
+			ctx = agent.WithTier(ctx, name)
+			if tier.Tools != nil {
+				ctx = agent.WithAllowedTools(ctx, tier.Tools)
+			}
+			if tier.MaxTokens > 0 {
+				ctx = agent.WithMaxTokens(ctx, tier.MaxTokens)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
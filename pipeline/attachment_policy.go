@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+// Attachment policy violation actions.
+const (
+	ViolationReject = "reject" // Reply with a message and drop the whole message
+	ViolationIgnore = "ignore" // Drop the attachment and keep the text
+)
+
+// DefaultAttachmentReply is sent when an attachment is rejected.
+const DefaultAttachmentReply = "Sorry, I can't accept {file}: {reason}."
+
+// AttachmentPolicy limits the files a channel accepts. Zero fields are
+// unlimited.
+type AttachmentPolicy struct {
+	MaxBytes int64 // Per file
+
+	// MimeTypes are the accepted types, such as "application/pdf" or
+	// "image/*". Empty accepts every type.
+	MimeTypes []string
+
+	// OnViolation is ViolationReject (the default) or ViolationIgnore.
+	OnViolation string
+
+	// Reply is sent on rejection and may contain {file} and {reason}
+	// (default: DefaultAttachmentReply).
+	Reply string
+}
+
+// AttachmentPolicyConfig configures the attachment policy middleware.
+type AttachmentPolicyConfig struct {
+	// Default applies to every channel. Channels overrides it per channel
+	// name; unset fields fall back to Default.
+	Default  AttachmentPolicy
+	Channels map[string]AttachmentPolicy
+
+	// Sender delivers rejection replies.
+	Sender Sender
+	Logger *slog.Logger
+}
+
+// AttachmentPolicies enforces per-channel attachment size and type limits
+// before anything reads the files, so rejected voice notes and images
+// never reach speech-to-text or vision models.
+func AttachmentPolicies(config AttachmentPolicyConfig) Middleware {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			if len(msg.Media) == 0 {
+				return next(ctx, msg)
+			}
+			policy := config.policy(msg.ProviderName)
+
+			kept := make([]provider.Media, 0, len(msg.Media))
+			for _, m := range msg.Media {
+				reason := policy.check(m)
+				if reason == "" {
+					kept = append(kept, m)
+					continue
+				}
+				config.Logger.Info("attachment refused by policy",
+					"channel", msg.ProviderName,
+					"file", m.Filename,
+					"mime_type", m.MimeType,
+					"size", len(m.Data),
+					"reason", reason,
+					"action", policy.OnViolation)
+				if policy.OnViolation == ViolationIgnore {
+					continue
+				}
+				if config.Sender == nil {
+					return nil
+				}
+				return config.Sender.Send(ctx, msg.ProviderName, msg.ChatID, provider.OutgoingMessage{
+					Content: formatAttachmentReply(policy.Reply, m, reason),
+					ReplyTo: msg.ID,
+				})
+			}
+
+			if len(kept) == 0 && strings.TrimSpace(msg.Content) == "" {
+				return nil // Nothing left to answer
+			}
+			msg.Media = kept
+			return next(ctx, msg)
+		}
+	}
+}
+
+// policy returns the policy for a channel.
+func (c AttachmentPolicyConfig) policy(channel string) AttachmentPolicy {
+	p := c.Default
+	if o, ok := c.Channels[channel]; ok {
+		if o.MaxBytes != 0 {
+			p.MaxBytes = o.MaxBytes
+		}
+		if len(o.MimeTypes) > 0 {
+			p.MimeTypes = o.MimeTypes
+		}
+		if o.OnViolation != "" {
+			p.OnViolation = o.OnViolation
+		}
+		if o.Reply != "" {
+			p.Reply = o.Reply
+		}
+	}
+	if p.OnViolation != ViolationIgnore {
+		p.OnViolation = ViolationReject
+	}
+	if p.Reply == "" {
+		p.Reply = DefaultAttachmentReply
+	}
+	return p
+}
+
+// check returns why m violates the policy, or "".
+func (p AttachmentPolicy) check(m provider.Media) string {
+	if p.MaxBytes > 0 && int64(len(m.Data)) > p.MaxBytes {
+		return fmt.Sprintf("it is larger than %s", formatBytes(p.MaxBytes))
+	}
+	if len(p.MimeTypes) == 0 {
+		return ""
+	}
+	mimeType, _, _ := strings.Cut(strings.ToLower(m.MimeType), ";")
+	mimeType = strings.TrimSpace(mimeType)
+	for _, pattern := range p.MimeTypes {
+		if ok, _ := path.Match(strings.ToLower(pattern), mimeType); ok {
+			return ""
+		}
+	}
+	if mimeType == "" {
+		return "its file type is unknown"
+	}
+	return fmt.Sprintf("%s files aren't accepted here", mimeType)
+}
+
+// formatAttachmentReply fills in a rejection reply.
+func formatAttachmentReply(reply string, m provider.Media, reason string) string {
+	file := "this file"
+	switch {
+	case m.Filename != "":
+		file = m.Filename
+	case m.Type == provider.MediaTypeVoice:
+		file = "this voice note"
+	case m.Type != "":
+		file = "this " + string(m.Type)
+	}
+	return strings.NewReplacer("{file}", file, "{reason}", reason).Replace(reply)
+}
+
+// formatBytes formats a size limit for users.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
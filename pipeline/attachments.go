@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Attachments passes files the user sent to tools and delivers files tools
+// attach to the reply, after the text reply has been sent. Nothing is
+// delivered when the handler fails, as the reply the files belong to was
+// not sent.
+func Attachments(sender Sender, logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			atts := &agent.Attachments{}
+			for _, m := range msg.Media {
+				if len(m.Data) == 0 || m.Type == provider.MediaTypeVoice {
+					continue // Voice notes are transcribed, not stored
+				}
+				atts.Incoming = append(atts.Incoming, agent.Attachment{
+					Filename: m.Filename,
+					MimeType: m.MimeType,
+					Data:     m.Data,
+				})
+			}
+
+			if err := next(agent.WithAttachments(ctx, atts), msg); err != nil {
+				if n := len(atts.Outgoing()); n > 0 {
+					logger.Warn("dropping attachments of failed reply", "files", n, "error", err)
+				}
+				return err
+			}
+
+			for _, att := range atts.Outgoing() {
+				out := provider.OutgoingMessage{Media: []provider.Media{{
+					Type:     mediaType(att.MimeType),
+					Data:     att.Data,
+					MimeType: att.MimeType,
+					Filename: att.Filename,
+				}}}
+				if sendErr := sender.Send(ctx, msg.ProviderName, msg.ChatID, out); sendErr != nil {
+					logger.Warn("failed to send attachment", "file", att.Filename, "error", sendErr)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// mediaType maps a MIME type to a channel media type.
+func mediaType(mimeType string) provider.MediaType {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return provider.MediaTypeImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return provider.MediaTypeVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return provider.MediaTypeAudio
+	default:
+		return provider.MediaTypeDocument
+	}
+}
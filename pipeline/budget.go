@@ -0,0 +1,46 @@
+package pipeline
+
+import (
+	"context"
+	"slices"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/budget"
+)
+
+// BudgetConfig configures the budget middleware.
+type BudgetConfig struct {
+	Tracker *budget.Tracker
+
+	// Owners are contact IDs ("telegram:12345") whose messages are still
+	// answered after a budget is used up.
+	Owners []string
+
+	// Sender delivers the paused reply.
+	Sender Sender
+
+	// Reply is sent to other senders while a budget is used up. An empty
+	// reply drops the message silently.
+	Reply string
+}
+
+// Budget pauses traffic from everyone but the owners while a global or
+// channel budget is exceeded.
+func Budget(config BudgetConfig) Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			if config.Tracker.Check(msg.ProviderName).Status != budget.StatusExceeded ||
+				slices.Contains(config.Owners, msg.ProviderName+":"+msg.SenderID) {
+				return next(ctx, msg)
+			}
+			if config.Reply == "" || config.Sender == nil {
+				return nil
+			}
+			return config.Sender.Send(ctx, msg.ProviderName, msg.ChatID, provider.OutgoingMessage{
+				Content: config.Reply,
+				ReplyTo: msg.ID,
+			})
+		}
+	}
+}
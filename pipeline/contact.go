@@ -0,0 +1,25 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Contact tags the context with the sender's contact ID so tools can keep
+// per-person state across chats, and with the sender's display name.
+func Contact() Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			if msg.SenderID != "" {
+				ctx = agent.WithContact(ctx, msg.ProviderName+":"+msg.SenderID)
+			}
+			if msg.SenderName != "" {
+				ctx = agent.WithSenderName(ctx, msg.SenderName)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
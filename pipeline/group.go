@@ -0,0 +1,26 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Group tags group chat messages with the sender's name, so the agent can
+// tell the people in a shared session apart.
+func Group() Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			if msg.ChatType != provider.ChatTypeDM && msg.ChatType != "" {
+				speaker := msg.SenderName
+				if speaker == "" {
+					speaker = msg.SenderID
+				}
+				ctx = agent.WithSpeaker(ctx, speaker)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
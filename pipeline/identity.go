@@ -0,0 +1,23 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/identity"
+)
+
+// Identity tags the context with the person behind the sender's contact,
+// so per-person state follows them across channels.
+func Identity(people *identity.Directory) Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			if p, ok := people.Resolve(msg.ProviderName + ":" + msg.SenderID); ok {
+				ctx = agent.WithPerson(ctx, p.ID)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Metadata passes the channel's message metadata to the agent, which
+// stores it on the session as context variables.
+func Metadata() Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			if len(msg.Metadata) > 0 {
+				ctx = agent.WithMetadata(ctx, msg.Metadata)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/contacts"
+)
+
+// OnboardingConfig configures the onboarding middleware.
+type OnboardingConfig struct {
+	Contacts *contacts.Store
+
+	// Sender delivers the onboarding message.
+	Sender Sender
+
+	// Message is sent before the first reply to a new contact. It may
+	// contain {name} and {capabilities}. Empty registers contacts without
+	// sending anything.
+	Message string
+
+	// Capabilities renders the {capabilities} placeholder.
+	Capabilities func() string
+
+	Logger *slog.Logger
+}
+
+// Onboarding registers senders in the contact store and greets new
+// contacts with the onboarding message before their first reply.
+func Onboarding(config OnboardingConfig) Middleware {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			sender := msg.SenderID
+			if sender == "" {
+				sender = msg.ChatID
+			}
+
+			contact, isNew, err := config.Contacts.Touch(msg.ProviderName, sender, msg.SenderName)
+			if err != nil {
+				config.Logger.Warn("failed to save contact", "contact", contact.ID, "error", err)
+			}
+
+			if isNew && config.Message != "" && config.Sender != nil {
+				config.Logger.Info("onboarding new contact", "contact", contact.ID)
+				text := formatOnboarding(config.Message, contact, config.Capabilities)
+				if err := config.Sender.Send(ctx, msg.ProviderName, msg.ChatID, provider.OutgoingMessage{Content: text}); err != nil {
+					config.Logger.Warn("failed to send onboarding message", "contact", contact.ID, "error", err)
+				}
+			}
+
+			return next(ctx, msg)
+		}
+	}
+}
+
+// formatOnboarding fills in onboarding message placeholders.
+func formatOnboarding(template string, c contacts.Contact, capabilities func() string) string {
+	name := c.Name
+	if name == "" {
+		name = "there"
+	}
+	caps := ""
+	if capabilities != nil && strings.Contains(template, "{capabilities}") {
+		caps = capabilities()
+	}
+	return strings.NewReplacer("{name}", name, "{capabilities}", caps).Replace(template)
+}
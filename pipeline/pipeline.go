@@ -0,0 +1,28 @@
+// Package pipeline provides middleware for channel message handling.
+package pipeline
+
+import (
+	"context"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+// Middleware wraps a message handler to add behavior before or after it.
+type Middleware func(next provider.MessageHandler) provider.MessageHandler
+
+// Chain wraps h with the given middleware. The first middleware is the
+// outermost and sees each message first.
+func Chain(h provider.MessageHandler, mws ...Middleware) provider.MessageHandler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Sender sends messages to channels. *provider.Router implements Sender.
+type Sender interface {
+	Send(ctx context.Context, providerName, chatID string, msg provider.OutgoingMessage) error
+}
+
+// Ensure Router implements Sender.
+var _ Sender = (*provider.Router)(nil)
@@ -0,0 +1,318 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/budget"
+	"github.com/plexusone/omniagent/contacts"
+)
+
+type fakeSender struct {
+	sent []provider.OutgoingMessage
+	mu   sync.Mutex
+}
+
+func (s *fakeSender) Send(_ context.Context, _, _ string, msg provider.OutgoingMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next provider.MessageHandler) provider.MessageHandler {
+			return func(ctx context.Context, msg provider.IncomingMessage) error {
+				order = append(order, name)
+				return next(ctx, msg)
+			}
+		}
+	}
+	h := Chain(func(context.Context, provider.IncomingMessage) error {
+		order = append(order, "handler")
+		return nil
+	}, mw("a"), mw("b"))
+
+	if err := h(context.Background(), provider.IncomingMessage{}); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+	if got := strings.Join(order, ","); got != "a,b,handler" {
+		t.Errorf("order = %s, want a,b,handler", got)
+	}
+}
+
+func TestOnboardingGreetsNewContactsOnce(t *testing.T) {
+	store, err := contacts.Open("")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	sender := &fakeSender{}
+	handled := 0
+	h := Chain(func(context.Context, provider.IncomingMessage) error {
+		handled++
+		return nil
+	}, Onboarding(OnboardingConfig{
+		Contacts:     store,
+		Sender:       sender,
+		Message:      "Hi {name}! {capabilities}",
+		Capabilities: func() string { return "Tools: time" },
+	}))
+
+	msg := provider.IncomingMessage{ProviderName: "telegram", ChatID: "100", SenderID: "42", SenderName: "Ada", Content: "hello"}
+	for i := 0; i < 2; i++ {
+		if err := h(context.Background(), msg); err != nil {
+			t.Fatalf("handler error = %v", err)
+		}
+	}
+
+	if handled != 2 {
+		t.Errorf("handled = %d, want 2", handled)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Content != "Hi Ada! Tools: time" {
+		t.Errorf("sent = %+v, want one greeting", sender.sent)
+	}
+	if _, ok := store.Get("telegram:42"); !ok {
+		t.Error("contact not registered")
+	}
+}
+
+func TestBudgetPausesNonOwners(t *testing.T) {
+	tracker, err := budget.New(budget.Config{Global: budget.Policy{Daily: budget.Limit{Tokens: 10}}})
+	if err != nil {
+		t.Fatalf("budget.New() error = %v", err)
+	}
+	sender := &fakeSender{}
+	handled := 0
+	h := Chain(func(context.Context, provider.IncomingMessage) error {
+		handled++
+		return nil
+	}, Budget(BudgetConfig{
+		Tracker: tracker,
+		Owners:  []string{"telegram:1"},
+		Sender:  sender,
+		Reply:   "paused",
+	}))
+
+	owner := provider.IncomingMessage{ProviderName: "telegram", ChatID: "1", SenderID: "1"}
+	other := provider.IncomingMessage{ProviderName: "telegram", ChatID: "2", SenderID: "2"}
+
+	_ = h(context.Background(), other)
+	tracker.Charge("telegram", "m", 10, 0)
+	_ = h(context.Background(), other)
+	_ = h(context.Background(), owner)
+
+	if handled != 2 {
+		t.Errorf("handled = %d, want 2 (before cap, then owner)", handled)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Content != "paused" {
+		t.Errorf("sent = %+v, want one paused reply", sender.sent)
+	}
+}
+
+func TestProgressBatchesToolOutput(t *testing.T) {
+	sender := &fakeSender{}
+	mw := Progress(ProgressConfig{Sender: sender, Interval: 20 * time.Millisecond, MaxLength: 8})
+
+	// A quick tool sends no updates
+	quick := Chain(func(ctx context.Context, _ provider.IncomingMessage) error {
+		agent.ReportProgress(ctx, "done")
+		return nil
+	}, mw)
+	if err := quick(context.Background(), provider.IncomingMessage{ProviderName: "telegram", ChatID: "1"}); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d updates for a quick tool", len(sender.sent))
+	}
+
+	slow := Chain(func(ctx context.Context, _ provider.IncomingMessage) error {
+		agent.ReportProgress(ctx, "line one\n")
+		agent.ReportProgress(ctx, "line two\n")
+		time.Sleep(70 * time.Millisecond)
+		return nil
+	}, mw)
+	if err := slow(context.Background(), provider.IncomingMessage{ID: "m1", ProviderName: "telegram", ChatID: "1"}); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d updates, want 1: %+v", len(sender.sent), sender.sent)
+	}
+	if got := sender.sent[0]; got.Content != "Working:\n…line two" || got.ReplyTo != "m1" {
+		t.Errorf("update = %+v", got)
+	}
+}
+
+func TestAttachmentPolicies(t *testing.T) {
+	sender := &fakeSender{}
+	var got []provider.IncomingMessage
+	h := Chain(func(_ context.Context, msg provider.IncomingMessage) error {
+		got = append(got, msg)
+		return nil
+	}, AttachmentPolicies(AttachmentPolicyConfig{
+		Default: AttachmentPolicy{MaxBytes: 4, MimeTypes: []string{"image/*", "audio/ogg"}},
+		Channels: map[string]AttachmentPolicy{
+			"sms": {OnViolation: ViolationIgnore},
+		},
+		Sender: sender,
+	}))
+
+	ctx := context.Background()
+	pdf := provider.Media{Type: provider.MediaTypeDocument, Filename: "a.pdf", MimeType: "application/pdf", Data: []byte("x")}
+	voice := provider.Media{Type: provider.MediaTypeVoice, MimeType: "audio/ogg; codecs=opus", Data: []byte("xxxxxx")}
+	image := provider.Media{Type: provider.MediaTypeImage, MimeType: "image/png", Data: []byte("xx")}
+
+	// Rejected: reply and drop the message before any handler runs
+	_ = h(ctx, provider.IncomingMessage{ProviderName: "telegram", Content: "read this", Media: []provider.Media{image, pdf}})
+	_ = h(ctx, provider.IncomingMessage{ProviderName: "telegram", Media: []provider.Media{voice}})
+	if len(got) != 0 {
+		t.Fatalf("handled %d rejected messages", len(got))
+	}
+	if len(sender.sent) != 2 {
+		t.Fatalf("sent %d replies, want 2", len(sender.sent))
+	}
+	if want := "Sorry, I can't accept a.pdf: application/pdf files aren't accepted here."; sender.sent[0].Content != want {
+		t.Errorf("reply = %q, want %q", sender.sent[0].Content, want)
+	}
+	if want := "Sorry, I can't accept this voice note: it is larger than 4 bytes."; sender.sent[1].Content != want {
+		t.Errorf("reply = %q, want %q", sender.sent[1].Content, want)
+	}
+
+	// Ignored: drop the attachment, keep the text
+	_ = h(ctx, provider.IncomingMessage{ProviderName: "sms", Content: "hi", Media: []provider.Media{image, pdf}})
+	_ = h(ctx, provider.IncomingMessage{ProviderName: "sms", Media: []provider.Media{voice}})
+	if len(sender.sent) != 2 {
+		t.Errorf("sent %d replies for ignored attachments", len(sender.sent)-2)
+	}
+	if len(got) != 1 || len(got[0].Media) != 1 || got[0].Media[0].MimeType != "image/png" {
+		t.Errorf("handled = %+v, want the text with only the image", got)
+	}
+}
+
+func TestAttachmentsSkippedOnError(t *testing.T) {
+	sender := &fakeSender{}
+	fail := errors.New("model unavailable")
+	var handlerErr error
+	h := Chain(func(ctx context.Context, _ provider.IncomingMessage) error {
+		agent.AttachmentsFromContext(ctx).Attach(agent.Attachment{Filename: "report.pdf", MimeType: "application/pdf", Data: []byte("x")})
+		return handlerErr
+	}, Attachments(sender, nil))
+
+	ctx := context.Background()
+	msg := provider.IncomingMessage{ProviderName: "telegram", ChatID: "1", Content: "report please"}
+
+	handlerErr = fail
+	if err := h(ctx, msg); !errors.Is(err, fail) {
+		t.Fatalf("err = %v, want handler error", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d attachments of a failed reply", len(sender.sent))
+	}
+
+	handlerErr = nil
+	if err := h(ctx, msg); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Media[0].Filename != "report.pdf" || sender.sent[0].Media[0].Type != provider.MediaTypeDocument {
+		t.Errorf("sent = %+v, want report.pdf as a document", sender.sent)
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	mw, err := Triggers(TriggerConfig{Channels: map[string]Trigger{
+		"discord": {Mentions: []string{"@omni"}, Prefixes: []string{"!ai"}, Patterns: []string{`(?i)\bweather\b`}},
+	}})
+	if err != nil {
+		t.Fatalf("Triggers() error = %v", err)
+	}
+	var got []string
+	h := Chain(func(_ context.Context, msg provider.IncomingMessage) error {
+		got = append(got, msg.Content)
+		return nil
+	}, mw)
+
+	for _, msg := range []provider.IncomingMessage{
+		{ProviderName: "discord", ChatType: provider.ChatTypeGroup, Content: "just chatting"},
+		{ProviderName: "discord", ChatType: provider.ChatTypeGroup, Content: "!aim for the stars"},
+		{ProviderName: "discord", ChatType: provider.ChatTypeGroup, Content: "email me@omni.dev"},
+		{ProviderName: "discord", ChatType: provider.ChatTypeGroup, Content: "!AI what time is it"},
+		{ProviderName: "discord", ChatType: provider.ChatTypeGroup, Content: "hey @Omni, hello"},
+		{ProviderName: "discord", ChatType: provider.ChatTypeGroup, Content: "Weather tomorrow?"},
+		{ProviderName: "discord", ChatType: provider.ChatTypeGroup, Content: "/catchup"},
+		{ProviderName: "discord", ChatType: provider.ChatTypeDM, Content: "hi"},
+		{ProviderName: "telegram", ChatType: provider.ChatTypeGroup, Content: "hi all"},
+	} {
+		if err := h(context.Background(), msg); err != nil {
+			t.Fatalf("handler error = %v", err)
+		}
+	}
+	want := "what time is it|hey @Omni, hello|Weather tomorrow?|/catchup|hi|hi all"
+	if s := strings.Join(got, "|"); s != want {
+		t.Errorf("handled = %q, want %q", s, want)
+	}
+
+	if _, err := Triggers(TriggerConfig{Channels: map[string]Trigger{"sms": {Patterns: []string{"("}}}}); err == nil {
+		t.Error("Triggers() accepted an invalid pattern")
+	}
+}
+
+func TestReactions(t *testing.T) {
+	ratings := map[string]int{}
+	var elaborated []provider.IncomingMessage
+	events := Reactions(ReactionConfig{
+		Channels: map[string]map[string]string{"discord": {"❓": ReactionNone}},
+		Feedback: func(_ context.Context, sessionID string, rating int) error {
+			ratings[sessionID] += rating
+			return nil
+		},
+		Elaborate: func(sessionID string) (string, bool) {
+			return "explain " + sessionID, sessionID != "telegram:empty"
+		},
+		Handler: func(_ context.Context, msg provider.IncomingMessage) error {
+			elaborated = append(elaborated, msg)
+			return nil
+		},
+	})
+	react := func(channel, chatID, emoji string, removed bool) {
+		t.Helper()
+		err := events(context.Background(), provider.Event{
+			Type:         provider.EventTypeReaction,
+			ProviderName: channel,
+			ChatID:       chatID,
+			Data:         map[string]any{"emoji": emoji, "user_id": "7", "removed": removed},
+		})
+		if err != nil {
+			t.Fatalf("reaction error = %v", err)
+		}
+	}
+
+	react("telegram", "1", "👍", false)
+	react("telegram", "1", "👎", false)
+	react("telegram", "1", "👎", false)
+	react("telegram", "1", "👍", true)
+	react("telegram", "1", "🎉", false)
+	if ratings["telegram:1"] != -1 {
+		t.Errorf("rating = %d, want -1", ratings["telegram:1"])
+	}
+
+	react("telegram", "1", "❓", false)
+	react("telegram", "empty", "❓", false)
+	react("discord", "2", "❓", false)
+	if len(elaborated) != 1 {
+		t.Fatalf("elaborated %d times, want 1", len(elaborated))
+	}
+	if msg := elaborated[0]; msg.Content != "explain telegram:1" || msg.SenderID != "7" || msg.ProviderName != "telegram" {
+		t.Errorf("elaboration message = %+v", msg)
+	}
+}
@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Defaults for progress updates.
+const (
+	defaultProgressInterval  = 10 * time.Second
+	defaultProgressMaxLength = 500
+)
+
+// ProgressConfig configures the progress middleware.
+type ProgressConfig struct {
+	// Sender delivers progress updates.
+	Sender Sender
+
+	// Interval is the minimum time between updates, and before the first
+	// one, so quick tools send nothing (default: 10s).
+	Interval time.Duration
+
+	// MaxLength caps the output in each update; the most recent output is
+	// kept (default: 500).
+	MaxLength int
+
+	Logger *slog.Logger
+}
+
+// Progress streams intermediate output from long-running tools back to
+// the chat. Output is batched into at most one update per interval.
+func Progress(config ProgressConfig) Middleware {
+	if config.Interval <= 0 {
+		config.Interval = defaultProgressInterval
+	}
+	if config.MaxLength <= 0 {
+		config.MaxLength = defaultProgressMaxLength
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			p := &progress{}
+			done := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ticker := time.NewTicker(config.Interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+					}
+					text := p.flush(config.MaxLength)
+					if text == "" {
+						continue
+					}
+					if err := config.Sender.Send(ctx, msg.ProviderName, msg.ChatID, provider.OutgoingMessage{
+						Content: text,
+						ReplyTo: msg.ID,
+					}); err != nil {
+						config.Logger.Warn("failed to send progress", "channel", msg.ProviderName, "error", err)
+					}
+				}
+			}()
+
+			err := next(agent.WithProgress(ctx, p.add), msg)
+			close(done)
+			wg.Wait()
+			return err
+		}
+	}
+}
+
+// progress buffers tool output between updates.
+type progress struct {
+	tool string
+	buf  strings.Builder
+	mu   sync.Mutex
+}
+
+// add records output from a tool. Output from a new tool replaces
+// buffered output from the previous one.
+func (p *progress) add(tool, text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if tool != p.tool {
+		p.tool = tool
+		p.buf.Reset()
+	}
+	p.buf.WriteString(text)
+}
+
+// flush returns an update with the buffered output, or "" if there is
+// none.
+func (p *progress) flush(maxLen int) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	text := strings.TrimSpace(p.buf.String())
+	p.buf.Reset()
+	if text == "" {
+		return ""
+	}
+	if len(text) > maxLen {
+		// Keep the tail, cut at a rune boundary
+		cut := len(text) - maxLen
+		for cut < len(text) && !utf8.RuneStart(text[cut]) {
+			cut++
+		}
+		text = "…" + text[cut:]
+	}
+
+	label := "Working"
+	if p.tool != "" {
+		label += " (" + p.tool + ")"
+	}
+	return label + ":\n" + text
+}
@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/identity"
+	"github.com/plexusone/omniagent/ratelimit"
+)
+
+// RateLimitConfig configures the rate limit middleware.
+type RateLimitConfig struct {
+	Limiter *ratelimit.Limiter
+
+	// Sender delivers over-limit replies.
+	Sender Sender
+
+	// MessageReply and TokenReply are sent when the message or token limit
+	// is exceeded. They may contain {retry_after}, {limit}, and {scope}.
+	// An empty reply drops the message silently.
+	MessageReply string
+	TokenReply   string
+}
+
+// RateLimit rejects messages over the configured limits and charges model
+// tokens used by accepted messages.
+func RateLimit(config RateLimitConfig) Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			user := msg.SenderID
+			if user == "" {
+				user = msg.ChatID
+			}
+			if person := agent.PersonFromContext(ctx); person != "" {
+				user = identity.Prefix + person
+			}
+
+			d := config.Limiter.Allow(msg.ProviderName, user)
+			if !d.Allowed {
+				reply := config.MessageReply
+				if d.Reason == ratelimit.ReasonTokens {
+					reply = config.TokenReply
+				}
+				if reply == "" || config.Sender == nil {
+					return nil
+				}
+				return config.Sender.Send(ctx, msg.ProviderName, msg.ChatID, provider.OutgoingMessage{
+					Content: ratelimit.FormatReply(reply, d),
+					ReplyTo: msg.ID,
+				})
+			}
+
+			ctx = agent.WithUsageFunc(ctx, func(promptTokens, completionTokens int) {
+				config.Limiter.AddTokens(msg.ProviderName, user, promptTokens+completionTokens)
+			})
+			return next(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/eval"
+)
+
+// Reaction actions.
+const (
+	ReactionUp        = "up"        // Rate the last reply as good
+	ReactionDown      = "down"      // Rate the last reply as bad
+	ReactionElaborate = "elaborate" // Ask the agent to expand on the last reply
+	ReactionNone      = "none"      // Ignore the emoji
+)
+
+// DefaultReactions maps emoji to actions.
+var DefaultReactions = map[string]string{
+	"👍":  ReactionUp,
+	"+1": ReactionUp,
+	"❤️": ReactionUp,
+	"❤":  ReactionUp,
+	"👎":  ReactionDown,
+	"-1": ReactionDown,
+	"❓":  ReactionElaborate,
+	"❔":  ReactionElaborate,
+	"🤔":  ReactionElaborate,
+}
+
+// ReactionConfig configures the reaction event handler.
+type ReactionConfig struct {
+	// Emoji maps emoji to actions (default: DefaultReactions). Channels
+	// overrides it per channel name; ReactionNone turns an emoji off.
+	Emoji    map[string]string
+	Channels map[string]map[string]string
+
+	// Feedback rates the session's last reply with eval.RatingUp or
+	// eval.RatingDown.
+	Feedback func(ctx context.Context, sessionID string, rating int) error
+
+	// Elaborate returns a message asking for more detail on the session's
+	// last reply, or false if there is none.
+	Elaborate func(sessionID string) (string, bool)
+
+	// Handler processes elaboration requests like any incoming message,
+	// so the reply is sent to the chat.
+	Handler provider.MessageHandler
+
+	Logger *slog.Logger
+}
+
+// Reactions turns emoji reactions on the agent's replies into signals:
+// thumbs rate the last reply and question marks ask the agent to explain
+// it. Providers report the emoji as "emoji" or "reaction" in the event
+// data, and may add "user_id", "message_id", "chat_type", and "removed".
+func Reactions(config ReactionConfig) provider.EventHandler {
+	if config.Emoji == nil {
+		config.Emoji = DefaultReactions
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return func(ctx context.Context, event provider.Event) error {
+		if event.Type != provider.EventTypeReaction {
+			return nil
+		}
+		if removed, _ := event.Data["removed"].(bool); removed {
+			return nil
+		}
+		emoji := eventString(event, "emoji")
+		if emoji == "" {
+			emoji = eventString(event, "reaction")
+		}
+		sessionID := event.ProviderName + ":" + event.ChatID
+
+		switch action := config.action(event.ProviderName, emoji); action {
+		case ReactionUp, ReactionDown:
+			if config.Feedback == nil {
+				return nil
+			}
+			rating := eval.RatingUp
+			if action == ReactionDown {
+				rating = eval.RatingDown
+			}
+			if err := config.Feedback(ctx, sessionID, rating); err != nil {
+				config.Logger.Debug("reaction feedback not recorded", "session", sessionID, "error", err)
+			}
+		case ReactionElaborate:
+			if config.Elaborate == nil || config.Handler == nil {
+				return nil
+			}
+			prompt, ok := config.Elaborate(sessionID)
+			if !ok {
+				return nil
+			}
+			config.Logger.Info("elaborating on reaction", "session", sessionID, "emoji", emoji)
+			timestamp := event.Timestamp
+			if timestamp.IsZero() {
+				timestamp = time.Now()
+			}
+			return config.Handler(ctx, provider.IncomingMessage{
+				ID:           eventString(event, "message_id"),
+				ProviderName: event.ProviderName,
+				ChatID:       event.ChatID,
+				ChatType:     provider.ChatType(eventString(event, "chat_type")),
+				SenderID:     eventString(event, "user_id"),
+				Content:      prompt,
+				Timestamp:    timestamp,
+			})
+		}
+		return nil
+	}
+}
+
+// action returns the action for an emoji on a channel.
+func (c ReactionConfig) action(channel, emoji string) string {
+	if emoji == "" {
+		return ReactionNone
+	}
+	if action, ok := c.Channels[channel][emoji]; ok {
+		return action
+	}
+	if action, ok := c.Emoji[emoji]; ok {
+		return action
+	}
+	return ReactionNone
+}
+
+// eventString returns a string from an event's data.
+func eventString(event provider.Event, key string) string {
+	s, _ := event.Data[key].(string)
+	return s
+}
@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+// Trigger decides which group chat messages the agent answers. A message
+// triggers the agent when it mentions one of Mentions, starts with one of
+// Prefixes, or matches one of Patterns. A trigger with none of these
+// answers every message.
+type Trigger struct {
+	// Mentions are names that address the agent, such as "@omniagent" or
+	// a Discord "<@id>" mention, matched as whole words ignoring case.
+	Mentions []string
+
+	// Prefixes, such as "!ai", are removed before the agent sees the
+	// message.
+	Prefixes []string
+
+	// Patterns are regular expressions matched against the message.
+	Patterns []string
+}
+
+// TriggerConfig configures the trigger middleware.
+type TriggerConfig struct {
+	Channels map[string]Trigger // By channel name
+}
+
+// compiledTrigger is a Trigger with compiled expressions.
+type compiledTrigger struct {
+	mentions []*regexp.Regexp
+	prefixes []string
+	patterns []*regexp.Regexp
+}
+
+// Triggers drops group chat messages that don't address the agent on
+// channels with a trigger. Direct messages and slash commands always pass.
+func Triggers(config TriggerConfig) (Middleware, error) {
+	triggers := make(map[string]compiledTrigger, len(config.Channels))
+	for channel, t := range config.Channels {
+		ct, err := compileTrigger(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s trigger: %w", channel, err)
+		}
+		if ct.empty() {
+			continue
+		}
+		triggers[channel] = ct
+	}
+
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) error {
+			t, ok := triggers[msg.ProviderName]
+			if !ok || msg.ChatType == provider.ChatTypeDM || msg.ChatType == "" ||
+				strings.HasPrefix(msg.Content, "/") {
+				return next(ctx, msg)
+			}
+			content, ok := t.match(msg.Content)
+			if !ok {
+				return nil
+			}
+			msg.Content = content
+			return next(ctx, msg)
+		}
+	}, nil
+}
+
+// compileTrigger compiles a trigger's mentions and patterns.
+func compileTrigger(t Trigger) (compiledTrigger, error) {
+	var ct compiledTrigger
+	for _, m := range t.Mentions {
+		if m = strings.TrimSpace(m); m == "" {
+			continue
+		}
+		ct.mentions = append(ct.mentions, regexp.MustCompile(`(?i)(^|[^\w@])`+regexp.QuoteMeta(m)+`($|[^\w])`))
+	}
+	for _, p := range t.Prefixes {
+		if p = strings.TrimSpace(p); p != "" {
+			ct.prefixes = append(ct.prefixes, p)
+		}
+	}
+	for _, p := range t.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return ct, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		ct.patterns = append(ct.patterns, re)
+	}
+	return ct, nil
+}
+
+// empty reports whether the trigger answers every message.
+func (t compiledTrigger) empty() bool {
+	return len(t.mentions) == 0 && len(t.prefixes) == 0 && len(t.patterns) == 0
+}
+
+// match reports whether content triggers the agent, returning it without
+// any prefix.
+func (t compiledTrigger) match(content string) (string, bool) {
+	trimmed := strings.TrimSpace(content)
+	for _, p := range t.prefixes {
+		if len(trimmed) < len(p) || !strings.EqualFold(trimmed[:len(p)], p) {
+			continue
+		}
+		rest := trimmed[len(p):]
+		if r, _ := utf8.DecodeRuneInString(rest); rest != "" && isWordRune(r) {
+			last, _ := utf8.DecodeLastRuneInString(p)
+			if isWordRune(last) {
+				continue // "!ai" doesn't trigger on "!aim"
+			}
+		}
+		if rest = strings.TrimSpace(rest); rest == "" {
+			return content, true
+		}
+		return rest, true
+	}
+	for _, re := range t.mentions {
+		if re.MatchString(content) {
+			return content, true
+		}
+	}
+	for _, re := range t.patterns {
+		if re.MatchString(content) {
+			return content, true
+		}
+	}
+	return "", false
+}
+
+// isWordRune reports whether r is part of a word.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,183 @@
+// Package privacy enforces local-only processing: every service that
+// sees conversation content must run on this machine or its private
+// network.
+package privacy
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/config"
+)
+
+// Mode is the privacy mode reported by the gateway when local-only
+// processing is enforced.
+const Mode = "local-only"
+
+// IsLocalIP reports whether ip is a loopback, private (RFC 1918 or IPv6
+// unique local), or link-local address.
+func IsLocalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast()
+}
+
+// IsLocalHost reports whether every address host resolves to is local.
+// "localhost" and names under it are local without a lookup.
+func IsLocalHost(ctx context.Context, host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return IsLocalIP(ip)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return false
+	}
+	for _, a := range addrs {
+		if !IsLocalIP(a.IP) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsLocalURL reports whether a URL's host is local.
+func IsLocalURL(ctx context.Context, raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	return IsLocalHost(ctx, u.Hostname())
+}
+
+// DialControl refuses connections to addresses that are not local. Use it
+// as a net.Dialer's Control so redirects and DNS changes cannot reach
+// other hosts.
+func DialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsLocalIP(ip) {
+		return fmt.Errorf("privacy mode: address %s is not local", host)
+	}
+	return nil
+}
+
+// Check returns what in the configuration would send conversation content
+// off this machine. Local-only mode refuses to start unless it is empty.
+func Check(ctx context.Context, cfg *config.Config) []string {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	// remote checks a service's URL, where "" means its cloud default
+	remote := func(raw string) bool { return raw == "" || !IsLocalURL(ctx, raw) }
+
+	checkModel := func(name, provider, baseURL string) {
+		if !agent.IsLocalProvider(provider) {
+			fail("%s: provider %q is not local; use ollama or openai-compatible", name, provider)
+			return
+		}
+		if baseURL == "" {
+			baseURL = agent.DefaultOllamaBaseURL
+			if provider == agent.ProviderOpenAICompatible {
+				baseURL = agent.DefaultOpenAICompatibleBaseURL
+			}
+		}
+		if !IsLocalURL(ctx, baseURL) {
+			fail("%s: base_url %s is not a local address", name, baseURL)
+		}
+	}
+	checkModel("agent", cfg.Agent.Provider, cfg.Agent.BaseURL)
+	for _, p := range cfg.Agents {
+		provider, baseURL := p.Provider, p.BaseURL
+		if provider == "" {
+			provider, baseURL = cfg.Agent.Provider, cfg.Agent.BaseURL
+		}
+		checkModel("agents."+p.Name, provider, baseURL)
+	}
+
+	if cfg.Voice.Enabled {
+		checkSTT := func(name string, stt config.STTConfig) {
+			if stt.Provider != "whisper-cpp" || !IsLocalURL(ctx, cmp.Or(stt.BaseURL, "http://127.0.0.1")) {
+				fail("%s: provider %q is a cloud service; use whisper-cpp", name, stt.Provider)
+			}
+		}
+		checkSTT("voice.stt", cfg.Voice.STT)
+		for i, stt := range cfg.Voice.STTFallbacks {
+			checkSTT(fmt.Sprintf("voice.stt_fallbacks[%d]", i), stt)
+		}
+		fail("voice.tts: provider %q is a cloud service; disable voice", cfg.Voice.TTS.Provider)
+	}
+	if cfg.Memory.Enabled && remote(cfg.Memory.Embedding.BaseURL) {
+		fail("memory: embedding.base_url must be a local embeddings server")
+	}
+	if cfg.Knowledge.Enabled {
+		embedding := cfg.Knowledge.Embedding
+		if embedding == (config.EmbeddingConfig{}) {
+			embedding = cfg.Memory.Embedding
+		}
+		if remote(embedding.BaseURL) {
+			fail("knowledge: embedding.base_url must be a local embeddings server")
+		}
+	}
+	for _, src := range cfg.Knowledge.Sources {
+		fail("knowledge.sources.%s: %s is a cloud service", src.Name, src.Type)
+	}
+	if cfg.Attachments.Read && cfg.Attachments.Vision.Model != "" && remote(cfg.Attachments.Vision.BaseURL) {
+		fail("attachments.vision: base_url must be a local vision model server")
+	}
+
+	if cfg.Tools.Browser.Enabled {
+		fail("tools.browser: browsing sends requests to the internet; disable it")
+	}
+	if cfg.Tools.Images.Enabled && remote(cfg.Tools.Images.BaseURL) {
+		fail("tools.images: base_url must be a local image generation server")
+	}
+	if cfg.Tools.WebDAV.Enabled && remote(cfg.Tools.WebDAV.URL) {
+		fail("tools.webdav: url %s is not a local address", cfg.Tools.WebDAV.URL)
+	}
+	for _, s := range cfg.MCPServers {
+		if s.URL != "" && !IsLocalURL(ctx, s.URL) {
+			fail("mcp_servers.%s: url %s is not a local address", s.Name, s.URL)
+		}
+	}
+
+	if cfg.Observability.Enabled && cfg.Observability.Provider != "" && cfg.Observability.Provider != "slog" {
+		fail("observability: provider %q exports traces; use slog", cfg.Observability.Provider)
+	}
+	if cfg.Storage.Backend == "s3" && remote(cfg.Storage.S3.Endpoint) {
+		fail("storage.s3: endpoint must be a local S3-compatible server")
+	}
+
+	allowed := make(map[string]bool)
+	for _, c := range cfg.Privacy.AllowedChannels {
+		allowed[c] = true
+	}
+	for _, ch := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"telegram", cfg.Channels.Telegram.Enabled},
+		{"discord", cfg.Channels.Discord.Enabled},
+		{"whatsapp", cfg.Channels.WhatsApp.Enabled},
+		{"signal", cfg.Channels.Signal.Enabled},
+		{"sms", cfg.Channels.SMS.Enabled},
+	} {
+		if ch.enabled && !allowed[ch.name] {
+			fail("channels.%s: messages pass through the %s service; disable it or list it in privacy.allowed_channels", ch.name, ch.name)
+		}
+	}
+	return problems
+}
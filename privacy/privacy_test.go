@@ -0,0 +1,70 @@
+package privacy
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omniagent/config"
+)
+
+func TestIsLocalURL(t *testing.T) {
+	ctx := context.Background()
+	for raw, want := range map[string]bool{
+		"http://localhost:11434":          true,
+		"http://ollama.localhost/v1":      true,
+		"http://127.0.0.1:8080/v1":        true,
+		"http://192.168.1.20:11434":       true,
+		"http://10.0.0.5/":                true,
+		"http://[fd00::1]:8080/":          true,
+		"https://8.8.8.8/":                false,
+		"http://172.32.0.1/":              false,
+		"https://api.openai.com.invalid/": false,
+		"not a url":                       false,
+	} {
+		if got := IsLocalURL(ctx, raw); got != want {
+			t.Errorf("IsLocalURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+
+	if err := DialControl("tcp", "127.0.0.1:80", nil); err != nil {
+		t.Errorf("DialControl(loopback) = %v", err)
+	}
+	if err := DialControl("tcp", net.JoinHostPort("1.1.1.1", "443"), nil); err == nil {
+		t.Error("DialControl(public) succeeded")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Memory.Enabled = false
+	cfg.Knowledge.Enabled = false
+	cfg.Tools.Browser.Enabled = false
+	cfg.Agent.Provider = "ollama"
+	cfg.Agent.BaseURL = ""
+	cfg.Voice.Enabled = false
+	if problems := Check(ctx, &cfg); len(problems) != 0 {
+		t.Fatalf("Check(local config) = %v", problems)
+	}
+
+	cfg.Agent.Provider = "anthropic"
+	cfg.Voice.Enabled = true
+	cfg.Voice.STT.Provider = "whisper-cpp"
+	cfg.Tools.Browser.Enabled = true
+	cfg.Channels.Telegram.Enabled = true
+	cfg.Channels.Signal.Enabled = true
+	cfg.Privacy.AllowedChannels = []string{"signal"}
+	cfg.Agents = []config.PersonaConfig{{Name: "coder", Provider: "openai-compatible", BaseURL: "http://10.1.2.3:8000/v1"}}
+	problems := Check(ctx, &cfg)
+	got := strings.Join(problems, "\n")
+	for _, want := range []string{"agent: provider", "voice.tts:", "tools.browser:", "channels.telegram:"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Check() missing %q in:\n%s", want, got)
+		}
+	}
+	if len(problems) != 4 {
+		t.Errorf("Check() = %d problems, want 4:\n%s", len(problems), got)
+	}
+}
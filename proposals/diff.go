@@ -0,0 +1,124 @@
+package proposals
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// edit is one line of a line diff.
+type edit struct {
+	op   byte   // ' ', '-', or '+'
+	text string // Line without its newline
+	a, b int    // Index of the line in the old and new text
+}
+
+// Diff returns a unified diff from before to after, labelled with name, or
+// "" when they are equal.
+func Diff(name, before, after string) string {
+	if before == after {
+		return ""
+	}
+	edits := diffLines(splitLines(before), splitLines(after))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", name, name)
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		// Grow the hunk while the next change is close enough that
+		// their context would overlap
+		start, end := max(0, k-diffContext), k
+		for next := k + 1; next < len(edits) && next <= end+2*diffContext; next++ {
+			if edits[next].op != ' ' {
+				end = next
+			}
+		}
+		stop := min(len(edits), end+diffContext+1)
+		writeHunk(&sb, edits[start:stop])
+		k = stop
+	}
+	return sb.String()
+}
+
+// writeHunk writes one @@ hunk.
+func writeHunk(sb *strings.Builder, hunk []edit) {
+	var oldCount, newCount int
+	for _, e := range hunk {
+		if e.op != '+' {
+			oldCount++
+		}
+		if e.op != '-' {
+			newCount++
+		}
+	}
+	// An empty range is numbered by the line before it
+	oldStart, newStart := hunk[0].a, hunk[0].b
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, e := range hunk {
+		sb.WriteByte(e.op)
+		sb.WriteString(e.text)
+		sb.WriteByte('\n')
+	}
+}
+
+// diffLines returns the edits turning a into b, using a longest common
+// subsequence so unchanged lines are kept together.
+func diffLines(a, b []string) []edit {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{op: ' ', text: a[i], a: i, b: j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{op: '-', text: a[i], a: i, b: j})
+			i++
+		default:
+			edits = append(edits, edit{op: '+', text: b[j], a: i, b: j})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{op: '-', text: a[i], a: i, b: j})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{op: '+', text: b[j], a: i, b: j})
+	}
+	return edits
+}
+
+// splitLines splits text into lines without their newlines.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
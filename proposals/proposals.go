@@ -0,0 +1,434 @@
+// Package proposals reviews changes the agent proposes to its own config
+// and skills. Each change is diffed, validated in a scratch directory,
+// approved by an owner, and written atomically, with the previous version
+// kept so it can be rolled back.
+package proposals
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omniagent/approvals"
+	"github.com/plexusone/omniagent/config"
+	"github.com/plexusone/omniagent/skills"
+)
+
+// Targets of a proposal.
+const (
+	TargetConfig = "config"
+	TargetSkill  = "skill"
+)
+
+var (
+	// ErrDenied is returned when the owner does not approve a change.
+	ErrDenied = errors.New("change was not approved")
+
+	// ErrNotFound is returned when rolling back an unknown change.
+	ErrNotFound = errors.New("change not found")
+)
+
+var skillNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Proposal is a change the agent wants to make.
+type Proposal struct {
+	Target    string // TargetConfig or TargetSkill
+	Name      string // Skill name; unused for config
+	Content   string // The complete new file
+	Reason    string // Why, shown to the approver
+	SessionID string // Session that proposed it
+}
+
+// Change is an applied proposal.
+type Change struct {
+	ID         string     `json:"id"`
+	Target     string     `json:"target"`
+	Name       string     `json:"name,omitempty"`
+	Path       string     `json:"path"`
+	Reason     string     `json:"reason,omitempty"`
+	SessionID  string     `json:"session_id,omitempty"`
+	Approver   string     `json:"approver"`
+	Diff       string     `json:"diff"`
+	Hash       string     `json:"hash"`              // SHA-256 of the written content
+	Created    bool       `json:"created,omitempty"` // The file did not exist before
+	Applied    time.Time  `json:"applied"`
+	RolledBack *time.Time `json:"rolled_back,omitempty"`
+}
+
+// Config configures a Manager.
+type Config struct {
+	// Dir holds the change log and the previous version of each changed
+	// file.
+	Dir string
+
+	// ConfigPath is the config file the agent may change; empty allows
+	// no config changes.
+	ConfigPath string
+
+	// SkillsDir is where skills are written, one directory per skill;
+	// empty allows no skill changes.
+	SkillsDir string
+
+	// Approvals asks owners to approve each change. Proposals fail
+	// without it.
+	Approvals *approvals.Manager
+
+	// OnApply is called after a change is applied or rolled back.
+	OnApply func(Change)
+
+	Logger *slog.Logger
+}
+
+// Manager reviews and applies proposals.
+type Manager struct {
+	config Config
+	logger *slog.Logger
+	mu     sync.Mutex // Serializes writes to changed files and the log
+}
+
+// logEntry is one line of the change log.
+type logEntry struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"` // "applied" or "rolled_back"
+	Change Change    `json:"change"`
+}
+
+// New creates a Manager.
+func New(config Config) (*Manager, error) {
+	if config.Dir == "" {
+		return nil, errors.New("proposals directory is required")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create proposals directory: %w", err)
+	}
+	return &Manager{config: config, logger: config.Logger}, nil
+}
+
+// Propose validates p, asks an owner to approve its diff, and applies it.
+// It blocks until the owner decides, returning ErrDenied unless they
+// approve.
+func (m *Manager) Propose(ctx context.Context, p Proposal) (Change, error) {
+	path, err := m.path(p)
+	if err != nil {
+		return Change{}, err
+	}
+	before, existed, err := readFile(path)
+	if err != nil {
+		return Change{}, err
+	}
+	name := filepath.Base(path)
+	if p.Target == TargetSkill {
+		name = filepath.Join(p.Name, name)
+	}
+	diff := Diff(name, before, p.Content)
+	if diff == "" {
+		return Change{}, errors.New("the proposed content matches the current file")
+	}
+	if err := validate(p, path); err != nil {
+		return Change{}, err
+	}
+
+	if m.config.Approvals == nil {
+		return Change{}, errors.New("owner approval is not configured")
+	}
+	args, _ := json.Marshal(map[string]string{"target": p.Target, "name": p.Name, "reason": p.Reason})
+	decision, err := m.config.Approvals.Await(ctx, approvals.Request{
+		Tool:      "propose_change",
+		Args:      args,
+		SessionID: p.SessionID,
+		Details:   diff,
+	})
+	if err != nil {
+		return Change{}, fmt.Errorf("await approval: %w", err)
+	}
+	if !decision.Approved {
+		return Change{}, ErrDenied
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The owner reviewed a diff against what was there; refuse if the
+	// file changed while they did
+	current, _, err := readFile(path)
+	if err != nil {
+		return Change{}, err
+	}
+	if current != before {
+		return Change{}, fmt.Errorf("%s changed while awaiting approval; propose the change again", path)
+	}
+
+	c := Change{
+		ID:        newID(),
+		Target:    p.Target,
+		Name:      p.Name,
+		Path:      path,
+		Reason:    p.Reason,
+		SessionID: p.SessionID,
+		Approver:  decision.Approver,
+		Diff:      diff,
+		Hash:      hash(p.Content),
+		Created:   !existed,
+		Applied:   time.Now(),
+	}
+	if existed {
+		if err := os.WriteFile(m.backupPath(c.ID), []byte(before), 0o600); err != nil {
+			return Change{}, fmt.Errorf("back up %s: %w", path, err)
+		}
+	}
+	if err := writeAtomic(path, p.Content); err != nil {
+		return Change{}, err
+	}
+	if err := m.appendLog("applied", c); err != nil {
+		return Change{}, err
+	}
+	m.logger.Warn("proposed change applied", "id", c.ID, "target", c.Target, "path", c.Path, "approver", c.Approver)
+	if m.config.OnApply != nil {
+		m.config.OnApply(c)
+	}
+	return c, nil
+}
+
+// History returns applied changes, newest first.
+func (m *Manager) History() ([]Change, error) {
+	f, err := os.Open(m.logPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open change log: %w", err)
+	}
+	defer f.Close()
+
+	var changes []Change
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("parse change log: %w", err)
+		}
+		switch e.Event {
+		case "applied":
+			index[e.Change.ID] = len(changes)
+			changes = append(changes, e.Change)
+		case "rolled_back":
+			if i, ok := index[e.Change.ID]; ok {
+				changes[i].RolledBack = &e.Time
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read change log: %w", err)
+	}
+	slices.Reverse(changes)
+	return changes, nil
+}
+
+// Rollback restores the file a change replaced, or removes it if the
+// change created it. Only the latest change to a file can be rolled back,
+// so later changes are never lost silently.
+func (m *Manager) Rollback(id string) (Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history, err := m.History()
+	if err != nil {
+		return Change{}, err
+	}
+	i := slices.IndexFunc(history, func(c Change) bool { return c.ID == id })
+	if i < 0 {
+		return Change{}, ErrNotFound
+	}
+	c := history[i]
+	if c.RolledBack != nil {
+		return Change{}, fmt.Errorf("change %s was already rolled back", id)
+	}
+	current, existed, err := readFile(c.Path)
+	if err != nil {
+		return Change{}, err
+	}
+	if !existed || hash(current) != c.Hash {
+		return Change{}, fmt.Errorf("%s changed after change %s; roll back later changes first", c.Path, id)
+	}
+
+	if c.Created {
+		if err := os.Remove(c.Path); err != nil {
+			return Change{}, fmt.Errorf("remove %s: %w", c.Path, err)
+		}
+		if c.Target == TargetSkill {
+			// Drop the skill directory if the change left it empty
+			_ = os.Remove(filepath.Dir(c.Path))
+		}
+	} else {
+		previous, err := os.ReadFile(m.backupPath(id))
+		if err != nil {
+			return Change{}, fmt.Errorf("read backup: %w", err)
+		}
+		if err := writeAtomic(c.Path, string(previous)); err != nil {
+			return Change{}, err
+		}
+	}
+	if err := m.appendLog("rolled_back", c); err != nil {
+		return Change{}, err
+	}
+	now := time.Now()
+	c.RolledBack = &now
+	m.logger.Warn("change rolled back", "id", c.ID, "path", c.Path)
+	if m.config.OnApply != nil {
+		m.config.OnApply(c)
+	}
+	return c, nil
+}
+
+// path returns the file a proposal changes.
+func (m *Manager) path(p Proposal) (string, error) {
+	switch p.Target {
+	case TargetConfig:
+		if m.config.ConfigPath == "" {
+			return "", errors.New("config changes are not allowed")
+		}
+		return m.config.ConfigPath, nil
+	case TargetSkill:
+		if m.config.SkillsDir == "" {
+			return "", errors.New("skill changes are not allowed")
+		}
+		if !skillNamePattern.MatchString(p.Name) {
+			return "", fmt.Errorf("invalid skill name %q: use lowercase letters, digits, and hyphens", p.Name)
+		}
+		return filepath.Join(m.config.SkillsDir, p.Name, "SKILL.md"), nil
+	default:
+		return "", fmt.Errorf("unknown target %q", p.Target)
+	}
+}
+
+// validate checks the proposed content in a scratch directory, the way it
+// will be loaded once applied, without touching the real file.
+func validate(p Proposal, path string) error {
+	dir, err := os.MkdirTemp("", "omniagent-proposal-")
+	if err != nil {
+		return fmt.Errorf("create scratch directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	switch p.Target {
+	case TargetConfig:
+		if err := config.Check([]byte(p.Content), filepath.Ext(path)); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+		scratch := filepath.Join(dir, filepath.Base(path))
+		if err := os.WriteFile(scratch, []byte(p.Content), 0o600); err != nil {
+			return fmt.Errorf("write scratch config: %w", err)
+		}
+		if _, err := config.Load(scratch); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	case TargetSkill:
+		skillDir := filepath.Join(dir, p.Name)
+		if err := os.Mkdir(skillDir, 0o700); err != nil {
+			return fmt.Errorf("create scratch skill: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte(p.Content), 0o600); err != nil {
+			return fmt.Errorf("write scratch skill: %w", err)
+		}
+		skill, err := skills.Load(skillDir)
+		if err != nil {
+			return fmt.Errorf("invalid skill: %w", err)
+		}
+		problems := skills.Lint(skill)
+		if skill.Name != "" && skill.Name != p.Name {
+			problems = append(problems, fmt.Sprintf("name %q does not match the skill directory %q", skill.Name, p.Name))
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("invalid skill: %s", strings.Join(problems, "; "))
+		}
+	}
+	return nil
+}
+
+// appendLog appends an event to the change log.
+func (m *Manager) appendLog(event string, c Change) error {
+	line, err := json.Marshal(logEntry{Time: time.Now(), Event: event, Change: c})
+	if err != nil {
+		return fmt.Errorf("encode change log entry: %w", err)
+	}
+	f, err := os.OpenFile(m.logPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open change log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write change log: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) logPath() string {
+	return filepath.Join(m.config.Dir, "changes.jsonl")
+}
+
+func (m *Manager) backupPath(id string) string {
+	return filepath.Join(m.config.Dir, id+".orig")
+}
+
+// readFile returns a file's content and whether it exists.
+func readFile(path string) (string, bool, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("read %s: %w", path, err)
+	}
+	return string(data), true, nil
+}
+
+// writeAtomic replaces path with content through a temporary file in the
+// same directory, keeping the file's permissions.
+func writeAtomic(path, content string) error {
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), mode); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+func hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// newID returns a short random change ID that is easy to type.
+func newID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,186 @@
+package proposals
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omniagent/approvals"
+)
+
+func TestDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	after := "a\nb\nC\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- a/config.yaml
++++ b/config.yaml
+@@ -1,6 +1,6 @@
+ a
+ b
+-c
++C
+ d
+ e
+ f
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := Diff("config.yaml", before, after); got != want {
+		t.Errorf("Diff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := Diff("x", "same\n", "same\n"); got != "" {
+		t.Errorf("Diff() of equal text = %q, want empty", got)
+	}
+	created := Diff("SKILL.md", "", "one\ntwo\n")
+	if !strings.Contains(created, "@@ -0,0 +1,2 @@\n+one\n+two\n") {
+		t.Errorf("Diff() of new file = %q", created)
+	}
+}
+
+// newTestManager returns a manager whose owner answers every request with
+// approve, recording the requests made.
+func newTestManager(t *testing.T, approve bool) (*Manager, *[]approvals.Request) {
+	t.Helper()
+	dir := t.TempDir()
+	owner, err := approvals.New(approvals.Config{Approvers: []string{"telegram:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests []approvals.Request
+	owner.OnRequest(func(ctx context.Context, req approvals.Request) error {
+		requests = append(requests, req)
+		_, err := owner.Resolve(req.ID, approve, "telegram:1")
+		return err
+	})
+	m, err := New(Config{
+		Dir:        filepath.Join(dir, "proposals"),
+		ConfigPath: filepath.Join(dir, "omniagent.yaml"),
+		SkillsDir:  filepath.Join(dir, "skills"),
+		Approvals:  owner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, &requests
+}
+
+func TestProposeConfig(t *testing.T) {
+	m, requests := newTestManager(t, true)
+	original := "agent:\n  model: gpt-4\n"
+	if err := os.WriteFile(m.config.ConfigPath, []byte(original), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Invalid config is rejected before anyone is asked
+	_, err := m.Propose(ctx, Proposal{Target: TargetConfig, Content: "agent:\n  modle: gpt-5\n"})
+	if err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Fatalf("Propose(unknown field) error = %v, want invalid config", err)
+	}
+	if len(*requests) != 0 {
+		t.Fatalf("approval requested for invalid config")
+	}
+
+	updated := "agent:\n  model: gpt-5\n"
+	c, err := m.Propose(ctx, Proposal{Target: TargetConfig, Content: updated, Reason: "newer model", SessionID: "telegram:1"})
+	if err != nil {
+		t.Fatalf("Propose() error = %v", err)
+	}
+	if len(*requests) != 1 || !strings.Contains((*requests)[0].Details, "+  model: gpt-5") {
+		t.Fatalf("approval requests = %+v, want one with the diff", *requests)
+	}
+	data, _ := os.ReadFile(m.config.ConfigPath)
+	if string(data) != updated {
+		t.Errorf("config = %q, want %q", data, updated)
+	}
+	if info, _ := os.Stat(m.config.ConfigPath); info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	history, err := m.History()
+	if err != nil || len(history) != 1 || history[0].ID != c.ID || history[0].Approver != "telegram:1" {
+		t.Fatalf("History() = %+v, %v", history, err)
+	}
+
+	if _, err := m.Rollback(c.ID); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	data, _ = os.ReadFile(m.config.ConfigPath)
+	if string(data) != original {
+		t.Errorf("config after rollback = %q, want %q", data, original)
+	}
+	if _, err := m.Rollback(c.ID); err == nil {
+		t.Error("second Rollback() succeeded")
+	}
+	if _, err := m.Rollback("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Rollback(missing) error = %v, want ErrNotFound", err)
+	}
+	history, _ = m.History()
+	if history[0].RolledBack == nil {
+		t.Error("history does not record the rollback")
+	}
+}
+
+func TestProposeSkill(t *testing.T) {
+	m, _ := newTestManager(t, true)
+	ctx := context.Background()
+	skill := "---\nname: daily-notes\ndescription: Keep daily notes\n---\n\nWrite notes to notes/.\n"
+
+	_, err := m.Propose(ctx, Proposal{Target: TargetSkill, Name: "other-name", Content: skill})
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Propose(mismatched name) error = %v", err)
+	}
+	_, err = m.Propose(ctx, Proposal{Target: TargetSkill, Name: "../escape", Content: skill})
+	if err == nil || !strings.Contains(err.Error(), "invalid skill name") {
+		t.Errorf("Propose(bad name) error = %v", err)
+	}
+	_, err = m.Propose(ctx, Proposal{Target: TargetSkill, Name: "daily-notes", Content: "---\nname: daily-notes\n---\n"})
+	if err == nil || !strings.Contains(err.Error(), "description is required") {
+		t.Errorf("Propose(lint failure) error = %v", err)
+	}
+
+	first, err := m.Propose(ctx, Proposal{Target: TargetSkill, Name: "daily-notes", Content: skill})
+	if err != nil {
+		t.Fatalf("Propose() error = %v", err)
+	}
+	if !first.Created {
+		t.Error("Created = false for a new skill")
+	}
+	second, err := m.Propose(ctx, Proposal{Target: TargetSkill, Name: "daily-notes", Content: skill + "Date each entry.\n"})
+	if err != nil {
+		t.Fatalf("Propose(edit) error = %v", err)
+	}
+
+	// Only the latest change to a file can be rolled back
+	if _, err := m.Rollback(first.ID); err == nil {
+		t.Error("Rollback() of an overwritten change succeeded")
+	}
+	if _, err := m.Rollback(second.ID); err != nil {
+		t.Fatalf("Rollback(second) error = %v", err)
+	}
+	if _, err := m.Rollback(first.ID); err != nil {
+		t.Fatalf("Rollback(first) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(m.config.SkillsDir, "daily-notes")); !os.IsNotExist(err) {
+		t.Errorf("skill directory remains after rolling back its creation: %v", err)
+	}
+}
+
+func TestProposeDenied(t *testing.T) {
+	m, _ := newTestManager(t, false)
+	_, err := m.Propose(context.Background(), Proposal{Target: TargetConfig, Content: "agent:\n  model: gpt-5\n"})
+	if !errors.Is(err, ErrDenied) {
+		t.Fatalf("Propose() error = %v, want ErrDenied", err)
+	}
+	if _, err := os.Stat(m.config.ConfigPath); !os.IsNotExist(err) {
+		t.Error("denied change was written")
+	}
+	if history, _ := m.History(); len(history) != 0 {
+		t.Errorf("History() = %+v, want empty", history)
+	}
+}
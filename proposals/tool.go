@@ -0,0 +1,91 @@
+package proposals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Tool lets the agent propose changes to its own config and skills.
+type Tool struct {
+	manager *Manager
+}
+
+// NewTool creates the propose_change tool.
+func NewTool(manager *Manager) *Tool {
+	return &Tool{manager: manager}
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "propose_change"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return "Propose a change to your own config file or to a skill, for example to remember a better way " +
+		"of doing a task. The change is validated, then sent to the owner as a diff and applied only " +
+		"if they approve, which can take minutes. Give the complete new file, not just the changed part."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"target": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{TargetConfig, TargetSkill},
+				"description": "What to change",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Skill name in kebab-case, for skill changes",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "The complete new config file, or SKILL.md with frontmatter",
+			},
+			"reason": map[string]interface{}{
+				"type":        "string",
+				"description": "Why the change helps, shown to the owner",
+			},
+		},
+		"required": []string{"target", "content", "reason"},
+	}
+}
+
+// Execute submits the proposal and waits for the owner's decision.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Target  string `json:"target"`
+		Name    string `json:"name"`
+		Content string `json:"content"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+
+	c, err := t.manager.Propose(ctx, Proposal{
+		Target:    params.Target,
+		Name:      params.Name,
+		Content:   params.Content,
+		Reason:    params.Reason,
+		SessionID: agent.SessionFromContext(ctx),
+	})
+	if errors.Is(err, ErrDenied) {
+		return "The owner did not approve the change; nothing was changed.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if c.Target == TargetSkill {
+		return fmt.Sprintf("Change %s approved and written to %s. The skill is used after the next restart.", c.ID, c.Path), nil
+	}
+	return fmt.Sprintf("Change %s approved and written to %s. Feature flags and access apply now; "+
+		"other settings after the next restart.", c.ID, c.Path), nil
+}
@@ -0,0 +1,244 @@
+// Package ratelimit enforces message and token limits per user and per channel.
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits are the allowed rates for one user or channel. Zero disables a limit.
+type Limits struct {
+	MessagesPerMinute int
+	TokensPerHour     int
+}
+
+// Config configures a Limiter.
+type Config struct {
+	// User limits apply to each end user (sender) separately.
+	User Limits
+
+	// Channel limits apply to each channel as a whole.
+	Channel Limits
+
+	// Channels overrides Channel and User limits for specific channels.
+	Channels map[string]ChannelLimits
+}
+
+// ChannelLimits overrides limits for one channel.
+type ChannelLimits struct {
+	User    *Limits
+	Channel *Limits
+}
+
+// Reason identifies which limit was exceeded.
+type Reason string
+
+const (
+	ReasonNone     Reason = ""
+	ReasonMessages Reason = "messages"
+	ReasonTokens   Reason = "tokens"
+)
+
+// Decision is the outcome of a limit check.
+type Decision struct {
+	Allowed    bool
+	Reason     Reason
+	Scope      string // "user" or "channel"
+	RetryAfter time.Duration
+}
+
+// sweepInterval is how often windows left empty, such as those of users
+// who stopped writing, are dropped.
+const sweepInterval = time.Minute
+
+// Limiter tracks usage in sliding windows.
+type Limiter struct {
+	config    Config
+	windows   map[string]*window
+	lastSweep time.Time
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+// New creates a Limiter.
+func New(config Config) *Limiter {
+	return &Limiter{
+		config:  config,
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}
+}
+
+// Allow checks the user and channel limits and, if allowed, counts the
+// message. user should identify the sender uniquely within the channel.
+func (l *Limiter) Allow(channel, user string) Decision {
+	userLimits, channelLimits := l.limitsFor(channel)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	checks := []struct {
+		scope  string
+		key    string
+		limits Limits
+	}{
+		{"user", userKey(channel, user), userLimits},
+		{"channel", channelKey(channel), channelLimits},
+	}
+
+	for _, c := range checks {
+		if c.limits.MessagesPerMinute > 0 {
+			if n, oldest := l.window(c.key+":messages").sum(now, time.Minute); n >= c.limits.MessagesPerMinute {
+				return Decision{Reason: ReasonMessages, Scope: c.scope, RetryAfter: oldest.Add(time.Minute).Sub(now)}
+			}
+		}
+		if c.limits.TokensPerHour > 0 {
+			if n, oldest := l.window(c.key+":tokens").sum(now, time.Hour); n >= c.limits.TokensPerHour {
+				return Decision{Reason: ReasonTokens, Scope: c.scope, RetryAfter: oldest.Add(time.Hour).Sub(now)}
+			}
+		}
+	}
+
+	// Disabled limits record nothing
+	for _, c := range checks {
+		if c.limits.MessagesPerMinute > 0 {
+			l.window(c.key+":messages").add(now, 1)
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// AddTokens charges model tokens used for a message to the user and channel.
+func (l *Limiter) AddTokens(channel, user string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	userLimits, channelLimits := l.limitsFor(channel)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if userLimits.TokensPerHour > 0 {
+		l.window(userKey(channel, user)+":tokens").add(now, tokens)
+	}
+	if channelLimits.TokensPerHour > 0 {
+		l.window(channelKey(channel)+":tokens").add(now, tokens)
+	}
+}
+
+// sweep prunes every window and drops the empty ones, at most once per
+// sweepInterval. Callers must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, w := range l.windows {
+		span := time.Minute
+		if strings.HasSuffix(key, ":tokens") {
+			span = time.Hour
+		}
+		if n, _ := w.sum(now, span); n == 0 {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// limitsFor returns the effective user and channel limits for a channel.
+func (l *Limiter) limitsFor(channel string) (Limits, Limits) {
+	user, ch := l.config.User, l.config.Channel
+	if o, ok := l.config.Channels[channel]; ok {
+		if o.User != nil {
+			user = *o.User
+		}
+		if o.Channel != nil {
+			ch = *o.Channel
+		}
+	}
+	return user, ch
+}
+
+// window returns the usage window for key. Callers must hold the lock.
+func (l *Limiter) window(key string) *window {
+	w, ok := l.windows[key]
+	if !ok {
+		w = &window{}
+		l.windows[key] = w
+	}
+	return w
+}
+
+// userKey keys a user's windows. A person linked across channels
+// ("person:alice") has one set of windows on every channel.
+func userKey(channel, user string) string {
+	if strings.HasPrefix(user, "person:") {
+		return "user:" + user
+	}
+	return "user:" + channel + ":" + user
+}
+
+func channelKey(channel string) string { return "channel:" + channel }
+
+// window is a sliding log of weighted events.
+type window struct {
+	events []event
+}
+
+type event struct {
+	at time.Time
+	n  int
+}
+
+func (w *window) add(at time.Time, n int) {
+	w.events = append(w.events, event{at: at, n: n})
+}
+
+// sum prunes events older than span and returns the total and the time of
+// the oldest remaining event.
+func (w *window) sum(now time.Time, span time.Duration) (int, time.Time) {
+	cutoff := now.Add(-span)
+	i := 0
+	for i < len(w.events) && !w.events[i].at.After(cutoff) {
+		i++
+	}
+	w.events = w.events[i:]
+
+	total := 0
+	for _, e := range w.events {
+		total += e.n
+	}
+	if len(w.events) == 0 {
+		return 0, now
+	}
+	return total, w.events[0].at
+}
+
+// FormatReply fills the {retry_after}, {limit}, and {scope} placeholders
+// in an over-limit reply template.
+func FormatReply(template string, d Decision) string {
+	retry := d.RetryAfter.Round(time.Second)
+	if retry < time.Second {
+		retry = time.Second
+	}
+	limit := "message"
+	if d.Reason == ReasonTokens {
+		limit = "usage"
+	}
+	return strings.NewReplacer(
+		"{retry_after}", retry.String(),
+		"{limit}", limit,
+		"{scope}", d.Scope,
+	).Replace(template)
+}
+
+// Error returns an error describing a denied decision.
+func (d Decision) Error() error {
+	if d.Allowed {
+		return nil
+	}
+	return fmt.Errorf("rate limit exceeded: %s %s limit, retry after %s", d.Scope, d.Reason, d.RetryAfter.Round(time.Second))
+}
@@ -0,0 +1,260 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterMessages(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(Config{
+		User:    Limits{MessagesPerMinute: 2},
+		Channel: Limits{MessagesPerMinute: 3},
+	})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if d := l.Allow("telegram", "alice"); !d.Allowed {
+			t.Fatalf("message %d denied: %+v", i, d)
+		}
+	}
+	d := l.Allow("telegram", "alice")
+	if d.Allowed || d.Reason != ReasonMessages || d.Scope != "user" {
+		t.Errorf("third message = %+v, want user messages limit", d)
+	}
+	if d.RetryAfter != time.Minute {
+		t.Errorf("RetryAfter = %v, want 1m", d.RetryAfter)
+	}
+
+	// Another user shares the channel limit
+	if d := l.Allow("telegram", "bob"); !d.Allowed {
+		t.Errorf("bob denied: %+v", d)
+	}
+	if d := l.Allow("telegram", "carol"); d.Allowed || d.Scope != "channel" {
+		t.Errorf("carol = %+v, want channel limit", d)
+	}
+
+	// Other channels are independent
+	if d := l.Allow("discord", "alice"); !d.Allowed {
+		t.Errorf("discord denied: %+v", d)
+	}
+
+	// Window slides
+	now = now.Add(61 * time.Second)
+	if d := l.Allow("telegram", "alice"); !d.Allowed {
+		t.Errorf("after window denied: %+v", d)
+	}
+}
+
+func TestLimiterEviction(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(Config{User: Limits{MessagesPerMinute: 5}})
+	l.now = func() time.Time { return now }
+
+	for _, user := range []string{"alice", "bob", "carol"} {
+		l.Allow("telegram", user)
+		l.AddTokens("telegram", user, 100) // No token limit, nothing recorded
+	}
+	if len(l.windows) != 3 {
+		t.Errorf("windows = %d, want 3 user message windows", len(l.windows))
+	}
+
+	now = now.Add(2 * time.Minute)
+	l.Allow("telegram", "dave")
+	if len(l.windows) != 1 {
+		t.Errorf("windows after sweep = %d, want only dave's", len(l.windows))
+	}
+}
+
+func TestLimiterPeople(t *testing.T) {
+	l := New(Config{User: Limits{MessagesPerMinute: 2}})
+	if d := l.Allow("telegram", "person:alice"); !d.Allowed {
+		t.Fatalf("telegram denied: %+v", d)
+	}
+	if d := l.Allow("whatsapp", "person:alice"); !d.Allowed {
+		t.Fatalf("whatsapp denied: %+v", d)
+	}
+	// A linked person's limit spans channels
+	if d := l.Allow("discord", "person:alice"); d.Allowed || d.Scope != "user" {
+		t.Errorf("discord = %+v, want user limit", d)
+	}
+}
+
+func TestLimiterTokens(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(Config{User: Limits{TokensPerHour: 1000}})
+	l.now = func() time.Time { return now }
+
+	if d := l.Allow("whatsapp", "alice"); !d.Allowed {
+		t.Fatalf("first message denied: %+v", d)
+	}
+	l.AddTokens("whatsapp", "alice", 1200)
+
+	now = now.Add(10 * time.Minute)
+	d := l.Allow("whatsapp", "alice")
+	if d.Allowed || d.Reason != ReasonTokens {
+		t.Errorf("over budget = %+v, want tokens limit", d)
+	}
+	if d.RetryAfter != 50*time.Minute {
+		t.Errorf("RetryAfter = %v, want 50m", d.RetryAfter)
+	}
+}
+
+func TestChannelOverrides(t *testing.T) {
+	l := New(Config{
+		User:     Limits{MessagesPerMinute: 1},
+		Channels: map[string]ChannelLimits{"discord": {User: &Limits{MessagesPerMinute: 5}}},
+	})
+	for i := 0; i < 5; i++ {
+		if d := l.Allow("discord", "alice"); !d.Allowed {
+			t.Fatalf("discord message %d denied", i)
+		}
+	}
+	l.Allow("telegram", "alice")
+	if d := l.Allow("telegram", "alice"); d.Allowed {
+		t.Error("telegram should use the default limit")
+	}
+}
+
+func TestFormatReply(t *testing.T) {
+	got := FormatReply("Slow down, try again in {retry_after}.", Decision{Reason: ReasonMessages, RetryAfter: 1500 * time.Millisecond})
+	if !strings.Contains(got, "2s") {
+		t.Errorf("FormatReply() = %q", got)
+	}
+}
+
+// drain empties a provider's request bucket.
+func drain(t *testing.T, th *Throttle, provider string, n int) {
+	t.Helper()
+	for range n {
+		if err := th.Wait(context.Background(), provider, "warmup", 0); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+}
+
+// waitQueued waits until n requests are queued for the only provider.
+func waitQueued(t *testing.T, th *Throttle, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if stats := th.Stats(); len(stats) == 1 && stats[0].Queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%d requests not queued: %+v", n, th.Stats())
+}
+
+func TestThrottleFairness(t *testing.T) {
+	// 600 per minute refills one request every 100ms after a burst of 100
+	th := NewThrottle(ThrottleConfig{Providers: map[string]ProviderLimits{"openai": {RequestsPerMinute: 600}}})
+	drain(t, th, "openai", 100)
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	send := func(session string, queued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := th.Wait(context.Background(), "openai", session, 0); err != nil {
+				t.Errorf("Wait() error = %v", err)
+			}
+			mu.Lock()
+			order = append(order, session)
+			mu.Unlock()
+		}()
+		waitQueued(t, th, queued)
+	}
+	// A busy session queues first, then another session arrives
+	send("a", 1)
+	send("a", 2)
+	send("a", 3)
+	send("b", 4)
+	wg.Wait()
+
+	if want := []string{"a", "b", "a", "a"}; !slices.Equal(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if s := th.Stats()[0]; s.Requests != 104 || s.Delayed != 4 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestThrottleMaxWait(t *testing.T) {
+	th := NewThrottle(ThrottleConfig{
+		Providers: map[string]ProviderLimits{"anthropic": {RequestsPerMinute: 6}},
+		MaxWait:   20 * time.Millisecond,
+	})
+	drain(t, th, "anthropic", 1)
+
+	if err := th.Wait(context.Background(), "anthropic", "s", 0); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("Wait() error = %v, want ErrThrottled", err)
+	}
+	if s := th.Stats()[0]; s.Rejected != 1 || s.Queued != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestThrottleSetLimits(t *testing.T) {
+	// One request every 10s; the queued request would wait that long
+	th := NewThrottle(ThrottleConfig{Providers: map[string]ProviderLimits{"openai": {RequestsPerMinute: 6}}})
+	drain(t, th, "openai", 1)
+
+	done := make(chan error, 1)
+	go func() { done <- th.Wait(context.Background(), "openai", "s", 0) }()
+	waitQueued(t, th, 1)
+
+	th.SetLimits(ThrottleConfig{Providers: map[string]ProviderLimits{"openai": {RequestsPerMinute: 6000}}})
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued request not admitted at the new rate")
+	}
+}
+
+func TestThrottleTokens(t *testing.T) {
+	// A bucket of 1000 tokens refilled at 100 per second
+	th := NewThrottle(ThrottleConfig{
+		Providers: map[string]ProviderLimits{"anthropic": {TokensPerMinute: 6000}},
+		MaxWait:   50 * time.Millisecond,
+	})
+	ctx := context.Background()
+	if err := th.Wait(ctx, "anthropic", "s", 1000); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if err := th.Wait(ctx, "anthropic", "s", 500); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("Wait() error = %v, want ErrThrottled", err)
+	}
+
+	// The request used far less than estimated, so the rest is returned
+	th.Used("anthropic", 1000, 200)
+	if err := th.Wait(ctx, "anthropic", "s", 500); err != nil {
+		t.Errorf("Wait() after refund error = %v", err)
+	}
+}
+
+func TestThrottleRateLimited(t *testing.T) {
+	th := NewThrottle(ThrottleConfig{})
+	th.RateLimited("ollama", 30*time.Millisecond)
+
+	start := time.Now()
+	if err := th.Wait(context.Background(), "ollama", "s", 100); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if d := time.Since(start); d < 25*time.Millisecond {
+		t.Errorf("Wait() returned after %v, want pause", d)
+	}
+	if s := th.Stats()[0]; s.RateLimited != 1 || s.Delayed != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
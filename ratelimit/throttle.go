@@ -0,0 +1,349 @@
+package ratelimit
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrThrottled is returned when a model request queued longer than the
+// throttle allows.
+var ErrThrottled = errors.New("model provider is busy, please try again shortly")
+
+// Throttle defaults.
+const (
+	defaultMaxWait = 2 * time.Minute
+
+	// burstWindow sizes the buckets: a burst may use this much of a
+	// minute's allowance at once, and the rest is spread out.
+	burstWindow = 10 * time.Second
+)
+
+// ProviderLimits are a model provider's request and token rates. Zero
+// disables a limit.
+type ProviderLimits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// ThrottleConfig configures a Throttle.
+type ThrottleConfig struct {
+	// Providers maps provider names to their limits. Requests to other
+	// providers are only paused after the provider reports a rate limit.
+	Providers map[string]ProviderLimits
+
+	// MaxWait is the longest a request queues before failing with
+	// ErrThrottled (default: 2m).
+	MaxWait time.Duration
+}
+
+// ThrottleStats reports throttling of one provider.
+type ThrottleStats struct {
+	Provider    string        `json:"provider"`
+	Requests    int64         `json:"requests"`     // Requests admitted
+	Delayed     int64         `json:"delayed"`      // Admitted after queueing
+	Rejected    int64         `json:"rejected"`     // Gave up after MaxWait
+	RateLimited int64         `json:"rate_limited"` // Rate limit errors from the provider
+	Queued      int           `json:"queued"`       // Waiting now
+	Wait        time.Duration `json:"wait_ns"`      // Total time spent queued
+}
+
+// Throttle paces model requests to each provider's rate limits with token
+// buckets, shared by every channel and persona. Queued requests are
+// admitted round-robin across sessions, so one busy conversation cannot
+// starve the rest.
+type Throttle struct {
+	config ThrottleConfig
+	queues map[string]*queue
+	mu     sync.Mutex
+	now    func() time.Time
+}
+
+// NewThrottle creates a Throttle.
+func NewThrottle(config ThrottleConfig) *Throttle {
+	if config.MaxWait <= 0 {
+		config.MaxWait = defaultMaxWait
+	}
+	return &Throttle{
+		config: config,
+		queues: make(map[string]*queue),
+		now:    time.Now,
+	}
+}
+
+// Wait blocks until a request for session estimated to use tokens may be
+// sent to provider. It returns ErrThrottled after MaxWait, or ctx's error.
+// Report actual usage with Used once the request completes.
+func (t *Throttle) Wait(ctx context.Context, provider, session string, tokens int) error {
+	t.mu.Lock()
+	now := t.now()
+	q := t.queue(provider, now)
+	n := q.clamp(tokens)
+
+	// Admit immediately unless others are already queued
+	if len(q.sessions) == 0 && q.delay(n, now) == 0 {
+		q.take(n)
+		q.stats.Requests++
+		t.mu.Unlock()
+		return nil
+	}
+
+	w := &waiter{tokens: n, ready: make(chan struct{})}
+	if len(q.waiting[session]) == 0 {
+		q.sessions = append(q.sessions, session)
+	}
+	q.waiting[session] = append(q.waiting[session], w)
+	t.dispatch(q, now)
+	maxWait := t.config.MaxWait
+	t.mu.Unlock()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+		err = ErrThrottled
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	q.stats.Wait += t.now().Sub(now)
+	if w.admitted {
+		if err != nil {
+			// Admitted while giving up; return the unused allowance
+			q.refund(1, w.tokens)
+			t.dispatch(q, t.now())
+		}
+		return err
+	}
+	if errors.Is(err, ErrThrottled) {
+		q.stats.Rejected++
+	}
+	q.remove(session, w)
+	t.dispatch(q, t.now())
+	return err
+}
+
+// SetLimits replaces the provider limits, as on a config reload. Queued
+// requests keep their place and are admitted at the new rates.
+func (t *Throttle) SetLimits(config ThrottleConfig) {
+	if config.MaxWait <= 0 {
+		config.MaxWait = defaultMaxWait
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.config = config
+	now := t.now()
+	for name, q := range t.queues {
+		limits := config.Providers[name]
+		q.requests.resize(limits.RequestsPerMinute, now)
+		q.tokens.resize(limits.TokensPerMinute, now)
+		t.dispatch(q, now)
+	}
+}
+
+// Used reconciles a request's estimated tokens with its actual usage. A
+// failed request that used nothing passes 0.
+func (t *Throttle) Used(provider string, estimated, actual int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	q := t.queue(provider, now)
+	q.refund(0, q.clamp(estimated)-float64(actual))
+	t.dispatch(q, now)
+}
+
+// RateLimited pauses requests to provider for retryAfter after it rejected
+// a request for exceeding its rate limit.
+func (t *Throttle) RateLimited(provider string, retryAfter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	q := t.queue(provider, now)
+	q.stats.RateLimited++
+	if until := now.Add(retryAfter); until.After(q.pausedUntil) {
+		q.pausedUntil = until
+	}
+}
+
+// Stats returns throttling statistics by provider.
+func (t *Throttle) Stats() []ThrottleStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := make([]ThrottleStats, 0, len(t.queues))
+	for name, q := range t.queues {
+		s := q.stats
+		s.Provider = name
+		for _, ws := range q.waiting {
+			s.Queued += len(ws)
+		}
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b ThrottleStats) int { return cmp.Compare(a.Provider, b.Provider) })
+	return stats
+}
+
+// queue returns provider's queue. Callers must hold the lock.
+func (t *Throttle) queue(provider string, now time.Time) *queue {
+	q, ok := t.queues[provider]
+	if !ok {
+		limits := t.config.Providers[provider]
+		q = &queue{
+			requests: newBucket(limits.RequestsPerMinute, now),
+			tokens:   newBucket(limits.TokensPerMinute, now),
+			waiting:  make(map[string][]*waiter),
+		}
+		t.queues[provider] = q
+	}
+	return q
+}
+
+// dispatch admits queued requests while capacity allows, taking one from
+// each session in turn, and schedules itself for when the next one fits.
+// Callers must hold the lock.
+func (t *Throttle) dispatch(q *queue, now time.Time) {
+	for len(q.sessions) > 0 {
+		session := q.sessions[0]
+		w := q.waiting[session][0]
+		if d := q.delay(w.tokens, now); d > 0 {
+			if q.timer != nil {
+				q.timer.Stop()
+			}
+			q.timer = time.AfterFunc(max(d, time.Millisecond), func() {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				t.dispatch(q, t.now())
+			})
+			return
+		}
+
+		q.take(w.tokens)
+		q.stats.Requests++
+		q.stats.Delayed++
+		w.admitted = true
+		close(w.ready)
+
+		q.sessions = q.sessions[1:]
+		if rest := q.waiting[session][1:]; len(rest) > 0 {
+			q.waiting[session] = rest
+			q.sessions = append(q.sessions, session)
+		} else {
+			delete(q.waiting, session)
+		}
+	}
+}
+
+// queue is one provider's buckets and waiting requests.
+type queue struct {
+	requests    bucket
+	tokens      bucket
+	pausedUntil time.Time
+
+	// sessions is the admission order of sessions with waiting requests.
+	sessions []string
+	waiting  map[string][]*waiter
+	timer    *time.Timer
+	stats    ThrottleStats
+}
+
+type waiter struct {
+	tokens   float64
+	ready    chan struct{}
+	admitted bool
+}
+
+// clamp caps a token estimate at the bucket size, so a request larger than
+// a burst still runs once the bucket is full.
+func (q *queue) clamp(tokens int) float64 {
+	n := float64(max(tokens, 0))
+	if q.tokens.rate > 0 {
+		n = min(n, q.tokens.size)
+	}
+	return n
+}
+
+// delay returns how long until a request using n tokens fits.
+func (q *queue) delay(n float64, now time.Time) time.Duration {
+	q.requests.refill(now)
+	q.tokens.refill(now)
+	d := max(q.requests.wait(1), q.tokens.wait(n))
+	if q.pausedUntil.After(now) {
+		d = max(d, q.pausedUntil.Sub(now))
+	}
+	return d
+}
+
+func (q *queue) take(n float64) {
+	q.requests.add(-1)
+	q.tokens.add(-n)
+}
+
+func (q *queue) refund(requests, tokens float64) {
+	q.requests.add(requests)
+	q.tokens.add(tokens)
+}
+
+// remove drops a waiter that gave up.
+func (q *queue) remove(session string, w *waiter) {
+	ws := slices.DeleteFunc(q.waiting[session], func(x *waiter) bool { return x == w })
+	if len(ws) > 0 {
+		q.waiting[session] = ws
+		return
+	}
+	delete(q.waiting, session)
+	q.sessions = slices.DeleteFunc(q.sessions, func(s string) bool { return s == session })
+}
+
+// bucket is a token bucket refilled continuously. A zero rate is
+// unlimited. The level may go negative when usage exceeds an estimate.
+type bucket struct {
+	rate    float64 // Per minute
+	size    float64
+	level   float64
+	updated time.Time
+}
+
+func newBucket(perMinute int, now time.Time) bucket {
+	rate := float64(perMinute)
+	size := max(1, rate*burstWindow.Minutes())
+	return bucket{rate: rate, size: size, level: size, updated: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if b.rate == 0 {
+		return
+	}
+	b.level = min(b.size, b.level+now.Sub(b.updated).Minutes()*b.rate)
+	b.updated = now
+}
+
+// resize changes the bucket's rate, keeping its level within the new size.
+func (b *bucket) resize(perMinute int, now time.Time) {
+	b.refill(now)
+	next := newBucket(perMinute, now)
+	if b.rate > 0 {
+		next.level = min(b.level, next.size)
+	}
+	*b = next
+}
+
+// wait returns how long until n can be taken.
+func (b *bucket) wait(n float64) time.Duration {
+	if b.rate == 0 || b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.rate * float64(time.Minute))
+}
+
+func (b *bucket) add(n float64) {
+	if b.rate == 0 {
+		return
+	}
+	b.level = min(b.size, b.level+n)
+}
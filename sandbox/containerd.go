@@ -0,0 +1,213 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nerdctl is the Docker-compatible containerd CLI the containerd sandbox
+// drives.
+const nerdctl = "nerdctl"
+
+// nerdctlFailed is the exit code nerdctl, like docker, uses when it could
+// not run the container at all.
+const nerdctlFailed = 125
+
+// ContainerdSandbox provides containerd-based isolation for command
+// execution, for hosts without a Docker or Podman API. Containers are run
+// with nerdctl, in CONTAINERD_NAMESPACE or nerdctl's default namespace.
+type ContainerdSandbox struct {
+	config DockerConfig
+	host   *HostFunctions // App-level permission checks
+}
+
+// NewContainerdSandbox creates a new containerd sandbox.
+func NewContainerdSandbox(ctx context.Context, config DockerConfig, appConfig *Config) (*ContainerdSandbox, error) {
+	if _, err := exec.LookPath(nerdctl); err != nil {
+		return nil, fmt.Errorf("containerd sandbox needs %s: %w", nerdctl, err)
+	}
+	if out, err := exec.CommandContext(ctx, nerdctl, "info").CombinedOutput(); err != nil { //nolint:gosec // G204: Fixed command
+		return nil, fmt.Errorf("containerd not accessible: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	var host *HostFunctions
+	if appConfig != nil {
+		host = NewHostFunctions(*appConfig)
+	}
+	return &ContainerdSandbox{config: config, host: host}, nil
+}
+
+// Close releases nothing; each command's container is removed when it
+// exits.
+func (c *ContainerdSandbox) Close() error {
+	return nil
+}
+
+// EnsureImage pulls the configured image if not present.
+func (c *ContainerdSandbox) EnsureImage(ctx context.Context) error {
+	if exec.CommandContext(ctx, nerdctl, "image", "inspect", c.config.Image).Run() == nil { //nolint:gosec // G204: Image comes from operator configuration
+		return nil
+	}
+	if out, err := exec.CommandContext(ctx, nerdctl, "pull", "--quiet", c.config.Image).CombinedOutput(); err != nil { //nolint:gosec // G204: Image comes from operator configuration
+		return fmt.Errorf("pull image %s: %w: %s", c.config.Image, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Run executes a command inside a container.
+func (c *ContainerdSandbox) Run(ctx context.Context, command string, args []string) (*Result, error) {
+	return c.run(ctx, nil, command, args, nil, nil)
+}
+
+// RunStream executes a command inside a container, streaming its output.
+func (c *ContainerdSandbox) RunStream(ctx context.Context, command string, args []string, stdout, stderr io.Writer) (*Result, error) {
+	return c.run(ctx, nil, command, args, stdout, stderr)
+}
+
+// RunShell executes a shell command inside a container.
+func (c *ContainerdSandbox) RunShell(ctx context.Context, shellCommand string) (*Result, error) {
+	return c.Run(ctx, "sh", []string{"-c", shellCommand})
+}
+
+// RunShellStream executes a shell command inside a container, streaming
+// its output.
+func (c *ContainerdSandbox) RunShellStream(ctx context.Context, shellCommand string, stdout, stderr io.Writer) (*Result, error) {
+	return c.RunStream(ctx, "sh", []string{"-c", shellCommand}, stdout, stderr)
+}
+
+// RunWithStdin executes a command with stdin input.
+func (c *ContainerdSandbox) RunWithStdin(ctx context.Context, stdin []byte, command string, args []string) (*Result, error) {
+	return c.run(ctx, stdin, command, args, nil, nil)
+}
+
+func (c *ContainerdSandbox) run(ctx context.Context, stdin []byte, command string, args []string, stdoutStream, stderrStream io.Writer) (*Result, error) {
+	start := time.Now()
+
+	// Apply app-level permission checks if configured
+	if c.host != nil {
+		if err := c.host.validateCommand(command); err != nil {
+			return nil, err
+		}
+		for _, m := range c.config.Mounts {
+			if _, err := c.host.validatePath(m.HostPath); err != nil {
+				return nil, fmt.Errorf("mount validation failed: %w", err)
+			}
+		}
+	}
+
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	name, err := containerName()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, nerdctl, c.runArgs(name, stdin != nil, command, args)...) //nolint:gosec // G204: Running commands is the point of a sandbox
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	maxBytes := c.config.MaxOutputBytes
+	if maxBytes == 0 {
+		maxBytes = 1024 * 1024
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = teeWriter{buf: &limitedWriter{w: &stdout, max: maxBytes}, stream: stdoutStream}
+	cmd.Stderr = teeWriter{buf: &limitedWriter{w: &stderr, max: maxBytes}, stream: stderrStream}
+
+	err = cmd.Run()
+	exitCode := 0
+	var exitErr *exec.ExitError
+	switch {
+	case err != nil && ctx.Err() == context.DeadlineExceeded:
+		// Killing nerdctl leaves the container running
+		rmCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = exec.CommandContext(rmCtx, nerdctl, "rm", "--force", name).Run() //nolint:gosec // G204: Generated container name
+		return nil, NewTimeoutError(c.config.Timeout)
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == nerdctlFailed:
+		return nil, fmt.Errorf("run container: %s", strings.TrimSpace(stderr.String()))
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("run container: %w", err)
+	}
+
+	return &Result{
+		Output:   stdout.Bytes(),
+		Error:    stderr.Bytes(),
+		ExitCode: exitCode,
+		Duration: time.Since(start),
+	}, nil
+}
+
+// runArgs builds the nerdctl run arguments from the sandbox options.
+func (c *ContainerdSandbox) runArgs(name string, interactive bool, command string, args []string) []string {
+	run := []string{"run", "--rm", "--name", name}
+	if interactive {
+		run = append(run, "--interactive")
+	}
+	if c.config.NetworkMode != "" {
+		run = append(run, "--network", c.config.NetworkMode)
+	}
+	if c.config.Runtime != "" {
+		run = append(run, "--runtime", c.config.Runtime)
+	}
+	if c.config.MemoryLimit > 0 {
+		run = append(run, "--memory", strconv.FormatInt(c.config.MemoryLimit, 10))
+	}
+	if c.config.CPUQuota > 0 {
+		run = append(run, "--cpu-quota", strconv.FormatInt(c.config.CPUQuota, 10))
+	}
+	if c.config.ReadonlyRootfs {
+		run = append(run, "--read-only")
+	}
+	for _, capability := range c.config.CapDrop {
+		run = append(run, "--cap-drop", capability)
+	}
+	for _, capability := range c.config.CapAdd {
+		run = append(run, "--cap-add", capability)
+	}
+	for _, opt := range c.config.SecurityOpt {
+		run = append(run, "--security-opt", opt)
+	}
+	for _, m := range c.config.Mounts {
+		volume := m.HostPath + ":" + m.ContainerPath
+		if m.ReadOnly {
+			volume += ":ro"
+		}
+		run = append(run, "--volume", volume)
+	}
+	for _, env := range c.config.Env {
+		run = append(run, "--env", env)
+	}
+	if c.config.User != "" {
+		run = append(run, "--user", c.config.User)
+	}
+	if c.config.WorkingDir != "" {
+		run = append(run, "--workdir", c.config.WorkingDir)
+	}
+	run = append(run, c.config.Image, command)
+	return append(run, args...)
+}
+
+// containerName returns a unique name for a sandbox container, so it can
+// be removed after a timeout.
+func containerName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "omniagent-" + hex.EncodeToString(b), nil
+}
@@ -15,17 +15,28 @@ import (
 	"github.com/moby/moby/client"
 )
 
-// DockerConfig configures a Docker-based sandbox.
+// DockerConfig configures a Docker-based sandbox. The Podman and
+// containerd sandboxes take the same options.
 type DockerConfig struct {
 	// Image is the Docker image to use (default: "alpine:latest").
 	Image string
 
+	// Host is the engine API endpoint, such as
+	// unix:///run/podman/podman.sock (default: DOCKER_HOST or the Docker
+	// socket).
+	Host string
+
 	// Mounts defines volume mounts for filesystem access.
 	Mounts []DockerMount
 
 	// NetworkMode controls network access ("none", "bridge", "host").
 	NetworkMode string
 
+	// Runtime is the OCI runtime containers run under, such as "runsc"
+	// for gVisor or "kata-runtime" for Kata Containers (default: the
+	// engine's, usually runc). It must be registered with the engine.
+	Runtime string
+
 	// Memory limit in bytes (0 = unlimited).
 	MemoryLimit int64
 
@@ -41,6 +52,9 @@ type DockerConfig struct {
 	// User to run as inside the container (e.g., "nobody", "1000:1000").
 	User string
 
+	// WorkingDir is the working directory inside the container.
+	WorkingDir string
+
 	// ReadonlyRootfs makes the container's root filesystem read-only.
 	ReadonlyRootfs bool
 
@@ -94,7 +108,11 @@ type DockerSandbox struct {
 // NewDockerSandbox creates a new Docker sandbox.
 func NewDockerSandbox(ctx context.Context, config DockerConfig, appConfig *Config) (*DockerSandbox, error) {
 	// Create Docker client
-	cli, err := client.New(client.FromEnv)
+	opts := []client.Opt{client.FromEnv}
+	if config.Host != "" {
+		opts = append(opts, client.WithHost(config.Host))
+	}
+	cli, err := client.New(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("create docker client: %w", err)
 	}
@@ -144,6 +162,12 @@ func (d *DockerSandbox) EnsureImage(ctx context.Context) error {
 
 // Run executes a command inside a Docker container.
 func (d *DockerSandbox) Run(ctx context.Context, command string, args []string) (*Result, error) {
+	return d.RunStream(ctx, command, args, nil, nil)
+}
+
+// RunStream executes a command inside a Docker container, copying its
+// output to stdout and stderr as it is written.
+func (d *DockerSandbox) RunStream(ctx context.Context, command string, args []string, stdout, stderr io.Writer) (*Result, error) {
 	start := time.Now()
 
 	// Apply app-level permission checks if configured
@@ -184,14 +208,16 @@ func (d *DockerSandbox) Run(ctx context.Context, command string, args []string)
 	// Create container
 	createResp, err := d.cli.ContainerCreate(ctx, client.ContainerCreateOptions{
 		Config: &container.Config{
-			Image: d.config.Image,
-			Cmd:   cmd,
-			Env:   d.config.Env,
-			User:  d.config.User,
-			Tty:   false,
+			Image:      d.config.Image,
+			Cmd:        cmd,
+			Env:        d.config.Env,
+			User:       d.config.User,
+			WorkingDir: d.config.WorkingDir,
+			Tty:        false,
 		},
 		HostConfig: &container.HostConfig{
 			NetworkMode:    container.NetworkMode(d.config.NetworkMode),
+			Runtime:        d.config.Runtime,
 			ReadonlyRootfs: d.config.ReadonlyRootfs,
 			CapDrop:        d.config.CapDrop,
 			CapAdd:         d.config.CapAdd,
@@ -222,6 +248,28 @@ func (d *DockerSandbox) Run(ctx context.Context, command string, args []string)
 		return nil, fmt.Errorf("start container: %w", err)
 	}
 
+	// Follow the logs until the container exits, separating stdout and
+	// stderr using stdcopy
+	logs, err := d.cli.ContainerLogs(ctx, containerID, client.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get logs: %w", err)
+	}
+	defer logs.Close()
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	maxBytes := d.config.MaxOutputBytes
+	if maxBytes == 0 {
+		maxBytes = 1024 * 1024 // 1MB default
+	}
+
+	stdoutWriter := teeWriter{buf: &limitedWriter{w: &stdoutBuf, max: maxBytes}, stream: stdout}
+	stderrWriter := teeWriter{buf: &limitedWriter{w: &stderrBuf, max: maxBytes}, stream: stderr}
+	_, _ = stdcopy.StdCopy(stdoutWriter, stderrWriter, logs)
+
 	// Wait for container to finish
 	waitResult := d.cli.ContainerWait(ctx, containerID, client.ContainerWaitOptions{
 		Condition: container.WaitConditionNotRunning,
@@ -244,30 +292,9 @@ func (d *DockerSandbox) Run(ctx context.Context, command string, args []string)
 		exitCode = int(status.StatusCode)
 	}
 
-	// Get container logs
-	logs, err := d.cli.ContainerLogs(ctx, containerID, client.ContainerLogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("get logs: %w", err)
-	}
-	defer logs.Close()
-
-	// Separate stdout and stderr using stdcopy
-	var stdout, stderr bytes.Buffer
-	maxBytes := d.config.MaxOutputBytes
-	if maxBytes == 0 {
-		maxBytes = 1024 * 1024 // 1MB default
-	}
-
-	stdoutWriter := &limitedWriter{w: &stdout, max: maxBytes}
-	stderrWriter := &limitedWriter{w: &stderr, max: maxBytes}
-	_, _ = stdcopy.StdCopy(stdoutWriter, stderrWriter, logs)
-
 	return &Result{
-		Output:   stdout.Bytes(),
-		Error:    stderr.Bytes(),
+		Output:   stdoutBuf.Bytes(),
+		Error:    stderrBuf.Bytes(),
 		ExitCode: exitCode,
 		Duration: time.Since(start),
 	}, nil
@@ -279,6 +306,12 @@ func (d *DockerSandbox) RunShell(ctx context.Context, shellCommand string) (*Res
 	return d.Run(ctx, "sh", []string{"-c", shellCommand})
 }
 
+// RunShellStream executes a shell command inside a Docker container,
+// streaming its output.
+func (d *DockerSandbox) RunShellStream(ctx context.Context, shellCommand string, stdout, stderr io.Writer) (*Result, error) {
+	return d.RunStream(ctx, "sh", []string{"-c", shellCommand}, stdout, stderr)
+}
+
 // RunWithStdin executes a command with stdin input.
 func (d *DockerSandbox) RunWithStdin(ctx context.Context, stdin []byte, command string, args []string) (*Result, error) {
 	start := time.Now()
@@ -324,6 +357,7 @@ func (d *DockerSandbox) RunWithStdin(ctx context.Context, stdin []byte, command
 			Cmd:          cmd,
 			Env:          d.config.Env,
 			User:         d.config.User,
+			WorkingDir:   d.config.WorkingDir,
 			Tty:          false,
 			AttachStdin:  true,
 			AttachStdout: true,
@@ -333,6 +367,7 @@ func (d *DockerSandbox) RunWithStdin(ctx context.Context, stdin []byte, command
 		},
 		HostConfig: &container.HostConfig{
 			NetworkMode:    container.NetworkMode(d.config.NetworkMode),
+			Runtime:        d.config.Runtime,
 			ReadonlyRootfs: d.config.ReadonlyRootfs,
 			CapDrop:        d.config.CapDrop,
 			CapAdd:         d.config.CapAdd,
@@ -425,7 +460,17 @@ func (d *DockerSandbox) RunWithStdin(ctx context.Context, stdin []byte, command
 
 // IsDockerAvailable checks if Docker is accessible.
 func IsDockerAvailable(ctx context.Context) bool {
-	cli, err := client.New(client.FromEnv)
+	return isAPIAvailable(ctx, "")
+}
+
+// isAPIAvailable checks if a Docker API endpoint is accessible. An empty
+// host means DOCKER_HOST or the Docker socket.
+func isAPIAvailable(ctx context.Context, host string) bool {
+	opts := []client.Opt{client.FromEnv}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	cli, err := client.New(opts...)
 	if err != nil {
 		return false
 	}
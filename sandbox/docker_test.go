@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -265,3 +266,47 @@ func TestDockerSandbox_RunWithStdin(t *testing.T) {
 		t.Errorf("Output = %q, want %q", result.Output, stdin)
 	}
 }
+
+func TestParseEngine(t *testing.T) {
+	for input, want := range map[string]string{"": EngineDocker, "Podman": EnginePodman, "containerd": EngineContainerd} {
+		if got, err := ParseEngine(input); err != nil || got != want {
+			t.Errorf("ParseEngine(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseEngine("lxc"); err == nil {
+		t.Error("expected an error for an unknown engine")
+	}
+}
+
+func TestPodmanSocket(t *testing.T) {
+	t.Setenv("CONTAINER_HOST", "tcp://podman:8080")
+	if got := PodmanSocket(); got != "tcp://podman:8080" {
+		t.Errorf("PodmanSocket() = %q, want CONTAINER_HOST", got)
+	}
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	want := "unix:///run/user/1000/podman/podman.sock"
+	if os.Geteuid() == 0 {
+		want = "unix:///run/podman/podman.sock"
+	}
+	if got := PodmanSocket(); got != want {
+		t.Errorf("PodmanSocket() = %q, want %q", got, want)
+	}
+}
+
+func TestContainerdRunArgs(t *testing.T) {
+	config := DefaultDockerConfig()
+	config.Mounts = []DockerMount{{HostPath: "/srv/work", ContainerPath: "/workspace", ReadOnly: true}}
+	config.WorkingDir = "/workspace"
+	config.Env = []string{"LANG=C"}
+	config.Runtime = "io.containerd.runsc.v1"
+	c := &ContainerdSandbox{config: config}
+
+	got := strings.Join(c.runArgs("omniagent-1", true, "sh", []string{"-c", "ls"}), " ")
+	want := "run --rm --name omniagent-1 --interactive --network none --runtime io.containerd.runsc.v1 --memory 268435456 --cpu-quota 50000 --read-only " +
+		"--cap-drop ALL --security-opt no-new-privileges --volume /srv/work:/workspace:ro --env LANG=C " +
+		"--workdir /workspace alpine:latest sh -c ls"
+	if got != want {
+		t.Errorf("runArgs() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Container engines a container sandbox runs on.
+const (
+	EngineDocker     = "docker"
+	EnginePodman     = "podman"
+	EngineContainerd = "containerd"
+)
+
+// ContainerSandbox is a Sandbox that runs each command in a container.
+type ContainerSandbox interface {
+	Sandbox
+
+	// EnsureImage pulls the configured image if not present.
+	EnsureImage(ctx context.Context) error
+}
+
+// ParseEngine validates a container engine. An empty engine means docker.
+func ParseEngine(engine string) (string, error) {
+	switch engine = strings.ToLower(engine); engine {
+	case "":
+		return EngineDocker, nil
+	case EngineDocker, EnginePodman, EngineContainerd:
+		return engine, nil
+	default:
+		return "", fmt.Errorf("invalid container engine %q (must be docker, podman, or containerd)", engine)
+	}
+}
+
+// NewContainerSandbox creates a container sandbox on engine. Docker and
+// Podman are driven through the Docker API, on Podman's socket unless
+// config.Host is set; containerd is driven through nerdctl.
+func NewContainerSandbox(ctx context.Context, engine string, config DockerConfig, appConfig *Config) (ContainerSandbox, error) {
+	switch engine {
+	case EngineDocker, "":
+		return NewDockerSandbox(ctx, config, appConfig)
+	case EnginePodman:
+		if config.Host == "" {
+			config.Host = PodmanSocket()
+		}
+		return NewDockerSandbox(ctx, config, appConfig)
+	case EngineContainerd:
+		return NewContainerdSandbox(ctx, config, appConfig)
+	default:
+		return nil, fmt.Errorf("invalid container engine %q", engine)
+	}
+}
+
+// IsEngineAvailable reports whether engine is reachable. For Docker and
+// Podman, host overrides the API endpoint.
+func IsEngineAvailable(ctx context.Context, engine, host string) bool {
+	switch engine {
+	case EngineDocker, "":
+		return isAPIAvailable(ctx, host)
+	case EnginePodman:
+		if host == "" {
+			host = PodmanSocket()
+		}
+		return isAPIAvailable(ctx, host)
+	case EngineContainerd:
+		return exec.CommandContext(ctx, nerdctl, "info").Run() == nil //nolint:gosec // G204: Fixed command
+	default:
+		return false
+	}
+}
+
+// PodmanSocket returns the Podman API endpoint: CONTAINER_HOST if set,
+// else the rootless socket under XDG_RUNTIME_DIR, else the system socket.
+func PodmanSocket() string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Geteuid() != 0 {
+		return "unix://" + filepath.Join(dir, "podman", "podman.sock")
+	}
+	return "unix:///run/podman/podman.sock"
+}
@@ -0,0 +1,146 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Executor runs shell commands inside an isolation boundary.
+// Tools that execute commands use an Executor instead of the host shell
+// when sandboxing is enabled.
+type Executor interface {
+	RunShell(ctx context.Context, shellCommand string) (*Result, error)
+}
+
+// Sandbox is an isolation backend. DockerSandbox (also used for Podman),
+// ContainerdSandbox, WASMShell, and Process implement it, so tools and
+// skills need not know which one they run in.
+type Sandbox interface {
+	Executor
+	Run(ctx context.Context, command string, args []string) (*Result, error)
+	RunWithStdin(ctx context.Context, stdin []byte, command string, args []string) (*Result, error)
+	Close() error
+}
+
+// Streamer is implemented by sandboxes that can stream a command's output
+// as it runs, so long-running commands can report progress. Either writer
+// may be nil. The Result still holds the output, up to the output limit.
+type Streamer interface {
+	RunStream(ctx context.Context, command string, args []string, stdout, stderr io.Writer) (*Result, error)
+	RunShellStream(ctx context.Context, shellCommand string, stdout, stderr io.Writer) (*Result, error)
+}
+
+// teeWriter keeps output in buf while copying it to stream. Stream errors
+// are ignored, so a slow or failed listener never stops the command.
+type teeWriter struct {
+	buf    io.Writer
+	stream io.Writer
+}
+
+func (t teeWriter) Write(p []byte) (int, error) {
+	_, _ = t.buf.Write(p)
+	if t.stream != nil {
+		_, _ = t.stream.Write(p)
+	}
+	return len(p), nil
+}
+
+// Executor modes selectable in configuration.
+const (
+	ModeNone    = "none"
+	ModeDocker  = "docker"
+	ModeWASM    = "wasm"
+	ModeProcess = "process"
+)
+
+// WASMShell runs shell commands with a WASI shell module such as busybox.
+type WASMShell struct {
+	runtime *Runtime
+}
+
+// wasmShellModule is the cache name of the compiled shell module.
+const wasmShellModule = "shell"
+
+// NewWASMShell compiles the WASI shell module at modulePath.
+func NewWASMShell(ctx context.Context, config Config, modulePath string) (*WASMShell, error) {
+	if modulePath == "" {
+		return nil, fmt.Errorf("wasm shell module not configured")
+	}
+
+	wasm, err := os.ReadFile(modulePath) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		return nil, fmt.Errorf("read wasm module: %w", err)
+	}
+
+	rt, err := NewRuntime(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rt.Compile(ctx, wasmShellModule, wasm); err != nil {
+		rt.Close(ctx)
+		return nil, err
+	}
+
+	return &WASMShell{runtime: rt}, nil
+}
+
+// RunShell executes a shell command with the WASI shell module.
+func (w *WASMShell) RunShell(ctx context.Context, shellCommand string) (*Result, error) {
+	return w.Run(ctx, "sh", []string{"-c", shellCommand})
+}
+
+// RunShellStream executes a shell command with the WASI shell module,
+// streaming its output.
+func (w *WASMShell) RunShellStream(ctx context.Context, shellCommand string, stdout, stderr io.Writer) (*Result, error) {
+	return w.RunStream(ctx, "sh", []string{"-c", shellCommand}, stdout, stderr)
+}
+
+// RunStream executes an applet, streaming its output.
+func (w *WASMShell) RunStream(ctx context.Context, command string, args []string, stdout, stderr io.Writer) (*Result, error) {
+	return w.runtime.ExecuteStream(ctx, wasmShellModule, nil, stdout, stderr, append([]string{command}, args...)...)
+}
+
+// Run executes one of the shell module's applets, such as ls, by name.
+func (w *WASMShell) Run(ctx context.Context, command string, args []string) (*Result, error) {
+	return w.RunWithStdin(ctx, nil, command, args)
+}
+
+// RunWithStdin executes an applet with stdin input.
+func (w *WASMShell) RunWithStdin(ctx context.Context, stdin []byte, command string, args []string) (*Result, error) {
+	return w.runtime.ExecuteArgs(ctx, wasmShellModule, stdin, append([]string{command}, args...)...)
+}
+
+// Close releases the runtime resources.
+func (w *WASMShell) Close() error {
+	return w.runtime.Close(context.Background())
+}
+
+// ParseMode validates an executor mode. An empty mode means "none".
+func ParseMode(mode string) (string, error) {
+	switch mode {
+	case "", ModeNone:
+		return ModeNone, nil
+	case ModeDocker, ModeWASM, ModeProcess:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid sandbox mode %q (must be none, docker, wasm, or process)", mode)
+	}
+}
+
+// Ensure the backends implement Sandbox, and stream where they can.
+var (
+	_ Sandbox = (*DockerSandbox)(nil)
+	_ Sandbox = (*ContainerdSandbox)(nil)
+	_ Sandbox = (*WASMShell)(nil)
+	_ Sandbox = (*Process)(nil)
+	_ Sandbox = (*WorkspaceSandbox)(nil)
+
+	_ Streamer = (*DockerSandbox)(nil)
+	_ Streamer = (*ContainerdSandbox)(nil)
+	_ Streamer = (*WASMShell)(nil)
+	_ Streamer = (*Process)(nil)
+	_ Streamer = (*WorkspaceSandbox)(nil)
+)
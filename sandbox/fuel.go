@@ -0,0 +1,72 @@
+package sandbox
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// wazero has no instruction counter, so fuel is metered by function
+// calls: each call a module makes to one of its own functions burns one
+// unit. This is call metering, not an instruction budget: loops that make
+// no calls burn none, and Timeout still bounds them.
+
+// fuelKey is the context key of the meter of a module run.
+type fuelKey struct{}
+
+// fuelLimitKey is the context key of a per-call fuel limit.
+type fuelLimitKey struct{}
+
+// WithFuelLimit limits the fuel of the module runs made with ctx. It can
+// only lower Config.FuelLimit, and has no effect when that is 0, as
+// modules are compiled without metering then.
+func WithFuelLimit(ctx context.Context, limit uint64) context.Context {
+	return context.WithValue(ctx, fuelLimitKey{}, limit)
+}
+
+// fuelMeter counts the fuel a module run burns and stops the run when it
+// exceeds the limit.
+type fuelMeter struct {
+	limit    uint64
+	used     atomic.Uint64
+	exceeded atomic.Bool
+	stop     context.CancelFunc
+}
+
+// burn uses one unit of fuel.
+func (m *fuelMeter) burn() {
+	if m.used.Add(1) > m.limit && !m.exceeded.Swap(true) {
+		m.stop() // The runtime closes modules when their context is done
+	}
+}
+
+// withFuelMeter returns a context that meters a module run against
+// limit, lowered by any WithFuelLimit on ctx, and the meter. Cancel the
+// context when the run ends.
+func withFuelMeter(ctx context.Context, limit uint64) (context.Context, context.CancelFunc, *fuelMeter) {
+	if perCall, ok := ctx.Value(fuelLimitKey{}).(uint64); ok && perCall < limit {
+		limit = perCall
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	m := &fuelMeter{limit: limit, stop: cancel}
+	return context.WithValue(ctx, fuelKey{}, m), cancel, m
+}
+
+// withFuelListener returns ctx set up to compile modules that report
+// their function calls to the run's meter.
+func withFuelListener(ctx context.Context) context.Context {
+	return experimental.WithFunctionListenerFactory(ctx, fuelListenerFactory{})
+}
+
+// fuelListenerFactory attaches fuelListener to every function.
+type fuelListenerFactory struct{}
+
+func (fuelListenerFactory) NewFunctionListener(api.FunctionDefinition) experimental.FunctionListener {
+	return experimental.FunctionListenerFunc(func(ctx context.Context, _ api.Module, _ api.FunctionDefinition, _ []uint64, _ experimental.StackIterator) {
+		if m, ok := ctx.Value(fuelKey{}).(*fuelMeter); ok {
+			m.burn()
+		}
+	})
+}
@@ -3,13 +3,19 @@ package sandbox
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -36,15 +42,20 @@ func (h *HostFunctions) FSRead(ctx context.Context, path string) ([]byte, error)
 	}
 
 	// Read file with size limit
-	data, err := os.ReadFile(absPath)
+	f, err := os.Open(absPath) //nolint:gosec // G304: Path is validated above
 	if err != nil {
 		return nil, fmt.Errorf("read file: %w", err)
 	}
+	defer f.Close()
 
-	if h.config.MaxOutputBytes > 0 && len(data) > h.config.MaxOutputBytes {
-		return data[:h.config.MaxOutputBytes], nil
+	var r io.Reader = f
+	if h.config.MaxOutputBytes > 0 {
+		r = io.LimitReader(f, int64(h.config.MaxOutputBytes))
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
 	}
-
 	return data, nil
 }
 
@@ -68,6 +79,67 @@ func (h *HostFunctions) FSWrite(ctx context.Context, path string, data []byte) e
 	return os.WriteFile(absPath, data, 0600) //nolint:gosec // G306: User-configurable file permissions
 }
 
+// FSList lists a directory if the fs_read capability is granted.
+func (h *HostFunctions) FSList(ctx context.Context, path string) ([]fs.DirEntry, error) {
+	if !h.config.HasCapability(CapFSRead) {
+		return nil, NewCapabilityError(CapFSRead, "fs_list")
+	}
+
+	absPath, err := h.validatePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("list directory: %w", err)
+	}
+	return entries, nil
+}
+
+// FSGlob returns the files matching pattern if the fs_read capability is
+// granted. The directory the pattern starts from must be allowed, and
+// matches that resolve outside the allowed directories are dropped.
+func (h *HostFunctions) FSGlob(ctx context.Context, pattern string) ([]string, error) {
+	if !h.config.HasCapability(CapFSRead) {
+		return nil, NewCapabilityError(CapFSRead, "fs_glob")
+	}
+
+	absPattern, err := filepath.Abs(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	// Check the longest directory without wildcards before walking
+	base := absPattern
+	for hasMeta(base) {
+		base = filepath.Dir(base)
+	}
+	if _, err := h.validatePath(base); err != nil {
+		return nil, err
+	}
+
+	matches, err := filepath.Glob(absPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	allowed := matches[:0]
+	for _, m := range matches {
+		if _, err := h.validatePath(m); err == nil {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed, nil
+}
+
+// hasMeta reports whether path contains glob wildcards.
+func hasMeta(path string) bool {
+	magic := `*?[`
+	if runtime.GOOS != "windows" {
+		magic = `*?[\`
+	}
+	return strings.ContainsAny(path, magic)
+}
+
 // HTTPFetch makes an HTTP request if the net_http capability is granted.
 func (h *HostFunctions) HTTPFetch(ctx context.Context, method, url string, body []byte, headers map[string]string) ([]byte, int, error) {
 	if !h.config.HasCapability(CapNetHTTP) {
@@ -95,9 +167,25 @@ func (h *HostFunctions) HTTPFetch(ctx context.Context, method, url string, body
 		req.Header.Set(k, v)
 	}
 
-	// Make request with timeout
+	// Make request with timeout, checking redirects against the allowlist
 	client := &http.Client{
 		Timeout: h.config.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return h.validateHost(req.URL.String())
+		},
+	}
+	if h.config.BlockPrivateNetworks || h.config.PrivateNetworksOnly {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, Control: publicAddressOnly}
+		if h.config.PrivateNetworksOnly {
+			dialer.Control = privateAddressOnly
+		}
+		client.Transport = &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}
 	}
 
 	resp, err := client.Do(req) //nolint:gosec // G704: URL is validated by validateHost
@@ -107,7 +195,10 @@ func (h *HostFunctions) HTTPFetch(ctx context.Context, method, url string, body
 	defer resp.Body.Close()
 
 	// Read response with limit
-	limitReader := io.LimitReader(resp.Body, int64(h.config.MaxOutputBytes))
+	var limitReader io.Reader = resp.Body
+	if h.config.MaxOutputBytes > 0 {
+		limitReader = io.LimitReader(resp.Body, int64(h.config.MaxOutputBytes))
+	}
 	respBody, err := io.ReadAll(limitReader)
 	if err != nil {
 		return nil, resp.StatusCode, fmt.Errorf("read response: %w", err)
@@ -166,16 +257,9 @@ func (h *HostFunctions) validatePath(path string) (string, error) {
 	}
 
 	// Resolve symlinks to prevent traversal attacks
-	// For non-existent files, resolve the parent directory
-	resolvedPath := absPath
-	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
-		resolvedPath = resolved
-	} else if os.IsNotExist(err) {
-		// File doesn't exist yet, resolve parent directory
-		parentPath := filepath.Dir(absPath)
-		if resolvedParent, parentErr := filepath.EvalSymlinks(parentPath); parentErr == nil {
-			resolvedPath = filepath.Join(resolvedParent, filepath.Base(absPath))
-		}
+	resolvedPath, err := resolveExisting(absPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
 	}
 
 	// Check against allowed paths
@@ -193,8 +277,7 @@ func (h *HostFunctions) validatePath(path string) (string, error) {
 				allowedAbs = resolvedAllowed
 			}
 
-			// Check if path starts with allowed path
-			if strings.HasPrefix(resolvedPath, allowedAbs+string(filepath.Separator)) || resolvedPath == allowedAbs {
+			if withinDir(resolvedPath, allowedAbs, foldPathCase) {
 				allowed = true
 				break
 			}
@@ -210,23 +293,111 @@ func (h *HostFunctions) validatePath(path string) (string, error) {
 	return resolvedPath, nil
 }
 
-// validateHost ensures the URL host is in the allowed list.
-func (h *HostFunctions) validateHost(url string) error {
+// resolveExisting resolves the symlinks in path. For paths that do not
+// exist yet, it resolves the deepest ancestor that does and appends the
+// rest, so a symlinked directory cannot carry missing subdirectories
+// outside the allowed ones.
+func resolveExisting(path string) (string, error) {
+	var rest []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+// foldPathCase is set where file paths are case-insensitive.
+var foldPathCase = runtime.GOOS == "windows"
+
+// withinDir reports whether path is dir or inside it. Both must be
+// absolute. Paths on different volumes (drive letters or UNC shares) are
+// never within each other; with foldCase, names compare case-insensitively.
+func withinDir(path, dir string, foldCase bool) bool {
+	if foldCase {
+		path, dir = strings.ToLower(path), strings.ToLower(dir)
+	}
+	if filepath.VolumeName(path) != filepath.VolumeName(dir) {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// validateHost ensures the URL is HTTP(S) and its host is in the allowed
+// list. An allowed host also allows its subdomains.
+func (h *HostFunctions) validateHost(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return &ExecutionError{
+			Kind:    "capability",
+			Message: fmt.Sprintf("invalid HTTP URL: %s", rawURL),
+		}
+	}
 	if len(h.config.AllowedHosts) == 0 {
 		return nil // All hosts allowed
 	}
 
-	// Extract host from URL
+	host := strings.ToLower(u.Hostname())
 	for _, allowed := range h.config.AllowedHosts {
-		if strings.Contains(url, allowed) {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
 			return nil
 		}
 	}
 
 	return &ExecutionError{
 		Kind:    "capability",
-		Message: fmt.Sprintf("host not in allowed list for URL: %s", url),
+		Message: fmt.Sprintf("host not in allowed list for URL: %s", rawURL),
+	}
+}
+
+// publicAddressOnly is a net.Dialer Control function that refuses
+// loopback, private, and link-local addresses. Checking at connect time
+// also covers redirects and DNS names that resolve to internal addresses.
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return &ExecutionError{
+			Kind:    "capability",
+			Message: fmt.Sprintf("address %s is not public", host),
+		}
+	}
+	return nil
+}
+
+// privateAddressOnly is a net.Dialer Control function that refuses
+// anything but loopback, private, and link-local addresses.
+func privateAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast()) {
+		return &ExecutionError{
+			Kind:    "capability",
+			Message: fmt.Sprintf("address %s is not on a private network", host),
+		}
 	}
+	return nil
 }
 
 // validateCommand ensures the command is in the allowed list.
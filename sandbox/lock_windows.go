@@ -0,0 +1,27 @@
+//go:build windows
+
+package sandbox
+
+import (
+	"errors"
+	"math"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockShared blocks until it holds a shared lock on f.
+func lockShared(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), 0, 0, math.MaxUint32, math.MaxUint32, new(windows.Overlapped))
+}
+
+// tryLockExclusive takes an exclusive lock on f, reporting false when
+// another holder has it locked.
+func tryLockExclusive(f *os.File) (bool, error) {
+	flags := uint32(windows.LOCKFILE_EXCLUSIVE_LOCK | windows.LOCKFILE_FAIL_IMMEDIATELY)
+	err := windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, math.MaxUint32, math.MaxUint32, new(windows.Overlapped))
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,116 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Process runs commands as plain child processes on the host, in the
+// working directory, with the timeout and output limit. It isolates
+// nothing else, so use it only for trusted commands where Docker and WASM
+// are unavailable.
+type Process struct {
+	config Config
+	host   *HostFunctions // For command checks; nil allows any command
+}
+
+// waitDelay bounds how long a command's output is read after it exits or
+// is killed, in case a child it left behind holds the pipes open.
+const waitDelay = time.Second
+
+// NewProcess creates a plain-process sandbox. Commands are limited to
+// AllowedCommands when it is set, and shell commands are then refused, as
+// the allowlist cannot see into them; Capabilities are not consulted.
+func NewProcess(config Config) *Process {
+	if config.MaxOutputBytes <= 0 {
+		config.MaxOutputBytes = DefaultConfig().MaxOutputBytes
+	}
+	p := &Process{config: config}
+	if len(config.AllowedCommands) > 0 {
+		p.host = NewHostFunctions(config)
+	}
+	return p
+}
+
+// Run executes a command.
+func (p *Process) Run(ctx context.Context, command string, args []string) (*Result, error) {
+	return p.run(ctx, nil, command, args, nil, nil)
+}
+
+// RunStream executes a command, streaming its output.
+func (p *Process) RunStream(ctx context.Context, command string, args []string, stdout, stderr io.Writer) (*Result, error) {
+	return p.run(ctx, nil, command, args, stdout, stderr)
+}
+
+// RunShell executes a command with sh, or cmd.exe on Windows.
+func (p *Process) RunShell(ctx context.Context, shellCommand string) (*Result, error) {
+	return p.RunShellStream(ctx, shellCommand, nil, nil)
+}
+
+// RunShellStream executes a shell command, streaming its output.
+func (p *Process) RunShellStream(ctx context.Context, shellCommand string, stdout, stderr io.Writer) (*Result, error) {
+	if p.host != nil {
+		return nil, &ExecutionError{
+			Kind:    "capability",
+			Message: "shell commands are not allowed when AllowedCommands is set",
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return p.RunStream(ctx, "cmd", []string{"/C", shellCommand}, stdout, stderr)
+	}
+	return p.RunStream(ctx, "sh", []string{"-c", shellCommand}, stdout, stderr)
+}
+
+// RunWithStdin executes a command with stdin input.
+func (p *Process) RunWithStdin(ctx context.Context, stdin []byte, command string, args []string) (*Result, error) {
+	return p.run(ctx, stdin, command, args, nil, nil)
+}
+
+func (p *Process) run(ctx context.Context, stdin []byte, command string, args []string, stdoutStream, stderrStream io.Writer) (*Result, error) {
+	if p.host != nil {
+		if err := p.host.validateCommand(command); err != nil {
+			return nil, err
+		}
+	}
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, command, args...) //nolint:gosec // G204: Running commands is the point of a sandbox
+	cmd.Dir = p.config.WorkingDir
+	killProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = teeWriter{buf: &limitedWriter{w: &stdout, max: p.config.MaxOutputBytes}, stream: stdoutStream}
+	cmd.Stderr = teeWriter{buf: &limitedWriter{w: &stderr, max: p.config.MaxOutputBytes}, stream: stderrStream}
+
+	err := cmd.Run()
+	result := &Result{Output: stdout.Bytes(), Error: stderr.Bytes(), Duration: time.Since(start)}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case ctx.Err() == context.DeadlineExceeded:
+		return result, NewTimeoutError(p.config.Timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		return nil, err
+	}
+	return result, nil
+}
+
+// Close releases nothing; processes do not outlive their command.
+func (p *Process) Close() error {
+	return nil
+}
@@ -3,12 +3,15 @@ package sandbox
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 // Runtime manages WASM module execution with sandboxing.
@@ -64,7 +67,7 @@ func (r *Runtime) Compile(ctx context.Context, name string, wasm []byte) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	compiled, err := r.runtime.CompileModule(ctx, wasm)
+	compiled, err := r.runtime.CompileModule(r.compileContext(ctx), wasm)
 	if err != nil {
 		return fmt.Errorf("compile module: %w", err)
 	}
@@ -83,21 +86,58 @@ func (r *Runtime) Execute(ctx context.Context, name string, stdin []byte) (*Resu
 		return nil, fmt.Errorf("module not found: %s", name)
 	}
 
-	return r.executeModule(ctx, compiled, stdin)
+	return r.executeModule(ctx, compiled, stdin, nil, nil, nil)
+}
+
+// ExecuteArgs runs a compiled WASM module with command-line arguments.
+// The first argument is the program name, as in os.Args.
+func (r *Runtime) ExecuteArgs(ctx context.Context, name string, stdin []byte, args ...string) (*Result, error) {
+	r.mu.Lock()
+	compiled, ok := r.modules[name]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("module not found: %s", name)
+	}
+
+	return r.executeModule(ctx, compiled, stdin, args, nil, nil)
+}
+
+// ExecuteStream runs a compiled WASM module with command-line arguments,
+// copying its output to stdout and stderr as it is written.
+func (r *Runtime) ExecuteStream(ctx context.Context, name string, stdin []byte, stdout, stderr io.Writer, args ...string) (*Result, error) {
+	r.mu.Lock()
+	compiled, ok := r.modules[name]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("module not found: %s", name)
+	}
+
+	return r.executeModule(ctx, compiled, stdin, args, stdout, stderr)
 }
 
 // ExecuteBytes compiles and runs WASM bytes directly (not cached).
 func (r *Runtime) ExecuteBytes(ctx context.Context, wasm, stdin []byte) (*Result, error) {
-	compiled, err := r.runtime.CompileModule(ctx, wasm)
+	compiled, err := r.runtime.CompileModule(r.compileContext(ctx), wasm)
 	if err != nil {
 		return nil, fmt.Errorf("compile module: %w", err)
 	}
 	defer compiled.Close(ctx)
 
-	return r.executeModule(ctx, compiled, stdin)
+	return r.executeModule(ctx, compiled, stdin, nil, nil, nil)
+}
+
+// compileContext returns the context to compile modules with, metering
+// fuel when a fuel limit is set.
+func (r *Runtime) compileContext(ctx context.Context) context.Context {
+	if r.config.FuelLimit > 0 {
+		return withFuelListener(ctx)
+	}
+	return ctx
 }
 
-func (r *Runtime) executeModule(ctx context.Context, compiled wazero.CompiledModule, stdin []byte) (*Result, error) {
+func (r *Runtime) executeModule(ctx context.Context, compiled wazero.CompiledModule, stdin []byte, args []string, stdout, stderr io.Writer) (*Result, error) {
 	start := time.Now()
 
 	// Apply timeout
@@ -107,6 +147,20 @@ func (r *Runtime) executeModule(ctx context.Context, compiled wazero.CompiledMod
 		defer cancel()
 	}
 
+	// Meter fuel
+	var fuel *fuelMeter
+	if r.config.FuelLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel, fuel = withFuelMeter(ctx, r.config.FuelLimit)
+		defer cancel()
+	}
+	fuelConsumed := func() uint64 {
+		if fuel == nil {
+			return 0
+		}
+		return min(fuel.used.Load(), fuel.limit)
+	}
+
 	// Setup I/O buffers
 	stdinBuf := bytes.NewReader(stdin)
 	stdoutBuf := &limitedBuffer{max: r.config.MaxOutputBytes}
@@ -115,12 +169,42 @@ func (r *Runtime) executeModule(ctx context.Context, compiled wazero.CompiledMod
 	// Configure the module
 	moduleConfig := wazero.NewModuleConfig().
 		WithStdin(stdinBuf).
-		WithStdout(stdoutBuf).
-		WithStderr(stderrBuf).
+		WithStdout(teeWriter{buf: stdoutBuf, stream: stdout}).
+		WithStderr(teeWriter{buf: stderrBuf, stream: stderr}).
 		WithStartFunctions("_start")
+	if len(args) > 0 {
+		moduleConfig = moduleConfig.WithArgs(args...)
+	}
+
+	// Expose the working directory when file capabilities are granted
+	if r.config.WorkingDir != "" {
+		switch {
+		case r.config.HasCapability(CapFSWrite):
+			moduleConfig = moduleConfig.WithFSConfig(wazero.NewFSConfig().WithDirMount(r.config.WorkingDir, "/"))
+		case r.config.HasCapability(CapFSRead):
+			moduleConfig = moduleConfig.WithFSConfig(wazero.NewFSConfig().WithReadOnlyDirMount(r.config.WorkingDir, "/"))
+		}
+	}
 
 	// Instantiate and run
 	mod, err := r.runtime.InstantiateModule(ctx, compiled, moduleConfig)
+	if fuel != nil && fuel.exceeded.Load() {
+		if mod != nil {
+			mod.Close(ctx)
+		}
+		return nil, NewFuelError(fuel.limit)
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		// Non-zero exit is a normal program result, not a sandbox failure
+		return &Result{
+			Output:       stdoutBuf.Bytes(),
+			Error:        stderrBuf.Bytes(),
+			ExitCode:     int(exitErr.ExitCode()),
+			Duration:     time.Since(start),
+			FuelConsumed: fuelConsumed(),
+		}, nil
+	}
 	if err != nil {
 		// Check if it was a timeout
 		if ctx.Err() == context.DeadlineExceeded {
@@ -148,7 +232,7 @@ func (r *Runtime) executeModule(ctx context.Context, compiled wazero.CompiledMod
 		ExitCode:     0,
 		Duration:     duration,
 		MemoryUsed:   memUsed,
-		FuelConsumed: 0, // Would need fuel metering enabled
+		FuelConsumed: fuelConsumed(),
 	}, nil
 }
 
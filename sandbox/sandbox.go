@@ -30,7 +30,9 @@ type Config struct {
 	// MemoryLimitMB is the maximum memory in megabytes (default: 16).
 	MemoryLimitMB int
 
-	// FuelLimit is the maximum number of instructions (0 = unlimited).
+	// FuelLimit is the maximum number of function calls a WASM module may
+	// make per run (0 = unlimited and unmetered). It is call metering, not
+	// an instruction budget.
 	FuelLimit uint64
 
 	// Timeout is the maximum execution time.
@@ -42,9 +44,18 @@ type Config struct {
 	// AllowedPaths restricts file access to these paths (empty = WorkingDir only).
 	AllowedPaths []string
 
-	// AllowedHosts restricts HTTP access to these hosts (empty = all allowed).
+	// AllowedHosts restricts HTTP access to these hosts and their
+	// subdomains (empty = all allowed).
 	AllowedHosts []string
 
+	// BlockPrivateNetworks refuses HTTP connections to loopback, private,
+	// and link-local addresses.
+	BlockPrivateNetworks bool
+
+	// PrivateNetworksOnly refuses HTTP connections to anything but loopback,
+	// private, and link-local addresses.
+	PrivateNetworksOnly bool
+
 	// AllowedCommands restricts exec to these commands (empty = none allowed).
 	AllowedCommands []string
 
@@ -85,13 +96,14 @@ type Result struct {
 	// MemoryUsed is the peak memory usage in bytes.
 	MemoryUsed uint64
 
-	// FuelConsumed is the number of instructions executed.
+	// FuelConsumed is the fuel a WASM module burned, in function calls,
+	// when FuelLimit is set.
 	FuelConsumed uint64
 }
 
 // ExecutionError represents an error during sandboxed execution.
 type ExecutionError struct {
-	Kind    string // "timeout", "memory", "capability", "runtime"
+	Kind    string // "timeout", "memory", "fuel", "capability", "runtime"
 	Message string
 	Cause   error
 }
@@ -124,6 +136,14 @@ func NewTimeoutError(timeout time.Duration) *ExecutionError {
 	}
 }
 
+// NewFuelError creates a fuel limit error.
+func NewFuelError(limit uint64) *ExecutionError {
+	return &ExecutionError{
+		Kind:    "fuel",
+		Message: fmt.Sprintf("execution exceeded fuel limit of %d", limit),
+	}
+}
+
 // NewMemoryError creates a memory limit error.
 func NewMemoryError(limit, used uint64) *ExecutionError {
 	return &ExecutionError{
@@ -2,8 +2,13 @@ package sandbox
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -82,6 +87,21 @@ func TestHostFunctions_FSRead(t *testing.T) {
 		}
 	})
 
+	t.Run("truncated", func(t *testing.T) {
+		h := NewHostFunctions(Config{
+			Capabilities:   []Capability{CapFSRead},
+			AllowedPaths:   []string{tmpDir},
+			MaxOutputBytes: 5,
+		})
+		data, err := h.FSRead(ctx, testFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(data) != "hello" {
+			t.Errorf("got %q, want %q", data, "hello")
+		}
+	})
+
 	t.Run("path outside allowed", func(t *testing.T) {
 		h := NewHostFunctions(Config{
 			Capabilities: []Capability{CapFSRead},
@@ -128,6 +148,32 @@ func TestHostFunctions_FSWrite(t *testing.T) {
 			t.Errorf("got %q, want %q", data, testContent)
 		}
 	})
+
+	t.Run("symlink to missing directories", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks need privileges on Windows")
+		}
+		allowed := filepath.Join(tmpDir, "allowed")
+		outside := filepath.Join(tmpDir, "outside")
+		for _, dir := range []string{allowed, outside} {
+			if err := os.Mkdir(dir, 0o750); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Symlink(outside, filepath.Join(allowed, "link")); err != nil {
+			t.Fatal(err)
+		}
+		h := NewHostFunctions(Config{
+			Capabilities: []Capability{CapFSWrite},
+			AllowedPaths: []string{allowed},
+		})
+		if err := h.FSWrite(ctx, filepath.Join(allowed, "link", "sub", "pwn.txt"), testContent); err == nil {
+			t.Error("expected error for write through symlink")
+		}
+		if _, err := os.Stat(filepath.Join(outside, "sub")); !os.IsNotExist(err) {
+			t.Errorf("directory created outside allowed path: %v", err)
+		}
+	})
 }
 
 func TestHostFunctions_ExecRun(t *testing.T) {
@@ -248,3 +294,378 @@ func TestExecutionError(t *testing.T) {
 		t.Error("Unwrap() should return DeadlineExceeded")
 	}
 }
+
+// callModule returns a WASM program whose _start calls an empty function
+// n times.
+func callModule(n byte) []byte {
+	section := func(id byte, content ...byte) []byte {
+		return append([]byte{id, byte(len(content))}, content...)
+	}
+	start := []byte{
+		0x01, 0x01, 0x7f, // One i32 local
+		0x03, 0x40, // loop
+		0x10, 0x01, // call 1
+		0x20, 0x00, 0x41, 0x01, 0x6a, 0x22, 0x00, // i++
+		0x41, n | 0x80, 0x00, 0x48, // i < n, n as a positive two-byte LEB
+		0x0d, 0x00, // br_if 0
+		0x0b, 0x0b, // end
+	}
+	m := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	m = append(m, section(1, 0x01, 0x60, 0x00, 0x00)...)
+	m = append(m, section(3, 0x02, 0x00, 0x00)...)
+	m = append(m, section(5, 0x01, 0x00, 0x01)...)
+	m = append(m, section(7, 0x01, 0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x00)...)
+	code := append([]byte{0x02, byte(len(start))}, start...)
+	code = append(code, 0x02, 0x00, 0x0b)
+	m = append(m, section(10, code...)...)
+	return m
+}
+
+func TestRuntime_Fuel(t *testing.T) {
+	ctx := context.Background()
+	wasm := callModule(10)
+
+	run := func(ctx context.Context, limit uint64) (*Result, error) {
+		cfg := DefaultConfig()
+		cfg.FuelLimit = limit
+		rt, err := NewRuntime(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rt.Close(ctx)
+		return rt.ExecuteBytes(ctx, wasm, nil)
+	}
+
+	res, err := run(ctx, 100)
+	if err != nil {
+		t.Fatalf("ExecuteBytes: %v", err)
+	}
+	if res.FuelConsumed == 0 || res.FuelConsumed > 100 {
+		t.Errorf("FuelConsumed = %d, want within the limit of 100", res.FuelConsumed)
+	}
+
+	res, err = run(ctx, 0)
+	if err != nil {
+		t.Fatalf("ExecuteBytes unmetered: %v", err)
+	}
+	if res.FuelConsumed != 0 {
+		t.Errorf("unmetered FuelConsumed = %d, want 0", res.FuelConsumed)
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"config":   ctx,
+		"per call": WithFuelLimit(ctx, 5),
+	} {
+		limit := uint64(5)
+		if name == "per call" {
+			limit = 100
+		}
+		_, err := run(ctx, limit)
+		var execErr *ExecutionError
+		if !errors.As(err, &execErr) || execErr.Kind != "fuel" {
+			t.Errorf("%s: err = %v, want fuel error", name, err)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"", ModeNone, false},
+		{"none", ModeNone, false},
+		{"docker", ModeDocker, false},
+		{"wasm", ModeWASM, false},
+		{"process", ModeProcess, false},
+		{"firecracker", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMode(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWASMShellRequiresModule(t *testing.T) {
+	if _, err := NewWASMShell(context.Background(), DefaultConfig(), ""); err == nil {
+		t.Error("NewWASMShell() should fail without a module path")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.WorkingDir = dir
+	p := NewProcess(config)
+	defer p.Close()
+
+	res, err := p.RunShell(ctx, "pwd; exit 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ExitCode != 3 || strings.TrimSpace(string(res.Output)) != dir {
+		t.Errorf("RunShell() = %q, exit %d; want %q, exit 3", res.Output, res.ExitCode, dir)
+	}
+
+	res, err = p.RunWithStdin(ctx, []byte("hello"), "cat", nil)
+	if err != nil || string(res.Output) != "hello" {
+		t.Errorf("RunWithStdin() = %v, %v; want hello", res, err)
+	}
+
+	var stdout, stderr strings.Builder
+	res, err = p.RunShellStream(ctx, "echo out; echo err >&2", &stdout, &stderr)
+	if err != nil || stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("RunShellStream() streamed %q, %q, %v", stdout.String(), stderr.String(), err)
+	}
+	if string(res.Output) != "out\n" || string(res.Error) != "err\n" {
+		t.Errorf("RunShellStream() result = %q, %q; want the streamed output too", res.Output, res.Error)
+	}
+
+	timed := config
+	timed.Timeout = 200 * time.Millisecond
+	start := time.Now()
+	_, err = NewProcess(timed).RunShell(ctx, "sleep 5; echo done")
+	var execErr *ExecutionError
+	if !errors.As(err, &execErr) || execErr.Kind != "timeout" {
+		t.Errorf("RunShell() past timeout error = %v, want timeout", err)
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("RunShell() returned after %v, want the timeout to kill the shell's children", d)
+	}
+
+	config.AllowedCommands = []string{"echo", "sh"}
+	p = NewProcess(config)
+	if _, err := p.Run(ctx, "echo", []string{"ok"}); err != nil {
+		t.Errorf("allowed command failed: %v", err)
+	}
+	if _, err := p.RunShell(ctx, "echo ok; rm -rf x"); err == nil {
+		t.Error("expected shell commands to be refused with an allowlist")
+	}
+}
+
+func TestWithinDir(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		foldCase  bool
+		want      bool
+	}{
+		{"/work", "/work", false, true},
+		{"/work/a/b.txt", "/work", false, true},
+		{"/work/..data", "/work", false, true},
+		{"/workshop/a", "/work", false, false},
+		{"/etc/passwd", "/work", false, false},
+		{"/anything", "/", false, true},
+		{"/Work/a", "/work", false, false},
+		{"/Work/a", "/work", true, true},
+	}
+	for _, tt := range tests {
+		if got := withinDir(tt.path, tt.dir, tt.foldCase); got != tt.want {
+			t.Errorf("withinDir(%q, %q, %v) = %v, want %v", tt.path, tt.dir, tt.foldCase, got, tt.want)
+		}
+	}
+}
+
+func TestWorkspaceName(t *testing.T) {
+	if got := WorkspaceName(""); got != DefaultWorkspace {
+		t.Errorf("WorkspaceName(\"\") = %q", got)
+	}
+	if got := WorkspaceName("cli-1"); got != "cli-1" {
+		t.Errorf("safe name rewritten to %q", got)
+	}
+	a, b := WorkspaceName("telegram:12345"), WorkspaceName("telegram/12345")
+	if a == b || !strings.HasPrefix(a, "telegram-12345-") || WorkspaceName(a) != a {
+		t.Errorf("WorkspaceName() = %q and %q; want distinct, stable names", a, b)
+	}
+	if got := WorkspaceName(".."); got == ".." {
+		t.Error("WorkspaceName(\"..\") must not escape the workspaces directory")
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	ws := NewWorkspaces(filepath.Join(t.TempDir(), "workspaces"))
+	if list, err := ws.List(); err != nil || len(list) != 0 {
+		t.Fatalf("List() on a missing directory = %v, %v", list, err)
+	}
+
+	dir, err := ws.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "out.txt"), []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.Open("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.Open("../escape"); err == nil {
+		t.Error("expected an invalid name to be refused")
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(filepath.Dir(dir), "b"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ws.List()
+	if err != nil || len(list) != 2 || list[0].Name != "b" || list[1].Size != 5 {
+		t.Fatalf("List() = %+v, %v; want b then a with 5 bytes", list, err)
+	}
+
+	if err := ws.Reset("a"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Reset() left %d entries", len(entries))
+	}
+	removed, err := ws.GC(24 * time.Hour)
+	if err != nil || len(removed) != 1 || removed[0] != "b" {
+		t.Errorf("GC() = %v, %v; want [b]", removed, err)
+	}
+	if err := ws.Remove("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Remove("a"); err == nil {
+		t.Error("expected removing a missing workspace to fail")
+	}
+}
+
+func TestWorkspaceSandbox(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	type key struct{}
+	ctx := context.Background()
+	created := 0
+	ws, err := NewWorkspaceSandbox(WorkspaceSandboxConfig{
+		Workspaces: NewWorkspaces(t.TempDir()),
+		Key:        func(ctx context.Context) string { s, _ := ctx.Value(key{}).(string); return s },
+		New: func(_ context.Context, dir string) (Sandbox, error) {
+			created++
+			config := DefaultConfig()
+			config.WorkingDir = dir
+			return NewProcess(config), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	alice := context.WithValue(ctx, key{}, "chat:alice")
+	if _, err := ws.RunShell(alice, "echo step1 > state"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ws.RunShell(alice, "cat state")
+	if err != nil || strings.TrimSpace(string(res.Output)) != "step1" {
+		t.Errorf("second call = %v, %v; want the first call's file", res, err)
+	}
+	res, err = ws.RunShell(context.WithValue(ctx, key{}, "chat:bob"), "cat state")
+	if err != nil || res.ExitCode == 0 {
+		t.Errorf("another session saw the file: %v, %v", res, err)
+	}
+	if created != 2 {
+		t.Errorf("created %d sandboxes, want one per session", created)
+	}
+}
+
+// closeCounter counts how often its sandbox is closed.
+type closeCounter struct {
+	Sandbox
+	closed *atomic.Int32
+}
+
+func (c closeCounter) Close() error {
+	c.closed.Add(1)
+	return c.Sandbox.Close()
+}
+
+func TestWorkspaceSandboxLifecycle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	type key struct{}
+	var created, closed atomic.Int32
+	gate := make(chan struct{})
+	workspaces := NewWorkspaces(t.TempDir())
+	ws, err := NewWorkspaceSandbox(WorkspaceSandboxConfig{
+		Workspaces: workspaces,
+		Key:        func(ctx context.Context) string { s, _ := ctx.Value(key{}).(string); return s },
+		New: func(ctx context.Context, dir string) (Sandbox, error) {
+			if ctx.Value(key{}) == "slow" {
+				<-gate
+			}
+			created.Add(1)
+			config := DefaultConfig()
+			config.WorkingDir = dir
+			return closeCounter{NewProcess(config), &closed}, nil
+		},
+		IdleTimeout: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ctx := context.Background()
+	slow := context.WithValue(ctx, key{}, "slow")
+	fast := context.WithValue(ctx, key{}, "fast")
+
+	// A slow start is shared by its session and holds up no other
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ws.RunShell(slow, "true"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	if _, err := ws.RunShell(fast, "true"); err != nil {
+		t.Fatal(err)
+	}
+	close(gate)
+	wg.Wait()
+	if n := created.Load(); n != 2 {
+		t.Errorf("created %d sandboxes, want one per session", n)
+	}
+
+	// GC leaves workspaces with an open sandbox alone
+	removed, err := workspaces.GC(-time.Hour)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("GC() = %v, %v; want open workspaces kept", removed, err)
+	}
+
+	if err := ws.Release("fast"); err != nil {
+		t.Fatal(err)
+	}
+	if n := closed.Load(); n != 1 {
+		t.Errorf("closed %d sandboxes after Release, want 1", n)
+	}
+	removed, err = workspaces.GC(-time.Hour)
+	if err != nil || len(removed) != 1 || removed[0] != "fast" {
+		t.Errorf("GC() = %v, %v; want the released workspace removed", removed, err)
+	}
+
+	ws.evictIdle(time.Now().Add(2 * time.Hour))
+	if n := closed.Load(); n != 2 {
+		t.Errorf("closed %d sandboxes after eviction, want 2", n)
+	}
+	if _, err := ws.RunShell(slow, "true"); err != nil {
+		t.Fatal(err)
+	}
+	if n := created.Load(); n != 3 {
+		t.Errorf("created %d sandboxes, want the evicted one recreated", n)
+	}
+}
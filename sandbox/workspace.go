@@ -0,0 +1,523 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultWorkspace names the workspace for calls outside a session.
+const DefaultWorkspace = "default"
+
+// workspaceUnsafe matches characters not kept in workspace names.
+var workspaceUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// WorkspaceName returns the workspace name for a session key, such as
+// telegram:12345. Keys that are not already safe directory names are
+// rewritten and suffixed with a hash, so distinct keys never share a
+// workspace. Safe names are returned unchanged.
+func WorkspaceName(key string) string {
+	if key == "" {
+		return DefaultWorkspace
+	}
+	name := workspaceUnsafe.ReplaceAllString(key, "-")
+	if name == key && name != "." && name != ".." {
+		return name
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return fmt.Sprintf("%s-%08x", name, h.Sum32())
+}
+
+// Workspace is a persistent directory commands in a sandbox share across
+// calls.
+type Workspace struct {
+	Name     string
+	Path     string
+	Size     int64     // Bytes of regular files
+	LastUsed time.Time // When a sandbox last ran in it
+}
+
+// Workspaces manages the persistent workspaces under a directory, one
+// subdirectory each.
+type Workspaces struct {
+	dir string
+}
+
+// NewWorkspaces manages the workspaces under dir.
+func NewWorkspaces(dir string) *Workspaces {
+	return &Workspaces{dir: dir}
+}
+
+// Open returns the directory of the named workspace, creating it if
+// needed, and marks it used now.
+func (w *Workspaces) Open(name string) (string, error) {
+	path, err := w.path(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(path, 0o750); err != nil {
+		return "", fmt.Errorf("create workspace: %w", err)
+	}
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return "", fmt.Errorf("touch workspace: %w", err)
+	}
+	return path, nil
+}
+
+// List returns the workspaces, least recently used first.
+func (w *Workspaces) List() ([]Workspace, error) {
+	entries, err := os.ReadDir(w.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Workspace
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(w.dir, entry.Name())
+		list = append(list, Workspace{Name: entry.Name(), Path: path, Size: dirSize(path), LastUsed: info.ModTime()})
+	}
+	slices.SortFunc(list, func(a, b Workspace) int { return a.LastUsed.Compare(b.LastUsed) })
+	return list, nil
+}
+
+// Reset empties the named workspace, keeping it in place for sandboxes
+// that have it mounted.
+func (w *Workspaces) Reset(name string) error {
+	path, err := w.existing(name)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(path, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove deletes the named workspace.
+func (w *Workspaces) Remove(name string) error {
+	path, err := w.existing(name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// Lock marks the named workspace in use until unlock is called, so GC in
+// this or another process leaves it alone. Any number of holders may lock
+// a workspace at once; Lock waits while GC is removing it.
+func (w *Workspaces) Lock(name string) (unlock func() error, err error) {
+	path, err := w.lockPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(w.dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create workspaces: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600) //nolint:gosec // G304: path is built from a validated workspace name
+	if err != nil {
+		return nil, fmt.Errorf("lock workspace: %w", err)
+	}
+	if err := lockShared(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("lock workspace: %w", err)
+	}
+	return f.Close, nil
+}
+
+// GC removes the workspaces unused for longer than maxIdle and returns
+// their names. Workspaces locked by a running sandbox are kept.
+func (w *Workspaces) GC(maxIdle time.Duration) ([]string, error) {
+	list, err := w.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, ws := range list {
+		if time.Since(ws.LastUsed) <= maxIdle {
+			continue
+		}
+		ok, err := w.removeUnlocked(ws.Name)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed = append(removed, ws.Name)
+		}
+	}
+	return removed, nil
+}
+
+// removeUnlocked deletes the named workspace unless it is locked, holding
+// its lock meanwhile so no sandbox opens it halfway through. The lock file
+// is kept, as a holder waiting on it would otherwise lock a stale file.
+func (w *Workspaces) removeUnlocked(name string) (bool, error) {
+	path, err := w.lockPath(name)
+	if err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600) //nolint:gosec // G304: path is built from a validated workspace name
+	if err != nil {
+		return false, fmt.Errorf("lock workspace: %w", err)
+	}
+	defer f.Close()
+	if ok, err := tryLockExclusive(f); !ok {
+		return false, err
+	}
+	return true, os.RemoveAll(filepath.Join(w.dir, name))
+}
+
+// path returns the directory of a workspace, refusing names that would
+// leave the workspaces directory.
+func (w *Workspaces) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || workspaceUnsafe.MatchString(name) {
+		return "", fmt.Errorf("invalid workspace name %q", name)
+	}
+	return filepath.Join(w.dir, name), nil
+}
+
+// lockPath returns the lock file of a workspace. The suffix holds a
+// character workspace names can't, so it never clashes with a workspace.
+func (w *Workspaces) lockPath(name string) (string, error) {
+	path, err := w.path(name)
+	if err != nil {
+		return "", err
+	}
+	return path + "~lock", nil
+}
+
+// existing returns the directory of a workspace that must exist.
+func (w *Workspaces) existing(name string) (string, error) {
+	path, err := w.path(name)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("workspace %s not found", name)
+	}
+	return path, nil
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// DefaultSandboxIdle is how long a session's sandbox is kept without calls
+// before it is closed.
+const DefaultSandboxIdle = 30 * time.Minute
+
+// WorkspaceSandboxConfig configures a WorkspaceSandbox.
+type WorkspaceSandboxConfig struct {
+	Workspaces *Workspaces // Required
+
+	// Key returns the session a call belongs to, such as telegram:12345.
+	// Default: every call shares DefaultWorkspace.
+	Key func(ctx context.Context) string
+
+	// New creates a sandbox whose working directory is dir. Required.
+	New func(ctx context.Context, dir string) (Sandbox, error)
+
+	// IdleTimeout closes a session's sandbox after this long without
+	// calls; the next call creates a new one. Default: DefaultSandboxIdle.
+	IdleTimeout time.Duration
+}
+
+// WorkspaceSandbox runs each session's commands in a sandbox rooted at
+// the session's persistent workspace, so files written by one call, such
+// as installed dependencies or build output, are there for the next.
+// Sandboxes are created on a session's first call and reused until the
+// session is released or idle for IdleTimeout. Workspaces stay locked
+// while their sandbox is open.
+type WorkspaceSandbox struct {
+	config WorkspaceSandboxConfig
+	stop   chan struct{}
+	closed sync.Once
+
+	mu        sync.Mutex
+	sandboxes map[string]*workspaceEntry // By workspace name
+}
+
+// workspaceEntry is a session's sandbox. Fields other than ready are
+// guarded by the WorkspaceSandbox's mu once ready is closed.
+type workspaceEntry struct {
+	ready    chan struct{} // Closed once sb or err is set
+	sb       Sandbox
+	unlock   func() error
+	err      error
+	active   int       // Calls using sb, including its creation
+	lastUsed time.Time // When the last call finished
+	released bool      // Close sb when the last call finishes
+}
+
+// close closes the entry's sandbox and unlocks its workspace.
+func (e *workspaceEntry) close() error {
+	return errors.Join(e.sb.Close(), e.unlock())
+}
+
+// NewWorkspaceSandbox creates a WorkspaceSandbox.
+func NewWorkspaceSandbox(config WorkspaceSandboxConfig) (*WorkspaceSandbox, error) {
+	if config.Workspaces == nil || config.New == nil {
+		return nil, errors.New("workspace sandbox: workspaces and New required")
+	}
+	if config.Key == nil {
+		config.Key = func(context.Context) string { return "" }
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultSandboxIdle
+	}
+	w := &WorkspaceSandbox{
+		config:    config,
+		stop:      make(chan struct{}),
+		sandboxes: make(map[string]*workspaceEntry),
+	}
+	go w.evictLoop()
+	return w, nil
+}
+
+// Run executes a command in the session's workspace.
+func (w *WorkspaceSandbox) Run(ctx context.Context, command string, args []string) (*Result, error) {
+	sb, done, err := w.sandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return sb.Run(ctx, command, args)
+}
+
+// RunShell executes a shell command in the session's workspace.
+func (w *WorkspaceSandbox) RunShell(ctx context.Context, shellCommand string) (*Result, error) {
+	sb, done, err := w.sandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return sb.RunShell(ctx, shellCommand)
+}
+
+// RunStream executes a command in the session's workspace, streaming its
+// output when the session's sandbox supports it.
+func (w *WorkspaceSandbox) RunStream(ctx context.Context, command string, args []string, stdout, stderr io.Writer) (*Result, error) {
+	sb, done, err := w.sandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if s, ok := sb.(Streamer); ok {
+		return s.RunStream(ctx, command, args, stdout, stderr)
+	}
+	return sb.Run(ctx, command, args)
+}
+
+// RunShellStream executes a shell command in the session's workspace,
+// streaming its output when the session's sandbox supports it.
+func (w *WorkspaceSandbox) RunShellStream(ctx context.Context, shellCommand string, stdout, stderr io.Writer) (*Result, error) {
+	sb, done, err := w.sandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if s, ok := sb.(Streamer); ok {
+		return s.RunShellStream(ctx, shellCommand, stdout, stderr)
+	}
+	return sb.RunShell(ctx, shellCommand)
+}
+
+// RunWithStdin executes a command with stdin input in the session's
+// workspace.
+func (w *WorkspaceSandbox) RunWithStdin(ctx context.Context, stdin []byte, command string, args []string) (*Result, error) {
+	sb, done, err := w.sandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return sb.RunWithStdin(ctx, stdin, command, args)
+}
+
+// Release closes the sandbox of the session with the given key, once its
+// running calls finish. The workspace is kept.
+func (w *WorkspaceSandbox) Release(key string) error {
+	name := WorkspaceName(key)
+	w.mu.Lock()
+	e, ok := w.sandboxes[name]
+	if !ok {
+		w.mu.Unlock()
+		return nil
+	}
+	delete(w.sandboxes, name)
+	e.released = true
+	idle := e.active == 0
+	w.mu.Unlock()
+	if idle {
+		return e.close()
+	}
+	return nil
+}
+
+// Close closes the sandboxes of all sessions, those in use once their
+// calls finish. Workspaces are kept.
+func (w *WorkspaceSandbox) Close() error {
+	w.closed.Do(func() { close(w.stop) })
+	w.mu.Lock()
+	var idle []*workspaceEntry
+	for name, e := range w.sandboxes {
+		delete(w.sandboxes, name)
+		e.released = true
+		if e.active == 0 {
+			idle = append(idle, e)
+		}
+	}
+	w.mu.Unlock()
+	var errs []error
+	for _, e := range idle {
+		errs = append(errs, e.close())
+	}
+	return errors.Join(errs...)
+}
+
+// sandbox returns the sandbox of the call's session, creating it on the
+// first call, and a done func to call once the call finishes. Sandboxes
+// are created outside the lock, so a slow start holds up only calls of
+// the same session. The workspace is recreated if it was removed.
+func (w *WorkspaceSandbox) sandbox(ctx context.Context) (Sandbox, func(), error) {
+	name := WorkspaceName(w.config.Key(ctx))
+
+	w.mu.Lock()
+	e, ok := w.sandboxes[name]
+	if !ok {
+		e = &workspaceEntry{ready: make(chan struct{})}
+		w.sandboxes[name] = e
+	}
+	e.active++
+	w.mu.Unlock()
+	done := func() { w.done(e) }
+
+	if !ok {
+		e.sb, e.unlock, e.err = w.create(ctx, name)
+		if e.err != nil {
+			// Let the next call try again
+			w.mu.Lock()
+			if w.sandboxes[name] == e {
+				delete(w.sandboxes, name)
+			}
+			w.mu.Unlock()
+		}
+		close(e.ready)
+	}
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		done()
+		return nil, nil, ctx.Err()
+	}
+	if e.err != nil {
+		done()
+		return nil, nil, e.err
+	}
+	if _, err := w.config.Workspaces.Open(name); err != nil {
+		done()
+		return nil, nil, err
+	}
+	return e.sb, done, nil
+}
+
+// create locks and opens a workspace and starts its sandbox.
+func (w *WorkspaceSandbox) create(ctx context.Context, name string) (Sandbox, func() error, error) {
+	unlock, err := w.config.Workspaces.Lock(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, err := w.config.Workspaces.Open(name)
+	if err != nil {
+		_ = unlock()
+		return nil, nil, err
+	}
+	sb, err := w.config.New(ctx, dir)
+	if err == nil && sb == nil {
+		err = errors.New("no sandbox configured")
+	}
+	if err != nil {
+		_ = unlock()
+		return nil, nil, fmt.Errorf("workspace %s: %w", name, err)
+	}
+	return sb, unlock, nil
+}
+
+// done ends a call, closing a released sandbox once no calls use it.
+// Calls that stop waiting leave the creating call counted, so the entry
+// is ready whenever active drops to zero.
+func (w *WorkspaceSandbox) done(e *workspaceEntry) {
+	w.mu.Lock()
+	e.active--
+	e.lastUsed = time.Now()
+	closing := e.active == 0 && e.released && e.err == nil
+	w.mu.Unlock()
+	if closing {
+		_ = e.close()
+	}
+}
+
+// evictLoop closes idle sandboxes until the WorkspaceSandbox is closed.
+func (w *WorkspaceSandbox) evictLoop() {
+	ticker := time.NewTicker(w.config.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			w.evictIdle(now)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// evictIdle closes the sandboxes no call has used since IdleTimeout
+// before now.
+func (w *WorkspaceSandbox) evictIdle(now time.Time) {
+	w.mu.Lock()
+	var idle []*workspaceEntry
+	for name, e := range w.sandboxes {
+		if e.active == 0 && now.Sub(e.lastUsed) > w.config.IdleTimeout {
+			delete(w.sandboxes, name)
+			idle = append(idle, e)
+		}
+	}
+	w.mu.Unlock()
+	for _, e := range idle {
+		_ = e.close()
+	}
+}
@@ -0,0 +1,522 @@
+// Package scheduler runs recurring agent prompts and tool invocations,
+// such as digests, reminders, and workflows, on cron and interval
+// schedules. Tasks and their run state persist in SQLite.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a task does not exist.
+	ErrNotFound = errors.New("task not found")
+
+	// ErrRunning is returned when running a task that has not finished
+	// its previous run.
+	ErrRunning = errors.New("task is already running")
+)
+
+// Task is a scheduled prompt or tool invocation.
+type Task struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Schedule string `json:"schedule,omitempty"` // Cron expression, e.g. "0 8 * * *"
+
+	// At runs the task once at this time instead of on Schedule.
+	// One-time tasks are removed once they start.
+	At time.Time `json:"at,omitempty"`
+
+	// Prompt is sent to the agent. Ignored when Tool is set.
+	Prompt string `json:"prompt,omitempty"`
+
+	// Tool invokes a tool directly with ToolArgs instead of prompting.
+	Tool     string          `json:"tool,omitempty"`
+	ToolArgs json.RawMessage `json:"tool_args,omitempty"`
+
+	// Message is delivered to Channel and ChatID as is, without the agent.
+	Message string `json:"message,omitempty"`
+
+	// Trigger runs a named task (see package tasks), which delivers its
+	// own reply.
+	Trigger string `json:"trigger,omitempty"`
+
+	// Channel and ChatID select where the result is delivered
+	// (e.g., "telegram" and a chat ID).
+	Channel string `json:"channel,omitempty"`
+	ChatID  string `json:"chat_id,omitempty"`
+
+	// Session is the session that created the task from a chat, if any.
+	// Tools only show and cancel a session's own tasks.
+	Session string `json:"session,omitempty"`
+
+	// Run is called instead of the handler. It is set by code for built-in
+	// jobs and is not persisted.
+	Run func(ctx context.Context) error `json:"-"`
+
+	// Jitter delays each run of a recurring task by a random duration up
+	// to this long, so tasks on the same schedule don't all start at once.
+	// Zero uses the scheduler's default.
+	Jitter time.Duration `json:"jitter,omitempty"`
+
+	// Paused tasks are kept but don't run until resumed.
+	Paused bool `json:"paused,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	NextRun   time.Time `json:"next_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+
+	// Running reports a run in progress. A recurring task that is still
+	// running when it comes due again skips that run.
+	Running bool `json:"running,omitempty"`
+}
+
+// Handler runs a due task.
+type Handler func(ctx context.Context, task Task) error
+
+// Config configures the scheduler.
+type Config struct {
+	// Handler runs due tasks. Required.
+	Handler Handler
+
+	// Location is the time zone for cron expressions (default: local).
+	Location *time.Location
+
+	// Path is a SQLite database persisting tasks added at runtime and the
+	// run state of every task, so they survive restarts. Empty keeps them
+	// in memory.
+	Path string
+
+	// LegacyPath is a JSON file of one-time tasks from earlier versions.
+	// Its tasks are imported once and the file is renamed.
+	LegacyPath string
+
+	// Jitter is the default for tasks without their own.
+	Jitter time.Duration
+
+	Logger *slog.Logger
+}
+
+// Scheduler runs tasks on cron schedules.
+type Scheduler struct {
+	config  Config
+	tasks   map[string]*entry
+	db      *store
+	saved   map[string]Task // Persisted state of defined tasks not yet defined again
+	mu      sync.Mutex
+	wake    chan struct{}
+	logger  *slog.Logger
+	now     func() time.Time
+	running sync.WaitGroup
+	runCtx  context.Context
+}
+
+// entry pairs a task with its parsed schedule. One-time tasks have no
+// schedule.
+type entry struct {
+	task     Task
+	schedule Schedule
+	active   bool // A run is in progress
+
+	// Defined tasks persist only their state; transient ones, added at
+	// runtime with a Run function, don't persist.
+	defined   bool
+	transient bool
+}
+
+// New creates a scheduler.
+func New(config Config) (*Scheduler, error) {
+	if config.Handler == nil {
+		return nil, fmt.Errorf("scheduler handler required")
+	}
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	s := &Scheduler{
+		config: config,
+		tasks:  make(map[string]*entry),
+		saved:  make(map[string]Task),
+		wake:   make(chan struct{}, 1),
+		logger: config.Logger,
+		now:    time.Now,
+	}
+	if config.Path != "" {
+		db, err := openStore(config.Path)
+		if err != nil {
+			return nil, err
+		}
+		s.db = db
+		if err := s.load(); err != nil {
+			_ = db.close()
+			return nil, err
+		}
+	}
+	if config.LegacyPath != "" {
+		if err := s.importLegacy(config.LegacyPath); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Close closes the task database.
+func (s *Scheduler) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.close()
+}
+
+// Add validates and schedules a task created at runtime, such as through
+// the gateway or a tool. A missing ID is generated. Tasks without a Run
+// function are persisted with their state.
+func (s *Scheduler) Add(task Task) (Task, error) {
+	return s.add(task, false)
+}
+
+// Define schedules a task defined by config or code, which is added again
+// on every start. It needs a stable ID, under which its run state and
+// whether it is paused persist; the task itself is not.
+func (s *Scheduler) Define(task Task) (Task, error) {
+	if task.ID == "" {
+		return Task{}, fmt.Errorf("defined tasks require an ID")
+	}
+	return s.add(task, true)
+}
+
+// add validates and schedules a task.
+func (s *Scheduler) add(task Task, defined bool) (Task, error) {
+	if task.Prompt == "" && task.Tool == "" && task.Message == "" && task.Trigger == "" && task.Run == nil {
+		return Task{}, fmt.Errorf("task requires a prompt, tool, message, trigger, or run function")
+	}
+	if task.Message != "" && task.Channel == "" {
+		return Task{}, fmt.Errorf("message tasks require a channel")
+	}
+	if task.Jitter < 0 {
+		return Task{}, fmt.Errorf("task jitter cannot be negative")
+	}
+
+	var sched Schedule
+	if task.At.IsZero() {
+		var err error
+		if sched, err = Parse(task.Schedule, s.config.Location); err != nil {
+			return Task{}, err
+		}
+	} else if task.Schedule != "" {
+		return Task{}, fmt.Errorf("task cannot have both a schedule and a time")
+	}
+	if task.ID == "" {
+		task.ID = newID()
+	}
+
+	now := s.now()
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = now
+	}
+	task.Running = false
+
+	s.mu.Lock()
+	if saved, ok := s.saved[task.ID]; ok && defined {
+		task.LastRun, task.LastError, task.Paused = saved.LastRun, saved.LastError, saved.Paused
+		delete(s.saved, task.ID)
+	}
+	e := &entry{task: task, schedule: sched, defined: defined, transient: task.Run != nil && !defined}
+	e.task.NextRun = s.next(e, now)
+	if old, ok := s.tasks[task.ID]; ok {
+		e.active = old.active
+	}
+	s.tasks[task.ID] = e
+	s.persist(e)
+	task = e.task
+	s.mu.Unlock()
+
+	s.logger.Info("task scheduled", "id", task.ID, "name", task.Name,
+		"schedule", task.Schedule, "next_run", task.NextRun, "paused", task.Paused)
+	s.notify()
+	return task, nil
+}
+
+// Remove deletes a task.
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.tasks, id)
+	s.unpersist(id)
+	s.logger.Info("task removed", "id", id)
+	return nil
+}
+
+// Pause stops a task from running until it is resumed.
+func (s *Scheduler) Pause(id string) (Task, error) {
+	return s.setPaused(id, true)
+}
+
+// Resume lets a paused task run again from its next scheduled time. A
+// one-time task whose time has passed runs right away.
+func (s *Scheduler) Resume(id string) (Task, error) {
+	return s.setPaused(id, false)
+}
+
+// setPaused pauses or resumes a task.
+func (s *Scheduler) setPaused(id string, paused bool) (Task, error) {
+	s.mu.Lock()
+	e, ok := s.tasks[id]
+	if !ok {
+		s.mu.Unlock()
+		return Task{}, ErrNotFound
+	}
+	if e.task.Paused != paused {
+		e.task.Paused = paused
+		if !paused {
+			e.task.NextRun = s.next(e, s.now())
+		}
+		s.persist(e)
+	}
+	task := s.view(e)
+	s.mu.Unlock()
+
+	if paused {
+		s.logger.Info("task paused", "id", id, "name", task.Name)
+	} else {
+		s.logger.Info("task resumed", "id", id, "name", task.Name, "next_run", task.NextRun)
+	}
+	s.notify()
+	return task, nil
+}
+
+// RunNow starts a task immediately, in addition to its schedule. Paused
+// tasks run too; a one-time task is removed as when it comes due. It
+// returns ErrRunning if the task's previous run has not finished.
+func (s *Scheduler) RunNow(id string) (Task, error) {
+	s.mu.Lock()
+	e, ok := s.tasks[id]
+	if !ok {
+		s.mu.Unlock()
+		return Task{}, ErrNotFound
+	}
+	if e.active {
+		s.mu.Unlock()
+		return Task{}, ErrRunning
+	}
+	ctx := s.runCtx
+	if ctx == nil {
+		s.mu.Unlock()
+		return Task{}, fmt.Errorf("scheduler is not running")
+	}
+	task := s.begin(e, s.now())
+	s.mu.Unlock()
+
+	s.logger.Info("task run requested", "id", id, "name", task.Name)
+	s.start(ctx, task)
+	return task, nil
+}
+
+// Get returns a task by ID.
+func (s *Scheduler) Get(id string) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.tasks[id]
+	if !ok {
+		return Task{}, ErrNotFound
+	}
+	return s.view(e), nil
+}
+
+// List returns all tasks ordered by next run time.
+func (s *Scheduler) List() []Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]Task, 0, len(s.tasks))
+	for _, e := range s.tasks {
+		tasks = append(tasks, s.view(e))
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].NextRun.Before(tasks[j].NextRun) })
+	return tasks
+}
+
+// Run executes due tasks until the context is canceled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	s.runCtx = ctx
+	s.mu.Unlock()
+	s.logger.Info("scheduler started", "tasks", len(s.List()))
+	defer s.running.Wait()
+
+	for {
+		timer := time.NewTimer(s.untilNext())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+			s.runDue(ctx)
+		}
+	}
+}
+
+// untilNext returns how long to sleep before the next due task.
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := time.Hour
+	now := s.now()
+	for _, e := range s.tasks {
+		if e.task.Paused || e.task.NextRun.IsZero() {
+			continue
+		}
+		if d := e.task.NextRun.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// runDue starts every task whose next run time has passed. A recurring
+// task whose previous run is still going skips this run.
+func (s *Scheduler) runDue(ctx context.Context) {
+	now := s.now()
+
+	s.mu.Lock()
+	var due []Task
+	for _, e := range s.tasks {
+		if e.task.Paused || e.task.NextRun.IsZero() || e.task.NextRun.After(now) {
+			continue
+		}
+		if e.active {
+			e.task.NextRun = s.next(e, now)
+			s.logger.Warn("scheduled task skipped, previous run still running", "id", e.task.ID, "name", e.task.Name)
+			continue
+		}
+		due = append(due, s.begin(e, now))
+	}
+	s.mu.Unlock()
+
+	for _, task := range due {
+		s.start(ctx, task)
+	}
+}
+
+// begin marks a task as running and returns it. One-time tasks are
+// removed. Callers must hold the lock.
+func (s *Scheduler) begin(e *entry, now time.Time) Task {
+	if e.schedule == nil {
+		delete(s.tasks, e.task.ID)
+		s.unpersist(e.task.ID)
+		task := e.task
+		task.LastRun = now
+		return task
+	}
+	e.active = true
+	e.task.LastRun = now
+	e.task.NextRun = s.next(e, now)
+	s.persist(e)
+	return e.task
+}
+
+// start runs a task in the background.
+func (s *Scheduler) start(ctx context.Context, task Task) {
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.execute(ctx, task)
+	}()
+}
+
+// execute runs a task and records the outcome.
+func (s *Scheduler) execute(ctx context.Context, task Task) {
+	s.logger.Info("running scheduled task", "id", task.ID, "name", task.Name)
+
+	var err error
+	if task.Run != nil {
+		err = task.Run(ctx)
+	} else {
+		err = s.config.Handler(ctx, task)
+	}
+	if err != nil {
+		s.logger.Error("scheduled task failed", "id", task.ID, "name", task.Name, "error", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.tasks[task.ID]; ok {
+		e.active = false
+		e.task.LastError = ""
+		if err != nil {
+			e.task.LastError = err.Error()
+		}
+		s.persist(e)
+	}
+}
+
+// next returns a task's next run time after now, with jitter for
+// recurring tasks, or the zero time if it never runs again.
+func (s *Scheduler) next(e *entry, now time.Time) time.Time {
+	if e.schedule == nil {
+		return e.task.At
+	}
+	next := e.schedule.Next(now)
+	if next.IsZero() {
+		return next
+	}
+	jitter := e.task.Jitter
+	if jitter == 0 {
+		jitter = s.config.Jitter
+	}
+	if jitter > 0 {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(jitter)))
+		if err == nil {
+			next = next.Add(time.Duration(n.Int64()))
+		}
+	}
+	return next
+}
+
+// view returns a copy of a task with its running state. Callers must hold
+// the lock.
+func (s *Scheduler) view(e *entry) Task {
+	task := e.task
+	task.Running = e.active
+	return task
+}
+
+// notify wakes the run loop to recompute its timer.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// newID returns a random task ID.
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
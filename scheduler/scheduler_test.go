@@ -0,0 +1,317 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseNext(t *testing.T) {
+	loc := time.UTC
+	base := time.Date(2026, 3, 10, 7, 30, 0, 0, loc) // Tuesday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 8 * * *", time.Date(2026, 3, 10, 8, 0, 0, 0, loc)},
+		{"*/15 * * * *", time.Date(2026, 3, 10, 7, 45, 0, 0, loc)},
+		{"0 9 * * 1-5", time.Date(2026, 3, 10, 9, 0, 0, 0, loc)},
+		{"0 9 * * 6,7", time.Date(2026, 3, 14, 9, 0, 0, 0, loc)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, loc)},
+		{"@daily", time.Date(2026, 3, 11, 0, 0, 0, 0, loc)},
+		{"@hourly", time.Date(2026, 3, 10, 8, 0, 0, 0, loc)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, loc)},
+		{"@every 90m", time.Date(2026, 3, 10, 9, 0, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			sched, err := Parse(tt.expr, loc)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			if got := sched.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "@every 10ms", "a b c d e", "0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		if _, err := Parse(expr, time.UTC); err == nil {
+			t.Errorf("Parse(%q) should fail", expr)
+		}
+	}
+}
+
+func TestSchedulerAddRemove(t *testing.T) {
+	s, err := New(Config{Handler: func(context.Context, Task) error { return nil }})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := s.Add(Task{Schedule: "0 8 * * *"}); err == nil {
+		t.Error("Add() should fail without prompt or tool")
+	}
+	if _, err := s.Add(Task{Schedule: "bad", Prompt: "hi"}); err == nil {
+		t.Error("Add() should fail with invalid schedule")
+	}
+
+	task, err := s.Add(Task{Name: "digest", Schedule: "0 8 * * *", Prompt: "news digest"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if task.ID == "" || task.NextRun.IsZero() {
+		t.Errorf("Add() = %+v, want ID and NextRun set", task)
+	}
+	if len(s.List()) != 1 {
+		t.Errorf("List() = %d tasks, want 1", len(s.List()))
+	}
+
+	if err := s.Remove(task.ID); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+	if err := s.Remove(task.ID); err != ErrNotFound {
+		t.Errorf("Remove() again error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSchedulerRunsDueTasks(t *testing.T) {
+	var runs atomic.Int32
+	s, err := New(Config{Handler: func(context.Context, Task) error {
+		runs.Add(1)
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := s.Add(Task{Schedule: "@every 1s", Prompt: "tick"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	_ = s.Run(ctx)
+
+	if n := runs.Load(); n < 1 {
+		t.Errorf("task ran %d times, want at least 1", n)
+	}
+}
+
+func TestSchedulerOneTimeTasks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduler.db")
+	ran := make(chan Task, 1)
+	handler := func(_ context.Context, task Task) error {
+		ran <- task
+		return nil
+	}
+
+	s, err := New(Config{Handler: handler, Path: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := s.Add(Task{At: time.Now().Add(time.Hour), Message: "hi"}); err == nil {
+		t.Error("Add() should fail for a message without a channel")
+	}
+	if _, err := s.Add(Task{At: time.Now(), Schedule: "@daily", Prompt: "hi"}); err == nil {
+		t.Error("Add() should fail with both a schedule and a time")
+	}
+	task, err := s.Add(Task{At: time.Now().Add(200 * time.Millisecond), Message: "hi", Channel: "telegram", ChatID: "42"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// Pending one-time tasks survive a restart
+	s, err = New(Config{Handler: handler, Path: path})
+	if err != nil {
+		t.Fatalf("New() reload error = %v", err)
+	}
+	if got, err := s.Get(task.ID); err != nil || got.Message != "hi" {
+		t.Fatalf("Get() after reload = %+v, %v", got, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go func() { _ = s.Run(ctx) }()
+
+	select {
+	case got := <-ran:
+		if got.ID != task.ID {
+			t.Errorf("ran task %s, want %s", got.ID, task.ID)
+		}
+	case <-ctx.Done():
+		t.Fatal("one-time task did not run")
+	}
+	if len(s.List()) != 0 {
+		t.Errorf("List() = %d tasks after run, want 0", len(s.List()))
+	}
+	s, err = New(Config{Handler: handler, Path: path})
+	if err != nil {
+		t.Fatalf("New() reload error = %v", err)
+	}
+	if _, err := s.Get(task.ID); err != ErrNotFound {
+		t.Errorf("Get() after run and reload error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSchedulerLegacyImport(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "scheduled.json")
+	at := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	data := `[{"id":"abc","at":"` + at.Format(time.RFC3339) + `","message":"hi","channel":"telegram","chat_id":"42"}]`
+	if err := os.WriteFile(legacy, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	handler := func(context.Context, Task) error { return nil }
+	s, err := New(Config{Handler: handler, Path: filepath.Join(dir, "scheduler.db"), LegacyPath: legacy})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got, err := s.Get("abc"); err != nil || !got.At.Equal(at) {
+		t.Errorf("Get() = %+v, %v; want imported task", got, err)
+	}
+	if _, err := os.Stat(legacy + ".imported"); err != nil {
+		t.Errorf("legacy file not renamed: %v", err)
+	}
+}
+
+func TestSchedulerControls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduler.db")
+	release := make(chan struct{})
+	var runs atomic.Int32
+	handler := func(ctx context.Context, _ Task) error {
+		runs.Add(1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+
+	s, err := New(Config{Handler: handler, Path: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := s.Define(Task{Schedule: "@daily", Prompt: "digest"}); err == nil {
+		t.Error("Define() should require an ID")
+	}
+	if _, err := s.Define(Task{ID: "digest:news", Schedule: "@daily", Prompt: "digest"}); err != nil {
+		t.Fatalf("Define() error = %v", err)
+	}
+	if _, err := s.RunNow("digest:news"); err == nil {
+		t.Error("RunNow() should fail before the scheduler runs")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = s.Run(ctx)
+		close(done)
+	}()
+	waitFor(t, func() bool {
+		_, err := s.RunNow("digest:news")
+		return err == nil
+	})
+	waitFor(t, func() bool { return runs.Load() == 1 })
+	if task, _ := s.Get("digest:news"); !task.Running || task.LastRun.IsZero() {
+		t.Errorf("Get() = %+v, want running", task)
+	}
+
+	// Overlapping runs are refused
+	if _, err := s.RunNow("digest:news"); err != ErrRunning {
+		t.Errorf("RunNow() while running error = %v, want ErrRunning", err)
+	}
+	close(release)
+	waitFor(t, func() bool {
+		task, _ := s.Get("digest:news")
+		return !task.Running
+	})
+
+	if task, err := s.Pause("digest:news"); err != nil || !task.Paused {
+		t.Fatalf("Pause() = %+v, %v", task, err)
+	}
+	if _, err := s.Pause("missing"); err != ErrNotFound {
+		t.Errorf("Pause(missing) error = %v, want ErrNotFound", err)
+	}
+	cancel()
+	<-done
+	_ = s.Close()
+
+	// Defined tasks get their state back when defined again
+	s, err = New(Config{Handler: handler, Path: path})
+	if err != nil {
+		t.Fatalf("New() reload error = %v", err)
+	}
+	if len(s.List()) != 0 {
+		t.Errorf("List() = %+v, want defined tasks not restored on their own", s.List())
+	}
+	task, err := s.Define(Task{ID: "digest:news", Schedule: "@daily", Prompt: "digest"})
+	if err != nil || !task.Paused || task.LastRun.IsZero() {
+		t.Errorf("Define() after reload = %+v, %v; want paused with last run", task, err)
+	}
+	if task, err := s.Resume("digest:news"); err != nil || task.Paused || task.NextRun.IsZero() {
+		t.Errorf("Resume() = %+v, %v", task, err)
+	}
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	s, err := New(Config{Handler: func(context.Context, Task) error { return nil }, Jitter: time.Hour})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	base := time.Date(2026, 3, 10, 7, 30, 0, 0, time.UTC)
+	s.now = func() time.Time { return base }
+	if _, err := s.Add(Task{Schedule: "@every 1h", Prompt: "hi", Jitter: -time.Second}); err == nil {
+		t.Error("Add() should reject negative jitter")
+	}
+	for range 5 {
+		task, err := s.Add(Task{Schedule: "@every 1h", Prompt: "hi"})
+		if err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if d := task.NextRun.Sub(base); d < time.Hour || d >= 2*time.Hour {
+			t.Errorf("NextRun = %v after now, want within the jitter", d)
+		}
+	}
+}
+
+// neverSchedule never fires.
+type neverSchedule struct{}
+
+func (neverSchedule) Next(time.Time) time.Time { return time.Time{} }
+
+func TestSchedulerNeverDue(t *testing.T) {
+	s, err := New(Config{Handler: func(context.Context, Task) error { return nil }, Jitter: time.Hour})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	e := &entry{task: Task{ID: "never"}, schedule: neverSchedule{}}
+	if next := s.next(e, time.Now()); !next.IsZero() {
+		t.Errorf("next() = %v, want zero time without jitter", next)
+	}
+	s.tasks[e.task.ID] = e
+	if wait := s.untilNext(); wait != time.Hour {
+		t.Errorf("untilNext() = %v, want an hour", wait)
+	}
+	if _, err := Parse("0 0 29 2 *", time.UTC); err != nil {
+		t.Errorf("Parse(Feb 29) error = %v", err)
+	}
+}
+
+// waitFor polls cond until it is true or a second passes.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver
+)
+
+// store persists tasks in SQLite.
+type store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	id      TEXT PRIMARY KEY,
+	defined INTEGER NOT NULL, -- 1 if only the state is restored
+	task    TEXT NOT NULL     -- JSON
+)`
+
+// openStore opens (or creates) a task database at path.
+func openStore(path string) (*store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("create scheduler dir: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open scheduler database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create scheduler schema: %w", err)
+	}
+	return &store{db: db}, nil
+}
+
+func (st *store) close() error {
+	return st.db.Close()
+}
+
+// load restores persisted tasks, and the state of defined tasks for when
+// they are defined again. One-time tasks that came due while the
+// scheduler was stopped run as soon as it starts; recurring ones resume
+// from their next scheduled time.
+func (s *Scheduler) load() error {
+	rows, err := s.db.db.Query(`SELECT defined, task FROM tasks`)
+	if err != nil {
+		return fmt.Errorf("read tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var restore []Task
+	for rows.Next() {
+		var defined bool
+		var data []byte
+		if err := rows.Scan(&defined, &data); err != nil {
+			return fmt.Errorf("read tasks: %w", err)
+		}
+		var task Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			return fmt.Errorf("parse task: %w", err)
+		}
+		if defined {
+			s.saved[task.ID] = task
+			continue
+		}
+		restore = append(restore, task)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read tasks: %w", err)
+	}
+
+	for _, task := range restore {
+		if _, err := s.add(task, false); err != nil {
+			s.logger.Warn("dropping invalid persisted task", "id", task.ID, "error", err)
+			s.mu.Lock()
+			s.unpersist(task.ID)
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// importLegacy imports one-time tasks from an earlier version's JSON file
+// and renames the file so they are imported only once.
+func (s *Scheduler) importLegacy(path string) error {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read tasks: %w", err)
+	}
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return fmt.Errorf("parse tasks: %w", err)
+	}
+	for _, task := range tasks {
+		if task.ID == "" || task.At.IsZero() {
+			continue
+		}
+		if _, err := s.add(task, false); err != nil {
+			s.logger.Warn("skipping invalid task", "id", task.ID, "error", err)
+		}
+	}
+	if err := os.Rename(path, path+".imported"); err != nil {
+		return fmt.Errorf("rename imported tasks: %w", err)
+	}
+	s.logger.Info("imported scheduled tasks", "path", path, "tasks", len(tasks))
+	return nil
+}
+
+// persist saves a task and its state. Callers must hold the lock.
+// Failures are logged; the task stays scheduled in memory.
+func (s *Scheduler) persist(e *entry) {
+	if s.db == nil || e.transient {
+		return
+	}
+	task := s.view(e)
+	task.Running = false
+	data, err := json.Marshal(task)
+	if err == nil {
+		_, err = s.db.db.Exec(`INSERT INTO tasks (id, defined, task) VALUES (?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET defined = excluded.defined, task = excluded.task`,
+			task.ID, e.defined, data)
+	}
+	if err != nil {
+		s.logger.Error("save task failed", "id", task.ID, "error", err)
+	}
+}
+
+// unpersist deletes a task. Callers must hold the lock.
+func (s *Scheduler) unpersist(id string) {
+	if s.db == nil {
+		return
+	}
+	if _, err := s.db.db.Exec(`DELETE FROM tasks WHERE id = ?`, id); err != nil {
+		s.logger.Error("delete task failed", "id", id, "error", err)
+	}
+}
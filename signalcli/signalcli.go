@@ -0,0 +1,427 @@
+// Package signalcli provides a Signal channel backed by the signal-cli
+// daemon's HTTP JSON-RPC interface (signal-cli daemon --http).
+package signalcli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/supervisor"
+)
+
+// Name is the channel name.
+const Name = "signal"
+
+// groupPrefix marks group chat IDs; direct chats use the sender's number.
+const groupPrefix = "group:"
+
+// maxAttachmentBytes caps attachments downloaded from the daemon.
+const maxAttachmentBytes = 25 << 20
+
+// Config configures the Signal channel.
+type Config struct {
+	// URL is the signal-cli HTTP daemon, e.g. http://127.0.0.1:8080.
+	URL string
+
+	// Account is the registered number to use when the daemon serves
+	// several accounts.
+	Account string
+
+	// AttachmentsDir is signal-cli's attachments directory. When set,
+	// attachments are read from disk instead of fetched over JSON-RPC.
+	AttachmentsDir string
+
+	HTTPClient *http.Client
+	Logger     *slog.Logger
+}
+
+// Provider is a Signal channel.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	logger   *slog.Logger
+	handlers []provider.MessageHandler
+	mu       sync.RWMutex
+	nextID   atomic.Int64
+	group    *supervisor.Group // Runs the event stream and message handlers
+}
+
+// New creates a Signal channel.
+func New(config Config) (*Provider, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("signal-cli URL required")
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{}
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Provider{config: config, client: config.HTTPClient, logger: config.Logger}, nil
+}
+
+// Name returns the channel name.
+func (p *Provider) Name() string {
+	return Name
+}
+
+// Connect subscribes to the daemon's event stream in the background,
+// reconnecting until Disconnect.
+func (p *Provider) Connect(ctx context.Context) error {
+	// Fail fast when the daemon is unreachable
+	if err := p.call(ctx, "version", nil, nil); err != nil {
+		return fmt.Errorf("connect to signal-cli: %w", err)
+	}
+
+	p.group = supervisor.New(context.WithoutCancel(ctx), supervisor.Config{Logger: p.logger})
+	return p.group.Go("signal-events", func(ctx context.Context) error {
+		backoff := time.Second
+		for {
+			err := p.listen(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+			p.logger.Warn("signal event stream closed, reconnecting", "error", err, "in", backoff)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, time.Minute)
+		}
+	})
+}
+
+// Disconnect stops the event stream and waits, until ctx is done, for
+// in-flight message handlers.
+func (p *Provider) Disconnect(ctx context.Context) error {
+	if p.group == nil {
+		return nil
+	}
+	return p.group.Stop(ctx)
+}
+
+// OnMessage registers a handler for incoming messages.
+func (p *Provider) OnMessage(handler provider.MessageHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, handler)
+}
+
+// OnEvent is a no-op; only messages are forwarded.
+func (p *Provider) OnEvent(handler provider.EventHandler) {}
+
+// Send sends a message to a number or "group:<id>", passing media such as
+// voice replies as attachments.
+func (p *Provider) Send(ctx context.Context, chatID string, msg provider.OutgoingMessage) error {
+	params := map[string]any{"message": msg.Content}
+	if groupID, ok := strings.CutPrefix(chatID, groupPrefix); ok {
+		params["groupId"] = groupID
+	} else {
+		params["recipient"] = []string{chatID}
+	}
+
+	var attachments []string
+	for _, m := range msg.Media {
+		if len(m.Data) == 0 {
+			continue
+		}
+		name := m.Filename
+		if name == "" {
+			name = "attachment"
+		}
+		attachments = append(attachments, fmt.Sprintf("data:%s;filename=%s;base64,%s",
+			m.MimeType, name, base64.StdEncoding.EncodeToString(m.Data)))
+	}
+	if len(attachments) > 0 {
+		params["attachments"] = attachments
+	}
+	return p.call(ctx, "send", params, nil)
+}
+
+// listen reads the daemon's server-sent events until the stream ends.
+func (p *Provider) listen(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.URL+"/api/v1/events", nil)
+	if err != nil {
+		return err
+	}
+	if p.config.Account != "" {
+		q := req.URL.Query()
+		q.Set("account", p.config.Account)
+		req.URL.RawQuery = q.Encode()
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(req) //nolint:gosec // G704: daemon URL comes from config
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("events returned %s", resp.Status)
+	}
+	p.logger.Info("signal event stream connected")
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(v, " "))
+			continue
+		}
+		if line == "" && data.Len() > 0 {
+			p.handleEvent(ctx, []byte(data.String()))
+			data.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// envelope is the part of a signal-cli receive notification used here.
+type envelope struct {
+	Source       string `json:"source"`
+	SourceNumber string `json:"sourceNumber"`
+	SourceUUID   string `json:"sourceUuid"`
+	SourceName   string `json:"sourceName"`
+	Timestamp    int64  `json:"timestamp"`
+	DataMessage  *struct {
+		Timestamp   int64        `json:"timestamp"`
+		Message     string       `json:"message"`
+		Attachments []attachment `json:"attachments"`
+		GroupInfo   *struct {
+			GroupID string `json:"groupId"`
+		} `json:"groupInfo"`
+	} `json:"dataMessage"`
+}
+
+type attachment struct {
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	VoiceNote   bool   `json:"voiceNote"`
+}
+
+// handleEvent converts a receive notification to a message and runs the
+// handlers. Events other than data messages (receipts, typing) are
+// ignored.
+func (p *Provider) handleEvent(ctx context.Context, data []byte) {
+	var event struct {
+		Method   string          `json:"method"`
+		Params   json.RawMessage `json:"params"`
+		Envelope *envelope       `json:"envelope"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		p.logger.Warn("invalid signal event", "error", err)
+		return
+	}
+	// Events may arrive as bare params or wrapped in a JSON-RPC notification
+	if event.Envelope == nil && len(event.Params) > 0 {
+		var params struct {
+			Envelope *envelope `json:"envelope"`
+		}
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return
+		}
+		event.Envelope = params.Envelope
+	}
+	env := event.Envelope
+	if env == nil || env.DataMessage == nil {
+		return
+	}
+
+	msg, err := p.incoming(ctx, env)
+	if err != nil {
+		p.logger.Warn("dropping signal message", "error", err)
+		return
+	}
+
+	p.mu.RLock()
+	handlers := append([]provider.MessageHandler(nil), p.handlers...)
+	p.mu.RUnlock()
+	err = p.group.Spawn("signal-message", func(ctx context.Context) error {
+		for _, h := range handlers {
+			if err := h(ctx, msg); err != nil {
+				p.logger.Error("signal message handler failed", "chat", msg.ChatID, "error", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		p.logger.Warn("dropping signal message during shutdown", "chat", msg.ChatID)
+	}
+}
+
+// incoming converts an envelope to a message, downloading attachments so
+// voice notes can be transcribed.
+func (p *Provider) incoming(ctx context.Context, env *envelope) (provider.IncomingMessage, error) {
+	dm := env.DataMessage
+	sender := env.SourceNumber
+	if sender == "" {
+		sender = env.Source
+	}
+	if sender == "" {
+		sender = env.SourceUUID
+	}
+	if sender == "" {
+		return provider.IncomingMessage{}, errors.New("envelope has no sender")
+	}
+
+	msg := provider.IncomingMessage{
+		ID:           fmt.Sprintf("%d", dm.Timestamp),
+		ProviderName: Name,
+		ChatID:       sender,
+		ChatType:     provider.ChatTypeDM,
+		SenderID:     sender,
+		SenderName:   env.SourceName,
+		Content:      dm.Message,
+		Timestamp:    time.UnixMilli(env.Timestamp),
+	}
+	if dm.GroupInfo != nil && dm.GroupInfo.GroupID != "" {
+		msg.ChatID = groupPrefix + dm.GroupInfo.GroupID
+		msg.ChatType = provider.ChatTypeGroup
+	}
+
+	for _, a := range dm.Attachments {
+		data, err := p.attachment(ctx, a, msg.ChatID)
+		if err != nil {
+			p.logger.Warn("failed to fetch signal attachment", "id", a.ID, "error", err)
+			continue
+		}
+		msg.Media = append(msg.Media, provider.Media{
+			Type:     mediaType(a),
+			Data:     data,
+			MimeType: a.ContentType,
+			Filename: a.Filename,
+		})
+	}
+	return msg, nil
+}
+
+// attachment reads an attachment from the attachments directory or the
+// daemon.
+func (p *Provider) attachment(ctx context.Context, a attachment, chatID string) ([]byte, error) {
+	if a.Size > maxAttachmentBytes {
+		return nil, fmt.Errorf("attachment too large (%d bytes)", a.Size)
+	}
+	if p.config.AttachmentsDir != "" {
+		return os.ReadFile(filepath.Join(p.config.AttachmentsDir, filepath.Base(a.ID)))
+	}
+
+	params := map[string]any{"id": a.ID}
+	if groupID, ok := strings.CutPrefix(chatID, groupPrefix); ok {
+		params["groupId"] = groupID
+	} else {
+		params["recipient"] = chatID
+	}
+	var result struct {
+		Data string `json:"data"`
+	}
+	if err := p.call(ctx, "getAttachment", params, &result); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Data)
+}
+
+// mediaType maps an attachment to a media type. Voice notes are marked so
+// they are transcribed.
+func mediaType(a attachment) provider.MediaType {
+	switch {
+	case a.VoiceNote:
+		return provider.MediaTypeVoice
+	case strings.HasPrefix(a.ContentType, "audio/"):
+		return provider.MediaTypeAudio
+	case strings.HasPrefix(a.ContentType, "image/"):
+		return provider.MediaTypeImage
+	case strings.HasPrefix(a.ContentType, "video/"):
+		return provider.MediaTypeVideo
+	default:
+		return provider.MediaTypeDocument
+	}
+}
+
+// rpcError is a JSON-RPC error.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("signal-cli error %d: %s", e.Code, e.Message)
+}
+
+// call makes a JSON-RPC request and decodes the result into result, if
+// non-nil.
+func (p *Provider) call(ctx context.Context, method string, params map[string]any, result any) error {
+	if p.config.Account != "" {
+		if params == nil {
+			params = make(map[string]any)
+		}
+		params["account"] = p.config.Account
+	}
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      p.nextID.Add(1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL+"/api/v1/rpc", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req) //nolint:gosec // G704: daemon URL comes from config
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: daemon returned %s", method, resp.Status)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 2*maxAttachmentBytes)).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("%s: decode response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if result != nil && len(rpcResp.Result) > 0 {
+		if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+			return fmt.Errorf("%s: decode result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// Ensure Provider implements provider.Provider.
+var _ provider.Provider = (*Provider)(nil)
@@ -0,0 +1,118 @@
+package signalcli
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+// fakeDaemon serves the signal-cli HTTP API with one queued event.
+type fakeDaemon struct {
+	event string
+	mu    sync.Mutex
+	calls []map[string]any
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v1/events":
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event:receive\ndata:%s\n\n", d.event)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	case "/api/v1/rpc":
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		d.mu.Lock()
+		d.calls = append(d.calls, req)
+		d.mu.Unlock()
+		result := any(map[string]any{})
+		if req["method"] == "getAttachment" {
+			result = map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("OGG"))}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req["id"], "result": result})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (d *fakeDaemon) lastCall(method string) map[string]any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := len(d.calls) - 1; i >= 0; i-- {
+		if d.calls[i]["method"] == method {
+			return d.calls[i]
+		}
+	}
+	return nil
+}
+
+func TestReceiveAndSend(t *testing.T) {
+	daemon := &fakeDaemon{event: `{"envelope":{"sourceNumber":"+15551112222","sourceName":"Ann","timestamp":1700000000000,` +
+		`"dataMessage":{"timestamp":1700000000000,"message":"hi","attachments":[{"id":"a1.ogg","contentType":"audio/ogg","voiceNote":true,"size":3}]}},"account":"+15550000000"}`}
+	server := httptest.NewServer(daemon)
+	defer server.Close()
+
+	p, err := New(Config{URL: server.URL, Account: "+15550000000"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	received := make(chan provider.IncomingMessage, 1)
+	p.OnMessage(func(ctx context.Context, msg provider.IncomingMessage) error {
+		received <- msg
+		return nil
+	})
+
+	ctx := context.Background()
+	if err := p.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer func() { _ = p.Disconnect(ctx) }()
+
+	select {
+	case msg := <-received:
+		if msg.ChatID != "+15551112222" || msg.Content != "hi" || msg.SenderName != "Ann" {
+			t.Errorf("message = %+v", msg)
+		}
+		if len(msg.Media) != 1 || msg.Media[0].Type != provider.MediaTypeVoice || string(msg.Media[0].Data) != "OGG" {
+			t.Errorf("media = %+v, want voice note data", msg.Media)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	err = p.Send(ctx, "group:abc=", provider.OutgoingMessage{
+		Content: "reply",
+		Media:   []provider.Media{{Type: provider.MediaTypeVoice, Data: []byte("MP3"), MimeType: "audio/mpeg", Filename: "reply.mp3"}},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	params, _ := daemon.lastCall("send")["params"].(map[string]any)
+	if params["groupId"] != "abc=" || params["message"] != "reply" || params["account"] != "+15550000000" {
+		t.Errorf("send params = %v", params)
+	}
+	atts, _ := params["attachments"].([]any)
+	if len(atts) != 1 || !strings.HasPrefix(atts[0].(string), "data:audio/mpeg;filename=reply.mp3;base64,") {
+		t.Errorf("attachments = %v", atts)
+	}
+}
+
+func TestConnectFailsWithoutDaemon(t *testing.T) {
+	p, err := New(Config{URL: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := p.Connect(context.Background()); err == nil {
+		t.Error("Connect succeeded without a daemon")
+	}
+}
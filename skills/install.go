@@ -0,0 +1,293 @@
+package skills
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultRegistry is the ClawHub registry skills are installed from by
+// name.
+const DefaultRegistry = "https://clawhub.ai"
+
+// maxArchiveBytes bounds a skill archive downloaded from a registry.
+const maxArchiveBytes = 50 << 20
+
+// InstallOptions configures Install.
+type InstallOptions struct {
+	Dir      string       // Default: DefaultInstallDir()
+	Registry string       // Default: DefaultRegistry
+	Force    bool         // Replace an installed skill of the same name
+	Client   *http.Client // Default: http.DefaultClient
+}
+
+// DefaultInstallDir returns ~/.omniagent/skills, where skills are
+// installed.
+func DefaultInstallDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".omniagent", "skills")
+	}
+	return filepath.Join(home, ".omniagent", "skills")
+}
+
+// Install downloads a skill into the install directory. The source is a
+// registry skill name or a git repository URL. The skill's SKILL.md must
+// parse and name the skill; the skill is installed under that name.
+func Install(ctx context.Context, source string, opts InstallOptions) (*Skill, error) {
+	if opts.Dir == "" {
+		opts.Dir = DefaultInstallDir()
+	}
+	if opts.Registry == "" {
+		opts.Registry = DefaultRegistry
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if err := os.MkdirAll(opts.Dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	// Stage in the install directory so the final rename stays on one
+	// file system
+	staging, err := os.MkdirTemp(opts.Dir, ".install-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	fetched := filepath.Join(staging, "skill")
+	switch {
+	case isGitURL(source):
+		err = cloneSkill(ctx, source, fetched)
+	case skillNamePattern.MatchString(source):
+		err = downloadSkill(ctx, opts, source, fetched)
+	default:
+		err = fmt.Errorf("%q is neither a skill name nor a git URL", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := findSkillRoot(fetched)
+	if err != nil {
+		return nil, err
+	}
+	skill, err := Load(root)
+	if err != nil {
+		return nil, err
+	}
+	if !skillNamePattern.MatchString(skill.Name) {
+		return nil, fmt.Errorf("SKILL.md name %q must be lowercase letters, digits, and hyphens", skill.Name)
+	}
+
+	dest := filepath.Join(opts.Dir, skill.Name)
+	if _, err := os.Stat(dest); err == nil {
+		if !opts.Force {
+			return nil, fmt.Errorf("skill %s is already installed at %s", skill.Name, dest)
+		}
+		if err := os.RemoveAll(dest); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Rename(root, dest); err != nil {
+		return nil, err
+	}
+	return Load(dest)
+}
+
+// isGitURL reports whether source names a git repository rather than a
+// registry skill.
+func isGitURL(source string) bool {
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://", "file://", "git@"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return strings.HasSuffix(source, ".git")
+}
+
+// cloneSkill clones a git repository into dir.
+func cloneSkill(ctx context.Context, repo, dir string) error {
+	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--quiet", "--", repo, dir) //nolint:gosec // G204: The operator names the repository
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git clone: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// downloadSkill downloads a skill's zip archive from the registry and
+// extracts it into dir.
+func downloadSkill(ctx context.Context, opts InstallOptions, name, dir string) error {
+	endpoint := strings.TrimSuffix(opts.Registry, "/") + "/api/v1/download?slug=" + url.QueryEscape(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := opts.Client.Do(req) //nolint:gosec // G704: Registry URL comes from the operator
+	if err != nil {
+		return fmt.Errorf("download skill: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("skill %s not found in %s", name, opts.Registry)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download skill: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveBytes+1))
+	if err != nil {
+		return fmt.Errorf("download skill: %w", err)
+	}
+	if len(data) > maxArchiveBytes {
+		return fmt.Errorf("skill archive is larger than %d MB", maxArchiveBytes>>20)
+	}
+	return extractZip(data, dir)
+}
+
+// extractZip extracts a zip archive into dir, refusing entries that would
+// land outside it.
+func extractZip(data []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("read skill archive: %w", err)
+	}
+	var total uint64
+	for _, f := range zr.File {
+		target := filepath.Join(dir, filepath.FromSlash(f.Name)) //nolint:gosec // G305: Checked to stay within dir below
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("skill archive entry %q is outside the skill", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o750); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue // Skip symlinks and devices
+		}
+		total += f.UncompressedSize64
+		if total > maxArchiveBytes {
+			return fmt.Errorf("skill archive is larger than %d MB unpacked", maxArchiveBytes>>20)
+		}
+		if err := extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	mode := os.FileMode(0o600)
+	if f.Mode()&0o100 != 0 {
+		mode = 0o700 // Keep scripts executable
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode) //nolint:gosec // G304: Target is checked to stay within the skill
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, io.LimitReader(rc, maxArchiveBytes)); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// findSkillRoot returns dir if it holds SKILL.md, or its only
+// subdirectory that does, as archives often wrap the skill in a folder.
+func findSkillRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "SKILL.md")); err == nil {
+		return dir, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var subdirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			subdirs = append(subdirs, entry.Name())
+		}
+	}
+	if len(subdirs) == 1 {
+		root := filepath.Join(dir, subdirs[0])
+		if _, err := os.Stat(filepath.Join(root, "SKILL.md")); err == nil {
+			return root, nil
+		}
+	}
+	return "", errors.New("no SKILL.md found")
+}
+
+// Command returns the command that runs the installer, or nil if its kind
+// is not supported on this host.
+func (i Installer) Command() []string {
+	var cmd []string
+	switch {
+	case i.Kind == "brew" && i.Formula != "":
+		cmd = []string{"brew", "install", i.Formula}
+	case i.Kind == "apt" && i.Package != "":
+		cmd = []string{"apt-get", "install", "-y", i.Package}
+	case i.Kind == "go" && i.Module != "":
+		module := i.Module
+		if !strings.Contains(module, "@") {
+			module += "@latest"
+		}
+		cmd = []string{"go", "install", module}
+	case i.Kind == "npm" && i.Package != "":
+		cmd = []string{"npm", "install", "-g", i.Package}
+	default:
+		return nil
+	}
+	if _, err := exec.LookPath(cmd[0]); err != nil {
+		return nil
+	}
+	if cmd[0] == "apt-get" && os.Geteuid() != 0 {
+		cmd = append([]string{"sudo"}, cmd...)
+	}
+	return cmd
+}
+
+// Installers returns the skill's installers that provide a missing
+// binary, or all of them when none declare which binaries they provide.
+func (s *Skill) Installers(missing []string) []Installer {
+	if s.Metadata.OpenClaw == nil {
+		return nil
+	}
+	all := s.Metadata.OpenClaw.Install
+	var matched []Installer
+	declared := false
+	for _, inst := range all {
+		declared = declared || len(inst.Bins) > 0
+		for _, bin := range inst.Bins {
+			if slices.Contains(missing, bin) {
+				matched = append(matched, inst)
+				break
+			}
+		}
+	}
+	if !declared {
+		return all
+	}
+	return matched
+}
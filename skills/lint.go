@@ -0,0 +1,193 @@
+package skills
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"gopkg.in/yaml.v3"
+
+	"github.com/plexusone/omniagent/sandbox"
+)
+
+var (
+	skillNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Lint limits for SKILL.md fields.
+const (
+	maxNameLength        = 64
+	maxDescriptionLength = 1024
+)
+
+// Lint reports problems that would keep the skill from being discovered or
+// used well: a missing or malformed name, a missing description, an empty
+// body, and incomplete requirements or permissions. It returns nil for a clean skill.
+func Lint(s *Skill) []string {
+	var problems []string
+	switch {
+	case s.Name == "":
+		problems = append(problems, "name is required")
+	case len(s.Name) > maxNameLength:
+		problems = append(problems, fmt.Sprintf("name is longer than %d characters", maxNameLength))
+	case !skillNamePattern.MatchString(s.Name):
+		problems = append(problems, fmt.Sprintf("name %q must be lowercase letters, digits, and hyphens", s.Name))
+	}
+	switch {
+	case s.Description == "":
+		problems = append(problems, "description is required")
+	case len(s.Description) > maxDescriptionLength:
+		problems = append(problems, fmt.Sprintf("description is longer than %d characters", maxDescriptionLength))
+	}
+	if s.Content == "" {
+		problems = append(problems, "no instructions after the frontmatter")
+	}
+
+	meta := s.Metadata.OpenClaw
+	if meta == nil {
+		return problems
+	}
+	if req := meta.Requires; req != nil {
+		for _, name := range req.Env {
+			if !envNamePattern.MatchString(name) {
+				problems = append(problems, fmt.Sprintf("required env %q is not a valid variable name", name))
+			}
+		}
+		declared := func(c sandbox.Capability) bool { return slices.Contains(req.Capabilities, string(c)) }
+		for _, name := range req.Capabilities {
+			if !slices.Contains(capabilities, sandbox.Capability(name)) {
+				problems = append(problems, fmt.Sprintf("unknown capability %q; use fs_read, fs_write, net_http, or exec_run", name))
+			}
+		}
+		if len(req.Hosts) > 0 && !declared(sandbox.CapNetHTTP) {
+			problems = append(problems, "hosts are declared without the net_http capability")
+		}
+		if len(req.Paths) > 0 && !declared(sandbox.CapFSRead) && !declared(sandbox.CapFSWrite) {
+			problems = append(problems, "paths are declared without the fs_read or fs_write capability")
+		}
+	}
+	for i, inst := range meta.Install {
+		if inst.ID == "" || inst.Kind == "" {
+			problems = append(problems, fmt.Sprintf("installer %d needs an id and a kind", i+1))
+			continue
+		}
+		field, known := installerFields[inst.Kind]
+		switch {
+		case known && installerField(inst, field) == "":
+			problems = append(problems, fmt.Sprintf("%s installer %q needs a %s", inst.Kind, inst.ID, field))
+		case !known && inst.Label == "":
+			problems = append(problems, fmt.Sprintf("installer %q has kind %q, which cannot be run; add a label saying how to install it", inst.ID, inst.Kind))
+		}
+	}
+	return problems
+}
+
+// installerFields names the field each runnable installer kind needs.
+var installerFields = map[string]string{
+	"brew": "formula",
+	"apt":  "package",
+	"npm":  "package",
+	"go":   "module",
+}
+
+func installerField(inst Installer, field string) string {
+	switch field {
+	case "formula":
+		return inst.Formula
+	case "module":
+		return inst.Module
+	default:
+		return inst.Package
+	}
+}
+
+// maxInjectedContent is the instructions length above which a skill is
+// flagged as costly, since it is added to the system prompt.
+const maxInjectedContent = 8000
+
+// referenceRe matches paths to scripts and hooks in SKILL.md instructions.
+var referenceRe = regexp.MustCompile("(?:^|[\\s`\"'(\\[])((?:scripts|hooks)/[A-Za-z0-9_./-]*[A-Za-z0-9_])")
+
+//go:embed schema.json
+var frontmatterSchema []byte
+
+// resolvedSchema compiles the frontmatter schema once.
+var resolvedSchema = sync.OnceValues(func() (*jsonschema.Resolved, error) {
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(frontmatterSchema, &schema); err != nil {
+		return nil, err
+	}
+	return schema.Resolve(nil)
+})
+
+// LintDir lints the skill in dir: its frontmatter against the SKILL.md
+// schema, the checks Lint makes, the length of its instructions, and that
+// the scripts and hooks its instructions mention exist. It returns an
+// error only if SKILL.md cannot be read.
+func LintDir(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "SKILL.md")) //nolint:gosec // G304: The operator names the skill directory
+	if err != nil {
+		return nil, err
+	}
+	frontmatter, body, err := splitFrontmatter(string(data))
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+	problems := validateFrontmatter(frontmatter)
+
+	skill, err := Parse(string(data))
+	if err != nil {
+		return append(problems, err.Error()), nil
+	}
+	problems = append(problems, Lint(skill)...)
+
+	if len(body) > maxInjectedContent {
+		problems = append(problems, fmt.Sprintf("instructions are %d characters; over %d is costly to add to every prompt, so move detail into scripts or linked files", len(body), maxInjectedContent))
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range referenceRe.FindAllStringSubmatch(body, -1) {
+		ref := m[1]
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(ref))); err != nil {
+			problems = append(problems, fmt.Sprintf("instructions mention %s, which does not exist", ref))
+		}
+	}
+	return problems, nil
+}
+
+// validateFrontmatter checks the frontmatter YAML against the schema.
+func validateFrontmatter(frontmatter string) []string {
+	var doc any
+	if err := yaml.Unmarshal([]byte(frontmatter), &doc); err != nil {
+		return []string{fmt.Sprintf("frontmatter is not valid YAML: %v", err)}
+	}
+	// Round-trip through JSON so values have the types the schema expects
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return []string{fmt.Sprintf("frontmatter: %v", err)}
+	}
+	var instance any
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return []string{fmt.Sprintf("frontmatter: %v", err)}
+	}
+
+	schema, err := resolvedSchema()
+	if err != nil {
+		return []string{fmt.Sprintf("frontmatter schema: %v", err)}
+	}
+	if err := schema.Validate(instance); err != nil {
+		return []string{fmt.Sprintf("frontmatter does not match the SKILL.md schema: %v", err)}
+	}
+	return nil
+}
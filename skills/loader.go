@@ -10,18 +10,19 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// DefaultSearchPaths returns the default skill directories to search.
+// DefaultSearchPaths returns the default skill directories to search: the
+// skills directory under the user config directory (%AppData%\omniagent on
+// Windows, ~/.config/omniagent on Linux), ~/.omniagent/skills, then skills
+// and .skills in the working directory.
 func DefaultSearchPaths() []string {
-	paths := []string{
-		"skills",
-		".skills",
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "omniagent", "skills"))
 	}
-
 	if home, err := os.UserHomeDir(); err == nil {
-		paths = append([]string{filepath.Join(home, ".omniagent", "skills")}, paths...)
+		paths = append(paths, filepath.Join(home, ".omniagent", "skills"))
 	}
-
-	return paths
+	return append(paths, "skills", ".skills")
 }
 
 // Discover finds all skills in the given directories.
@@ -93,15 +94,11 @@ func Load(skillDir string) (*Skill, error) {
 
 // Parse extracts skill data from SKILL.md content.
 func Parse(content string) (*Skill, error) {
-	// Split on YAML frontmatter delimiters
-	parts := strings.SplitN(content, "---", 3)
-	if len(parts) < 3 {
-		return nil, fmt.Errorf("invalid SKILL.md: missing frontmatter delimiters")
+	frontmatter, body, err := splitFrontmatter(content)
+	if err != nil {
+		return nil, err
 	}
 
-	frontmatter := strings.TrimSpace(parts[1])
-	body := strings.TrimSpace(parts[2])
-
 	// Parse basic YAML fields
 	var skill Skill
 	if err := yaml.Unmarshal([]byte(frontmatter), &skill); err != nil {
@@ -115,6 +112,15 @@ func Parse(content string) (*Skill, error) {
 	return &skill, nil
 }
 
+// splitFrontmatter splits SKILL.md into its YAML frontmatter and body.
+func splitFrontmatter(content string) (frontmatter, body string, err error) {
+	parts := strings.SplitN(content, "---", 3)
+	if len(parts) < 3 {
+		return "", "", fmt.Errorf("invalid SKILL.md: missing frontmatter delimiters")
+	}
+	return strings.TrimSpace(parts[1]), strings.TrimSpace(parts[2]), nil
+}
+
 // parseMetadata extracts the metadata field.
 // The metadata field in SKILL.md uses YAML syntax that looks like JSON.
 func parseMetadata(frontmatter string) SkillMeta {
@@ -0,0 +1,70 @@
+package skills
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/plexusone/omniagent/sandbox"
+)
+
+// capabilities are the sandbox capabilities a skill may declare.
+var capabilities = []sandbox.Capability{sandbox.CapFSRead, sandbox.CapFSWrite, sandbox.CapNetHTTP, sandbox.CapExecRun}
+
+// Sandbox returns base restricted to what the skill declares it needs:
+// the capabilities in requires.capabilities, the hosts net_http may
+// reach, the paths fs_read and fs_write cover besides the skill
+// directory, and the binaries exec_run may start (requires.bins). The
+// skill directory is the working directory. Nothing undeclared is
+// granted, whatever base allows.
+func (s *Skill) Sandbox(base sandbox.Config) (sandbox.Config, error) {
+	var req Requires
+	if s.Metadata.OpenClaw != nil && s.Metadata.OpenClaw.Requires != nil {
+		req = *s.Metadata.OpenClaw.Requires
+	}
+
+	sc := base
+	sc.WorkingDir = s.Path
+	sc.Capabilities = nil
+	sc.AllowedHosts = nil
+	sc.AllowedPaths = []string{s.Path}
+	sc.AllowedCommands = nil
+	for _, name := range req.Capabilities {
+		c := sandbox.Capability(name)
+		if !slices.Contains(capabilities, c) {
+			return sandbox.Config{}, fmt.Errorf("skill %s: unknown capability %q", s.Name, name)
+		}
+		if !slices.Contains(sc.Capabilities, c) {
+			sc.Capabilities = append(sc.Capabilities, c)
+		}
+	}
+
+	if sc.HasCapability(sandbox.CapNetHTTP) {
+		sc.AllowedHosts = slices.Clone(req.Hosts)
+	}
+	if sc.HasCapability(sandbox.CapFSRead) || sc.HasCapability(sandbox.CapFSWrite) {
+		for _, p := range req.Paths {
+			sc.AllowedPaths = append(sc.AllowedPaths, s.resolvePath(p))
+		}
+	}
+	if sc.HasCapability(sandbox.CapExecRun) {
+		sc.AllowedCommands = slices.Clone(req.Bins)
+	}
+	return sc, nil
+}
+
+// resolvePath expands ~ and makes a declared path absolute, relative to
+// the skill directory.
+func (s *Skill) resolvePath(p string) string {
+	if rest, ok := strings.CutPrefix(p, "~"); ok && (rest == "" || os.IsPathSeparator(rest[0])) {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(s.Path, p)
+	}
+	return filepath.Clean(p)
+}
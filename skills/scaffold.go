@@ -0,0 +1,73 @@
+package skills
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed scaffold
+var scaffoldFS embed.FS
+
+// Scaffold creates a skill named name in a new directory under parent:
+// a SKILL.md with frontmatter to fill in, a script, a pre_tool hook
+// source, and a Makefile that builds the hook and smoke-tests the skill.
+// It returns the skill directory.
+func Scaffold(parent, name string) (string, error) {
+	if !skillNamePattern.MatchString(name) {
+		return "", fmt.Errorf("skill name %q must be lowercase letters, digits, and hyphens", name)
+	}
+	dir := filepath.Join(parent, name)
+	if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("%s already exists", dir)
+	}
+
+	data := struct{ Name, Title string }{
+		Name:  name,
+		Title: titleCase(strings.ReplaceAll(name, "-", " ")),
+	}
+	err := fs.WalkDir(scaffoldFS, "scaffold", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		tmpl, err := template.ParseFS(scaffoldFS, file)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return err
+		}
+
+		rel := strings.TrimSuffix(strings.TrimPrefix(file, "scaffold/"), ".tmpl")
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+			return err
+		}
+		mode := os.FileMode(0o600)
+		if path.Dir(rel) == "scripts" {
+			mode = 0o700
+		}
+		return os.WriteFile(target, buf.Bytes(), mode)
+	})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("scaffold skill: %w", err)
+	}
+	return dir, nil
+}
+
+// titleCase capitalizes the first letter of each word.
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
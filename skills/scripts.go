@@ -0,0 +1,173 @@
+package skills
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Script is an executable a skill ships in its scripts/ directory.
+type Script struct {
+	Name        string // Tool name, from the file name
+	File        string // Path relative to the skill directory, e.g. scripts/deploy.sh
+	Description string // From the header comment or a SKILL.md section
+	Interpreter string // Command that runs the file, e.g. python3
+}
+
+// interpreters runs scripts by file extension.
+var interpreters = map[string]string{
+	".sh":   "sh",
+	".bash": "bash",
+	".py":   "python3",
+	".js":   "node",
+	".mjs":  "node",
+	".rb":   "ruby",
+	".pl":   "perl",
+}
+
+// scriptNameRe matches characters not allowed in tool names.
+var scriptNameRe = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Scripts lists the scripts in the skill's scripts/ directory, in file
+// name order.
+func (s *Skill) Scripts() ([]Script, error) {
+	if !s.HasScripts {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(s.Path, "scripts"))
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []Script
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		file := path.Join("scripts", entry.Name())
+		ext := filepath.Ext(entry.Name())
+		name := strings.Trim(scriptNameRe.ReplaceAllString(strings.TrimSuffix(entry.Name(), ext), "_"), "_")
+		if name == "" {
+			continue
+		}
+
+		shebang, header := readScriptHeader(filepath.Join(s.Path, file))
+		script := Script{
+			Name:        strings.ToLower(name),
+			File:        file,
+			Description: header,
+			Interpreter: interpreters[ext],
+		}
+		if script.Interpreter == "" {
+			script.Interpreter = shebang
+		}
+		if script.Interpreter == "" {
+			script.Interpreter = "sh"
+		}
+		if script.Description == "" {
+			script.Description = s.scriptSection(entry.Name())
+		}
+		if script.Description == "" {
+			script.Description = "Run the " + entry.Name() + " script of the " + s.Name + " skill."
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// readScriptHeader returns the interpreter named by a script's shebang line
+// and the comment block at the top of the file.
+func readScriptHeader(file string) (interpreter, header string) {
+	f, err := os.Open(file) //nolint:gosec // G304: Path is within a skill directory
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for first := true; scanner.Scan(); first = false {
+		line := strings.TrimSpace(scanner.Text())
+		if first && strings.HasPrefix(line, "#!") {
+			interpreter = shebangInterpreter(strings.TrimPrefix(line, "#!"))
+			continue
+		}
+		text, ok := commentText(line)
+		if !ok {
+			break
+		}
+		if text == "" {
+			if len(lines) > 0 {
+				break // The header ends at the first blank comment line
+			}
+			continue
+		}
+		lines = append(lines, text)
+	}
+	return interpreter, strings.Join(lines, " ")
+}
+
+// shebangInterpreter returns the command a shebang line runs, looking
+// through /usr/bin/env.
+func shebangInterpreter(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	if path.Base(fields[0]) == "env" {
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				return f
+			}
+		}
+		return ""
+	}
+	return path.Base(fields[0])
+}
+
+// commentText returns the text of a line comment in shell, Python, Ruby,
+// JavaScript, or Lua style.
+func commentText(line string) (string, bool) {
+	for _, prefix := range []string{"#", "//", "--"} {
+		if strings.HasPrefix(line, prefix) {
+			text := strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			if strings.HasPrefix(text, "-*-") || strings.HasPrefix(text, "shellcheck ") {
+				return "", true
+			}
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// scriptSection returns the first paragraph under the SKILL.md heading that
+// names file, such as "## scripts/deploy.sh" or "### `deploy.sh`".
+func (s *Skill) scriptSection(file string) string {
+	var paragraph []string
+	inSection := false
+	for line := range strings.SplitSeq(s.Content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			if inSection {
+				break
+			}
+			heading := strings.Trim(strings.TrimLeft(line, "# "), "`")
+			inSection = heading == file || heading == "scripts/"+file
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if line == "" {
+			if len(paragraph) > 0 {
+				break
+			}
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	return strings.Join(paragraph, " ")
+}
@@ -0,0 +1,152 @@
+package skills
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Selection defaults.
+const DefaultSelectTopK = 5
+
+// Embedder turns texts into vectors. memory.Embedder satisfies it.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+}
+
+// SelectorConfig configures a Selector.
+type SelectorConfig struct {
+	Embedder Embedder
+	TopK     int     // Skills injected per message; default DefaultSelectTopK
+	MinScore float64 // Minimum cosine similarity; 0 keeps the top TopK
+}
+
+// Selector picks the skills relevant to a message by comparing embeddings
+// of the message and of each skill's name and description. Skill
+// embeddings are cached until the skill's description changes.
+type Selector struct {
+	config SelectorConfig
+
+	mu    sync.Mutex
+	cache map[string][]float32 // Keyed by skillText
+}
+
+// NewSelector creates a Selector.
+func NewSelector(config SelectorConfig) *Selector {
+	if config.TopK <= 0 {
+		config.TopK = DefaultSelectTopK
+	}
+	return &Selector{config: config, cache: make(map[string][]float32)}
+}
+
+// Select returns the skills most relevant to content, best first, along
+// with skills marked always. Skills with missing requirements are left
+// out, as they are not injected either.
+func (s *Selector) Select(ctx context.Context, skills []*Skill, content string) ([]*Skill, error) {
+	var always, candidates []*Skill
+	for _, skill := range skills {
+		switch {
+		case !skill.IsAvailable():
+		case skill.Metadata.OpenClaw != nil && skill.Metadata.OpenClaw.Always:
+			always = append(always, skill)
+		default:
+			candidates = append(candidates, skill)
+		}
+	}
+	if len(candidates) == 0 || content == "" {
+		return always, nil
+	}
+
+	vecs, err := s.skillVectors(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	query, err := s.config.Embedder.Embed(ctx, []string{content})
+	if err != nil {
+		return nil, fmt.Errorf("embed message: %w", err)
+	}
+	if len(query) != 1 {
+		return nil, fmt.Errorf("embed message: got %d vectors", len(query))
+	}
+
+	type scored struct {
+		skill *Skill
+		score float64
+	}
+	ranked := make([]scored, 0, len(candidates))
+	for i, skill := range candidates {
+		if score := cosine(query[0], vecs[i]); score >= s.config.MinScore {
+			ranked = append(ranked, scored{skill, score})
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b scored) int { return cmp.Compare(b.score, a.score) })
+
+	selected := always
+	for _, r := range ranked[:min(len(ranked), s.config.TopK)] {
+		selected = append(selected, r.skill)
+	}
+	return selected, nil
+}
+
+// skillVectors returns the embedding of each skill, embedding those not
+// cached in one batch.
+func (s *Selector) skillVectors(ctx context.Context, skills []*Skill) ([][]float32, error) {
+	s.mu.Lock()
+	var missing []string
+	for _, skill := range skills {
+		if _, ok := s.cache[skillText(skill)]; !ok {
+			missing = append(missing, skillText(skill))
+		}
+	}
+	s.mu.Unlock()
+
+	if len(missing) > 0 {
+		vecs, err := s.config.Embedder.Embed(ctx, missing)
+		if err != nil {
+			return nil, fmt.Errorf("embed skills: %w", err)
+		}
+		if len(vecs) != len(missing) {
+			return nil, fmt.Errorf("embed skills: got %d vectors for %d skills", len(vecs), len(missing))
+		}
+		s.mu.Lock()
+		for i, text := range missing {
+			s.cache[text] = vecs[i]
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([][]float32, len(skills))
+	for i, skill := range skills {
+		out[i] = s.cache[skillText(skill)]
+	}
+	return out, nil
+}
+
+// skillText is the text embedded for a skill.
+func skillText(skill *Skill) string {
+	return skill.Name + ": " + skill.Description
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 if their
+// lengths differ.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -34,6 +34,12 @@ type Requires struct {
 	Bins    []string `json:"bins,omitempty"`    // Required binaries on PATH
 	AnyBins []string `json:"anyBins,omitempty"` // At least one required
 	Env     []string `json:"env,omitempty"`     // Required environment variables
+
+	// Sandbox permissions for the skill's scripts and hooks. Nothing
+	// beyond reading the skill directory is granted unless declared.
+	Capabilities []string `json:"capabilities,omitempty"` // fs_read, fs_write, net_http, exec_run
+	Hosts        []string `json:"hosts,omitempty"`        // Hosts net_http may reach; empty allows any
+	Paths        []string `json:"paths,omitempty"`        // Paths beyond the skill directory fs_read and fs_write cover
 }
 
 // Installer specifies how to install a dependency.
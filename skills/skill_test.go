@@ -1,10 +1,19 @@
 package skills
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/plexusone/omniagent/sandbox"
 )
 
 func TestParse(t *testing.T) {
@@ -366,3 +375,427 @@ func TestFilterAvailable(t *testing.T) {
 		t.Errorf("FilterAvailable() returned wrong skill: %q", available[0].Name)
 	}
 }
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "clean",
+			content: "---\nname: daily-notes\ndescription: Keep daily notes\n---\n\nWrite notes to notes/.",
+		},
+		{
+			name:    "missing fields",
+			content: "---\nname: Daily Notes\n---\n",
+			want: []string{
+				`name "Daily Notes" must be lowercase letters, digits, and hyphens`,
+				"description is required",
+				"no instructions after the frontmatter",
+			},
+		},
+		{
+			name: "bad metadata",
+			content: `---
+name: deploy
+description: Deploy the site
+metadata: { "openclaw": { "requires": { "env": ["API-KEY"] }, "install": [{ "kind": "brew" }] } }
+---
+
+Run make deploy.`,
+			want: []string{
+				`required env "API-KEY" is not a valid variable name`,
+				"installer 1 needs an id and a kind",
+			},
+		},
+		{
+			name: "bad permissions",
+			content: `---
+name: fetch
+description: Fetch pages
+metadata: { "openclaw": { "requires": { "capabilities": ["network"], "hosts": ["example.com"], "paths": ["/data"] } } }
+---
+
+Run scripts/fetch.sh.`,
+			want: []string{
+				`unknown capability "network"; use fs_read, fs_write, net_http, or exec_run`,
+				"hosts are declared without the net_http capability",
+				"paths are declared without the fs_read or fs_write capability",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skill, err := Parse(tt.content)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := Lint(skill)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Lint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScripts(t *testing.T) {
+	dir := t.TempDir()
+	scripts := filepath.Join(dir, "scripts")
+	if err := os.MkdirAll(scripts, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"deploy.sh":     "#!/bin/sh\n# Deploy the site to production.\n# Takes the environment name.\n\necho ok\n",
+		"Fetch Data.py": "#!/usr/bin/env python3\nprint('hi')\n",
+		"report":        "#!/usr/bin/env -S node\n// shellcheck disable=all\n// Build the weekly report.\n",
+		".hidden":       "secret",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(scripts, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	skill := &Skill{
+		Name:       "site",
+		Path:       dir,
+		HasScripts: true,
+		Content:    "# Site\n\n## scripts/Fetch Data.py\n\nDownload the latest analytics.\n\nMore detail.\n\n## Other\n",
+	}
+	got, err := skill.Scripts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Script{
+		{Name: "fetch_data", File: "scripts/Fetch Data.py", Description: "Download the latest analytics.", Interpreter: "python3"},
+		{Name: "deploy", File: "scripts/deploy.sh", Description: "Deploy the site to production. Takes the environment name.", Interpreter: "sh"},
+		{Name: "report", File: "scripts/report", Description: "Build the weekly report.", Interpreter: "node"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Scripts() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("script %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	zipArchive := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, content := range files {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write([]byte(content))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	skillMD := "---\nname: weather\ndescription: Get forecasts\n---\n\n# Weather\n\nUse curl.\n"
+	archives := map[string][]byte{
+		"weather": zipArchive(map[string]string{"weather/SKILL.md": skillMD, "weather/scripts/get.sh": "echo sunny"}),
+		"evil":    zipArchive(map[string]string{"SKILL.md": skillMD, "../escape.txt": "x"}),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := archives[r.URL.Query().Get("slug")]
+		if r.URL.Path != "/api/v1/download" || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	opts := InstallOptions{Dir: dir, Registry: srv.URL}
+	skill, err := Install(context.Background(), "weather", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skill.Name != "weather" || skill.Path != filepath.Join(dir, "weather") || !skill.HasScripts {
+		t.Errorf("installed %+v", skill)
+	}
+	if _, err := Install(context.Background(), "weather", opts); err == nil {
+		t.Error("expected reinstall without force to fail")
+	}
+	opts.Force = true
+	if _, err := Install(context.Background(), "weather", opts); err != nil {
+		t.Errorf("reinstall with force: %v", err)
+	}
+
+	if _, err := Install(context.Background(), "missing", opts); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing skill error = %v", err)
+	}
+	if _, err := Install(context.Background(), "evil", opts); err == nil {
+		t.Error("expected archive entry outside the skill to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); err == nil {
+		t.Error("archive entry escaped the skill directory")
+	}
+
+	// Git repositories are cloned
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "SKILL.md"), []byte(strings.Replace(skillMD, "weather", "forecast", 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "SKILL.md"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "skill"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	skill, err = Install(context.Background(), "file://"+repo, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skill.Name != "forecast" {
+		t.Errorf("cloned skill name = %q, want forecast", skill.Name)
+	}
+}
+
+func TestInstallers(t *testing.T) {
+	skill := &Skill{Metadata: SkillMeta{OpenClaw: &OpenClawMeta{Install: []Installer{
+		{ID: "gh", Kind: "brew", Formula: "gh", Bins: []string{"gh"}},
+		{ID: "jq", Kind: "brew", Formula: "jq", Bins: []string{"jq"}},
+	}}}}
+	got := skill.Installers([]string{"jq"})
+	if len(got) != 1 || got[0].ID != "jq" {
+		t.Errorf("Installers(jq) = %+v", got)
+	}
+	if cmd := (Installer{Kind: "unknown", Package: "x"}).Command(); cmd != nil {
+		t.Errorf("unknown installer command = %v, want nil", cmd)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	empty := Fingerprint([]string{dir, filepath.Join(dir, "missing")})
+
+	skillDir := filepath.Join(dir, "weather")
+	if err := os.MkdirAll(filepath.Join(skillDir, "scripts"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: weather\n---\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	added := Fingerprint([]string{dir})
+	if added == empty {
+		t.Error("fingerprint unchanged after adding a skill")
+	}
+	if Fingerprint([]string{dir}) != added {
+		t.Error("fingerprint changed without changes")
+	}
+
+	if err := os.WriteFile(filepath.Join(skillDir, "scripts", "get.sh"), []byte("echo"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if Fingerprint([]string{dir}) == added {
+		t.Error("fingerprint unchanged after adding a script")
+	}
+
+	if err := os.RemoveAll(skillDir); err != nil {
+		t.Fatal(err)
+	}
+	if Fingerprint([]string{dir}) != empty {
+		t.Error("fingerprint differs after removing the skill")
+	}
+}
+
+// keywordEmbedder embeds texts as counts of a few keywords.
+type keywordEmbedder struct{ calls int }
+
+func (e *keywordEmbedder) Model() string { return "keywords" }
+
+func (e *keywordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	keywords := []string{"weather", "music", "github"}
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vecs[i] = make([]float32, len(keywords))
+		for j, kw := range keywords {
+			vecs[i][j] = float32(strings.Count(strings.ToLower(text), kw))
+		}
+	}
+	return vecs, nil
+}
+
+func TestSelector(t *testing.T) {
+	loaded := []*Skill{
+		{Name: "forecast", Description: "Weather forecasts"},
+		{Name: "sonos", Description: "Play music on speakers"},
+		{Name: "gh", Description: "GitHub issues and pull requests"},
+		{Name: "core", Description: "Always on", Metadata: SkillMeta{OpenClaw: &OpenClawMeta{Always: true}}},
+	}
+	embedder := &keywordEmbedder{}
+	s := NewSelector(SelectorConfig{Embedder: embedder, TopK: 1, MinScore: 0.5})
+
+	got, err := s.Select(context.Background(), loaded, "Will the weather be nice for some music outside? weather!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, skill := range got {
+		names = append(names, skill.Name)
+	}
+	if strings.Join(names, ",") != "core,forecast" {
+		t.Errorf("selected %v, want core and forecast", names)
+	}
+
+	// Skill embeddings are cached; only the message is embedded again
+	if _, err := s.Select(context.Background(), loaded, "play some music"); err != nil {
+		t.Fatal(err)
+	}
+	if embedder.calls != 3 {
+		t.Errorf("embed calls = %d, want 3", embedder.calls)
+	}
+
+	// Nothing relevant leaves only the always-on skills
+	got, err = s.Select(context.Background(), loaded, "hello")
+	if err != nil || len(got) != 1 || got[0].Name != "core" {
+		t.Errorf("Select(hello) = %v, %v; want only core", got, err)
+	}
+}
+
+func TestLintDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "scripts"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scripts", "deploy.sh"), []byte("echo"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("---\nname: deploy\ndescription: Deploy the site\n---\n\nRun `scripts/deploy.sh prod`.\n")
+	if problems, err := LintDir(dir); err != nil || len(problems) != 0 {
+		t.Errorf("clean skill: %q, %v", problems, err)
+	}
+
+	write(`---
+name: deploy
+description: Deploy the site
+homepage: 42
+metadata: { "openclaw": { "install": [{ "id": "gh", "kind": "brew" }, { "id": "x", "kind": "uv" }] } }
+---
+
+Run scripts/deploy.sh, then scripts/verify.sh.
+` + strings.Repeat("x", maxInjectedContent))
+	problems, err := LintDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"frontmatter does not match the SKILL.md schema",
+		`brew installer "gh" needs a formula`,
+		`installer "x" has kind "uv", which cannot be run`,
+		"costly to add to every prompt",
+		"instructions mention scripts/verify.sh, which does not exist",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %q, want %d", problems, len(want))
+	}
+	for i, w := range want {
+		if !strings.Contains(problems[i], w) {
+			t.Errorf("problem %d = %q, want it to mention %q", i, problems[i], w)
+		}
+	}
+
+	if _, err := LintDir(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without SKILL.md")
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	base := sandbox.DefaultConfig()
+	base.Capabilities = []sandbox.Capability{sandbox.CapFSWrite, sandbox.CapNetHTTP}
+	base.AllowedCommands = []string{"rm"}
+
+	skill := &Skill{Name: "plain", Path: "/skills/plain"}
+	sc, err := skill.Sandbox(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Capabilities) != 0 || len(sc.AllowedCommands) != 0 || sc.WorkingDir != skill.Path {
+		t.Errorf("undeclared skill got %+v, want no capabilities", sc)
+	}
+	if sc.MemoryLimitMB != base.MemoryLimitMB {
+		t.Errorf("MemoryLimitMB = %d, want base %d", sc.MemoryLimitMB, base.MemoryLimitMB)
+	}
+
+	skill.Metadata.OpenClaw = &OpenClawMeta{Requires: &Requires{
+		Bins:         []string{"curl"},
+		Capabilities: []string{"net_http", "fs_read", "exec_run"},
+		Hosts:        []string{"api.example.com"},
+		Paths:        []string{"data", "/etc/ssl"},
+	}}
+	sc, err = skill.Sandbox(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.HasCapability(sandbox.CapFSWrite) || !sc.HasCapability(sandbox.CapNetHTTP) || !sc.HasCapability(sandbox.CapExecRun) {
+		t.Errorf("Capabilities = %v, want the declared ones", sc.Capabilities)
+	}
+	wantPaths := []string{"/skills/plain", filepath.FromSlash("/skills/plain/data"), "/etc/ssl"}
+	if !slices.Equal(sc.AllowedPaths, wantPaths) {
+		t.Errorf("AllowedPaths = %v, want %v", sc.AllowedPaths, wantPaths)
+	}
+	if !slices.Equal(sc.AllowedHosts, []string{"api.example.com"}) || !slices.Equal(sc.AllowedCommands, []string{"curl"}) {
+		t.Errorf("hosts = %v, commands = %v", sc.AllowedHosts, sc.AllowedCommands)
+	}
+
+	skill.Metadata.OpenClaw.Requires.Capabilities = []string{"root"}
+	if _, err := skill.Sandbox(base); err == nil {
+		t.Error("expected an error for an unknown capability")
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	parent := t.TempDir()
+	dir, err := Scaffold(parent, "weather-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	skill, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skill.Name != "weather-report" || skill.Emoji() == "" || !skill.HasScripts || !skill.HasHooks {
+		t.Errorf("scaffolded skill = %+v", skill)
+	}
+	if !strings.Contains(skill.Content, "# Weather Report") {
+		t.Errorf("content = %q, want the title", skill.Content)
+	}
+	if problems, err := LintDir(dir); err != nil || len(problems) != 0 {
+		t.Errorf("scaffold lint: %q, %v", problems, err)
+	}
+	scripts, err := skill.Scripts()
+	if err != nil || len(scripts) != 1 || scripts[0].Description != "Print a greeting from the weather-report skill. Arguments: an optional name to greet." {
+		t.Errorf("scripts = %+v, %v", scripts, err)
+	}
+
+	if _, err := Scaffold(parent, "weather-report"); err == nil {
+		t.Error("expected scaffolding over an existing skill to fail")
+	}
+	if _, err := Scaffold(parent, "Bad Name"); err == nil {
+		t.Error("expected an invalid name to fail")
+	}
+}
@@ -0,0 +1,40 @@
+package skills
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+)
+
+// Fingerprint summarizes the skills in dirs. It changes when a skill is
+// added or removed, or its SKILL.md, scripts, or hooks change, so callers
+// can poll it to reload skills.
+func Fingerprint(dirs []string) uint64 {
+	h := fnv.New64a()
+	stat := func(path string) {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			skillDir := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(skillDir); err != nil || !info.IsDir() {
+				continue
+			}
+			stat(filepath.Join(skillDir, "SKILL.md"))
+			for _, pattern := range []string{"scripts/*", "hooks/*", "hooks/*/*"} {
+				files, _ := filepath.Glob(filepath.Join(skillDir, filepath.FromSlash(pattern)))
+				for _, file := range files {
+					stat(file)
+				}
+			}
+		}
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,365 @@
+// Package sms provides a Twilio SMS channel. Inbound messages arrive on a
+// webhook served by the gateway; replies are sent with the Twilio REST API.
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // G505: Twilio signs webhooks with HMAC-SHA1
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/supervisor"
+)
+
+// Name is the channel name. Sessions are "sms:<sender number>".
+const Name = "sms"
+
+// DefaultAPIURL is the Twilio REST API base URL.
+const DefaultAPIURL = "https://api.twilio.com/2010-04-01"
+
+// maxWebhookBytes limits the size of webhook requests.
+const maxWebhookBytes = 64 * 1024
+
+// Config configures the SMS channel.
+type Config struct {
+	AccountSID string // Required
+	AuthToken  string //nolint:gosec // G117: AuthToken is required for the Twilio API
+	From       string // Default sending number (E.164) for chats without an inbound message
+
+	// WebhookURL is the public URL Twilio posts to, used to verify
+	// request signatures. Empty derives it from the request, which breaks
+	// behind proxies that rewrite the host or path.
+	WebhookURL string
+
+	// MaxLength splits replies into messages of at most this many
+	// characters (default: 1600, Twilio's limit).
+	MaxLength int
+
+	APIURL     string // Default: DefaultAPIURL
+	HTTPClient *http.Client
+	Logger     *slog.Logger
+}
+
+// Provider is a Twilio SMS channel.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	logger   *slog.Logger
+	handlers []provider.MessageHandler
+
+	// numbers maps sender numbers to the Twilio number they last texted,
+	// so replies come from the number the user knows.
+	numbers map[string]string
+	mu      sync.RWMutex
+
+	// workers runs message handlers, which outlive the webhook request.
+	workers *supervisor.Group
+}
+
+// New creates an SMS channel.
+func New(config Config) (*Provider, error) {
+	if config.AccountSID == "" || config.AuthToken == "" {
+		return nil, fmt.Errorf("twilio account SID and auth token required")
+	}
+	if config.MaxLength <= 0 {
+		config.MaxLength = 1600
+	}
+	if config.APIURL == "" {
+		config.APIURL = DefaultAPIURL
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Provider{
+		config:  config,
+		client:  config.HTTPClient,
+		logger:  config.Logger,
+		numbers: make(map[string]string),
+		workers: supervisor.New(context.Background(), supervisor.Config{Logger: config.Logger}),
+	}, nil
+}
+
+// Name returns the channel name.
+func (p *Provider) Name() string {
+	return Name
+}
+
+// Connect is a no-op; messages arrive through the webhook.
+func (p *Provider) Connect(ctx context.Context) error {
+	return nil
+}
+
+// Disconnect waits, until ctx is done, for in-flight message handlers.
+// Webhooks received afterwards are rejected.
+func (p *Provider) Disconnect(ctx context.Context) error {
+	return p.workers.Stop(ctx)
+}
+
+// OnMessage registers a handler for incoming messages.
+func (p *Provider) OnMessage(handler provider.MessageHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, handler)
+}
+
+// OnEvent is a no-op; SMS has no events.
+func (p *Provider) OnEvent(handler provider.EventHandler) {}
+
+// Send sends a message to a phone number, split into parts of at most
+// MaxLength characters. Media with a URL is attached as MMS to the first
+// part.
+func (p *Provider) Send(ctx context.Context, chatID string, msg provider.OutgoingMessage) error {
+	from := p.fromNumber(chatID)
+	if from == "" {
+		return fmt.Errorf("no sending number for %s", chatID)
+	}
+
+	var mediaURLs []string
+	for _, m := range msg.Media {
+		if m.URL != "" {
+			mediaURLs = append(mediaURLs, m.URL)
+		}
+	}
+	parts := Split(msg.Content, p.config.MaxLength)
+	if len(parts) == 0 && len(mediaURLs) > 0 {
+		parts = []string{""}
+	}
+	for i, part := range parts {
+		form := url.Values{"To": {chatID}, "From": {from}, "Body": {part}}
+		if i == 0 {
+			form["MediaUrl"] = mediaURLs
+		}
+		if err := p.post(ctx, form); err != nil {
+			return fmt.Errorf("send part %d/%d: %w", i+1, len(parts), err)
+		}
+	}
+	return nil
+}
+
+// fromNumber returns the Twilio number to reply to chatID from.
+func (p *Provider) fromNumber(chatID string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if n := p.numbers[chatID]; n != "" {
+		return n
+	}
+	return p.config.From
+}
+
+// post creates a message with the Twilio API.
+func (p *Provider) post(ctx context.Context, form url.Values) error {
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", p.config.APIURL, url.PathEscape(p.config.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.SetBasicAuth(p.config.AccountSID, p.config.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post message: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("twilio error %d: %s", apiErr.Code, apiErr.Message)
+		}
+		return fmt.Errorf("twilio returned %s", resp.Status)
+	}
+	return nil
+}
+
+// ServeHTTP handles Twilio's inbound message webhook. It acknowledges
+// immediately with empty TwiML and hands the message to the registered
+// handlers in the background, since agent replies can outlast Twilio's
+// webhook timeout; replies are sent with Send.
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBytes)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	if !p.validSignature(r) {
+		p.logger.Warn("rejected sms webhook with invalid signature", "remote", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	msg := p.incoming(r.PostForm)
+	if msg.ChatID == "" {
+		http.Error(w, "missing sender", http.StatusBadRequest)
+		return
+	}
+	if to := r.PostForm.Get("To"); to != "" {
+		p.mu.Lock()
+		p.numbers[msg.ChatID] = to
+		p.mu.Unlock()
+	}
+
+	p.mu.RLock()
+	handlers := append([]provider.MessageHandler(nil), p.handlers...)
+	p.mu.RUnlock()
+
+	err := p.workers.Spawn("sms-message", func(ctx context.Context) error {
+		for _, h := range handlers {
+			if err := h(ctx, msg); err != nil {
+				p.logger.Error("sms message handler failed", "from", msg.ChatID, "error", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`)
+}
+
+// incoming converts webhook parameters to a message.
+func (p *Provider) incoming(form url.Values) provider.IncomingMessage {
+	from := form.Get("From")
+	msg := provider.IncomingMessage{
+		ID:           form.Get("MessageSid"),
+		ProviderName: Name,
+		ChatID:       from,
+		ChatType:     provider.ChatTypeDM,
+		SenderID:     from,
+		Content:      form.Get("Body"),
+		Timestamp:    time.Now(),
+		Metadata:     map[string]any{"to": form.Get("To")},
+	}
+	n, _ := strconv.Atoi(form.Get("NumMedia"))
+	for i := 0; i < n; i++ {
+		mimeType := form.Get(fmt.Sprintf("MediaContentType%d", i))
+		msg.Media = append(msg.Media, provider.Media{
+			Type:     mediaType(mimeType),
+			URL:      form.Get(fmt.Sprintf("MediaUrl%d", i)),
+			MimeType: mimeType,
+		})
+	}
+	return msg
+}
+
+// mediaType maps a MIME type to a media type.
+func mediaType(mimeType string) provider.MediaType {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return provider.MediaTypeImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return provider.MediaTypeVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return provider.MediaTypeAudio
+	default:
+		return provider.MediaTypeDocument
+	}
+}
+
+// validSignature verifies the X-Twilio-Signature header: the base64
+// HMAC-SHA1, keyed by the auth token, of the webhook URL followed by the
+// sorted POST parameters.
+func (p *Provider) validSignature(r *http.Request) bool {
+	got := r.Header.Get("X-Twilio-Signature")
+	if got == "" {
+		return false
+	}
+	want := Signature(p.config.AuthToken, p.webhookURL(r), r.PostForm)
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// webhookURL returns the URL Twilio signed.
+func (p *Provider) webhookURL(r *http.Request) string {
+	if p.config.WebhookURL != "" {
+		return p.config.WebhookURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// Signature computes a Twilio request signature.
+func Signature(authToken, webhookURL string, params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(webhookURL)
+	for _, k := range keys {
+		for _, v := range params[k] {
+			b.WriteString(k)
+			b.WriteString(v)
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(b.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Split breaks text into parts of at most maxLen characters, preferring
+// paragraph, line, sentence, and word boundaries.
+func Split(text string, maxLen int) []string {
+	text = strings.TrimSpace(text)
+	var parts []string
+	for text != "" {
+		runes := []rune(text)
+		if len(runes) <= maxLen {
+			parts = append(parts, text)
+			break
+		}
+		head := string(runes[:maxLen])
+		cut := len(head)
+		for _, sep := range []string{"\n\n", "\n", ". ", "! ", "? ", " "} {
+			// Ignore boundaries in the first half to avoid tiny parts
+			if i := strings.LastIndex(head, sep); i > len(head)/2 {
+				cut = i + len(sep)
+				break
+			}
+		}
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	return parts
+}
+
+// Ensure Provider implements provider.Provider and http.Handler.
+var (
+	_ provider.Provider = (*Provider)(nil)
+	_ http.Handler      = (*Provider)(nil)
+)
@@ -0,0 +1,128 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+func TestSplit(t *testing.T) {
+	if parts := Split("short", 160); len(parts) != 1 || parts[0] != "short" {
+		t.Errorf("Split(short) = %q", parts)
+	}
+
+	text := strings.Repeat("word ", 100) + "\n\n" + strings.Repeat("next ", 50)
+	parts := Split(text, 300)
+	if len(parts) < 2 {
+		t.Fatalf("Split = %d parts, want several", len(parts))
+	}
+	for _, p := range parts {
+		if len([]rune(p)) > 300 {
+			t.Errorf("part of %d chars exceeds limit", len([]rune(p)))
+		}
+		if strings.HasPrefix(p, "ord") || strings.HasSuffix(p, " wo") {
+			t.Errorf("part splits a word: %q", p)
+		}
+	}
+	if got := strings.Join(parts, " "); strings.Count(got, "word") != 100 || strings.Count(got, "next") != 50 {
+		t.Error("Split lost text")
+	}
+
+	// Text without boundaries is cut at the limit
+	if parts := Split(strings.Repeat("x", 250), 100); len(parts) != 3 {
+		t.Errorf("Split(no spaces) = %d parts, want 3", len(parts))
+	}
+}
+
+func TestWebhookAndSend(t *testing.T) {
+	var mu sync.Mutex
+	var sent []url.Values
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "AC1" || pass != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/Accounts/AC1/Messages.json" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_ = r.ParseForm()
+		mu.Lock()
+		sent = append(sent, r.PostForm)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer api.Close()
+
+	const webhook = "https://agent.example.com/webhooks/sms"
+	p, err := New(Config{AccountSID: "AC1", AuthToken: "token", From: "+15550000000",
+		WebhookURL: webhook, MaxLength: 20, APIURL: api.URL})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	received := make(chan provider.IncomingMessage, 1)
+	p.OnMessage(func(ctx context.Context, msg provider.IncomingMessage) error {
+		received <- msg
+		return nil
+	})
+
+	form := url.Values{"From": {"+15551112222"}, "To": {"+15553334444"}, "Body": {"hi"}, "MessageSid": {"SM1"}}
+	post := func(signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/sms", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Twilio-Signature", signature)
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("bogus"); rec.Code != http.StatusForbidden {
+		t.Errorf("bad signature = %d, want 403", rec.Code)
+	}
+	rec := post(Signature("token", webhook, form))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<Response>") {
+		t.Fatalf("webhook = %d %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case msg := <-received:
+		if msg.ChatID != "+15551112222" || msg.Content != "hi" || msg.ProviderName != Name {
+			t.Errorf("message = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler not called")
+	}
+
+	// Replies come from the number the user texted, split into parts
+	if err := p.Send(context.Background(), "+15551112222", provider.OutgoingMessage{
+		Content: "This reply is longer than twenty characters.",
+	}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) < 2 {
+		t.Fatalf("sent %d messages, want split reply", len(sent))
+	}
+	for _, m := range sent {
+		if m.Get("From") != "+15553334444" || m.Get("To") != "+15551112222" {
+			t.Errorf("sent From=%s To=%s", m.Get("From"), m.Get("To"))
+		}
+	}
+
+	// Unknown chats use the default number
+	sent = nil
+	mu.Unlock()
+	err = p.Send(context.Background(), "+15559999999", provider.OutgoingMessage{Content: "hello"})
+	mu.Lock()
+	if err != nil || len(sent) != 1 || sent[0].Get("From") != "+15550000000" {
+		t.Errorf("Send to new chat = %v, sent %v", err, sent)
+	}
+}
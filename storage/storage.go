@@ -0,0 +1,160 @@
+// Package storage provides blob storage backends for attachments, exports, and backups.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a blob does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores opaque blobs addressed by slash-separated keys.
+type BlobStore interface {
+	// Put stores data under key, replacing any existing blob.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the blob stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key.
+	Delete(ctx context.Context, key string) error
+	// List returns the blobs whose keys start with prefix.
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+}
+
+// BlobInfo describes a stored blob.
+type BlobInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Config selects and configures a blob store backend.
+type Config struct {
+	// Backend is "local" (default) or "s3".
+	Backend string
+
+	// Path is the root directory for the local backend.
+	Path string
+
+	// S3 configures the S3-compatible backend.
+	S3 S3Config
+}
+
+// New creates a blob store for the configured backend.
+func New(config Config) (BlobStore, error) {
+	switch strings.ToLower(config.Backend) {
+	case "", "local":
+		return NewFileStore(config.Path)
+	case "s3":
+		return NewS3Store(config.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s (must be local or s3)", config.Backend)
+	}
+}
+
+// Appender is implemented by blob stores that can append to a blob in
+// place, such as FileStore.
+type Appender interface {
+	// Append adds data to the end of the blob stored under key, creating
+	// it if needed.
+	Append(ctx context.Context, key string, r io.Reader) error
+}
+
+// Locator is implemented by blob stores whose blobs are files on the
+// local disk.
+type Locator interface {
+	// LocalPath returns the file holding the blob stored under key.
+	LocalPath(key string) (string, bool)
+}
+
+// Append adds data to the end of the blob stored under key, in place when
+// the store is an Appender, and otherwise by rewriting the blob.
+func Append(ctx context.Context, s BlobStore, key string, r io.Reader) error {
+	if a, ok := s.(Appender); ok {
+		return a.Append(ctx, key, r)
+	}
+	var buf bytes.Buffer
+	rc, err := s.Get(ctx, key)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return err
+	default:
+		_, err = io.Copy(&buf, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("read blob: %w", err)
+		}
+	}
+	if _, err := io.Copy(&buf, r); err != nil {
+		return fmt.Errorf("read data: %w", err)
+	}
+	return s.Put(ctx, key, &buf, int64(buf.Len()))
+}
+
+// LocalPath returns the file holding a blob, reporting false when the
+// store keeps blobs elsewhere.
+func LocalPath(s BlobStore, key string) (string, bool) {
+	if l, ok := s.(Locator); ok {
+		return l.LocalPath(key)
+	}
+	return "", false
+}
+
+// WithPrefix returns a view of s holding only the blobs whose keys start
+// with prefix, addressed without it.
+func WithPrefix(s BlobStore, prefix string) BlobStore {
+	return &prefixStore{store: s, prefix: strings.TrimSuffix(prefix, "/") + "/"}
+}
+
+// prefixStore is a BlobStore confined to a key prefix of another.
+type prefixStore struct {
+	store  BlobStore
+	prefix string
+}
+
+func (p *prefixStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	return p.store.Put(ctx, p.prefix+key, r, size)
+}
+
+func (p *prefixStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return p.store.Get(ctx, p.prefix+key)
+}
+
+func (p *prefixStore) Delete(ctx context.Context, key string) error {
+	return p.store.Delete(ctx, p.prefix+key)
+}
+
+func (p *prefixStore) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	blobs, err := p.store.List(ctx, p.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i := range blobs {
+		blobs[i].Key = strings.TrimPrefix(blobs[i].Key, p.prefix)
+	}
+	return blobs, nil
+}
+
+func (p *prefixStore) Append(ctx context.Context, key string, r io.Reader) error {
+	return Append(ctx, p.store, p.prefix+key, r)
+}
+
+func (p *prefixStore) LocalPath(key string) (string, bool) {
+	return LocalPath(p.store, p.prefix+key)
+}
+
+// cleanKey normalizes a blob key and rejects keys that escape the store root.
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, "\\", "/")), "/")
+	if key == "" || key == "." {
+		return "", fmt.Errorf("invalid blob key")
+	}
+	return key, nil
+}
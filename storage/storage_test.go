@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	testBlobStore(ctx, t, store)
+}
+
+func TestFileStoreRejectsEscape(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	p, err := store.path("../../etc/passwd")
+	if err != nil {
+		t.Fatalf("path() error = %v", err)
+	}
+	if !strings.HasPrefix(p, dir) {
+		t.Errorf("path() = %q escapes store root %q", p, dir)
+	}
+}
+
+// fakeS3 is a minimal in-memory S3 server supporting path-style requests.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	authErr string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		!strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date") {
+		f.mu.Lock()
+		f.authErr = auth
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Path is /<bucket>/<key>
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if parts[0] != "bucket" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := ""
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case r.Method == http.MethodGet && key == "":
+		prefix := r.URL.Query().Get("prefix")
+		var result struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []struct {
+				Key  string `xml:"Key"`
+				Size int64  `xml:"Size"`
+			} `xml:"Contents"`
+		}
+		keys := make([]string, 0, len(f.objects))
+		for k := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			result.Contents = append(result.Contents, struct {
+				Key  string `xml:"Key"`
+				Size int64  `xml:"Size"`
+			}{Key: k, Size: int64(len(f.objects[k]))})
+		}
+		_ = xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Prefix:          "/instance-a/",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store() error = %v", err)
+	}
+
+	testBlobStore(ctx, t, store)
+
+	if fake.authErr != "" {
+		t.Errorf("unexpected Authorization header: %q", fake.authErr)
+	}
+
+	// Prefix is applied to stored object keys
+	if err := store.Put(ctx, "x.txt", strings.NewReader("x"), 1); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, ok := fake.objects["instance-a/x.txt"]; !ok {
+		t.Errorf("object not stored under prefix, have keys %v", fake.objects)
+	}
+}
+
+func TestNewS3StoreValidation(t *testing.T) {
+	if _, err := NewS3Store(S3Config{AccessKeyID: "a", SecretAccessKey: "b"}); err == nil {
+		t.Error("NewS3Store() should fail without bucket")
+	}
+	if _, err := NewS3Store(S3Config{Bucket: "b"}); err == nil {
+		t.Error("NewS3Store() should fail without credentials")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{Backend: "local", Path: t.TempDir()}); err != nil {
+		t.Errorf("New(local) error = %v", err)
+	}
+	if _, err := New(Config{Backend: "ftp"}); err == nil {
+		t.Error("New(ftp) should fail")
+	}
+}
+
+func testBlobStore(ctx context.Context, t *testing.T, store BlobStore) {
+	t.Helper()
+
+	if err := store.Put(ctx, "backups/a.txt", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put(ctx, "exports/b.md", strings.NewReader("# hi"), 4); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	rc, err := store.Get(ctx, "backups/a.txt")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello" {
+		t.Errorf("Get() = %q, want %q", data, "hello")
+	}
+
+	blobs, err := store.List(ctx, "backups/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(blobs) != 1 || blobs[0].Key != "backups/a.txt" || blobs[0].Size != 5 {
+		t.Errorf("List() = %+v, want one blob backups/a.txt", blobs)
+	}
+
+	if err := store.Delete(ctx, "backups/a.txt"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "backups/a.txt"); err != ErrNotFound {
+		t.Errorf("Get() after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	// Embedding hides Append, so the rewrite fallback is used
+	stores := map[string]BlobStore{
+		"appender": local,
+		"rewrite":  struct{ BlobStore }{local},
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			key := name + "/log.jsonl"
+			for _, line := range []string{"a\n", "b\n"} {
+				if err := Append(ctx, store, key, strings.NewReader(line)); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+			}
+			rc, err := store.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if string(data) != "a\nb\n" {
+				t.Errorf("blob = %q, want both appends", data)
+			}
+		})
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	ctx := context.Background()
+	root, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	store := WithPrefix(root, "transcripts")
+
+	testBlobStore(ctx, t, store)
+
+	if err := store.Put(ctx, "x.txt", strings.NewReader("x"), 1); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := root.Get(ctx, "transcripts/x.txt"); err != nil {
+		t.Errorf("blob not stored under the prefix: %v", err)
+	}
+	if p, ok := LocalPath(store, "x.txt"); !ok || !strings.HasSuffix(p, "transcripts/x.txt") {
+		t.Errorf("LocalPath() = %q, %v", p, ok)
+	}
+	if _, ok := LocalPath(struct{ BlobStore }{root}, "x.txt"); ok {
+		t.Error("LocalPath() found a file in a store without local files")
+	}
+}
@@ -0,0 +1,268 @@
+// Package supervisor runs goroutines as a group with shared cancellation,
+// panic recovery, and ordered shutdown.
+//
+// A group has two kinds of members. Services, started with Go, run for the
+// life of the group; if one fails or panics the whole group stops. Workers,
+// started with Spawn, are short-lived tasks such as request handlers; their
+// failures are reported but do not stop the group. On shutdown, services
+// are stopped one at a time in reverse start order, so a service started
+// after another (and likely depending on it) stops first. Workers are then
+// given until the shutdown deadline to finish before they are canceled.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// defaultShutdownTimeout bounds Wait's shutdown.
+const defaultShutdownTimeout = 15 * time.Second
+
+// ErrStopped is returned by Go and Spawn once the group is stopping.
+var ErrStopped = errors.New("supervisor: group stopped")
+
+// Event reports a member that failed or panicked.
+type Event struct {
+	Name   string
+	Err    error // Returned error, or a *PanicError
+	Worker bool  // The member was a worker, so the group keeps running
+}
+
+// PanicError is a recovered panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Config configures a group.
+type Config struct {
+	// ShutdownTimeout bounds the shutdown performed by Wait (default: 15s).
+	ShutdownTimeout time.Duration
+
+	// OnEvent is called for each failed or panicking member, in addition
+	// to logging.
+	OnEvent func(Event)
+
+	Logger *slog.Logger
+}
+
+// Group supervises services and workers.
+type Group struct {
+	config   Config
+	logger   *slog.Logger
+	parent   context.Context
+	stopping chan struct{} // Closed when shutdown begins
+
+	// workerCtx is canceled once workers have had until the shutdown
+	// deadline to finish.
+	workerCtx    context.Context
+	cancelWorker context.CancelFunc
+	workers      sync.WaitGroup
+
+	mu       sync.Mutex
+	services []*service
+	stopped  bool
+	err      error // First service failure
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// service is a long-running member with its own cancellation.
+type service struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a group that shuts down when ctx is canceled or a service
+// fails.
+func New(ctx context.Context, config Config) *Group {
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	// Members are canceled individually during shutdown, not all at once
+	// by ctx, so shutdown order holds
+	base := context.WithoutCancel(ctx)
+	workerCtx, cancelWorker := context.WithCancel(base)
+	return &Group{
+		config:       config,
+		logger:       config.Logger,
+		parent:       ctx,
+		stopping:     make(chan struct{}),
+		workerCtx:    workerCtx,
+		cancelWorker: cancelWorker,
+	}
+}
+
+// Go starts a service. Its context is canceled when the group shuts down.
+// If fn returns an error other than context cancellation, or panics, the
+// group shuts down and Wait returns the error.
+func (g *Group) Go(name string, fn func(ctx context.Context) error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return ErrStopped
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(g.parent))
+	s := &service{name: name, cancel: cancel, done: make(chan struct{})}
+	g.services = append(g.services, s)
+
+	go func() {
+		defer close(s.done)
+		err := g.call(name, func() error { return fn(ctx) })
+		if err == nil || errors.Is(err, context.Canceled) {
+			return
+		}
+		select {
+		case <-g.stopping:
+			// Errors while shutting down are reported but not fatal
+			g.report(Event{Name: name, Err: err})
+			return
+		default:
+		}
+		g.report(Event{Name: name, Err: err})
+		g.mu.Lock()
+		if g.err == nil {
+			g.err = fmt.Errorf("%s: %w", name, err)
+		}
+		g.mu.Unlock()
+		g.beginStop()
+	}()
+	return nil
+}
+
+// Spawn starts a worker. Its context is canceled if it is still running at
+// the shutdown deadline. Errors and panics are reported without stopping
+// the group.
+func (g *Group) Spawn(name string, fn func(ctx context.Context) error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return ErrStopped
+	}
+
+	g.workers.Add(1)
+	go func() {
+		defer g.workers.Done()
+		if err := g.call(name, func() error { return fn(g.workerCtx) }); err != nil && !errors.Is(err, context.Canceled) {
+			g.report(Event{Name: name, Err: err, Worker: true})
+		}
+	}()
+	return nil
+}
+
+// call runs fn, converting a panic into a *PanicError.
+func (g *Group) call(name string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// report logs an event and passes it to OnEvent.
+func (g *Group) report(e Event) {
+	attrs := []any{"name", e.Name, "error", e.Err, "worker", e.Worker}
+	var p *PanicError
+	if errors.As(e.Err, &p) {
+		attrs = append(attrs, "stack", string(p.Stack))
+		g.logger.Error("goroutine panicked", attrs...)
+	} else {
+		g.logger.Error("goroutine failed", attrs...)
+	}
+	if g.config.OnEvent != nil {
+		g.config.OnEvent(e)
+	}
+}
+
+// beginStop marks the group as stopping so Wait begins shutdown.
+func (g *Group) beginStop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.stopped {
+		g.stopped = true
+		close(g.stopping)
+	}
+}
+
+// Stop shuts the group down: services in reverse start order, each
+// awaited before the next is canceled, then workers. Workers still running
+// when ctx is done are canceled. Stop returns ctx's error if shutdown did
+// not finish in time, and is safe to call more than once.
+func (g *Group) Stop(ctx context.Context) error {
+	g.beginStop()
+	g.stopOnce.Do(func() {
+		g.stopErr = g.shutdown(ctx)
+	})
+	return g.stopErr
+}
+
+// shutdown performs the ordered shutdown.
+func (g *Group) shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	services := g.services
+	g.mu.Unlock()
+
+	for i := len(services) - 1; i >= 0; i-- {
+		s := services[i]
+		s.cancel()
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			g.logger.Warn("service did not stop in time", "name", s.name)
+			g.cancelWorker()
+			return fmt.Errorf("stop %s: %w", s.name, ctx.Err())
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		g.cancelWorker()
+		return nil
+	case <-ctx.Done():
+		g.logger.Warn("canceling workers still running at shutdown")
+		g.cancelWorker()
+		<-done
+		return nil
+	}
+}
+
+// Wait blocks until ctx is canceled or a service fails, shuts the group
+// down within the configured timeout, and returns the first service
+// failure.
+func (g *Group) Wait() error {
+	select {
+	case <-g.parent.Done():
+	case <-g.stopping:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.config.ShutdownTimeout)
+	defer cancel()
+	stopErr := g.Stop(ctx)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		return g.err
+	}
+	return stopErr
+}
@@ -0,0 +1,107 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStopOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := New(ctx, Config{})
+
+	var mu sync.Mutex
+	var order []string
+	for _, name := range []string{"channels", "scheduler", "gateway"} {
+		_ = g.Go(name, func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return ctx.Err()
+		})
+	}
+
+	cancel()
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if want := []string{"gateway", "scheduler", "channels"}; !slices.Equal(order, want) {
+		t.Errorf("stop order = %v, want %v", order, want)
+	}
+	if err := g.Go("late", func(context.Context) error { return nil }); !errors.Is(err, ErrStopped) {
+		t.Errorf("Go() after stop error = %v, want ErrStopped", err)
+	}
+}
+
+func TestServiceFailureStopsGroup(t *testing.T) {
+	var events []Event
+	g := New(context.Background(), Config{OnEvent: func(e Event) { events = append(events, e) }})
+
+	stopped := make(chan struct{})
+	_ = g.Go("poller", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+	_ = g.Go("server", func(context.Context) error {
+		panic("boom")
+	})
+
+	err := g.Wait()
+	var p *PanicError
+	if !errors.As(err, &p) || p.Value != "boom" {
+		t.Fatalf("Wait() error = %v, want panic", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("other services were not stopped")
+	}
+	if len(events) != 1 || events[0].Name != "server" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := New(ctx, Config{})
+
+	// A failing worker does not stop the group
+	_ = g.Spawn("bad", func(context.Context) error { panic("handler bug") })
+
+	finished := make(chan struct{})
+	_ = g.Spawn("reply", func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		close(finished)
+		return nil
+	})
+	time.Sleep(5 * time.Millisecond)
+
+	// Workers that finish before the deadline are drained
+	cancel()
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("worker was not awaited")
+	}
+
+	// Workers still running at the deadline are canceled
+	g = New(context.Background(), Config{})
+	_ = g.Spawn("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stopCancel()
+	if err := g.Stop(stopCtx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+// Package tasks runs named agent tasks, triggered by inbound webhooks or
+// the scheduler, whose prompts are templates filled from the trigger's
+// payload.
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/pipeline"
+	"github.com/plexusone/omniagent/supervisor"
+)
+
+// ErrNotFound is returned for unknown task names.
+var ErrNotFound = errors.New("task not found")
+
+// maxPayloadBytes limits webhook payloads.
+const maxPayloadBytes = 1 << 20
+
+// Definition is a named task.
+type Definition struct {
+	Name string
+
+	// Prompt is a text/template rendered with the trigger payload as dot,
+	// e.g. "Summarize this CI failure: {{.workflow_run.name}}".
+	Prompt string
+
+	// Channel and ChatID receive the agent's reply. Empty only logs it.
+	Channel string
+	ChatID  string
+
+	// Tools lists the tools the agent may call; empty allows none, "*"
+	// allows all. Payloads are untrusted, so keep this narrow.
+	Tools []string
+
+	// Secret authenticates webhook triggers, as a bearer token or a
+	// GitHub-style X-Hub-Signature-256 HMAC of the body. Tasks without a
+	// secret cannot be triggered by webhook.
+	Secret string
+}
+
+// Processor runs prompts through the agent.
+type Processor interface {
+	Process(ctx context.Context, sessionID, content string) (string, error)
+}
+
+// Config configures a Runner.
+type Config struct {
+	Tasks  []Definition
+	Agent  Processor       // Required
+	Sender pipeline.Sender // Required for tasks with a channel
+	Logger *slog.Logger
+}
+
+// Runner runs named tasks.
+type Runner struct {
+	config Config
+	tasks  map[string]*task
+	logger *slog.Logger
+
+	// workers runs webhook-triggered tasks, which outlive the request.
+	workers *supervisor.Group
+}
+
+type task struct {
+	Definition
+	prompt *template.Template
+}
+
+// New creates a Runner, parsing each task's prompt template.
+func New(config Config) (*Runner, error) {
+	if config.Agent == nil {
+		return nil, fmt.Errorf("agent required")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	r := &Runner{
+		config:  config,
+		tasks:   make(map[string]*task, len(config.Tasks)),
+		logger:  config.Logger,
+		workers: supervisor.New(context.Background(), supervisor.Config{Logger: config.Logger}),
+	}
+	for _, d := range config.Tasks {
+		switch {
+		case d.Name == "":
+			return nil, fmt.Errorf("task name required")
+		case r.tasks[d.Name] != nil:
+			return nil, fmt.Errorf("duplicate task %q", d.Name)
+		case d.Prompt == "":
+			return nil, fmt.Errorf("task %q: prompt required", d.Name)
+		case d.Channel != "" && d.ChatID == "":
+			return nil, fmt.Errorf("task %q: chat_id required with channel", d.Name)
+		case d.Channel != "" && config.Sender == nil:
+			return nil, fmt.Errorf("task %q: no sender for channel %s", d.Name, d.Channel)
+		}
+		tmpl, err := template.New(d.Name).Funcs(funcs).Option("missingkey=zero").Parse(d.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("task %q: parse prompt: %w", d.Name, err)
+		}
+		r.tasks[d.Name] = &task{Definition: d, prompt: tmpl}
+	}
+	return r, nil
+}
+
+// funcs are available in prompt templates.
+var funcs = template.FuncMap{
+	// json renders a value as indented JSON, e.g. {{json .}}.
+	"json": func(v any) (string, error) {
+		b, err := json.MarshalIndent(v, "", "  ")
+		return string(b), err
+	},
+	// truncate shortens text to n characters, e.g. {{truncate 2000 .log}}.
+	"truncate": func(n int, v any) string {
+		s := []rune(fmt.Sprint(v))
+		if len(s) <= n {
+			return string(s)
+		}
+		return string(s[:n]) + "…"
+	},
+	"now": time.Now,
+}
+
+// Has reports whether a task is defined.
+func (r *Runner) Has(name string) bool {
+	return r.tasks[name] != nil
+}
+
+// Run renders a task's prompt with payload, runs it through the agent with
+// the task's tools, and delivers the reply. It returns the reply.
+func (r *Runner) Run(ctx context.Context, name string, payload any) (string, error) {
+	t := r.tasks[name]
+	if t == nil {
+		return "", ErrNotFound
+	}
+
+	var prompt bytes.Buffer
+	if err := t.prompt.Execute(&prompt, payload); err != nil {
+		return "", fmt.Errorf("render prompt: %w", err)
+	}
+	// Missing payload fields render as "<no value>" in maps; drop them
+	content := strings.ReplaceAll(prompt.String(), "<no value>", "")
+	if len(t.Tools) != 1 || t.Tools[0] != "*" {
+		ctx = agent.WithAllowedTools(ctx, t.Tools)
+	}
+
+	reply, err := r.config.Agent.Process(ctx, "task:"+name, content)
+	if err != nil {
+		return "", err
+	}
+	if t.Channel == "" {
+		r.logger.Info("task completed", "task", name, "output_length", len(reply))
+		return reply, nil
+	}
+	if err := r.config.Sender.Send(ctx, t.Channel, t.ChatID, provider.OutgoingMessage{Content: reply}); err != nil {
+		return reply, fmt.Errorf("deliver reply: %w", err)
+	}
+	return reply, nil
+}
+
+// ServeHTTP triggers the task named by the last path element (or the
+// "name" path value) with the request body as payload. JSON bodies are
+// decoded; form bodies become a map of fields; anything else is passed as
+// a string. The task runs in the background and the request is answered
+// with 202.
+func (r *Runner) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := req.PathValue("name")
+	if name == "" {
+		name = path.Base(req.URL.Path)
+	}
+	t := r.tasks[name]
+	if t == nil {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxPayloadBytes))
+	if err != nil {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !authorized(t.Secret, req, body) {
+		r.logger.Warn("rejected task webhook", "task", name, "remote", req.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	payload, err := decodePayload(req.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = r.workers.Spawn("task:"+name, func(ctx context.Context) error {
+		_, err := r.Run(ctx, name, payload)
+		return err
+	})
+	if err != nil {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Stop waits, until ctx is done, for webhook-triggered tasks to finish.
+// Webhooks received afterwards are rejected.
+func (r *Runner) Stop(ctx context.Context) error {
+	return r.workers.Stop(ctx)
+}
+
+// authorized checks a bearer token or X-Hub-Signature-256 against secret.
+// Tasks without a secret are never authorized.
+func authorized(secret string, req *http.Request, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+	if sig, ok := strings.CutPrefix(req.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+	return false
+}
+
+// decodePayload converts a webhook body to template data.
+func decodePayload(contentType string, body []byte) (any, error) {
+	switch {
+	case len(bytes.TrimSpace(body)) == 0:
+		return map[string]any{}, nil
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		values, err := parseForm(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid form body")
+		}
+		return values, nil
+	case strings.Contains(contentType, "json") || json.Valid(body):
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil, fmt.Errorf("invalid JSON body")
+		}
+		return v, nil
+	default:
+		return string(body), nil
+	}
+}
+
+// parseForm decodes a form body to a map of single values.
+func parseForm(body []byte) (map[string]any, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any, len(form))
+	for k := range form {
+		values[k] = form.Get(k)
+	}
+	return values, nil
+}
+
+// Ensure Runner implements http.Handler.
+var _ http.Handler = (*Runner)(nil)
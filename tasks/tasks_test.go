@@ -0,0 +1,140 @@
+package tasks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omnichat/provider"
+)
+
+// recorder is a Processor and Sender that records calls.
+type recorder struct {
+	mu      sync.Mutex
+	prompts []string
+	sent    []string
+	done    chan struct{}
+}
+
+func (r *recorder) Process(ctx context.Context, sessionID, content string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prompts = append(r.prompts, content)
+	return "summary", nil
+}
+
+func (r *recorder) Send(ctx context.Context, providerName, chatID string, msg provider.OutgoingMessage) error {
+	r.mu.Lock()
+	r.sent = append(r.sent, providerName+":"+chatID+":"+msg.Content)
+	r.mu.Unlock()
+	if r.done != nil {
+		r.done <- struct{}{}
+	}
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	rec := &recorder{}
+	r, err := New(Config{
+		Tasks: []Definition{{
+			Name:    "ci-failure",
+			Prompt:  "Summarize the failure of {{.workflow.name}} ({{.missing}}): {{truncate 5 .log}}",
+			Channel: "telegram",
+			ChatID:  "42",
+		}},
+		Agent:  rec,
+		Sender: rec,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	payload := map[string]any{"workflow": map[string]any{"name": "build"}, "log": "panic: nil map"}
+	if _, err := r.Run(context.Background(), "ci-failure", payload); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := "Summarize the failure of build (): panic…"; rec.prompts[0] != want {
+		t.Errorf("prompt = %q, want %q", rec.prompts[0], want)
+	}
+	if len(rec.sent) != 1 || rec.sent[0] != "telegram:42:summary" {
+		t.Errorf("sent = %v", rec.sent)
+	}
+
+	if _, err := r.Run(context.Background(), "unknown", nil); err != ErrNotFound {
+		t.Errorf("Run(unknown) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	rec := &recorder{}
+	for name, def := range map[string]Definition{
+		"no prompt":    {Name: "a"},
+		"bad template": {Name: "a", Prompt: "{{.x"},
+		"no chat":      {Name: "a", Prompt: "p", Channel: "telegram"},
+	} {
+		if _, err := New(Config{Tasks: []Definition{def}, Agent: rec, Sender: rec}); err == nil {
+			t.Errorf("%s: New succeeded", name)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	rec := &recorder{done: make(chan struct{}, 1)}
+	r, err := New(Config{
+		Tasks: []Definition{
+			{Name: "deploy", Prompt: "Deployed {{.version}}", Channel: "sms", ChatID: "+1555", Secret: "s3cret"},
+			{Name: "nightly", Prompt: "Report"},
+		},
+		Agent:  rec,
+		Sender: rec,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	post := func(name, body string, header map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/tasks/"+name, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	body := `{"version":"v1.2.0"}`
+	if code := post("deploy", body, nil); code != http.StatusUnauthorized {
+		t.Errorf("without secret = %d, want 401", code)
+	}
+	if code := post("nightly", body, nil); code != http.StatusUnauthorized {
+		t.Errorf("task without secret = %d, want 401", code)
+	}
+	if code := post("missing", body, nil); code != http.StatusNotFound {
+		t.Errorf("unknown task = %d, want 404", code)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+	sig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if code := post("deploy", body, map[string]string{"X-Hub-Signature-256": sig}); code != http.StatusAccepted {
+		t.Fatalf("signed webhook = %d, want 202", code)
+	}
+	select {
+	case <-rec.done:
+	case <-time.After(time.Second):
+		t.Fatal("task did not run")
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.prompts[0] != "Deployed v1.2.0" {
+		t.Errorf("prompt = %q", rec.prompts[0])
+	}
+}
@@ -0,0 +1,199 @@
+// Package templates keeps canned responses the agent sends with standard
+// wording, such as meeting proposals or invoice replies.
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotFound is returned for an unknown template.
+var ErrNotFound = errors.New("template not found")
+
+// placeholder matches {name} in a template's text.
+var placeholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// validName matches template names, which are typed in commands.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Template is a canned response. Its text may contain {placeholders}
+// filled in when it is used.
+type Template struct {
+	Name        string `yaml:"-"`
+	Description string `yaml:"description,omitempty"` // When to use it
+	Text        string `yaml:"text"`
+}
+
+// Variables returns the template's placeholder names in order of first
+// use.
+func (t Template) Variables() []string {
+	var vars []string
+	for _, m := range placeholder.FindAllStringSubmatch(t.Text, -1) {
+		if !slices.Contains(vars, m[1]) {
+			vars = append(vars, m[1])
+		}
+	}
+	return vars
+}
+
+// Render fills in the placeholders. Every placeholder needs a value.
+func (t Template) Render(values map[string]string) (string, error) {
+	var missing []string
+	for _, v := range t.Variables() {
+		if _, ok := values[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %q needs values for: %s", t.Name, strings.Join(missing, ", "))
+	}
+	return placeholder.ReplaceAllStringFunc(t.Text, func(m string) string {
+		return values[m[1:len(m)-1]]
+	}), nil
+}
+
+// Store keeps templates in a YAML file mapping names to templates, which
+// may be edited by hand or with "omniagent templates". Changes to the file
+// are picked up on the next read.
+type Store struct {
+	path      string
+	templates map[string]Template
+	modTime   time.Time
+	mu        sync.Mutex
+}
+
+// Open loads the template file at path; a missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, templates: make(map[string]Template)}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload reads the file if it changed since it was last read. Callers
+// must hold the lock, except in Open.
+func (s *Store) reload() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.templates = make(map[string]Template)
+		s.modTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read templates: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("read templates: %w", err)
+	}
+	templates := make(map[string]Template)
+	if err := yaml.Unmarshal(data, &templates); err != nil {
+		return fmt.Errorf("parse templates: %w", err)
+	}
+	s.templates = make(map[string]Template, len(templates))
+	for name, t := range templates {
+		t.Name = strings.ToLower(name)
+		s.templates[t.Name] = t
+	}
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// Get returns a template by name.
+func (s *Store) Get(name string) (Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return Template{}, err
+	}
+	t, ok := s.templates[strings.ToLower(name)]
+	if !ok {
+		return Template{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return t, nil
+}
+
+// List returns the templates sorted by name.
+func (s *Store) List() ([]Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	list := make([]Template, 0, len(s.templates))
+	for _, t := range s.templates {
+		list = append(list, t)
+	}
+	slices.SortFunc(list, func(a, b Template) int { return strings.Compare(a.Name, b.Name) })
+	return list, nil
+}
+
+// Put adds or replaces a template.
+func (s *Store) Put(t Template) error {
+	t.Name = strings.ToLower(strings.TrimSpace(t.Name))
+	if !validName.MatchString(t.Name) {
+		return fmt.Errorf("invalid template name %q: use lowercase letters, digits, - and _", t.Name)
+	}
+	if strings.TrimSpace(t.Text) == "" {
+		return errors.New("template text is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return err
+	}
+	s.templates[t.Name] = t
+	return s.save()
+}
+
+// Remove deletes a template.
+func (s *Store) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return err
+	}
+	name = strings.ToLower(name)
+	if _, ok := s.templates[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(s.templates, name)
+	return s.save()
+}
+
+// save writes the template file. Callers must hold the lock.
+func (s *Store) save() error {
+	data, err := yaml.Marshal(s.templates)
+	if err != nil {
+		return fmt.Errorf("encode templates: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("create templates dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write templates: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("write templates: %w", err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.yaml")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(Template{Name: "Bad Name", Text: "x"}); err == nil {
+		t.Error("expected an invalid name to be rejected")
+	}
+	if err := s.Put(Template{Name: "meeting", Text: "Hi {name}, does {day} work? Thanks, {name}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := s.Get("Meeting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tmpl.Variables(); len(got) != 2 || got[0] != "name" || got[1] != "day" {
+		t.Errorf("Variables() = %v", got)
+	}
+	if _, err := tmpl.Render(map[string]string{"name": "Ada"}); err == nil || !strings.Contains(err.Error(), "day") {
+		t.Errorf("Render() with a missing value: %v", err)
+	}
+	got, err := tmpl.Render(map[string]string{"name": "Ada", "day": "Tuesday"})
+	if err != nil || got != "Hi Ada, does Tuesday work? Thanks, Ada" {
+		t.Errorf("Render() = %q, %v", got, err)
+	}
+
+	// Hand edits are picked up
+	data := "invoice-paid:\n  description: Confirm a payment\n  text: Invoice {number} is paid.\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.List()
+	if err != nil || len(list) != 1 || list[0].Name != "invoice-paid" {
+		t.Fatalf("List() after edit = %+v, %v", list, err)
+	}
+	if err := s.Remove("meeting"); err == nil {
+		t.Error("expected removing a missing template to fail")
+	}
+}
+
+func TestToolAndCommand(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "templates.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(Template{Name: "meeting", Description: "Propose a time", Text: "Hi {name}, how about {day}?"}); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewTool(s)
+	ctx := context.Background()
+
+	list, err := tool.Execute(ctx, json.RawMessage(`{}`))
+	if err != nil || list != "- meeting: Propose a time (placeholders: name, day)" {
+		t.Errorf("list = %q, %v", list, err)
+	}
+	got, err := tool.Execute(ctx, json.RawMessage(`{"name":"meeting","values":{"name":"Ada","day":"Friday"}}`))
+	if err != nil || got != "Hi Ada, how about Friday?" {
+		t.Errorf("use_template = %q, %v", got, err)
+	}
+
+	handler := tool.Command().Handler
+	got, err = handler(ctx, "test:1", `meeting name="Ada Lovelace" day=Monday`)
+	if err != nil || got != "Hi Ada Lovelace, how about Monday?" {
+		t.Errorf("/send-template = %q, %v", got, err)
+	}
+	got, _ = handler(ctx, "test:1", "meeting name=Ada")
+	if !strings.Contains(got, "day") {
+		t.Errorf("/send-template with a missing value = %q", got)
+	}
+}
@@ -0,0 +1,176 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Tool lets the agent fill in templates for its replies.
+type Tool struct {
+	store *Store
+}
+
+// NewTool creates the use_template tool.
+func NewTool(store *Store) *Tool {
+	return &Tool{store: store}
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "use_template"
+}
+
+// Description returns the tool description with the current templates.
+func (t *Tool) Description() string {
+	desc := "Fill in a canned response so replies on common topics use approved wording. " +
+		"Send the returned text to the user as written, without rewording it. " +
+		"Call without a name to list the templates and their placeholders."
+	list, err := t.store.List()
+	if err != nil || len(list) == 0 {
+		return desc
+	}
+	names := make([]string, len(list))
+	for i, tmpl := range list {
+		names[i] = tmpl.Name
+	}
+	return desc + " Templates: " + strings.Join(names, ", ") + "."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Template to use; omit to list templates",
+			},
+			"values": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Text for each {placeholder} in the template",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+}
+
+// Execute lists the templates or renders one.
+func (t *Tool) Execute(_ context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Name   string            `json:"name"`
+		Values map[string]string `json:"values"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.Name == "" {
+		return t.list()
+	}
+	tmpl, err := t.store.Get(params.Name)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(params.Values)
+}
+
+// list describes each template and its placeholders.
+func (t *Tool) list() (string, error) {
+	list, err := t.store.List()
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return "No templates are defined.", nil
+	}
+	var b strings.Builder
+	for _, tmpl := range list {
+		fmt.Fprintf(&b, "- %s", tmpl.Name)
+		if tmpl.Description != "" {
+			fmt.Fprintf(&b, ": %s", tmpl.Description)
+		}
+		if vars := tmpl.Variables(); len(vars) > 0 {
+			fmt.Fprintf(&b, " (placeholders: %s)", strings.Join(vars, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// Command returns /send-template, which replies with a template directly:
+// "/send-template invoice-paid number=1042 amount=$120". Quote values
+// with spaces: name="Ada Lovelace".
+func (t *Tool) Command() agent.Command {
+	return agent.Command{
+		Name:        "send-template",
+		Description: "Send a canned response",
+		Usage:       "/send-template [name] [key=value ...]",
+		Handler: func(_ context.Context, _, args string) (string, error) {
+			fields, err := splitArgs(args)
+			if err != nil {
+				return "", err
+			}
+			if len(fields) == 0 {
+				return t.list()
+			}
+			tmpl, err := t.store.Get(fields[0])
+			if errors.Is(err, ErrNotFound) {
+				return fmt.Sprintf("No template %q. Send /send-template to list them.", fields[0]), nil
+			}
+			if err != nil {
+				return "", err
+			}
+			values := make(map[string]string)
+			for _, f := range fields[1:] {
+				k, v, ok := strings.Cut(f, "=")
+				if !ok {
+					return fmt.Sprintf("Expected key=value, got %q.", f), nil
+				}
+				values[k] = v
+			}
+			text, err := tmpl.Render(values)
+			if err != nil {
+				return err.Error() + ".", nil
+			}
+			return text, nil
+		},
+	}
+}
+
+// splitArgs splits command arguments on spaces, keeping double-quoted
+// text together and dropping the quotes.
+func splitArgs(s string) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	inQuotes, inField := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inField = true
+		case r == ' ' && !inQuotes:
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if inQuotes {
+		return nil, errors.New("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
+
+// Ensure Tool implements agent.Tool.
+var _ agent.Tool = (*Tool)(nil)
@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-rod/rod"
@@ -16,19 +18,65 @@ import (
 	"github.com/plexusone/omniagent/agent"
 )
 
-// Tool provides browser automation capabilities.
+// Defaults for browser resource limits.
+const (
+	defaultMaxSessions   = 4
+	defaultMaxConcurrent = 2
+	defaultIdleTimeout   = 5 * time.Minute
+)
+
+// Tool provides browser automation capabilities. Each agent session gets
+// its own incognito context and page, so concurrent conversations never
+// share cookies or interleave actions. The browser process is launched on
+// first use and shut down after it has been idle.
 type Tool struct {
+	headless    bool
+	userData    string
+	maxSessions int
+	idleTimeout time.Duration
+	slots       chan struct{} // Limits actions running at once
+	logger      *slog.Logger
+
+	mu       sync.Mutex
+	launcher *launcher.Launcher
 	browser  *rod.Browser
+	sessions map[string]*session
+	lastUsed time.Time
+	stop     chan struct{} // Stops the idle reaper
+
+	launches atomic.Int64
+	actions  atomic.Int64
+	failures atomic.Int64
+	waiting  atomic.Int64
+}
+
+// session is one conversation's incognito context and page.
+type session struct {
+	mu       sync.Mutex // Serializes actions within the session
+	context  *rod.Browser
 	page     *rod.Page
-	headless bool
-	logger   *slog.Logger
+	inUse    int
+	lastUsed time.Time
 }
 
 // Config configures the browser tool.
 type Config struct {
 	Headless bool
 	UserData string
-	Logger   *slog.Logger
+
+	// MaxSessions caps open incognito contexts; the least recently used
+	// idle one is closed to make room (default: 4).
+	MaxSessions int
+
+	// MaxConcurrent caps actions running at once across sessions
+	// (default: 2). Further actions wait their turn.
+	MaxConcurrent int
+
+	// IdleTimeout closes a session's context, and the browser once no
+	// sessions remain, after this long without use (default: 5m).
+	IdleTimeout time.Duration
+
+	Logger *slog.Logger
 }
 
 // New creates a new browser tool.
@@ -36,10 +84,24 @@ func New(config Config) (*Tool, error) {
 	if config.Logger == nil {
 		config.Logger = slog.Default()
 	}
+	if config.MaxSessions <= 0 {
+		config.MaxSessions = defaultMaxSessions
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = defaultMaxConcurrent
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultIdleTimeout
+	}
 
 	return &Tool{
-		headless: config.Headless,
-		logger:   config.Logger,
+		headless:    config.Headless,
+		userData:    config.UserData,
+		maxSessions: config.MaxSessions,
+		idleTimeout: config.IdleTimeout,
+		slots:       make(chan struct{}, config.MaxConcurrent),
+		logger:      config.Logger,
+		sessions:    make(map[string]*session),
 	}, nil
 }
 
@@ -50,7 +112,8 @@ func (t *Tool) Name() string {
 
 // Description returns the tool description.
 func (t *Tool) Description() string {
-	return "Control a web browser to navigate pages, click elements, fill forms, and take screenshots."
+	return "Control a web browser to navigate pages, click elements, fill forms, and take screenshots. " +
+		"Each conversation has its own private browser session that keeps its page between calls."
 }
 
 // Parameters returns the JSON schema for tool parameters.
@@ -102,85 +165,288 @@ func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error
 		params.Timeout = 30
 	}
 
-	// Ensure browser is launched
-	if err := t.ensureBrowser(); err != nil {
-		return "", err
-	}
-
 	timeout := time.Duration(params.Timeout) * time.Second
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	switch params.Action {
+	// Wait for a free slot so a burst of sessions cannot exhaust the host
+	t.waiting.Add(1)
+	select {
+	case t.slots <- struct{}{}:
+		t.waiting.Add(-1)
+	case <-ctx.Done():
+		t.waiting.Add(-1)
+		return "", fmt.Errorf("browser busy: %w", ctx.Err())
+	}
+	defer func() { <-t.slots }()
+
+	s, err := t.acquire(agent.SessionFromContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer t.release(s)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.actions.Add(1)
+	result, err := s.do(ctx, params.Action, params.URL, params.Selector, params.Text)
+	if err != nil {
+		t.failures.Add(1)
+	}
+	return result, err
+}
+
+// do runs one action on the session's page.
+func (s *session) do(ctx context.Context, action, url, selector, text string) (string, error) {
+	switch action {
 	case "navigate":
-		return t.navigate(ctx, params.URL)
+		return s.navigate(ctx, url)
 	case "click":
-		return t.click(ctx, params.Selector)
+		return s.click(ctx, selector)
 	case "type":
-		return t.typeText(ctx, params.Selector, params.Text)
+		return s.typeText(ctx, selector, text)
 	case "screenshot":
-		return t.screenshot(ctx)
+		return s.screenshot(ctx)
 	case "get_text":
-		return t.getText(ctx, params.Selector)
+		return s.getText(ctx, selector)
 	case "wait":
-		return t.wait(ctx, params.Selector)
+		return s.wait(ctx, selector)
 	default:
-		return "", fmt.Errorf("unknown action: %s", params.Action)
+		return "", fmt.Errorf("unknown action: %s", action)
+	}
+}
+
+// acquire returns the session's context, launching the browser and
+// creating the context as needed.
+func (t *Tool) acquire(sessionID string) (*session, error) {
+	if sessionID == "" {
+		sessionID = "default"
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.ensureBrowser(); err != nil {
+		return nil, err
+	}
+
+	s, ok := t.sessions[sessionID]
+	if !ok {
+		if len(t.sessions) >= t.maxSessions && !t.evictLocked() {
+			return nil, fmt.Errorf("browser busy: all %d sessions are in use", t.maxSessions)
+		}
+		incognito, err := t.browser.Incognito()
+		if err != nil {
+			return nil, fmt.Errorf("create browser context: %w", err)
+		}
+		page, err := incognito.Page(proto.TargetCreateTarget{URL: "about:blank"})
+		if err != nil {
+			_ = incognito.Close()
+			return nil, fmt.Errorf("create page: %w", err)
+		}
+		s = &session{context: incognito, page: page}
+		t.sessions[sessionID] = s
 	}
+	s.inUse++
+	s.lastUsed = time.Now()
+	t.lastUsed = s.lastUsed
+	return s, nil
 }
 
-// ensureBrowser ensures the browser is launched.
+// release marks a session's action as finished.
+func (t *Tool) release(s *session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.inUse--
+	s.lastUsed = time.Now()
+	t.lastUsed = s.lastUsed
+}
+
+// evictLocked closes the least recently used idle session. It reports
+// false if every session is in use. Callers hold t.mu.
+func (t *Tool) evictLocked() bool {
+	var oldestID string
+	var oldest *session
+	for id, s := range t.sessions {
+		if s.inUse == 0 && (oldest == nil || s.lastUsed.Before(oldest.lastUsed)) {
+			oldestID, oldest = id, s
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	t.closeSessionLocked(oldestID, oldest)
+	return true
+}
+
+// closeSessionLocked disposes of a session's context. Callers hold t.mu.
+func (t *Tool) closeSessionLocked(id string, s *session) {
+	delete(t.sessions, id)
+	if err := s.context.Close(); err != nil {
+		t.logger.Debug("close browser context", "error", err)
+	}
+}
+
+// ensureBrowser launches the browser if it is not running. Callers hold
+// t.mu.
 func (t *Tool) ensureBrowser() error {
 	if t.browser != nil {
 		return nil
 	}
 
 	l := launcher.New().Headless(t.headless)
+	if t.userData != "" {
+		l = l.UserDataDir(t.userData)
+	}
 	url, err := l.Launch()
 	if err != nil {
 		return fmt.Errorf("launch browser: %w", err)
 	}
 
-	t.browser = rod.New().ControlURL(url)
-	if err := t.browser.Connect(); err != nil {
+	browser := rod.New().ControlURL(url)
+	if err := browser.Connect(); err != nil {
+		l.Kill()
 		return fmt.Errorf("connect browser: %w", err)
 	}
 
-	t.page, err = t.browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
-	if err != nil {
-		return fmt.Errorf("create page: %w", err)
-	}
+	t.launcher = l
+	t.browser = browser
+	t.lastUsed = time.Now()
+	t.stop = make(chan struct{})
+	t.launches.Add(1)
+	go t.reapIdle(t.stop)
 
-	t.logger.Info("browser launched", "headless", t.headless)
+	t.logger.Info("browser launched", "headless", t.headless, "pid", l.PID())
 	return nil
 }
 
+// reapIdle closes idle sessions, and the browser once none remain, until
+// stop is closed.
+func (t *Tool) reapIdle(stop chan struct{}) {
+	interval := max(t.idleTimeout/2, time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		t.mu.Lock()
+		now := time.Now()
+		for id, s := range t.sessions {
+			if s.inUse == 0 && now.Sub(s.lastUsed) >= t.idleTimeout {
+				t.closeSessionLocked(id, s)
+			}
+		}
+		if len(t.sessions) == 0 && now.Sub(t.lastUsed) >= t.idleTimeout {
+			t.logger.Info("closing idle browser", "idle", now.Sub(t.lastUsed).Round(time.Second))
+			t.shutdownLocked()
+			t.mu.Unlock()
+			return
+		}
+		t.mu.Unlock()
+	}
+}
+
+// shutdownLocked closes all sessions and stops the browser process.
+// Callers hold t.mu.
+func (t *Tool) shutdownLocked() error {
+	if t.browser == nil {
+		return nil
+	}
+	for id, s := range t.sessions {
+		t.closeSessionLocked(id, s)
+	}
+	close(t.stop)
+	err := t.browser.Close()
+	t.launcher.Kill()
+	t.launcher.Cleanup()
+	t.browser, t.launcher, t.stop = nil, nil, nil
+	return err
+}
+
+// Stats reports browser resource usage.
+type Stats struct {
+	Running    bool    // Browser process is up
+	Sessions   int     // Open incognito contexts
+	Active     int     // Actions running
+	Waiting    int     // Actions waiting for a slot
+	Launches   int64   // Times the browser has been launched
+	Actions    int64   // Actions run
+	Failures   int64   // Actions that returned an error
+	Processes  int     // Browser processes (renderers, GPU, ...)
+	CPUSeconds float64 // CPU time used across browser processes
+	HeapBytes  int64   // JavaScript heap in use across session pages
+}
+
+// Stats returns current usage. Process and heap figures are queried from
+// the browser and are zero when it is not running.
+func (t *Tool) Stats(ctx context.Context) Stats {
+	st := Stats{
+		Active:   len(t.slots),
+		Waiting:  int(t.waiting.Load()),
+		Launches: t.launches.Load(),
+		Actions:  t.actions.Load(),
+		Failures: t.failures.Load(),
+	}
+
+	t.mu.Lock()
+	browser := t.browser
+	pages := make([]*rod.Page, 0, len(t.sessions))
+	for _, s := range t.sessions {
+		pages = append(pages, s.page)
+	}
+	t.mu.Unlock()
+	if browser == nil {
+		return st
+	}
+	st.Running = true
+	st.Sessions = len(pages)
+
+	if info, err := (proto.SystemInfoGetProcessInfo{}).Call(browser.Context(ctx)); err == nil {
+		st.Processes = len(info.ProcessInfo)
+		for _, p := range info.ProcessInfo {
+			st.CPUSeconds += p.CPUTime
+		}
+	}
+	for _, page := range pages {
+		if heap, err := (proto.RuntimeGetHeapUsage{}).Call(page.Context(ctx)); err == nil {
+			st.HeapBytes += int64(heap.UsedSize)
+		}
+	}
+	return st
+}
+
 // navigate navigates to a URL.
-func (t *Tool) navigate(ctx context.Context, url string) (string, error) {
+func (s *session) navigate(ctx context.Context, url string) (string, error) {
 	if url == "" {
 		return "", fmt.Errorf("url required for navigate action")
 	}
+	agent.ReportProgress(ctx, "Loading "+url)
 
-	if err := t.page.Context(ctx).Navigate(url); err != nil {
+	if err := s.page.Context(ctx).Navigate(url); err != nil {
 		return "", fmt.Errorf("navigate: %w", err)
 	}
 
-	if err := t.page.WaitStable(time.Second); err != nil {
+	if err := s.page.WaitStable(time.Second); err != nil {
 		return "", fmt.Errorf("wait stable: %w", err)
 	}
 
-	title := t.page.MustInfo().Title
+	title := s.page.MustInfo().Title
 
 	return fmt.Sprintf("Navigated to: %s (title: %s)", url, title), nil
 }
 
 // click clicks an element.
-func (t *Tool) click(ctx context.Context, selector string) (string, error) {
+func (s *session) click(ctx context.Context, selector string) (string, error) {
 	if selector == "" {
 		return "", fmt.Errorf("selector required for click action")
 	}
 
-	el, err := t.page.Context(ctx).Element(selector)
+	el, err := s.page.Context(ctx).Element(selector)
 	if err != nil {
 		return "", fmt.Errorf("find element: %w", err)
 	}
@@ -193,12 +459,12 @@ func (t *Tool) click(ctx context.Context, selector string) (string, error) {
 }
 
 // typeText types text into an element.
-func (t *Tool) typeText(ctx context.Context, selector, text string) (string, error) {
+func (s *session) typeText(ctx context.Context, selector, text string) (string, error) {
 	if selector == "" {
 		return "", fmt.Errorf("selector required for type action")
 	}
 
-	el, err := t.page.Context(ctx).Element(selector)
+	el, err := s.page.Context(ctx).Element(selector)
 	if err != nil {
 		return "", fmt.Errorf("find element: %w", err)
 	}
@@ -211,8 +477,8 @@ func (t *Tool) typeText(ctx context.Context, selector, text string) (string, err
 }
 
 // screenshot takes a screenshot.
-func (t *Tool) screenshot(ctx context.Context) (string, error) {
-	data, err := t.page.Context(ctx).Screenshot(false, nil)
+func (s *session) screenshot(ctx context.Context) (string, error) {
+	data, err := s.page.Context(ctx).Screenshot(false, nil)
 	if err != nil {
 		return "", fmt.Errorf("screenshot: %w", err)
 	}
@@ -223,12 +489,12 @@ func (t *Tool) screenshot(ctx context.Context) (string, error) {
 }
 
 // getText gets text from an element.
-func (t *Tool) getText(ctx context.Context, selector string) (string, error) {
+func (s *session) getText(ctx context.Context, selector string) (string, error) {
 	if selector == "" {
 		return "", fmt.Errorf("selector required for get_text action")
 	}
 
-	el, err := t.page.Context(ctx).Element(selector)
+	el, err := s.page.Context(ctx).Element(selector)
 	if err != nil {
 		return "", fmt.Errorf("find element: %w", err)
 	}
@@ -242,12 +508,12 @@ func (t *Tool) getText(ctx context.Context, selector string) (string, error) {
 }
 
 // wait waits for an element to appear.
-func (t *Tool) wait(ctx context.Context, selector string) (string, error) {
+func (s *session) wait(ctx context.Context, selector string) (string, error) {
 	if selector == "" {
 		return "", fmt.Errorf("selector required for wait action")
 	}
 
-	_, err := t.page.Context(ctx).Element(selector)
+	_, err := s.page.Context(ctx).Element(selector)
 	if err != nil {
 		return "", fmt.Errorf("wait for element: %w", err)
 	}
@@ -255,12 +521,11 @@ func (t *Tool) wait(ctx context.Context, selector string) (string, error) {
 	return fmt.Sprintf("Element found: %s", selector), nil
 }
 
-// Close closes the browser.
+// Close closes all sessions and the browser.
 func (t *Tool) Close() error {
-	if t.browser != nil {
-		return t.browser.Close()
-	}
-	return nil
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.shutdownLocked()
 }
 
 // Ensure Tool implements agent.Tool interface.
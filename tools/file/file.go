@@ -0,0 +1,241 @@
+// Package file provides a local file tool limited to allowed directories.
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/sandbox"
+)
+
+// DefaultMaxFileSize limits reads and writes to 256 KB.
+const DefaultMaxFileSize = 256 << 10
+
+// maxMatches caps the entries returned by list_dir and glob.
+const maxMatches = 200
+
+// Tool reads, writes, and lists files inside allowed directories. Access is
+// enforced by sandbox.HostFunctions, which resolves symlinks before
+// checking paths.
+type Tool struct {
+	host        *sandbox.HostFunctions
+	paths       []string
+	write       bool
+	maxFileSize int
+	logger      *slog.Logger
+}
+
+// Config configures the file tool.
+type Config struct {
+	// Paths are the directories the tool may access. Relative paths in
+	// tool calls resolve against the first one.
+	Paths       []string
+	Write       bool // Allow write_file; reading is always allowed
+	MaxFileSize int  // Bytes; default DefaultMaxFileSize
+	Logger      *slog.Logger
+}
+
+// New creates a new file tool.
+func New(config Config) (*Tool, error) {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = DefaultMaxFileSize
+	}
+	if len(config.Paths) == 0 {
+		return nil, errors.New("at least one path must be allowed")
+	}
+
+	paths := make([]string, len(config.Paths))
+	for i, p := range config.Paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("resolve path %q: %w", p, err)
+		}
+		paths[i] = abs
+	}
+
+	caps := []sandbox.Capability{sandbox.CapFSRead}
+	if config.Write {
+		caps = append(caps, sandbox.CapFSWrite)
+	}
+	sc := sandbox.DefaultConfig()
+	sc.Capabilities = caps
+	sc.AllowedPaths = paths
+	sc.MaxOutputBytes = config.MaxFileSize + 1 // One more to detect truncation
+
+	return &Tool{
+		host:        sandbox.NewHostFunctions(sc),
+		paths:       paths,
+		write:       config.Write,
+		maxFileSize: config.MaxFileSize,
+		logger:      config.Logger,
+	}, nil
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "file"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	mode := "read-only"
+	if t.write {
+		mode = "read-write"
+	}
+	return "Read, write, and list local files. " +
+		"Relative paths are resolved against " + t.paths[0] + ". " +
+		"Accessible directories (" + mode + "): " + strings.Join(t.paths, ", ") + "."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"read_file", "write_file", "list_dir", "glob"},
+				"description": "The operation to perform",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File to read or write, directory to list, or glob pattern such as docs/*.md",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to write (write_file only); replaces the file",
+			},
+		},
+		"required": []string{"action", "path"},
+	}
+}
+
+// Execute performs a file operation.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Action  string `json:"action"`
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("path required")
+	}
+	path := t.resolve(params.Path)
+
+	t.logger.Info("file tool", "action", params.Action, "path", path)
+	switch params.Action {
+	case "read_file":
+		return t.read(ctx, path)
+	case "write_file":
+		return t.writeFile(ctx, path, params.Content)
+	case "list_dir":
+		return t.list(ctx, path)
+	case "glob":
+		return t.glob(ctx, path)
+	default:
+		return "", fmt.Errorf("unknown action %q", params.Action)
+	}
+}
+
+func (t *Tool) read(ctx context.Context, path string) (string, error) {
+	data, err := t.host.FSRead(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > t.maxFileSize {
+		return string(data[:t.maxFileSize]) + fmt.Sprintf("\n[truncated at %d bytes]", t.maxFileSize), nil
+	}
+	return string(data), nil
+}
+
+func (t *Tool) writeFile(ctx context.Context, path, content string) (string, error) {
+	if !t.write {
+		return "", errors.New("writing files is not allowed")
+	}
+	if len(content) > t.maxFileSize {
+		return "", fmt.Errorf("content is %d bytes; the limit is %d", len(content), t.maxFileSize)
+	}
+	if err := t.host.FSWrite(ctx, path, []byte(content)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wrote %d bytes to %s.", len(content), t.display(path)), nil
+}
+
+func (t *Tool) list(ctx context.Context, path string) (string, error) {
+	entries, err := t.host.FSList(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "The directory is empty.", nil
+	}
+
+	var sb strings.Builder
+	for i, e := range entries {
+		if i == maxMatches {
+			fmt.Fprintf(&sb, "... and %d more\n", len(entries)-maxMatches)
+			break
+		}
+		if e.IsDir() {
+			sb.WriteString(e.Name() + "/\n")
+			continue
+		}
+		size := ""
+		if info, err := e.Info(); err == nil {
+			size = fmt.Sprintf(" (%d bytes)", info.Size())
+		}
+		sb.WriteString(e.Name() + size + "\n")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func (t *Tool) glob(ctx context.Context, pattern string) (string, error) {
+	matches, err := t.host.FSGlob(ctx, pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "No files match.", nil
+	}
+
+	var sb strings.Builder
+	for i, m := range matches {
+		if i == maxMatches {
+			fmt.Fprintf(&sb, "... and %d more\n", len(matches)-maxMatches)
+			break
+		}
+		sb.WriteString(t.display(m) + "\n")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// resolve makes path absolute, relative to the first allowed directory.
+func (t *Tool) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(t.paths[0], path)
+}
+
+// display shortens paths inside the first allowed directory.
+func (t *Tool) display(path string) string {
+	if rel, err := filepath.Rel(t.paths[0], path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
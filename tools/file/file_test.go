@@ -0,0 +1,65 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToolAllowlist(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// A symlink inside the root must not reach outside it
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	tool, err := New(Config{Paths: []string{root}, Write: true, MaxFileSize: 16})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	run := func(args string) (string, error) {
+		return tool.Execute(context.Background(), json.RawMessage(args))
+	}
+
+	if _, err := run(`{"action":"write_file","path":"notes/a.md","content":"hello"}`); err != nil {
+		t.Fatalf("write_file error = %v", err)
+	}
+	if got, err := run(`{"action":"read_file","path":"notes/a.md"}`); err != nil || got != "hello" {
+		t.Errorf("read_file = %q, %v", got, err)
+	}
+	if got, err := run(`{"action":"list_dir","path":"notes"}`); err != nil || got != "a.md (5 bytes)" {
+		t.Errorf("list_dir = %q, %v", got, err)
+	}
+	if got, err := run(`{"action":"glob","path":"*/*.md"}`); err != nil || got != filepath.Join("notes", "a.md") {
+		t.Errorf("glob = %q, %v", got, err)
+	}
+	if _, err := run(`{"action":"write_file","path":"big.txt","content":"more than sixteen bytes"}`); err == nil {
+		t.Error("write_file over the size limit succeeded")
+	}
+
+	for _, args := range []string{
+		`{"action":"read_file","path":"../secret.txt"}`,
+		`{"action":"read_file","path":"link/secret.txt"}`,
+		`{"action":"list_dir","path":"` + filepath.ToSlash(outside) + `"}`,
+		`{"action":"write_file","path":"link/new.txt","content":"x"}`,
+	} {
+		if got, err := run(args); err == nil {
+			t.Errorf("Execute(%s) = %q, want error", args, got)
+		}
+	}
+	if got, err := run(`{"action":"glob","path":"link/*"}`); err == nil && strings.Contains(got, "secret") {
+		t.Errorf("glob through symlink = %q", got)
+	}
+
+	readOnly, _ := New(Config{Paths: []string{root}})
+	if _, err := readOnly.Execute(context.Background(), json.RawMessage(`{"action":"write_file","path":"b.md","content":"x"}`)); err == nil {
+		t.Error("write_file on a read-only tool succeeded")
+	}
+}
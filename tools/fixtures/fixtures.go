@@ -0,0 +1,184 @@
+// Package fixtures answers tool calls from canned results on disk instead
+// of running the tools, so end-to-end tests of channel, gateway, and agent
+// flows are reproducible in CI without Docker, browsers, or network.
+//
+// Fixtures live at <dir>/<tool>/<key>.json, where key hashes the call's
+// arguments (see Key). A <dir>/<tool>/default.json answers any call to the
+// tool without a fixture of its own.
+package fixtures
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Modes.
+const (
+	ModeReplay = "replay" // Answer from fixtures; fail on a missing one
+	ModeRecord = "record" // Run the real tool and save its result
+)
+
+// defaultFixture answers calls to a tool without a fixture of their own.
+const defaultFixture = "default"
+
+// Fixture is a recorded tool call.
+type Fixture struct {
+	Tool   string          `json:"tool"`
+	Args   json.RawMessage `json:"args,omitempty"`
+	Result string          `json:"result"`
+	Error  string          `json:"error,omitempty"` // Returned as the tool's error
+}
+
+// Config configures fixtures.
+type Config struct {
+	Dir    string
+	Mode   string // ModeReplay (default) or ModeRecord
+	Logger *slog.Logger
+}
+
+// Fixtures replays or records tool calls.
+type Fixtures struct {
+	dir    string
+	mode   string
+	logger *slog.Logger
+}
+
+var _ agent.ToolMock = (*Fixtures)(nil)
+
+// New creates fixtures.
+func New(config Config) (*Fixtures, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("fixtures dir required")
+	}
+	if config.Mode == "" {
+		config.Mode = ModeReplay
+	}
+	if config.Mode != ModeReplay && config.Mode != ModeRecord {
+		return nil, fmt.Errorf("unknown fixtures mode %q", config.Mode)
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Fixtures{dir: config.Dir, mode: config.Mode, logger: config.Logger}, nil
+}
+
+// Key returns the fixture key for a call's arguments. Arguments that
+// differ only in key order or whitespace share a key.
+func Key(args json.RawMessage) string {
+	canonical := []byte(strings.TrimSpace(string(args)))
+	var v interface{}
+	if len(canonical) == 0 {
+		canonical = []byte("{}")
+	} else if err := json.Unmarshal(canonical, &v); err == nil {
+		if b, err := json.Marshal(v); err == nil {
+			canonical = b
+		}
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Path returns the fixture file for a call.
+func (f *Fixtures) Path(tool string, args json.RawMessage) string {
+	return f.path(tool, Key(args))
+}
+
+// Call answers a tool call from its fixture, or in record mode runs the
+// tool and saves the result.
+func (f *Fixtures) Call(ctx context.Context, tool string, args json.RawMessage, run func(context.Context, json.RawMessage) (string, error)) (string, error) {
+	if f.mode == ModeRecord {
+		return f.record(ctx, tool, args, run)
+	}
+
+	path := f.Path(tool, args)
+	fx, err := load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fx, err = load(f.path(tool, defaultFixture))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("no fixture for %s call %s: create %s", tool, string(args), path)
+	}
+	if err != nil {
+		return "", err
+	}
+	f.logger.Debug("tool call replayed", "tool", tool, "fixture", path)
+	if fx.Error != "" {
+		return fx.Result, errors.New(fx.Error)
+	}
+	return fx.Result, nil
+}
+
+// record runs the tool and saves its result as a fixture.
+func (f *Fixtures) record(ctx context.Context, tool string, args json.RawMessage, run func(context.Context, json.RawMessage) (string, error)) (string, error) {
+	result, runErr := run(ctx, args)
+	fx := Fixture{Tool: tool, Result: result}
+	if json.Valid(args) {
+		fx.Args = args
+	}
+	if runErr != nil {
+		fx.Error = runErr.Error()
+	}
+
+	path := f.Path(tool, args)
+	if err := save(path, fx); err != nil {
+		f.logger.Warn("failed to record fixture", "tool", tool, "path", path, "error", err)
+	} else {
+		f.logger.Debug("tool call recorded", "tool", tool, "fixture", path)
+	}
+	return result, runErr
+}
+
+// path returns the file for a tool's fixture key.
+func (f *Fixtures) path(tool, key string) string {
+	return filepath.Join(f.dir, sanitize(tool), key+".json")
+}
+
+// load reads a fixture file.
+func load(path string) (Fixture, error) {
+	var fx Fixture
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		return fx, err
+	}
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return fx, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	return fx, nil
+}
+
+// save writes a fixture file.
+func save(path string, fx Fixture) error {
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("create fixture dir: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// sanitize makes a tool name safe to use as a directory name.
+func sanitize(name string) string {
+	if name == "." || name == ".." {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
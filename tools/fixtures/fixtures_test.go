@@ -0,0 +1,84 @@
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+func TestFixtures(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	registry := agent.NewToolRegistry()
+	err := registry.Register(agent.NewBaseTool("lookup", "Looks things up.", nil, func(_ context.Context, args json.RawMessage) (string, error) {
+		calls++
+		return "live " + string(args), nil
+	}))
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	ctx := context.Background()
+
+	recorder, err := New(Config{Dir: dir, Mode: ModeRecord})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	registry.SetMock(recorder)
+	if got, err := registry.Execute(ctx, "lookup", json.RawMessage(`{"q":"go","n":1}`)); err != nil || got != `live {"q":"go","n":1}` {
+		t.Fatalf("record Execute() = %q, %v", got, err)
+	}
+
+	replayer, err := New(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	registry.SetMock(replayer)
+	got, err := registry.Execute(ctx, "lookup", json.RawMessage(`{ "n": 1, "q": "go" }`))
+	if err != nil || got != `live {"q":"go","n":1}` {
+		t.Errorf("replay Execute() = %q, %v, want the recorded result", got, err)
+	}
+	if calls != 1 {
+		t.Errorf("tool ran %d times, want 1", calls)
+	}
+
+	_, err = registry.Execute(ctx, "lookup", json.RawMessage(`{"q":"rust"}`))
+	if err == nil || !strings.Contains(err.Error(), replayer.Path("lookup", json.RawMessage(`{"q":"rust"}`))) {
+		t.Errorf("missing fixture error = %v, want the path to create", err)
+	}
+
+	writeFixture(t, filepath.Join(dir, "lookup", "default.json"), Fixture{Tool: "lookup", Result: "fallback"})
+	if got, err := registry.Execute(ctx, "lookup", json.RawMessage(`{"q":"rust"}`)); err != nil || got != "fallback" {
+		t.Errorf("default Execute() = %q, %v, want fallback", got, err)
+	}
+
+	writeFixture(t, replayer.Path("lookup", json.RawMessage(`{"q":"fail"}`)), Fixture{Tool: "lookup", Error: "upstream down"})
+	if _, err := registry.Execute(ctx, "lookup", json.RawMessage(`{"q":"fail"}`)); err == nil || err.Error() != "upstream down" {
+		t.Errorf("error fixture Execute() error = %v, want upstream down", err)
+	}
+
+	if _, err := New(Config{Dir: dir, Mode: "live"}); err == nil {
+		t.Error("unknown mode should fail")
+	}
+	if sanitize("..") != "_" || sanitize("mcp:github/search") != "mcp_github_search" {
+		t.Error("sanitize() should make tool names safe directory names")
+	}
+}
+
+func writeFixture(t *testing.T, path string, fx Fixture) {
+	t.Helper()
+	data, err := json.Marshal(fx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
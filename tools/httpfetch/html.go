@@ -0,0 +1,89 @@
+package httpfetch
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skipped are elements whose content is never shown.
+var skipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true,
+}
+
+// blocks are elements that start a new line.
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Section: true, atom.Article: true, atom.Header: true, atom.Footer: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Ol: true,
+	atom.Hr: true, atom.Main: true, atom.Nav: true,
+}
+
+// HTMLToText extracts the readable text of an HTML document, keeping the
+// title and one line per block element.
+func HTMLToText(body []byte) string {
+	var sb strings.Builder
+	var title string
+	skip := 0
+	inTitle := false
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return finish(title, sb.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			if a == atom.Title {
+				inTitle = true
+			}
+			if skipped[a] {
+				skip++
+			}
+			if blocks[a] {
+				sb.WriteString("\n")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			if a == atom.Title {
+				inTitle = false
+			}
+			if skipped[a] && skip > 0 {
+				skip--
+			}
+			if blocks[a] {
+				sb.WriteString("\n")
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			sb.WriteString(strings.Join(strings.Fields(string(z.Text())), " "))
+			sb.WriteString(" ")
+		}
+	}
+}
+
+// finish collapses blank lines and prefixes the title.
+func finish(title, text string) string {
+	var lines []string
+	if t := strings.Join(strings.Fields(title), " "); t != "" {
+		lines = append(lines, "# "+t)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
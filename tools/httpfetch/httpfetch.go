@@ -0,0 +1,191 @@
+// Package httpfetch provides a tool for fetching URLs.
+package httpfetch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/sandbox"
+)
+
+// Defaults.
+const (
+	DefaultMaxResponseSize = 1 << 20
+	DefaultTimeout         = 30 * time.Second
+
+	// maxTextLen caps the text returned to the model.
+	maxTextLen = 32 << 10
+)
+
+// Tool fetches URLs with GET or POST. Host allowlisting and response size
+// limits are enforced by sandbox.HostFunctions.
+type Tool struct {
+	host         *sandbox.HostFunctions
+	allowedHosts []string
+	logger       *slog.Logger
+}
+
+// Config configures the HTTP fetch tool.
+type Config struct {
+	// AllowedHosts limits requests to these hosts and their subdomains.
+	// When empty, any public host may be fetched, but not loopback or
+	// private network addresses.
+	AllowedHosts []string
+	// PrivateOnly limits requests to loopback and private network
+	// addresses, for local-only privacy mode.
+	PrivateOnly     bool
+	MaxResponseSize int           // Bytes; default DefaultMaxResponseSize
+	Timeout         time.Duration // Default DefaultTimeout
+	Logger          *slog.Logger
+}
+
+// New creates a new HTTP fetch tool.
+func New(config Config) (*Tool, error) {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Tool{
+		host:         NewHost(config),
+		allowedHosts: config.AllowedHosts,
+		logger:       config.Logger,
+	}, nil
+}
+
+// NewHost returns the egress policy of config as host functions, for
+// other tools that make HTTP requests on the agent's behalf, such as web
+// search, to share.
+func NewHost(config Config) *sandbox.HostFunctions {
+	if config.MaxResponseSize <= 0 {
+		config.MaxResponseSize = DefaultMaxResponseSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	sc := sandbox.DefaultConfig()
+	sc.Capabilities = []sandbox.Capability{sandbox.CapNetHTTP}
+	sc.AllowedHosts = config.AllowedHosts
+	sc.BlockPrivateNetworks = len(config.AllowedHosts) == 0 && !config.PrivateOnly
+	sc.PrivateNetworksOnly = config.PrivateOnly
+	sc.MaxOutputBytes = config.MaxResponseSize
+	sc.Timeout = config.Timeout
+	return sandbox.NewHostFunctions(sc)
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "http_fetch"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	desc := "Fetch a URL with GET or POST and return the response. " +
+		"HTML pages are converted to plain text."
+	if len(t.allowedHosts) > 0 {
+		desc += " Allowed hosts: " + strings.Join(t.allowedHosts, ", ") + "."
+	}
+	return desc
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "The http or https URL to fetch",
+			},
+			"method": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"GET", "POST"},
+				"description": "HTTP method (default: GET)",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Request body for POST",
+			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+				"description":          "Request headers, such as Content-Type",
+			},
+			"raw": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return HTML as-is instead of extracting its text",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+// Execute fetches the URL.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		URL     string            `json:"url"`
+		Method  string            `json:"method"`
+		Body    string            `json:"body"`
+		Headers map[string]string `json:"headers"`
+		Raw     bool              `json:"raw"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+	if params.URL == "" {
+		return "", errors.New("url required")
+	}
+	method := strings.ToUpper(params.Method)
+	switch method {
+	case "":
+		method = http.MethodGet
+	case http.MethodGet, http.MethodPost:
+	default:
+		return "", fmt.Errorf("unsupported method %q", params.Method)
+	}
+	if method == http.MethodGet && params.Body != "" {
+		return "", errors.New("body is only allowed with POST")
+	}
+
+	t.logger.Info("fetching url", "method", method, "url", params.URL)
+	body, status, err := t.host.HTTPFetch(ctx, method, params.URL, []byte(params.Body), params.Headers)
+	if err != nil {
+		return "", err
+	}
+
+	text := string(body)
+	if !params.Raw && isHTML(body) {
+		text = HTMLToText(body)
+	}
+	if len(text) > maxTextLen {
+		text = truncateUTF8(text, maxTextLen) + "\n[truncated]"
+	}
+
+	result := fmt.Sprintf("Status: %d %s\n\n%s", status, http.StatusText(status), text)
+	if status >= 400 {
+		return result, fmt.Errorf("request failed with status %d", status)
+	}
+	return result, nil
+}
+
+// isHTML sniffs whether a response body is an HTML document.
+func isHTML(body []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(body), "text/html")
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	for n > 0 && n < len(s) && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
@@ -0,0 +1,83 @@
+package httpfetch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, `<!DOCTYPE html><html><head><title>Weather</title>
+<style>body { color: red }</style><script>track()</script></head>
+<body><h1>Today</h1><p>Sunny,   <b>24°C</b></p><ul><li>Wind: light</li></ul></body></html>`)
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			_, _ = io.WriteString(w, r.Method+" "+r.Header.Get("Content-Type")+" "+string(body))
+		case "/big":
+			_, _ = io.WriteString(w, strings.Repeat("x", 2000))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// The test server is on loopback, so it must be allowed explicitly
+	tool, err := New(Config{AllowedHosts: []string{"127.0.0.1"}, MaxResponseSize: 1024})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	run := func(args map[string]any) (string, error) {
+		b, _ := json.Marshal(args)
+		return tool.Execute(context.Background(), b)
+	}
+
+	got, err := run(map[string]any{"url": server.URL + "/page"})
+	if err != nil {
+		t.Fatalf("fetch page error = %v", err)
+	}
+	if want := "Status: 200 OK\n\n# Weather\nToday\nSunny, 24°C\nWind: light"; got != want {
+		t.Errorf("fetch page = %q, want %q", got, want)
+	}
+
+	got, err = run(map[string]any{"url": server.URL + "/echo", "method": "post", "body": `{"a":1}`,
+		"headers": map[string]string{"Content-Type": "application/json"}})
+	if err != nil || !strings.HasSuffix(got, `POST application/json {"a":1}`) {
+		t.Errorf("post = %q, %v", got, err)
+	}
+
+	if got, _ := run(map[string]any{"url": server.URL + "/big"}); strings.Count(got, "x") != 1024 {
+		t.Errorf("response not limited: %q", got)
+	}
+	if _, err := run(map[string]any{"url": server.URL + "/missing"}); err == nil {
+		t.Error("404 should be an error")
+	}
+
+	for _, url := range []string{"https://example.com/", "file:///etc/passwd", "http://127.0.0.1.evil.com/"} {
+		if _, err := run(map[string]any{"url": url}); err == nil {
+			t.Errorf("fetch %s succeeded", url)
+		}
+	}
+
+	// Without an allowlist, private addresses are refused
+	open, _ := New(Config{})
+	if _, err := open.Execute(context.Background(), json.RawMessage(`{"url":"`+server.URL+`/page"}`)); err == nil {
+		t.Error("fetch of loopback address without allowlist succeeded")
+	}
+
+	// In privacy mode, only private addresses are fetched
+	private, _ := New(Config{PrivateOnly: true})
+	if _, err := private.Execute(context.Background(), json.RawMessage(`{"url":"`+server.URL+`/page"}`)); err != nil {
+		t.Errorf("private fetch of loopback address error = %v", err)
+	}
+	if _, err := private.Execute(context.Background(), json.RawMessage(`{"url":"http://93.184.215.14/"}`)); err == nil {
+		t.Error("private fetch of public address succeeded")
+	}
+}
@@ -0,0 +1,188 @@
+// Package scratchpad provides a persistent key-value tool the agent uses
+// to remember small facts across turns.
+package scratchpad
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Scopes partition stored values.
+const (
+	ScopeSession = "session" // The current conversation
+	ScopeContact = "contact" // The sender, across conversations
+)
+
+// Tool exposes a Store to the agent.
+type Tool struct {
+	store  *Store
+	logger *slog.Logger
+}
+
+// Config configures the scratchpad tool.
+type Config struct {
+	Store  *Store
+	Logger *slog.Logger
+}
+
+// New creates a new scratchpad tool.
+func New(config Config) (*Tool, error) {
+	if config.Store == nil {
+		return nil, fmt.Errorf("store required")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Tool{store: config.Store, logger: config.Logger}, nil
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "scratchpad"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return "Remember small facts across turns, such as a flight number or a preference. " +
+		"Values persist per conversation (scope \"session\") or per person (scope \"contact\"). " +
+		"Use list to see what is saved before asking the user again."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"get", "set", "delete", "list"},
+				"description": "The operation to perform",
+			},
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Key to read or write (not needed for list)",
+			},
+			"value": map[string]interface{}{
+				"description": "JSON value to store (for set)",
+			},
+			"scope": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{ScopeSession, ScopeContact},
+				"description": "Where the value lives (default: session)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// Execute performs a scratchpad operation.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Action string          `json:"action"`
+		Key    string          `json:"key"`
+		Value  json.RawMessage `json:"value"`
+		Scope  string          `json:"scope"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+
+	scope, err := resolveScope(ctx, params.Scope)
+	if err != nil {
+		return "", err
+	}
+
+	switch params.Action {
+	case "get":
+		if params.Key == "" {
+			return "", fmt.Errorf("key required")
+		}
+		entry, err := t.store.Get(ctx, scope, params.Key)
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Sprintf("No value saved for %q.", params.Key), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(entry.Value), nil
+
+	case "set":
+		if params.Key == "" {
+			return "", fmt.Errorf("key required")
+		}
+		if len(params.Value) == 0 {
+			return "", fmt.Errorf("value required")
+		}
+		if err := t.store.Set(ctx, scope, params.Key, params.Value); err != nil {
+			return "", err
+		}
+		t.logger.Debug("scratchpad set", "scope", scope, "key", params.Key)
+		return fmt.Sprintf("Saved %q.", params.Key), nil
+
+	case "delete":
+		if params.Key == "" {
+			return "", fmt.Errorf("key required")
+		}
+		err := t.store.Delete(ctx, scope, params.Key)
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Sprintf("No value saved for %q.", params.Key), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted %q.", params.Key), nil
+
+	case "list":
+		entries, err := t.store.List(ctx, scope)
+		if err != nil {
+			return "", err
+		}
+		if len(entries) == 0 {
+			return "The scratchpad is empty.", nil
+		}
+		var sb strings.Builder
+		for _, e := range entries {
+			fmt.Fprintf(&sb, "%s = %s\n", e.Key, e.Value)
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+
+	default:
+		return "", fmt.Errorf("unknown action %q", params.Action)
+	}
+}
+
+// resolveScope maps the requested scope to a storage partition for the
+// conversation or contact in ctx. Contact scope falls back to the session
+// when the channel does not identify senders.
+func resolveScope(ctx context.Context, scope string) (string, error) {
+	session := agent.SessionFromContext(ctx)
+	switch scope {
+	case "", ScopeSession:
+		if session == "" {
+			return "", fmt.Errorf("scratchpad requires a conversation")
+		}
+		return "session:" + session, nil
+	case ScopeContact:
+		if person := agent.PersonFromContext(ctx); person != "" {
+			return "person:" + person, nil
+		}
+		if contact := agent.ContactFromContext(ctx); contact != "" {
+			return "contact:" + contact, nil
+		}
+		if session == "" {
+			return "", fmt.Errorf("scratchpad requires a conversation")
+		}
+		return "session:" + session, nil
+	default:
+		return "", fmt.Errorf("unknown scope %q", scope)
+	}
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
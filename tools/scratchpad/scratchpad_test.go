@@ -0,0 +1,89 @@
+package scratchpad
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+func TestToolScopes(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "scratchpad.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	tool, err := New(Config{Store: store})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	run := func(ctx context.Context, args string) string {
+		t.Helper()
+		out, err := tool.Execute(ctx, json.RawMessage(args))
+		if err != nil {
+			t.Fatalf("Execute(%s) error = %v", args, err)
+		}
+		return out
+	}
+
+	chatA := agent.WithContact(agent.WithSession(context.Background(), "telegram:100"), "telegram:42")
+	chatB := agent.WithContact(agent.WithSession(context.Background(), "telegram:200"), "telegram:42")
+
+	run(chatA, `{"action":"set","key":"flight","value":"UA 123"}`)
+	run(chatA, `{"action":"set","key":"home","value":{"city":"Austin"},"scope":"contact"}`)
+
+	if got := run(chatA, `{"action":"get","key":"flight"}`); got != `"UA 123"` {
+		t.Errorf("get flight = %s", got)
+	}
+	// Session values stay in their conversation; contact values follow the sender
+	if got := run(chatB, `{"action":"get","key":"flight"}`); !strings.Contains(got, "No value") {
+		t.Errorf("get flight in other chat = %s", got)
+	}
+	if got := run(chatB, `{"action":"get","key":"home","scope":"contact"}`); got != `{"city":"Austin"}` {
+		t.Errorf("get home in other chat = %s", got)
+	}
+
+	run(chatA, `{"action":"delete","key":"flight"}`)
+	if got := run(chatA, `{"action":"list"}`); got != "The scratchpad is empty." {
+		t.Errorf("list after delete = %s", got)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"action":"list"}`)); err == nil {
+		t.Error("Execute() without session should fail")
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(filepath.Join(t.TempDir(), "scratchpad.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	big := json.RawMessage(`"` + strings.Repeat("x", MaxValueBytes) + `"`)
+	if err := store.Set(ctx, "s", "big", big); err == nil {
+		t.Error("Set() oversized value should fail")
+	}
+	if err := store.Set(ctx, "s", "bad", json.RawMessage(`{`)); err == nil {
+		t.Error("Set() invalid JSON should fail")
+	}
+
+	for i := 0; i < MaxKeysPerScope; i++ {
+		if err := store.Set(ctx, "s", "k"+strings.Repeat("0", i), json.RawMessage(`1`)); err != nil {
+			t.Fatalf("Set(%d) error = %v", i, err)
+		}
+	}
+	if err := store.Set(ctx, "s", "overflow", json.RawMessage(`1`)); err == nil {
+		t.Error("Set() past key limit should fail")
+	}
+	// Updating an existing key is allowed when full
+	if err := store.Set(ctx, "s", "k", json.RawMessage(`2`)); err != nil {
+		t.Errorf("Set() update when full error = %v", err)
+	}
+}
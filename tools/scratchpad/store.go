@@ -0,0 +1,159 @@
+package scratchpad
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver
+)
+
+// Limits keep the scratchpad small; it holds notes, not documents.
+const (
+	MaxKeyLength    = 128
+	MaxValueBytes   = 4096
+	MaxKeysPerScope = 100
+)
+
+// ErrNotFound is returned when a key does not exist.
+var ErrNotFound = errors.New("key not found")
+
+// Entry is a stored value.
+type Entry struct {
+	Key       string
+	Value     json.RawMessage
+	UpdatedAt time.Time
+}
+
+// Store persists scratchpad values in SQLite, partitioned by scope.
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS scratchpad (
+	scope      TEXT NOT NULL,
+	key        TEXT NOT NULL,
+	value      TEXT NOT NULL,
+	updated_at INTEGER NOT NULL,
+	PRIMARY KEY (scope, key)
+)`
+
+// Open opens (or creates) a scratchpad database at path.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("create scratchpad dir: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open scratchpad: %w", err)
+	}
+	// SQLite allows one writer; serialize access instead of retrying
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create scratchpad schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get returns the value stored under key in scope.
+func (s *Store) Get(ctx context.Context, scope, key string) (Entry, error) {
+	var value string
+	var updated int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT value, updated_at FROM scratchpad WHERE scope = ? AND key = ?`, scope, key).
+		Scan(&value, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, ErrNotFound
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("get %s: %w", key, err)
+	}
+	return Entry{Key: key, Value: json.RawMessage(value), UpdatedAt: time.Unix(updated, 0)}, nil
+}
+
+// Set stores a JSON value under key in scope, replacing any previous value.
+func (s *Store) Set(ctx context.Context, scope, key string, value json.RawMessage) error {
+	if key == "" || len(key) > MaxKeyLength {
+		return fmt.Errorf("key must be 1-%d characters", MaxKeyLength)
+	}
+	if len(value) > MaxValueBytes {
+		return fmt.Errorf("value is %d bytes, limit is %d", len(value), MaxValueBytes)
+	}
+	if !json.Valid(value) {
+		return fmt.Errorf("value must be valid JSON")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	var exists bool
+	var count int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(key = ?), 0) > 0 FROM scratchpad WHERE scope = ?`, key, scope).
+		Scan(&count, &exists); err != nil {
+		return fmt.Errorf("count keys: %w", err)
+	}
+	if !exists && count >= MaxKeysPerScope {
+		return fmt.Errorf("scratchpad is full (%d keys); delete unused keys first", MaxKeysPerScope)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO scratchpad (scope, key, value, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (scope, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		scope, key, string(value), time.Now().Unix()); err != nil {
+		return fmt.Errorf("set %s: %w", key, err)
+	}
+	return tx.Commit()
+}
+
+// Delete removes key from scope. Deleting a missing key returns ErrNotFound.
+func (s *Store) Delete(ctx context.Context, scope, key string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM scratchpad WHERE scope = ? AND key = ?`, scope, key)
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", key, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns all entries in scope ordered by key.
+func (s *Store) List(ctx context.Context, scope string) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, value, updated_at FROM scratchpad WHERE scope = ? ORDER BY key`, scope)
+	if err != nil {
+		return nil, fmt.Errorf("list: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var value string
+		var updated int64
+		if err := rows.Scan(&e.Key, &value, &updated); err != nil {
+			return nil, fmt.Errorf("list: %w", err)
+		}
+		e.Value = json.RawMessage(value)
+		e.UpdatedAt = time.Unix(updated, 0)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
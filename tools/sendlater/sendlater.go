@@ -0,0 +1,257 @@
+// Package sendlater provides a tool for scheduling outbound messages.
+package sendlater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/access"
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/clock"
+	"github.com/plexusone/omniagent/scheduler"
+)
+
+// outputLayout formats send times for the model.
+const outputLayout = "Mon 2006-01-02 15:04 MST"
+
+// Tool schedules messages for delivery at a later time.
+type Tool struct {
+	scheduler *scheduler.Scheduler
+	clock     *clock.Resolver
+	channels  []string
+	logger    *slog.Logger
+}
+
+// Config configures the send-later tool.
+type Config struct {
+	Scheduler *scheduler.Scheduler // Required
+	Clock     *clock.Resolver      // Required; interprets times in the user's timezone
+	Channels  []string             // Channels messages may be sent to; empty allows any
+	Logger    *slog.Logger
+}
+
+// New creates a new send-later tool.
+func New(config Config) (*Tool, error) {
+	if config.Scheduler == nil {
+		return nil, fmt.Errorf("scheduler required")
+	}
+	if config.Clock == nil {
+		return nil, fmt.Errorf("clock required")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Tool{
+		scheduler: config.Scheduler,
+		clock:     config.Clock,
+		channels:  slices.Sorted(slices.Values(config.Channels)),
+		logger:    config.Logger,
+	}, nil
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "send_later"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return "Schedule a message to be sent at a future time, list pending scheduled messages, or cancel one. " +
+		"Messages go to the current chat; only the owner may give another channel and chat_id. " +
+		"Use at for a time (\"2006-01-02 15:04\" in the user's timezone, or RFC 3339) or delay for a duration like \"2h\"."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"schedule", "list", "cancel"},
+				"description": "The operation to perform",
+			},
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "Message text to send (schedule)",
+			},
+			"at": map[string]interface{}{
+				"type":        "string",
+				"description": "When to send (schedule)",
+			},
+			"delay": map[string]interface{}{
+				"type":        "string",
+				"description": "Send after this duration, e.g. \"90m\" (schedule)",
+			},
+			"timezone": map[string]interface{}{
+				"type":        "string",
+				"description": "IANA timezone for at; defaults to the user's",
+			},
+			"channel": map[string]interface{}{
+				"type":        "string",
+				"description": "Channel to send on, e.g. telegram (schedule); defaults to the current one",
+			},
+			"chat_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Chat or contact to send to (schedule); defaults to the current one",
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Scheduled message ID (cancel)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// Execute performs a send-later operation.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Action   string `json:"action"`
+		Message  string `json:"message"`
+		At       string `json:"at"`
+		Delay    string `json:"delay"`
+		Timezone string `json:"timezone"`
+		Channel  string `json:"channel"`
+		ChatID   string `json:"chat_id"`
+		ID       string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+
+	loc := t.clock.Location(agent.TimezoneKeys(ctx)...)
+	if params.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(params.Timezone); err != nil {
+			return "", fmt.Errorf("unknown timezone %q", params.Timezone)
+		}
+	}
+
+	switch params.Action {
+	case "schedule":
+		if strings.TrimSpace(params.Message) == "" {
+			return "", fmt.Errorf("message required")
+		}
+		at, err := t.sendTime(params.At, params.Delay, loc)
+		if err != nil {
+			return "", err
+		}
+		channel, chatID, err := t.target(ctx, params.Channel, params.ChatID)
+		if err != nil {
+			return "", err
+		}
+		task, err := t.scheduler.Add(scheduler.Task{
+			Name:    "send_later",
+			At:      at,
+			Message: params.Message,
+			Channel: channel,
+			ChatID:  chatID,
+			Session: agent.SessionFromContext(ctx),
+		})
+		if err != nil {
+			return "", err
+		}
+		t.logger.Info("message scheduled", "id", task.ID, "channel", channel, "at", at)
+		return fmt.Sprintf("Scheduled message %s to %s:%s for %s.", task.ID, channel, chatID, at.In(loc).Format(outputLayout)), nil
+
+	case "list":
+		var b strings.Builder
+		for _, task := range t.scheduler.List() {
+			if !t.owns(ctx, task) {
+				continue
+			}
+			fmt.Fprintf(&b, "- %s: %s to %s:%s: %q\n", task.ID, task.At.In(loc).Format(outputLayout),
+				task.Channel, task.ChatID, task.Message)
+		}
+		if b.Len() == 0 {
+			return "No scheduled messages.", nil
+		}
+		return b.String(), nil
+
+	case "cancel":
+		if params.ID == "" {
+			return "", fmt.Errorf("id required")
+		}
+		task, err := t.scheduler.Get(params.ID)
+		if err == nil && !t.owns(ctx, task) {
+			err = scheduler.ErrNotFound
+		}
+		if err == nil {
+			err = t.scheduler.Remove(params.ID)
+		}
+		if errors.Is(err, scheduler.ErrNotFound) {
+			return "", fmt.Errorf("no scheduled message %q", params.ID)
+		}
+		if err != nil {
+			return "", err
+		}
+		return "Canceled scheduled message " + params.ID + ".", nil
+
+	default:
+		return "", fmt.Errorf("unknown action %q", params.Action)
+	}
+}
+
+// sendTime resolves the delivery time from at or delay.
+func (t *Tool) sendTime(at, delay string, loc *time.Location) (time.Time, error) {
+	now := t.clock.Now(loc)
+	var when time.Time
+	switch {
+	case at != "" && delay != "":
+		return time.Time{}, fmt.Errorf("use either at or delay, not both")
+	case delay != "":
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid delay %q: %w", delay, err)
+		}
+		when = now.Add(d)
+	case at != "":
+		var err error
+		if when, err = t.clock.Parse(at, loc); err != nil {
+			return time.Time{}, err
+		}
+	default:
+		return time.Time{}, fmt.Errorf("at or delay required")
+	}
+	if !when.After(now) {
+		return time.Time{}, fmt.Errorf("send time %s is in the past", when.Format(outputLayout))
+	}
+	return when, nil
+}
+
+// target resolves the destination, defaulting to the chat of the current
+// session ("channel:chatID"). Only owners may send elsewhere, so other
+// users cannot have the agent message arbitrary contacts.
+func (t *Tool) target(ctx context.Context, channel, chatID string) (string, string, error) {
+	current, currentID, _ := strings.Cut(agent.SessionFromContext(ctx), ":")
+	if channel == "" && chatID == "" {
+		channel, chatID = current, currentID
+	}
+	if channel == "" || chatID == "" {
+		return "", "", fmt.Errorf("channel and chat_id required")
+	}
+	if (channel != current || chatID != currentID) && agent.TierFromContext(ctx) != access.TierOwner {
+		return "", "", errors.New("only the owner may schedule messages to other chats")
+	}
+	if len(t.channels) > 0 && !slices.Contains(t.channels, channel) {
+		return "", "", fmt.Errorf("unknown channel %q (available: %s)", channel, strings.Join(t.channels, ", "))
+	}
+	return channel, chatID, nil
+}
+
+// owns reports whether a task is a message scheduled from the current
+// session.
+func (t *Tool) owns(ctx context.Context, task scheduler.Task) bool {
+	session := agent.SessionFromContext(ctx)
+	return task.Message != "" && !task.At.IsZero() && session != "" && task.Session == session
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
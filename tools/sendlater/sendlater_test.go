@@ -0,0 +1,119 @@
+package sendlater
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omniagent/access"
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/clock"
+	"github.com/plexusone/omniagent/scheduler"
+)
+
+func TestSendLater(t *testing.T) {
+	sched, err := scheduler.New(scheduler.Config{Handler: func(context.Context, scheduler.Task) error { return nil }})
+	if err != nil {
+		t.Fatalf("scheduler.New() error = %v", err)
+	}
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	resolver, err := clock.New(clock.Config{Default: "UTC", Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("clock.New() error = %v", err)
+	}
+	tool, err := New(Config{Scheduler: sched, Clock: resolver, Channels: []string{"telegram", "discord"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := agent.WithSession(context.Background(), "telegram:42")
+	run := func(args map[string]interface{}) (string, error) {
+		raw, _ := json.Marshal(args)
+		return tool.Execute(ctx, raw)
+	}
+
+	if _, err := run(map[string]interface{}{"action": "schedule", "message": "hi", "at": "2026-03-10 08:00"}); err == nil {
+		t.Error("schedule in the past should fail")
+	}
+	if _, err := run(map[string]interface{}{"action": "schedule", "message": "hi", "delay": "1h", "channel": "sms", "chat_id": "1"}); err == nil {
+		t.Error("schedule to an unknown channel should fail")
+	}
+
+	if _, err := run(map[string]interface{}{"action": "schedule", "message": "call mom", "at": "2026-03-10 18:30"}); err != nil {
+		t.Fatalf("schedule error = %v", err)
+	}
+	tasks := sched.List()
+	if len(tasks) != 1 {
+		t.Fatalf("scheduler has %d tasks, want 1", len(tasks))
+	}
+	task := tasks[0]
+	if task.Channel != "telegram" || task.ChatID != "42" || !task.At.Equal(time.Date(2026, 3, 10, 18, 30, 0, 0, time.UTC)) {
+		t.Errorf("scheduled task = %+v, want telegram:42 at 18:30", task)
+	}
+
+	list, err := run(map[string]interface{}{"action": "list"})
+	if err != nil || !strings.Contains(list, "call mom") {
+		t.Errorf("list = %q, %v", list, err)
+	}
+
+	if _, err := run(map[string]interface{}{"action": "cancel", "id": task.ID}); err != nil {
+		t.Errorf("cancel error = %v", err)
+	}
+	if _, err := run(map[string]interface{}{"action": "cancel", "id": task.ID}); err == nil {
+		t.Error("cancel again should fail")
+	}
+}
+
+func TestSendLaterScope(t *testing.T) {
+	sched, err := scheduler.New(scheduler.Config{Handler: func(context.Context, scheduler.Task) error { return nil }})
+	if err != nil {
+		t.Fatalf("scheduler.New() error = %v", err)
+	}
+	resolver, err := clock.New(clock.Config{Default: "UTC"})
+	if err != nil {
+		t.Fatalf("clock.New() error = %v", err)
+	}
+	tool, err := New(Config{Scheduler: sched, Clock: resolver})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	run := func(ctx context.Context, args map[string]interface{}) (string, error) {
+		raw, _ := json.Marshal(args)
+		return tool.Execute(ctx, raw)
+	}
+	alice := agent.WithTier(agent.WithSession(context.Background(), "telegram:1"), access.TierGuest)
+	bob := agent.WithTier(agent.WithSession(context.Background(), "telegram:2"), access.TierGuest)
+	owner := agent.WithTier(agent.WithSession(context.Background(), "telegram:0"), access.TierOwner)
+
+	if _, err := run(alice, map[string]interface{}{"action": "schedule", "message": "spam", "delay": "1h", "channel": "telegram", "chat_id": "3"}); err == nil {
+		t.Error("guest scheduling to another chat should fail")
+	}
+	if _, err := run(owner, map[string]interface{}{"action": "schedule", "message": "hello", "delay": "1h", "channel": "telegram", "chat_id": "3"}); err != nil {
+		t.Errorf("owner scheduling to another chat: %v", err)
+	}
+	if _, err := run(alice, map[string]interface{}{"action": "schedule", "message": "alice note", "delay": "1h"}); err != nil {
+		t.Fatalf("schedule error = %v", err)
+	}
+	var id string
+	for _, task := range sched.List() {
+		if task.Session == "telegram:1" {
+			id = task.ID
+		}
+	}
+
+	if list, _ := run(bob, map[string]interface{}{"action": "list"}); strings.Contains(list, "alice note") || strings.Contains(list, "hello") {
+		t.Errorf("bob sees other sessions' messages: %q", list)
+	}
+	if _, err := run(bob, map[string]interface{}{"action": "cancel", "id": id}); err == nil {
+		t.Error("bob canceling alice's message should fail")
+	}
+	if list, _ := run(alice, map[string]interface{}{"action": "list"}); !strings.Contains(list, "alice note") || strings.Contains(list, "hello") {
+		t.Errorf("alice list = %q, want only her message", list)
+	}
+	if _, err := run(alice, map[string]interface{}{"action": "cancel", "id": id}); err != nil {
+		t.Errorf("alice canceling her message: %v", err)
+	}
+}
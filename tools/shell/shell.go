@@ -8,16 +8,19 @@ import (
 	"fmt"
 	"log/slog"
 	"os/exec"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/sandbox"
 )
 
 // Tool provides shell command execution capabilities.
 type Tool struct {
 	workingDir string
 	allowlist  []string
+	executor   sandbox.Executor
 	logger     *slog.Logger
 }
 
@@ -25,6 +28,7 @@ type Tool struct {
 type Config struct {
 	WorkingDir string
 	Allowlist  []string
+	Executor   sandbox.Executor // Optional; nil runs commands on the host
 	Logger     *slog.Logger
 }
 
@@ -37,6 +41,7 @@ func New(config Config) (*Tool, error) {
 	return &Tool{
 		workingDir: config.WorkingDir,
 		allowlist:  config.Allowlist,
+		executor:   config.Executor,
 		logger:     config.Logger,
 	}, nil
 }
@@ -48,6 +53,9 @@ func (t *Tool) Name() string {
 
 // Description returns the tool description.
 func (t *Tool) Description() string {
+	if t.executor == nil && runtime.GOOS == "windows" {
+		return "Execute cmd.exe commands on the Windows system. Use with caution."
+	}
 	return "Execute shell commands on the system. Use with caution."
 }
 
@@ -104,49 +112,117 @@ func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error
 	t.logger.Info("executing shell command",
 		"command", params.Command,
 		"timeout", timeout,
-		"working_dir", t.workingDir)
+		"working_dir", t.workingDir,
+		"sandboxed", t.executor != nil)
+
+	if t.executor != nil {
+		return t.executeSandboxed(ctx, params.Command, timeout)
+	}
 
 	// Create command
 	// #nosec G204 - Command execution is intentional; allowlist restricts commands when configured
-	cmd := exec.CommandContext(ctx, "sh", "-c", params.Command)
+	name, shellArgs := hostShell(params.Command)
+	cmd := exec.CommandContext(ctx, name, shellArgs...)
 	if t.workingDir != "" {
 		cmd.Dir = t.workingDir
 	}
 
-	// Capture output
+	// Capture output, streaming it as progress while the command runs
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	cmd.Stdout = &progressWriter{ctx: ctx, buf: &stdout}
+	cmd.Stderr = &progressWriter{ctx: ctx, buf: &stderr}
 
 	// Run command
 	err := cmd.Run()
 
 	// Build result
-	result := strings.Builder{}
-	if stdout.Len() > 0 {
-		result.WriteString("stdout:\n")
-		result.WriteString(stdout.String())
-	}
-	if stderr.Len() > 0 {
-		if result.Len() > 0 {
-			result.WriteString("\n")
+	result := formatOutput(stdout.Bytes(), stderr.Bytes())
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return result, fmt.Errorf("command timed out after %v", timeout)
 		}
-		result.WriteString("stderr:\n")
-		result.WriteString(stderr.String())
+		return result, fmt.Errorf("command failed: %w", err)
+	}
+
+	if result == "" {
+		return "(no output)", nil
+	}
+
+	return result, nil
+}
+
+// hostShell returns the host's shell invocation for command: cmd.exe on
+// Windows, sh elsewhere. Sandboxes always run sh.
+func hostShell(command string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", command}
 	}
+	return "sh", []string{"-c", command}
+}
 
+// executeSandboxed runs the command through the configured sandbox
+// executor, streaming output as progress when the sandbox supports it.
+func (t *Tool) executeSandboxed(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	var res *sandbox.Result
+	var err error
+	if streamer, ok := t.executor.(sandbox.Streamer); ok {
+		res, err = streamer.RunShellStream(ctx, command, &progressWriter{ctx: ctx}, &progressWriter{ctx: ctx})
+	} else {
+		res, err = t.executor.RunShell(ctx, command)
+	}
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return result.String(), fmt.Errorf("command timed out after %v", timeout)
+			return "", fmt.Errorf("command timed out after %v", timeout)
 		}
-		return result.String(), fmt.Errorf("command failed: %w", err)
+		return "", fmt.Errorf("sandbox execution failed: %w", err)
 	}
 
-	if result.Len() == 0 {
+	output := formatOutput(res.Output, res.Error)
+	if res.ExitCode != 0 {
+		return output, fmt.Errorf("command failed: exit status %d", res.ExitCode)
+	}
+	if output == "" {
 		return "(no output)", nil
 	}
+	return output, nil
+}
+
+// SetExecutor routes command execution through a sandbox executor.
+func (t *Tool) SetExecutor(executor sandbox.Executor) {
+	t.executor = executor
+}
+
+// progressWriter reports command output as progress, capturing it in buf
+// when set.
+type progressWriter struct {
+	ctx context.Context
+	buf *bytes.Buffer
+}
 
-	return result.String(), nil
+func (w *progressWriter) Write(p []byte) (int, error) {
+	agent.ReportProgress(w.ctx, string(p))
+	if w.buf == nil {
+		return len(p), nil
+	}
+	return w.buf.Write(p)
+}
+
+// formatOutput combines stdout and stderr into the tool result.
+func formatOutput(stdout, stderr []byte) string {
+	result := strings.Builder{}
+	if len(stdout) > 0 {
+		result.WriteString("stdout:\n")
+		result.Write(stdout)
+	}
+	if len(stderr) > 0 {
+		if result.Len() > 0 {
+			result.WriteString("\n")
+		}
+		result.WriteString("stderr:\n")
+		result.Write(stderr)
+	}
+	return result.String()
 }
 
 // isAllowed checks if a command is in the allowlist.
@@ -172,5 +248,5 @@ func (t *Tool) isAllowed(command string) bool {
 	return false
 }
 
-// Ensure Tool implements agent.Tool interface.
-var _ agent.Tool = (*Tool)(nil)
+// Ensure Tool implements agent.ExecTool interface.
+var _ agent.ExecTool = (*Tool)(nil)
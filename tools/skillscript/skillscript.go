@@ -0,0 +1,188 @@
+// Package skillscript exposes the scripts skills ship as agent tools.
+package skillscript
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/sandbox"
+	"github.com/plexusone/omniagent/skills"
+)
+
+// Timeout defaults and limits.
+const (
+	defaultTimeout = 60 * time.Second
+	maxTimeout     = 300 * time.Second
+)
+
+// Tool runs one skill script in a sandbox. The executor's working
+// directory is the skill directory.
+type Tool struct {
+	skill    *skills.Skill
+	script   skills.Script
+	executor sandbox.Executor
+	logger   *slog.Logger
+}
+
+// Config configures a skill script tool.
+type Config struct {
+	Skill    *skills.Skill
+	Script   skills.Script
+	Executor sandbox.Executor // Required; scripts never run on the host
+	Logger   *slog.Logger
+}
+
+// New creates a tool for a skill script.
+func New(config Config) (*Tool, error) {
+	if config.Skill == nil || config.Script.Name == "" {
+		return nil, errors.New("skill script required")
+	}
+	if config.Executor == nil {
+		return nil, errors.New("skill scripts require a sandbox")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Tool{
+		skill:    config.Skill,
+		script:   config.Script,
+		executor: config.Executor,
+		logger:   config.Logger,
+	}, nil
+}
+
+// Source returns the tool source for a skill's scripts, "skill:<name>".
+func Source(skill *skills.Skill) string {
+	return "skill:" + skill.Name
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return t.script.Name
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return t.script.Description
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"args": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Command-line arguments for the script",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "Timeout in seconds (default: 60, max: 300)",
+			},
+		},
+	}
+}
+
+// Execute runs the script in the sandbox.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Args    []string `json:"args"`
+		Timeout int      `json:"timeout"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("parse parameters: %w", err)
+		}
+	}
+
+	timeout := defaultTimeout
+	if params.Timeout > 0 {
+		timeout = min(time.Duration(params.Timeout)*time.Second, maxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	t.logger.Info("running skill script", "skill", t.skill.Name, "script", t.script.File, "timeout", timeout)
+	res, err := t.executor.RunShell(ctx, t.command(params.Args))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("script timed out after %v", timeout)
+		}
+		return "", fmt.Errorf("sandbox execution failed: %w", err)
+	}
+
+	output := formatOutput(res.Output, res.Error)
+	if res.ExitCode != 0 {
+		return output, fmt.Errorf("script failed: exit status %d", res.ExitCode)
+	}
+	if output == "" {
+		return "(no output)", nil
+	}
+	return output, nil
+}
+
+// command builds the shell command that runs the script. Only the
+// environment variables the skill declares it requires are passed on.
+func (t *Tool) command(args []string) string {
+	var parts []string
+	if meta := t.skill.Metadata.OpenClaw; meta != nil && meta.Requires != nil {
+		for _, name := range meta.Requires.Env {
+			if value, ok := os.LookupEnv(name); ok && validEnvName(name) {
+				parts = append(parts, name+"="+quote(value))
+			}
+		}
+	}
+	parts = append(parts, quote(t.script.Interpreter), quote(t.script.File))
+	for _, arg := range args {
+		parts = append(parts, quote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// SetExecutor routes script execution through a sandbox executor.
+func (t *Tool) SetExecutor(executor sandbox.Executor) {
+	t.executor = executor
+}
+
+// quote quotes s for the shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// validEnvName reports whether name can be assigned in the shell.
+func validEnvName(name string) bool {
+	for i, c := range name {
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// formatOutput combines stdout and stderr into the tool result.
+func formatOutput(stdout, stderr []byte) string {
+	var result strings.Builder
+	if len(stdout) > 0 {
+		result.WriteString("stdout:\n")
+		result.Write(stdout)
+	}
+	if len(stderr) > 0 {
+		if result.Len() > 0 {
+			result.WriteString("\n")
+		}
+		result.WriteString("stderr:\n")
+		result.Write(stderr)
+	}
+	return result.String()
+}
+
+// Ensure Tool implements agent.ExecTool interface.
+var _ agent.ExecTool = (*Tool)(nil)
@@ -0,0 +1,60 @@
+package skillscript
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/plexusone/omniagent/sandbox"
+	"github.com/plexusone/omniagent/skills"
+)
+
+// fakeExecutor records commands and returns a canned result.
+type fakeExecutor struct {
+	commands []string
+	result   sandbox.Result
+}
+
+func (e *fakeExecutor) RunShell(_ context.Context, command string) (*sandbox.Result, error) {
+	e.commands = append(e.commands, command)
+	return &e.result, nil
+}
+
+func TestExecute(t *testing.T) {
+	t.Setenv("SITE_TOKEN", "it's secret")
+	t.Setenv("OTHER_TOKEN", "not passed")
+	skill := &skills.Skill{
+		Name: "site",
+		Metadata: skills.SkillMeta{OpenClaw: &skills.OpenClawMeta{
+			Requires: &skills.Requires{Env: []string{"SITE_TOKEN", "UNSET_VAR"}},
+		}},
+	}
+	script := skills.Script{Name: "deploy", File: "scripts/deploy.sh", Description: "Deploy.", Interpreter: "sh"}
+
+	if _, err := New(Config{Skill: skill, Script: script}); err == nil {
+		t.Fatal("expected a script without a sandbox to be refused")
+	}
+
+	exec := &fakeExecutor{result: sandbox.Result{Output: []byte("done\n")}}
+	tool, err := New(Config{Skill: skill, Script: script, Executor: exec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool.Name() != "deploy" || Source(skill) != "skill:site" {
+		t.Errorf("name = %q, source = %q", tool.Name(), Source(skill))
+	}
+
+	out, err := tool.Execute(context.Background(), json.RawMessage(`{"args":["prod","a b; rm -rf /"]}`))
+	if err != nil || out != "stdout:\ndone\n" {
+		t.Fatalf("Execute() = %q, %v", out, err)
+	}
+	want := `SITE_TOKEN='it'\''s secret' 'sh' 'scripts/deploy.sh' 'prod' 'a b; rm -rf /'`
+	if len(exec.commands) != 1 || exec.commands[0] != want {
+		t.Errorf("command = %q, want %q", exec.commands, want)
+	}
+
+	exec.result = sandbox.Result{Error: []byte("boom"), ExitCode: 2}
+	if out, err := tool.Execute(context.Background(), nil); err == nil || out != "stderr:\nboom" {
+		t.Errorf("failing script = %q, %v; want stderr and an error", out, err)
+	}
+}
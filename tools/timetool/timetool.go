@@ -0,0 +1,197 @@
+// Package timetool provides a time tool for current time, timezone
+// conversion, and date arithmetic.
+package timetool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/clock"
+)
+
+// outputLayout formats times for the model.
+const outputLayout = "Mon 2006-01-02 15:04 MST (-07:00)"
+
+// Tool answers time questions in the user's timezone.
+type Tool struct {
+	clock  *clock.Resolver
+	logger *slog.Logger
+}
+
+// Config configures the time tool.
+type Config struct {
+	Clock  *clock.Resolver
+	Logger *slog.Logger
+}
+
+// New creates a new time tool.
+func New(config Config) (*Tool, error) {
+	if config.Clock == nil {
+		return nil, fmt.Errorf("clock required")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Tool{clock: config.Clock, logger: config.Logger}, nil
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "time"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return "Get the current time, convert times between timezones, add durations to dates, " +
+		"compute the difference between two times, or save the user's timezone when they mention where they are. " +
+		"Timezones are IANA names like America/New_York; times are RFC 3339 or \"2006-01-02 15:04\"."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"now", "convert", "add", "diff", "set_timezone"},
+				"description": "The operation to perform",
+			},
+			"time": map[string]interface{}{
+				"type":        "string",
+				"description": "Input time (convert, add, diff); defaults to now",
+			},
+			"to_time": map[string]interface{}{
+				"type":        "string",
+				"description": "End time (diff)",
+			},
+			"timezone": map[string]interface{}{
+				"type":        "string",
+				"description": "Timezone of the input time, or the timezone to save (set_timezone); defaults to the user's",
+			},
+			"to_timezone": map[string]interface{}{
+				"type":        "string",
+				"description": "Target timezone (now, convert)",
+			},
+			"duration": map[string]interface{}{
+				"type":        "string",
+				"description": "Duration to add, e.g. \"90m\" or \"-2h\" (add)",
+			},
+			"days": map[string]interface{}{
+				"type":        "integer",
+				"description": "Calendar days to add; may be negative (add)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// Execute performs a time operation.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Action     string `json:"action"`
+		Time       string `json:"time"`
+		ToTime     string `json:"to_time"`
+		Timezone   string `json:"timezone"`
+		ToTimezone string `json:"to_timezone"`
+		Duration   string `json:"duration"`
+		Days       int    `json:"days"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+
+	keys := agent.TimezoneKeys(ctx)
+	userLoc := t.clock.Location(keys...)
+
+	if params.Action == "set_timezone" {
+		if len(keys) == 0 {
+			return "", fmt.Errorf("no user to set a timezone for")
+		}
+		loc, err := t.clock.SetLocation(keys[0], params.Timezone)
+		if err != nil {
+			return "", err
+		}
+		if loc == nil {
+			return "Timezone cleared.", nil
+		}
+		t.logger.Info("timezone saved", "contact", keys[0], "timezone", loc)
+		return "Saved timezone " + loc.String() + ". It is " + t.clock.Now(loc).Format(outputLayout) + ".", nil
+	}
+
+	fromLoc, err := loadLocation(params.Timezone, userLoc)
+	if err != nil {
+		return "", err
+	}
+	toLoc, err := loadLocation(params.ToTimezone, userLoc)
+	if err != nil {
+		return "", err
+	}
+	start, err := t.clock.Parse(params.Time, fromLoc)
+	if err != nil {
+		return "", err
+	}
+
+	switch params.Action {
+	case "now":
+		return t.clock.Now(toLoc).Format(outputLayout), nil
+
+	case "convert":
+		return start.In(fromLoc).Format(outputLayout) + " = " + start.In(toLoc).Format(outputLayout), nil
+
+	case "add":
+		result := start.AddDate(0, 0, params.Days)
+		if params.Duration != "" {
+			d, err := time.ParseDuration(params.Duration)
+			if err != nil {
+				return "", fmt.Errorf("invalid duration %q: %w", params.Duration, err)
+			}
+			result = result.Add(d)
+		}
+		return result.Format(outputLayout), nil
+
+	case "diff":
+		end, err := t.clock.Parse(params.ToTime, fromLoc)
+		if err != nil {
+			return "", err
+		}
+		return formatDiff(end.Sub(start)), nil
+
+	default:
+		return "", fmt.Errorf("unknown action %q", params.Action)
+	}
+}
+
+// loadLocation loads a timezone by name, falling back to def when empty.
+func loadLocation(name string, def *time.Location) (*time.Location, error) {
+	if name == "" {
+		return def, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// formatDiff renders a duration in days, hours, and minutes.
+func formatDiff(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	days := int(d / (24 * time.Hour))
+	d -= time.Duration(days) * 24 * time.Hour
+	hours := int(d / time.Hour)
+	d -= time.Duration(hours) * time.Hour
+	minutes := int(d / time.Minute)
+	return fmt.Sprintf("%s%dd %dh %dm", sign, days, hours, minutes)
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
@@ -0,0 +1,135 @@
+package timetool
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/clock"
+)
+
+func newTestTool(t *testing.T) *Tool {
+	t.Helper()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c, err := clock.New(clock.Config{
+		Default: "Europe/Paris",
+		Now:     func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("clock.New() error = %v", err)
+	}
+	tool, err := New(Config{Clock: c})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return tool
+}
+
+func TestExecute(t *testing.T) {
+	tool := newTestTool(t)
+
+	tests := []struct {
+		name    string
+		args    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "now in user timezone",
+			args: `{"action":"now"}`,
+			want: "Sun 2026-03-01 13:00 CET (+01:00)",
+		},
+		{
+			name: "now in another timezone",
+			args: `{"action":"now","to_timezone":"Asia/Tokyo"}`,
+			want: "Sun 2026-03-01 21:00 JST (+09:00)",
+		},
+		{
+			name: "convert summer time",
+			args: `{"action":"convert","time":"2026-07-04 09:00","timezone":"America/New_York","to_timezone":"Europe/London"}`,
+			want: "Sat 2026-07-04 09:00 EDT (-04:00) = Sat 2026-07-04 14:00 BST (+01:00)",
+		},
+		{
+			name: "convert half-hour offset",
+			args: `{"action":"convert","time":"2026-01-15 09:00","timezone":"America/New_York","to_timezone":"Asia/Kolkata"}`,
+			want: "Thu 2026-01-15 09:00 EST (-05:00) = Thu 2026-01-15 19:30 IST (+05:30)",
+		},
+		{
+			name: "convert from user timezone",
+			args: `{"action":"convert","time":"2026-03-01 09:00","to_timezone":"UTC"}`,
+			want: "Sun 2026-03-01 09:00 CET (+01:00) = Sun 2026-03-01 08:00 UTC (+00:00)",
+		},
+		{
+			name: "convert RFC 3339 keeps its offset",
+			args: `{"action":"convert","time":"2026-03-01T09:00:00-08:00","timezone":"America/Los_Angeles","to_timezone":"Europe/Berlin"}`,
+			want: "Sun 2026-03-01 09:00 PST (-08:00) = Sun 2026-03-01 18:00 CET (+01:00)",
+		},
+		{
+			name: "add calendar day across DST",
+			args: `{"action":"add","time":"2026-03-07 12:00","timezone":"America/New_York","days":1}`,
+			want: "Sun 2026-03-08 12:00 EDT (-04:00)",
+		},
+		{
+			name: "add duration across DST",
+			args: `{"action":"add","time":"2026-03-07 12:00","timezone":"America/New_York","duration":"24h"}`,
+			want: "Sun 2026-03-08 13:00 EDT (-04:00)",
+		},
+		{
+			name: "diff",
+			args: `{"action":"diff","time":"2026-03-01 10:00","to_time":"2026-03-02 12:30"}`,
+			want: "1d 2h 30m",
+		},
+		{
+			name: "diff backwards",
+			args: `{"action":"diff","time":"2026-03-02 12:30","to_time":"2026-03-01 10:00"}`,
+			want: "-1d 2h 30m",
+		},
+		{
+			name:    "unknown timezone",
+			args:    `{"action":"convert","time":"2026-03-01 09:00","to_timezone":"Mars/Olympus"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			args:    `{"action":"add","duration":"soon"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			args:    `{"action":"sleep"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tool.Execute(context.Background(), json.RawMessage(tt.args))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTimezone(t *testing.T) {
+	tool := newTestTool(t)
+	ctx := agent.WithContact(context.Background(), "telegram:42")
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"action":"set_timezone","timezone":"Asia/Tokyo"}`)); err == nil {
+		t.Error("set_timezone without a contact should fail")
+	}
+	if _, err := tool.Execute(ctx, json.RawMessage(`{"action":"set_timezone","timezone":"Asia/Tokyo"}`)); err != nil {
+		t.Fatalf("set_timezone error = %v", err)
+	}
+	got, err := tool.Execute(ctx, json.RawMessage(`{"action":"now"}`))
+	if err != nil || got != "Sun 2026-03-01 21:00 JST (+09:00)" {
+		t.Errorf("now after set_timezone = %q, %v", got, err)
+	}
+	if got, _ := tool.Execute(context.Background(), json.RawMessage(`{"action":"now"}`)); got != "Sun 2026-03-01 13:00 CET (+01:00)" {
+		t.Errorf("now for another user = %q, want the default timezone", got)
+	}
+}
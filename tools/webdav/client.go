@@ -0,0 +1,204 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a file or folder on the server.
+type Entry struct {
+	Path     string // Relative to the client's base URL
+	Name     string
+	IsDir    bool
+	Size     int64
+	MimeType string
+	Modified time.Time
+}
+
+// Client is a minimal WebDAV client for listing, reading, and writing files.
+type Client struct {
+	base     *url.URL
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient creates a client for the WebDAV root at baseURL, such as
+// https://cloud.example.com/remote.php/dav/files/alice for Nextcloud.
+func NewClient(baseURL, username, password string) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid webdav url %q", baseURL)
+	}
+	return &Client{
+		base:     u,
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 2 * time.Minute},
+	}, nil
+}
+
+// propfindBody requests the properties used by List.
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop>
+<d:displayname/><d:getcontentlength/><d:getcontenttype/><d:getlastmodified/><d:resourcetype/>
+</d:prop></d:propfind>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ContentLength int64  `xml:"getcontentlength"`
+				ContentType   string `xml:"getcontenttype"`
+				LastModified  string `xml:"getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// List returns the entries directly inside dir, folders first.
+func (c *Client) List(ctx context.Context, dir string) ([]Entry, error) {
+	resp, err := c.do(ctx, "PROPFIND", dir, strings.NewReader(propfindBody), map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError("list", dir, resp)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("parse listing: %w", err)
+	}
+
+	self := cleanPath(dir)
+	var entries []Entry
+	for _, r := range ms.Responses {
+		p, err := c.relative(r.Href)
+		if err != nil || p == self {
+			continue
+		}
+		e := Entry{Path: p, Name: path.Base(p)}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			e.IsDir = ps.Prop.ResourceType.Collection != nil
+			e.Size = ps.Prop.ContentLength
+			e.MimeType = ps.Prop.ContentType
+			if t, err := http.ParseTime(ps.Prop.LastModified); err == nil {
+				e.Modified = t
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// Download reads a file, failing if it is larger than maxBytes.
+func (c *Client) Download(ctx context.Context, file string, maxBytes int64) (data []byte, mimeType string, err error) {
+	resp, err := c.do(ctx, http.MethodGet, file, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", statusError("download", file, resp)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("%s is %d bytes, limit is %d", file, resp.ContentLength, maxBytes)
+	}
+
+	data, err = io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", file, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("%s exceeds the %d byte limit", file, maxBytes)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// Upload writes a file, replacing any existing one.
+func (c *Client) Upload(ctx context.Context, file string, data []byte, mimeType string) error {
+	headers := map[string]string{}
+	if mimeType != "" {
+		headers["Content-Type"] = mimeType
+	}
+	resp, err := c.do(ctx, http.MethodPut, file, bytes.NewReader(data), headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return statusError("upload", file, resp)
+	}
+	return nil
+}
+
+// do sends an authenticated request for a path relative to the base URL.
+func (c *Client) do(ctx context.Context, method, p string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	u := *c.base
+	u.Path = c.base.Path + cleanPath(p)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(req) //nolint:gosec // G704: Server URL comes from operator configuration
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, p, err)
+	}
+	return resp, nil
+}
+
+// relative converts a response href to a path relative to the base URL.
+func (c *Client) relative(href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	p := strings.TrimPrefix(u.Path, c.base.Path)
+	return cleanPath(p), nil
+}
+
+// cleanPath normalizes a path to "/a/b" form. ".." cannot climb above "/".
+func cleanPath(p string) string {
+	return path.Clean("/" + strings.TrimSpace(p))
+}
+
+func statusError(op, p string, resp *http.Response) error {
+	return fmt.Errorf("%s %s: server returned %s", op, p, resp.Status)
+}
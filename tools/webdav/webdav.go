@@ -0,0 +1,263 @@
+// Package webdav provides a file tool for WebDAV servers such as Nextcloud.
+package webdav
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// DefaultMaxFileSize limits downloads and uploads to 20 MB.
+const DefaultMaxFileSize = 20 << 20
+
+// maxInlineText is the largest text file returned inline when the channel
+// cannot receive attachments.
+const maxInlineText = 16 << 10
+
+// Folder grants access to a folder and everything below it.
+type Folder struct {
+	Path  string
+	Write bool // Allow uploads; folders are always readable
+}
+
+// Tool lists, downloads, and uploads files on a WebDAV server.
+type Tool struct {
+	client      *Client
+	folders     []Folder
+	maxFileSize int64
+	logger      *slog.Logger
+}
+
+// Config configures the WebDAV tool.
+type Config struct {
+	URL         string
+	Username    string
+	Password    string //nolint:gosec // G117: Password is intentionally stored for server authentication
+	Folders     []Folder
+	MaxFileSize int64 // Bytes; default DefaultMaxFileSize
+	Logger      *slog.Logger
+}
+
+// New creates a new WebDAV tool.
+func New(config Config) (*Tool, error) {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = DefaultMaxFileSize
+	}
+	if len(config.Folders) == 0 {
+		return nil, fmt.Errorf("at least one folder must be allowed")
+	}
+
+	client, err := NewClient(config.URL, config.Username, config.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]Folder, len(config.Folders))
+	for i, f := range config.Folders {
+		folders[i] = Folder{Path: cleanPath(f.Path), Write: f.Write}
+	}
+
+	return &Tool{
+		client:      client,
+		folders:     folders,
+		maxFileSize: config.MaxFileSize,
+		logger:      config.Logger,
+	}, nil
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "files"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	var allowed []string
+	for _, f := range t.folders {
+		mode := "read-only"
+		if f.Write {
+			mode = "read-write"
+		}
+		allowed = append(allowed, fmt.Sprintf("%s (%s)", f.Path, mode))
+	}
+	return "List, download, and upload files on the user's cloud drive. " +
+		"Downloaded files are sent to the user as attachments. " +
+		"Upload saves text content, or a file the user attached to their message. " +
+		"Accessible folders: " + strings.Join(allowed, ", ") + "."
+}
+
+// Parameters returns the JSON schema for tool parameters.
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"list", "download", "upload"},
+				"description": "The operation to perform",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Folder to list, file to download, or destination file or folder for upload",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to upload; omit to upload the user's attachment",
+			},
+		},
+		"required": []string{"action", "path"},
+	}
+}
+
+// Execute performs a file operation.
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Action  string  `json:"action"`
+		Path    string  `json:"path"`
+		Content *string `json:"content"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse parameters: %w", err)
+	}
+	p := cleanPath(params.Path)
+
+	switch params.Action {
+	case "list":
+		if err := t.checkAccess(p, false); err != nil {
+			return "", err
+		}
+		return t.list(ctx, p)
+	case "download":
+		if err := t.checkAccess(p, false); err != nil {
+			return "", err
+		}
+		return t.download(ctx, p)
+	case "upload":
+		return t.upload(ctx, p, params.Content)
+	default:
+		return "", fmt.Errorf("unknown action %q", params.Action)
+	}
+}
+
+func (t *Tool) list(ctx context.Context, dir string) (string, error) {
+	entries, err := t.client.List(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return dir + " is empty.", nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s:\n", dir)
+	for _, e := range entries {
+		if e.IsDir {
+			fmt.Fprintf(&sb, "  %s/\n", e.Name)
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s  %s  %s\n", e.Name, formatSize(e.Size), e.Modified.Format("2006-01-02"))
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+func (t *Tool) download(ctx context.Context, file string) (string, error) {
+	data, mimeType, err := t.client.Download(ctx, file, t.maxFileSize)
+	if err != nil {
+		return "", err
+	}
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(file)); byExt != "" {
+			mimeType = byExt
+		} else {
+			mimeType = http.DetectContentType(data)
+		}
+	}
+	t.logger.Info("webdav download", "path", file, "bytes", len(data))
+
+	if atts := agent.AttachmentsFromContext(ctx); atts != nil {
+		atts.Attach(agent.Attachment{Filename: path.Base(file), MimeType: mimeType, Data: data})
+		return fmt.Sprintf("Attached %s (%s) to the reply.", path.Base(file), formatSize(int64(len(data)))), nil
+	}
+
+	// Without attachment support, small text files can still be shown inline
+	if strings.HasPrefix(mimeType, "text/") && len(data) <= maxInlineText {
+		return string(data), nil
+	}
+	return "", fmt.Errorf("this channel cannot receive file attachments")
+}
+
+func (t *Tool) upload(ctx context.Context, dest string, content *string) (string, error) {
+	var data []byte
+	var mimeType string
+
+	if content != nil {
+		data = []byte(*content)
+		mimeType = mime.TypeByExtension(path.Ext(dest))
+	} else {
+		atts := agent.AttachmentsFromContext(ctx)
+		if atts == nil || len(atts.Incoming) == 0 {
+			return "", fmt.Errorf("nothing to upload: provide content or ask the user to attach a file")
+		}
+		att := atts.Incoming[0]
+		data = att.Data
+		mimeType = att.MimeType
+		// A destination without an extension is treated as a folder
+		if path.Ext(dest) == "" && att.Filename != "" {
+			dest = path.Join(dest, path.Base(att.Filename))
+		}
+	}
+
+	if int64(len(data)) > t.maxFileSize {
+		return "", fmt.Errorf("file is %s, limit is %s", formatSize(int64(len(data))), formatSize(t.maxFileSize))
+	}
+	if err := t.checkAccess(dest, true); err != nil {
+		return "", err
+	}
+	if err := t.client.Upload(ctx, dest, data, mimeType); err != nil {
+		return "", err
+	}
+	t.logger.Info("webdav upload", "path", dest, "bytes", len(data))
+	return fmt.Sprintf("Uploaded %s (%s).", dest, formatSize(int64(len(data)))), nil
+}
+
+// checkAccess verifies p lies within an allowed folder, and that the
+// folder is writable when write is set.
+func (t *Tool) checkAccess(p string, write bool) error {
+	for _, f := range t.folders {
+		if p == f.Path || f.Path == "/" || strings.HasPrefix(p, f.Path+"/") {
+			if write && !f.Write {
+				continue
+			}
+			return nil
+		}
+	}
+	if write {
+		return fmt.Errorf("uploads to %s are not allowed", p)
+	}
+	return fmt.Errorf("access to %s is not allowed", p)
+}
+
+// formatSize renders a byte count for display.
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// Ensure Tool implements agent.Tool interface.
+var _ agent.Tool = (*Tool)(nil)
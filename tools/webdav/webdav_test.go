@@ -0,0 +1,104 @@
+package webdav
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// fakeServer serves a tiny WebDAV tree under /dav.
+func fakeServer(t *testing.T, uploads map[string]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "PROPFIND" && r.URL.Path == "/dav/Documents":
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:">
+<d:response><d:href>/dav/Documents/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+<d:response><d:href>/dav/Documents/contract%20v2.pdf</d:href><d:propstat><d:prop><d:getcontentlength>2048</d:getcontentlength><d:getlastmodified>Mon, 02 Mar 2026 10:00:00 GMT</d:getlastmodified><d:resourcetype/></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+<d:response><d:href>/dav/Documents/Archive/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+</d:multistatus>`)
+		case r.Method == http.MethodGet && r.URL.Path == "/dav/Documents/contract v2.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = io.WriteString(w, "%PDF-1.7 fake")
+		case r.Method == http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			uploads[r.URL.Path] = string(data)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestTool(t *testing.T) {
+	uploads := map[string]string{}
+	srv := fakeServer(t, uploads)
+	defer srv.Close()
+
+	tool, err := New(Config{
+		URL:      srv.URL + "/dav",
+		Username: "alice",
+		Password: "secret",
+		Folders:  []Folder{{Path: "/Documents"}, {Path: "/Uploads", Write: true}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	atts := &agent.Attachments{Incoming: []agent.Attachment{{Filename: "photo.jpg", MimeType: "image/jpeg", Data: []byte("jpeg")}}}
+	ctx := agent.WithAttachments(context.Background(), atts)
+	run := func(args string) (string, error) {
+		return tool.Execute(ctx, json.RawMessage(args))
+	}
+
+	out, err := run(`{"action":"list","path":"/Documents"}`)
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if !strings.Contains(out, "Archive/") || !strings.Contains(out, "contract v2.pdf  2.0 KB  2026-03-02") {
+		t.Errorf("list = %q", out)
+	}
+	if strings.Index(out, "Archive/") > strings.Index(out, "contract") {
+		t.Errorf("list should put folders first: %q", out)
+	}
+
+	if _, err := run(`{"action":"download","path":"/Documents/contract v2.pdf"}`); err != nil {
+		t.Fatalf("download error = %v", err)
+	}
+	if got := atts.Outgoing(); len(got) != 1 || got[0].Filename != "contract v2.pdf" || got[0].MimeType != "application/pdf" {
+		t.Errorf("attachments = %+v", got)
+	}
+
+	if _, err := run(`{"action":"upload","path":"/Uploads"}`); err != nil {
+		t.Fatalf("upload attachment error = %v", err)
+	}
+	if _, err := run(`{"action":"upload","path":"/Uploads/notes.txt","content":"hi"}`); err != nil {
+		t.Fatalf("upload content error = %v", err)
+	}
+	if uploads["/dav/Uploads/photo.jpg"] != "jpeg" || uploads["/dav/Uploads/notes.txt"] != "hi" {
+		t.Errorf("uploads = %v", uploads)
+	}
+
+	for _, args := range []string{
+		`{"action":"upload","path":"/Documents/x.txt","content":"no"}`, // read-only folder
+		`{"action":"list","path":"/Private"}`,                          // not allowed
+		`{"action":"list","path":"/Documents/../Private"}`,             // traversal
+		`{"action":"list","path":"/DocumentsOther"}`,                   // prefix match only on folders
+	} {
+		if _, err := run(args); err == nil {
+			t.Errorf("Execute(%s) should be denied", args)
+		}
+	}
+}
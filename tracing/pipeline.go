@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/plexusone/omnichat/provider"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/plexusone/omniagent/pipeline"
+)
+
+// Attribute keys of channel spans.
+const (
+	channelKey   = attribute.Key("messaging.system")
+	chatKey      = attribute.Key("messaging.destination.name")
+	messageIDKey = attribute.Key("messaging.message.id")
+)
+
+// Receive starts a span for each incoming message, the root of its trace.
+// Use it as the outermost middleware, so the span covers the whole
+// pipeline.
+func Receive() pipeline.Middleware {
+	return func(next provider.MessageHandler) provider.MessageHandler {
+		return func(ctx context.Context, msg provider.IncomingMessage) (err error) {
+			ctx, span := tracer().Start(ctx, "channel.receive",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					channelKey.String(msg.ProviderName),
+					chatKey.String(msg.ChatID),
+					messageIDKey.String(msg.ID),
+				))
+			defer func() { end(span, err) }()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Provider wraps p so each message sent gets a span, a child of the
+// message being answered when there is one.
+func Provider(p provider.Provider) provider.Provider {
+	return &sender{Provider: p}
+}
+
+type sender struct {
+	provider.Provider
+}
+
+func (s *sender) Send(ctx context.Context, chatID string, msg provider.OutgoingMessage) (err error) {
+	ctx, span := tracer().Start(ctx, "channel.send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			channelKey.String(s.Name()),
+			chatKey.String(chatID),
+			attribute.Int("omniagent.message.length", len(msg.Content)),
+			attribute.Int("omniagent.message.media", len(msg.Media)),
+		))
+	defer func() { end(span, err) }()
+	return s.Provider.Send(ctx, chatID, msg)
+}
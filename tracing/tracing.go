@@ -0,0 +1,99 @@
+// Package tracing exports OpenTelemetry traces of the message pipeline:
+// each message received on a channel, its agent run with model calls and
+// tool executions, and the replies sent back, over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// name is the instrumentation name of the pipeline tracer.
+const name = "github.com/plexusone/omniagent/tracing"
+
+// Config configures trace export.
+type Config struct {
+	// Endpoint is the OTLP/HTTP collector URL, such as
+	// http://localhost:4318. Empty uses OTEL_EXPORTER_OTLP_ENDPOINT, then
+	// the OTLP default.
+	Endpoint string
+
+	// Headers are sent with each export, such as an API key for a hosted
+	// collector.
+	Headers map[string]string
+
+	// ServiceName identifies this agent in traces (default: omniagent).
+	ServiceName string
+
+	// Instance identifies this instance when several share a service.
+	Instance string
+
+	// SampleRatio is the share of traces kept, from 0 to 1 (default: 1).
+	SampleRatio float64
+}
+
+// Setup installs a global tracer provider exporting to the configured
+// collector, and returns a function that flushes and stops it.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	if config.ServiceName == "" {
+		config.ServiceName = "omniagent"
+	}
+	if config.SampleRatio <= 0 || config.SampleRatio > 1 {
+		config.SampleRatio = 1
+	}
+
+	var opts []otlptracehttp.Option
+	if config.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(config.Endpoint))
+	}
+	if len(config.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(config.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	attrs := []attribute.KeyValue{semconv.ServiceName(config.ServiceName)}
+	if config.Instance != "" {
+		attrs = append(attrs, semconv.ServiceInstanceID(config.Instance))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// tracer returns omniagent's tracer from the global provider. Until Setup
+// is called it records nothing.
+func tracer() trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// end records err on span, if any, and ends it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
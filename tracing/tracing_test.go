@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omnichat/provider"
+	"github.com/plexusone/omnichat/provider/providertest"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPipelineSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	var logs bytes.Buffer
+	logger := slog.New(LogHandler(slog.NewTextHandler(&logs, nil)))
+	channel := Provider(providertest.NewMockProvider("telegram"))
+	handler := Receive()(func(ctx context.Context, msg provider.IncomingMessage) error {
+		logger.InfoContext(ctx, "handling")
+		if err := channel.Send(ctx, msg.ChatID, provider.OutgoingMessage{Content: "hi"}); err != nil {
+			return err
+		}
+		return errors.New("agent failed")
+	})
+
+	err := handler(context.Background(), provider.IncomingMessage{ProviderName: "telegram", ChatID: "42", ID: "m1"})
+	if err == nil {
+		t.Fatal("handler error lost")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	send, receive := spans[0], spans[1]
+	if send.Name() != "channel.send" || receive.Name() != "channel.receive" {
+		t.Fatalf("spans = %s, %s", send.Name(), receive.Name())
+	}
+	if send.Parent().SpanID() != receive.SpanContext().SpanID() {
+		t.Error("send span is not a child of the receive span")
+	}
+	if receive.Status().Code != codes.Error {
+		t.Errorf("receive status = %v, want error", receive.Status())
+	}
+	traceID := receive.SpanContext().TraceID().String()
+	if !strings.Contains(logs.String(), "trace_id="+traceID) {
+		t.Errorf("log lacks trace ID %s: %s", traceID, logs.String())
+	}
+
+	logs.Reset()
+	logger.Info("outside")
+	if strings.Contains(logs.String(), "trace_id") {
+		t.Errorf("log outside a span has a trace ID: %s", logs.String())
+	}
+}
@@ -0,0 +1,278 @@
+package transcripts
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Format is an export format.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatJSON     Format = "json"
+)
+
+// ParseFormat parses a format name, accepting "md" for Markdown.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "markdown", "md":
+		return FormatMarkdown, nil
+	case "html":
+		return FormatHTML, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (use markdown, html, or json)", s)
+	}
+}
+
+// ContentType returns the MIME type of an export format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+// Options configures an export.
+type Options struct {
+	Format Format // Default: Markdown
+
+	// Tools includes tool calls with their arguments and results.
+	Tools bool
+
+	// Inline embeds attachments as data URIs instead of linking them.
+	Inline bool
+
+	// LinkPrefix is prepended to attachment file names in links. Empty
+	// links to the files on disk.
+	LinkPrefix string
+
+	// Location formats timestamps (default: local).
+	Location *time.Location
+}
+
+// Export renders a session's transcript to w.
+func (s *Store) Export(w io.Writer, sessionID string, opts Options) error {
+	entries, err := s.Entries(sessionID)
+	if err != nil {
+		return err
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.Format == FormatJSON {
+		return s.writeJSON(w, sessionID, entries, opts)
+	}
+
+	doc := document{Session: sessionID}
+	for _, e := range entries {
+		if e.Role == RoleTool && !opts.Tools {
+			continue
+		}
+		v := view{
+			Entry: e,
+			Time:  e.Time.In(opts.Location).Format("2006-01-02 15:04:05 MST"),
+			Title: title(e),
+		}
+		if len(e.Args) > 0 {
+			v.Args = indentJSON(e.Args)
+		}
+		for _, f := range e.Attachments {
+			v.Files = append(v.Files, s.fileView(f, opts))
+		}
+		doc.Entries = append(doc.Entries, v)
+	}
+	if len(entries) > 0 {
+		doc.Start = entries[0].Time.In(opts.Location).Format("2006-01-02 15:04 MST")
+		doc.End = entries[len(entries)-1].Time.In(opts.Location).Format("2006-01-02 15:04 MST")
+	}
+
+	if opts.Format == FormatHTML {
+		return htmlTemplate.Execute(w, doc)
+	}
+	return writeMarkdown(w, doc)
+}
+
+// document is the data rendered by an export.
+type document struct {
+	Session    string
+	Start, End string
+	Entries    []view
+}
+
+// view is an entry prepared for rendering.
+type view struct {
+	Entry
+	Time  string
+	Title string
+	Args  string
+	Files []fileView
+}
+
+// fileView is an attachment prepared for rendering.
+type fileView struct {
+	File
+	URL   template.URL
+	Image bool
+}
+
+// jsonEntry is an entry in a JSON export, with attachment URLs.
+type jsonEntry struct {
+	Entry
+	Attachments []jsonFile `json:"attachments,omitempty"`
+}
+
+// jsonFile is an attachment in a JSON export.
+type jsonFile struct {
+	File
+	URL string `json:"url"`
+}
+
+// writeJSON exports entries as a JSON document for audits and tooling.
+func (s *Store) writeJSON(w io.Writer, sessionID string, entries []Entry, opts Options) error {
+	doc := struct {
+		Session string      `json:"session"`
+		Entries []jsonEntry `json:"entries"`
+	}{Session: sessionID, Entries: make([]jsonEntry, 0, len(entries))}
+	for _, e := range entries {
+		if e.Role == RoleTool && !opts.Tools {
+			continue
+		}
+		je := jsonEntry{Entry: e}
+		for _, f := range e.Attachments {
+			je.Attachments = append(je.Attachments, jsonFile{File: f, URL: string(s.fileView(f, opts).URL)})
+		}
+		doc.Entries = append(doc.Entries, je)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// fileView resolves how an attachment is referenced.
+func (s *Store) fileView(f File, opts Options) fileView {
+	v := fileView{File: f, Image: strings.HasPrefix(f.MimeType, "image/")}
+	switch {
+	case opts.Inline:
+		data, err := s.readBlob(f.Path)
+		if err == nil {
+			v.URL = template.URL("data:" + f.MimeType + ";base64," + base64.StdEncoding.EncodeToString(data)) //nolint:gosec // G203: data URI built from stored bytes
+			break
+		}
+		v.URL = template.URL(s.FilePath(f)) //nolint:gosec // G203: local file path or blob key
+	case opts.LinkPrefix != "":
+		v.URL = template.URL(opts.LinkPrefix + path.Base(f.Path)) //nolint:gosec // G203: prefix is set by the caller
+	default:
+		v.URL = template.URL(s.FilePath(f)) //nolint:gosec // G203: local file path or blob key
+	}
+	return v
+}
+
+// title labels an entry.
+func title(e Entry) string {
+	switch e.Role {
+	case RoleUser:
+		return "User"
+	case RoleAssistant:
+		return "Assistant"
+	case RoleTool:
+		return "Tool: " + e.Tool
+	default:
+		return string(e.Role)
+	}
+}
+
+// indentJSON pretty-prints JSON, returning it unchanged if invalid.
+func indentJSON(raw json.RawMessage) string {
+	var b bytes.Buffer
+	if err := json.Indent(&b, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	return b.String()
+}
+
+// writeMarkdown renders a document as Markdown.
+func writeMarkdown(w io.Writer, doc document) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Transcript: %s\n\n", doc.Session)
+	if doc.Start != "" {
+		fmt.Fprintf(&b, "%s – %s\n\n", doc.Start, doc.End)
+	}
+	for _, e := range doc.Entries {
+		fmt.Fprintf(&b, "### %s\n\n*%s*\n\n", e.Title, e.Time)
+		if e.Role == RoleTool {
+			if e.Args != "" {
+				fmt.Fprintf(&b, "Arguments:\n\n%s\n\n", fence(e.Args, "json"))
+			}
+			if e.Error != "" {
+				fmt.Fprintf(&b, "Error: %s\n\n", e.Error)
+			} else {
+				fmt.Fprintf(&b, "Result:\n\n%s\n\n", fence(e.Content, ""))
+			}
+		} else if e.Content != "" {
+			fmt.Fprintf(&b, "%s\n\n", e.Content)
+		}
+		for _, f := range e.Files {
+			if f.Image {
+				fmt.Fprintf(&b, "![%s](<%s>)\n\n", f.Name, f.URL)
+			} else {
+				fmt.Fprintf(&b, "[%s](<%s>) (%s, %d bytes)\n\n", f.Name, f.URL, f.MimeType, f.Size)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fence wraps text in a code block longer than any backtick run inside it.
+func fence(text, lang string) string {
+	ticks := "```"
+	for strings.Contains(text, ticks) {
+		ticks += "`"
+	}
+	return ticks + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + ticks
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Transcript: {{.Session}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.entry { border-left: 3px solid #ccc; padding: 0.25rem 1rem; margin: 1rem 0; }
+.user { border-color: #3b82f6; }
+.assistant { border-color: #10b981; }
+.tool { border-color: #f59e0b; font-size: 0.9rem; }
+.meta { color: #666; font-size: 0.8rem; }
+.content { white-space: pre-wrap; }
+pre { background: #f5f5f5; padding: 0.5rem; overflow-x: auto; }
+img { max-width: 100%; }
+.error { color: #b91c1c; }
+</style>
+</head>
+<body>
+<h1>Transcript: {{.Session}}</h1>
+{{if .Start}}<p class="meta">{{.Start}} – {{.End}}</p>{{end}}
+{{range .Entries}}<div class="entry {{.Role}}">
+<p><strong>{{.Title}}</strong> <span class="meta">{{.Time}}</span></p>
+{{if eq .Role "tool"}}{{if .Args}}<pre>{{.Args}}</pre>{{end}}{{if .Error}}<p class="error">{{.Error}}</p>{{else}}<pre>{{.Content}}</pre>{{end}}{{else if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{range .Files}}{{if .Image}}<p><img src="{{.URL}}" alt="{{.Name}}"></p>{{else}}<p><a href="{{.URL}}" download="{{.Name}}">{{.Name}}</a> <span class="meta">{{.MimeType}}, {{.Size}} bytes</span></p>{{end}}
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
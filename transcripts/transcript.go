@@ -0,0 +1,249 @@
+// Package transcripts records conversations and renders them as Markdown
+// or HTML documents.
+package transcripts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omniagent/storage"
+)
+
+// ErrNotFound is returned for sessions or files without a transcript.
+var ErrNotFound = errors.New("transcript not found")
+
+// Role identifies who produced an entry.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// Entry is one step of a conversation.
+type Entry struct {
+	Time        time.Time       `json:"time"`
+	Role        Role            `json:"role"`
+	Content     string          `json:"content,omitempty"` // Message text, or the tool result
+	Tool        string          `json:"tool,omitempty"`
+	Args        json.RawMessage `json:"args,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Model       string          `json:"model,omitempty"`
+	Attachments []File          `json:"attachments,omitempty"`
+}
+
+// File is an attachment saved alongside a transcript.
+type File struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Size     int    `json:"size"`
+	Path     string `json:"path"` // Blob key, relative to the store directory
+}
+
+// Session summarizes a recorded session.
+type Session struct {
+	ID      string    `json:"id"`
+	Entries int       `json:"entries"`
+	Updated time.Time `json:"updated"`
+}
+
+// Store keeps one JSON lines blob per session, with attachments under
+// files/. Blobs live in a directory or any other blob store, such as an
+// S3 bucket.
+type Store struct {
+	blobs storage.BlobStore
+	mu    sync.Mutex
+}
+
+// Open opens (or creates) a store in dir.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0o750); err != nil {
+		return nil, fmt.Errorf("create transcripts dir: %w", err)
+	}
+	blobs, err := storage.NewFileStore(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open transcripts: %w", err)
+	}
+	return New(blobs), nil
+}
+
+// New creates a store keeping its blobs in blobs.
+func New(blobs storage.BlobStore) *Store {
+	return &Store{blobs: blobs}
+}
+
+// Append adds entries to a session's transcript.
+func (s *Store) Append(sessionID string, entries ...Entry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("encode transcript: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := storage.Append(context.Background(), s.blobs, sessionKey(sessionID), &buf); err != nil {
+		return fmt.Errorf("write transcript: %w", err)
+	}
+	return nil
+}
+
+// SaveFile stores an attachment for a session and returns its reference.
+func (s *Store) SaveFile(sessionID, name, mimeType string, data []byte) (File, error) {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		name = "attachment"
+	}
+	key := path.Join("files", encodeSession(sessionID), fmt.Sprintf("%d-%s", time.Now().UnixNano(), name))
+	if err := s.blobs.Put(context.Background(), key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return File{}, fmt.Errorf("write attachment: %w", err)
+	}
+	return File{Name: name, MimeType: mimeType, Size: len(data), Path: key}, nil
+}
+
+// Entries returns a session's transcript, oldest first.
+func (s *Store) Entries(sessionID string) ([]Entry, error) {
+	rc, err := s.blobs.Get(context.Background(), sessionKey(sessionID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open transcript: %w", err)
+	}
+	defer rc.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("parse transcript: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+	return entries, nil
+}
+
+// ReadFile returns an attachment of a session by the base name of its
+// path.
+func (s *Store) ReadFile(sessionID, name string) ([]byte, error) {
+	if name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") || strings.Contains(name, "\\") {
+		return nil, ErrNotFound
+	}
+	return s.readBlob(path.Join("files", encodeSession(sessionID), name))
+}
+
+// readBlob returns the contents of a blob.
+func (s *Store) readBlob(key string) ([]byte, error) {
+	rc, err := s.blobs.Get(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// FilePath returns the absolute path of an attachment, or its key when
+// the store keeps files off the local disk.
+func (s *Store) FilePath(f File) string {
+	if p, ok := storage.LocalPath(s.blobs, f.Path); ok {
+		return p
+	}
+	return f.Path
+}
+
+// Delete removes a session's transcript and attachments.
+func (s *Store) Delete(sessionID string) error {
+	ctx := context.Background()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc, err := s.blobs.Get(ctx, sessionKey(sessionID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("delete transcript: %w", err)
+	}
+	rc.Close()
+	if err := s.blobs.Delete(ctx, sessionKey(sessionID)); err != nil {
+		return fmt.Errorf("delete transcript: %w", err)
+	}
+	files, err := s.blobs.List(ctx, path.Join("files", encodeSession(sessionID))+"/")
+	if err != nil {
+		return fmt.Errorf("delete attachments: %w", err)
+	}
+	for _, f := range files {
+		if err := s.blobs.Delete(ctx, f.Key); err != nil {
+			return fmt.Errorf("delete attachments: %w", err)
+		}
+	}
+	return nil
+}
+
+// Sessions lists recorded sessions, most recently updated first.
+func (s *Store) Sessions() ([]Session, error) {
+	blobs, err := s.blobs.List(context.Background(), "")
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, 0, len(blobs))
+	for _, b := range blobs {
+		name, ok := strings.CutSuffix(b.Key, ".jsonl")
+		if !ok || strings.Contains(name, "/") {
+			continue
+		}
+		id, ok := decodeSession(name)
+		if !ok {
+			continue
+		}
+		entries, err := s.Entries(id)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, Session{ID: id, Entries: len(entries), Updated: b.LastModified})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Updated.After(sessions[j].Updated) })
+	return sessions, nil
+}
+
+// sessionKey returns the key of a session's transcript blob.
+func sessionKey(sessionID string) string {
+	return encodeSession(sessionID) + ".jsonl"
+}
+
+// encodeSession makes a session ID safe for file names. Session IDs such
+// as "telegram:123" contain characters some file systems reject.
+func encodeSession(sessionID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sessionID))
+}
+
+func decodeSession(name string) (string, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(name)
+	return string(b), err == nil
+}
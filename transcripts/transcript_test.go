@@ -0,0 +1,182 @@
+package transcripts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omniagent/storage"
+)
+
+func TestStoreExport(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	const session = "telegram:123"
+	img, err := store.SaveFile(session, "../chart.png", "image/png", []byte("PNG"))
+	if err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+	if img.Name != "chart.png" {
+		t.Errorf("file name = %q, want path stripped", img.Name)
+	}
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	err = store.Append(session,
+		Entry{Time: start, Role: RoleUser, Content: "Agree to 10% off?"},
+		Entry{Time: start, Role: RoleTool, Tool: "calculator", Args: []byte(`{"expr":"100*0.9"}`), Content: "90"},
+		Entry{Time: start.Add(time.Minute), Role: RoleAssistant, Content: "Deal at <b>90</b>.", Attachments: []File{img}},
+	)
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	entries, err := store.Entries(session)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Entries = %d, %v; want 3", len(entries), err)
+	}
+	sessions, err := store.Sessions()
+	if err != nil || len(sessions) != 1 || sessions[0].ID != session || sessions[0].Entries != 3 {
+		t.Errorf("Sessions = %+v, %v", sessions, err)
+	}
+
+	export := func(opts Options) string {
+		t.Helper()
+		opts.Location = time.UTC
+		var b bytes.Buffer
+		if err := store.Export(&b, session, opts); err != nil {
+			t.Fatalf("Export: %v", err)
+		}
+		return b.String()
+	}
+
+	md := export(Options{})
+	if !strings.Contains(md, "# Transcript: telegram:123") || !strings.Contains(md, "Deal at <b>90</b>.") {
+		t.Errorf("markdown missing content:\n%s", md)
+	}
+	if strings.Contains(md, "calculator") {
+		t.Error("markdown includes tool calls without Tools")
+	}
+	if !strings.Contains(md, "![chart.png](<"+store.FilePath(img)+">)") {
+		t.Errorf("markdown missing image link:\n%s", md)
+	}
+
+	md = export(Options{Tools: true, LinkPrefix: "/files/"})
+	if !strings.Contains(md, "### Tool: calculator") || !strings.Contains(md, `"expr": "100*0.9"`) {
+		t.Errorf("markdown missing tool call:\n%s", md)
+	}
+	if !strings.Contains(md, "(</files/") {
+		t.Errorf("markdown ignores link prefix:\n%s", md)
+	}
+
+	page := export(Options{Format: FormatHTML, Inline: true})
+	if !strings.Contains(page, "Deal at &lt;b&gt;90&lt;/b&gt;.") {
+		t.Error("html does not escape content")
+	}
+	if !strings.Contains(page, `src="data:image/png;base64,UE5H"`) {
+		t.Errorf("html missing inline image:\n%s", page)
+	}
+
+	var doc struct {
+		Session string `json:"session"`
+		Entries []struct {
+			Role        Role            `json:"role"`
+			Tool        string          `json:"tool"`
+			Args        json.RawMessage `json:"args"`
+			Attachments []struct {
+				Name string `json:"name"`
+				URL  string `json:"url"`
+			} `json:"attachments"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(export(Options{Format: FormatJSON, Tools: true, LinkPrefix: "/files/"})), &doc); err != nil {
+		t.Fatalf("json export: %v", err)
+	}
+	if doc.Session != session || len(doc.Entries) != 3 || doc.Entries[1].Tool != "calculator" || !strings.Contains(string(doc.Entries[1].Args), "100*0.9") {
+		t.Errorf("json export = %+v", doc)
+	}
+	if files := doc.Entries[2].Attachments; len(files) != 1 || !strings.HasPrefix(files[0].URL, "/files/") {
+		t.Errorf("json attachments = %+v, want a link under the prefix", files)
+	}
+
+	if err := store.Export(&bytes.Buffer{}, "telegram:999", Options{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Export unknown session = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreReadFile(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	f, err := store.SaveFile("http:a", "notes.txt", "text/plain", []byte("hi"))
+	if err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+	name := f.Path[strings.LastIndex(f.Path, "/")+1:]
+
+	if data, err := store.ReadFile("http:a", name); err != nil || string(data) != "hi" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+	if _, err := store.ReadFile("http:b", name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReadFile from other session = %v, want ErrNotFound", err)
+	}
+	if _, err := store.ReadFile("http:a", "../"+name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReadFile with traversal = %v, want ErrNotFound", err)
+	}
+}
+
+func TestBlobStore(t *testing.T) {
+	local, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	// Hide the local-file methods, as an S3 store lacks them
+	store := New(storage.WithPrefix(struct{ storage.BlobStore }{local}, "transcripts"))
+
+	f, err := store.SaveFile("telegram:1", "chart.png", "image/png", []byte("png"))
+	if err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+	if err := store.Append("telegram:1", Entry{Role: RoleUser, Content: "hi", Attachments: []File{f}}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := store.Append("telegram:1", Entry{Role: RoleAssistant, Content: "hello"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if got := store.FilePath(f); got != f.Path {
+		t.Errorf("FilePath = %q, want the blob key %q", got, f.Path)
+	}
+
+	entries, err := store.Entries("telegram:1")
+	if err != nil || len(entries) != 2 || entries[1].Content != "hello" {
+		t.Fatalf("Entries = %+v, %v", entries, err)
+	}
+	sessions, err := store.Sessions()
+	if err != nil || len(sessions) != 1 || sessions[0].ID != "telegram:1" || sessions[0].Entries != 2 {
+		t.Errorf("Sessions = %+v, %v", sessions, err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Export(&buf, "telegram:1", Options{Inline: true}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if !strings.Contains(buf.String(), "data:image/png;base64,") {
+		t.Errorf("inline export lacks the attachment:\n%s", buf.String())
+	}
+
+	if err := store.Delete("telegram:1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if blobs, _ := local.List(context.Background(), "transcripts/"); len(blobs) != 0 {
+		t.Errorf("Delete left %v", blobs)
+	}
+	if err := store.Delete("telegram:1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete twice = %v, want ErrNotFound", err)
+	}
+}
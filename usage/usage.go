@@ -0,0 +1,202 @@
+// Package usage records the tokens and estimated cost of every model
+// request, so spending can be broken down by provider, model, channel, or
+// session over any period.
+package usage
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omniagent/eval"
+)
+
+// Groupings for Summarize.
+const (
+	ByProvider = "provider"
+	ByModel    = "model"
+	ByChannel  = "channel"
+	BySession  = "session"
+	ByDay      = "day"
+)
+
+// Record is the usage of one model request.
+type Record struct {
+	Time             time.Time `json:"time"`
+	SessionID        string    `json:"session_id"`
+	Channel          string    `json:"channel,omitempty"`
+	Persona          string    `json:"persona,omitempty"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Cost             float64   `json:"cost"` // USD, estimated when recorded
+}
+
+// Total is the usage of one group.
+type Total struct {
+	Key              string  `json:"key"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Tokens returns the prompt and completion tokens combined.
+func (t Total) Tokens() int {
+	return t.PromptTokens + t.CompletionTokens
+}
+
+// Ledger appends records to a JSON lines file. It is safe for concurrent
+// use.
+type Ledger struct {
+	path    string
+	pricing eval.Pricing
+	mu      sync.Mutex
+	f       *os.File
+}
+
+// Open opens the ledger at path for appending, creating it if needed.
+// Costs are estimated with pricing; unpriced models cost 0.
+func Open(path string, pricing eval.Pricing) (*Ledger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("create usage directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) //nolint:gosec // G304: Path comes from operator configuration
+	if err != nil {
+		return nil, fmt.Errorf("open usage ledger: %w", err)
+	}
+	return &Ledger{path: path, pricing: pricing, f: f}, nil
+}
+
+// Record appends r, filling in its time and cost if unset.
+func (l *Ledger) Record(r Record) error {
+	if r.PromptTokens+r.CompletionTokens <= 0 {
+		return nil
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if r.Cost == 0 {
+		r.Cost = l.pricing.Cost(r.Model, r.PromptTokens, r.CompletionTokens)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode usage record: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write usage ledger: %w", err)
+	}
+	return nil
+}
+
+// Summary returns totals since a time, grouped by one of the By
+// constants.
+func (l *Ledger) Summary(since time.Time, by string) ([]Total, error) {
+	records, err := Query(l.path, since, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return Summarize(records, by, time.Local)
+}
+
+// Close closes the ledger file.
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// Query reads the ledger at path and returns records from since until
+// until, oldest first. Zero times are unbounded, and a missing ledger has
+// no records.
+func Query(path string, since, until time.Time) ([]Record, error) {
+	f, err := os.Open(path) //nolint:gosec // G304: Path comes from operator configuration
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("usage ledger line %d: %w", line, err)
+		}
+		if (!since.IsZero() && r.Time.Before(since)) || (!until.IsZero() && !r.Time.Before(until)) {
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read usage ledger: %w", err)
+	}
+	return records, nil
+}
+
+// Summarize totals records by provider, model, channel, session, or day
+// (in loc). Groups are sorted by cost, then tokens, highest first; days
+// are sorted by date.
+func Summarize(records []Record, by string, loc *time.Location) ([]Total, error) {
+	var key func(Record) string
+	switch by {
+	case ByProvider:
+		key = func(r Record) string { return r.Provider }
+	case ByModel:
+		key = func(r Record) string { return r.Model }
+	case ByChannel:
+		key = func(r Record) string { return r.Channel }
+	case BySession:
+		key = func(r Record) string { return r.SessionID }
+	case ByDay:
+		key = func(r Record) string { return r.Time.In(loc).Format(time.DateOnly) }
+	default:
+		return nil, fmt.Errorf("unknown grouping %q (want provider, model, channel, session, or day)", by)
+	}
+
+	index := make(map[string]int)
+	var totals []Total
+	for _, r := range records {
+		k := key(r)
+		i, ok := index[k]
+		if !ok {
+			i = len(totals)
+			index[k] = i
+			totals = append(totals, Total{Key: k})
+		}
+		totals[i].Requests++
+		totals[i].PromptTokens += r.PromptTokens
+		totals[i].CompletionTokens += r.CompletionTokens
+		totals[i].Cost += r.Cost
+	}
+
+	if by == ByDay {
+		slices.SortFunc(totals, func(a, b Total) int { return cmp.Compare(a.Key, b.Key) })
+		return totals, nil
+	}
+	slices.SortFunc(totals, func(a, b Total) int {
+		if c := cmp.Compare(b.Cost, a.Cost); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Tokens(), a.Tokens()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return totals, nil
+}
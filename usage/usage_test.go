@@ -0,0 +1,61 @@
+package usage
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omniagent/eval"
+)
+
+func TestLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	ledger, err := Open(path, eval.Pricing{"gpt-4o": {Prompt: 2, Completion: 10}})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: day, SessionID: "telegram:1", Channel: "telegram", Provider: "openai", Model: "gpt-4o", PromptTokens: 1000, CompletionTokens: 100},
+		{Time: day.Add(time.Hour), SessionID: "telegram:1", Channel: "telegram", Provider: "ollama", Model: "llama3", PromptTokens: 500, CompletionTokens: 50},
+		{Time: day.Add(24 * time.Hour), SessionID: "discord:2", Channel: "discord", Provider: "openai", Model: "gpt-4o", PromptTokens: 2000, CompletionTokens: 200},
+		{Time: day.Add(25 * time.Hour), SessionID: "discord:2", Provider: "openai", Model: "gpt-4o"}, // No usage; skipped
+	}
+	for _, r := range records {
+		if err := ledger.Record(r); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	ledger.Close()
+
+	all, err := Query(path, time.Time{}, time.Time{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Query() = %d records, %v; want 3", len(all), err)
+	}
+	if want := 0.003; math.Abs(all[0].Cost-want) > 1e-9 {
+		t.Errorf("cost = %v, want %v", all[0].Cost, want)
+	}
+	if got, _ := Query(path, day.Add(time.Hour), day.Add(24*time.Hour)); len(got) != 1 || got[0].Model != "llama3" {
+		t.Errorf("Query(range) = %+v", got)
+	}
+
+	byProvider, err := Summarize(all, ByProvider, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byProvider) != 2 || byProvider[0].Key != "openai" || byProvider[0].Requests != 2 || byProvider[0].Tokens() != 3300 {
+		t.Errorf("by provider = %+v", byProvider)
+	}
+	byDay, _ := Summarize(all, ByDay, time.UTC)
+	if len(byDay) != 2 || byDay[0].Key != "2026-03-10" || byDay[0].Requests != 2 {
+		t.Errorf("by day = %+v", byDay)
+	}
+	bySession, _ := Summarize(all, BySession, time.UTC)
+	if bySession[0].Key != "discord:2" {
+		t.Errorf("by session = %+v, want the costliest first", bySession)
+	}
+	if _, err := Summarize(all, "user", time.UTC); err == nil {
+		t.Error("Summarize(unknown grouping) succeeded")
+	}
+}
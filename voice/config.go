@@ -1,6 +1,8 @@
 // Package voice provides voice processing capabilities for omniagent.
 package voice
 
+import "github.com/plexusone/omniagent/flags"
+
 // Config configures voice processing.
 type Config struct {
 	// Enabled indicates whether voice processing is enabled.
@@ -12,6 +14,26 @@ type Config struct {
 	STT STTConfig
 	// TTS configures text-to-speech.
 	TTS TTSConfig
+	// STTFallbacks and TTSFallbacks are tried in order when the provider
+	// before them fails, such as on an outage or exhausted quota.
+	STTFallbacks []STTConfig
+	TTSFallbacks []TTSConfig
+	// Transcode converts audio with ffmpeg.
+	Transcode TranscodeConfig
+	// Flags can turn streaming transcription off at runtime.
+	Flags *flags.Flags
+}
+
+// TranscodeConfig configures audio conversion.
+type TranscodeConfig struct {
+	// Enabled converts voice notes STT providers may not accept (AMR, M4A,
+	// WebM) to WAV, and replies to each channel's format.
+	Enabled bool
+	// FFmpegPath is the ffmpeg binary (default: ffmpeg on PATH).
+	FFmpegPath string
+	// ChannelFormats overrides DefaultChannelFormats: "ogg", "mp3", or
+	// "wav" keyed by channel name.
+	ChannelFormats map[string]string
 }
 
 // STTConfig configures the speech-to-text provider.
@@ -20,10 +42,18 @@ type STTConfig struct {
 	Provider string
 	// APIKey is the provider API key.
 	APIKey string //nolint:gosec // G117: APIKey loaded from config file
+	// BaseURL overrides the provider's endpoint, where it supports one.
+	BaseURL string
+	// Options are provider-specific settings, passed to the provider as
+	// "<provider>.<key>" extensions.
+	Options map[string]string
 	// Model is the provider-specific model identifier.
 	Model string
 	// Language is the BCP-47 language code. Empty for auto-detection.
 	Language string
+	// Streaming transcribes voice notes over the provider's streaming API
+	// when it has one, so long notes are transcribed incrementally.
+	Streaming bool
 }
 
 // TTSConfig configures the text-to-speech provider.
@@ -32,6 +62,10 @@ type TTSConfig struct {
 	Provider string
 	// APIKey is the provider API key.
 	APIKey string //nolint:gosec // G117: APIKey loaded from config file
+	// BaseURL overrides the provider's endpoint, where it supports one.
+	BaseURL string
+	// Options are provider-specific settings, such as Azure's "region".
+	Options map[string]string
 	// Model is the provider-specific model identifier.
 	Model string
 	// VoiceID is the provider-specific voice identifier.
@@ -2,20 +2,35 @@ package voice
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"time"
 
 	"github.com/plexusone/omnivoice"
 	_ "github.com/plexusone/omnivoice/providers/all" // Register all providers
+
+	"github.com/plexusone/omniagent/flags"
 )
 
 // Processor handles voice transcription and synthesis using OmniVoice interfaces.
 type Processor struct {
 	sttProvider  omnivoice.STTProvider
 	ttsProvider  omnivoice.TTSProvider
+	sttFallbacks []sttBackend
+	ttsFallbacks []ttsBackend
 	config       Config
 	logger       *slog.Logger
 	responseMode string
+
+	// transcoder converts audio when transcoding is enabled.
+	transcoder     *Transcoder
+	channelFormats map[string]string
+
+	// streamIdle overrides streamIdleTimeout when set.
+	streamIdle time.Duration
 }
 
 // New creates a new voice processor with the configured providers.
@@ -38,9 +53,9 @@ func New(config Config, logger *slog.Logger) (*Processor, error) {
 	if config.STT.Provider == "" {
 		return nil, fmt.Errorf("STT provider not configured")
 	}
-	sttProv, err := omnivoice.GetSTTProvider(config.STT.Provider, omnivoice.WithAPIKey(config.STT.APIKey))
+	sttProv, err := newSTT(config.STT)
 	if err != nil {
-		return nil, fmt.Errorf("create %s stt: %w", config.STT.Provider, err)
+		return nil, err
 	}
 	p.sttProvider = sttProv
 
@@ -48,59 +63,207 @@ func New(config Config, logger *slog.Logger) (*Processor, error) {
 	if config.TTS.Provider == "" {
 		return nil, fmt.Errorf("TTS provider not configured")
 	}
-	ttsProv, err := omnivoice.GetTTSProvider(config.TTS.Provider, omnivoice.WithAPIKey(config.TTS.APIKey))
+	ttsProv, err := newTTS(config.TTS)
 	if err != nil {
-		return nil, fmt.Errorf("create %s tts: %w", config.TTS.Provider, err)
+		return nil, err
 	}
 	p.ttsProvider = ttsProv
 
+	for _, c := range config.STTFallbacks {
+		prov, err := newSTT(c)
+		if err != nil {
+			return nil, fmt.Errorf("stt fallback: %w", err)
+		}
+		p.sttFallbacks = append(p.sttFallbacks, sttBackend{provider: prov, config: c})
+	}
+	for _, c := range config.TTSFallbacks {
+		prov, err := newTTS(c)
+		if err != nil {
+			return nil, fmt.Errorf("tts fallback: %w", err)
+		}
+		p.ttsFallbacks = append(p.ttsFallbacks, ttsBackend{provider: prov, config: c})
+	}
+
+	if config.Transcode.Enabled {
+		p.transcoder, err = NewTranscoder(config.Transcode.FFmpegPath)
+		if err != nil {
+			return nil, err
+		}
+		p.channelFormats = maps.Clone(DefaultChannelFormats)
+		for channel, format := range config.Transcode.ChannelFormats {
+			if _, ok := formatArgs[format]; !ok {
+				return nil, fmt.Errorf("transcode: unknown format %q for %s", format, channel)
+			}
+			p.channelFormats[channel] = format
+		}
+	}
+
 	return p, nil
 }
 
-// TranscribeAudio converts audio to text using the configured STT provider.
+// sttBackend is an STT provider with its settings.
+type sttBackend struct {
+	provider omnivoice.STTProvider
+	config   STTConfig
+}
+
+// ttsBackend is a TTS provider with its settings.
+type ttsBackend struct {
+	provider omnivoice.TTSProvider
+	config   TTSConfig
+}
+
+// newSTT creates an STT provider.
+func newSTT(c STTConfig) (omnivoice.STTProvider, error) {
+	if c.Provider == "" {
+		return nil, fmt.Errorf("STT provider not configured")
+	}
+	prov, err := omnivoice.GetSTTProvider(c.Provider, providerOptions(c.Provider, c.APIKey, c.BaseURL, c.Options)...)
+	if err != nil {
+		return nil, fmt.Errorf("create %s stt: %w", c.Provider, err)
+	}
+	return prov, nil
+}
+
+// newTTS creates a TTS provider.
+func newTTS(c TTSConfig) (omnivoice.TTSProvider, error) {
+	if c.Provider == "" {
+		return nil, fmt.Errorf("TTS provider not configured")
+	}
+	prov, err := omnivoice.GetTTSProvider(c.Provider, providerOptions(c.Provider, c.APIKey, c.BaseURL, c.Options)...)
+	if err != nil {
+		return nil, fmt.Errorf("create %s tts: %w", c.Provider, err)
+	}
+	return prov, nil
+}
+
+// sttChain returns the STT providers in the order they are tried.
+func (p *Processor) sttChain() []sttBackend {
+	return append([]sttBackend{{provider: p.sttProvider, config: p.config.STT}}, p.sttFallbacks...)
+}
+
+// ttsChain returns the TTS providers in the order they are tried.
+func (p *Processor) ttsChain() []ttsBackend {
+	return append([]ttsBackend{{provider: p.ttsProvider, config: p.config.TTS}}, p.ttsFallbacks...)
+}
+
+// providerOptions builds the options for creating a provider, namespacing
+// provider-specific settings as omnivoice extensions ("azure.region").
+func providerOptions(provider, apiKey, baseURL string, options map[string]string) []omnivoice.ProviderOption {
+	opts := []omnivoice.ProviderOption{omnivoice.WithAPIKey(apiKey)}
+	if baseURL != "" {
+		opts = append(opts, omnivoice.WithBaseURL(baseURL))
+	}
+	for k, v := range options {
+		opts = append(opts, omnivoice.WithExtension(provider+"."+k, v))
+	}
+	return opts
+}
+
+// TranscribeAudio converts audio to text using the configured STT
+// provider, falling back to the next provider in order when one fails.
 func (p *Processor) TranscribeAudio(ctx context.Context, audio []byte, mimeType string) (string, error) {
-	config := omnivoice.TranscriptionConfig{
-		Model:    p.config.STT.Model,
-		Language: p.config.STT.Language,
+	audio, mimeType = p.inboundAudio(ctx, audio, mimeType)
+	var errs []error
+	for i, b := range p.sttChain() {
+		text, err := p.transcribeWith(ctx, b, audio, mimeType, i > 0)
+		if err == nil {
+			return text, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.provider.Name(), err))
+		if ctx.Err() != nil || i == len(p.sttFallbacks) {
+			break
+		}
+		p.logger.Warn("stt provider failed, trying fallback",
+			"provider", b.provider.Name(),
+			"fallback", p.sttFallbacks[i].provider.Name(),
+			"error", err)
 	}
+	if len(errs) == 1 {
+		return "", fmt.Errorf("transcribe: %w", errors.Unwrap(errs[0]))
+	}
+	return "", fmt.Errorf("transcribe: %w", errors.Join(errs...))
+}
 
-	// Set encoding based on MIME type
-	switch mimeType {
-	case "audio/ogg; codecs=opus", "audio/ogg":
-		config.Encoding = "opus"
-	case "audio/mpeg", "audio/mp3":
-		config.Encoding = "mp3"
-	case "audio/wav", "audio/wave":
-		config.Encoding = "wav"
-	case "audio/flac":
-		config.Encoding = "flac"
+// transcribeWith transcribes audio with one provider.
+func (p *Processor) transcribeWith(ctx context.Context, b sttBackend, audio []byte, mimeType string, fallback bool) (string, error) {
+	if sp, ok := b.provider.(omnivoice.STTStreamingProvider); ok && b.config.Streaming && p.config.Flags.Enabled(flags.Streaming) {
+		return p.transcribeStreaming(ctx, sp, b.config, audio, mimeType, fallback)
+	}
+
+	config := omnivoice.TranscriptionConfig{
+		Model:    b.config.Model,
+		Language: b.config.Language,
+		Encoding: encoding(mimeType),
 	}
 
-	result, err := p.sttProvider.Transcribe(ctx, audio, config)
+	result, err := b.provider.Transcribe(ctx, audio, config)
 	if err != nil {
-		return "", fmt.Errorf("transcribe: %w", err)
+		return "", err
 	}
 
 	p.logger.Info("transcription complete",
-		"provider", p.sttProvider.Name(),
+		"provider", b.provider.Name(),
+		"fallback", fallback,
 		"text_length", len(result.Text),
 		"language", result.Language)
 
 	return result.Text, nil
 }
 
-// SynthesizeSpeech converts text to audio using the configured TTS provider.
+// encoding returns the STT encoding for an audio MIME type, or "" to let
+// the provider detect it.
+func encoding(mimeType string) string {
+	switch mimeType {
+	case "audio/ogg; codecs=opus", "audio/ogg":
+		return "opus"
+	case "audio/mpeg", "audio/mp3":
+		return "mp3"
+	case "audio/wav", "audio/wave":
+		return "wav"
+	case "audio/flac":
+		return "flac"
+	}
+	return ""
+}
+
+// SynthesizeSpeech converts text to audio using the configured TTS
+// provider, falling back to the next provider in order when one fails.
 // Returns audio bytes and MIME type.
 func (p *Processor) SynthesizeSpeech(ctx context.Context, text string) ([]byte, string, error) {
+	var errs []error
+	for i, b := range p.ttsChain() {
+		audio, mimeType, err := p.synthesizeWith(ctx, b, text, i > 0)
+		if err == nil {
+			audio, mimeType = p.channelAudio(ctx, audio, mimeType)
+			return audio, mimeType, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.provider.Name(), err))
+		if ctx.Err() != nil || i == len(p.ttsFallbacks) {
+			break
+		}
+		p.logger.Warn("tts provider failed, trying fallback",
+			"provider", b.provider.Name(),
+			"fallback", p.ttsFallbacks[i].provider.Name(),
+			"error", err)
+	}
+	if len(errs) == 1 {
+		return nil, "", fmt.Errorf("synthesize: %w", errors.Unwrap(errs[0]))
+	}
+	return nil, "", fmt.Errorf("synthesize: %w", errors.Join(errs...))
+}
+
+// synthesizeWith synthesizes speech with one provider.
+func (p *Processor) synthesizeWith(ctx context.Context, b ttsBackend, text string, fallback bool) ([]byte, string, error) {
 	config := omnivoice.SynthesisConfig{
-		VoiceID:      p.config.TTS.VoiceID,
-		Model:        p.config.TTS.Model,
+		VoiceID:      b.config.VoiceID,
+		Model:        b.config.Model,
 		OutputFormat: "mp3", // MP3 for broad compatibility; WhatsApp accepts this
 	}
 
-	result, err := p.ttsProvider.Synthesize(ctx, text, config)
+	result, err := b.provider.Synthesize(ctx, text, config)
 	if err != nil {
-		return nil, "", fmt.Errorf("synthesize: %w", err)
+		return nil, "", err
 	}
 
 	// Determine MIME type based on format
@@ -115,7 +278,8 @@ func (p *Processor) SynthesizeSpeech(ctx context.Context, text string) ([]byte,
 	}
 
 	p.logger.Info("synthesis complete",
-		"provider", p.ttsProvider.Name(),
+		"provider", b.provider.Name(),
+		"fallback", fallback,
 		"audio_size", len(result.Audio),
 		"format", result.Format)
 
@@ -131,3 +295,45 @@ func (p *Processor) ResponseMode() string {
 func (p *Processor) Close() error {
 	return nil
 }
+
+// PingTTS lists voices to check that the TTS provider is reachable and the
+// credentials are valid.
+func (p *Processor) PingTTS(ctx context.Context) error {
+	if _, err := p.ttsProvider.ListVoices(ctx); err != nil {
+		return fmt.Errorf("list voices: %w", err)
+	}
+	return nil
+}
+
+// PingSTT transcribes a short silent clip to check the STT provider.
+func (p *Processor) PingSTT(ctx context.Context) error {
+	_, err := p.sttProvider.Transcribe(ctx, silentWAV(), omnivoice.TranscriptionConfig{
+		Model:    p.config.STT.Model,
+		Language: p.config.STT.Language,
+		Encoding: "wav",
+	})
+	if err != nil {
+		return fmt.Errorf("transcribe: %w", err)
+	}
+	return nil
+}
+
+// silentWAV returns half a second of 16 kHz mono 16-bit PCM silence.
+func silentWAV() []byte {
+	const sampleRate, samples = 16000, 8000
+	dataSize := samples * 2
+	b := make([]byte, 44+dataSize)
+	copy(b[0:], "RIFF")
+	binary.LittleEndian.PutUint32(b[4:], uint32(36+dataSize))
+	copy(b[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(b[16:], 16)           // fmt chunk size
+	binary.LittleEndian.PutUint16(b[20:], 1)            // PCM
+	binary.LittleEndian.PutUint16(b[22:], 1)            // mono
+	binary.LittleEndian.PutUint32(b[24:], sampleRate)   // sample rate
+	binary.LittleEndian.PutUint32(b[28:], sampleRate*2) // byte rate
+	binary.LittleEndian.PutUint16(b[32:], 2)            // block align
+	binary.LittleEndian.PutUint16(b[34:], 16)           // bits per sample
+	copy(b[36:], "data")
+	binary.LittleEndian.PutUint32(b[40:], uint32(dataSize))
+	return b
+}
@@ -3,11 +3,20 @@ package voice
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/plexusone/omnivoice"
+
+	"github.com/plexusone/omniagent/agent"
 )
 
 // mockSTTProvider implements omnivoice.STTProvider for testing.
@@ -397,3 +406,242 @@ func TestClose(t *testing.T) {
 		t.Errorf("Close() error = %v", err)
 	}
 }
+
+// mockStreamingSTTProvider streams like Deepgram: each write yields an
+// interim then a final result, and closing the writer closes the events.
+type mockStreamingSTTProvider struct {
+	mockSTTProvider
+	writes int
+}
+
+func (m *mockStreamingSTTProvider) TranscribeStream(ctx context.Context, config omnivoice.TranscriptionConfig) (io.WriteCloser, <-chan omnivoice.StreamEvent, error) {
+	events := make(chan omnivoice.StreamEvent, 16)
+	return &mockStreamWriter{provider: m, events: events}, events, nil
+}
+
+type mockStreamWriter struct {
+	provider *mockStreamingSTTProvider
+	events   chan omnivoice.StreamEvent
+	closed   bool
+}
+
+func (w *mockStreamWriter) Write(p []byte) (int, error) {
+	w.provider.writes++
+	word := fmt.Sprintf("part%d", w.provider.writes)
+	w.events <- omnivoice.StreamEvent{Transcript: word[:3]}
+	w.events <- omnivoice.StreamEvent{Transcript: word, IsFinal: true}
+	return len(p), nil
+}
+
+func (w *mockStreamWriter) Close() error {
+	if !w.closed {
+		w.closed = true
+		close(w.events)
+	}
+	return nil
+}
+
+func TestFallbacks(t *testing.T) {
+	quota := errors.New("quota exceeded")
+	failingSTT := &mockSTTProvider{
+		name: "primary",
+		transcribeFunc: func(ctx context.Context, audio []byte, config omnivoice.TranscriptionConfig) (*omnivoice.TranscriptionResult, error) {
+			return nil, quota
+		},
+	}
+	failingTTS := &mockTTSProvider{
+		name: "primary",
+		synthesizeFunc: func(ctx context.Context, text string, config omnivoice.SynthesisConfig) (*omnivoice.SynthesisResult, error) {
+			return nil, quota
+		},
+	}
+	var gotVoice string
+	backupTTS := &mockTTSProvider{
+		name: "backup",
+		synthesizeFunc: func(ctx context.Context, text string, config omnivoice.SynthesisConfig) (*omnivoice.SynthesisResult, error) {
+			gotVoice = config.VoiceID
+			return &omnivoice.SynthesisResult{Audio: []byte("backup audio"), Format: "mp3"}, nil
+		},
+	}
+
+	p := newTestProcessor(failingSTT, failingTTS, Config{TTS: TTSConfig{VoiceID: "primary-voice"}})
+	p.sttFallbacks = []sttBackend{{provider: &mockSTTProvider{name: "backup"}}}
+	p.ttsFallbacks = []ttsBackend{{provider: backupTTS, config: TTSConfig{VoiceID: "backup-voice"}}}
+
+	text, err := p.TranscribeAudio(context.Background(), []byte("audio"), "audio/ogg")
+	if err != nil || text != "mock transcription" {
+		t.Errorf("TranscribeAudio() = %q, %v; want fallback transcription", text, err)
+	}
+	audio, _, err := p.SynthesizeSpeech(context.Background(), "hello")
+	if err != nil || string(audio) != "backup audio" {
+		t.Errorf("SynthesizeSpeech() = %q, %v; want fallback audio", audio, err)
+	}
+	if gotVoice != "backup-voice" {
+		t.Errorf("fallback voice = %q, want its own config", gotVoice)
+	}
+
+	// When every provider fails, each error is reported
+	p.sttFallbacks = []sttBackend{{provider: failingSTT}}
+	_, err = p.TranscribeAudio(context.Background(), []byte("audio"), "audio/ogg")
+	if !errors.Is(err, quota) || strings.Count(err.Error(), "primary: quota exceeded") != 2 {
+		t.Errorf("TranscribeAudio() error = %v, want both failures", err)
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	if _, err := NewTranscoder(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("NewTranscoder() with a missing binary should fail")
+	}
+
+	// A stand-in for ffmpeg that prefixes the input, which is the argument
+	// after -i, and writes it to the output, the last argument
+	bin := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nfor out; do :; done\n" +
+		"while [ $# -gt 0 ]; do [ \"$1\" = -i ] && in=$2; shift; done\n" +
+		"{ printf converted:; cat \"$in\"; } > \"$out\"\n"
+	if err := os.WriteFile(bin, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTranscoder(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotAudio, gotEncoding string
+	stt := &mockSTTProvider{
+		name: "stt",
+		transcribeFunc: func(ctx context.Context, audio []byte, config omnivoice.TranscriptionConfig) (*omnivoice.TranscriptionResult, error) {
+			gotAudio, gotEncoding = string(audio), config.Encoding
+			return &omnivoice.TranscriptionResult{Text: "ok"}, nil
+		},
+	}
+	p := newTestProcessor(stt, &mockTTSProvider{name: "tts"}, Config{})
+	p.transcoder, p.channelFormats = tr, DefaultChannelFormats
+
+	if _, err := p.TranscribeAudio(context.Background(), []byte("amr"), "audio/amr"); err != nil {
+		t.Fatal(err)
+	}
+	if gotAudio != "converted:amr" || gotEncoding != "wav" {
+		t.Errorf("AMR note reached STT as %q (%s), want converted WAV", gotAudio, gotEncoding)
+	}
+	if _, err := p.TranscribeAudio(context.Background(), []byte("ogg"), "audio/ogg"); err != nil {
+		t.Fatal(err)
+	}
+	if gotAudio != "ogg" {
+		t.Errorf("Ogg note reached STT as %q, want it unchanged", gotAudio)
+	}
+
+	ctx := agent.WithContact(context.Background(), "whatsapp:123")
+	audio, mimeType, err := p.SynthesizeSpeech(ctx, "hi")
+	if err != nil || string(audio) != "converted:mock audio data" || mimeType != "audio/ogg; codecs=opus" {
+		t.Errorf("WhatsApp reply = %q (%s), %v; want Ogg/Opus", audio, mimeType, err)
+	}
+	ctx = agent.WithContact(context.Background(), "discord:123")
+	audio, mimeType, _ = p.SynthesizeSpeech(ctx, "hi")
+	if string(audio) != "mock audio data" || mimeType != "audio/mpeg" {
+		t.Errorf("Discord reply = %q (%s), want MP3 unchanged", audio, mimeType)
+	}
+}
+
+func TestTranscribeStream(t *testing.T) {
+	batch := newTestProcessor(&mockSTTProvider{name: "batch"}, &mockTTSProvider{name: "test"}, Config{})
+	if batch.SupportsStreaming() {
+		t.Error("batch provider should not support streaming")
+	}
+	if _, err := batch.TranscribeStream(context.Background(), "audio/ogg"); !errors.Is(err, omnivoice.ErrStreamingNotSupported) {
+		t.Errorf("TranscribeStream() error = %v, want ErrStreamingNotSupported", err)
+	}
+
+	stt := &mockStreamingSTTProvider{mockSTTProvider: mockSTTProvider{name: "stream"}}
+	p := newTestProcessor(stt, &mockTTSProvider{name: "test"}, Config{STT: STTConfig{Streaming: true}})
+	p.streamIdle = 20 * time.Millisecond
+
+	stream, err := p.TranscribeStream(context.Background(), "audio/ogg")
+	if err != nil {
+		t.Fatalf("TranscribeStream() error = %v", err)
+	}
+	if _, err := stream.Write([]byte("audio")); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-stream.Partials(); got.Text != "par" || got.Final {
+		t.Errorf("first partial = %+v", got)
+	}
+	if got := <-stream.Partials(); got.Text != "part1" || !got.Final {
+		t.Errorf("second partial = %+v", got)
+	}
+	text, err := stream.Finish(context.Background())
+	if err != nil || text != "part1" {
+		t.Errorf("Finish() = %q, %v", text, err)
+	}
+
+	// A long voice note is written in chunks
+	stt.writes = 0
+	text, err = p.TranscribeAudio(context.Background(), make([]byte, streamChunkSize*2+1), "audio/ogg")
+	if err != nil || text != "part1 part2 part3" {
+		t.Errorf("TranscribeAudio() = %q, %v", text, err)
+	}
+}
+
+func TestAddedProviders(t *testing.T) {
+	var gotSSML, gotFormat, gotLang string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/inference":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			gotLang = r.FormValue("language")
+			_, _ = io.WriteString(w, `{"text":" hello from whisper\n"}`)
+		case "/cognitiveservices/v1":
+			if r.Header.Get("Ocp-Apim-Subscription-Key") != "key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			gotSSML, gotFormat = string(body), r.Header.Get("X-Microsoft-OutputFormat")
+			_, _ = w.Write([]byte("ID3audio"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(Config{
+		STT: STTConfig{Provider: ProviderWhisperCPP, BaseURL: server.URL, Language: "en"},
+		TTS: TTSConfig{Provider: ProviderAzure, APIKey: "key", BaseURL: server.URL, VoiceID: "en-GB-SoniaNeural"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	text, err := p.TranscribeAudio(context.Background(), []byte("OggS"), "audio/ogg")
+	if err != nil || text != "hello from whisper" || gotLang != "en" {
+		t.Errorf("TranscribeAudio() = %q, %v (language %q)", text, err, gotLang)
+	}
+
+	audio, mimeType, err := p.SynthesizeSpeech(context.Background(), "Fish & chips")
+	if err != nil || string(audio) != "ID3audio" || mimeType != "audio/mpeg" {
+		t.Errorf("SynthesizeSpeech() = %q, %q, %v", audio, mimeType, err)
+	}
+	if !strings.Contains(gotSSML, `xml:lang="en-GB"`) || !strings.Contains(gotSSML, `<voice name="en-GB-SoniaNeural">Fish &amp; chips</voice>`) {
+		t.Errorf("SSML = %s", gotSSML)
+	}
+	if gotFormat != "audio-24khz-48kbitrate-mono-mp3" {
+		t.Errorf("output format = %q", gotFormat)
+	}
+
+	// Azure needs a region or base URL
+	if _, err := New(Config{
+		STT: STTConfig{Provider: ProviderWhisperCPP},
+		TTS: TTSConfig{Provider: ProviderAzure, APIKey: "key"},
+	}, nil); err == nil || !strings.Contains(err.Error(), "region") {
+		t.Errorf("New() without region error = %v", err)
+	}
+	if _, err := New(Config{
+		STT: STTConfig{Provider: ProviderWhisperCPP},
+		TTS: TTSConfig{Provider: ProviderAzure, APIKey: "key", Options: map[string]string{"region": "westeurope"}},
+	}, nil); err != nil {
+		t.Errorf("New() with region error = %v", err)
+	}
+}
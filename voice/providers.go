@@ -0,0 +1,282 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/plexusone/omnivoice"
+)
+
+// Providers added to omnivoice's registry.
+const (
+	ProviderWhisperCPP = "whisper-cpp"
+	ProviderAzure      = "azure"
+)
+
+// Defaults for the added providers.
+const (
+	DefaultWhisperCPPBaseURL = "http://127.0.0.1:8080"
+	DefaultAzureVoice        = "en-US-JennyNeural"
+)
+
+// providerTimeout bounds a single request to the added providers.
+const providerTimeout = 2 * time.Minute
+
+func init() {
+	omnivoice.RegisterSTTProvider(ProviderWhisperCPP, func(config omnivoice.ProviderConfig) (omnivoice.STTProvider, error) {
+		baseURL := config.BaseURL
+		if baseURL == "" {
+			baseURL = DefaultWhisperCPPBaseURL
+		}
+		return &whisperCPP{baseURL: strings.TrimSuffix(baseURL, "/"), client: &http.Client{Timeout: providerTimeout}}, nil
+	})
+
+	omnivoice.RegisterTTSProvider(ProviderAzure, func(config omnivoice.ProviderConfig) (omnivoice.TTSProvider, error) {
+		if config.APIKey == "" {
+			return nil, errors.New("azure: API key is required")
+		}
+		baseURL := config.BaseURL
+		if baseURL == "" {
+			region, _ := config.Extensions["azure.region"].(string)
+			if region == "" {
+				return nil, errors.New("azure: set the region option or base_url")
+			}
+			baseURL = "https://" + region + ".tts.speech.microsoft.com"
+		}
+		return &azureTTS{
+			apiKey:  config.APIKey,
+			baseURL: strings.TrimSuffix(baseURL, "/"),
+			client:  &http.Client{Timeout: providerTimeout},
+		}, nil
+	})
+}
+
+// whisperCPP transcribes with a whisper.cpp server, which runs on this
+// machine. Start it with --convert to accept formats other than 16 kHz WAV.
+type whisperCPP struct {
+	baseURL string
+	client  *http.Client
+}
+
+// Name returns the provider name.
+func (w *whisperCPP) Name() string { return ProviderWhisperCPP }
+
+// Transcribe posts audio to the server's /inference endpoint.
+func (w *whisperCPP) Transcribe(ctx context.Context, audio []byte, config omnivoice.TranscriptionConfig) (*omnivoice.TranscriptionResult, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	ext := config.Encoding
+	if ext == "" || ext == "opus" {
+		ext = "ogg"
+	}
+	part, err := mw.CreateFormFile("file", "audio."+ext)
+	if err != nil {
+		return nil, fmt.Errorf("whisper-cpp: %w", err)
+	}
+	if _, err := part.Write(audio); err != nil {
+		return nil, fmt.Errorf("whisper-cpp: %w", err)
+	}
+	_ = mw.WriteField("response_format", "json")
+	if config.Language != "" {
+		_ = mw.WriteField("language", config.Language)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("whisper-cpp: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.baseURL+"/inference", &body)
+	if err != nil {
+		return nil, fmt.Errorf("whisper-cpp: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	data, err := do(w.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("whisper-cpp: %w", err)
+	}
+	var resp struct {
+		Text  string `json:"text"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("whisper-cpp: decode response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("whisper-cpp: %s", resp.Error)
+	}
+	return &omnivoice.TranscriptionResult{Text: strings.TrimSpace(resp.Text), Language: config.Language}, nil
+}
+
+// TranscribeFile transcribes an audio file.
+func (w *whisperCPP) TranscribeFile(ctx context.Context, path string, config omnivoice.TranscriptionConfig) (*omnivoice.TranscriptionResult, error) {
+	audio, err := os.ReadFile(path) //nolint:gosec // G304: path is supplied by the caller
+	if err != nil {
+		return nil, fmt.Errorf("whisper-cpp: %w", err)
+	}
+	if config.Encoding == "" {
+		config.Encoding = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	return w.Transcribe(ctx, audio, config)
+}
+
+// TranscribeURL is not supported; the server only accepts uploads.
+func (w *whisperCPP) TranscribeURL(context.Context, string, omnivoice.TranscriptionConfig) (*omnivoice.TranscriptionResult, error) {
+	return nil, errors.New("whisper-cpp: URL transcription not supported")
+}
+
+// azureTTS synthesizes speech with Azure AI Speech.
+type azureTTS struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// azureFormats maps output formats to Azure's names.
+var azureFormats = map[string]string{
+	"mp3":  "audio-24khz-48kbitrate-mono-mp3",
+	"opus": "ogg-24khz-16bit-mono-opus",
+	"ogg":  "ogg-24khz-16bit-mono-opus",
+	"wav":  "riff-24khz-16bit-mono-pcm",
+	"pcm":  "raw-24khz-16bit-mono-pcm",
+}
+
+// Name returns the provider name.
+func (a *azureTTS) Name() string { return ProviderAzure }
+
+// Synthesize converts text to speech with an SSML request.
+func (a *azureTTS) Synthesize(ctx context.Context, text string, config omnivoice.SynthesisConfig) (*omnivoice.SynthesisResult, error) {
+	voice := config.VoiceID
+	if voice == "" {
+		voice = DefaultAzureVoice
+	}
+	format := config.OutputFormat
+	if _, ok := azureFormats[format]; !ok {
+		format = "mp3"
+	}
+
+	var escaped strings.Builder
+	if err := xml.EscapeText(&escaped, []byte(text)); err != nil {
+		return nil, fmt.Errorf("azure: %w", err)
+	}
+	ssml := fmt.Sprintf(`<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="%s"><voice name="%s">%s</voice></speak>`,
+		voiceLocale(voice), voice, escaped.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/cognitiveservices/v1", strings.NewReader(ssml))
+	if err != nil {
+		return nil, fmt.Errorf("azure: %w", err)
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", a.apiKey)
+	req.Header.Set("Content-Type", "application/ssml+xml")
+	req.Header.Set("X-Microsoft-OutputFormat", azureFormats[format])
+	req.Header.Set("User-Agent", "omniagent")
+	audio, err := do(a.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("azure: %w", err)
+	}
+	return &omnivoice.SynthesisResult{Audio: audio, Format: format, SampleRate: 24000, CharacterCount: len(text)}, nil
+}
+
+// SynthesizeStream synthesizes the whole text and sends it as one chunk.
+func (a *azureTTS) SynthesizeStream(ctx context.Context, text string, config omnivoice.SynthesisConfig) (<-chan omnivoice.TTSStreamChunk, error) {
+	result, err := a.Synthesize(ctx, text, config)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan omnivoice.TTSStreamChunk, 1)
+	ch <- omnivoice.TTSStreamChunk{Audio: result.Audio, IsFinal: true}
+	close(ch)
+	return ch, nil
+}
+
+// ListVoices lists the region's voices.
+func (a *azureTTS) ListVoices(ctx context.Context) ([]omnivoice.Voice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"/cognitiveservices/voices/list", nil)
+	if err != nil {
+		return nil, fmt.Errorf("azure: %w", err)
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", a.apiKey)
+	data, err := do(a.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("azure: %w", err)
+	}
+	var list []struct {
+		ShortName   string `json:"ShortName"`
+		DisplayName string `json:"DisplayName"`
+		Locale      string `json:"Locale"`
+		Gender      string `json:"Gender"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("azure: decode voices: %w", err)
+	}
+	voices := make([]omnivoice.Voice, len(list))
+	for i, v := range list {
+		voices[i] = omnivoice.Voice{
+			ID:       v.ShortName,
+			Name:     v.DisplayName,
+			Language: v.Locale,
+			Gender:   strings.ToLower(v.Gender),
+			Provider: ProviderAzure,
+		}
+	}
+	return voices, nil
+}
+
+// GetVoice returns a voice by its short name.
+func (a *azureTTS) GetVoice(ctx context.Context, voiceID string) (*omnivoice.Voice, error) {
+	voices, err := a.ListVoices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range voices {
+		if v.ID == voiceID {
+			return &v, nil
+		}
+	}
+	return nil, omnivoice.ErrVoiceNotFound
+}
+
+// voiceLocale returns the locale of an Azure voice name such as
+// "en-US-JennyNeural".
+func voiceLocale(voice string) string {
+	parts := strings.SplitN(voice, "-", 3)
+	if len(parts) < 3 {
+		return "en-US"
+	}
+	return parts[0] + "-" + parts[1]
+}
+
+// do sends a request and returns the body of a successful response.
+func do(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req) //nolint:gosec // G704: URL comes from the voice config
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		return nil, fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return data, nil
+}
+
+var (
+	_ omnivoice.STTProvider = (*whisperCPP)(nil)
+	_ omnivoice.TTSProvider = (*azureTTS)(nil)
+)
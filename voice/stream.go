@@ -0,0 +1,208 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plexusone/omnivoice"
+)
+
+// streamChunkSize is how much of a voice note is written to a stream at a
+// time.
+const streamChunkSize = 32 << 10
+
+// streamIdleTimeout is how long Finish waits for more results before
+// closing a stream. Some providers drop results still in flight when the
+// stream closes, so Finish waits for them to stop arriving first.
+const streamIdleTimeout = 3 * time.Second
+
+// Partial is a streaming transcription result.
+type Partial struct {
+	// Text is the transcript so far: the final results, then the current
+	// interim one.
+	Text string
+	// Final is set when Text ends in a final result, which won't change.
+	Final bool
+}
+
+// Stream is a streaming transcription session. Write audio as it arrives,
+// read results from Partials, and call Finish for the transcript.
+type Stream struct {
+	writer   io.WriteCloser
+	partials chan Partial
+	activity chan struct{}
+	done     chan struct{}
+	idle     time.Duration
+
+	mu    sync.Mutex
+	final []string
+	err   error
+}
+
+// SupportsStreaming reports whether the STT provider has a streaming API.
+func (p *Processor) SupportsStreaming() bool {
+	_, ok := p.sttProvider.(omnivoice.STTStreamingProvider)
+	return ok
+}
+
+// TranscribeStream starts a streaming transcription of audio in the given
+// MIME type. It returns omnivoice.ErrStreamingNotSupported when the
+// provider has no streaming API.
+func (p *Processor) TranscribeStream(ctx context.Context, mimeType string) (*Stream, error) {
+	sp, ok := p.sttProvider.(omnivoice.STTStreamingProvider)
+	if !ok {
+		return nil, omnivoice.ErrStreamingNotSupported
+	}
+	return p.startStream(ctx, sp, p.config.STT, mimeType)
+}
+
+// startStream starts a streaming transcription with one provider.
+func (p *Processor) startStream(ctx context.Context, sp omnivoice.STTStreamingProvider, config STTConfig, mimeType string) (*Stream, error) {
+	writer, events, err := sp.TranscribeStream(ctx, omnivoice.TranscriptionConfig{
+		Model:    config.Model,
+		Language: config.Language,
+		Encoding: encoding(mimeType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start stream: %w", err)
+	}
+
+	s := &Stream{
+		writer:   writer,
+		partials: make(chan Partial, 16),
+		activity: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		idle:     p.streamIdle,
+	}
+	if s.idle == 0 {
+		s.idle = streamIdleTimeout
+	}
+	go s.read(events)
+	return s, nil
+}
+
+// read collects results until the provider closes the event channel.
+func (s *Stream) read(events <-chan omnivoice.StreamEvent) {
+	defer close(s.done)
+	defer close(s.partials)
+	for ev := range events {
+		select {
+		case s.activity <- struct{}{}:
+		default:
+		}
+
+		s.mu.Lock()
+		if ev.Error != nil {
+			if s.err == nil {
+				s.err = ev.Error
+			}
+			s.mu.Unlock()
+			continue
+		}
+		if ev.SpeechStarted || ev.SpeechEnded || (ev.Transcript == "" && !ev.IsFinal) {
+			s.mu.Unlock()
+			continue
+		}
+		if ev.IsFinal && strings.TrimSpace(ev.Transcript) != "" {
+			s.final = append(s.final, strings.TrimSpace(ev.Transcript))
+		}
+		text := strings.Join(s.final, " ")
+		if !ev.IsFinal {
+			text = strings.TrimSpace(text + " " + ev.Transcript)
+		}
+		s.mu.Unlock()
+
+		// Partials are advisory; a slow reader misses some rather than
+		// stalling the stream
+		select {
+		case s.partials <- Partial{Text: text, Final: ev.IsFinal}:
+		default:
+		}
+	}
+}
+
+// Write sends audio to the provider.
+func (s *Stream) Write(audio []byte) (int, error) {
+	return s.writer.Write(audio)
+}
+
+// Partials returns interim and final results as they arrive. It is closed
+// when the stream ends.
+func (s *Stream) Partials() <-chan Partial {
+	return s.partials
+}
+
+// Finish ends the audio, waits for the remaining results, and returns the
+// transcript.
+func (s *Stream) Finish(ctx context.Context) (string, error) {
+	timer := time.NewTimer(s.idle)
+	defer timer.Stop()
+wait:
+	for {
+		select {
+		case <-s.activity:
+			timer.Reset(s.idle)
+		case <-timer.C:
+			break wait
+		case <-s.done:
+			break wait
+		case <-ctx.Done():
+			_ = s.writer.Close()
+			return "", ctx.Err()
+		}
+	}
+
+	if err := s.writer.Close(); err != nil {
+		return "", fmt.Errorf("close stream: %w", err)
+	}
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil && len(s.final) == 0 {
+		return "", s.err
+	}
+	return strings.Join(s.final, " "), nil
+}
+
+// Close abandons the stream.
+func (s *Stream) Close() error {
+	return s.writer.Close()
+}
+
+// transcribeStreaming writes a voice note to a stream in chunks and waits
+// for the transcript.
+func (p *Processor) transcribeStreaming(ctx context.Context, sp omnivoice.STTStreamingProvider, config STTConfig, audio []byte, mimeType string, fallback bool) (string, error) {
+	stream, err := p.startStream(ctx, sp, config, mimeType)
+	if err != nil {
+		return "", err
+	}
+	for len(audio) > 0 {
+		n := min(len(audio), streamChunkSize)
+		if _, err := stream.Write(audio[:n]); err != nil {
+			_ = stream.Close()
+			return "", err
+		}
+		audio = audio[n:]
+	}
+	text, err := stream.Finish(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	p.logger.Info("transcription complete",
+		"provider", sp.Name(),
+		"fallback", fallback,
+		"text_length", len(text),
+		"streaming", true)
+
+	return text, nil
+}
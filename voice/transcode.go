@@ -0,0 +1,138 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/plexusone/omniagent/agent"
+)
+
+// Audio formats the transcoder produces.
+const (
+	FormatWAV = "wav" // 16 kHz mono PCM, which every STT provider accepts
+	FormatOGG = "ogg" // Opus in Ogg, as voice notes on WhatsApp and Telegram
+	FormatMP3 = "mp3"
+)
+
+// DefaultChannelFormats are the voice reply formats channels need: WhatsApp
+// and Telegram only show Ogg/Opus audio as a voice note.
+var DefaultChannelFormats = map[string]string{
+	"whatsapp": FormatOGG,
+	"telegram": FormatOGG,
+}
+
+// formatArgs are the ffmpeg output arguments for each format.
+var formatArgs = map[string][]string{
+	FormatWAV: {"-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", "-f", "wav"},
+	FormatOGG: {"-ac", "1", "-c:a", "libopus", "-b:a", "32k", "-application", "voip", "-f", "ogg"},
+	FormatMP3: {"-ac", "1", "-c:a", "libmp3lame", "-q:a", "4", "-f", "mp3"},
+}
+
+// formatMIME are the MIME types of each format.
+var formatMIME = map[string]string{
+	FormatWAV: "audio/wav",
+	FormatOGG: "audio/ogg; codecs=opus",
+	FormatMP3: "audio/mpeg",
+}
+
+// Transcoder converts audio between formats with ffmpeg.
+type Transcoder struct {
+	bin string
+}
+
+// NewTranscoder finds ffmpeg at path, or on PATH when path is empty.
+func NewTranscoder(path string) (*Transcoder, error) {
+	if path == "" {
+		path = "ffmpeg"
+	}
+	bin, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("transcode: ffmpeg not found: %w", err)
+	}
+	return &Transcoder{bin: bin}, nil
+}
+
+// Convert transcodes audio in any format ffmpeg reads to format, returning
+// the audio and its MIME type. Input and output go through temporary
+// files, since containers such as M4A cannot be read from a pipe.
+func (t *Transcoder) Convert(ctx context.Context, audio []byte, format string) ([]byte, string, error) {
+	args, ok := formatArgs[format]
+	if !ok {
+		return nil, "", fmt.Errorf("transcode: unknown format %q", format)
+	}
+	dir, err := os.MkdirTemp("", "omniagent-audio-")
+	if err != nil {
+		return nil, "", fmt.Errorf("transcode: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out."+format)
+	if err := os.WriteFile(in, audio, 0o600); err != nil {
+		return nil, "", fmt.Errorf("transcode: %w", err)
+	}
+	cmdArgs := append([]string{"-hide_banner", "-loglevel", "error", "-nostdin", "-y", "-i", in, "-vn"}, args...)
+	cmd := exec.CommandContext(ctx, t.bin, append(cmdArgs, out)...) //nolint:gosec // G204: binary comes from operator configuration
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("transcode: ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	data, err := os.ReadFile(out) //nolint:gosec // G304: path is in our temporary directory
+	if err != nil {
+		return nil, "", fmt.Errorf("transcode: %w", err)
+	}
+	return data, formatMIME[format], nil
+}
+
+// formatOf returns the transcoder format of a MIME type, or "".
+func formatOf(mimeType string) string {
+	switch encoding(mimeType) {
+	case "opus":
+		return FormatOGG
+	case "mp3":
+		return FormatMP3
+	case "wav":
+		return FormatWAV
+	}
+	return ""
+}
+
+// inboundAudio converts audio STT providers may not accept, such as AMR,
+// M4A, or WebM, to WAV. Audio is left as is when no transcoder is
+// configured or conversion fails.
+func (p *Processor) inboundAudio(ctx context.Context, audio []byte, mimeType string) ([]byte, string) {
+	if p.transcoder == nil || mimeType == "" || encoding(mimeType) != "" {
+		return audio, mimeType
+	}
+	converted, convertedType, err := p.transcoder.Convert(ctx, audio, FormatWAV)
+	if err != nil {
+		p.logger.Warn("audio transcoding failed, sending original", "mime_type", mimeType, "error", err)
+		return audio, mimeType
+	}
+	p.logger.Debug("transcoded voice note", "from", mimeType, "to", convertedType)
+	return converted, convertedType
+}
+
+// channelAudio converts synthesized speech to the format the reply's
+// channel needs. The channel comes from the sender's contact ID.
+func (p *Processor) channelAudio(ctx context.Context, audio []byte, mimeType string) ([]byte, string) {
+	if p.transcoder == nil {
+		return audio, mimeType
+	}
+	channel, _, _ := strings.Cut(agent.ContactFromContext(ctx), ":")
+	want := p.channelFormats[channel]
+	if want == "" || want == formatOf(mimeType) {
+		return audio, mimeType
+	}
+	converted, convertedType, err := p.transcoder.Convert(ctx, audio, want)
+	if err != nil {
+		p.logger.Warn("audio transcoding failed, sending original", "channel", channel, "format", want, "error", err)
+		return audio, mimeType
+	}
+	return converted, convertedType
+}
@@ -0,0 +1,162 @@
+// Package worker runs tools for a remote agent. A worker connects to the
+// gateway with a worker token, advertises its tools, and executes the
+// calls the agent routes to it, so one agent can use a shell on a home
+// server and a browser on a desktop.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/gateway/client"
+)
+
+// resultTimeout bounds sending a result back to the gateway.
+const resultTimeout = 30 * time.Second
+
+// Config configures a worker.
+type Config struct {
+	// URL is the gateway WebSocket URL, such as ws://gateway:18789/ws.
+	URL string
+
+	// Token is a worker token from the gateway's config.
+	Token string //nolint:gosec // G117: Token loaded from config file
+
+	// Tools are the tools the worker offers.
+	Tools []agent.Tool
+
+	Logger *slog.Logger
+}
+
+// Worker executes tool calls from the gateway.
+type Worker struct {
+	config Config
+	tools  map[string]agent.Tool
+	client atomic.Pointer[client.Client]
+	calls  sync.WaitGroup
+	logger *slog.Logger
+}
+
+// New creates a worker.
+func New(config Config) (*Worker, error) {
+	if config.URL == "" {
+		return nil, errors.New("gateway URL required")
+	}
+	if len(config.Tools) == 0 {
+		return nil, errors.New("no tools to offer")
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	w := &Worker{
+		config: config,
+		tools:  make(map[string]agent.Tool, len(config.Tools)),
+		logger: config.Logger,
+	}
+	for _, t := range config.Tools {
+		w.tools[t.Name()] = t
+	}
+	return w, nil
+}
+
+// Run connects to the gateway and serves tool calls until ctx is done,
+// registering the tools again after each reconnect. Calls in flight are
+// finished before it returns.
+func (w *Worker) Run(ctx context.Context) error {
+	connected := make(chan struct{}, 1)
+	c, err := client.Dial(ctx, w.config.URL, client.Options{
+		Token:  w.config.Token,
+		Logger: w.logger,
+		OnEvent: func(e client.Event) {
+			if e.Name == "tool.call" {
+				w.calls.Add(1)
+				go w.call(ctx, e.Data)
+			}
+		},
+		OnState: func(s client.State) {
+			if s == client.StateConnected {
+				select {
+				case connected <- struct{}{}:
+				default:
+				}
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	w.client.Store(c)
+	defer func() {
+		w.calls.Wait()
+		_ = c.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-connected:
+			if err := w.register(ctx, c); err != nil {
+				// An unknown token is not going to work on retry
+				var gwErr *client.Error
+				if errors.As(err, &gwErr) {
+					return err
+				}
+				w.logger.Warn("registering tools failed", "error", err)
+			}
+		}
+	}
+}
+
+// register advertises the worker's tools.
+func (w *Worker) register(ctx context.Context, c *client.Client) error {
+	specs := make([]map[string]any, 0, len(w.config.Tools))
+	for _, t := range w.config.Tools {
+		specs = append(specs, map[string]any{
+			"name":        t.Name(),
+			"description": t.Description(),
+			"parameters":  t.Parameters(),
+		})
+	}
+	resp, err := c.Request(ctx, client.Message{Type: "worker.register", Data: map[string]any{"tools": specs}})
+	if err != nil {
+		return fmt.Errorf("register tools: %w", err)
+	}
+	w.logger.Info("registered with gateway", "tools", resp.Data["tools"])
+	return nil
+}
+
+// call executes one tool call and sends back its result.
+func (w *Worker) call(ctx context.Context, data map[string]any) {
+	defer w.calls.Done()
+	callID, _ := data["call_id"].(string)
+	name, _ := data["tool"].(string)
+	args, _ := data["args"].(string)
+
+	reply := map[string]any{"call_id": callID}
+	if tool, ok := w.tools[name]; !ok {
+		reply["error"] = "unknown tool " + name
+	} else {
+		w.logger.Info("running tool", "tool", name, "call", callID)
+		result, err := tool.Execute(ctx, json.RawMessage(args))
+		if err != nil {
+			reply["error"] = err.Error()
+		} else {
+			reply["result"] = result
+		}
+	}
+
+	// Still answer when ctx ended, so the agent is not left waiting
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resultTimeout)
+	defer cancel()
+	if _, err := w.client.Load().Request(sendCtx, client.Message{Type: "tool.result", Data: reply}); err != nil {
+		w.logger.Warn("sending tool result failed", "tool", name, "call", callID, "error", err)
+	}
+}
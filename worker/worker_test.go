@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omniagent/agent"
+	"github.com/plexusone/omniagent/gateway"
+)
+
+// echoTool returns its "text" argument.
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Echo text" }
+func (echoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (echoTool) Execute(_ context.Context, args json.RawMessage) (string, error) {
+	var p struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(args, &p); err != nil {
+		return "", err
+	}
+	return "echo: " + p.Text, nil
+}
+
+// registrar collects the tools the gateway registers.
+type registrar struct {
+	mu    sync.Mutex
+	tools map[string]agent.Tool
+}
+
+func (r *registrar) RegisterToolFrom(source string, tool agent.Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[agent.ToolID(source, tool.Name())] = tool
+	return nil
+}
+
+func (r *registrar) UnregisterTool(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, id)
+}
+
+func (r *registrar) get(id string) agent.Tool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tools[id]
+}
+
+// startGateway runs a gateway on a free port and returns its WebSocket URL.
+func startGateway(t *testing.T, config gateway.Config) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Address = l.Addr().String()
+	l.Close()
+	gw, err := gateway.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = gw.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		resp, err := http.Get("http://" + config.Address + "/health")
+		if err == nil {
+			resp.Body.Close()
+			return "ws://" + config.Address + "/ws"
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gateway not listening: %v", err)
+		}
+	}
+}
+
+func TestWorker(t *testing.T) {
+	tools := &registrar{tools: map[string]agent.Tool{}}
+	url := startGateway(t, gateway.Config{
+		Workers: []gateway.WorkerConfig{{Name: "desk", Token: "secret"}},
+		Tools:   tools,
+	})
+
+	w, err := New(Config{
+		URL:   url,
+		Token: "secret",
+		Tools: []agent.Tool{echoTool{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	var tool agent.Tool
+	for deadline := time.Now().Add(5 * time.Second); tool == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("worker tools not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+		tool = tools.get("worker:desk/desk_echo")
+	}
+	out, err := tool.Execute(context.Background(), json.RawMessage(`{"text":"hi"}`))
+	if err != nil || out != "echo: hi" {
+		t.Errorf("Execute() = %q, %v", out, err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestWorkerRejectedToken(t *testing.T) {
+	url := startGateway(t, gateway.Config{Tools: &registrar{tools: map[string]agent.Tool{}}})
+
+	w, err := New(Config{
+		URL:   url,
+		Token: "unknown",
+		Tools: []agent.Tool{echoTool{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Run(ctx); err == nil || !strings.Contains(err.Error(), "worker role") {
+		t.Errorf("Run() error = %v, want forbidden", err)
+	}
+}